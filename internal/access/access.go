@@ -0,0 +1,111 @@
+// Package access decides which clients of the serve proxy may pull which
+// repositories. Clients present a token; only its SHA-256 digest is kept in
+// the policy file, so the file itself holds no secrets.
+package access
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnauthenticated is returned for requests without a known token
+var ErrUnauthenticated = errors.New("unknown or missing token")
+
+// ErrForbidden is returned when a client may not pull a repository
+var ErrForbidden = errors.New("repository not allowed")
+
+// Client is a named client of the proxy and the repositories it may pull, as
+// owner/repo or patterns such as owner/*
+type Client struct {
+	TokenSHA256 string   `yaml:"token_sha256"`
+	Repos       []string `yaml:"repos"`
+}
+
+// Policy is a set of clients by name
+type Policy struct {
+	Clients map[string]Client `yaml:"clients"`
+}
+
+// Parse parses and validates a policy. Unknown fields are rejected so that a
+// typo never opens up access.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse access policy: %w", err)
+	}
+	if len(p.Clients) == 0 {
+		return nil, fmt.Errorf("access policy has no clients")
+	}
+
+	for _, name := range p.Names() {
+		client := p.Clients[name]
+		client.TokenSHA256 = strings.ToLower(client.TokenSHA256)
+		p.Clients[name] = client
+		if digest, err := hex.DecodeString(client.TokenSHA256); err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("client %s: token_sha256 must be a hex SHA-256 digest", name)
+		}
+		for _, repo := range client.Repos {
+			if _, err := path.Match(repo, ""); err != nil {
+				return nil, fmt.Errorf("client %s: invalid repo pattern %q", name, repo)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// Load reads and parses the policy at path
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access policy: %w", err)
+	}
+	return Parse(data)
+}
+
+// Names returns the names of the clients, sorted
+func (p *Policy) Names() []string {
+	names := make([]string, 0, len(p.Clients))
+	for name := range p.Clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Authorize returns the name of the client the token belongs to, and
+// ErrUnauthenticated or ErrForbidden unless it may pull repo. Repositories
+// match case-insensitively, as GitHub names them.
+func (p *Policy) Authorize(token, repo string) (string, error) {
+	if token == "" {
+		return "", ErrUnauthenticated
+	}
+	sum := sha256.Sum256([]byte(token))
+	presented := hex.EncodeToString(sum[:])
+
+	for _, name := range p.Names() {
+		client := p.Clients[name]
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(client.TokenSHA256)) != 1 {
+			continue
+		}
+		for _, pattern := range client.Repos {
+			if ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(repo)); err == nil && ok {
+				return name, nil
+			}
+		}
+		return name, fmt.Errorf("%w: %s may not pull %s", ErrForbidden, name, repo)
+	}
+	return "", ErrUnauthenticated
+}
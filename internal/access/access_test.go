@@ -0,0 +1,72 @@
+package access
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tokenDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParse(t *testing.T) {
+	valid := "clients:\n  ci:\n    token_sha256: " + tokenDigest("secret") + "\n    repos: [owner/*]\n"
+	if _, err := Parse([]byte(valid)); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	testCases := map[string]string{
+		"empty":         "",
+		"no digest":     "clients:\n  ci:\n    repos: [owner/*]\n",
+		"short digest":  "clients:\n  ci:\n    token_sha256: abc\n",
+		"unknown field": "clients:\n  ci:\n    token: secret\n",
+		"bad pattern":   "clients:\n  ci:\n    token_sha256: " + tokenDigest("secret") + "\n    repos: ['owner/[']\n",
+	}
+	for name, data := range testCases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.yml")
+	data := "clients:\n" +
+		"  ci:\n    token_sha256: " + tokenDigest("ci-token") + "\n    repos: [owner/*, other/tool]\n" +
+		"  laptop:\n    token_sha256: " + tokenDigest("laptop-token") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	testCases := []struct {
+		token    string
+		repo     string
+		client   string
+		expected error
+	}{
+		{"ci-token", "owner/repo", "ci", nil},
+		{"ci-token", "other/tool", "ci", nil},
+		{"ci-token", "Owner/Repo", "ci", nil},
+		{"ci-token", "OTHER/Tool", "ci", nil},
+		{"ci-token", "Other/Secret", "ci", ErrForbidden},
+		{"ci-token", "other/secret", "ci", ErrForbidden},
+		{"laptop-token", "owner/repo", "laptop", ErrForbidden},
+		{"wrong", "owner/repo", "", ErrUnauthenticated},
+		{"", "owner/repo", "", ErrUnauthenticated},
+	}
+	for _, tc := range testCases {
+		client, err := policy.Authorize(tc.token, tc.repo)
+		if client != tc.client || !errors.Is(err, tc.expected) {
+			t.Errorf("Expected %q, %v for %s on %s, got %q, %v", tc.client, tc.expected, tc.token, tc.repo, client, err)
+		}
+	}
+}
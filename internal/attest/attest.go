@@ -0,0 +1,263 @@
+package attest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/23prime/gh-download/internal/checksum"
+)
+
+const (
+	// PayloadType is the DSSE payload type of in-toto statements
+	PayloadType = "application/vnd.in-toto+json"
+	// StatementType is the in-toto statement version produced
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType identifies the mirror predicate
+	PredicateType = "https://github.com/23prime/gh-download/mirror/v1"
+)
+
+// Subject is a file of the mirror with its digests
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate describes the mirror as a whole. Root is the Merkle root of
+// the subjects (see MerkleRoot), so that a single digest pins every file.
+type Predicate struct {
+	Root  string `json:"root"`
+	Files int    `json:"files"`
+}
+
+// Statement is an in-toto statement over the files of a mirror
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Envelope is a DSSE envelope
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a DSSE signature
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Subjects returns the regular files below dir with their SHA-256 digests,
+// named by their slash-separated path relative to dir and sorted by name.
+// Files for which skip returns true are left out.
+func Subjects(dir string, skip func(name string) bool) ([]Subject, error) {
+	var subjects []Subject
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if skip != nil && skip(name) {
+			return nil
+		}
+
+		digest, err := checksum.File(path, checksum.SHA256)
+		if err != nil {
+			return err
+		}
+		subjects = append(subjects, Subject{Name: name, Digest: map[string]string{"sha256": digest}})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index %s: %w", dir, err)
+	}
+
+	sort.Slice(subjects, func(i, j int) bool {
+		return subjects[i].Name < subjects[j].Name
+	})
+	return subjects, nil
+}
+
+// NewStatement returns the statement over the subjects of a mirror
+func NewStatement(subjects []Subject) Statement {
+	return Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate:     Predicate{Root: MerkleRoot(subjects), Files: len(subjects)},
+	}
+}
+
+// MerkleRoot returns the hex root of a binary Merkle tree over the subjects
+// in order. A leaf hashes 0x00, the name, 0x00 and the hex SHA-256 digest;
+// an inner node hashes 0x01 and its two children, and an odd node is
+// carried up unchanged. The root of no subjects is the hash of nothing.
+func MerkleRoot(subjects []Subject) string {
+	if len(subjects) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+
+	level := make([][]byte, 0, len(subjects))
+	for _, subject := range subjects {
+		h := sha256.New()
+		h.Write([]byte{0})
+		h.Write([]byte(subject.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(subject.Digest["sha256"]))
+		level = append(level, h.Sum(nil))
+	}
+
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			h := sha256.New()
+			h.Write([]byte{1})
+			h.Write(level[i])
+			h.Write(level[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// LoadSigner reads a PEM-encoded PKCS #8 Ed25519 or ECDSA private key, as
+// written by "openssl genpkey"
+func LoadSigner(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s is not a PEM-encoded PKCS #8 private key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T: use an Ed25519 or ECDSA key", key)
+}
+
+// KeyID returns the hex SHA-256 of the DER-encoded public key
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sign returns the statement in a DSSE envelope signed by signer
+func Sign(statement Statement, signer crypto.Signer) (Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to encode statement: %w", err)
+	}
+
+	keyID, err := KeyID(signer.Public())
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	message := PAE(PayloadType, payload)
+	var sig []byte
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		sig, err = signer.Sign(rand.Reader, message, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(message)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to sign statement: %w", err)
+	}
+
+	return Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []Signature{{KeyID: keyID, Sig: base64.StdEncoding.EncodeToString(sig)}},
+	}, nil
+}
+
+// Verify checks that one of the signatures of the envelope was made by pub
+// and returns the statement it carries
+func Verify(envelope Envelope, pub crypto.PublicKey) (Statement, error) {
+	var statement Statement
+	if envelope.PayloadType != PayloadType {
+		return statement, fmt.Errorf("unexpected payload type %s", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return statement, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	message := PAE(envelope.PayloadType, payload)
+	digest := sha256.Sum256(message)
+	verified := false
+	for _, signature := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err != nil {
+			continue
+		}
+		switch pub := pub.(type) {
+		case ed25519.PublicKey:
+			verified = verified || ed25519.Verify(pub, message, sig)
+		case *ecdsa.PublicKey:
+			verified = verified || ecdsa.VerifyASN1(pub, digest[:], sig)
+		default:
+			return statement, fmt.Errorf("unsupported key type %T", pub)
+		}
+	}
+	if !verified {
+		return statement, errors.New("no valid signature")
+	}
+
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return statement, fmt.Errorf("invalid statement: %w", err)
+	}
+	return statement, nil
+}
+
+// PAE returns the DSSE pre-authentication encoding of a payload, the bytes
+// that are actually signed
+func PAE(payloadType string, payload []byte) []byte {
+	encoded := "DSSEv1 " + strconv.Itoa(len(payloadType)) + " " + payloadType + " " + strconv.Itoa(len(payload)) + " "
+	return append([]byte(encoded), payload...)
+}
@@ -0,0 +1,144 @@
+package attest
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeMirror(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"owner/repo/tool.zip":        "zip",
+		"owner/repo/tool.zip.sha256": "sidecar",
+		"skip.json":                  "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSubjects(t *testing.T) {
+	dir := writeMirror(t)
+
+	subjects, err := Subjects(dir, func(name string) bool { return name == "skip.json" })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(subjects) != 2 {
+		t.Fatalf("Expected 2 subjects, got %v", subjects)
+	}
+	if subjects[0].Name != "owner/repo/tool.zip" || subjects[1].Name != "owner/repo/tool.zip.sha256" {
+		t.Errorf("Expected sorted relative names, got %v", subjects)
+	}
+	// sha256("zip")
+	if subjects[0].Digest["sha256"] != "4a70fe9aa6436e02c2dea340fbd1e352e4ef2d8ce6ca52ad25d4b95471fc8bf2" {
+		t.Errorf("Unexpected digest %v", subjects[0].Digest)
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	a := Subject{Name: "a", Digest: map[string]string{"sha256": "00"}}
+	b := Subject{Name: "b", Digest: map[string]string{"sha256": "11"}}
+	c := Subject{Name: "c", Digest: map[string]string{"sha256": "22"}}
+
+	root := MerkleRoot([]Subject{a, b, c})
+	if root == MerkleRoot([]Subject{a, b}) || root == MerkleRoot([]Subject{b, a, c}) {
+		t.Error("Expected the root to depend on every subject and their order")
+	}
+	if root != MerkleRoot([]Subject{a, b, c}) {
+		t.Error("Expected the root to be deterministic")
+	}
+
+	changed := c
+	changed.Digest = map[string]string{"sha256": "33"}
+	if root == MerkleRoot([]Subject{a, b, changed}) {
+		t.Error("Expected a changed digest to change the root")
+	}
+}
+
+func writeKey(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSignAndVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subjects, err := Subjects(writeMirror(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement := NewStatement(subjects)
+
+	for _, key := range []any{edKey, ecKey} {
+		signer, err := LoadSigner(writeKey(t, key))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		envelope, err := Sign(statement, signer)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		verified, err := Verify(envelope, signer.Public())
+		if err != nil {
+			t.Fatalf("Expected a valid signature, got %v", err)
+		}
+		if verified.Predicate.Root != statement.Predicate.Root || verified.Predicate.Files != 3 {
+			t.Errorf("Unexpected statement %+v", verified)
+		}
+
+		envelope.Payload = envelope.Payload[:len(envelope.Payload)-4] + "AAAA"
+		if _, err := Verify(envelope, signer.Public()); err == nil {
+			t.Error("Expected a modified payload to fail verification, got nil")
+		}
+	}
+}
+
+func TestLoadSigner_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSigner(path); err == nil {
+		t.Error("Expected error for an invalid key, got nil")
+	}
+}
+
+func TestPAE(t *testing.T) {
+	expected := "DSSEv1 28 application/vnd.in-toto+json 2 {}"
+	if got := string(PAE(PayloadType, []byte("{}"))); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
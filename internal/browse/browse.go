@@ -0,0 +1,362 @@
+// Package browse is the full-screen release browser of the browse command:
+// the releases of a repository on the left and the assets and notes of the
+// selected one on the right, with keys to mark assets for download.
+package browse
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/progress"
+)
+
+// Key is a key press, as read from the terminal
+type Key string
+
+const (
+	KeyUp     Key = "up"
+	KeyDown   Key = "down"
+	KeyLeft   Key = "left"
+	KeyRight  Key = "right"
+	KeyTab    Key = "tab"
+	KeySpace  Key = "space"
+	KeyEnter  Key = "enter"
+	KeyEscape Key = "escape"
+	KeyCtrlC  Key = "ctrl-c"
+)
+
+// help lists the keys in the bottom line
+const help = "↑↓ move  ←→/tab switch  space mark  a mark all  c copy URL  p prereleases  enter download  q quit"
+
+// Selection is what the user chose to download: assets of one release, by
+// name
+type Selection struct {
+	Tag    string
+	Assets []string
+}
+
+// Model is the state of the browser. It changes only through Update, so it
+// can be driven by tests as well as by the terminal.
+type Model struct {
+	// Copy puts text on the clipboard and returns how, for the c key;
+	// copying is unavailable when nil
+	Copy func(text string) (string, error)
+
+	repository string
+	releases   []github.Release
+	// visible are the indexes of the releases shown, which leave out
+	// prereleases unless showPrereleases
+	visible         []int
+	showPrereleases bool
+	// release is the cursor in visible and asset the one in the assets of
+	// that release
+	release     int
+	asset       int
+	assetsFocus bool
+	// marked are the asset IDs of the selected release marked for download
+	marked    map[int]bool
+	status    string
+	selection *Selection
+}
+
+// New returns a browser of the releases of repository, newest first as the
+// API lists them, with prereleases hidden
+func New(repository string, releases []github.Release) *Model {
+	m := &Model{repository: repository, releases: releases, marked: make(map[int]bool)}
+	m.filter()
+	return m
+}
+
+// Selection returns what was chosen for download, or nil when the browser
+// was quit
+func (m *Model) Selection() *Selection {
+	return m.selection
+}
+
+// Update applies a key and reports whether the browser is done
+func (m *Model) Update(key Key) bool {
+	m.status = ""
+	switch key {
+	case "q", KeyEscape, KeyCtrlC:
+		return true
+	case KeyUp, "k":
+		m.move(-1)
+	case KeyDown, "j":
+		m.move(1)
+	case KeyLeft, "h":
+		m.assetsFocus = false
+	case KeyRight, "l":
+		m.assetsFocus = len(m.assets()) > 0
+	case KeyTab:
+		m.assetsFocus = !m.assetsFocus && len(m.assets()) > 0
+	case KeySpace:
+		m.toggle()
+	case "a":
+		m.toggleAll()
+	case "c":
+		m.copyURL()
+	case "p":
+		m.showPrereleases = !m.showPrereleases
+		m.filter()
+	case KeyEnter, "d":
+		return m.choose()
+	}
+	return false
+}
+
+// filter recomputes the visible releases, keeping the selected one when it
+// is still shown
+func (m *Model) filter() {
+	current, selected := m.current()
+	m.visible = m.visible[:0]
+	for i, release := range m.releases {
+		if release.Prerelease && !m.showPrereleases {
+			continue
+		}
+		if selected && i == current {
+			m.release = len(m.visible)
+		}
+		m.visible = append(m.visible, i)
+	}
+	if !selected || m.release >= len(m.visible) || m.visible[m.release] != current {
+		m.selectRelease(0)
+	}
+}
+
+// current returns the index of the selected release in releases
+func (m *Model) current() (int, bool) {
+	if m.release >= len(m.visible) {
+		return 0, false
+	}
+	return m.visible[m.release], true
+}
+
+// assets returns the assets of the selected release
+func (m *Model) assets() []github.Asset {
+	if i, ok := m.current(); ok {
+		return m.releases[i].Assets
+	}
+	return nil
+}
+
+// selectRelease moves the release cursor, dropping the marks, which are for
+// the assets of one release
+func (m *Model) selectRelease(i int) {
+	m.release = i
+	m.asset = 0
+	m.marked = make(map[int]bool)
+	if len(m.assets()) == 0 {
+		m.assetsFocus = false
+	}
+}
+
+func (m *Model) move(delta int) {
+	if m.assetsFocus {
+		m.asset = clamp(m.asset+delta, len(m.assets()))
+		return
+	}
+	if i := clamp(m.release+delta, len(m.visible)); i != m.release {
+		m.selectRelease(i)
+	}
+}
+
+func (m *Model) toggle() {
+	assets := m.assets()
+	if !m.assetsFocus || len(assets) == 0 {
+		m.status = "Switch to the assets with → to mark them"
+		return
+	}
+	id := assets[m.asset].ID
+	m.marked[id] = !m.marked[id]
+	if !m.marked[id] {
+		delete(m.marked, id)
+	}
+}
+
+// toggleAll marks every asset of the release, or none when all are marked
+func (m *Model) toggleAll() {
+	assets := m.assets()
+	all := len(m.marked) == len(assets)
+	m.marked = make(map[int]bool)
+	if !all {
+		for _, asset := range assets {
+			m.marked[asset.ID] = true
+		}
+	}
+}
+
+// copyURL copies the browser download URL of the asset under the cursor
+func (m *Model) copyURL() {
+	assets := m.assets()
+	switch {
+	case m.Copy == nil:
+		m.status = "Copying is not available"
+	case !m.assetsFocus || len(assets) == 0:
+		m.status = "Move to an asset with → to copy its URL"
+	default:
+		asset := assets[m.asset]
+		method, err := m.Copy(asset.BrowserDownloadURL)
+		if err != nil {
+			m.status = fmt.Sprintf("Failed to copy the URL of %s: %v", asset.Name, err)
+			return
+		}
+		m.status = fmt.Sprintf("Copied the URL of %s with %s", asset.Name, method)
+	}
+}
+
+// choose selects the marked assets, or the asset under the cursor when none
+// are marked, and reports whether there was any
+func (m *Model) choose() bool {
+	assets := m.assets()
+	i, ok := m.current()
+	if !ok || len(assets) == 0 {
+		m.status = "This release has no assets"
+		return false
+	}
+
+	selection := &Selection{Tag: m.releases[i].TagName}
+	for _, asset := range assets {
+		if m.marked[asset.ID] {
+			selection.Assets = append(selection.Assets, asset.Name)
+		}
+	}
+	if len(selection.Assets) == 0 {
+		if !m.assetsFocus {
+			m.status = "Mark assets with space, or move to one with →"
+			return false
+		}
+		selection.Assets = []string{assets[m.asset].Name}
+	}
+	m.selection = selection
+	return true
+}
+
+// View renders the browser as height lines of width columns
+func (m *Model) View(width, height int) []string {
+	if width < 20 || height < 4 {
+		return []string{fit("Terminal too small", width)}
+	}
+	leftWidth := min(32, width/3)
+	rightWidth := width - leftWidth - 3
+	rows := height - 2
+
+	shown := "hidden"
+	if m.showPrereleases {
+		shown = "shown"
+	}
+	lines := []string{fit(fmt.Sprintf("%s: %d releases, prereleases %s", m.repository, len(m.visible), shown), width)}
+
+	left := m.releaseLines(rows)
+	right := m.detailLines(rightWidth, rows)
+	for i := range rows {
+		lines = append(lines, fit(left[i], leftWidth)+" │ "+fit(right[i], rightWidth))
+	}
+
+	footer := help
+	if m.status != "" {
+		footer = m.status
+	}
+	return append(lines, fit(footer, width))
+}
+
+// releaseLines renders the releases, scrolled to keep the cursor in view
+func (m *Model) releaseLines(rows int) []string {
+	lines := make([]string, rows)
+	first := scroll(m.release, len(m.visible), rows)
+	for row := range rows {
+		i := first + row
+		if i >= len(m.visible) {
+			break
+		}
+		release := m.releases[m.visible[i]]
+		label := release.TagName
+		if release.Prerelease {
+			label += " (pre)"
+		}
+		lines[row] = cursor(i == m.release, !m.assetsFocus) + label
+	}
+	if len(m.visible) == 0 {
+		lines[0] = "  no releases"
+	}
+	return lines
+}
+
+// detailLines renders the assets of the selected release, scrolled to keep
+// the cursor in view, followed by its notes
+func (m *Model) detailLines(width, rows int) []string {
+	var lines []string
+	assets := m.assets()
+	if len(assets) == 0 {
+		lines = append(lines, "  no assets")
+	}
+	// Leave at least a third of the pane to the notes
+	assetRows := min(len(assets), max(rows*2/3, 1))
+	first := scroll(m.asset, len(assets), assetRows)
+	for i := first; i < first+assetRows && i < len(assets); i++ {
+		asset := assets[i]
+		mark := "[ ]"
+		if m.marked[asset.ID] {
+			mark = "[x]"
+		}
+		size := progress.FormatBytes(int64(asset.Size))
+		name := fit(asset.Name, max(width-len(mark)-len(size)-4, 1))
+		lines = append(lines, cursor(i == m.asset, m.assetsFocus)+mark+" "+name+" "+size)
+	}
+
+	if i, ok := m.current(); ok {
+		lines = append(lines, "")
+		for _, line := range strings.Split(strings.ReplaceAll(m.releases[i].Body, "\r\n", "\n"), "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+	for len(lines) < rows {
+		lines = append(lines, "")
+	}
+	return lines[:rows]
+}
+
+// cursor returns the prefix of a line: an arrow for the line under the
+// cursor of the focused pane, a dot for that of the other
+func cursor(selected, focused bool) string {
+	switch {
+	case selected && focused:
+		return "> "
+	case selected:
+		return "· "
+	}
+	return "  "
+}
+
+// scroll returns the first of n items to show in rows so that the item at
+// cursor is visible
+func scroll(cursor, n, rows int) int {
+	if rows <= 0 || n <= rows {
+		return 0
+	}
+	return min(max(cursor-rows+1, 0), n-rows)
+}
+
+func clamp(i, n int) int {
+	return min(max(i, 0), max(n-1, 0))
+}
+
+// fit pads or truncates s to width columns, counting one per rune and
+// replacing tabs and control characters so they cannot move the cursor
+func fit(s string, width int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+	if n := utf8.RuneCountInString(s); n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	runes := []rune(s)
+	if width < 1 {
+		return ""
+	}
+	return string(runes[:width-1]) + "…"
+}
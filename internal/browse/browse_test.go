@@ -0,0 +1,160 @@
+package browse
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func testReleases() []github.Release {
+	return []github.Release{
+		{TagName: "v2.0.0-rc.1", Prerelease: true, Assets: []github.Asset{{ID: 5, Name: "app-rc.tar.gz"}}},
+		{TagName: "v1.1.0", Body: "Fixes\r\n- a bug", Assets: []github.Asset{
+			{ID: 3, Name: "app_linux.tar.gz", Size: 2048},
+			{ID: 4, Name: "app_darwin.tar.gz", Size: 1024},
+		}},
+		{TagName: "v1.0.0", Assets: []github.Asset{{ID: 1, Name: "app_linux.tar.gz", BrowserDownloadURL: "https://github.com/owner/app/releases/download/v1.0.0/app_linux.tar.gz"}}},
+	}
+}
+
+func update(m *Model, keys ...Key) bool {
+	for _, key := range keys {
+		if m.Update(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestModel_MarkAndDownload(t *testing.T) {
+	m := New("owner/app", testReleases())
+	if !update(m, KeyRight, KeySpace, KeyDown, KeySpace, KeyUp, KeySpace, KeyEnter) {
+		t.Fatal("Expected enter to finish the browser")
+	}
+	selection := m.Selection()
+	if selection == nil || selection.Tag != "v1.1.0" || !slices.Equal(selection.Assets, []string{"app_darwin.tar.gz"}) {
+		t.Errorf("Expected app_darwin.tar.gz of v1.1.0, got %+v", selection)
+	}
+}
+
+func TestModel_DownloadUnderCursor(t *testing.T) {
+	m := New("owner/app", testReleases())
+	if update(m, KeyEnter) {
+		t.Error("Expected enter on the releases without marks to ask for a choice")
+	}
+	if !update(m, KeyDown, KeyTab, KeyDown, KeyEnter) {
+		t.Fatal("Expected enter to finish the browser")
+	}
+	if selection := m.Selection(); selection.Tag != "v1.0.0" || !slices.Equal(selection.Assets, []string{"app_linux.tar.gz"}) {
+		t.Errorf("Expected app_linux.tar.gz of v1.0.0, got %+v", selection)
+	}
+}
+
+func TestModel_MarkAll(t *testing.T) {
+	m := New("owner/app", testReleases())
+	if !update(m, "a", "d") {
+		t.Fatal("Expected d to finish the browser")
+	}
+	if selection := m.Selection(); len(selection.Assets) != 2 {
+		t.Errorf("Expected both assets of v1.1.0, got %+v", selection)
+	}
+}
+
+func TestModel_Prereleases(t *testing.T) {
+	m := New("owner/app", testReleases())
+	update(m, KeyDown)
+	update(m, "p")
+	// The selected release stays selected when prereleases are shown
+	if !update(m, "a", KeyEnter) || m.Selection().Tag != "v1.0.0" {
+		t.Errorf("Expected v1.0.0 to stay selected, got %+v", m.Selection())
+	}
+
+	m = New("owner/app", testReleases())
+	update(m, "p", KeyUp)
+	if !update(m, "a", KeyEnter) || m.Selection().Tag != "v2.0.0-rc.1" {
+		t.Errorf("Expected the prerelease above v1.1.0, got %+v", m.Selection())
+	}
+}
+
+func TestModel_MarksAreDroppedOnReleaseChange(t *testing.T) {
+	m := New("owner/app", testReleases())
+	update(m, "a", KeyDown, KeyUp)
+	if update(m, KeyEnter) {
+		t.Errorf("Expected the marks of v1.1.0 to be dropped, got %+v", m.Selection())
+	}
+}
+
+func TestModel_CopyURL(t *testing.T) {
+	m := New("owner/app", testReleases())
+	var copied string
+	m.Copy = func(text string) (string, error) {
+		copied = text
+		return "pbcopy", nil
+	}
+	update(m, KeyDown, "c")
+	if copied != "" || !strings.Contains(strings.Join(m.View(120, 6), "\n"), "Move to an asset") {
+		t.Errorf("Expected copying from the releases to ask for an asset, copied %q", copied)
+	}
+	update(m, KeyRight, "c")
+	if copied != "https://github.com/owner/app/releases/download/v1.0.0/app_linux.tar.gz" {
+		t.Errorf("Expected the URL of app_linux.tar.gz, got %q", copied)
+	}
+	if view := strings.Join(m.View(120, 6), "\n"); !strings.Contains(view, "Copied the URL of app_linux.tar.gz with pbcopy") {
+		t.Errorf("Expected the copy to be reported, got:\n%s", view)
+	}
+	if m.Selection() != nil {
+		t.Error("Expected copying not to end the browser")
+	}
+}
+
+func TestModel_Quit(t *testing.T) {
+	for _, key := range []Key{"q", KeyEscape, KeyCtrlC} {
+		m := New("owner/app", testReleases())
+		if !m.Update(key) || m.Selection() != nil {
+			t.Errorf("Expected %s to quit without a selection", key)
+		}
+	}
+}
+
+func TestModel_View(t *testing.T) {
+	m := New("owner/app", testReleases())
+	update(m, KeyRight, KeySpace)
+	lines := m.View(80, 10)
+	if len(lines) != 10 {
+		t.Fatalf("Expected 10 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if n := utf8.RuneCountInString(line); n != 80 {
+			t.Errorf("Expected line %d to be 80 columns, got %d: %q", i, n, line)
+		}
+	}
+	view := strings.Join(lines, "\n")
+	for _, expected := range []string{"owner/app: 2 releases, prereleases hidden", "· v1.1.0", "> [x] app_linux.tar.gz", "[ ] app_darwin.tar.gz", "  Fixes", "- a bug"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in the view:\n%s", expected, view)
+		}
+	}
+	if strings.Contains(view, "v2.0.0-rc.1") {
+		t.Error("Expected the prerelease to be hidden")
+	}
+}
+
+func TestFit(t *testing.T) {
+	testCases := []struct {
+		s, expected string
+		width       int
+	}{
+		{"abc", "abc  ", 5},
+		{"abcdef", "abc…", 4},
+		{"a\tb\x1b", "a b ", 4},
+		{"äöü", "äöü", 3},
+	}
+	for _, tc := range testCases {
+		if got := fit(tc.s, tc.width); got != tc.expected {
+			t.Errorf("fit(%q, %d): expected %q, got %q", tc.s, tc.width, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package browse
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Escape sequences switching to the alternate screen with the cursor hidden
+// and back, and moving home and clearing below
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	home        = "\x1b[H"
+	clearBelow  = "\x1b[J"
+)
+
+// Run shows m full screen on the terminal of in and out until the user
+// chooses assets or quits, and returns the selection, nil when quit. The
+// terminal is restored before Run returns.
+func Run(in, out *os.File, m *Model) (*Selection, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer func() {
+		if err := term.Restore(int(in.Fd()), state); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restore the terminal: %v\n", err)
+		}
+	}()
+
+	if _, err := io.WriteString(out, enterScreen); err != nil {
+		return nil, err
+	}
+	err = loop(in, out, m, func() (int, int) {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			return 80, 24
+		}
+		return width, height
+	})
+	if _, leaveErr := io.WriteString(out, leaveScreen); leaveErr != nil && err == nil {
+		err = leaveErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.Selection(), nil
+}
+
+// loop draws m, with the terminal size size reports before each frame so
+// the browser follows resizes, and feeds it the keys read from in until it
+// is done
+func loop(in io.Reader, out io.Writer, m *Model, size func() (int, int)) error {
+	buf := make([]byte, 64)
+	for {
+		width, height := size()
+		frame := home + strings.Join(m.View(width, height), "\r\n") + clearBelow
+		if _, err := io.WriteString(out, frame); err != nil {
+			return err
+		}
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read from the terminal: %w", err)
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			if m.Update(key) {
+				return nil
+			}
+		}
+	}
+}
+
+// parseKeys splits the bytes of one read from a raw terminal into keys.
+// Arrow keys arrive as escape sequences in a single read, so an escape
+// followed by nothing is the escape key; other sequences are dropped.
+func parseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b && len(b) >= 3 && (b[1] == '[' || b[1] == 'O'):
+			if key, ok := map[byte]Key{'A': KeyUp, 'B': KeyDown, 'C': KeyRight, 'D': KeyLeft}[b[2]]; ok {
+				keys = append(keys, key)
+			}
+			// Skip the parameters of longer sequences up to their final byte
+			i := 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			b = b[min(i+1, len(b)):]
+			continue
+		case b[0] == 0x1b:
+			keys = append(keys, KeyEscape)
+		case b[0] == 0x03:
+			keys = append(keys, KeyCtrlC)
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, KeyEnter)
+		case b[0] == '\t':
+			keys = append(keys, KeyTab)
+		case b[0] == ' ':
+			keys = append(keys, KeySpace)
+		case b[0] > ' ' && b[0] < 0x7f:
+			keys = append(keys, Key(b[:1]))
+		}
+		b = b[1:]
+	}
+	return keys
+}
@@ -0,0 +1,47 @@
+package browse
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []Key
+	}{
+		{"\x1b[A\x1b[B", []Key{KeyUp, KeyDown}},
+		{"\x1bOC\x1b[D", []Key{KeyRight, KeyLeft}},
+		{"\x1b", []Key{KeyEscape}},
+		{"\x1b[1;5A", []Key{}},
+		{"\x1b[5~j", []Key{"j"}},
+		{" \t\r\x03q", []Key{KeySpace, KeyTab, KeyEnter, KeyCtrlC, "q"}},
+	}
+	for _, tc := range testCases {
+		keys := parseKeys([]byte(tc.input))
+		if !slices.Equal(keys, tc.expected) && !(len(keys) == 0 && len(tc.expected) == 0) {
+			t.Errorf("parseKeys(%q): expected %v, got %v", tc.input, tc.expected, keys)
+		}
+	}
+}
+
+func TestLoop(t *testing.T) {
+	m := New("owner/app", testReleases())
+	var out bytes.Buffer
+	if err := loop(strings.NewReader("\x1b[C \r"), &out, m, func() (int, int) { return 60, 8 }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if selection := m.Selection(); selection == nil || selection.Assets[0] != "app_linux.tar.gz" {
+		t.Errorf("Expected app_linux.tar.gz to be chosen, got %+v", selection)
+	}
+	if !strings.HasPrefix(out.String(), home) || !strings.Contains(out.String(), "owner/app") {
+		t.Errorf("Expected the browser to be drawn, got %q", out.String())
+	}
+
+	// The end of the input before a choice is an error
+	if err := loop(strings.NewReader("j"), &out, New("owner/app", testReleases()), func() (int, int) { return 60, 8 }); err == nil {
+		t.Error("Expected an error when the terminal closes")
+	}
+}
@@ -0,0 +1,175 @@
+// Package cas is a content-addressable store of downloaded assets, keyed by
+// their SHA-256 digest, so that content can be reused across runs and moved
+// between machines.
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/23prime/gh-download/internal/confine"
+	ghconfig "github.com/cli/go-gh/v2/pkg/config"
+)
+
+// DirEnv overrides the directory of the store
+const DirEnv = "GH_DOWNLOAD_CACHE_DIR"
+
+// Dir returns the directory of the store: $GH_DOWNLOAD_CACHE_DIR, or
+// gh-download in the cache directory of gh
+func Dir() string {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(ghconfig.CacheDir(), "gh-download")
+}
+
+// ErrDigestMismatch is returned when content does not have the digest it is
+// stored under
+var ErrDigestMismatch = errors.New("content does not match its digest")
+
+// Store keeps objects below Root in sha256/<first two hex digits>/<digest>
+type Store struct {
+	Root string
+}
+
+// ValidDigest reports whether digest is a lowercase hex SHA-256 digest, the
+// only form used as a path
+func ValidDigest(digest string) bool {
+	if len(digest) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range digest {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Path returns the path of the object with the given digest
+func (s Store) Path(digest string) string {
+	return filepath.Join(s.Root, "sha256", digest[:2], digest)
+}
+
+// Has reports whether the object with the given digest is stored
+func (s Store) Has(digest string) bool {
+	if !ValidDigest(digest) {
+		return false
+	}
+	_, err := os.Stat(s.Path(digest))
+	return err == nil
+}
+
+// Open opens the object with the given digest for reading
+func (s Store) Open(digest string) (*os.File, error) {
+	if !ValidDigest(digest) {
+		return nil, fmt.Errorf("invalid digest %q", digest)
+	}
+	return os.Open(s.Path(digest))
+}
+
+// Add stores the content read from r under digest. Content with another
+// digest is rejected with ErrDigestMismatch and nothing is stored.
+func (s Store) Add(digest string, r io.Reader) (err error) {
+	if !ValidDigest(digest) {
+		return fmt.Errorf("invalid digest %q", digest)
+	}
+	root, err := confine.Open(s.Root)
+	if err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer func() {
+		if closeErr := root.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", s.Root, closeErr)
+		}
+	}()
+
+	// Concurrent runs may add the same object, so each writes its own
+	// temporary file and the last rename wins with identical content
+	path := s.Path(digest)
+	tmpPath := fmt.Sprintf("%s.%016x.tmp", path, rand.Uint64())
+	tmp, err := root.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to the cache: %w", digest, err)
+	}
+	defer func() {
+		if err != nil {
+			if removeErr := root.Remove(tmpPath); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", tmpPath, removeErr)
+			}
+		}
+	}()
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add %s to the cache: %w", digest, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != digest {
+		return fmt.Errorf("%w: expected sha256:%s, got sha256:%s", ErrDigestMismatch, digest, got)
+	}
+	return root.Rename(tmpPath, path)
+}
+
+// Put stores the file at path under digest
+func (s Store) Put(digest, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", path, closeErr)
+		}
+	}()
+	return s.Add(digest, file)
+}
+
+// CopyTo writes the object with the given digest to path below root,
+// replacing it only once the copy is complete, and returns its size
+func (s Store) CopyTo(digest string, root *confine.Root, path string) (int64, error) {
+	src, err := os.Open(s.Path(digest))
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := src.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", src.Name(), closeErr)
+		}
+	}()
+
+	return root.Copy(path, src, 0644)
+}
+
+// Objects returns the digests of the stored objects, sorted
+func (s Store) Objects() ([]string, error) {
+	var digests []string
+	err := filepath.WalkDir(filepath.Join(s.Root, "sha256"), func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && ValidDigest(d.Name()) {
+			digests = append(digests, d.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached objects: %w", err)
+	}
+	sort.Strings(digests)
+	return digests, nil
+}
@@ -0,0 +1,137 @@
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/confine"
+)
+
+func digestOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestStore(t *testing.T) {
+	store := Store{Root: t.TempDir()}
+	content := "asset content"
+	digest := digestOf(content)
+
+	if store.Has(digest) {
+		t.Fatal("Expected an empty store")
+	}
+	if err := store.Add(digest, strings.NewReader(content)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !store.Has(digest) {
+		t.Error("Expected the object to be stored")
+	}
+
+	file, err := store.Open(digest)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil || string(data) != content {
+		t.Errorf("Unexpected object %q: %v", data, err)
+	}
+	if err := file.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := store.Open("not-a-digest"); err == nil {
+		t.Error("Expected an error for an invalid digest")
+	}
+
+	root, err := confine.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := root.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	dst := filepath.Join(root.Dir(), "asset.bin")
+	written, err := store.CopyTo(digest, root, dst)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err = os.ReadFile(dst)
+	if err != nil || string(data) != content || written != int64(len(content)) {
+		t.Errorf("Unexpected copy %q (%d bytes): %v", data, written, err)
+	}
+	if _, err := store.CopyTo(digest, root, filepath.Join(root.Dir(), "..", "escaped.bin")); !errors.Is(err, confine.ErrOutside) {
+		t.Errorf("Expected ErrOutside for a copy out of the directory, got %v", err)
+	}
+
+	other := digestOf("other")
+	src := filepath.Join(t.TempDir(), "other.bin")
+	if err := os.WriteFile(src, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(other, src); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	objects, err := store.Objects()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []string{digest, other}
+	if expected[0] > expected[1] {
+		expected[0], expected[1] = expected[1], expected[0]
+	}
+	if strings.Join(objects, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected objects %v, got %v", expected, objects)
+	}
+}
+
+func TestStore_Add_Mismatch(t *testing.T) {
+	store := Store{Root: t.TempDir()}
+	digest := digestOf("expected")
+
+	if err := store.Add(digest, strings.NewReader("tampered")); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Expected ErrDigestMismatch, got %v", err)
+	}
+	if store.Has(digest) {
+		t.Error("Expected nothing to be stored")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(store.Path(digest))); len(entries) != 0 {
+		t.Errorf("Expected no leftovers, got %v", entries)
+	}
+
+	if err := store.Add("../../etc/passwd", strings.NewReader("")); err == nil {
+		t.Error("Expected error for an invalid digest, got nil")
+	}
+}
+
+func TestStore_Objects_Empty(t *testing.T) {
+	objects, err := Store{Root: filepath.Join(t.TempDir(), "missing")}.Objects()
+	if err != nil || len(objects) != 0 {
+		t.Errorf("Expected no objects, got %v, %v", objects, err)
+	}
+}
+
+func TestValidDigest(t *testing.T) {
+	if !ValidDigest(digestOf("x")) {
+		t.Error("Expected a SHA-256 digest to be valid")
+	}
+	for _, digest := range []string{"", "abc", strings.ToUpper(digestOf("x")), strings.Repeat("g", 64)} {
+		if ValidDigest(digest) {
+			t.Errorf("Expected %q to be invalid", digest)
+		}
+	}
+}
+
+func TestDir(t *testing.T) {
+	t.Setenv(DirEnv, "/var/cache/mirror")
+	if got := Dir(); got != "/var/cache/mirror" {
+		t.Errorf("Expected %q, got %q", "/var/cache/mirror", got)
+	}
+}
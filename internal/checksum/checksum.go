@@ -0,0 +1,351 @@
+package checksum
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/confine"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Algorithm is a digest algorithm for checksums
+type Algorithm string
+
+const (
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+	// BLAKE2b is BLAKE2b-512, as written by b2sum
+	BLAKE2b Algorithm = "blake2b"
+	// MD5 is only emitted for legacy consumers; it is too weak to verify
+	// anything
+	MD5 Algorithm = "md5"
+)
+
+// Parse returns the algorithm with the given name
+func Parse(name string) (Algorithm, error) {
+	switch a := Algorithm(strings.ToLower(name)); a {
+	case SHA256, SHA512, BLAKE2b, MD5:
+		return a, nil
+	}
+	return "", fmt.Errorf("invalid checksum algorithm '%s': must be sha256, sha512, blake2b or md5", name)
+}
+
+// New returns a hash computing the algorithm
+func (a Algorithm) New() (hash.Hash, error) {
+	switch a {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	case BLAKE2b:
+		return blake2b.New512(nil)
+	case MD5:
+		return md5.New(), nil
+	}
+	return nil, fmt.Errorf("unknown checksum algorithm '%s'", a)
+}
+
+// CanVerify reports whether digests of the algorithm are trusted to verify
+// content
+func (a Algorithm) CanVerify() bool {
+	return a != MD5
+}
+
+// Extension returns the extension of sidecar files holding digests of the
+// algorithm, such as ".sha256"
+func (a Algorithm) Extension() string {
+	if a == BLAKE2b {
+		return ".b2"
+	}
+	return "." + string(a)
+}
+
+// Sum returns the hex digest of the content read from r
+func (a Algorithm) Sum(r io.Reader) (string, error) {
+	h, err := a.New()
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// File returns the hex digest of the file at path
+func File(path string, a Algorithm) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+
+	digest, err := a.Sum(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return digest, nil
+}
+
+// Entry is the expected digest of a file, as found in a checksum file
+type Entry struct {
+	Algorithm Algorithm
+	Digest    string
+	// Source is the name of the checksum file listing the entry
+	Source string
+}
+
+// ParseDigest parses a digest given as "algorithm:hex", such as
+// "sha256:9f86d0...". MD5 digests are refused, as they cannot verify.
+func ParseDigest(s string) (Entry, error) {
+	name, digest, ok := strings.Cut(s, ":")
+	if !ok {
+		return Entry{}, fmt.Errorf("invalid digest '%s': must be <algorithm>:<hex>, e.g. sha256:<hex>", s)
+	}
+	a, err := Parse(name)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !a.CanVerify() {
+		return Entry{}, fmt.Errorf("invalid digest '%s': %s is too weak to verify", s, a)
+	}
+	digest = strings.ToLower(digest)
+	if !isHex(digest) || len(digest) != hexSize(a) {
+		return Entry{}, fmt.Errorf("invalid digest '%s': expected %d hex characters for %s", s, hexSize(a), a)
+	}
+	return Entry{Algorithm: a, Digest: digest}, nil
+}
+
+// sumsNames are the conventional names of combined checksum files, by
+// algorithm
+var sumsNames = map[string]Algorithm{
+	"sha256sums": SHA256,
+	"sha512sums": SHA512,
+	"b2sums":     BLAKE2b,
+	"md5sums":    MD5,
+}
+
+// extensions are the extensions of checksum files, by algorithm
+var extensions = map[string]Algorithm{
+	".sha256":    SHA256,
+	".sha256sum": SHA256,
+	".sha512":    SHA512,
+	".sha512sum": SHA512,
+	".b2":        BLAKE2b,
+	".blake2b":   BLAKE2b,
+	".md5":       MD5,
+}
+
+// IsChecksumFile reports whether a file name looks like a checksum file:
+// SHA256SUMS and similar, a per-file sidecar such as tool.zip.sha256, or a
+// name containing "checksums"
+func IsChecksumFile(name string) bool {
+	if _, ok := conventionalAlgorithm(name); ok {
+		return true
+	}
+	return strings.Contains(strings.ToLower(name), "checksums")
+}
+
+// AlgorithmForName returns the algorithm a checksum file name announces,
+// by convention or by mentioning it, as in "checksums-sha512.txt"
+func AlgorithmForName(name string) (Algorithm, bool) {
+	if a, ok := conventionalAlgorithm(name); ok {
+		return a, true
+	}
+	lower := strings.ToLower(name)
+	for _, a := range []Algorithm{SHA512, SHA256, BLAKE2b, MD5} {
+		if strings.Contains(lower, string(a)) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// conventionalAlgorithm returns the algorithm of a file named like
+// SHA256SUMS or with a checksum extension
+func conventionalAlgorithm(name string) (Algorithm, bool) {
+	lower := strings.ToLower(name)
+	base := strings.TrimSuffix(lower, ".txt")
+	for prefix, a := range sumsNames {
+		if base == prefix || strings.HasSuffix(base, "_"+prefix) || strings.HasSuffix(base, "-"+prefix) || strings.HasSuffix(base, "."+prefix) {
+			return a, true
+		}
+	}
+	for ext, a := range extensions {
+		if strings.HasSuffix(lower, ext) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// bsdTags are the algorithm names of BSD-style "SHA256 (file) = digest"
+// lines
+var bsdTags = map[string]Algorithm{
+	"SHA256":  SHA256,
+	"SHA512":  SHA512,
+	"BLAKE2b": BLAKE2b,
+	"BLAKE2B": BLAKE2b,
+	"MD5":     MD5,
+}
+
+// ParseFile parses the checksum file name, detecting its format, and returns
+// its entries by file name. It understands the lines of sha256sum and
+// similar tools, "<digest>  <file>" with "*" marking binary mode, BSD-style
+// "SHA256 (<file>) = <digest>" lines, and sidecar files holding only the
+// digest of the file named like them without the checksum extension.
+//
+// The algorithm of an entry is taken from a BSD tag, then from the name of
+// the checksum file, then from fallback if its digests have the right
+// length, and finally from the length alone. Listed paths are reduced to
+// their file names.
+func ParseFile(name string, r io.Reader, fallback Algorithm) (map[string]Entry, error) {
+	named, hasName := AlgorithmForName(name)
+
+	entries := make(map[string]Entry)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		file, entry, ok := parseBSDLine(text)
+		if !ok {
+			file, entry, ok = parseGNULine(text)
+		}
+		if !ok && isHex(text) {
+			file, entry, ok = sidecarTarget(name), Entry{Digest: strings.ToLower(text)}, sidecarTarget(name) != ""
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum line %d in %s: %q", line, name, text)
+		}
+
+		if entry.Algorithm == "" {
+			switch {
+			case hasName:
+				entry.Algorithm = named
+			case hexSize(fallback) == len(entry.Digest):
+				entry.Algorithm = fallback
+			default:
+				a, ok := algorithmForLength(len(entry.Digest))
+				if !ok {
+					return nil, fmt.Errorf("unknown digest length on line %d in %s", line, name)
+				}
+				entry.Algorithm = a
+			}
+		}
+		if hexSize(entry.Algorithm) != len(entry.Digest) {
+			return nil, fmt.Errorf("%s digest on line %d in %s has the wrong length", entry.Algorithm, line, name)
+		}
+
+		entry.Source = name
+		entries[path.Base(file)] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return entries, nil
+}
+
+func parseBSDLine(text string) (string, Entry, bool) {
+	tag, rest, ok := strings.Cut(text, " (")
+	if !ok {
+		return "", Entry{}, false
+	}
+	a, ok := bsdTags[tag]
+	if !ok {
+		return "", Entry{}, false
+	}
+	i := strings.LastIndex(rest, ") = ")
+	if i < 0 || !isHex(rest[i+4:]) {
+		return "", Entry{}, false
+	}
+	return rest[:i], Entry{Algorithm: a, Digest: strings.ToLower(rest[i+4:])}, true
+}
+
+func parseGNULine(text string) (string, Entry, bool) {
+	digest, file, ok := strings.Cut(text, " ")
+	file = strings.TrimPrefix(strings.TrimLeft(file, " "), "*")
+	if !ok || !isHex(digest) || file == "" {
+		return "", Entry{}, false
+	}
+	return file, Entry{Digest: strings.ToLower(digest)}, true
+}
+
+// sidecarTarget returns the file a sidecar checksum file belongs to, or ""
+func sidecarTarget(name string) string {
+	lower := strings.ToLower(name)
+	for ext := range extensions {
+		if strings.HasSuffix(lower, ext) && len(name) > len(ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return ""
+}
+
+func isHex(s string) bool {
+	if s == "" || len(s)%2 != 0 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// hexSize returns the length of the hex digests of an algorithm
+func hexSize(a Algorithm) int {
+	switch a {
+	case SHA256:
+		return 64
+	case SHA512, BLAKE2b:
+		return 128
+	case MD5:
+		return 32
+	}
+	return 0
+}
+
+func algorithmForLength(length int) (Algorithm, bool) {
+	switch length {
+	case 64:
+		return SHA256, true
+	case 128:
+		return SHA512, true
+	case 32:
+		return MD5, true
+	}
+	return "", false
+}
+
+// WriteSidecar writes the digest of the file at path to a sidecar file next
+// to it, named with the extension of the algorithm, in the format the
+// sha256sum family checks with -c from the same directory. The sidecar is
+// written through root, so a symlink in its place cannot redirect it. It
+// returns the path of the sidecar.
+func WriteSidecar(root *confine.Root, path string, a Algorithm) (string, error) {
+	digest, err := File(path, a)
+	if err != nil {
+		return "", err
+	}
+
+	sidecar := path + a.Extension()
+	line := fmt.Sprintf("%s  %s\n", digest, filepath.Base(path))
+	if err := root.WriteFile(sidecar, []byte(line), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", sidecar, err)
+	}
+	return sidecar, nil
+}
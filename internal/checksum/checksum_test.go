@@ -0,0 +1,254 @@
+package checksum
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/confine"
+)
+
+func TestAlgorithm_Sum(t *testing.T) {
+	testCases := []struct {
+		algorithm Algorithm
+		expected  string
+	}{
+		{SHA256, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{SHA512, "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"},
+		{BLAKE2b, "e4cfa39a3d37be31c59609e807970799caa68a19bfaa15135f165085e01d41a65ba1e1b146aeb6bd0092b49eac214c103ccfa3a365954bbbe52f74a2b3620c94"},
+		{MD5, "5d41402abc4b2a76b9719d911017c592"},
+	}
+
+	for _, tc := range testCases {
+		got, err := tc.algorithm.Sum(strings.NewReader("hello"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.algorithm, tc.expected, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if a, err := Parse("SHA512"); err != nil || a != SHA512 {
+		t.Errorf("Expected sha512, got %q %v", a, err)
+	}
+	if _, err := Parse("crc32"); err == nil {
+		t.Error("Expected error for an unknown algorithm, got nil")
+	}
+	if MD5.CanVerify() || !BLAKE2b.CanVerify() {
+		t.Error("Expected only md5 to be unable to verify")
+	}
+}
+
+func TestParseDigest(t *testing.T) {
+	entry, err := ParseDigest("SHA256:" + strings.Repeat("AB", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Algorithm != SHA256 || entry.Digest != strings.Repeat("ab", 32) {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+
+	for _, invalid := range []string{
+		strings.Repeat("ab", 32),
+		"sha256:abc",
+		"sha512:" + strings.Repeat("ab", 32),
+		"md5:" + strings.Repeat("ab", 16),
+		"crc32:abcd",
+		"sha256:" + strings.Repeat("zz", 32),
+	} {
+		if _, err := ParseDigest(invalid); err == nil {
+			t.Errorf("%s: expected error, got nil", invalid)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := File(path, MD5)
+	if err != nil || digest != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected digest %s %v", digest, err)
+	}
+	if _, err := File(filepath.Join(t.TempDir(), "missing"), MD5); err == nil {
+		t.Error("Expected error for a missing file, got nil")
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	sha256Digest := strings.Repeat("ab", 32)
+	sha512Digest := strings.Repeat("cd", 64)
+
+	testCases := []struct {
+		name     string
+		content  string
+		fallback Algorithm
+		expected map[string]Entry
+	}{
+		{
+			"SHA256SUMS",
+			"# release checksums\n" + strings.ToUpper(sha256Digest) + "  dist/tool_linux.tar.gz\n\n" + sha256Digest + " *tool windows.zip\n",
+			SHA256,
+			map[string]Entry{
+				"tool_linux.tar.gz": {SHA256, sha256Digest, "SHA256SUMS"},
+				"tool windows.zip":  {SHA256, sha256Digest, "SHA256SUMS"},
+			},
+		},
+		{
+			"checksums.txt",
+			"SHA512 (tool.zip) = " + sha512Digest + "\n",
+			SHA256,
+			map[string]Entry{"tool.zip": {SHA512, sha512Digest, "checksums.txt"}},
+		},
+		{
+			"tool.zip.sha256",
+			sha256Digest + "\n",
+			SHA512,
+			map[string]Entry{"tool.zip": {SHA256, sha256Digest, "tool.zip.sha256"}},
+		},
+		{
+			"tool.zip.sha512",
+			sha512Digest + "  tool.zip\n",
+			SHA256,
+			map[string]Entry{"tool.zip": {SHA512, sha512Digest, "tool.zip.sha512"}},
+		},
+		{
+			"checksums_linux.txt",
+			sha512Digest + "  tool.zip\n",
+			BLAKE2b,
+			map[string]Entry{"tool.zip": {BLAKE2b, sha512Digest, "checksums_linux.txt"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := ParseFile(tc.name, strings.NewReader(tc.content), tc.fallback)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(entries) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, entries)
+			}
+			for file, entry := range tc.expected {
+				if entries[file] != entry {
+					t.Errorf("%s: expected %+v, got %+v", file, entry, entries[file])
+				}
+			}
+		})
+	}
+}
+
+func TestParseFile_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"SHA256SUMS", "not-hex  tool.zip\n"},
+		{"SHA256SUMS", strings.Repeat("ab", 64) + "  tool.zip\n"},
+		{"checksums.txt", strings.Repeat("ab", 32) + "\n"},
+	}
+
+	for _, tc := range testCases {
+		if _, err := ParseFile(tc.name, strings.NewReader(tc.content), SHA256); err == nil {
+			t.Errorf("Expected error for %s with %q, got nil", tc.name, tc.content)
+		}
+	}
+}
+
+func TestIsChecksumFile(t *testing.T) {
+	for _, name := range []string{"SHA256SUMS", "sha512sums.txt", "tool_1.0_checksums.txt", "tool.zip.sha256", "B2SUMS", "tool.tar.gz.md5"} {
+		if !IsChecksumFile(name) {
+			t.Errorf("Expected %s to be a checksum file", name)
+		}
+	}
+	for _, name := range []string{"tool.zip", "tool_linux_amd64.tar.gz", "sha256-tool.zip", "README.md"} {
+		if IsChecksumFile(name) {
+			t.Errorf("Expected %s not to be a checksum file", name)
+		}
+	}
+}
+
+func TestWriteSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tool v1.zip")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := confine.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := root.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	sidecar, err := WriteSidecar(root, path, SHA256)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sidecar != path+".sha256" {
+		t.Errorf("Expected %s, got %s", path+".sha256", sidecar)
+	}
+
+	data, err := os.ReadFile(sidecar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  tool v1.zip\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+
+	// The sidecar parses back to the digest of the file
+	entries, err := ParseFile(filepath.Base(sidecar), strings.NewReader(string(data)), MD5)
+	if err != nil || entries["tool v1.zip"].Algorithm != SHA256 {
+		t.Errorf("Expected the sidecar to parse back, got %v %v", entries, err)
+	}
+
+	if sidecar, err := WriteSidecar(root, path, BLAKE2b); err != nil || filepath.Ext(sidecar) != ".b2" {
+		t.Errorf("Expected a .b2 sidecar, got %s %v", sidecar, err)
+	}
+}
+
+func TestWriteSidecar_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.zip")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A sidecar planted as a symlink to a file outside of the directory
+	outside := filepath.Join(t.TempDir(), "profile")
+	if err := os.WriteFile(outside, []byte("kept\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, path+".sha256"); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	root, err := confine.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := root.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if _, err := WriteSidecar(root, path, SHA256); !errors.Is(err, confine.ErrOutside) {
+		t.Errorf("Expected ErrOutside, got %v", err)
+	}
+	if data, err := os.ReadFile(outside); err != nil || string(data) != "kept\n" {
+		t.Errorf("Expected the file outside to be left alone, got %q %v", data, err)
+	}
+}
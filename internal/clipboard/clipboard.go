@@ -0,0 +1,84 @@
+// Package clipboard copies text to the system clipboard with the tool the
+// platform has: pbcopy on macOS, wl-copy, xclip or xsel under Wayland and
+// X11, and clip.exe on Windows and in WSL. Without one the text is sent to
+// the terminal as an OSC 52 sequence, which most terminal emulators put on
+// the clipboard, also over SSH.
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when there is neither a clipboard tool nor a
+// terminal to send the text to
+var ErrUnavailable = errors.New("no clipboard tool found; install wl-copy, xclip or xsel")
+
+// OSC52 names the terminal escape sequence as the method of Copy
+const OSC52 = "the terminal (OSC 52)"
+
+// lookPath finds clipboard tools; replaced in tests
+var lookPath = exec.LookPath
+
+// command is a clipboard tool reading the text from stdin
+type command struct {
+	program string
+	args    []string
+}
+
+// Copy puts text on the clipboard and returns how: the program used, or
+// OSC52 when none was found and terminal, which may be nil, took the
+// escape sequence
+func Copy(text string, terminal io.Writer) (string, error) {
+	for _, candidate := range commands(runtime.GOOS, os.Getenv) {
+		if _, err := lookPath(candidate.program); err != nil {
+			continue
+		}
+		// xclip and wl-copy stay in the background to serve the
+		// clipboard, so their output is not waited for
+		cmd := exec.Command(candidate.program, candidate.args...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s failed: %w", candidate.program, err)
+		}
+		return candidate.program, nil
+	}
+
+	if terminal == nil {
+		return "", ErrUnavailable
+	}
+	if _, err := fmt.Fprintf(terminal, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text))); err != nil {
+		return "", err
+	}
+	return OSC52, nil
+}
+
+// commands returns the clipboard tools to try on goos, in order. Wayland
+// and X11 tools are only tried with a display to talk to.
+func commands(goos string, getenv func(string) string) []command {
+	switch goos {
+	case "darwin":
+		return []command{{"pbcopy", nil}}
+	case "windows":
+		return []command{{"clip.exe", nil}}
+	}
+
+	var candidates []command
+	if getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, command{"wl-copy", nil})
+	}
+	if getenv("DISPLAY") != "" {
+		candidates = append(candidates, command{"xclip", []string{"-selection", "clipboard"}}, command{"xsel", []string{"--clipboard", "--input"}})
+	}
+	// WSL reaches the Windows clipboard
+	if getenv("WSL_DISTRO_NAME") != "" {
+		candidates = append(candidates, command{"clip.exe", nil})
+	}
+	return candidates
+}
@@ -0,0 +1,60 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+	testCases := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		expected []string
+	}{
+		{"macos", "darwin", nil, []string{"pbcopy"}},
+		{"windows", "windows", nil, []string{"clip.exe"}},
+		{"wayland", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"wl-copy", "xclip", "xsel"}},
+		{"x11", "freebsd", map[string]string{"DISPLAY": ":0"}, []string{"xclip", "xsel"}},
+		{"wsl", "linux", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, []string{"clip.exe"}},
+		{"headless", "linux", nil, nil},
+	}
+	for _, tc := range testCases {
+		var programs []string
+		for _, command := range commands(tc.goos, env(tc.env)) {
+			programs = append(programs, command.program)
+		}
+		if len(programs) != len(tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, programs)
+			continue
+		}
+		for i := range programs {
+			if programs[i] != tc.expected[i] {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, programs)
+			}
+		}
+	}
+}
+
+func TestCopy_OSC52(t *testing.T) {
+	previous := lookPath
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	t.Cleanup(func() { lookPath = previous })
+
+	var terminal bytes.Buffer
+	method, err := Copy("https://example.com/a", &terminal)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if method != OSC52 || terminal.String() != "\x1b]52;c;aHR0cHM6Ly9leGFtcGxlLmNvbS9h\a" {
+		t.Errorf("Expected an OSC 52 sequence, got %s: %q", method, terminal.String())
+	}
+
+	if _, err := Copy("text", nil); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable without a terminal, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Package color colors terminal output as gh does: only on terminals, unless
+// NO_COLOR or CLICOLOR=0 disable it or CLICOLOR_FORCE forces it, and as
+// --color chooses.
+package color
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// Modes of --color
+const (
+	ModeAuto   = "auto"
+	ModeAlways = "always"
+	ModeNever  = "never"
+)
+
+// Modes lists the values --color accepts
+var Modes = []string{ModeAuto, ModeAlways, ModeNever}
+
+// Scheme colors text written to one stream, or returns it unchanged when
+// color is disabled for that stream
+type Scheme struct {
+	enabled bool
+}
+
+// Stdout and Stderr color the text written to standard output and standard
+// error. Both are disabled until Setup enables them.
+var (
+	Stdout = &Scheme{}
+	Stderr = &Scheme{}
+)
+
+// Setup enables color on stdout and stderr for mode: always, never, or auto
+// (or empty), which colors each stream that is a terminal as go-gh's term
+// package decides from GH_FORCE_TTY, NO_COLOR, CLICOLOR and CLICOLOR_FORCE
+func Setup(mode string) error {
+	switch mode {
+	case ModeAuto, "":
+		Stdout.enabled = term.FromEnv().IsColorEnabled()
+		Stderr.enabled = term.IsColorForced() || (!term.IsColorDisabled() && term.IsTerminal(os.Stderr))
+	case ModeAlways:
+		Stdout.enabled = true
+		Stderr.enabled = true
+	case ModeNever:
+		Stdout.enabled = false
+		Stderr.enabled = false
+	default:
+		return fmt.Errorf("invalid --color %q: must be one of %s", mode, strings.Join(Modes, ", "))
+	}
+	return nil
+}
+
+// Enabled reports whether the scheme colors text
+func (s *Scheme) Enabled() bool {
+	return s.enabled
+}
+
+func (s *Scheme) wrap(code, text string) string {
+	if !s.enabled || text == "" {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+// Bold is used for release names
+func (s *Scheme) Bold(text string) string {
+	return s.wrap("1", text)
+}
+
+// Red is used for errors and draft releases
+func (s *Scheme) Red(text string) string {
+	return s.wrap("31", text)
+}
+
+// Yellow is used for warnings and prereleases
+func (s *Scheme) Yellow(text string) string {
+	return s.wrap("33", text)
+}
+
+// Green is used for matches and successes
+func (s *Scheme) Green(text string) string {
+	return s.wrap("32", text)
+}
+
+// Cyan is used for patterns
+func (s *Scheme) Cyan(text string) string {
+	return s.wrap("36", text)
+}
+
+// Gray is used for table headers and secondary details
+func (s *Scheme) Gray(text string) string {
+	return s.wrap("90", text)
+}
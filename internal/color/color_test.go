@@ -0,0 +1,86 @@
+package color
+
+import "testing"
+
+func TestSetup(t *testing.T) {
+	t.Cleanup(func() {
+		Stdout.enabled = false
+		Stderr.enabled = false
+	})
+
+	testCases := []struct {
+		mode    string
+		noColor string
+		force   string
+		enabled bool
+	}{
+		{ModeAlways, "", "", true},
+		{ModeAlways, "1", "", true},
+		{ModeNever, "", "1", false},
+		// Test output is not a terminal
+		{ModeAuto, "", "", false},
+		{"", "", "", false},
+		{ModeAuto, "", "1", true},
+		{ModeAuto, "1", "1", true},
+	}
+	for _, tc := range testCases {
+		t.Setenv("GH_FORCE_TTY", "")
+		t.Setenv("NO_COLOR", tc.noColor)
+		t.Setenv("CLICOLOR", "")
+		t.Setenv("CLICOLOR_FORCE", tc.force)
+		if err := Setup(tc.mode); err != nil {
+			t.Fatalf("Expected no error for %q, got %v", tc.mode, err)
+		}
+		if Stdout.Enabled() != tc.enabled || Stderr.Enabled() != tc.enabled {
+			t.Errorf("Expected color enabled %v for %q with NO_COLOR=%q CLICOLOR_FORCE=%q, got stdout %v stderr %v",
+				tc.enabled, tc.mode, tc.noColor, tc.force, Stdout.Enabled(), Stderr.Enabled())
+		}
+	}
+}
+
+func TestSetup_ForcedTTY(t *testing.T) {
+	t.Cleanup(func() {
+		Stdout.enabled = false
+		Stderr.enabled = false
+	})
+	t.Setenv("GH_FORCE_TTY", "1")
+	t.Setenv("CLICOLOR_FORCE", "")
+	t.Setenv("CLICOLOR", "")
+
+	t.Setenv("NO_COLOR", "")
+	if err := Setup(ModeAuto); err != nil {
+		t.Fatal(err)
+	}
+	if !Stdout.Enabled() {
+		t.Error("Expected color on stdout with GH_FORCE_TTY")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if err := Setup(ModeAuto); err != nil {
+		t.Fatal(err)
+	}
+	if Stdout.Enabled() {
+		t.Error("Expected NO_COLOR to disable color")
+	}
+}
+
+func TestSetup_Invalid(t *testing.T) {
+	if err := Setup("sometimes"); err == nil {
+		t.Error("Expected an error for an invalid mode, got nil")
+	}
+}
+
+func TestScheme(t *testing.T) {
+	enabled := &Scheme{enabled: true}
+	if got := enabled.Red("Draft"); got != "\x1b[31mDraft\x1b[0m" {
+		t.Errorf("Expected red text, got %q", got)
+	}
+	if got := enabled.Bold(""); got != "" {
+		t.Errorf("Expected empty text to stay empty, got %q", got)
+	}
+
+	disabled := &Scheme{}
+	if got := disabled.Red("Draft"); got != "Draft" {
+		t.Errorf("Expected plain text when disabled, got %q", got)
+	}
+}
@@ -3,49 +3,425 @@ package config
 import (
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/retry"
+)
+
+// Subcommands selected by the first positional argument
+const (
+	CommandPeek         = "peek"
+	CommandCompare      = "compare"
+	CommandActionYAML   = "action-yaml"
+	CommandAttestMirror = "attest-mirror"
+	CommandVerify       = "verify"
+	CommandHistory      = "history"
+	CommandClean        = "clean"
+	CommandTap          = "tap"
+	CommandMatchTest    = "match-test"
+	CommandExport       = "export"
+	CommandDB           = "db"
+	CommandCache        = "cache"
+	CommandServe        = "serve"
+	CommandPlan         = "plan"
+	CommandSchema       = "schema"
+	CommandAdopt        = "adopt"
+	CommandFreeze       = "freeze"
+	CommandInstall      = "install"
+	CommandAuditReport  = "audit-report"
+	CommandBrowse       = "browse"
 )
 
+var commands = []string{CommandPeek, CommandCompare, CommandActionYAML, CommandAttestMirror, CommandVerify, CommandHistory, CommandClean, CommandTap, CommandMatchTest, CommandExport, CommandDB, CommandCache, CommandServe, CommandPlan, CommandSchema, CommandAdopt, CommandFreeze, CommandInstall, CommandAuditReport, CommandBrowse}
+
+// shorthands maps short flag names to their long names
+var shorthands = map[string]string{
+	"R": "repo",
+	"t": "tag",
+	"p": "pattern",
+	"d": "dir",
+	"l": "list",
+	"r": "releases",
+	"h": "help",
+	"q": "quiet",
+	"O": "output",
+}
+
+// Flag is a flag given explicitly on the command line
+type Flag struct {
+	// Name is the long name of the flag
+	Name  string
+	Value string
+	// Bool is set for flags that take no value
+	Bool bool
+}
+
 type Config struct {
-	Repository string
-	Tag        string
-	Pattern    string
-	Directory  string
-	Archive    string
-	List       bool
-	Releases   bool
-	Help       bool
+	Command              string
+	Repository           string
+	Tag                  string
+	Pattern              string
+	Directory            string
+	Archive              string
+	Order                string
+	Sort                 string
+	Bytes                int
+	Extract              bool
+	Include              string
+	Strip                int
+	Delta                bool
+	Submodules           bool
+	ResolveLFS           bool
+	RenameByType         bool
+	ContinueOnError      bool
+	Clobber              bool
+	SkipExisting         bool
+	PrintPaths           bool
+	Stdout               bool
+	Quiet                bool
+	NoPager              bool
+	GitHubOutput         bool
+	EnvFile              string
+	IdempotentJSON       bool
+	Stdin                bool
+	URLsOnly             bool
+	SignedURLs           bool
+	APIURLs              bool
+	EmitCommands         string
+	Downloader           string
+	DownloaderArgs       string
+	Retries              int
+	RetryOn              string
+	RetryDelay           time.Duration
+	MaxHostFailures      int
+	MaxDuration          time.Duration
+	AssetTimeout         time.Duration
+	Resume               string
+	OTelEndpoint         string
+	ChecksumAlgo         string
+	ChecksumFile         string
+	EmitSidecarChecksums bool
+	Key                  string
+	Paranoid             bool
+	Repair               bool
+	VerifyTagSignature   bool
+	TagSigningKey        string
+	RequireChecksPassed  bool
+	RequireDeployment    string
+	MinReactions         int
+	Author               string
+	JSON                 bool
+	// JSONFields are the fields --json selects, as in --json tagName,assets
+	JSONFields            []string
+	StaleAfter            time.Duration
+	WaitLock              time.Duration
+	NoLock                bool
+	Check                 bool
+	StaleOK               time.Duration
+	All                   bool
+	Output                string
+	OutputTemplate        string
+	MirrorToFork          string
+	Format                string
+	Template              string
+	UseCache              bool
+	Presign               bool
+	Listen                string
+	AccessFile            string
+	OverlapPolicy         string
+	Concurrency           int
+	Preflight             bool
+	Verify                bool
+	Checksum              string
+	WriteProvenance       bool
+	Strategy              string
+	NoVerifyDigest        bool
+	VerifySignature       bool
+	SignerKey             string
+	FallbackArchive       string
+	ChecksumsOnly         bool
+	Cosign                bool
+	CertificateIdentity   string
+	CertificateOIDCIssuer string
+	FromFile              string
+	VerifyProvenance      bool
+	MaxAssetSize          string
+	OnOversize            string
+	HeadCheck             bool
+	Verbose               bool
+	Debug                 bool
+	DebugHTTP             bool
+	Color                 string
+	LogFormat             string
+	Apply                 bool
+	// Deadline is when MaxDuration runs out, set when the run starts
+	Deadline time.Time
+	Commits  bool
+	Files    bool
+	List     bool
+	CopyURL  bool
+	Releases bool
+	Help     bool
+	Flags    []Flag
+	Args     []string
+}
+
+// IsSet reports whether the flag with the given long name was given on the
+// command line
+func (c Config) IsSet(name string) bool {
+	return slices.ContainsFunc(c.Flags, func(flag Flag) bool {
+		return flag.Name == name
+	})
 }
 
 func ParseArgs() Config {
+	config, err := Parse(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'gh download --help' for usage.")
+		os.Exit(2)
+	}
+	return config
+}
+
+// Parse parses command line arguments (without the program name).
+// Flags may appear before, between or after positional arguments.
+func Parse(args []string) (Config, error) {
 	var config Config
 
-	flag.StringVar(&config.Repository, "repo", "", "Repository in format owner/repo (required)")
-	flag.StringVar(&config.Repository, "R", "", "Repository in format owner/repo (shorthand)")
-	flag.StringVar(&config.Tag, "tag", "", "Release tag (defaults to latest)")
-	flag.StringVar(&config.Tag, "t", "", "Release tag (shorthand)")
-	flag.StringVar(&config.Pattern, "pattern", "*", "Glob pattern to match asset names")
-	flag.StringVar(&config.Pattern, "p", "*", "Glob pattern to match asset names (shorthand)")
-	flag.StringVar(&config.Directory, "dir", ".", "Directory to download files to")
-	flag.StringVar(&config.Directory, "d", ".", "Directory to download files to (shorthand)")
-	flag.StringVar(&config.Archive, "archive", "", "Download source archive (zip or tar.gz)")
-	flag.BoolVar(&config.List, "list", false, "List release assets without downloading")
-	flag.BoolVar(&config.List, "l", false, "List release assets without downloading (shorthand)")
-	flag.BoolVar(&config.Releases, "releases", false, "List all releases")
-	flag.BoolVar(&config.Releases, "r", false, "List all releases (shorthand)")
-	flag.BoolVar(&config.Help, "help", false, "Show help")
-	flag.BoolVar(&config.Help, "h", false, "Show help (shorthand)")
-
-	flag.Parse()
-
-	args := flag.Args()
-	if len(args) > 0 && config.Repository == "" {
-		config.Repository = args[0]
+	if len(args) > 0 && slices.Contains(commands, args[0]) {
+		config.Command = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("gh-download", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	args = joinJSONFields(args)
+
+	fs.StringVar(&config.Repository, "repo", "", "Repository in format owner/repo (required)")
+	fs.StringVar(&config.Repository, "R", "", "Repository in format owner/repo (shorthand)")
+	fs.StringVar(&config.Tag, "tag", "", "Release tag (defaults to latest)")
+	fs.StringVar(&config.Tag, "t", "", "Release tag (shorthand)")
+	fs.StringVar(&config.Pattern, "pattern", "*", "Glob pattern to match asset names")
+	fs.BoolVar(&config.ChecksumsOnly, "checksums-only", false, "Only download the checksum, signature and other small metadata assets")
+	fs.StringVar(&config.MaxAssetSize, "max-asset-size", "", "Refuse matching assets above this size, e.g. 500M or 2G")
+	fs.StringVar(&config.OnOversize, "on-oversize", "fail", "What to do with assets above --max-asset-size: fail or skip")
+	fs.StringVar(&config.Pattern, "p", "*", "Glob pattern to match asset names (shorthand)")
+	fs.StringVar(&config.Directory, "dir", ".", "Directory to download files to")
+	fs.StringVar(&config.Directory, "d", ".", "Directory to download files to (shorthand)")
+	fs.StringVar(&config.Archive, "archive", "", "Download source archive (zip or tar.gz)")
+	fs.StringVar(&config.FallbackArchive, "fallback-archive", "", "Download the source archive (zip or tar.gz) of releases without assets")
+	fs.StringVar(&config.Order, "order", "manifest", "Download order: size-asc, size-desc, name or manifest")
+	fs.StringVar(&config.Sort, "sort", "", "Order of listed and exported assets and releases: name, date or api")
+	fs.IntVar(&config.Bytes, "bytes", 256, "Number of leading bytes to fetch with peek")
+	fs.BoolVar(&config.Extract, "extract", false, "Extract archive assets instead of saving them")
+	fs.StringVar(&config.Include, "include", "", "Glob pattern to match files inside archives when extracting")
+	fs.StringVar(&config.Include, "pattern-in-archive", "", "Glob pattern to match files inside archives (alias of --include)")
+	fs.IntVar(&config.Strip, "strip-components", 0, "Strip leading path elements from extracted files")
+	fs.BoolVar(&config.Delta, "delta", false, "Update an extracted source archive with only the files changed since the last run")
+	fs.BoolVar(&config.Submodules, "with-submodules", false, "Place the archives of submodules at their pinned commits into the extracted source")
+	fs.BoolVar(&config.ResolveLFS, "resolve-lfs", false, "Replace Git LFS pointer files in extracted sources with their content")
+	fs.BoolVar(&config.RenameByType, "rename-by-type", false, "Rename downloaded assets whose extension contradicts their content")
+	fs.BoolVar(&config.ContinueOnError, "continue-on-error", false, "Keep downloading the remaining assets when one fails")
+	fs.BoolVar(&config.Clobber, "clobber", false, "Overwrite files that already exist")
+	fs.BoolVar(&config.SkipExisting, "skip-existing", false, "Skip assets whose file already exists and is identical")
+	fs.BoolVar(&config.PrintPaths, "print-paths", false, "Print only the absolute paths of downloaded files on stdout")
+	fs.BoolVar(&config.Stdout, "stdout", false, "Write the single matching asset to stdout; other output goes to stderr")
+	fs.BoolVar(&config.Quiet, "quiet", false, "Print nothing but the absolute paths of downloaded files")
+	fs.BoolVar(&config.Quiet, "q", false, "Print nothing but the absolute paths of downloaded files (shorthand)")
+	fs.BoolVar(&config.GitHubOutput, "github-output", false, "Write the results to $GITHUB_OUTPUT for later workflow steps")
+	fs.StringVar(&config.EnvFile, "env-file", "", "Append the results as key=value pairs to a file")
+	fs.BoolVar(&config.IdempotentJSON, "idempotent-json", false, "Print only a JSON object describing the files and whether anything changed")
+	fs.BoolVar(&config.Stdin, "stdin", false, "Read newline-separated repositories from stdin")
+	fs.BoolVar(&config.URLsOnly, "urls-only", false, "Print the download URLs of matching assets instead of downloading them")
+	fs.BoolVar(&config.URLsOnly, "urls", false, "Print the download URLs of matching assets (alias of --urls-only)")
+	fs.BoolVar(&config.APIURLs, "api-urls", false, "With --urls-only, print the REST API URLs of the assets instead of the browser URLs")
+	fs.BoolVar(&config.SignedURLs, "signed", false, "With --urls-only or --emit-commands, use pre-authorized URLs that work without credentials")
+	fs.BoolVar(&config.Presign, "presign", false, "Print the short-lived signed URLs of matching assets and when they expire")
+	fs.StringVar(&config.EmitCommands, "emit-commands", "", "Print download commands for aria2, curl or wget instead of downloading")
+	fs.StringVar(&config.Downloader, "downloader", "", "Program that transfers each asset, such as aria2c, curl or wget")
+	fs.StringVar(&config.DownloaderArgs, "downloader-args", "", "Argument template for --downloader with {url}, {path}, {dir} and {name}")
+	fs.IntVar(&config.Retries, "retries", 2, "Number of times to retry a failed transfer")
+	fs.StringVar(&config.RetryOn, "retry-on", retry.DefaultClasses, "Failure classes to retry: 5xx, rate-limit, network, checksum")
+	fs.DurationVar(&config.RetryDelay, "retry-delay", retry.DefaultBackoff, "Wait before the first retry, doubling for each following one")
+	fs.IntVar(&config.MaxHostFailures, "max-host-failures", 3, "With --stdin, skip a host after this many consecutive failures (0 never skips)")
+	fs.DurationVar(&config.MaxDuration, "max-duration", 0, "Stop starting new downloads after this duration, e.g. 30m")
+	fs.DurationVar(&config.AssetTimeout, "asset-timeout", 0, "Give up on an asset whose transfer takes longer than this, e.g. 10m")
+	fs.StringVar(&config.Resume, "resume", "", "Continue the work left undone by a run stopped by --max-duration")
+	fs.StringVar(&config.OTelEndpoint, "otel-endpoint", "", "Export trace spans of the run to this OTLP/HTTP collector")
+	fs.StringVar(&config.ChecksumAlgo, "checksum-algo", "sha256", "Checksum algorithm for digests and verification: sha256, sha512, blake2b or md5")
+	fs.StringVar(&config.ChecksumFile, "checksum-file", "", "Checksum release assets (glob pattern or auto) to verify downloaded assets against")
+	fs.StringVar(&config.Checksum, "checksum", "", "Expected digest of the single matching asset, e.g. sha256:<hex>")
+	fs.BoolVar(&config.Verify, "verify", false, "Verify downloaded assets against every checksum asset of the release (--checksum-file auto)")
+	fs.BoolVar(&config.EmitSidecarChecksums, "emit-sidecar-checksums", false, "Write a checksum file next to each downloaded file")
+	fs.StringVar(&config.Key, "key", "", "Private key that attest-mirror signs with")
+	fs.BoolVar(&config.Paranoid, "paranoid", false, "Verify downloaded assets against the digests GitHub reports (the default)")
+	fs.BoolVar(&config.NoVerifyDigest, "no-verify-digest", false, "Do not verify downloaded assets against the digests GitHub reports")
+	fs.BoolVar(&config.Repair, "repair", false, "Download assets failing verification again")
+	fs.BoolVar(&config.VerifyTagSignature, "verify-tag-signature", false, "Refuse releases whose tag signature GitHub did not verify")
+	fs.StringVar(&config.TagSigningKey, "tag-signing-key", "", "Public key to check the tag signature against locally")
+	fs.BoolVar(&config.VerifySignature, "verify-signature", false, "Verify downloaded assets against their .asc or .sig signature assets with gpg")
+	fs.StringVar(&config.SignerKey, "signer-key", "", "GPG public keys to verify signature assets against")
+	fs.BoolVar(&config.Cosign, "cosign", false, "Verify downloaded assets against their sigstore bundles or .sig and .pem assets with cosign")
+	fs.BoolVar(&config.VerifyProvenance, "verify-provenance", false, "Verify the SLSA provenance of the release and that downloaded assets are its subjects")
+	fs.StringVar(&config.CertificateIdentity, "certificate-identity", "", "With --cosign or --verify-provenance, the identity the signing certificates must be issued to")
+	fs.StringVar(&config.CertificateOIDCIssuer, "certificate-oidc-issuer", "", "With --cosign or --verify-provenance, the OIDC issuer the signing certificates must be issued by")
+	fs.BoolVar(&config.RequireChecksPassed, "require-checks-passed", false, "Refuse releases whose commit has failed or pending checks")
+	fs.StringVar(&config.RequireDeployment, "require-deployment", "", "Refuse releases not successfully deployed to this environment")
+	fs.IntVar(&config.MinReactions, "min-reactions", 0, "Only use releases with at least this many reactions")
+	fs.StringVar(&config.Author, "author", "", "Only use releases created by this account")
+	fs.StringVar(&config.Strategy, "strategy", "", "Without --tag, choose the release by most-downloaded, most-recent-stable or highest-semver")
+	fs.Var(&jsonFlag{enabled: &config.JSON, fields: &config.JSONFields}, "json", "Print records as JSON, optionally only the comma-separated fields given")
+	fs.DurationVar(&config.StaleAfter, "stale-after", time.Hour, "Age after which temporary files of crashed runs are removed")
+	fs.DurationVar(&config.WaitLock, "wait-lock", 0, "How long to wait for another run to release the target directory")
+	fs.BoolVar(&config.NoLock, "no-lock", false, "Do not lock the target directory against concurrent runs")
+	fs.BoolVar(&config.Check, "check", false, "Only check whether --dir already holds the matching assets")
+	fs.DurationVar(&config.StaleOK, "stale-ok", 0, "Fall back to release metadata cached within this duration while the API is unavailable")
+	fs.BoolVar(&config.All, "all", false, "With export, export every release")
+	fs.StringVar(&config.Output, "output", "", "File to save the single matching asset as, or with export, freeze and audit-report to write instead of stdout; \"-\" is --stdout")
+	fs.StringVar(&config.Output, "O", "", "File to save the single matching asset as (shorthand)")
+	fs.StringVar(&config.OutputTemplate, "output-template", "", "Path of each asset below --dir, e.g. \"{tag}/{os}/{name}\"")
+	fs.StringVar(&config.MirrorToFork, "mirror-to-fork", "", "Republish the release and the downloaded assets in the fork owned by this organization")
+	fs.StringVar(&config.Format, "format", "", "Output format of --list, --releases, compare, history and --idempotent-json; with export, json or ndjson")
+	fs.StringVar(&config.Template, "template", "", "Go template to execute over the records, with the functions of gh templates")
+	fs.BoolVar(&config.UseCache, "cache", false, "Serve assets from and add them to the content-addressable cache")
+	fs.StringVar(&config.Listen, "listen", "127.0.0.1:8080", "With serve, address to listen on")
+	fs.StringVar(&config.AccessFile, "access-file", "", "With serve, YAML file of the clients allowed to pull and their repositories")
+	fs.StringVar(&config.OverlapPolicy, "overlap-policy", "queue", "With serve, what a request does while the asset is being fetched for another: queue, skip or cancel-and-restart")
+	fs.StringVar(&config.FromFile, "from-file", "", "With plan, tool manifest to sync --dir with")
+	fs.BoolVar(&config.Apply, "apply", false, "With plan, make the planned changes")
+	fs.IntVar(&config.Concurrency, "concurrency", 4, "Number of assets to download at once")
+	fs.BoolVar(&config.HeadCheck, "head-check", false, "Check the size and range support of each asset with a HEAD request before transferring it")
+	fs.BoolVar(&config.Verbose, "verbose", false, "Log cache hits, --head-check findings and timings to stderr")
+	fs.BoolVar(&config.Debug, "debug", false, "Log the HTTP requests made as well, as with GH_DEBUG")
+	fs.BoolVar(&config.DebugHTTP, "debug-http", false, "Trace HTTP requests, responses, rate limits and redirects to stderr")
+	fs.StringVar(&config.Color, "color", "auto", "Use color in output: always, never or auto")
+	fs.StringVar(&config.LogFormat, "log-format", "text", "Format of the diagnostics on stderr: text or json")
+	fs.BoolVar(&config.Preflight, "preflight", false, "With --stdin, check that the token can read every repository before downloading")
+	fs.BoolVar(&config.WriteProvenance, "write-provenance", false, "Write .gh-download.json describing the run to the target directory")
+	fs.BoolVar(&config.Commits, "commits", false, "List commits between the compared tags")
+	fs.BoolVar(&config.Files, "files", false, "List files changed between the compared tags")
+	fs.BoolVar(&config.List, "list", false, "List release assets without downloading")
+	fs.BoolVar(&config.List, "l", false, "List release assets without downloading (shorthand)")
+	fs.BoolVar(&config.CopyURL, "copy-url", false, "With --list, copy the browser download URL of the single matching asset to the clipboard")
+	fs.BoolVar(&config.Releases, "releases", false, "List all releases")
+	fs.BoolVar(&config.Releases, "r", false, "List all releases (shorthand)")
+	fs.BoolVar(&config.NoPager, "no-pager", false, "Do not page --list and --releases output")
+	fs.BoolVar(&config.Help, "help", false, "Show help")
+	fs.BoolVar(&config.Help, "h", false, "Show help (shorthand)")
+
+	// The flag package stops at the first positional argument, so keep
+	// parsing the remainder to allow flags after positional arguments.
+	var positionals []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return Config{}, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positionals = append(positionals, fs.Arg(0))
+		args = fs.Args()[1:]
 	}
-	if len(args) > 1 && config.Tag == "" {
-		config.Tag = args[1]
+
+	// Positional arguments fill the repository and tag unless they were
+	// given as flags; the rest are left to the command.
+	if config.Repository == "" && len(positionals) > 0 {
+		config.Repository = positionals[0]
+		positionals = positionals[1:]
 	}
+	if config.Tag == "" && len(positionals) > 0 {
+		config.Tag = positionals[0]
+		positionals = positionals[1:]
+	}
+	config.Args = positionals
 
-	return config
+	// "--output -" writes to stdout: the asset of a download, like --stdout,
+	// and the records of export, as without --output
+	if config.Output == "-" {
+		config.Output = ""
+		if config.Command == "" {
+			config.Stdout = true
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		name := f.Name
+		if long, ok := shorthands[name]; ok {
+			name = long
+		}
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		value := f.Value.String()
+		config.Flags = append(config.Flags, Flag{
+			Name:  name,
+			Value: value,
+			// --json takes no value unless it is given fields
+			Bool: ok && boolFlag.IsBoolFlag() && (value == "true" || value == "false"),
+		})
+	})
+
+	return config, nil
+}
+
+// jsonFlag is --json: a switch like a bool flag that also takes the fields to
+// print, as gh does
+type jsonFlag struct {
+	enabled *bool
+	fields  *[]string
+}
+
+func (f *jsonFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (f *jsonFlag) String() string {
+	if f.enabled == nil {
+		return "false"
+	}
+	if len(*f.fields) > 0 {
+		return strings.Join(*f.fields, ",")
+	}
+	return strconv.FormatBool(*f.enabled)
+}
+
+func (f *jsonFlag) Set(value string) error {
+	if enabled, err := strconv.ParseBool(value); err == nil {
+		*f.enabled = enabled
+		*f.fields = nil
+		return nil
+	}
+	*f.fields = nil
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			*f.fields = append(*f.fields, field)
+		}
+	}
+	*f.enabled = true
+	return nil
+}
+
+// jsonFieldList matches the field lists --json takes, such as tagName,assets
+var jsonFieldList = regexp.MustCompile(`^[a-z][A-Za-z0-9]*(,[a-z][A-Za-z0-9]*)*$`)
+
+// joinJSONFields turns "--json tagName,assets" into "--json=tagName,assets",
+// since the flag package only takes values of bool flags after "="
+func joinJSONFields(args []string) []string {
+	joined := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if (arg == "--json" || arg == "-json") && i+1 < len(args) && jsonFieldList.MatchString(args[i+1]) {
+			arg += "=" + args[i+1]
+			i++
+		}
+		joined = append(joined, arg)
+	}
+	return joined
 }
 
 func PrintUsage() {
@@ -53,26 +429,349 @@ func PrintUsage() {
 
 Usage:
   gh download [repository] [tag] [flags]
+  gh download peek [repository] [tag] [flags]
+  gh download compare [repository] <base-tag> <head-tag> [flags]
+  gh download action-yaml [repository] [tag] [flags]
+  gh download attest-mirror --dir <mirror> --key <key> [flags]
+  gh download verify [repository] [tag] --dir <dir> [flags]
+  gh download history [repository] [flags]
+  gh download clean --dir <dir> [flags]
+  gh download tap add|update|remove|list [source] [name]
+  gh download match-test --pattern <pattern> <name>... | --repo <repo> [tag]
+  gh download export <repository> [tag] [--all] [--output <file>] [flags]
+  gh download db query <sql> | migrate
+  gh download cache export|import <bundle.tar>
+  gh download serve [--listen <address>] [--access-file <file>] [--overlap-policy <policy>]
+  gh download plan --from-file <manifest.yml> --dir <dir> [--apply]
+  gh download schema json|report|history|events
+  gh download adopt [repository] [tag] --dir <dir> [flags]
+  gh download freeze [repository] [--output <manifest.yml>]
+  gh download install --from-file <manifest.yml> --dir <dir>
+  gh download audit-report [repository] [--output <file.csv>]
+  gh download browse <repository> [flags]
+
+Commands:
+  peek            Show the file type and leading bytes of matching assets
+                  without downloading them; zip assets also list their contents
+  compare         Show the asset changes between two releases, and with --commits
+                  and --files the commits and changed files between their tags
+  action-yaml     Print a composite GitHub Action that runs this command line,
+                  with the tag as an input and the --github-output results as outputs
+  attest-mirror   Sign an in-toto statement over the digests of every file in the
+                  --dir mirror and write it to mirror.intoto.json as a DSSE envelope
+  verify          Check previously downloaded files in --dir against the digests of
+                  the matching assets and --checksum-file without downloading
+  history         Show what earlier runs downloaded, when, from which tag and where
+                  to; the history is kept in the gh state directory or
+                  $GH_DOWNLOAD_STATE_DIR
+  clean           Remove the partial downloads and temporary files crashed runs
+                  left anywhere under --dir that are older than --stale-after
+  tap             Manage shared tool manifests: "add" fetches one from a repository
+                  (owner/repo, reading gh-download.yml) or a URL with a name,
+                  "update" refetches them, "remove" drops one and "list" shows
+                  them with their tools; a tool name, or <tap>:<tool>, then
+                  stands in for the repository
+  match-test      Show which of the given names, or with --repo the assets of a
+                  release, --pattern matches after expanding its templates;
+                  exits with 1 when none match
+  export          Write the metadata of a release, or with --all of every release,
+                  including all asset metadata as JSON or NDJSON for analysis
+  db              Work with the SQLite state database of
+                  GH_DOWNLOAD_STATE_BACKEND=sqlite: "query" runs a read-only SQL
+                  query and "migrate" copies the JSON download history into it
+  cache           Move the --cache assets and the --stale-ok metadata between
+                  machines: "export" writes them to a tar bundle and "import"
+                  adds those of a bundle, verifying every asset
+  serve           Run a read-through cache on --listen: requests for
+                  /<owner>/<repo>/<tag>/<asset> are answered from the --cache
+                  store, or fetched from GitHub with this machine's credentials
+                  and added to it; --access-file limits who may pull what, and
+                  every request is added to the audit log
+  plan            Compare --dir with the tools of the manifest given to --from-file
+                  and print what syncing would download (+), update (~) and
+                  delete (-); --apply then makes those changes
+  schema          Print the JSON Schema, generated from the code that writes it, of
+                  a machine-readable output: "json" for the records of export,
+                  "report" for --idempotent-json, "history" for history --json
+                  and "events" for the lines of --log-format json
+  adopt           Record the files already in --dir that are assets of the release,
+                  matched by digest, in the download history as if downloaded
+  freeze          Write a manifest pinning the tag and file digests of the latest
+                  download of each repository and directory in the history
+  install         Sync --dir with the manifest given to --from-file, such as one
+                  written by freeze; the same as plan --apply
+  audit-report    Write a CSV of the downloads and bytes the serve proxy handed
+                  each client of each repository per month, from the audit log
+  browse          Browse the releases full screen, with the assets and notes of the
+                  selected one, and download the assets marked there
 
 Arguments:
-  repository    Repository in format owner/repo
-  tag           Release tag (optional, defaults to latest)
+  repository      Repository in format owner/repo
+  tag             Release tag (optional, defaults to latest)
 
 Flags:
   -R, --repo string      Repository in format owner/repo
   -t, --tag string       Release tag (defaults to latest)
   -p, --pattern string   Glob pattern to match asset names (default "*")
   -d, --dir string       Directory to download files to (default ".")
+                         --pattern and --dir may be Go templates over the release,
+                         e.g. "tools/{{.Name}}/v{{semverMajor .Tag}}"
+      --checksums-only   Only download the matching assets that describe the others:
+                         checksum files, signatures, certificates, attestations, SBOMs
+                         and other small text files
+      --max-asset-size string
+                         Refuse to download matching assets above this size, in bytes
+                         or with a binary unit such as 500M or 2G
+      --on-oversize string
+                         What to do with assets above --max-asset-size: fail before
+                         downloading anything, or skip them with a warning
+                         (default "fail")
       --archive string   Download source archive (zip or tar.gz)
+      --fallback-archive string
+                         Download the source archive (zip or tar.gz) of the release
+                         instead when it has no assets at all
+      --order string     Download order: size-asc, size-desc, name or manifest (default "manifest")
+      --sort string      Order of the assets and releases of --list, --releases and export:
+                         name, date (newest first) or api for the API order (default:
+                         assets by name, releases by publication date, newest first)
+      --concurrency int  Number of assets to download or extract at once; with more
+                         than one, assets are started in --order (default 4)
+      --head-check       Send a HEAD request before each transfer: a size other than
+                         GitHub reports fails the asset early, and a partial download
+                         the server cannot resume is started over
+      --verbose          Log what happens along the way to stderr: how long the release
+                         and each transfer took, cache hits, what --head-check found
+      --debug            Log the HTTP requests made as well (like GH_DEBUG=1; headers
+                         and bodies with GH_DEBUG=api)
+      --debug-http       Trace each HTTP request to stderr: method, URL and headers, then
+                         status, time, rate-limit headers and redirect target, with
+                         tokens and the signatures of signed URLs redacted
+      --color string     Color release names, draft and prerelease badges, patterns and
+                         errors: always, never or auto, on terminals unless NO_COLOR is
+                         set (default "auto")
+      --log-format string
+                         Write diagnostics to stderr as text or as JSON objects, one per
+                         line, with download_start, download_resume, download_done,
+                         verify_failed, download_failed, retry and error events for CI
+                         (default "text")
+      --bytes int        Number of leading bytes to fetch with peek (default 256)
+      --extract          Extract archive assets instead of saving them
+                         (zip assets are read remotely, tar.gz assets are streamed)
+      --include string   Glob pattern to match files inside archives when extracting;
+                         "**" matches any number of directories (alias: --pattern-in-archive)
+      --strip-components int
+                         Strip leading path elements from extracted files
+      --delta            Keep an extracted source archive up to date by fetching only
+                         the files changed since the last run (requires --archive tar.gz)
+      --with-submodules  Also extract each submodule at its pinned commit into its path
+                         (requires --archive tar.gz --extract)
+      --resolve-lfs      Replace Git LFS pointer files in extracted sources with the
+                         objects they point to (with --extract, --delta)
+      --rename-by-type   Rename downloaded assets whose extension contradicts their
+                         content, e.g. a gzip file named .zip
+      --continue-on-error
+                         Keep going when an asset fails and report the failures at
+                         the end (exit code 10 if some failed, 11 if all failed)
+      --clobber          Overwrite the files of assets that already exist; without it or
+                         --skip-existing, an existing file is an error
+      --skip-existing    Skip assets whose file already exists with the asset's size and
+                         digest; an existing file that differs is an error
+      --print-paths      Print only the absolute paths of downloaded files, one per
+                         line on stdout; other output goes to stderr
+      --stdout           Write the single matching asset to stdout, e.g. to pipe it into
+                         tar, after verifying it; other output goes to stderr
+  -q, --quiet            Like --print-paths, but drop the other output instead of moving
+                         it to stderr; warnings and errors are still printed
+      --github-output    Write tag, count, paths, digests and up-to-date to $GITHUB_OUTPUT
+      --env-file string  Append the same key=value pairs to a file
+      --idempotent-json  Print no progress, only one JSON object with the resulting files
+                         sorted by path and whether anything changed; assets whose file
+                         already matches the digest reported by GitHub are skipped
+      --stdin            Read repositories from stdin, one per line, and download each
+                         into <dir>/<owner>/<repo>
+      --preflight        With --stdin, check that the token can read every repository
+                         first and fail with a report of those it cannot
+      --urls-only        Print the browser download URLs of matching assets (or of the
+                         source archive with --archive) instead of downloading them, one
+                         per line (alias: --urls)
+      --api-urls         With --urls-only, print the REST API URLs of the assets, which
+                         serve the content with Accept: application/octet-stream and a
+                         token, also for private repositories
+      --signed           With --urls-only or --emit-commands, use short-lived
+                         pre-authorized URLs that work without credentials, also for
+                         private repositories
+      --presign          Print the short-lived signed URLs of matching assets for a
+                         system without GitHub credentials, and when they expire
+      --emit-commands string
+                         Print ready-to-run aria2, curl or wget commands instead of
+                         downloading; they read the token from $GH_TOKEN
+      --downloader string
+                         Transfer each asset with aria2c, curl, wget or another program;
+                         naming, checks and reporting stay with gh download
+      --downloader-args string
+                         Argument template for --downloader, with {url}, {path}, {dir}
+                         and {name} replaced for each asset (required for other programs)
+      --retries int      Number of times to retry a failed transfer (default 2)
+      --retry-on string  Comma-separated failure classes to retry: 5xx, rate-limit,
+                         network and checksum; others such as 404 or auth errors
+                         fail fast (default "5xx,rate-limit,network")
+      --retry-delay duration
+                         Wait before the first retry, doubling for each following one
+                         with some random jitter (default 1s)
+      --max-host-failures int
+                         With --stdin --continue-on-error, skip the remaining repositories
+                         of a host after this many consecutive failures (default 3, 0 never)
+      --max-duration duration
+                         Stop starting new downloads after this duration, e.g. 30m; the
+                         run finishes the current one and prints a resume token (exit 13)
+      --asset-timeout duration
+                         Give up on an asset whose transfer, retries included, takes longer
+                         than this, e.g. 10m; it fails without stopping the other assets
+                         and a later run with --repair fetches it again
+      --resume string    Download only what the run that printed this token left undone
+      --otel-endpoint string
+                         Export spans for release resolution and each transfer,
+                         verification and extraction to an OTLP/HTTP collector,
+                         e.g. http://localhost:4318
+      --checksum-algo string
+                         Algorithm of the digests written by --github-output and
+                         --env-file and of unlabeled --checksum-file digests: sha256,
+                         sha512, blake2b or md5 (never used to verify) (default "sha256")
+      --checksum-file string
+                         Verify downloaded assets against the release assets matching
+                         this pattern, or "auto" for every checksum file; an asset
+                         without an entry or with a mismatch fails (exit code 12)
+      --checksum string  Expected digest of the single matching asset, such as
+                         sha256:<hex>; a mismatching file is deleted (exit code 12)
+      --verify           Verify downloaded assets against the checksum files of the
+                         release, such as SHA256SUMS or checksums.txt; the same as
+                         --checksum-file auto
+      --emit-sidecar-checksums
+                         Write <file>.sha256 next to each downloaded file, in the format
+                         of sha256sum -c (.sha512, .b2 or .md5 with --checksum-algo)
+      --write-provenance Write .gh-download.json to --dir with the command line, the
+                         resolved release, the file digests, the tool version and time
+      --key string       PEM PKCS #8 Ed25519 or ECDSA private key for attest-mirror
+      --paranoid         Verify each downloaded asset against the digest GitHub reports;
+                         the default now, kept for existing scripts
+      --no-verify-digest Do not verify downloaded assets against the digests GitHub
+                         reports, e.g. for assets altered by a proxy
+      --repair           Download assets that fail verification again: verify repairs
+                         failed and missing files, downloads retry checksum failures
+      --verify-tag-signature
+                         Refuse releases whose tag is not annotated and signed with a
+                         signature GitHub verified
+      --tag-signing-key string
+                         GPG or SSH public key to also check the tag signature against
+                         locally with gpg or ssh-keygen; implies --verify-tag-signature
+      --verify-signature Verify each downloaded asset against its detached GPG
+                         signature, the asset of the same name with .asc or .sig
+                         appended, when the release has one. The keys are those the
+                         release author added to GitHub unless --signer-key is given
+      --signer-key string
+                         GPG public keys to verify signatures against; implies
+                         --verify-signature
+      --cosign           Verify each downloaded asset against its sigstore keyless
+                         signature: a bundle named after it with .sigstore.json,
+                         .sigstore or .bundle appended, or its .sig and .pem assets.
+                         The identity is checked here and the rest with cosign
+      --verify-provenance
+                         Verify that every downloaded asset is a subject of the
+                         *.intoto.jsonl SLSA provenance of the release, checking the
+                         statement with cosign; requires --certificate-identity and
+                         --certificate-oidc-issuer
+      --certificate-identity string
+                         With --cosign or --verify-provenance, the email or URI the
+                         signing certificates must be issued to, e.g. the workflow of
+                         a release job
+      --certificate-oidc-issuer string
+                         With --cosign or --verify-provenance, the OIDC issuer the
+                         signing certificates must be issued by, e.g.
+                         https://token.actions.githubusercontent.com
+      --require-checks-passed
+                         Refuse releases whose tagged commit has failed or pending
+                         commit statuses or check runs
+      --require-deployment string
+                         Refuse releases whose tagged commit has no successful
+                         deployment to this environment, e.g. production
+      --min-reactions int
+                         Only use releases with at least this many reactions: without
+                         --tag, download the newest such stable release; with
+                         --releases, list only those
+      --author string    Only use releases created by this account, e.g.
+                         github-actions[bot]; filters like --min-reactions
+      --strategy string  Without --tag, choose among all stable releases instead of the
+                         latest: most-downloaded, most-recent-stable or highest-semver
+      --json [fields]    Print the records of --list, --releases, compare and history as
+                         JSON, only the comma-separated fields given as gh does, e.g.
+                         --json tagName,assets (all fields for history)
+      --stale-after duration
+                         Age after which *.part and *.tmp files left by crashed runs
+                         are removed by clean and before downloads (default 1h)
+      --wait-lock duration
+                         How long to wait for another run writing to the same --dir
+                         to finish, e.g. 10m (default: fail right away)
+      --no-lock          Do not lock --dir against concurrent runs
+      --check            Download nothing; exit 0 if --dir already holds every matching
+                         asset with the right size and digest, 1 otherwise
+      --stale-ok duration
+                         Cache release metadata and, while the API is down or slow,
+                         fall back to a cache entry at most this old (e.g. 24h);
+                         metadata requests time out after 30s
+      --all              With export, export every release
+  -O, --output string    File to save the single matching asset as, below --dir unless
+                         absolute; with export, freeze and audit-report, file to
+                         write instead of stdout; "-" writes to stdout like --stdout
+      --output-template string
+                         Path of each asset below --dir built from {owner}, {repo},
+                         {tag}, {version}, {published}, {name} and the {os} and {arch}
+                         its name is for, e.g. "{tag}/{os}/{name}"; directories are
+                         created as needed
+      --mirror-to-fork string
+                         Republish the release, with its tag, notes and the downloaded
+                         assets, in the fork of the repository owned by this
+                         organization, forking it first when there is none
+      --format string    Print the records of --list, --releases, compare and history as
+                         table, json, ndjson, csv, tsv, yaml or template; the result of
+                         --idempotent-json as json or yaml; with export, json or ndjson
+                         (default ndjson for .ndjson and .jsonl --output files, json
+                         otherwise)
+      --template string  Print the records with a Go template executed over their list,
+                         with gh's template functions (timeago, timefmt, truncate, join,
+                         pluck, tablerow, tablerender, hyperlink) and humanSize; implies
+                         --format template, e.g. '{{range .}}{{println .name}}{{end}}'
+      --cache            Copy assets GitHub reports a digest for from the
+                         content-addressable cache when present, and add downloaded
+                         ones to it ($GH_DOWNLOAD_CACHE_DIR or the gh cache directory)
+      --listen string    With serve, address to listen on (default "127.0.0.1:8080")
+      --access-file string
+                         With serve, YAML file of the clients allowed to pull, by the
+                         SHA-256 of their token, and the repositories each may pull
+      --overlap-policy string
+                         With serve, what a request for an asset another request is
+                         fetching does: wait for that fetch (queue), get 503 with
+                         Retry-After at once (skip), or cancel it and fetch the asset
+                         again (cancel-and-restart) (default "queue")
+      --from-file string With plan, tool manifest in the tap format to sync --dir with;
+                         each tool goes to its dir below --dir, or one named after it
+      --apply            With plan, download, update and delete the planned files
+      --commits          List commits between the compared tags
+      --files            List files changed between the compared tags
   -l, --list             List release assets without downloading
+      --copy-url         With --list, copy the browser download URL of the single
+                         matching asset to the clipboard
   -r, --releases         List all releases
+      --no-pager         Print --list and --releases output directly instead of through
+                         the pager (GH_PAGER, the gh pager setting or PAGER) on terminals
   -h, --help             Show help
 
 Examples:
-  gh download owner/repo                       # Download all assets from latest release
-  gh download owner/repo v1.0.0                # Download all assets from v1.0.0
-  gh download -R owner/repo -p "*.tar.gz"      # Download only .tar.gz files
-  gh download --repo owner/repo --archive zip  # Download source code as zip
-  gh download --repo owner/repo --list         # List all assets without downloading
-  gh download --repo owner/repo --releases     # List all releases`)
+  gh download owner/repo                          # Download all assets from latest release
+  gh download owner/repo v1.0.0                   # Download all assets from v1.0.0
+  gh download -R owner/repo -p "*.tar.gz"         # Download only .tar.gz files
+  gh download --repo owner/repo --archive zip     # Download source code as zip
+  gh download --repo owner/repo --order size-asc  # Download smallest assets first
+  gh download --repo owner/repo --list            # List all assets without downloading
+  gh download --repo owner/repo --releases        # List all releases
+  gh download peek owner/repo -p "*.zip"          # Inspect zip assets before downloading`)
 }
@@ -157,9 +157,256 @@ func TestConfig_StructFields(t *testing.T) {
 	}
 }
 
-// NOTE: ParseArgs() testing is complex due to flag package's global state.
-// In a real-world scenario, we might refactor ParseArgs to accept arguments
-// or use dependency injection for better testability.
+func TestParse_Defaults(t *testing.T) {
+	config, err := Parse([]string{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != "" {
+		t.Errorf("Expected no command, got %q", config.Command)
+	}
+	if config.Pattern != "*" {
+		t.Errorf("Expected Pattern to be '*', got %q", config.Pattern)
+	}
+	if config.Directory != "." {
+		t.Errorf("Expected Directory to be '.', got %q", config.Directory)
+	}
+	if config.Order != "manifest" {
+		t.Errorf("Expected Order to be 'manifest', got %q", config.Order)
+	}
+}
+
+func TestParse_PositionalArguments(t *testing.T) {
+	config, err := Parse([]string{"owner/repo", "v1.0.0"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Repository != "owner/repo" {
+		t.Errorf("Expected Repository to be 'owner/repo', got %q", config.Repository)
+	}
+	if config.Tag != "v1.0.0" {
+		t.Errorf("Expected Tag to be 'v1.0.0', got %q", config.Tag)
+	}
+}
+
+func TestParse_FlagsAfterPositionalArguments(t *testing.T) {
+	config, err := Parse([]string{"owner/repo", "-p", "*.zip", "v1.0.0", "--dir", "out"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Repository != "owner/repo" {
+		t.Errorf("Expected Repository to be 'owner/repo', got %q", config.Repository)
+	}
+	if config.Tag != "v1.0.0" {
+		t.Errorf("Expected Tag to be 'v1.0.0', got %q", config.Tag)
+	}
+	if config.Pattern != "*.zip" {
+		t.Errorf("Expected Pattern to be '*.zip', got %q", config.Pattern)
+	}
+	if config.Directory != "out" {
+		t.Errorf("Expected Directory to be 'out', got %q", config.Directory)
+	}
+}
+
+func TestParse_FlagsTakePrecedence(t *testing.T) {
+	config, err := Parse([]string{"--repo", "flag/repo", "--tag", "v2", "arg/repo", "v1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Repository != "flag/repo" {
+		t.Errorf("Expected Repository to be 'flag/repo', got %q", config.Repository)
+	}
+	if config.Tag != "v2" {
+		t.Errorf("Expected Tag to be 'v2', got %q", config.Tag)
+	}
+}
+
+func TestParse_Command(t *testing.T) {
+	config, err := Parse([]string{"peek", "owner/repo", "-p", "app.zip", "--bytes", "64"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandPeek {
+		t.Errorf("Expected Command to be %q, got %q", CommandPeek, config.Command)
+	}
+	if config.Repository != "owner/repo" {
+		t.Errorf("Expected Repository to be 'owner/repo', got %q", config.Repository)
+	}
+	if config.Pattern != "app.zip" {
+		t.Errorf("Expected Pattern to be 'app.zip', got %q", config.Pattern)
+	}
+	if config.Bytes != 64 {
+		t.Errorf("Expected Bytes to be 64, got %d", config.Bytes)
+	}
+}
+
+func TestParse_AttestMirror(t *testing.T) {
+	config, err := Parse([]string{"attest-mirror", "--dir", "./mirror", "--key", "key.pem"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandAttestMirror {
+		t.Errorf("Expected Command to be %q, got %q", CommandAttestMirror, config.Command)
+	}
+	if config.Directory != "./mirror" || config.Key != "key.pem" {
+		t.Errorf("Expected Directory './mirror' and Key 'key.pem', got %q and %q", config.Directory, config.Key)
+	}
+}
+
+func TestParse_Adopt(t *testing.T) {
+	config, err := Parse([]string{"adopt", "--dir", "./tools", "owner/repo", "v1.2.3"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandAdopt {
+		t.Errorf("Expected Command to be %q, got %q", CommandAdopt, config.Command)
+	}
+	if config.Repository != "owner/repo" || config.Tag != "v1.2.3" || config.Directory != "./tools" {
+		t.Errorf("Expected owner/repo v1.2.3 in ./tools, got %q %q in %q", config.Repository, config.Tag, config.Directory)
+	}
+}
+
+func TestParse_AuditReport(t *testing.T) {
+	config, err := Parse([]string{"audit-report", "owner/repo", "--output", "usage.csv"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandAuditReport {
+		t.Errorf("Expected Command to be %q, got %q", CommandAuditReport, config.Command)
+	}
+	if config.Repository != "owner/repo" || config.Output != "usage.csv" {
+		t.Errorf("Expected owner/repo to usage.csv, got %q to %q", config.Repository, config.Output)
+	}
+}
+
+func TestParse_OutputDash(t *testing.T) {
+	config, err := Parse([]string{"owner/repo", "-p", "*.tar.gz", "--output", "-"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !config.Stdout || config.Output != "" {
+		t.Errorf("Expected --output - to set Stdout, got Stdout %v and Output %q", config.Stdout, config.Output)
+	}
+
+	config, err = Parse([]string{"export", "owner/repo", "--output", "-"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Stdout || config.Output != "" {
+		t.Errorf("Expected export to write to stdout, got Stdout %v and Output %q", config.Stdout, config.Output)
+	}
+}
+
+func TestParse_OutputShorthand(t *testing.T) {
+	config, err := Parse([]string{"owner/repo", "-p", "*.deb", "-O", "bin/app.deb"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Output != "bin/app.deb" || config.Stdout {
+		t.Errorf("Expected Output 'bin/app.deb', got %q (Stdout %v)", config.Output, config.Stdout)
+	}
+	if !config.IsSet("output") {
+		t.Error("Expected -O to be recorded as --output")
+	}
+}
+
+func TestParse_CompareArguments(t *testing.T) {
+	config, err := Parse([]string{"compare", "owner/repo", "v1.0.0", "v1.1.0", "--commits"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandCompare {
+		t.Errorf("Expected Command to be %q, got %q", CommandCompare, config.Command)
+	}
+	if config.Tag != "v1.0.0" {
+		t.Errorf("Expected Tag to be 'v1.0.0', got %q", config.Tag)
+	}
+	if len(config.Args) != 1 || config.Args[0] != "v1.1.0" {
+		t.Errorf("Expected remaining Args to be [v1.1.0], got %v", config.Args)
+	}
+	if !config.Commits {
+		t.Error("Expected Commits to be true")
+	}
+}
+
+func TestParse_PositionalTagWithRepoFlag(t *testing.T) {
+	config, err := Parse([]string{"-R", "owner/repo", "v1.0.0"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Repository != "owner/repo" {
+		t.Errorf("Expected Repository to be 'owner/repo', got %q", config.Repository)
+	}
+	if config.Tag != "v1.0.0" {
+		t.Errorf("Expected Tag to be 'v1.0.0', got %q", config.Tag)
+	}
+}
+
+func TestParse_Quiet(t *testing.T) {
+	config, err := Parse([]string{"owner/repo", "-q"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !config.Quiet {
+		t.Error("Expected Quiet to be true")
+	}
+	if !config.IsSet("quiet") {
+		t.Error("Expected -q to be recorded as --quiet")
+	}
+}
+
+func TestParse_URLsAlias(t *testing.T) {
+	config, err := Parse([]string{"owner/repo", "--urls", "--api-urls"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !config.URLsOnly {
+		t.Error("Expected --urls to set URLsOnly")
+	}
+	if !config.APIURLs {
+		t.Error("Expected APIURLs to be true")
+	}
+}
+
+func TestParse_LogFormat(t *testing.T) {
+	config, err := Parse([]string{"owner/repo"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.LogFormat != "text" {
+		t.Errorf("Expected LogFormat to default to 'text', got %q", config.LogFormat)
+	}
+
+	config, err = Parse([]string{"owner/repo", "--log-format", "json"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.LogFormat != "json" {
+		t.Errorf("Expected LogFormat to be 'json', got %q", config.LogFormat)
+	}
+}
+
+func TestParse_UnknownFlag(t *testing.T) {
+	_, err := Parse([]string{"--unknown"})
+	if err == nil {
+		t.Fatal("Expected error for unknown flag, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "flag provided but not defined") {
+		t.Errorf("Expected unknown flag error, got %q", err.Error())
+	}
+}
+
 func TestPrintUsage_ContainsKeyElements(t *testing.T) {
 	output := captureOutput(func() {
 		PrintUsage()
@@ -184,3 +431,127 @@ func TestPrintUsage_ContainsKeyElements(t *testing.T) {
 		}
 	}
 }
+
+func TestParse_Flags(t *testing.T) {
+	cfg, err := Parse([]string{"owner/repo", "-p", "*.deb", "--extract", "-d", "out"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []Flag{
+		{Name: "dir", Value: "out"},
+		{Name: "extract", Value: "true", Bool: true},
+		{Name: "pattern", Value: "*.deb"},
+	}
+	if len(cfg.Flags) != len(expected) {
+		t.Fatalf("Expected %d flags, got %+v", len(expected), cfg.Flags)
+	}
+	for i, flag := range expected {
+		if cfg.Flags[i] != flag {
+			t.Errorf("Expected flag %+v, got %+v", flag, cfg.Flags[i])
+		}
+	}
+
+	if !cfg.IsSet("pattern") || !cfg.IsSet("dir") || cfg.IsSet("tag") {
+		t.Errorf("Unexpected IsSet results for %+v", cfg.Flags)
+	}
+}
+
+func TestParse_MatchTest(t *testing.T) {
+	config, err := Parse([]string{"match-test", "-p", "app-*-{{os}}-*", "app-linux.tar.gz", "app-darwin.tar.gz", "app.txt"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandMatchTest {
+		t.Errorf("Expected Command to be %q, got %q", CommandMatchTest, config.Command)
+	}
+	if config.Pattern != "app-*-{{os}}-*" {
+		t.Errorf("Expected Pattern to be kept unexpanded, got %q", config.Pattern)
+	}
+	if config.Repository != "app-linux.tar.gz" || config.Tag != "app-darwin.tar.gz" || len(config.Args) != 1 {
+		t.Errorf("Unexpected positional arguments %q %q %v", config.Repository, config.Tag, config.Args)
+	}
+}
+
+func TestParse_Tap(t *testing.T) {
+	config, err := Parse([]string{"tap", "add", "https://example.com/tools.yml", "team"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandTap {
+		t.Errorf("Expected Command to be %q, got %q", CommandTap, config.Command)
+	}
+	if config.Repository != "add" || config.Tag != "https://example.com/tools.yml" || len(config.Args) != 1 || config.Args[0] != "team" {
+		t.Errorf("Unexpected positional arguments %q %q %v", config.Repository, config.Tag, config.Args)
+	}
+}
+
+func TestParse_History(t *testing.T) {
+	config, err := Parse([]string{"history", "owner/repo", "--json"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Command != CommandHistory {
+		t.Errorf("Expected Command to be %q, got %q", CommandHistory, config.Command)
+	}
+	if config.Repository != "owner/repo" || !config.JSON {
+		t.Errorf("Expected Repository 'owner/repo' and JSON, got %q and %t", config.Repository, config.JSON)
+	}
+}
+
+func TestParse_JSONFields(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		repo     string
+		expected string
+	}{
+		{[]string{"owner/repo", "--releases", "--json", "tagName,assets"}, "owner/repo", "tagName,assets"},
+		{[]string{"--json=id, name", "owner/repo", "--list"}, "owner/repo", "id,name"},
+		{[]string{"--json", "owner/repo", "--list"}, "owner/repo", ""},
+		{[]string{"--list", "--json", "name", "owner/repo"}, "owner/repo", "name"},
+	}
+	for _, tc := range testCases {
+		config, err := Parse(tc.args)
+		if err != nil {
+			t.Fatalf("Expected no error for %v, got %v", tc.args, err)
+		}
+		if !config.JSON || strings.Join(config.JSONFields, ",") != tc.expected || config.Repository != tc.repo {
+			t.Errorf("Expected JSON with fields %q and repository %q for %v, got %t, %v and %q", tc.expected, tc.repo, tc.args, config.JSON, config.JSONFields, config.Repository)
+		}
+	}
+
+	config, err := Parse([]string{"owner/repo", "--json=false"})
+	if err != nil || config.JSON {
+		t.Errorf("Expected --json=false to disable JSON, got %t (%v)", config.JSON, err)
+	}
+}
+
+func TestParse_ExistingFiles(t *testing.T) {
+	config, err := Parse([]string{"owner/repo", "--skip-existing"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !config.SkipExisting || config.Clobber {
+		t.Errorf("Expected SkipExisting only, got SkipExisting %v and Clobber %v", config.SkipExisting, config.Clobber)
+	}
+
+	if config, err = Parse([]string{"owner/repo", "--clobber"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !config.Clobber {
+		t.Error("Expected Clobber to be set")
+	}
+}
+
+func TestParse_Browse(t *testing.T) {
+	config, err := Parse([]string{"browse", "owner/repo", "-p", "*.tar.gz"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Command != CommandBrowse || config.Repository != "owner/repo" || config.Pattern != "*.tar.gz" {
+		t.Errorf("Expected browse of owner/repo with *.tar.gz, got %q %q %q", config.Command, config.Repository, config.Pattern)
+	}
+}
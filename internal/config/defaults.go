@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ghconfig "github.com/cli/go-gh/v2/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultsEnv overrides the path of the defaults file
+const DefaultsEnv = "GH_DOWNLOAD_CONFIG"
+
+// RepoDefaults are the pattern and directory used for a repository when the
+// command line gives none
+type RepoDefaults struct {
+	Pattern string `yaml:"pattern,omitempty"`
+	Dir     string `yaml:"dir,omitempty"`
+}
+
+// Defaults is the defaults file, mapping OWNER/REPO or HOST/OWNER/REPO to
+// the defaults of that repository
+type Defaults struct {
+	Repos map[string]RepoDefaults `yaml:"repos"`
+}
+
+// DefaultsPath returns the path of the defaults file: $GH_DOWNLOAD_CONFIG,
+// or gh-download/config.yml in the configuration directory of gh
+func DefaultsPath() string {
+	if path := os.Getenv(DefaultsEnv); path != "" {
+		return path
+	}
+	return filepath.Join(ghconfig.ConfigDir(), "gh-download", "config.yml")
+}
+
+// LoadDefaults reads the defaults file at path. A missing file has no
+// defaults; unknown fields are rejected so that typos are not ignored.
+func LoadDefaults(path string) (*Defaults, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Defaults{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var d Defaults
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&d); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for repo := range d.Repos {
+		if slashes := strings.Count(repo, "/"); slashes != 1 && slashes != 2 {
+			return nil, fmt.Errorf("%s: repository must be in format owner/repo, got %q", path, repo)
+		}
+	}
+	return &d, nil
+}
+
+// Lookup returns the defaults of a repository, compared case-insensitively
+// as GitHub does
+func (d *Defaults) Lookup(repo string) (RepoDefaults, bool) {
+	for name, defaults := range d.Repos {
+		if strings.EqualFold(name, repo) {
+			return defaults, true
+		}
+	}
+	return RepoDefaults{}, false
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	d, err := LoadDefaults(path)
+	if err != nil {
+		t.Fatalf("Expected no error for a missing file, got %v", err)
+	}
+	if _, ok := d.Lookup("cli/cli"); ok {
+		t.Error("Expected no defaults without a file")
+	}
+
+	content := "repos:\n  cli/cli:\n    pattern: '*linux_amd64.tar.gz'\n    dir: ~/tools/gh\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err = LoadDefaults(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defaults, ok := d.Lookup("CLI/cli")
+	if !ok || defaults.Pattern != "*linux_amd64.tar.gz" || defaults.Dir != "~/tools/gh" {
+		t.Errorf("Unexpected defaults %+v (%v)", defaults, ok)
+	}
+
+	for _, invalid := range []string{
+		"repos:\n  cli/cli:\n    patern: '*'\n",
+		"repos:\n  cli:\n    pattern: '*'\n",
+	} {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadDefaults(path); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestDefaultsPath(t *testing.T) {
+	t.Setenv(DefaultsEnv, "/tmp/defaults.yml")
+	if got := DefaultsPath(); got != "/tmp/defaults.yml" {
+		t.Errorf("Expected %q, got %q", "/tmp/defaults.yml", got)
+	}
+}
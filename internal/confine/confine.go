@@ -0,0 +1,187 @@
+// Package confine confines file writes to a directory with os.Root, so that
+// no name, whether it comes from a template, an asset or an archive member,
+// can place a file outside of it, not even through symlinks or ".."
+// components resolved on the way.
+package confine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutside is returned for paths that would leave the directory
+var ErrOutside = errors.New("path escapes the target directory")
+
+// Root is a directory that writes are confined to. Paths given to its
+// methods are the full paths of files below the directory, as built with
+// filepath.Join(Dir(), name), so code that computes paths keeps doing so.
+type Root struct {
+	dir  string
+	root *os.Root
+}
+
+// Open creates dir when missing and opens it as the root of the writes
+func Open(dir string) (*Root, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	return &Root{dir: dir, root: root}, nil
+}
+
+// Close releases the directory
+func (r *Root) Close() error {
+	return r.root.Close()
+}
+
+// Dir returns the directory writes are confined to
+func (r *Root) Dir() string {
+	return r.dir
+}
+
+// Rel returns path relative to the directory, refusing paths outside of it
+func (r *Root) Rel(path string) (string, error) {
+	rel, err := filepath.Rel(filepath.Clean(r.dir), filepath.Clean(path))
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: %s is not below %s", ErrOutside, path, r.dir)
+	}
+	return rel, nil
+}
+
+// wrap turns the errors os.Root reports for escaping paths into ErrOutside
+func (r *Root) wrap(path string, err error) error {
+	if err != nil && strings.Contains(err.Error(), "path escapes from parent") {
+		return fmt.Errorf("%w: %s resolves outside %s", ErrOutside, path, r.dir)
+	}
+	return err
+}
+
+// MkdirAll creates the directory at path with any missing parents
+func (r *Root) MkdirAll(path string, perm os.FileMode) error {
+	rel, err := r.Rel(path)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	return r.wrap(path, r.root.MkdirAll(rel, perm))
+}
+
+// OpenFile opens the file at path like os.OpenFile, creating its parent
+// directories first when flag has os.O_CREATE
+func (r *Root) OpenFile(path string, flag int, perm os.FileMode) (*os.File, error) {
+	rel, err := r.Rel(path)
+	if err != nil {
+		return nil, err
+	}
+	if flag&os.O_CREATE != 0 {
+		if err := r.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := r.root.OpenFile(rel, flag, perm)
+	return file, r.wrap(path, err)
+}
+
+// Create creates or truncates the file at path
+func (r *Root) Create(path string) (*os.File, error) {
+	return r.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// WriteFile writes data to the file at path, creating its parents
+func (r *Root) WriteFile(path string, data []byte, perm os.FileMode) error {
+	file, err := r.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Copy writes src to the file at path, replacing it only once the copy is
+// complete, and returns the number of bytes written
+func (r *Root) Copy(path string, src io.Reader, perm os.FileMode) (int64, error) {
+	tmp := path + ".tmp"
+	file, err := r.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(file, src)
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := r.Remove(tmp); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", tmp, removeErr)
+		}
+		return 0, err
+	}
+	return written, r.Rename(tmp, path)
+}
+
+// Rename renames oldpath to newpath, both below the directory
+func (r *Root) Rename(oldpath, newpath string) error {
+	oldRel, err := r.Rel(oldpath)
+	if err != nil {
+		return err
+	}
+	newRel, err := r.Rel(newpath)
+	if err != nil {
+		return err
+	}
+	return r.wrap(newpath, r.root.Rename(oldRel, newRel))
+}
+
+// Remove removes the file or empty directory at path
+func (r *Root) Remove(path string) error {
+	rel, err := r.Rel(path)
+	if err != nil {
+		return err
+	}
+	return r.wrap(path, r.root.Remove(rel))
+}
+
+// Stat describes the file at path, following symlinks that stay below the
+// directory
+func (r *Root) Stat(path string) (os.FileInfo, error) {
+	rel, err := r.Rel(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := r.root.Stat(rel)
+	return info, r.wrap(path, err)
+}
+
+// Lstat describes the file at path without following a final symlink
+func (r *Root) Lstat(path string) (os.FileInfo, error) {
+	rel, err := r.Rel(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := r.root.Lstat(rel)
+	return info, r.wrap(path, err)
+}
+
+// Symlink creates newname as a symlink to oldname. The link itself is
+// confined; where it points is left to the caller to check.
+func (r *Root) Symlink(oldname, newname string) error {
+	rel, err := r.Rel(newname)
+	if err != nil {
+		return err
+	}
+	if err := r.MkdirAll(filepath.Dir(newname), 0755); err != nil {
+		return err
+	}
+	return r.wrap(newname, r.root.Symlink(oldname, rel))
+}
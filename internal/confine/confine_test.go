@@ -0,0 +1,115 @@
+package confine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openRoot(t *testing.T) (*Root, string) {
+	t.Helper()
+	parent := t.TempDir()
+	root, err := Open(filepath.Join(parent, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := root.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return root, parent
+}
+
+func TestWriteFile(t *testing.T) {
+	root, _ := openRoot(t)
+	path := filepath.Join(root.Dir(), "a", "b", "file.txt")
+	if err := root.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "data" {
+		t.Errorf("Expected data, got %q", data)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	root, _ := openRoot(t)
+	path := filepath.Join(root.Dir(), "app.zip")
+	written, err := root.Copy(path, strings.NewReader("content"), 0644)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if written != 7 {
+		t.Errorf("Expected 7 bytes, got %d", written)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary file to be gone, got %v", err)
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	root, parent := openRoot(t)
+	outside := filepath.Join(parent, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A symlink planted in the directory beforehand, as a malicious archive
+	// or another user could, must not be followed out of it
+	if err := os.Symlink(outside, filepath.Join(root.Dir(), "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "target"), filepath.Join(root.Dir(), "file-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{"dot dot", filepath.Join(root.Dir(), "..", "outside", "evil")},
+		{"absolute", filepath.Join(outside, "evil")},
+		{"symlinked directory", filepath.Join(root.Dir(), "link", "evil")},
+		{"symlinked file", filepath.Join(root.Dir(), "file-link")},
+		{"nested symlinked directory", filepath.Join(root.Dir(), "link", "sub", "evil")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := root.WriteFile(tc.path, []byte("evil"), 0644)
+			if !errors.Is(err, ErrOutside) {
+				t.Errorf("Expected ErrOutside, got %v", err)
+			}
+		})
+	}
+
+	if err := root.Rename(filepath.Join(root.Dir(), "file-link"), filepath.Join(outside, "moved")); !errors.Is(err, ErrOutside) {
+		t.Errorf("Expected ErrOutside for a rename out of the directory, got %v", err)
+	}
+	if err := root.Symlink("target", filepath.Join(root.Dir(), "link", "evil")); !errors.Is(err, ErrOutside) {
+		t.Errorf("Expected ErrOutside for a symlink through a symlink, got %v", err)
+	}
+
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing written outside the directory, got %v", entries)
+	}
+}
+
+func TestRel(t *testing.T) {
+	root, _ := openRoot(t)
+	rel, err := root.Rel(filepath.Join(root.Dir(), "a", "..", "b"))
+	if err != nil || rel != "b" {
+		t.Errorf("Expected b, got %q (%v)", rel, err)
+	}
+	if _, err := root.Rel(root.Dir() + "-sibling"); !errors.Is(err, ErrOutside) {
+		t.Errorf("Expected ErrOutside for a sibling directory, got %v", err)
+	}
+}
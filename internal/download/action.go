@@ -0,0 +1,133 @@
+package download
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+// actionSkippedFlags are not baked into generated actions: the repository
+// and tag are handled separately, and the results are always written to
+// $GITHUB_OUTPUT
+var actionSkippedFlags = map[string]bool{
+	"repo":          true,
+	"tag":           true,
+	"help":          true,
+	"github-output": true,
+	"print-paths":   true,
+}
+
+// actionOutputs are the --github-output results exposed by generated actions
+var actionOutputs = []struct {
+	name        string
+	description string
+}{
+	{"tag", "Tag of the downloaded release"},
+	{"count", "Number of files written"},
+	{"paths", "Absolute paths of the files written, one per line"},
+	{"digests", "SHA-256 digests of the files written in sha256sum format"},
+	{"up-to-date", "Whether --delta found nothing to update"},
+}
+
+// ActionYAML prints a composite GitHub Action that runs the download command
+// line given to action-yaml, so a command tested locally becomes a pinned
+// workflow step.
+func ActionYAML(cfg config.Config) error {
+	if cfg.Repository == "" {
+		return fmt.Errorf("repository is required")
+	}
+
+	fmt.Print(actionYAML(cfg))
+	return nil
+}
+
+func actionYAML(cfg config.Config) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "name: %s\n", yamlQuote("Download "+cfg.Repository))
+	fmt.Fprintf(&b, "description: %s\n", yamlQuote("Download release files of "+cfg.Repository+" with gh download"))
+
+	b.WriteString("inputs:\n")
+	b.WriteString("  tag:\n")
+	b.WriteString("    description: Release tag (empty for the latest release)\n")
+	b.WriteString("    required: false\n")
+	fmt.Fprintf(&b, "    default: %s\n", yamlQuote(cfg.Tag))
+	b.WriteString("  version:\n")
+	b.WriteString("    description: Version of the gh-download extension to pin (empty for the latest)\n")
+	b.WriteString("    required: false\n")
+	b.WriteString("    default: \"\"\n")
+	b.WriteString("  token:\n")
+	b.WriteString("    description: Token used to access the repository\n")
+	b.WriteString("    required: false\n")
+	b.WriteString("    default: ${{ github.token }}\n")
+
+	b.WriteString("outputs:\n")
+	for _, output := range actionOutputs {
+		fmt.Fprintf(&b, "  %s:\n", output.name)
+		fmt.Fprintf(&b, "    description: %s\n", yamlQuote(output.description))
+		fmt.Fprintf(&b, "    value: ${{ steps.download.outputs.%s }}\n", output.name)
+	}
+
+	b.WriteString("runs:\n")
+	b.WriteString("  using: composite\n")
+	b.WriteString("  steps:\n")
+	b.WriteString("    - name: Install gh-download\n")
+	b.WriteString("      shell: bash\n")
+	b.WriteString("      env:\n")
+	b.WriteString("        GH_TOKEN: ${{ inputs.token }}\n")
+	b.WriteString("        VERSION: ${{ inputs.version }}\n")
+	b.WriteString("      run: |\n")
+	b.WriteString("        gh extension remove download >/dev/null 2>&1 || true\n")
+	b.WriteString("        gh extension install 23prime/gh-download ${VERSION:+--pin \"$VERSION\"}\n")
+	b.WriteString("    - id: download\n")
+	b.WriteString("      shell: bash\n")
+	b.WriteString("      env:\n")
+	b.WriteString("        GH_TOKEN: ${{ inputs.token }}\n")
+	b.WriteString("        TAG: ${{ inputs.tag }}\n")
+	b.WriteString("      run: |\n")
+	fmt.Fprintf(&b, "        %s\n", actionCommand(cfg))
+
+	return b.String()
+}
+
+// actionCommand returns the shell command run by the generated action. The
+// tag comes from the environment so that inputs are never interpolated into
+// the script.
+func actionCommand(cfg config.Config) string {
+	args := []string{"gh", "download", shellQuote(cfg.Repository), `${TAG:+--tag "$TAG"}`}
+	for _, flag := range cfg.Flags {
+		if actionSkippedFlags[flag.Name] {
+			continue
+		}
+		switch {
+		case flag.Bool && flag.Value == "true":
+			args = append(args, "--"+flag.Name)
+		case flag.Bool:
+			args = append(args, "--"+flag.Name+"=false")
+		default:
+			args = append(args, "--"+flag.Name, shellQuote(flag.Value))
+		}
+	}
+	args = append(args, "--github-output")
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for bash unless it only contains safe characters.
+// Line breaks use $'...' quoting so the command stays on one line.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@+,") == "" {
+		return s
+	}
+	if strings.ContainsAny(s, "\r\n") {
+		replacer := strings.NewReplacer(`\`, `\\`, "'", `\'`, "\n", `\n`, "\r", `\r`)
+		return "$'" + replacer.Replace(s) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// yamlQuote returns s as a double-quoted YAML scalar
+func yamlQuote(s string) string {
+	return strconv.Quote(s)
+}
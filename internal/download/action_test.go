@@ -0,0 +1,60 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+func TestActionCommand(t *testing.T) {
+	cfg, err := config.Parse([]string{"action-yaml", "owner/repo", "v1.0.0", "-p", "*.deb", "--dir", "out dir", "--extract", "--github-output"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	got := actionCommand(cfg)
+	expected := `gh download owner/repo ${TAG:+--tag "$TAG"} --dir 'out dir' --extract --pattern '*.deb' --github-output`
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestActionYAML(t *testing.T) {
+	cfg, err := config.Parse([]string{"action-yaml", "--repo", "owner/repo", "--tag", "v1.0.0"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	yaml := actionYAML(cfg)
+	for _, expected := range []string{
+		"  using: composite\n",
+		"    default: \"v1.0.0\"\n",
+		"        TAG: ${{ inputs.tag }}\n",
+		"    value: ${{ steps.download.outputs.paths }}\n",
+	} {
+		if !strings.Contains(yaml, expected) {
+			t.Errorf("Expected action to contain %q, got:\n%s", expected, yaml)
+		}
+	}
+	if strings.Contains(yaml, "--repo") || strings.Contains(yaml, "--tag v1.0.0") {
+		t.Errorf("Expected repository and tag not to be baked in as flags, got:\n%s", yaml)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	testCases := map[string]string{
+		"out":       "out",
+		"":          "''",
+		"*.deb":     "'*.deb'",
+		"it's":      `'it'\''s'`,
+		"a\nb":      `$'a\nb'`,
+		"owner/rep": "owner/rep",
+	}
+
+	for input, expected := range testCases {
+		if got := shellQuote(input); got != expected {
+			t.Errorf("shellQuote(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// adoptedFile is a file of the directory given to adopt and the asset it
+// was found to be
+type adoptedFile struct {
+	Path  string
+	Asset github.Asset
+	// Verified is false for files matched by name and size only, because
+	// GitHub reports no digest for their asset
+	Verified bool
+}
+
+// Adopt records the files already in --dir that are assets of a release in
+// the download history, as if a run had downloaded them, so directories
+// filled by hand come under management. A file is matched by the digest
+// GitHub reports for an asset whatever its name, or by name and size when
+// GitHub reports none.
+func Adopt(cfg config.Config) error {
+	if cfg.Repository == "" {
+		return fmt.Errorf("usage: gh download adopt --dir <dir> <repository> [tag]")
+	}
+	cfg, restoreHost, err := useRepositoryHost(cfg)
+	if err != nil {
+		return err
+	}
+	defer restoreHost()
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	release, err := github.GetRelease(client, cfg.Repository, cfg.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to get release: %w", err)
+	}
+	assets, err := github.FilterAssets(release.Assets, cfg.Pattern)
+	if err != nil {
+		return fmt.Errorf("failed to filter assets: %w", err)
+	}
+
+	paths, err := adoptableFiles(cfg.Directory)
+	if err != nil {
+		return err
+	}
+	adopted, skipped, err := matchFiles(paths, assets)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range adopted {
+		var notes []string
+		if name := filepath.Base(file.Path); name != file.Asset.Name {
+			notes = append(notes, "asset "+file.Asset.Name)
+		}
+		if !file.Verified {
+			notes = append(notes, "matched by name and size only")
+		}
+		if len(notes) > 0 {
+			fmt.Printf("Adopted %s (%s)\n", file.Path, strings.Join(notes, ", "))
+		} else {
+			fmt.Printf("Adopted %s\n", file.Path)
+		}
+	}
+	for _, path := range skipped {
+		fmt.Printf("Skipped %s: not an asset of %s\n", path, release.TagName)
+	}
+	if len(adopted) == 0 {
+		return fmt.Errorf("%w: no file in %s is an asset of %s %s", errNoMatchingAssets, cfg.Directory, cfg.Repository, release.TagName)
+	}
+
+	result := runResult{Tag: release.TagName, Commit: release.CommitSHA, Assets: make(map[string]string, len(adopted))}
+	for _, file := range adopted {
+		result.Paths = append(result.Paths, file.Path)
+		result.Assets[file.Path] = file.Asset.Name
+	}
+	if err := appendHistory(cfg, result); err != nil {
+		return fmt.Errorf("failed to record download history: %w", err)
+	}
+	fmt.Printf("Recorded %d files of %s %s in the download history\n", len(adopted), cfg.Repository, release.TagName)
+	return nil
+}
+
+// adoptableFiles returns the regular files directly in dir, sorted, leaving
+// out hidden files such as the directory lock
+func adoptableFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// matchFiles finds the asset each file is. Only assets of the same size are
+// candidates, so most files are not hashed at all; of those, an asset with
+// a digest must have the digest of the file, and one without must have its
+// name. It returns the files matched and the others.
+func matchFiles(paths []string, assets []github.Asset) ([]adoptedFile, []string, error) {
+	var adopted []adoptedFile
+	var skipped []string
+	for _, path := range paths {
+		file, ok, err := matchFile(path, assets)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			adopted = append(adopted, file)
+		} else {
+			skipped = append(skipped, path)
+		}
+	}
+	return adopted, skipped, nil
+}
+
+// matchFile returns the asset the file at path is, preferring one with a
+// digest to one matched by name
+func matchFile(path string, assets []github.Asset) (adoptedFile, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return adoptedFile{}, false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+
+	// Digests of the file by algorithm, computed once each
+	digests := make(map[checksum.Algorithm]string)
+	var byName *github.Asset
+	for i, asset := range assets {
+		if int64(asset.Size) != info.Size() {
+			continue
+		}
+		algorithm, expected, ok := githubDigest(asset)
+		if !ok {
+			if byName == nil && asset.Name == filepath.Base(path) {
+				byName = &assets[i]
+			}
+			continue
+		}
+
+		actual, ok := digests[algorithm]
+		if !ok {
+			if actual, err = checksum.File(path, algorithm); err != nil {
+				return adoptedFile{}, false, err
+			}
+			digests[algorithm] = actual
+		}
+		if actual == expected {
+			return adoptedFile{Path: path, Asset: asset, Verified: true}, true, nil
+		}
+	}
+	if byName != nil {
+		return adoptedFile{Path: path, Asset: *byName}, true, nil
+	}
+	return adoptedFile{}, false, nil
+}
@@ -0,0 +1,77 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestAdoptableFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.tar.gz", "a.zip", dirLockName} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := adoptableFiles(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(paths) != 2 || paths[0] != filepath.Join(dir, "a.zip") || paths[1] != filepath.Join(dir, "b.tar.gz") {
+		t.Errorf("Expected a.zip and b.tar.gz, got %v", paths)
+	}
+}
+
+func TestMatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"renamed":      "hello\n",
+		"plain.txt":    "abc",
+		"other.txt":    "abc",
+		"modified.bin": "hellO\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// sha256 of "hello\n"
+	digest := "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	assets := []github.Asset{
+		{ID: 1, Name: "app.tar.gz", Size: 6, Digest: digest},
+		{ID: 2, Name: "plain.txt", Size: 3},
+		{ID: 3, Name: "modified.bin", Size: 6, Digest: digest},
+	}
+
+	paths, err := adoptableFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adopted, skipped, err := matchFiles(paths, assets)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	got := make(map[string]adoptedFile)
+	for _, file := range adopted {
+		got[filepath.Base(file.Path)] = file
+	}
+	if file, ok := got["renamed"]; !ok || file.Asset.ID != 1 || !file.Verified {
+		t.Errorf("Expected renamed to be app.tar.gz by its digest, got %+v", file)
+	}
+	if file, ok := got["plain.txt"]; !ok || file.Asset.ID != 2 || file.Verified {
+		t.Errorf("Expected plain.txt to be matched by name only, got %+v", file)
+	}
+	if len(adopted) != 2 {
+		t.Errorf("Expected 2 adopted files, got %+v", adopted)
+	}
+	if len(skipped) != 2 || filepath.Base(skipped[0]) != "modified.bin" || filepath.Base(skipped[1]) != "other.txt" {
+		t.Errorf("Expected modified.bin and other.txt to be skipped, got %v", skipped)
+	}
+}
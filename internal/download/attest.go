@@ -0,0 +1,57 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/23prime/gh-download/internal/attest"
+	"github.com/23prime/gh-download/internal/config"
+)
+
+// mirrorManifestName is the file attest-mirror writes into the mirror
+const mirrorManifestName = "mirror.intoto.json"
+
+// AttestMirror signs an in-toto statement over the digests of every file in
+// the mirror directory and writes it as a DSSE envelope into the directory,
+// so consumers can check that the mirrored files were not modified.
+func AttestMirror(cfg config.Config) error {
+	if cfg.Key == "" {
+		return fmt.Errorf("--key is required")
+	}
+
+	signer, err := attest.LoadSigner(cfg.Key)
+	if err != nil {
+		return err
+	}
+
+	subjects, err := attest.Subjects(cfg.Directory, func(name string) bool {
+		return name == mirrorManifestName || name == dirLockName
+	})
+	if err != nil {
+		return err
+	}
+	if len(subjects) == 0 {
+		return fmt.Errorf("no files to attest in %s", cfg.Directory)
+	}
+
+	statement := attest.NewStatement(subjects)
+	envelope, err := attest.Sign(statement, signer)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	path := filepath.Join(cfg.Directory, mirrorManifestName)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	fmt.Printf("Attested %d files in %s (root %s)\n", len(subjects), cfg.Directory, statement.Predicate.Root)
+	fmt.Printf("Wrote %s (key %s)\n", path, envelope.Signatures[0].KeyID)
+	return nil
+}
@@ -0,0 +1,63 @@
+package download
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/attest"
+	"github.com/23prime/gh-download/internal/config"
+)
+
+func TestAttestMirror(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tool.zip"), []byte("zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{Directory: dir, Key: keyPath}
+	// A second run must not attest the manifest of the first
+	for range 2 {
+		if err := AttestMirror(cfg); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, mirrorManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envelope attest.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	statement, err := attest.Verify(envelope, pub)
+	if err != nil {
+		t.Fatalf("Expected a valid signature, got %v", err)
+	}
+	if len(statement.Subject) != 1 || statement.Subject[0].Name != "tool.zip" {
+		t.Errorf("Expected only tool.zip to be attested, got %+v", statement.Subject)
+	}
+
+	if err := AttestMirror(config.Config{Directory: dir}); err == nil {
+		t.Error("Expected error without --key, got nil")
+	}
+}
@@ -0,0 +1,115 @@
+package download
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// auditUsage is a row of audit-report: what the serve proxy handed one
+// client of one repository in one month
+type auditUsage struct {
+	Month      string
+	Repository string
+	Client     string
+	Downloads  int
+	Bytes      int64
+}
+
+// AuditReport writes a CSV of the assets the serve proxy handed out, from
+// its audit log: for each month, repository and client the number of
+// downloads and the bytes sent, so the bandwidth and storage of a mirror can
+// be charged to the teams using it. Optionally only for one repository; with
+// --output the CSV is written to that file instead of stdout.
+func AuditReport(cfg config.Config) (err error) {
+	var entries []state.AuditEntry
+	err = withStore(func(store state.Store) error {
+		var err error
+		entries, err = store.ReadAudit()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	usage := aggregateAudit(entries, cfg.Repository)
+
+	if cfg.Output == "" {
+		return writeAuditReport(os.Stdout, usage)
+	}
+	file, err := os.Create(cfg.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", cfg.Output, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to write %s: %w", cfg.Output, closeErr)
+		}
+	}()
+	return writeAuditReport(file, usage)
+}
+
+// aggregateAudit sums the successful GET requests for assets by month in
+// UTC, repository and client, sorted in that order. Denied, failed and HEAD
+// requests cost no bandwidth worth billing and are left out, as are
+// requests for other repositories when repository is given.
+func aggregateAudit(entries []state.AuditEntry, repository string) []auditUsage {
+	type key struct{ month, repository, client string }
+	totals := make(map[key]*auditUsage)
+	for _, entry := range entries {
+		if entry.Method != http.MethodGet || entry.Status != http.StatusOK && entry.Status != http.StatusPartialContent {
+			continue
+		}
+		repo, _, _, ok := parseProxyPath(entry.Path)
+		if !ok || repository != "" && !strings.EqualFold(repo, repository) {
+			continue
+		}
+
+		k := key{entry.Time.UTC().Format("2006-01"), strings.ToLower(repo), entry.Client}
+		usage, ok := totals[k]
+		if !ok {
+			usage = &auditUsage{Month: k.month, Repository: k.repository, Client: k.client}
+			totals[k] = usage
+		}
+		usage.Downloads++
+		usage.Bytes += entry.Bytes
+	}
+
+	rows := make([]auditUsage, 0, len(totals))
+	for _, usage := range totals {
+		rows = append(rows, *usage)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Month != rows[j].Month {
+			return rows[i].Month < rows[j].Month
+		}
+		if rows[i].Repository != rows[j].Repository {
+			return rows[i].Repository < rows[j].Repository
+		}
+		return rows[i].Client < rows[j].Client
+	})
+	return rows
+}
+
+// writeAuditReport writes the rows as CSV with a header
+func writeAuditReport(w io.Writer, rows []auditUsage) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"month", "repository", "client", "downloads", "bytes"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{row.Month, row.Repository, row.Client, strconv.Itoa(row.Downloads), strconv.FormatInt(row.Bytes, 10)}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,41 @@
+package download
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/state"
+)
+
+func TestAggregateAudit(t *testing.T) {
+	march := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	april := time.Date(2026, 4, 1, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	entries := []state.AuditEntry{
+		{Time: march, Client: "web", Method: "GET", Path: "/owner/app/v1/app.tar.gz", Status: 200, Bytes: 100},
+		{Time: march, Client: "web", Method: "GET", Path: "/Owner/App/v1/app.zip", Status: 200, Bytes: 50},
+		{Time: march, Client: "ci", Method: "GET", Path: "/owner/app/v1/app.tar.gz", Status: 206, Bytes: 10},
+		{Time: march, Client: "ci", Method: "GET", Path: "/owner/app/v1/app.tar.gz", Status: 403},
+		{Time: march, Client: "ci", Method: "HEAD", Path: "/owner/app/v1/app.tar.gz", Status: 200},
+		{Time: march, Method: "GET", Path: "/owner", Status: 200},
+		// 2026-03-31T23:00 in UTC
+		{Time: april, Client: "web", Method: "GET", Path: "/owner/app/v1/app.tar.gz", Status: 200, Bytes: 1},
+		{Time: april.Add(2 * time.Hour), Client: "web", Method: "GET", Path: "/owner/lib/v2/lib.tar.gz", Status: 200, Bytes: 7},
+	}
+
+	var out bytes.Buffer
+	if err := writeAuditReport(&out, aggregateAudit(entries, "")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "month,repository,client,downloads,bytes\n" +
+		"2026-03,owner/app,ci,1,10\n" +
+		"2026-03,owner/app,web,3,151\n" +
+		"2026-04,owner/lib,web,1,7\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	if rows := aggregateAudit(entries, "OWNER/LIB"); len(rows) != 1 || rows[0].Repository != "owner/lib" {
+		t.Errorf("Expected only owner/lib, got %+v", rows)
+	}
+}
@@ -0,0 +1,35 @@
+package download
+
+import "fmt"
+
+// circuitBreaker stops a bulk run from trying a host once it failed limit
+// times in a row, so that one broken host does not use up the whole run. A
+// limit of zero never opens the circuit.
+type circuitBreaker struct {
+	limit       int
+	consecutive map[string]int
+}
+
+func newCircuitBreaker(limit int) *circuitBreaker {
+	return &circuitBreaker{limit: limit, consecutive: make(map[string]int)}
+}
+
+// check returns an error if the circuit of host is open
+func (b *circuitBreaker) check(host string) error {
+	if b.limit > 0 && b.consecutive[host] >= b.limit {
+		return fmt.Errorf("skipped after %d consecutive failures on %s", b.consecutive[host], host)
+	}
+	return nil
+}
+
+// record counts the outcome of an attempt on host
+func (b *circuitBreaker) record(host string, failed bool) {
+	if !failed {
+		b.consecutive[host] = 0
+		return
+	}
+	b.consecutive[host]++
+	if b.limit > 0 && b.consecutive[host] == b.limit {
+		fmt.Printf("Giving up on %s after %d consecutive failures\n", host, b.limit)
+	}
+}
@@ -0,0 +1,36 @@
+package download
+
+import "testing"
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := newCircuitBreaker(2)
+
+	breaker.record("ghe.example.com", true)
+	if err := breaker.check("ghe.example.com"); err != nil {
+		t.Errorf("Expected circuit to stay closed after one failure, got %v", err)
+	}
+
+	breaker.record("ghe.example.com", false)
+	breaker.record("ghe.example.com", true)
+	if err := breaker.check("ghe.example.com"); err != nil {
+		t.Errorf("Expected a success to reset the count, got %v", err)
+	}
+
+	breaker.record("ghe.example.com", true)
+	if err := breaker.check("ghe.example.com"); err == nil {
+		t.Error("Expected circuit to open after 2 consecutive failures, got nil")
+	}
+	if err := breaker.check("github.com"); err != nil {
+		t.Errorf("Expected other hosts to be unaffected, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	breaker := newCircuitBreaker(0)
+	for range 5 {
+		breaker.record("github.com", true)
+	}
+	if err := breaker.check("github.com"); err != nil {
+		t.Errorf("Expected a zero limit to never open the circuit, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package download
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/23prime/gh-download/internal/browse"
+	"github.com/23prime/gh-download/internal/clipboard"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// Browse shows the releases of a repository full screen, with the assets
+// matching --pattern and the notes of the selected one, and downloads the
+// assets marked there as a run with the other flags would, with its
+// progress bars once the browser is closed. The c key copies the URL of an
+// asset instead.
+func Browse(cfg config.Config) error {
+	if cfg.Repository == "" {
+		return fmt.Errorf("usage: gh download browse <repository> [flags]")
+	}
+	if cfg.CopyURL {
+		return fmt.Errorf("browse copies the URL of the asset under the cursor with c; --copy-url is for --list")
+	}
+	if !term.IsTerminal(os.Stdin) || !term.IsTerminal(os.Stdout) {
+		return fmt.Errorf("browse needs a terminal; use --releases and --list to look at releases from scripts")
+	}
+	run := cfg
+	cfg, restoreHost, err := useRepositoryHost(cfg)
+	if err != nil {
+		return err
+	}
+	defer restoreHost()
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	releases, err := github.GetReleases(client, cfg.Repository)
+	if err != nil {
+		return fmt.Errorf("failed to get releases: %w", err)
+	}
+	if releases, err = browsableReleases(releases, cfg.Pattern); err != nil {
+		return err
+	}
+
+	model := browse.New(cfg.Repository, releases)
+	model.Copy = func(text string) (string, error) {
+		return clipboard.Copy(text, os.Stdout)
+	}
+	selection, err := browse.Run(os.Stdin, os.Stdout, model)
+	if err != nil || selection == nil {
+		return err
+	}
+
+	// The assets chosen are passed on like those a resumed run has left
+	token, err := resumeToken{Repository: cfg.Repository, Tag: selection.Tag, Assets: selection.Assets}.encode()
+	if err != nil {
+		return err
+	}
+	run.Command = ""
+	run.Tag = selection.Tag
+	run.Resume = token
+	return DownloadFromRelease(run)
+}
+
+// browsableReleases leaves out drafts, which cannot be downloaded by tag,
+// and the assets not matching pattern
+func browsableReleases(releases []github.Release, pattern string) ([]github.Release, error) {
+	var browsable []github.Release
+	for _, release := range releases {
+		if release.Draft {
+			continue
+		}
+		assets, err := github.FilterAssets(release.Assets, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter assets: %w", err)
+		}
+		release.Assets = assets
+		browsable = append(browsable, release)
+	}
+	return browsable, nil
+}
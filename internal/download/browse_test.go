@@ -0,0 +1,24 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestBrowsableReleases(t *testing.T) {
+	releases := []github.Release{
+		{TagName: "v2.0.0", Draft: true, Assets: []github.Asset{{Name: "app.tar.gz"}}},
+		{TagName: "v1.0.0", Assets: []github.Asset{{Name: "app.tar.gz"}, {Name: "checksums.txt"}}},
+	}
+	browsable, err := browsableReleases(releases, "*.tar.gz")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(browsable) != 1 || browsable[0].TagName != "v1.0.0" || len(browsable[0].Assets) != 1 {
+		t.Errorf("Expected v1.0.0 with app.tar.gz only, got %+v", browsable)
+	}
+	if len(releases[1].Assets) != 2 {
+		t.Error("Expected the releases given to be left alone")
+	}
+}
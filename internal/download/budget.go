@@ -0,0 +1,78 @@
+package download
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// resumeToken records the work a run left undone when --max-duration ran
+// out. It is printed as an opaque string for --resume.
+type resumeToken struct {
+	// Repository and Tag pin the release of a single repository run
+	Repository string `json:"repository,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+	// Assets are the names of the assets left undone
+	Assets []string `json:"assets,omitempty"`
+	// Repositories are the repositories of a --stdin run left undone
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+func (t resumeToken) encode() (string, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeResumeToken parses a --resume value; an empty value is a zero token
+func decodeResumeToken(s string) (resumeToken, error) {
+	var token resumeToken
+	if s == "" {
+		return token, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, fmt.Errorf("invalid resume token: %w", err)
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return token, fmt.Errorf("invalid resume token: %w", err)
+	}
+	return token, nil
+}
+
+// BudgetError reports that --max-duration ran out before every download was
+// started. Downloads in flight were finished; Undone lists the rest.
+type BudgetError struct {
+	Undone []string
+	Token  string
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("time budget exhausted with %d left undone; resume with --resume %s", len(e.Undone), e.Token)
+}
+
+// newBudgetError reports the work left undone and returns a *BudgetError
+// resuming it
+func newBudgetError(undone []string, token resumeToken) error {
+	encoded, err := token.encode()
+	if err != nil {
+		return fmt.Errorf("failed to create resume token: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nTime budget exhausted, %d left undone:\n", len(undone))
+	for _, name := range undone {
+		fmt.Fprintf(os.Stderr, "  - %s\n", name)
+	}
+	return &BudgetError{Undone: undone, Token: encoded}
+}
+
+// expired reports whether the --max-duration deadline has passed; a zero
+// deadline never expires
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
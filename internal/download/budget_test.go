@@ -0,0 +1,81 @@
+package download
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestResumeToken_RoundTrip(t *testing.T) {
+	token := resumeToken{Repository: "owner/repo", Tag: "v1.0.0", Assets: []string{"a.zip", "b.tar.gz"}}
+
+	encoded, err := token.encode()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	decoded, err := decodeResumeToken(encoded)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded.Repository != token.Repository || decoded.Tag != token.Tag || !slices.Equal(decoded.Assets, token.Assets) {
+		t.Errorf("Expected %+v, got %+v", token, decoded)
+	}
+
+	if _, err := decodeResumeToken("not a token!"); err == nil {
+		t.Error("Expected error for an invalid token, got nil")
+	}
+	if empty, err := decodeResumeToken(""); err != nil || empty.Assets != nil {
+		t.Errorf("Expected a zero token, got %+v %v", empty, err)
+	}
+}
+
+func TestAssetRun_DeadlineLeavesRestUndone(t *testing.T) {
+	run := newAssetRun(3, false)
+	run.deadline = time.Now().Add(-time.Second)
+
+	called := 0
+	assets := []github.Asset{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if err := run.each(assets, func(github.Asset) error {
+		called++
+		return nil
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if called != 0 {
+		t.Errorf("Expected no asset to start, got %d", called)
+	}
+	if !slices.Equal(run.undone, []string{"a", "b", "c"}) {
+		t.Errorf("Expected every asset undone, got %v", run.undone)
+	}
+}
+
+func TestDownloadFromRepositories_BudgetExhausted(t *testing.T) {
+	encoded, err := resumeToken{Repositories: []string{"owner/one", "owner/two"}}.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Config{Resume: encoded, Deadline: time.Now().Add(-time.Second)}
+
+	_, err = downloadFromRepositories(cfg, strings.NewReader(""))
+	var berr *BudgetError
+	if !errors.As(err, &berr) {
+		t.Fatalf("Expected BudgetError, got %v", err)
+	}
+	if !slices.Equal(berr.Undone, []string{"owner/one", "owner/two"}) {
+		t.Errorf("Expected the resumed repositories undone, got %v", berr.Undone)
+	}
+	if ExitCode(err) != ExitBudgetExhausted {
+		t.Errorf("Expected exit code %d, got %d", ExitBudgetExhausted, ExitCode(err))
+	}
+
+	resumed, err := decodeResumeToken(berr.Token)
+	if err != nil || !slices.Equal(resumed.Repositories, berr.Undone) {
+		t.Errorf("Expected the token to resume %v, got %+v %v", berr.Undone, resumed, err)
+	}
+}
@@ -0,0 +1,228 @@
+package download
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/cas"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/log"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// Directories of a cache bundle: objects holds the assets by digest and
+// metadata the cached release metadata, one JSON file per cache key
+const (
+	bundleObjects  = "objects/sha256/"
+	bundleMetadata = "metadata/"
+)
+
+// bundleEntry is a cache entry in a bundle
+type bundleEntry struct {
+	Fetched time.Time       `json:"fetched"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// assetSHA256 returns the SHA-256 digest GitHub reports for an asset, or
+// empty when it reports none
+func assetSHA256(asset github.Asset) string {
+	digest, ok := strings.CutPrefix(asset.Digest, "sha256:")
+	if !ok || !cas.ValidDigest(digest) {
+		return ""
+	}
+	return digest
+}
+
+// cacheAsset stores a downloaded asset in the content-addressable cache when
+// GitHub reports its digest. The cache is an optimization, so failing to
+// store it only warns.
+func cacheAsset(objects *cas.Store, asset github.Asset, path string) {
+	digest := assetSHA256(asset)
+	if objects == nil || digest == "" || objects.Has(digest) {
+		return
+	}
+	if err := objects.Put(digest, path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache %s: %v\n", asset.Name, err)
+		return
+	}
+	log.Debugf("%s: added to the cache (sha256:%s)", asset.Name, digest)
+}
+
+// Cache moves the cache between machines: "export" writes the cached assets
+// and release metadata to a tar bundle, and "import" adds those of a bundle,
+// e.g. to install offline in an air-gapped network with --cache and
+// --stale-ok.
+func Cache(cfg config.Config) error {
+	args := positionalArgs(cfg)
+	if len(args) != 2 || (args[0] != "export" && args[0] != "import") {
+		return fmt.Errorf("usage: gh download cache export|import <bundle.tar>")
+	}
+
+	objects := cas.Store{Root: cas.Dir()}
+	return withStore(func(store state.Store) error {
+		if args[0] == "export" {
+			return exportCache(args[1], objects, store)
+		}
+		return importCache(args[1], objects, store)
+	})
+}
+
+func exportCache(bundle string, objects cas.Store, store state.Store) (err error) {
+	digests, err := objects.Objects()
+	if err != nil {
+		return err
+	}
+	keys, err := store.CacheKeys()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", bundle, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	tw := tar.NewWriter(file)
+	for _, digest := range digests {
+		if err := addBundleFile(tw, bundleObjects+digest, objects.Path(digest)); err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		var entry bundleEntry
+		if entry.Fetched, err = store.ReadCache(key, &entry.Value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: bundleMetadata + key + ".json", Mode: 0644, Size: int64(len(data)), ModTime: entry.Fetched}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", bundle, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", bundle, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", bundle, err)
+	}
+
+	fmt.Printf("Exported %d assets and %d metadata entries to %s\n", len(digests), len(keys), bundle)
+	return nil
+}
+
+func addBundleFile(tw *tar.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", path, closeErr)
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	return nil
+}
+
+// importCache adds the assets and metadata of a bundle. Every asset is
+// verified against its digest, so a corrupted or tampered bundle cannot
+// poison the cache; metadata replaces older entries with the same key only.
+func importCache(bundle string, objects cas.Store, store state.Store) error {
+	file, err := os.Open(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", bundle, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", bundle, closeErr)
+		}
+	}()
+
+	var assets, entries int
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", bundle, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(header.Name, bundleObjects):
+			digest := strings.TrimPrefix(header.Name, bundleObjects)
+			if objects.Has(digest) {
+				continue
+			}
+			if err := objects.Add(digest, tr); err != nil {
+				return fmt.Errorf("failed to import %s: %w", header.Name, err)
+			}
+			assets++
+		case strings.HasPrefix(header.Name, bundleMetadata) && strings.HasSuffix(header.Name, ".json"):
+			key := strings.TrimSuffix(strings.TrimPrefix(header.Name, bundleMetadata), ".json")
+			if key == "" || path.Clean(key) != key || strings.HasPrefix(key, "../") || strings.HasPrefix(key, "/") {
+				return fmt.Errorf("invalid metadata entry %s in %s", header.Name, bundle)
+			}
+			imported, err := importCacheEntry(store, key, tr)
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", header.Name, err)
+			}
+			if imported {
+				entries++
+			}
+		default:
+			return fmt.Errorf("unexpected entry %s in %s", header.Name, bundle)
+		}
+	}
+
+	fmt.Printf("Imported %d assets and %d metadata entries from %s\n", assets, entries, bundle)
+	return nil
+}
+
+func importCacheEntry(store state.Store, key string, r io.Reader) (bool, error) {
+	var entry bundleEntry
+	if err := json.NewDecoder(r).Decode(&entry); err != nil {
+		return false, err
+	}
+
+	var current json.RawMessage
+	fetched, err := store.ReadCache(key, &current)
+	if err != nil && !errors.Is(err, state.ErrNotCached) {
+		return false, err
+	}
+	if err == nil && !fetched.Before(entry.Fetched) {
+		return false, nil
+	}
+	return true, store.WriteCache(key, entry.Value, entry.Fetched)
+}
@@ -0,0 +1,145 @@
+package download
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/cas"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAssetSHA256(t *testing.T) {
+	digest := sha256Hex("content")
+	testCases := map[string]string{
+		"sha256:" + digest: digest,
+		"sha512:" + digest: "",
+		"sha256:abc":       "",
+		"":                 "",
+	}
+	for value, expected := range testCases {
+		if got := assetSHA256(github.Asset{Digest: value}); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, value, got)
+		}
+	}
+}
+
+func TestCacheAsset(t *testing.T) {
+	objects := &cas.Store{Root: t.TempDir()}
+	path := filepath.Join(t.TempDir(), "tool.tar.gz")
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cacheAsset(objects, github.Asset{Name: "tool.tar.gz"}, path)
+	if digests, _ := objects.Objects(); len(digests) != 0 {
+		t.Errorf("Expected assets without a digest not to be cached, got %v", digests)
+	}
+
+	cacheAsset(objects, github.Asset{Name: "tool.tar.gz", Digest: "sha256:" + sha256Hex("content")}, path)
+	if !objects.Has(sha256Hex("content")) {
+		t.Error("Expected the asset to be cached")
+	}
+
+	cacheAsset(nil, github.Asset{Digest: "sha256:" + sha256Hex("content")}, path)
+}
+
+func TestExportImportCache(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	source := cas.Store{Root: t.TempDir()}
+	if err := source.Add(sha256Hex("asset"), strings.NewReader("asset")); err != nil {
+		t.Fatal(err)
+	}
+	store, err := state.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.WriteCache("releases/owner/repo/latest", github.Release{TagName: "v1.0.0"}, fetched); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar")
+	captureStdout(t, func() {
+		if err := exportCache(bundle, source, store); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Setenv(state.DirEnv, t.TempDir())
+	target := cas.Store{Root: t.TempDir()}
+	output := captureStdout(t, func() {
+		if err := importCache(bundle, target, store); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "Imported 1 assets and 1 metadata entries") {
+		t.Errorf("Unexpected output %q", output)
+	}
+	if !target.Has(sha256Hex("asset")) {
+		t.Error("Expected the asset to be imported")
+	}
+	var release github.Release
+	got, err := store.ReadCache("releases/owner/repo/latest", &release)
+	if err != nil || !got.Equal(fetched) || release.TagName != "v1.0.0" {
+		t.Errorf("Unexpected imported metadata %+v at %v: %v", release, got, err)
+	}
+
+	output = captureStdout(t, func() {
+		if err := importCache(bundle, target, store); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "Imported 0 assets and 0 metadata entries") {
+		t.Errorf("Expected a second import to change nothing, got %q", output)
+	}
+}
+
+func TestImportCache_Tampered(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	store, err := state.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]string{
+		bundleObjects + sha256Hex("asset"):   "tampered",
+		bundleMetadata + "../../escape.json": "{}",
+		"other/file":                         "",
+	}
+	for name, content := range testCases {
+		bundle := filepath.Join(t.TempDir(), "bundle.tar")
+		file, err := os.Create(bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw := tar.NewWriter(file)
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := file.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := importCache(bundle, cas.Store{Root: t.TempDir()}, store); err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package download
+
+import (
+	"fmt"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// CheckError reports that --dir does not hold the requested release files.
+// It maps to exit code 1, so configuration management tools can tell
+// "would change" apart from success.
+type CheckError struct {
+	Mismatched []string
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("%d files are missing or out of date", len(e.Mismatched))
+}
+
+// checkDirectory reports whether --dir already holds every matching asset
+// with the size and digest GitHub reports for it, without downloading
+// anything, for --check
+func checkDirectory(cfg config.Config, release *github.Release) error {
+	if cfg.Archive != "" {
+		return fmt.Errorf("--check does not support --archive")
+	}
+
+	matchingAssets, err := github.FilterAssets(release.Assets, cfg.Pattern)
+	if err != nil {
+		return fmt.Errorf("failed to filter assets: %w", err)
+	}
+	if len(matchingAssets) == 0 {
+		return fmt.Errorf("%w matching pattern '%s'", errNoMatchingAssets, cfg.Pattern)
+	}
+
+	algorithm, err := checksumAlgorithm(cfg)
+	if err != nil {
+		return err
+	}
+	checksums, err := loadChecksums(cfg, release, algorithm)
+	if err != nil {
+		return err
+	}
+
+	checks := checkAssets(matchingAssets, cfg.Directory, checksums)
+	if reportChecks(checks) != nil {
+		var mismatched []string
+		for _, check := range checks {
+			if check.Status == verifyFail || check.Status == verifyMissing {
+				mismatched = append(mismatched, check.Asset.Name)
+			}
+		}
+		return &CheckError{Mismatched: mismatched}
+	}
+	fmt.Printf("%s is up to date with %d assets\n", cfg.Directory, len(matchingAssets))
+	return nil
+}
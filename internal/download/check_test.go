@@ -0,0 +1,50 @@
+package download
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestCheckDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest := "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	cfg := config.Config{Directory: dir, Pattern: "*.txt", Check: true}
+
+	release := &github.Release{Assets: []github.Asset{
+		{ID: 1, Name: "app.txt", Size: 6, Digest: digest},
+		{ID: 2, Name: "app.zip", Size: 100},
+	}}
+	if err := checkDirectory(cfg, release); err != nil {
+		t.Errorf("Expected the directory to be up to date, got %v", err)
+	}
+
+	release.Assets = append(release.Assets, github.Asset{ID: 3, Name: "notes.txt", Size: 10})
+	err := checkDirectory(cfg, release)
+	var cerr *CheckError
+	if !errors.As(err, &cerr) || len(cerr.Mismatched) != 1 || cerr.Mismatched[0] != "notes.txt" {
+		t.Fatalf("Expected notes.txt to be reported missing, got %v", err)
+	}
+	if code := ExitCode(err); code != ExitError {
+		t.Errorf("Expected exit code %d, got %d", ExitError, code)
+	}
+
+	release.Assets[0].Size = 7
+	release.Assets = release.Assets[:2]
+	if err := checkDirectory(cfg, release); !errors.As(err, &cerr) {
+		t.Errorf("Expected a size mismatch to be reported, got %v", err)
+	}
+}
+
+func TestCheckDirectory_Archive(t *testing.T) {
+	if err := checkDirectory(config.Config{Archive: "zip", Check: true}, &github.Release{}); err == nil {
+		t.Error("Expected an error for --archive, got nil")
+	}
+}
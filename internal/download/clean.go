@@ -0,0 +1,126 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+// partSuffix marks files still being downloaded
+const partSuffix = ".part"
+
+// isTemporaryFile reports whether a file name is one of the temporary files
+// runs leave behind when they crash: partial downloads, ".tmp" files and
+// the temporary files of LFS downloads
+func isTemporaryFile(name string) bool {
+	return strings.HasSuffix(name, partSuffix) || strings.HasSuffix(name, ".tmp") || strings.HasPrefix(name, ".lfs-")
+}
+
+// resumeOffset returns the size of a partial download to resume from, or 0
+// to start over when there is none or it cannot belong to an asset of size
+func resumeOffset(part string, size int64) int64 {
+	info, err := os.Stat(part)
+	if err != nil || !info.Mode().IsRegular() || info.Size() >= size {
+		return 0
+	}
+	return info.Size()
+}
+
+// staleFiles returns the temporary files under dir that were not modified
+// for staleAfter. Files still being written are modified all the time, so
+// only those of crashed runs are returned. With recursive unset only dir
+// itself is scanned.
+func staleFiles(dir string, staleAfter time.Duration, recursive bool) ([]string, error) {
+	cutoff := time.Now().Add(-staleAfter)
+
+	var stale []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !isTemporaryFile(entry.Name()) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(cutoff) {
+			stale = append(stale, path)
+		}
+		return nil
+	})
+	return stale, err
+}
+
+// removeStaleFiles removes the stale temporary files in dir left by crashed
+// runs before a new run starts, except the partial downloads of the files in
+// keep, which the run resumes
+func removeStaleFiles(dir string, staleAfter time.Duration, keep map[int]string) error {
+	stale, err := staleFiles(dir, staleAfter, false)
+	if err != nil {
+		return fmt.Errorf("failed to scan %s for stale files: %w", dir, err)
+	}
+
+	resumable := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		resumable[filepath.Base(name)+partSuffix] = true
+	}
+	for _, path := range stale {
+		if resumable[filepath.Base(path)] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale file %s: %w", path, err)
+		}
+		fmt.Printf("Removed stale %s\n", path)
+	}
+	return nil
+}
+
+// Clean removes the temporary files crashed runs left anywhere under --dir
+// that were not modified for --stale-after. It holds the lock on --dir, so
+// it never removes the files of a run in progress.
+func Clean(cfg config.Config) error {
+	if _, err := os.Stat(cfg.Directory); err != nil {
+		return fmt.Errorf("failed to scan %s: %w", cfg.Directory, err)
+	}
+	unlock, err := lockDirectory(cfg)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stale, err := staleFiles(cfg.Directory, cfg.StaleAfter, true)
+	if err != nil {
+		return fmt.Errorf("failed to scan %s: %w", cfg.Directory, err)
+	}
+	if len(stale) == 0 {
+		fmt.Printf("No stale files in %s\n", cfg.Directory)
+		return nil
+	}
+
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		fmt.Printf("Removed %s\n", path)
+	}
+	fmt.Printf("Removed %d stale files from %s\n", len(stale), cfg.Directory)
+	return nil
+}
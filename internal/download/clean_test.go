@@ -0,0 +1,114 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+// writeAged writes a file and sets its modification time age into the past
+func writeAged(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsTemporaryFile(t *testing.T) {
+	for _, name := range []string{"app.tar.gz.part", "index.tmp", ".lfs-123456"} {
+		if !isTemporaryFile(name) {
+			t.Errorf("Expected %q to be temporary", name)
+		}
+	}
+	for _, name := range []string{"app.tar.gz", "partition.img", "tmp"} {
+		if isTemporaryFile(name) {
+			t.Errorf("Expected %q not to be temporary", name)
+		}
+	}
+}
+
+func TestResumeOffset(t *testing.T) {
+	part := filepath.Join(t.TempDir(), "app.bin.part")
+	if got := resumeOffset(part, 100); got != 0 {
+		t.Errorf("Expected 0 without a partial file, got %d", got)
+	}
+
+	writeAged(t, part, 0)
+	if got := resumeOffset(part, 100); got != int64(len("partial")) {
+		t.Errorf("Expected %d, got %d", len("partial"), got)
+	}
+	if got := resumeOffset(part, 3); got != 0 {
+		t.Errorf("Expected 0 for a partial file larger than the asset, got %d", got)
+	}
+}
+
+func TestStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeAged(t, filepath.Join(dir, "old.bin.part"), 2*time.Hour)
+	writeAged(t, filepath.Join(dir, "new.bin.part"), time.Minute)
+	writeAged(t, filepath.Join(dir, "app.bin"), 2*time.Hour)
+	writeAged(t, filepath.Join(dir, "sub", "nested.tmp"), 2*time.Hour)
+
+	stale, err := staleFiles(dir, time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stale, []string{filepath.Join(dir, "old.bin.part")}) {
+		t.Errorf("Unexpected stale files %v", stale)
+	}
+
+	stale, err = staleFiles(dir, time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 2 {
+		t.Errorf("Expected nested stale files with recursion, got %v", stale)
+	}
+
+	if stale, err := staleFiles(filepath.Join(dir, "missing"), time.Hour, true); err != nil || len(stale) != 0 {
+		t.Errorf("Expected nothing for a missing directory, got %v and %v", stale, err)
+	}
+}
+
+func TestRemoveStaleFiles_KeepsResumable(t *testing.T) {
+	dir := t.TempDir()
+	writeAged(t, filepath.Join(dir, "app.bin.part"), 2*time.Hour)
+	writeAged(t, filepath.Join(dir, "gone.bin.part"), 2*time.Hour)
+
+	if err := removeStaleFiles(dir, time.Hour, map[int]string{1: "app.bin"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.bin.part")); err != nil {
+		t.Errorf("Expected the partial file of an asset in the run to be kept, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gone.bin.part")); !os.IsNotExist(err) {
+		t.Errorf("Expected the orphaned partial file to be removed, got %v", err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+	writeAged(t, filepath.Join(dir, "sub", "old.zip.part"), 2*time.Hour)
+	writeAged(t, filepath.Join(dir, "new.zip.part"), time.Minute)
+
+	if err := Clean(config.Config{Directory: dir, StaleAfter: time.Hour}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "old.zip.part")); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.zip.part")); err != nil {
+		t.Errorf("Expected the recent file to be kept, got %v", err)
+	}
+}
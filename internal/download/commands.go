@@ -0,0 +1,146 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// Downloaders supported by --emit-commands
+const (
+	DownloaderAria2 = "aria2"
+	DownloaderCurl  = "curl"
+	DownloaderWget  = "wget"
+)
+
+// tokenHeader authenticates emitted commands without printing the token:
+// the shell expands it when the command runs
+const tokenHeader = `"Authorization: Bearer $GH_TOKEN"`
+
+// transfer is a file an emitted command downloads
+type transfer struct {
+	url     string
+	name    string
+	headers []string
+}
+
+// emitCommands returns shell commands that download the matching assets, or
+// the source archive with --archive, with the given downloader. API URLs
+// are used with the token taken from $GH_TOKEN; with --signed the commands
+// use pre-authorized URLs and need no headers.
+func emitCommands(cfg config.Config, release *github.Release) ([]string, error) {
+	switch cfg.EmitCommands {
+	case DownloaderAria2, DownloaderCurl, DownloaderWget:
+	default:
+		return nil, fmt.Errorf("invalid downloader '%s': must be aria2, curl or wget", cfg.EmitCommands)
+	}
+
+	transfers, err := commandTransfers(cfg, release)
+	if err != nil {
+		return nil, err
+	}
+
+	var commands []string
+	if cfg.EmitCommands == DownloaderWget {
+		commands = append(commands, "mkdir -p "+shellQuote(cfg.Directory))
+	}
+	for _, t := range transfers {
+		commands = append(commands, downloaderCommand(cfg.EmitCommands, t, cfg.Directory))
+	}
+	return commands, nil
+}
+
+func commandTransfers(cfg config.Config, release *github.Release) ([]transfer, error) {
+	var redirects *http.Client
+	if cfg.SignedURLs {
+		client, err := newRedirectClient()
+		if err != nil {
+			return nil, err
+		}
+		redirects = client
+	}
+
+	if cfg.Archive != "" {
+		endpoint, filename, err := archiveEndpoint(cfg.Repository, cfg.Tag, cfg.Archive)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := repository.Parse(cfg.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository format: %w", err)
+		}
+
+		t := transfer{url: apiURL(parsed.Host, endpoint), name: archiveFileName(filename, archiveCommit(cfg, release)), headers: []string{tokenHeader}}
+		if redirects != nil {
+			if t.url, err = redirectLocation(redirects, t.url); err != nil {
+				return nil, err
+			}
+			t.headers = nil
+		}
+		return []transfer{t}, nil
+	}
+
+	matchingAssets, err := github.FilterAssets(release.Assets, cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter assets: %w", err)
+	}
+	if len(matchingAssets) == 0 {
+		return nil, fmt.Errorf("%w matching pattern '%s'", errNoMatchingAssets, cfg.Pattern)
+	}
+
+	matchingAssets, err = orderAssets(matchingAssets, cfg.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	fileNames := assetFileNames(matchingAssets)
+	transfers := make([]transfer, 0, len(matchingAssets))
+	for _, asset := range matchingAssets {
+		t := transfer{
+			url:     asset.URL,
+			name:    fileNames[asset.ID],
+			headers: []string{shellQuote("Accept: application/octet-stream"), tokenHeader},
+		}
+		if redirects != nil {
+			if t.url, err = redirectLocation(redirects, asset.URL); err != nil {
+				return nil, fmt.Errorf("failed to resolve URL of %s: %w", asset.Name, err)
+			}
+			t.headers = nil
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
+
+// downloaderCommand renders one transfer for a downloader. Headers are
+// already quoted for the shell.
+func downloaderCommand(downloader string, t transfer, dir string) string {
+	var args []string
+	switch downloader {
+	case DownloaderAria2:
+		args = append(args, "aria2c")
+		for _, header := range t.headers {
+			args = append(args, "--header", header)
+		}
+		args = append(args, "--dir", shellQuote(dir), "--out", shellQuote(t.name))
+	case DownloaderCurl:
+		args = append(args, "curl", "--fail", "--location")
+		for _, header := range t.headers {
+			args = append(args, "--header", header)
+		}
+		args = append(args, "--create-dirs", "--output", shellQuote(filepath.Join(dir, t.name)))
+	case DownloaderWget:
+		args = append(args, "wget")
+		for _, header := range t.headers {
+			args = append(args, "--header", header)
+		}
+		args = append(args, "--output-document", shellQuote(filepath.Join(dir, t.name)))
+	}
+	args = append(args, shellQuote(t.url))
+	return strings.Join(args, " ")
+}
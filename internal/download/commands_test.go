@@ -0,0 +1,73 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestDownloaderCommand(t *testing.T) {
+	api := transfer{
+		url:     "https://api.github.com/repos/owner/repo/releases/assets/1",
+		name:    "tool v1.tar.gz",
+		headers: []string{shellQuote("Accept: application/octet-stream"), tokenHeader},
+	}
+	signed := transfer{url: "https://objects.example.com/signed?token=abc&x=1", name: "tool.zip"}
+
+	testCases := []struct {
+		downloader string
+		transfer   transfer
+		expected   string
+	}{
+		{
+			DownloaderAria2, api,
+			`aria2c --header 'Accept: application/octet-stream' --header "Authorization: Bearer $GH_TOKEN" --dir out --out 'tool v1.tar.gz' https://api.github.com/repos/owner/repo/releases/assets/1`,
+		},
+		{
+			DownloaderCurl, api,
+			`curl --fail --location --header 'Accept: application/octet-stream' --header "Authorization: Bearer $GH_TOKEN" --create-dirs --output 'out/tool v1.tar.gz' https://api.github.com/repos/owner/repo/releases/assets/1`,
+		},
+		{
+			DownloaderWget, signed,
+			`wget --output-document out/tool.zip 'https://objects.example.com/signed?token=abc&x=1'`,
+		},
+	}
+
+	for _, tc := range testCases {
+		got := downloaderCommand(tc.downloader, tc.transfer, "out")
+		if got != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, got)
+		}
+	}
+}
+
+func TestEmitCommands(t *testing.T) {
+	release := &github.Release{Assets: []github.Asset{
+		{ID: 2, Name: "tool.tar.gz", URL: "https://api.github.com/repos/owner/repo/releases/assets/2"},
+		{ID: 1, Name: "tool.zip", URL: "https://api.github.com/repos/owner/repo/releases/assets/1"},
+	}}
+	cfg := config.Config{Repository: "owner/repo", Pattern: "*.tar.gz", Directory: "out dir", EmitCommands: DownloaderWget}
+
+	commands, err := emitCommands(cfg, release)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("Expected 2 commands, got %v", commands)
+	}
+	if commands[0] != "mkdir -p 'out dir'" {
+		t.Errorf("Expected mkdir first, got %s", commands[0])
+	}
+
+	cfg.EmitCommands = "axel"
+	if _, err := emitCommands(cfg, release); err == nil {
+		t.Error("Expected error for an unknown downloader, got nil")
+	}
+
+	cfg.EmitCommands = DownloaderCurl
+	cfg.Pattern = "*.deb"
+	if _, err := emitCommands(cfg, release); err == nil {
+		t.Error("Expected error without matching assets, got nil")
+	}
+}
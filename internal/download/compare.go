@@ -0,0 +1,60 @@
+package download
+
+import (
+	"fmt"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/render"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// Compare prints the upgrade impact between two releases: asset changes and,
+// on request, the commits and changed files between their tags. With
+// --format only the asset changes are printed, unchanged ones included.
+func Compare(cfg config.Config) error {
+	if cfg.Repository == "" {
+		return fmt.Errorf("repository is required")
+	}
+
+	var head string
+	if len(cfg.Args) > 0 {
+		head = cfg.Args[0]
+	}
+
+	renderer, err := outputRenderer(cfg)
+	if err != nil {
+		return err
+	}
+	if renderer != nil && (cfg.Commits || cfg.Files) {
+		return fmt.Errorf("--format and --json cannot be used with --commits or --files")
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+
+	if renderer != nil {
+		return renderAssetChanges(client, cfg, renderer, head)
+	}
+	return github.CompareReleases(client, cfg.Repository, cfg.Tag, head, cfg.Commits, cfg.Files)
+}
+
+// renderAssetChanges prints the asset changes between two releases in the
+// format of renderer
+func renderAssetChanges(client github.HTTPClient, cfg config.Config, renderer *render.Renderer, head string) error {
+	repo, base := cfg.Repository, cfg.Tag
+	if base == "" || head == "" {
+		return fmt.Errorf("compare requires a base and a head tag")
+	}
+	baseRelease, err := github.GetRelease(client, repo, base)
+	if err != nil {
+		return fmt.Errorf("failed to get release %s: %w", base, err)
+	}
+	headRelease, err := github.GetRelease(client, repo, head)
+	if err != nil {
+		return fmt.Errorf("failed to get release %s: %w", head, err)
+	}
+	return renderRecords(cfg, renderer, assetChangeTable(github.DiffAssets(baseRelease.Assets, headRelease.Assets, base, head)), github.AssetChange{})
+}
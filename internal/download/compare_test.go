@@ -0,0 +1,113 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/render"
+)
+
+func TestRenderAssetChanges(t *testing.T) {
+	client := jsonClient{
+		"repos/owner/repo/releases/tags/v1.0.0": `{"tag_name":"v1.0.0","assets":[
+			{"name":"app-1.0.0.tar.gz","size":10},
+			{"name":"checksums.txt","size":5},
+			{"name":"old.txt","size":1}]}`,
+		"repos/owner/repo/releases/tags/v1.1.0": `{"tag_name":"v1.1.0","assets":[
+			{"name":"app-1.1.0.tar.gz","size":12},
+			{"name":"checksums.txt","size":5},
+			{"name":"new.txt","size":2}]}`,
+	}
+
+	testCases := []struct {
+		name     string
+		cfg      config.Config
+		format   string
+		head     string
+		expected string
+		err      string
+	}{
+		{
+			name:   "csv",
+			cfg:    config.Config{Repository: "owner/repo", Tag: "v1.0.0"},
+			format: render.FormatCSV,
+			head:   "v1.1.0",
+			expected: "kind,key,base,head,base_size,head_size\n" +
+				"changed,app-{version}.tar.gz,app-1.0.0.tar.gz,app-1.1.0.tar.gz,10,12\n" +
+				"unchanged,checksums.txt,checksums.txt,checksums.txt,5,5\n" +
+				"added,new.txt,,new.txt,,2\n" +
+				"removed,old.txt,old.txt,,1,\n",
+		},
+		{
+			name:     "json fields",
+			cfg:      config.Config{Repository: "owner/repo", Tag: "v1.0.0", JSON: true, JSONFields: []string{"kind", "key"}},
+			format:   render.FormatJSON,
+			head:     "v1.1.0",
+			expected: `{"key":"new.txt","kind":"added"}`,
+		},
+		{
+			name:   "no head",
+			cfg:    config.Config{Repository: "owner/repo", Tag: "v1.0.0"},
+			format: render.FormatCSV,
+			err:    "compare requires a base and a head tag",
+		},
+		{
+			name:   "unknown release",
+			cfg:    config.Config{Repository: "owner/repo", Tag: "v1.0.0"},
+			format: render.FormatCSV,
+			head:   "v2.0.0",
+			err:    "failed to get release v2.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			renderer, err := render.New(tc.format, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output := captureStdout(t, func() {
+				err = renderAssetChanges(client, tc.cfg, renderer, tc.head)
+			})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tc.format == render.FormatCSV && output != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+			if tc.format == render.FormatJSON && !strings.Contains(strings.Join(strings.Fields(output), ""), tc.expected) {
+				t.Errorf("Expected output to contain %s, got %s", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestCompare_Validation(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  config.Config
+		err  string
+	}{
+		{name: "no repository", cfg: config.Config{}, err: "repository is required"},
+		{name: "unknown format", cfg: config.Config{Repository: "owner/repo", Format: "xml"}, err: "xml"},
+		{name: "format with commits", cfg: config.Config{Repository: "owner/repo", Format: render.FormatCSV, Commits: true}, err: "cannot be used with --commits or --files"},
+		{name: "json with files", cfg: config.Config{Repository: "owner/repo", JSON: true, Files: true}, err: "cannot be used with --commits or --files"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Compare(tc.cfg)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
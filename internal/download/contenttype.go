@@ -0,0 +1,65 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/filetype"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// checkContentType compares the type detected from the content of a
+// downloaded asset with its declared content type and file extension,
+// warning about disagreements. With rename, a file whose extension promises a
+// different format than its content is renamed to the detected extension.
+// It returns the final path.
+func checkContentType(root *confine.Root, asset github.Asset, path string, rename bool) (string, error) {
+	detected, err := filetype.DetectFile(path)
+	if err != nil {
+		return path, fmt.Errorf("failed to detect type of %s: %w", path, err)
+	}
+	if !detected.Specific() {
+		return path, nil
+	}
+
+	if declared := filetype.NormalizeMIME(asset.ContentType); declaredSpecific(declared) && declared != detected.MIME {
+		fmt.Fprintf(os.Stderr, "Warning: %s is declared as %s but looks like %s\n", asset.Name, asset.ContentType, detected.Name)
+	}
+
+	promised, ext, ok := filetype.ForName(asset.Name)
+	if !ok || promised.MIME == detected.MIME {
+		return path, nil
+	}
+
+	if !rename || detected.Extension == "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s has a %s extension but looks like %s\n", asset.Name, ext, detected.Name)
+		return path, nil
+	}
+
+	renamed := path[:len(path)-len(ext)] + detected.Extension
+	if _, err := root.Lstat(renamed); err == nil {
+		fmt.Fprintf(os.Stderr, "Warning: not renaming %s, %s already exists\n", path, filepath.Base(renamed))
+		return path, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return path, err
+	}
+
+	if err := root.Rename(path, renamed); err != nil {
+		return path, fmt.Errorf("failed to rename %s: %w", path, err)
+	}
+	fmt.Printf("Renamed %s to %s (content is %s)\n", asset.Name, filepath.Base(renamed), detected.Name)
+	return renamed, nil
+}
+
+// declaredSpecific reports whether a declared content type names a concrete
+// format worth comparing; generic types say nothing about the content
+func declaredSpecific(mime string) bool {
+	switch mime {
+	case "", "application/octet-stream", "binary/octet-stream", "application/binary", "application/unknown":
+		return false
+	}
+	return true
+}
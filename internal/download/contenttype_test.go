@@ -0,0 +1,48 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestCheckContentType(t *testing.T) {
+	gzipHead := []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	testCases := []struct {
+		name     string
+		asset    github.Asset
+		content  []byte
+		rename   bool
+		expected string
+	}{
+		{"matching", github.Asset{Name: "app.zip", ContentType: "application/zip"}, []byte("PK\x03\x04"), true, "app.zip"},
+		{"wrong extension kept", github.Asset{Name: "app.zip"}, gzipHead, false, "app.zip"},
+		{"wrong extension renamed", github.Asset{Name: "app.zip"}, gzipHead, true, "app.gz"},
+		{"no extension", github.Asset{Name: "app-linux"}, []byte("\x7fELF\x02"), true, "app-linux"},
+		{"text", github.Asset{Name: "app.zip"}, []byte("Not Found"), true, "app.zip"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tc.asset.Name)
+			if err := os.WriteFile(path, tc.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := checkContentType(testRoot(t, dir), tc.asset, path, tc.rename)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != filepath.Join(dir, tc.expected) {
+				t.Errorf("Expected %s, got %s", tc.expected, filepath.Base(got))
+			}
+			if _, err := os.Stat(got); err != nil {
+				t.Errorf("Expected %s to exist, got %v", got, err)
+			}
+		})
+	}
+}
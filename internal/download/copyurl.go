@@ -0,0 +1,40 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/23prime/gh-download/internal/clipboard"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// copyAssetURL copies the browser download URL of the single asset --list
+// matched to the clipboard, with --copy-url, for pasting a link into a
+// ticket or chat instead of downloading
+func copyAssetURL(cfg config.Config, assets []github.Asset) error {
+	if !cfg.CopyURL {
+		return nil
+	}
+	if len(assets) != 1 {
+		return fmt.Errorf("--copy-url copies the URL of a single asset, but %d assets match pattern '%s'; narrow it with --pattern", len(assets), cfg.Pattern)
+	}
+
+	method, err := clipboard.Copy(assets[0].BrowserDownloadURL, clipboardTerminal())
+	if err != nil {
+		return fmt.Errorf("failed to copy the URL of %s: %w", assets[0].Name, err)
+	}
+	fmt.Fprintf(os.Stderr, "Copied the URL of %s to the clipboard with %s\n", assets[0].Name, method)
+	return nil
+}
+
+// clipboardTerminal returns the terminal to send an OSC 52 sequence to when
+// there is no clipboard tool: stderr, as stdout may be piped into the pager
+func clipboardTerminal() io.Writer {
+	if term.IsTerminal(os.Stderr) {
+		return os.Stderr
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestCopyAssetURL(t *testing.T) {
+	assets := []github.Asset{{Name: "a.tar.gz"}, {Name: "b.tar.gz"}}
+	if err := copyAssetURL(config.Config{}, assets); err != nil {
+		t.Errorf("Expected nothing to be copied without --copy-url, got %v", err)
+	}
+	err := copyAssetURL(config.Config{CopyURL: true, Pattern: "*.tar.gz"}, assets)
+	if err == nil || !strings.Contains(err.Error(), "2 assets match pattern '*.tar.gz'") {
+		t.Errorf("Expected an error for two matching assets, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/middleware"
+	"github.com/23prime/gh-download/internal/verify/sigstore"
+)
+
+// cosignVerifier checks downloaded assets against the sigstore bundles, or
+// signatures and certificates, published next to them, with --cosign
+type cosignVerifier struct {
+	dir    string
+	assets map[string]github.Asset
+	names  []string
+	// matched are the names of the assets being downloaded
+	matched  map[string]bool
+	identity sigstore.Identity
+	client   middleware.Doer
+	// mu fetches the material of one asset at a time into dir
+	mu sync.Mutex
+}
+
+// newCosignVerifier returns a verifier for the matched assets of release,
+// whose signing material is downloaded with downloads. The caller must close
+// the verifier.
+func newCosignVerifier(downloads middleware.Doer, cfg config.Config, release *github.Release, matched []github.Asset) (*cosignVerifier, error) {
+	if cfg.CertificateIdentity == "" || cfg.CertificateOIDCIssuer == "" {
+		return nil, fmt.Errorf("--cosign requires --certificate-identity and --certificate-oidc-issuer")
+	}
+	if cfg.Extract {
+		return nil, fmt.Errorf("--cosign cannot be used with --extract")
+	}
+
+	dir, err := os.MkdirTemp("", "gh-download-cosign-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	v := &cosignVerifier{
+		dir:      dir,
+		assets:   make(map[string]github.Asset),
+		matched:  make(map[string]bool, len(matched)),
+		identity: sigstore.Identity{Subject: cfg.CertificateIdentity, Issuer: cfg.CertificateOIDCIssuer},
+		client:   downloads,
+	}
+	for _, asset := range release.Assets {
+		v.assets[asset.Name] = asset
+		v.names = append(v.names, asset.Name)
+	}
+	for _, asset := range matched {
+		v.matched[asset.Name] = true
+	}
+	return v, nil
+}
+
+func (v *cosignVerifier) close() {
+	if v == nil {
+		return
+	}
+	if removeErr := os.RemoveAll(v.dir); removeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", v.dir, removeErr)
+	}
+}
+
+// isSigstoreMaterial reports whether an asset is the signing material of
+// another of the matched assets, which is not signed itself. Other assets
+// that merely end in .sig, .pem and the like are verified as usual.
+func isSigstoreMaterial(name string, matched map[string]bool) bool {
+	suffixes := append([]string{sigstore.SignatureSuffix, sigstore.CertificateSuffix}, sigstore.BundleSuffixes...)
+	for _, suffix := range suffixes {
+		if blob, ok := strings.CutSuffix(name, suffix); ok && blob != "" && matched[blob] {
+			return true
+		}
+	}
+	return false
+}
+
+// verify downloads the signing material of an asset and checks the file at
+// path against it, reporting whether it did. An asset without signing
+// material fails, as --cosign asks for every asset to be signed.
+func (v *cosignVerifier) verify(asset github.Asset, path string) (bool, error) {
+	if v == nil || isSigstoreMaterial(asset.Name, v.matched) {
+		return false, nil
+	}
+	material, ok := sigstore.FindMaterial(v.names, asset.Name)
+	if !ok {
+		return false, &VerificationError{Err: fmt.Errorf("%s has no sigstore bundle or .sig and .pem assets to verify it with; leave it out with --pattern", asset.Name)}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	root, err := confine.Open(v.dir)
+	if err != nil {
+		return true, err
+	}
+	defer closeRoot(root)
+	local := sigstore.Material{}
+	for name, target := range map[string]*string{
+		material.Bundle:      &local.Bundle,
+		material.Signature:   &local.Signature,
+		material.Certificate: &local.Certificate,
+	} {
+		if name == "" {
+			continue
+		}
+		*target = filepath.Join(v.dir, name)
+		if _, _, err := fetchAsset(v.client, root, v.assets[name], *target, nil); err != nil {
+			return true, err
+		}
+	}
+	if err := sigstore.Verify(path, local, v.identity); err != nil {
+		return true, &VerificationError{Err: fmt.Errorf("sigstore signature of %s: %w", asset.Name, err)}
+	}
+	return true, nil
+}
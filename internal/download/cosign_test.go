@@ -0,0 +1,59 @@
+package download
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestNewCosignVerifier(t *testing.T) {
+	release := &github.Release{TagName: "v1.0.0", Assets: []github.Asset{{Name: "app.tar.gz"}, {Name: "app.tar.gz.sigstore.json"}}}
+
+	if _, err := newCosignVerifier(nil, config.Config{Cosign: true}, release, release.Assets); err == nil {
+		t.Error("Expected an error without the certificate identity")
+	}
+	cfg := config.Config{Cosign: true, CertificateIdentity: "someone@example.com", CertificateOIDCIssuer: "https://accounts.google.com"}
+	cfg.Extract = true
+	if _, err := newCosignVerifier(nil, cfg, release, release.Assets); err == nil {
+		t.Error("Expected an error with --extract")
+	}
+
+	cfg.Extract = false
+	v, err := newCosignVerifier(nil, cfg, release, release.Assets)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer v.close()
+
+	if signed, err := v.verify(github.Asset{Name: "app.tar.gz.sigstore.json"}, "unused"); err != nil || signed {
+		t.Errorf("Expected signing material to pass unchecked, got %v (%v)", err, signed)
+	}
+	var verr *VerificationError
+	if _, err := v.verify(github.Asset{Name: "unsigned.zip"}, "unused"); !errors.As(err, &verr) {
+		t.Errorf("Expected a VerificationError for an asset without signing material, got %v", err)
+	}
+	if _, err := v.verify(github.Asset{Name: "release-key.pem"}, "unused"); !errors.As(err, &verr) {
+		t.Errorf("Expected a .pem asset signing no matched asset to be verified, got %v", err)
+	}
+}
+
+func TestIsSigstoreMaterial(t *testing.T) {
+	matched := map[string]bool{"app.tar.gz": true}
+	for name, want := range map[string]bool{
+		"app.tar.gz":               false,
+		"app.tar.gz.sig":           true,
+		"app.tar.gz.pem":           true,
+		"app.tar.gz.sigstore.json": true,
+		"app.tar.gz.bundle":        true,
+		"other.tar.gz.sig":         false,
+		"release-key.pem":          false,
+		"firmware.bundle":          false,
+		".sig":                     false,
+	} {
+		if got := isSigstoreMaterial(name, matched); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// withStore calls fn with the state store selected by
+// $GH_DOWNLOAD_STATE_BACKEND, closing it afterwards
+func withStore(fn func(state.Store) error) (err error) {
+	store, err := state.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	return fn(store)
+}
+
+// DB works with the SQLite state database: "query" runs a read-only SQL
+// query and prints the result as a table, and "migrate" copies the JSON
+// download history into the database.
+func DB(cfg config.Config) error {
+	args := positionalArgs(cfg)
+	if len(args) == 0 {
+		return fmt.Errorf("db requires a subcommand: query or migrate")
+	}
+
+	switch args[0] {
+	case "query":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: gh download db query <sql>")
+		}
+		return queryDatabase(state.DatabasePath(), args[1])
+	case "migrate":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: gh download db migrate")
+		}
+		return migrateHistory(state.HistoryPath(), state.DatabasePath())
+	default:
+		return fmt.Errorf("unknown db subcommand %q: use query or migrate", args[0])
+	}
+}
+
+func queryDatabase(path, query string) error {
+	columns, rows, err := state.Query(path, query)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, strings.Join(columns, "\t")); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// migrateHistory appends the entries of the JSON history to the database.
+// The JSON history is left in place, so migrating twice duplicates entries.
+func migrateHistory(historyPath, databasePath string) (err error) {
+	entries, err := state.ReadHistory(historyPath)
+	if err != nil {
+		return err
+	}
+
+	store, err := state.OpenSQLite(databasePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, entry := range entries {
+		if err := store.AppendHistory(entry); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Copied %d history entries to %s\n", len(entries), databasePath)
+	return nil
+}
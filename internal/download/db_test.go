@@ -0,0 +1,67 @@
+package download
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+func TestMigrateHistoryAndQuery(t *testing.T) {
+	dir := t.TempDir()
+	historyPath := filepath.Join(dir, state.HistoryFile)
+	databasePath := filepath.Join(dir, state.DatabaseFile)
+	for _, repo := range []string{"owner/app", "owner/tool", "owner/app"} {
+		if err := state.AppendHistory(historyPath, state.HistoryEntry{Repository: repo, Tag: "v1.0.0", Paths: []string{"/mirror/" + repo}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	captureStdout(t, func() {
+		if err := migrateHistory(historyPath, databasePath); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	output := captureStdout(t, func() {
+		if err := queryDatabase(databasePath, "SELECT repository, count(*) AS runs FROM history GROUP BY repository ORDER BY repository"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	expected := []string{"repository  runs", "owner/app   2", "owner/tool  1"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+
+	if err := queryDatabase(databasePath, "SELECT nope FROM history"); err == nil {
+		t.Error("Expected error for an invalid query, got nil")
+	}
+}
+
+func TestHistory_SQLiteBackend(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	t.Setenv(state.BackendEnv, state.BackendSQLite)
+
+	dir := t.TempDir()
+	recordHistory(config.Config{Repository: "owner/repo", Directory: dir}, runResult{Tag: "v1.0.0", Paths: []string{filepath.Join(dir, "app")}})
+
+	output := captureStdout(t, func() {
+		if err := History(config.Config{}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "owner/repo v1.0.0") {
+		t.Errorf("Expected the entry recorded in the database, got %q", output)
+	}
+}
+
+func TestDB_Usage(t *testing.T) {
+	for _, cfg := range []config.Config{{}, {Repository: "drop"}, {Repository: "query"}} {
+		if err := DB(cfg); err == nil {
+			t.Errorf("Expected error for %+v, got nil", cfg)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package download
+
+import (
+	"fmt"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+// applyRepoDefaults fills in the pattern and directory of the repository
+// from the defaults file at path, for the flags not given on the command line
+func applyRepoDefaults(cfg config.Config, path string) (config.Config, error) {
+	defaults, err := config.LoadDefaults(path)
+	if err != nil {
+		return cfg, err
+	}
+	repo, ok := defaults.Lookup(cfg.Repository)
+	if !ok {
+		return cfg, nil
+	}
+
+	var applied bool
+	if repo.Pattern != "" && !cfg.IsSet("pattern") {
+		cfg.Pattern = repo.Pattern
+		applied = true
+	}
+	if repo.Dir != "" && !cfg.IsSet("dir") {
+		cfg.Directory = expandHome(repo.Dir)
+		applied = true
+	}
+	if applied {
+		fmt.Printf("Using defaults for %s from %s\n", cfg.Repository, path)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,46 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+func TestApplyRepoDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := "repos:\n  cli/cli:\n    pattern: '*linux_amd64.tar.gz'\n    dir: ~/tools/gh\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := applyRepoDefaults(config.Config{Repository: "cli/cli", Pattern: "*", Directory: "."}, path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Pattern != "*linux_amd64.tar.gz" || cfg.Directory != filepath.Join(home, "tools", "gh") {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	explicit := config.Config{
+		Repository: "cli/cli",
+		Pattern:    "*.deb",
+		Directory:  ".",
+		Flags:      []config.Flag{{Name: "pattern", Value: "*.deb"}},
+	}
+	cfg, err = applyRepoDefaults(explicit, path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Pattern != "*.deb" || cfg.Directory != filepath.Join(home, "tools", "gh") {
+		t.Errorf("Expected the pattern flag to take precedence, got %+v", cfg)
+	}
+
+	cfg, err = applyRepoDefaults(config.Config{Repository: "owner/repo", Pattern: "*", Directory: "."}, path)
+	if err != nil || cfg.Pattern != "*" || cfg.Directory != "." {
+		t.Errorf("Expected other repositories to be left alone, got %+v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,214 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/extract"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/middleware"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// deltaMarker records the commit a delta-synced source tree was taken from
+const deltaMarker = ".gh-download-ref"
+
+// syncArchiveDelta keeps an extracted source tree in dir up to date. The
+// first run extracts the whole tarball; later runs only fetch the files the
+// compare API reports as changed since the recorded commit. It returns the
+// paths written, and whether the tree was already up to date.
+func syncArchiveDelta(client *api.RESTClient, archives middleware.Doer, cfg config.Config, release *github.Release) ([]string, bool, error) {
+	if cfg.Archive != "tar.gz" {
+		return nil, false, fmt.Errorf("--delta requires --archive tar.gz")
+	}
+
+	ref := cfg.Tag
+	if ref == "" {
+		ref = release.TagName
+	}
+
+	head, err := github.GetCommit(client, cfg.Repository, ref)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+
+	// GitHub tarballs wrap everything in an "owner-repo-sha" directory
+	opts := extractOptions(cfg)
+	opts.StripComponents = 1
+
+	base, err := readDeltaMarker(cfg.Directory)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var written []string
+	switch {
+	case base == "":
+		fmt.Printf("No previous snapshot in %s, extracting %s\n", cfg.Directory, shortSHA(head.SHA))
+		written, err = extractArchive(archives, cfg.Repository, head.SHA, cfg.Archive, cfg.Directory, opts)
+	case base == head.SHA:
+		fmt.Printf("Already up to date at %s\n", shortSHA(head.SHA))
+		return nil, true, nil
+	default:
+		comparison, compareErr := github.GetComparison(client, cfg.Repository, base, head.SHA)
+		if compareErr != nil {
+			return nil, false, fmt.Errorf("failed to compare %s...%s: %w", shortSHA(base), shortSHA(head.SHA), compareErr)
+		}
+
+		if comparison.Status != "ahead" || len(comparison.Files) >= github.MaxComparisonFiles {
+			fmt.Printf("Cannot apply %s...%s as a delta (status: %s, %d files), extracting the full archive\n",
+				shortSHA(base), shortSHA(head.SHA), comparison.Status, len(comparison.Files))
+			fmt.Fprintln(os.Stderr, "Warning: files removed upstream are not deleted by a full extraction")
+			written, err = extractArchive(archives, cfg.Repository, head.SHA, cfg.Archive, cfg.Directory, opts)
+		} else {
+			written, err = applyComparison(cfg.Repository, head.SHA, comparison.Files, cfg.Directory, opts.Include)
+		}
+	}
+	if err != nil {
+		return written, false, err
+	}
+
+	if err := resolvePointers(cfg, written); err != nil {
+		return written, false, err
+	}
+	return written, false, writeDeltaMarker(cfg.Directory, head.SHA)
+}
+
+// applyComparison fetches added and modified files at ref and removes
+// deleted ones, turning the tree at the compare base into the tree at ref.
+// It returns the paths written.
+func applyComparison(repo, ref string, files []github.ComparisonFile, dir, include string) ([]string, error) {
+	rawClient, err := newRESTClient(api.ClientOptions{
+		Headers: map[string]string{"Accept": "application/vnd.github.raw"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download client: %w", err)
+	}
+
+	// Removals and the modes of existing files go through root, so no
+	// symlink in the tree leads them outside of dir
+	root, err := confine.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRoot(root)
+
+	var written []string
+	var removed int
+	for _, file := range files {
+		if file.Status == "renamed" && file.PreviousFilename != "" {
+			if err := removeTreeFile(root, file.PreviousFilename); err != nil {
+				return written, err
+			}
+			removed++
+		}
+
+		match, err := extract.Match(include, file.Filename)
+		if err != nil {
+			return written, err
+		}
+		if !match {
+			continue
+		}
+
+		if file.Status == "removed" {
+			if err := removeTreeFile(root, file.Filename); err != nil {
+				return written, err
+			}
+			removed++
+			continue
+		}
+
+		target, err := fetchTreeFile(rawClient, repo, ref, root, file.Filename)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+
+	fmt.Printf("Updated %d files and removed %d files in %s\n", len(written), removed, dir)
+	return written, nil
+}
+
+func fetchTreeFile(client *api.RESTClient, repo, ref string, root *confine.Root, name string) (string, error) {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	endpoint := fmt.Sprintf("repos/%s/contents/%s?ref=%s", repo, strings.Join(segments, "/"), url.QueryEscape(ref))
+
+	resp, err := client.Request("GET", endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	// The compare API does not report modes, so keep the mode of an existing file
+	perm := os.FileMode(0644)
+	if target, err := extract.SafePath(root.Dir(), name); err == nil {
+		if info, err := root.Stat(target); err == nil {
+			perm = info.Mode().Perm()
+		}
+	}
+
+	return extract.Write(root.Dir(), name, resp.Body, perm)
+}
+
+func removeTreeFile(root *confine.Root, name string) error {
+	target, err := extract.SafePath(root.Dir(), name)
+	if err != nil {
+		return err
+	}
+	if err := root.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", target, err)
+	}
+	return nil
+}
+
+func readDeltaMarker(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, deltaMarker))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", deltaMarker, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func writeDeltaMarker(dir, sha string) error {
+	root, err := confine.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer closeRoot(root)
+	if err := root.WriteFile(filepath.Join(dir, deltaMarker), []byte(sha+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", deltaMarker, err)
+	}
+	return nil
+}
+
+// resolvePointers resolves LFS pointers among written when --resolve-lfs is set
+func resolvePointers(cfg config.Config, written []string) error {
+	if !cfg.ResolveLFS {
+		return nil
+	}
+	return resolveLFSPointers(cfg.Repository, cfg.Directory, written)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,88 @@
+package download
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/confine"
+)
+
+func TestDeltaMarker_RoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mirror")
+
+	sha, err := readDeltaMarker(dir)
+	if err != nil {
+		t.Fatalf("Expected no error for missing marker, got %v", err)
+	}
+	if sha != "" {
+		t.Errorf("Expected empty sha for missing marker, got %q", sha)
+	}
+
+	if err := writeDeltaMarker(dir, "0123456789abcdef"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sha, err = readDeltaMarker(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sha != "0123456789abcdef" {
+		t.Errorf("Expected recorded sha, got %q", sha)
+	}
+}
+
+func TestRemoveTreeFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(target, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := testRoot(t, dir)
+
+	if err := removeTreeFile(root, "a.txt"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("Expected file to be removed")
+	}
+
+	// Removing a missing file is not an error
+	if err := removeTreeFile(root, "a.txt"); err != nil {
+		t.Errorf("Expected no error for missing file, got %v", err)
+	}
+
+	if err := removeTreeFile(root, "../outside"); err == nil {
+		t.Error("Expected error for path traversal, got nil")
+	}
+}
+
+func TestRemoveTreeFile_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim.txt")
+	if err := os.WriteFile(victim, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeTreeFile(testRoot(t, dir), "link/victim.txt"); !errors.Is(err, confine.ErrOutside) {
+		t.Errorf("Expected ErrOutside for a removal through a symlink, got %v", err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("Expected the file outside the directory to be kept, got %v", err)
+	}
+}
+
+func TestShortSHA(t *testing.T) {
+	if got := shortSHA("0123456789abcdef"); got != "0123456" {
+		t.Errorf("Expected '0123456', got %q", got)
+	}
+	if got := shortSHA("abc"); got != "abc" {
+		t.Errorf("Expected 'abc', got %q", got)
+	}
+}
@@ -0,0 +1,42 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/lock"
+)
+
+// dirLockName is the advisory lock file a run holds in the directory it
+// writes to, so overlapping runs, such as cron jobs, do not corrupt each
+// other's partial files
+const dirLockName = ".gh-download.lock"
+
+// lockDirectory locks --dir for the run, waiting up to --wait-lock for
+// another run to finish, and returns the function releasing the lock. With
+// --no-lock nothing is locked.
+func lockDirectory(cfg config.Config) (func(), error) {
+	if cfg.NoLock {
+		return func() {}, nil
+	}
+
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	held, err := lock.Acquire(filepath.Join(cfg.Directory, dirLockName), cfg.WaitLock)
+	if errors.Is(err, lock.ErrLocked) {
+		return nil, fmt.Errorf("another run is using %s: %w; wait for it with --wait-lock or skip locking with --no-lock", cfg.Directory, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", cfg.Directory, err)
+	}
+
+	return func() {
+		if err := held.Release(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to unlock %s: %v\n", cfg.Directory, err)
+		}
+	}, nil
+}
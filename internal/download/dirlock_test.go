@@ -0,0 +1,48 @@
+package download
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/lock"
+)
+
+func TestLockDirectory_Contention(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mirror")
+	cfg := config.Config{Directory: dir}
+
+	unlock, err := lockDirectory(cfg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = lockDirectory(cfg)
+	if !errors.Is(err, lock.ErrLocked) || !strings.Contains(err.Error(), "--wait-lock") {
+		t.Errorf("Expected a locked error suggesting --wait-lock, got %v", err)
+	}
+	if err := Clean(config.Config{Directory: dir, StaleAfter: time.Hour}); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("Expected clean to respect the lock, got %v", err)
+	}
+
+	noLock, err := lockDirectory(config.Config{Directory: dir, NoLock: true})
+	if err != nil {
+		t.Errorf("Expected --no-lock to skip the lock, got %v", err)
+	}
+	noLock()
+
+	unlock()
+	unlock, err = lockDirectory(config.Config{Directory: dir, WaitLock: time.Second})
+	if err != nil {
+		t.Fatalf("Expected the released directory to be locked, got %v", err)
+	}
+	unlock()
+
+	if _, err := os.Stat(filepath.Join(dir, dirLockName)); err != nil {
+		t.Errorf("Expected the lock file to be kept, got %v", err)
+	}
+}
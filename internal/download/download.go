@@ -1,59 +1,523 @@
 package download
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/23prime/gh-download/internal/cas"
+	"github.com/23prime/gh-download/internal/color"
 	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
 	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/log"
+	"github.com/23prime/gh-download/internal/middleware"
+	"github.com/23prime/gh-download/internal/pager"
+	"github.com/23prime/gh-download/internal/progress"
+	"github.com/23prime/gh-download/internal/render"
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/23prime/gh-download/internal/state"
+	"github.com/23prime/gh-download/internal/tracing"
 	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/term"
 )
 
-func DownloadFromRelease(cfg config.Config) error {
+// runResult describes what a download run produced
+type runResult struct {
+	// Tag is the tag of the resolved release
+	Tag string
+	// Paths are the files written
+	Paths []string
+	// Assets are the names of the release assets of Paths, by path, for the
+	// files saved as they were downloaded
+	Assets map[string]string
+	// UpToDate reports that --delta found nothing to update
+	UpToDate bool
+	// Changed reports that the content of any file changed
+	Changed bool
+	// Lines are printed on stdout instead of the progress with --urls-only
+	// and --emit-commands
+	Lines []string
+	// Author is the login of the account that created the release
+	Author string
+	// Commit is the commit the tag points to and TargetCommitish the branch
+	// or commit the release was created from
+	Commit          string
+	TargetCommitish string
+	// Reactions and DiscussionURL describe the community feedback on the
+	// resolved release
+	Reactions     int
+	DiscussionURL string
+	// CachedAt is when the release metadata was cached if --stale-ok fell
+	// back to the cache, and zero otherwise
+	CachedAt time.Time
+	// Resolved tells how the release was chosen; nil before it was
+	Resolved *ResolvedRelease
+}
+
+// DownloadFromRelease runs the download command. With --print-paths the
+// human-readable output moves to stderr and stdout receives only the absolute
+// paths of the files written, one per line; with --quiet the human-readable
+// output is dropped instead. With --idempotent-json nothing but
+// a single JSON object describing the result is printed on stdout, or with
+// --format yaml the same object as YAML. With
+// --urls-only or --emit-commands stdout receives only the download URLs or
+// commands, and with --stdout only the content of the single matching asset.
+func DownloadFromRelease(cfg config.Config) (err error) {
+	if err := checkOutputModes(cfg); err != nil {
+		return err
+	}
+
+	endTracing, err := startTracing(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		endTracing(err)
+	}()
+
+	stdout := os.Stdout
+	switch {
+	case cfg.PrintPaths, cfg.Stdout, cfg.URLsOnly, cfg.Presign, cfg.EmitCommands != "":
+		os.Stdout = os.Stderr
+	case cfg.IdempotentJSON, cfg.Quiet:
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+		}
+		defer func() {
+			if closeErr := devNull.Close(); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", os.DevNull, closeErr)
+			}
+		}()
+		os.Stdout = devNull
+	}
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	if (cfg.List || cfg.Releases) && !cfg.NoPager {
+		stopPager, err := pager.Start(pager.Command())
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if pagerErr := stopPager(); pagerErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", pagerErr)
+			}
+		}()
+	}
+
+	if cfg.MaxDuration > 0 {
+		cfg.Deadline = time.Now().Add(cfg.MaxDuration)
+	}
+
+	// --stdout downloads and verifies the asset as usual, into a directory
+	// of its own, before a byte of it is written to stdout
+	if cfg.Stdout {
+		dir, err := os.MkdirTemp("", "gh-download-stdout-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer func() {
+			if removeErr := os.RemoveAll(dir); removeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", dir, removeErr)
+			}
+		}()
+		cfg.Directory = dir
+	}
+
+	var result runResult
+	if cfg.Stdin {
+		result, err = downloadFromRepositories(cfg, os.Stdin)
+	} else {
+		result, err = downloadFromRelease(cfg)
+	}
+	if cfg.IdempotentJSON {
+		writeResult := writeResultJSON
+		if cfg.Format == render.FormatYAML {
+			writeResult = writeResultYAML
+		}
+		if jsonErr := writeResult(stdout, result, err); jsonErr != nil && err == nil {
+			err = jsonErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if cfg.Stdout {
+		return copyToStdout(stdout, result.Paths)
+	}
+
+	for _, line := range result.Lines {
+		if _, err := fmt.Fprintln(stdout, line); err != nil {
+			return err
+		}
+	}
+
+	if err := writeRunOutputs(cfg, result); err != nil {
+		return err
+	}
+
+	if !cfg.PrintPaths && !cfg.Quiet {
+		return nil
+	}
+	for _, path := range absPaths(result.Paths) {
+		if _, err := fmt.Fprintln(stdout, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOutputModes rejects flags that each claim stdout for themselves, and
+// --check, which replaces the download
+func checkOutputModes(cfg config.Config) error {
+	var modes []string
+	for flag, set := range map[string]bool{
+		"--print-paths":     cfg.PrintPaths,
+		"--stdout":          cfg.Stdout,
+		"--idempotent-json": cfg.IdempotentJSON,
+		"--urls-only":       cfg.URLsOnly,
+		"--presign":         cfg.Presign,
+		"--emit-commands":   cfg.EmitCommands != "",
+		"--check":           cfg.Check,
+		"--quiet":           cfg.Quiet,
+	} {
+		if set {
+			modes = append(modes, flag)
+		}
+	}
+	if len(modes) > 1 {
+		slices.Sort(modes)
+		return fmt.Errorf("%s cannot be used together", strings.Join(modes, " and "))
+	}
+	if cfg.IdempotentJSON && cfg.Format != "" && cfg.Format != render.FormatJSON && cfg.Format != render.FormatYAML {
+		return fmt.Errorf("--idempotent-json can only be printed as json or yaml")
+	}
+	if cfg.Quiet && (cfg.List || cfg.Releases) {
+		return fmt.Errorf("--quiet cannot be used with --list or --releases")
+	}
+	if cfg.SignedURLs && !cfg.URLsOnly && cfg.EmitCommands == "" {
+		return fmt.Errorf("--signed requires --urls-only or --emit-commands")
+	}
+	if cfg.APIURLs && !cfg.URLsOnly {
+		return fmt.Errorf("--api-urls requires --urls-only")
+	}
+	if cfg.APIURLs && cfg.SignedURLs {
+		return fmt.Errorf("--api-urls and --signed cannot be used together")
+	}
+	if cfg.Output != "" && (cfg.Stdout || cfg.Extract || cfg.Archive != "" || cfg.Stdin || cfg.List || cfg.Releases) {
+		return fmt.Errorf("--output names a single asset and cannot be used with --stdout, --extract, --archive, --stdin, --list or --releases")
+	}
+	if cfg.OutputTemplate != "" {
+		if cfg.Output != "" || cfg.Stdout || cfg.Extract || cfg.Archive != "" {
+			return fmt.Errorf("--output-template cannot be used with --output, --stdout, --extract or --archive")
+		}
+		if err := checkPathTemplate(cfg.OutputTemplate); err != nil {
+			return err
+		}
+	}
+	if cfg.MirrorToFork != "" && (cfg.Extract || cfg.Archive != "" || cfg.Stdout || cfg.Stdin || cfg.Output != "" || cfg.OutputTemplate != "") {
+		return fmt.Errorf("--mirror-to-fork republishes the assets as they are named and cannot be used with --extract, --archive, --stdout, --stdin, --output or --output-template")
+	}
+	if cfg.Stdout && (cfg.Extract || cfg.Stdin || cfg.List || cfg.Releases || cfg.GitHubOutput || cfg.EnvFile != "") {
+		return fmt.Errorf("--stdout writes a single asset and cannot be used with --extract, --stdin, --list, --releases, --github-output or --env-file")
+	}
+	if cfg.Preflight && !cfg.Stdin {
+		return fmt.Errorf("--preflight requires --stdin")
+	}
+	if cfg.CopyURL && !cfg.List {
+		return fmt.Errorf("--copy-url requires --list")
+	}
+	return nil
+}
+
+func downloadFromRelease(cfg config.Config) (runResult, error) {
 	if cfg.Repository == "" {
-		return fmt.Errorf("repository is required")
+		return runResult{}, fmt.Errorf("repository is required")
+	}
+	cfg, err := applyRepoDefaults(cfg, config.DefaultsPath())
+	if err != nil {
+		return runResult{}, err
 	}
+	cfg, err = applyTapTool(cfg, state.TapsPath())
+	if err != nil {
+		return runResult{}, err
+	}
+	cfg, restoreHost, err := useRepositoryHost(cfg)
+	if err != nil {
+		return runResult{}, err
+	}
+	defer restoreHost()
+	cfg = outputDirectory(cfg)
 
-	client, err := api.DefaultRESTClient()
+	if cfg.FallbackArchive != "" && cfg.FallbackArchive != "zip" && cfg.FallbackArchive != "tar.gz" {
+		return runResult{}, fmt.Errorf("--fallback-archive must be 'zip' or 'tar.gz'")
+	}
+	if err := validateSort(cfg.Sort); err != nil {
+		return runResult{}, err
+	}
+	var renderer *render.Renderer
+	if cfg.List || cfg.Releases {
+		if renderer, err = outputRenderer(cfg); err != nil {
+			return runResult{}, err
+		}
+	}
+	resume, err := decodeResumeToken(cfg.Resume)
+	if err != nil {
+		return runResult{}, err
+	}
+	if resume.Repository != "" && resume.Repository != cfg.Repository {
+		return runResult{}, fmt.Errorf("resume token is for %s, not %s", resume.Repository, cfg.Repository)
+	}
+	resumed := cfg.Tag == "" && resume.Tag != ""
+	if resumed {
+		cfg.Tag = resume.Tag
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
+		return runResult{}, fmt.Errorf("failed to create GitHub client: %w", err)
 	}
 
 	if cfg.Releases {
-		return github.ListReleases(client, cfg.Repository)
+		return runResult{}, listReleases(client, cfg, renderer)
 	}
 
-	release, err := github.GetRelease(client, cfg.Repository, cfg.Tag)
+	metaClient, err := metadataClient(cfg, client)
+	if err != nil {
+		return runResult{}, err
+	}
+	span := tracer.Start("resolve release", tracing.String("repository", cfg.Repository), tracing.String("tag", cfg.Tag))
+	started := time.Now()
+	release, cachedAt, err := resolveReleaseOrCached(metaClient, cfg)
+	if err == nil {
+		log.Verbosef("Resolved %s of %s in %v", release.TagName, cfg.Repository, time.Since(started).Round(time.Millisecond))
+		span.SetAttr(tracing.String("release.tag", release.TagName), tracing.Int("release.assets", int64(len(release.Assets))))
+	}
+	span.End(err)
 	if err != nil {
-		return fmt.Errorf("failed to get release: %w", err)
+		return runResult{}, fmt.Errorf("failed to get release: %w", err)
 	}
 
-	fmt.Printf("Release: %s", release.Name)
-	if cfg.Tag != "" {
-		fmt.Printf(" (tag: %s)", cfg.Tag)
-	} else {
-		fmt.Printf(" (latest)")
+	if cachedAt.IsZero() {
+		if err := github.ResolveCommitSHA(metaClient, cfg.Repository, release); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to resolve the commit of %s: %v\n", release.TagName, err)
+		}
+		cacheRelease(cfg, release)
+	}
+
+	if cfg, err = expandTemplates(cfg, release); err != nil {
+		return runResult{}, err
+	}
+
+	var filter string
+	if cfg.Tag == "" && (cfg.MinReactions > 0 || cfg.Author != "") {
+		filter = releaseFilterDescription(cfg)
+	}
+	resolved := newResolvedRelease(release, cfg.Tag, filter)
+	if cfg.Strategy != "" {
+		resolved.Method = ResolvedStrategy
+		resolved.Strategy = cfg.Strategy
 	}
-	fmt.Printf(" from %s\n", cfg.Repository)
+	if resumed {
+		resolved.Method = ResolvedResume
+	}
+	if cfg.List && renderer != nil {
+		assets, err := github.FilterAssets(sortAssets(release.Assets, cfg.Sort), cfg.Pattern)
+		if err != nil {
+			return runResult{}, fmt.Errorf("failed to filter assets: %w", err)
+		}
+		if err := renderRecords(cfg, renderer, assetTable(assets), github.Asset{}); err != nil {
+			return runResult{}, err
+		}
+		return runResult{}, copyAssetURL(cfg, assets)
+	}
+	printReleaseHeader(release, resolved, cfg.Repository)
 
 	if cfg.List {
-		return github.ListAssets(release.Assets, cfg.Pattern)
+		if err := github.ListAssets(sortAssets(release.Assets, cfg.Sort), cfg.Pattern); err != nil {
+			return runResult{}, err
+		}
+		assets, err := github.FilterAssets(release.Assets, cfg.Pattern)
+		if err != nil {
+			return runResult{}, fmt.Errorf("failed to filter assets: %w", err)
+		}
+		return runResult{}, copyAssetURL(cfg, assets)
 	}
 
-	if cfg.Archive != "" {
-		return downloadArchive(client, cfg.Repository, cfg.Tag, cfg.Archive, cfg.Directory)
+	if cfg.VerifyTagSignature || cfg.TagSigningKey != "" {
+		if err := verifyTagSignature(client, cfg, release); err != nil {
+			return runResult{}, err
+		}
+	}
+	if cfg.RequireChecksPassed {
+		if err := requireChecksPassed(client, cfg, release); err != nil {
+			return runResult{}, err
+		}
+	}
+	if cfg.RequireDeployment != "" {
+		if err := requireDeployment(client, cfg, release); err != nil {
+			return runResult{}, err
+		}
 	}
 
+	if !cfg.URLsOnly && !cfg.Presign && cfg.EmitCommands == "" && !cfg.Check {
+		unlock, err := lockDirectory(cfg)
+		if err != nil {
+			return runResult{}, err
+		}
+		defer unlock()
+	}
+
+	result := runResult{Tag: release.TagName, Author: release.Author.Login, Commit: release.CommitSHA, TargetCommitish: release.TargetCommitish, Reactions: release.ReactionCount(), DiscussionURL: release.DiscussionURL, CachedAt: cachedAt, Resolved: &resolved}
+	switch {
+	case cfg.Check:
+		err = checkDirectory(cfg, release)
+	case cfg.URLsOnly:
+		result.Lines, err = resolveURLs(cfg, release)
+	case cfg.Presign:
+		result.Lines, err = presignURLs(cfg, release)
+	case cfg.EmitCommands != "":
+		result.Lines, err = emitCommands(cfg, release)
+	case cfg.Archive != "":
+		result.Paths, result.UpToDate, err = downloadSourceArchive(client, cfg, release)
+		result.Changed = !result.UpToDate
+	case useFallbackArchive(cfg, release):
+		fmt.Printf("Release %s has no assets, downloading its source archive instead\n", release.TagName)
+		cfg.Archive, cfg.Tag = cfg.FallbackArchive, release.TagName
+		result.Paths, result.UpToDate, err = downloadSourceArchive(client, cfg, release)
+		result.Changed = !result.UpToDate
+	default:
+		result.Paths, result.Assets, result.Changed, err = downloadReleaseAssets(cfg, release, resume.Assets)
+	}
+	if err == nil && cfg.MirrorToFork != "" && len(result.Paths) > 0 {
+		err = mirrorRelease(client, cfg, release, result.Paths)
+	}
+	if err == nil && cfg.WriteProvenance && len(result.Paths) > 0 {
+		err = writeProvenance(cfg, result, os.Args[1:], time.Now())
+	}
+	recordHistory(cfg, result)
+	return result, err
+}
+
+// downloadSourceArchive downloads or extracts the source archive of a
+// release and returns the paths written, and whether --delta found the
+// extracted tree already up to date
+func downloadSourceArchive(client *api.RESTClient, cfg config.Config, release *github.Release) ([]string, bool, error) {
+	policy, err := retryPolicy(cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	archives, err := newArchiveDoer(policy)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create download client: %w", err)
+	}
+
+	if cfg.Delta {
+		return syncArchiveDelta(client, archives, cfg, release)
+	}
+	if cfg.Submodules {
+		written, err := extractWithSubmodules(client, archives, cfg, release)
+		return written, false, err
+	}
+	if cfg.Extract {
+		written, err := extractArchive(archives, cfg.Repository, cfg.Tag, cfg.Archive, cfg.Directory, extractOptions(cfg))
+		if err != nil {
+			return written, false, err
+		}
+		return written, false, resolvePointers(cfg, written)
+	}
+	if cfg.ResolveLFS {
+		return nil, false, fmt.Errorf("--resolve-lfs requires --extract or --delta for source archives")
+	}
+
+	existing, err := existingFilePolicy(cfg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	span := tracer.Start("transfer archive", tracing.String("repository", cfg.Repository), tracing.String("format", cfg.Archive))
+	path, err := downloadArchive(archives, cfg.Repository, cfg.Tag, archiveCommit(cfg, release), cfg.Archive, cfg.Directory, existing)
+	span.End(err)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if cfg.EmitSidecarChecksums {
+		algorithm, err := checksumAlgorithm(cfg)
+		if err != nil {
+			return nil, false, err
+		}
+		root, err := confine.Open(cfg.Directory)
+		if err != nil {
+			return nil, false, err
+		}
+		defer closeRoot(root)
+		if err := writeSidecar(root, path, algorithm); err != nil {
+			return nil, false, err
+		}
+	}
+	return []string{path}, false, nil
+}
+
+// useFallbackArchive reports whether the source archive is downloaded
+// instead, for a release without any assets with --fallback-archive
+func useFallbackArchive(cfg config.Config, release *github.Release) bool {
+	return cfg.FallbackArchive != "" && cfg.Archive == "" && len(release.Assets) == 0
+}
+
+// downloadReleaseAssets downloads or extracts the assets matching the
+// pattern, restricted to the names in only when resuming, and returns the
+// paths written, the asset names of those that are an asset as downloaded,
+// and whether any content changed
+func downloadReleaseAssets(cfg config.Config, release *github.Release, only []string) ([]string, map[string]string, bool, error) {
+	if len(release.Assets) == 0 {
+		return nil, nil, false, fmt.Errorf("%w in release %s; --fallback-archive zip or tar.gz downloads its source archive instead", errNoMatchingAssets, release.TagName)
+	}
 	matchingAssets, err := github.FilterAssets(release.Assets, cfg.Pattern)
 	if err != nil {
-		return fmt.Errorf("failed to filter assets: %w", err)
+		return nil, nil, false, fmt.Errorf("failed to filter assets: %w", err)
+	}
+	if only != nil {
+		matchingAssets = slices.DeleteFunc(matchingAssets, func(asset github.Asset) bool {
+			return !slices.Contains(only, asset.Name)
+		})
+	}
+	if cfg.ChecksumsOnly {
+		matchingAssets = metadataAssets(matchingAssets)
+	}
+	if matchingAssets, err = applySizeLimit(cfg, matchingAssets); err != nil {
+		return nil, nil, false, err
 	}
 
 	if len(matchingAssets) == 0 {
-		return fmt.Errorf("no assets found matching pattern '%s'", cfg.Pattern)
+		if cfg.ChecksumsOnly {
+			return nil, nil, false, fmt.Errorf("%w matching pattern '%s' that look like checksums or metadata", errNoMatchingAssets, cfg.Pattern)
+		}
+		return nil, nil, false, fmt.Errorf("%w matching pattern '%s'", errNoMatchingAssets, cfg.Pattern)
+	}
+
+	if (cfg.Stdout || cfg.Output != "") && len(matchingAssets) > 1 {
+		flag := "--stdout"
+		if cfg.Output != "" {
+			flag = "--output"
+		}
+		return nil, nil, false, fmt.Errorf("%s requires exactly one matching asset, %d match pattern '%s'", flag, len(matchingAssets), cfg.Pattern)
+	}
+
+	matchingAssets, err = orderAssets(matchingAssets, cfg.Order)
+	if err != nil {
+		return nil, nil, false, err
 	}
 
 	fmt.Printf("Found %d matching assets to download to %s:\n", len(matchingAssets), cfg.Directory)
@@ -61,12 +525,389 @@ func DownloadFromRelease(cfg config.Config) error {
 		fmt.Printf("  - %s (%d bytes)\n", asset.Name, asset.Size)
 	}
 
-	return downloadAssets(matchingAssets, cfg.Directory)
+	if cfg.IsSet("concurrency") && cfg.Concurrency < 1 {
+		return nil, nil, false, fmt.Errorf("concurrency must be at least 1")
+	}
+	run := newAssetRun(len(matchingAssets), cfg.ContinueOnError)
+	run.concurrency = cfg.Concurrency
+	if term.IsTerminal(os.Stdout) {
+		var size int64
+		for _, asset := range matchingAssets {
+			size += int64(asset.Size)
+		}
+		run.display = progress.New(os.Stdout, size, len(matchingAssets))
+		defer run.display.Close()
+		// Log lines go above the bars instead of through them
+		previous := log.SetOutput(noteWriter{run})
+		defer log.SetOutput(previous)
+	}
+	run.skipUnchanged = cfg.IdempotentJSON
+	if run.existing, err = existingFilePolicy(cfg); err != nil {
+		return nil, nil, false, err
+	}
+	run.deadline = cfg.Deadline
+	if cfg.AssetTimeout < 0 {
+		return nil, nil, false, fmt.Errorf("invalid asset timeout %s: must not be negative", cfg.AssetTimeout)
+	}
+	run.assetTimeout = cfg.AssetTimeout
+	algorithm, err := checksumAlgorithm(cfg)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if run.checksums, err = loadChecksums(cfg, release, algorithm); err != nil {
+		return nil, nil, false, err
+	}
+	if run.pinned, err = pinnedChecksum(cfg, matchingAssets); err != nil {
+		return nil, nil, false, err
+	}
+	if cfg.EmitSidecarChecksums {
+		run.sidecar = algorithm
+	}
+	if cfg.Paranoid && cfg.NoVerifyDigest {
+		return nil, nil, false, fmt.Errorf("--paranoid and --no-verify-digest cannot be used together")
+	}
+	run.verifyDigest = !cfg.NoVerifyDigest
+	run.headCheck = cfg.HeadCheck
+	if cfg.VerifySignature || cfg.SignerKey != "" {
+		client, err := newRESTClient(api.ClientOptions{})
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to create GitHub client: %w", err)
+		}
+		downloads, err := newAssetDoer()
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to create download client: %w", err)
+		}
+		if run.signatures, err = newSignatureVerifier(client, downloads, cfg, release); err != nil {
+			return nil, nil, false, err
+		}
+		defer run.signatures.close()
+	}
+	if cfg.Cosign {
+		downloads, err := newAssetDoer()
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to create download client: %w", err)
+		}
+		if run.cosign, err = newCosignVerifier(downloads, cfg, release, matchingAssets); err != nil {
+			return nil, nil, false, err
+		}
+		defer run.cosign.close()
+	}
+	if cfg.VerifyProvenance {
+		if run.provenance, err = loadProvenance(cfg, release); err != nil {
+			return nil, nil, false, err
+		}
+		defer run.provenance.close()
+	}
+	if run.policy, err = retryPolicy(cfg); err != nil {
+		return nil, nil, false, err
+	}
+	if cfg.UseCache {
+		run.objects = &cas.Store{Root: cas.Dir()}
+	}
+	if cfg.Downloader != "" {
+		run.downloader, err = newExternalDownloader(cfg.Downloader, cfg.DownloaderArgs)
+		if err != nil {
+			return nil, nil, false, err
+		}
+	}
+
+	run.fileNames = assetFileNames(matchingAssets)
+	if cfg.Output != "" {
+		run.fileNames[matchingAssets[0].ID] = filepath.Base(cfg.Output)
+	}
+	if cfg.OutputTemplate != "" {
+		run.fileNames, err = templateFileNames(cfg.OutputTemplate, templateData(cfg.Repository, release), matchingAssets, run.fileNames)
+		if err != nil {
+			return nil, nil, false, err
+		}
+	}
+	if err := removeStaleFiles(cfg.Directory, cfg.StaleAfter, run.fileNames); err != nil {
+		return nil, nil, false, err
+	}
+
+	if cfg.Extract {
+		zipAssets, tarAssets, otherAssets := splitExtractable(matchingAssets)
+		if err := extractZipAssets(run, zipAssets, cfg.Directory, extractOptions(cfg)); err != nil {
+			return run.results(), run.assets, run.changed, err
+		}
+		if err := extractTarGzAssets(run, tarAssets, cfg.Directory, extractOptions(cfg)); err != nil {
+			return run.results(), run.assets, run.changed, err
+		}
+		matchingAssets = otherAssets
+	}
+
+	if len(matchingAssets) > 0 {
+		if err := downloadAssets(run, matchingAssets, cfg.Directory, cfg.RenameByType); err != nil {
+			return run.results(), run.assets, run.changed, err
+		}
+	}
+
+	err = run.err()
+	if len(run.undone) > 0 {
+		token := resumeToken{Repository: cfg.Repository, Tag: release.TagName, Assets: run.undone}
+		err = newBudgetError(run.undone, token)
+	}
+	return run.results(), run.assets, run.changed, err
 }
 
-func downloadArchive(client *api.RESTClient, repo, tag, archiveFormat, dir string) error {
+// listReleases prints every release of the repository, leaving out those
+// --min-reactions and --author exclude
+func listReleases(client github.HTTPClient, cfg config.Config, renderer *render.Renderer) error {
+	releases, err := github.GetAllReleases(client, cfg.Repository)
+	if err != nil {
+		return fmt.Errorf("failed to get releases: %w", err)
+	}
+
+	releases = sortReleases(filterReleases(cfg, releases), cfg.Sort)
+	if renderer != nil {
+		return renderRecords(cfg, renderer, releaseTable(releases), github.Release{})
+	}
+	github.PrintReleases(cfg.Repository, releases)
+	return nil
+}
+
+// filterReleases returns the releases with at least --min-reactions
+// reactions that were created by --author
+func filterReleases(cfg config.Config, releases []github.Release) []github.Release {
+	return github.FilterByAuthor(github.FilterByReactions(releases, cfg.MinReactions), cfg.Author)
+}
+
+// resolveRelease returns the release to download. With --strategy and no
+// tag it is the release the strategy chooses among all releases passing
+// --min-reactions and --author. With only those filters it is the newest
+// stable release passing them rather than the latest one, and a release
+// given by tag must pass them.
+func resolveRelease(client github.HTTPClient, cfg config.Config) (*github.Release, error) {
+	if cfg.Strategy != "" {
+		if cfg.Tag != "" {
+			return nil, fmt.Errorf("--strategy cannot be used with a tag")
+		}
+		releases, err := github.GetAllReleases(client, cfg.Repository)
+		if err != nil {
+			return nil, err
+		}
+		return github.SelectRelease(filterReleases(cfg, releases), cfg.Strategy)
+	}
+
+	filtered := cfg.MinReactions > 0 || cfg.Author != ""
+	if filtered && cfg.Tag == "" {
+		releases, err := github.GetAllReleases(client, cfg.Repository)
+		if err != nil {
+			return nil, err
+		}
+		release, ok := github.LatestStable(filterReleases(cfg, releases))
+		if !ok {
+			return nil, fmt.Errorf("no release of %s matches %s", cfg.Repository, releaseFilterDescription(cfg))
+		}
+		return release, nil
+	}
+
+	release, err := github.GetRelease(client, cfg.Repository, cfg.Tag)
+	if err != nil {
+		return nil, err
+	}
+	if filtered && len(filterReleases(cfg, []github.Release{*release})) == 0 {
+		return nil, fmt.Errorf("release %s does not match %s: it has %d reactions and was created by %s", release.TagName, releaseFilterDescription(cfg), release.ReactionCount(), release.Author.Login)
+	}
+	return release, nil
+}
+
+// releaseFilterDescription describes --min-reactions and --author for errors
+func releaseFilterDescription(cfg config.Config) string {
+	var parts []string
+	if cfg.MinReactions > 0 {
+		parts = append(parts, fmt.Sprintf("--min-reactions %d", cfg.MinReactions))
+	}
+	if cfg.Author != "" {
+		parts = append(parts, "--author "+cfg.Author)
+	}
+	return strings.Join(parts, " ")
+}
+
+// retryPolicy returns the retry policy configured by --retries, --retry-on
+// and --retry-delay; --repair also retries checksum failures
+func retryPolicy(cfg config.Config) (retry.Policy, error) {
+	policy, err := retry.NewPolicy(cfg.Retries, cfg.RetryOn)
+	if err != nil {
+		return policy, err
+	}
+	if cfg.IsSet("retry-delay") {
+		if cfg.RetryDelay < 0 {
+			return policy, fmt.Errorf("invalid retry delay %s: must not be negative", cfg.RetryDelay)
+		}
+		policy.Backoff = cfg.RetryDelay
+	}
+	if cfg.Repair {
+		policy.Classes[retry.Checksum] = true
+	}
+	return policy, nil
+}
+
+// absPaths returns paths made absolute where possible
+func absPaths(paths []string) []string {
+	abs := make([]string, len(paths))
+	for i, path := range paths {
+		abs[i] = path
+		if resolved, err := filepath.Abs(path); err == nil {
+			abs[i] = resolved
+		}
+	}
+	return abs
+}
+
+// outputDirectory makes the directory of --output the directory of the
+// run. Relative paths are taken below --dir, as curl takes -o below
+// --output-dir.
+func outputDirectory(cfg config.Config) config.Config {
+	if cfg.Output == "" {
+		return cfg
+	}
+	if !filepath.IsAbs(cfg.Output) {
+		cfg.Output = filepath.Join(cfg.Directory, cfg.Output)
+	}
+	cfg.Directory = filepath.Dir(cfg.Output)
+	return cfg
+}
+
+// copyToStdout writes the single file a --stdout run downloaded to stdout
+func copyToStdout(stdout io.Writer, paths []string) error {
+	if len(paths) != 1 {
+		return fmt.Errorf("--stdout requires exactly one file, the run wrote %d", len(paths))
+	}
+	file, err := os.Open(paths[0])
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", paths[0], err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", paths[0], closeErr)
+		}
+	}()
+
+	if _, err := io.Copy(stdout, file); err != nil {
+		return fmt.Errorf("failed to write %s to stdout: %w", filepath.Base(paths[0]), err)
+	}
+	return nil
+}
+
+func printReleaseHeader(release *github.Release, resolved ResolvedRelease, repo string) {
+	fmt.Printf("Release: %s (%s) from %s%s\n", color.Stdout.Bold(release.Name), resolved, repo, releaseBadges(release))
+	if release.Author.Login != "" {
+		fmt.Printf("Author: %s (%s)\n", release.Author.Login, release.Author.Type)
+	}
+	if release.CommitSHA != "" {
+		fmt.Printf("Commit: %s", release.CommitSHA)
+		if release.TargetCommitish != "" && release.TargetCommitish != release.CommitSHA {
+			fmt.Printf(" (target: %s)", release.TargetCommitish)
+		}
+		fmt.Println()
+	}
+}
+
+// releaseBadges marks draft releases and prereleases, as gh does
+func releaseBadges(release *github.Release) string {
+	var badges string
+	if release.Draft {
+		badges += " " + color.Stdout.Red("[draft]")
+	}
+	if release.Prerelease {
+		badges += " " + color.Stdout.Yellow("[prerelease]")
+	}
+	return badges
+}
+
+// clientOptions routes the request logging of go-gh clients, which GH_DEBUG
+// turns on, through internal/log, colored as --color says, and traces their
+// requests with --debug-http
+func clientOptions(opts api.ClientOptions) api.ClientOptions {
+	opts.LogIgnoreEnv = true
+	if log.TracingHTTP() {
+		transport := opts.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		opts.Transport = log.TraceTransport(transport)
+	}
+	if log.Enabled(log.LevelDebug) {
+		opts.Log = log.Writer()
+		opts.LogColorize = color.Stderr.Enabled()
+		opts.LogVerboseHTTP = log.DebugAPI()
+	}
+	return opts
+}
+
+// newRESTClient returns a go-gh REST client, see clientOptions
+func newRESTClient(opts api.ClientOptions) (*api.RESTClient, error) {
+	return api.NewRESTClient(clientOptions(opts))
+}
+
+// newHTTPClient returns an authenticated go-gh HTTP client, see
+// clientOptions
+func newHTTPClient(opts api.ClientOptions) (*http.Client, error) {
+	return api.NewHTTPClient(clientOptions(opts))
+}
+
+// newPlainHTTPClient returns a client without GitHub credentials, for hosts
+// such as LFS servers and tap sources, logging its requests like the go-gh
+// clients
+func newPlainHTTPClient() *http.Client {
+	return &http.Client{Transport: log.Transport(log.TraceTransport(http.DefaultTransport))}
+}
+
+// assetClientOptions requests raw asset content instead of asset metadata
+func assetClientOptions() api.ClientOptions {
+	return api.ClientOptions{
+		Headers: map[string]string{"Accept": "application/octet-stream"},
+	}
+}
+
+// newAssetRESTClient returns a REST client that downloads raw asset content
+func newAssetRESTClient() (*api.RESTClient, error) {
+	return newRESTClient(assetClientOptions())
+}
+
+// newAssetHTTPClient returns an authenticated HTTP client that downloads raw
+// asset content, for requests needing custom headers such as Range.
+func newAssetHTTPClient() (*http.Client, error) {
+	return newHTTPClient(assetClientOptions())
+}
+
+// newAssetDoer returns an authenticated client that downloads raw asset
+// content through the shared middlewares, and then the given ones
+func newAssetDoer(middlewares ...middleware.Middleware) (middleware.Doer, error) {
+	client, err := newHTTPClient(api.ClientOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return middleware.Chain(client, append([]middleware.Middleware{
+		middleware.Trace(tracer),
+		middleware.RateLimit(),
+		middleware.Headers(assetClientOptions().Headers),
+	}, middlewares...)...), nil
+}
+
+// newArchiveDoer returns an authenticated client that downloads source
+// archives through the shared middlewares, retrying failed requests and
+// resuming cut transfers under policy
+func newArchiveDoer(policy retry.Policy) (middleware.Doer, error) {
+	client, err := newHTTPClient(api.ClientOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return middleware.Chain(client,
+		middleware.Trace(tracer),
+		middleware.RateLimit(),
+		middleware.Resume(policy),
+		middleware.Retry(policy),
+		middleware.CheckStatus(),
+	), nil
+}
+
+// archiveEndpoint returns the API endpoint and local file name of a source
+// archive.
+func archiveEndpoint(repo, tag, archiveFormat string) (string, string, error) {
 	if archiveFormat != "zip" && archiveFormat != "tar.gz" {
-		return fmt.Errorf("archive format must be 'zip' or 'tar.gz'")
+		return "", "", fmt.Errorf("archive format must be 'zip' or 'tar.gz'")
 	}
 
 	tagRef := tag
@@ -84,88 +925,398 @@ func downloadArchive(client *api.RESTClient, repo, tag, archiveFormat, dir strin
 		filename = fmt.Sprintf("%s-%s.tar.gz", strings.ReplaceAll(repo, "/", "-"), tagRef)
 	}
 
-	resp, err := client.Request("GET", endpoint, nil)
+	return endpoint, filename, nil
+}
+
+// archiveCommit returns the commit to name the source archive after. Without
+// a tag the archive is of the default branch, not of the release.
+func archiveCommit(cfg config.Config, release *github.Release) string {
+	if cfg.Tag == "" {
+		return ""
+	}
+	return release.CommitSHA
+}
+
+// archiveFileName appends the abbreviated commit, when known, to the file
+// name of a source archive, e.g. "owner-repo-v1.0.0-0123abc.tar.gz"
+func archiveFileName(filename, commit string) string {
+	if commit == "" {
+		return filename
+	}
+	return suffixFileName(filename, "-"+shortSHA(commit))
+}
+
+// downloadArchive saves the source archive of a tag into dir, named after
+// the commit when given, and returns its path. GitHub reports no size or
+// digest for archives, so under skipExisting one already there is kept
+// without comparing. The archive is written to a temporary file first, so a
+// failed transfer leaves no partial archive under its name.
+func downloadArchive(archives middleware.Doer, repo, tag, commit, archiveFormat, dir string, existing existingPolicy) (string, error) {
+	_, filename, err := archiveEndpoint(repo, tag, archiveFormat)
 	if err != nil {
-		return fmt.Errorf("failed to download archive: %w", err)
+		return "", err
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
-		}
-	}()
+	filename = archiveFileName(filename, commit)
 
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	root, err := confine.Open(dir)
+	if err != nil {
+		return "", err
 	}
+	defer closeRoot(root)
 
 	fullPath := filepath.Join(dir, filename)
-	file, err := os.Create(fullPath)
+	if _, err := root.Stat(fullPath); err == nil && existing != overwriteExisting {
+		if existing == failExisting {
+			return "", fmt.Errorf("%s already exists; use --clobber to overwrite it or --skip-existing to keep it", fullPath)
+		}
+		fmt.Printf("Skipped archive: %s already exists\n", fullPath)
+		return fullPath, nil
+	}
+
+	resp, err := requestArchive(archives, repo, tag, archiveFormat)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return "", err
 	}
 	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
 		}
 	}()
 
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if _, err := root.Copy(fullPath, resp.Body, 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
 	fmt.Printf("Downloaded archive: %s\n", fullPath)
-	return nil
+	return fullPath, nil
 }
 
-func downloadAssets(assets []github.Asset, dir string) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// requestArchive requests the source archive of a tag of repo through
+// archives, see newArchiveDoer
+func requestArchive(archives middleware.Doer, repo, tag, archiveFormat string) (*http.Response, error) {
+	url, err := archiveURL(nil, repo, tag, archiveFormat, true)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive: %w", err)
+	}
+	resp, err := archives.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive: %w", err)
 	}
+	return resp, nil
+}
 
-	// Create download client once with octet-stream header
-	opts := api.ClientOptions{
-		Headers: map[string]string{"Accept": "application/octet-stream"},
+// downloadAssets downloads assets into dir, checking each file's content
+// against its declared type; see checkContentType for renameByType.
+func downloadAssets(run *assetRun, assets []github.Asset, dir string, renameByType bool) error {
+	// Every file of the run is written through root, so no asset name can
+	// place one outside of dir
+	root, err := confine.Open(dir)
+	if err != nil {
+		return err
 	}
-	downloadClient, err := api.NewRESTClient(opts)
+	defer closeRoot(root)
+
+	// Create download client once with octet-stream header. Its middlewares
+	// serve cached assets, resume cut transfers and retry failed requests;
+	// only a download failing verification is started over below.
+	var middlewares []middleware.Middleware
+	if run.objects != nil {
+		middlewares = append(middlewares, middleware.Cache(run.objects))
+	}
+	middlewares = append(middlewares, middleware.Resume(run.policy), middleware.Retry(run.policy), middleware.CheckStatus())
+	downloadClient, err := newAssetDoer(middlewares...)
 	if err != nil {
 		return fmt.Errorf("failed to create download client: %w", err)
 	}
 
-	for _, asset := range assets {
-		fmt.Printf("Downloading %s... ", asset.Name)
+	fileNames := run.fileNames
+	if fileNames == nil {
+		fileNames = assetFileNames(assets)
+	}
+	failed, undone := len(run.failures), len(run.undone)
+	err = run.each(assets, logFailures(func(asset github.Asset) error {
+		run.begin("Downloading", asset.Name)
+		fullPath := filepath.Join(dir, fileNames[asset.ID])
+		log.Event(log.LevelDebug, "download_start", log.DownloadStart{Asset: asset.Name, Size: asset.Size, Path: fullPath}, "")
 
-		resp, err := downloadClient.Request("GET", asset.URL, nil)
+		var previous string
+		if run.skipUnchanged {
+			digest, err := existingSHA256(fullPath)
+			if err != nil {
+				return err
+			}
+			previous = digest
+			if previous != "" && asset.Digest == "sha256:"+previous {
+				run.done(asset.Name, "unchanged")
+				run.recordAsset(fullPath, asset.Name)
+				return writeSidecar(root, fullPath, run.sidecar)
+			}
+		}
+		skip, err := checkExisting(run.existing, asset, fullPath)
 		if err != nil {
-			return fmt.Errorf("failed to download %s: %w", asset.Name, err)
+			return err
+		}
+		if skip {
+			run.done(asset.Name, "skipped, already exists")
+			run.recordAsset(fullPath, asset.Name)
+			return writeSidecar(root, fullPath, run.sidecar)
 		}
 
-		fullPath := filepath.Join(dir, asset.Name)
-		file, err := os.Create(fullPath)
-		if err != nil {
-			if closeErr := resp.Body.Close(); closeErr != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		var written int64
+		var cached bool
+		started := time.Now()
+		client := downloadClient
+		if run.assetTimeout > 0 {
+			client = middleware.Chain(downloadClient, middleware.Deadline(started.Add(run.assetTimeout)))
+		}
+		// The external downloader bypasses the middlewares, so its transfers
+		// are retried here on every class of the policy
+		policy := run.policy.Only(retry.Checksum)
+		if run.downloader != nil {
+			policy = run.policy
+		}
+		bar := run.startBar(asset)
+		span := startAssetSpan("transfer", asset)
+		err = policy.Do(asset.Name, run.abandonOnTimeout(func() error {
+			// The digests of --checksum and GitHub are computed while
+			// streaming; the other transfers hash the file afterwards
+			var sum, digestSum hash.Hash
+			var err error
+			switch {
+			case run.downloader != nil && run.objects != nil && run.objects.Has(assetSHA256(asset)):
+				if written, err = run.objects.CopyTo(assetSHA256(asset), root, fullPath); err != nil {
+					return fmt.Errorf("failed to copy %s from the cache: %w", asset.Name, err)
+				}
+				cached = true
+			case run.downloader != nil:
+				// --output-template may put the file in a directory of its own
+				if err := root.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+					return err
+				}
+				if written, err = run.downloader.download(root, asset.URL, fullPath); err != nil {
+					return fmt.Errorf("failed to download %s: %w", asset.Name, err)
+				}
+			default:
+				var sinks []io.Writer
+				if run.pinned != nil {
+					if sum, err = run.pinned.Algorithm.New(); err != nil {
+						return err
+					}
+					sinks = append(sinks, sum)
+				}
+				if run.verifyDigest {
+					if digestSum, err = githubDigestHash(asset); err != nil {
+						return err
+					}
+					if digestSum != nil {
+						sinks = append(sinks, digestSum)
+					}
+				}
+				if err := run.prepareTransfer(client, root, asset, fullPath); err != nil {
+					return err
+				}
+				if written, cached, err = fetchAsset(client, root, asset, fullPath, bar, sinks...); err != nil {
+					return err
+				}
+			}
+			if err := verifyPinned(run.pinned, asset, fullPath, sum); err != nil {
+				return err
+			}
+			if err := run.checksums.verify(asset, fullPath); err != nil {
+				return err
 			}
-			return fmt.Errorf("failed to create file %s: %w", fullPath, err)
+			if run.verifyDigest {
+				if _, err := verifyGitHubDigest(asset, fullPath, digestSum); err != nil {
+					return discardUnverified(root, fullPath, err)
+				}
+			}
+			return nil
+		}))
+		bar.Finish()
+		span.SetAttr(tracing.Int("bytes", written))
+		span.End(err)
+		if err != nil {
+			return err
+		}
+		signed, err := run.signatures.verify(asset, fullPath)
+		if err != nil {
+			return err
+		}
+		var note string
+		if signed {
+			note = ", signature verified"
+		}
+		if signed, err = run.cosign.verify(asset, fullPath); err != nil {
+			return err
+		}
+		if signed {
+			note += ", sigstore signature verified"
+		}
+		if signed, err = run.provenance.verify(asset, fullPath); err != nil {
+			return err
+		}
+		if signed {
+			note += ", provenance verified"
 		}
 
-		written, err := io.Copy(file, resp.Body)
+		elapsed := time.Since(started).Round(time.Millisecond)
+		log.Event(log.LevelVerbose, "download_done", log.DownloadDone{Asset: asset.Name, Bytes: written, DurationMS: elapsed.Milliseconds(), Cached: cached, Path: fullPath},
+			"%s: %d bytes in %v", asset.Name, written, elapsed)
+		if cached {
+			log.Verbosef("%s: copied from the cache (sha256:%s)", asset.Name, assetSHA256(asset))
+			run.done(asset.Name, "done (%d bytes, from cache%s)", written, note)
+		} else {
+			run.done(asset.Name, "done (%d bytes%s)", written, note)
+			cacheAsset(run.objects, asset, fullPath)
+		}
 
-		// Close resources immediately after use
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
+		span = startAssetSpan("verify", asset)
+		finalPath, err := checkContentType(root, asset, fullPath, renameByType)
+		span.End(err)
+		if err != nil {
+			return err
+		}
+		run.recordAsset(finalPath, asset.Name)
+		if err := writeSidecar(root, finalPath, run.sidecar); err != nil {
+			return err
+		}
+
+		if !run.skipUnchanged || finalPath != fullPath {
+			run.markChanged()
+			return nil
+		}
+		current, err := fileSHA256(fullPath)
+		if err != nil {
+			return err
+		}
+		if current != previous {
+			run.markChanged()
 		}
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	succeeded := len(assets) - (len(run.failures) - failed) - (len(run.undone) - undone)
+	if succeeded == len(assets) {
+		fmt.Printf("Successfully downloaded %d assets to %s\n", len(assets), dir)
+	} else {
+		fmt.Printf("Downloaded %d of %d assets to %s\n", succeeded, len(assets), dir)
+	}
+	return nil
+}
+
+// logFailures logs the failure of fn for an asset as a verify_failed event
+// when the asset was downloaded but did not verify, and as download_failed
+// otherwise
+func logFailures(fn func(github.Asset) error) func(github.Asset) error {
+	return func(asset github.Asset) error {
+		err := fn(asset)
+		if err == nil {
+			return nil
+		}
+		event := "download_failed"
+		var verr *VerificationError
+		if errors.As(err, &verr) {
+			event = "verify_failed"
+		}
+		log.Event(log.LevelDebug, event, log.AssetFailed{Asset: asset.Name, Error: err.Error()}, "")
+		return err
+	}
+}
+
+// fetchAsset downloads the content of an asset to path below root and returns
+// its size, and whether the client served it from the cache, see
+// middleware.Cache. The content is written to a ".part" file first and only renamed
+// to path once complete, so an interrupted transfer never leaves a truncated
+// file under the final name; the next run resumes it with a range request.
+// The bytes are counted on bar, which may be nil, and the whole content is
+// also written to sums, such as hashes verifying it.
+func fetchAsset(client middleware.Doer, root *confine.Root, asset github.Asset, path string, bar *progress.Bar, sums ...io.Writer) (int64, bool, error) {
+	part := path + partSuffix
+	offset := resumeOffset(part, int64(asset.Size))
+	bar.Reset()
+
+	ctx := middleware.WithDigest(context.Background(), assetSHA256(asset))
+	req, err := http.NewRequestWithContext(ctx, "GET", asset.URL, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
 		}
+	}()
 
-		if err != nil {
-			return fmt.Errorf("failed to write %s: %w", fullPath, err)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		log.Event(log.LevelInfo, "download_resume", log.DownloadResume{Asset: asset.Name, Offset: offset},
+			"%s: resuming at %d bytes", asset.Name, offset)
+		bar.Add(offset)
+		if len(sums) > 0 {
+			if err := copyPrefix(io.MultiWriter(sums...), part, offset); err != nil {
+				return 0, false, err
+			}
 		}
+		flags = os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	default:
+		return 0, false, fmt.Errorf("failed to download %s: %w", asset.Name, api.HandleHTTPError(resp))
+	}
+
+	file, err := root.OpenFile(part, flags, 0644)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create file %s: %w", part, err)
+	}
+	written, err := io.Copy(file, io.TeeReader(resp.Body, io.MultiWriter(append(sums, bar)...)))
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to write %s: %w", part, err)
+	}
 
-		fmt.Printf("done (%d bytes)\n", written)
+	if err := root.Rename(part, path); err != nil {
+		return 0, false, fmt.Errorf("failed to rename %s: %w", part, err)
 	}
+	return offset + written, middleware.FromCache(resp), nil
+}
+
+// closeRoot closes a directory writes were confined to
+func closeRoot(root *confine.Root) {
+	if err := root.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", root.Dir(), err)
+	}
+}
 
-	fmt.Printf("Successfully downloaded %d assets to %s\n", len(assets), dir)
+// copyPrefix writes the first n bytes of the file at path to w, for a
+// transfer resuming after them
+func copyPrefix(w io.Writer, path string, n int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+	if _, err := io.CopyN(w, file, n); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
 	return nil
 }
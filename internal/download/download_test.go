@@ -1,10 +1,27 @@
 package download
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/23prime/gh-download/internal/cas"
+	"github.com/23prime/gh-download/internal/color"
 	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/log"
+	"github.com/23prime/gh-download/internal/middleware"
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/cli/go-gh/v2/pkg/api"
 )
 
 func TestDownloadFromRelease_EmptyRepository(t *testing.T) {
@@ -62,3 +79,458 @@ func TestDownloadFromRelease_InvalidRepository(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadFromRelease_PrintPathsRestoresStdout(t *testing.T) {
+	stdout := os.Stdout
+
+	err := DownloadFromRelease(config.Config{PrintPaths: true})
+	if err == nil {
+		t.Fatal("Expected error for empty repository, got nil")
+	}
+	if os.Stdout != stdout {
+		t.Error("Expected stdout to be restored after the run")
+	}
+}
+
+func TestDownloadFromRelease_QuietRestoresStdout(t *testing.T) {
+	stdout := os.Stdout
+
+	err := DownloadFromRelease(config.Config{Quiet: true})
+	if err == nil {
+		t.Fatal("Expected error for empty repository, got nil")
+	}
+	if os.Stdout != stdout {
+		t.Error("Expected stdout to be restored after the run")
+	}
+}
+
+func TestReleaseBadges(t *testing.T) {
+	release := &github.Release{Draft: true, Prerelease: true}
+	if got := releaseBadges(release); got != " [draft] [prerelease]" {
+		t.Errorf("Expected both badges, got %q", got)
+	}
+	if got := releaseBadges(&github.Release{}); got != "" {
+		t.Errorf("Expected no badges for a published release, got %q", got)
+	}
+
+	if err := color.Setup(color.ModeAlways); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := color.Setup(color.ModeNever); err != nil {
+			t.Error(err)
+		}
+	})
+	if got := releaseBadges(release); got != " \x1b[31m[draft]\x1b[0m \x1b[33m[prerelease]\x1b[0m" {
+		t.Errorf("Expected colored badges, got %q", got)
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	opts := clientOptions(api.ClientOptions{Host: "ghe.example.com"})
+	if !opts.LogIgnoreEnv || opts.Log != nil || opts.Host != "ghe.example.com" {
+		t.Errorf("Expected no logging and the environment ignored, got %+v", opts)
+	}
+
+	t.Setenv("GH_DEBUG", "api")
+	log.FromEnv()
+	t.Cleanup(func() { log.SetLevel(log.LevelInfo) })
+	opts = clientOptions(api.ClientOptions{})
+	if opts.Log == nil || !opts.LogVerboseHTTP {
+		t.Errorf("Expected GH_DEBUG=api to log requests with their bodies, got %+v", opts)
+	}
+
+	if opts.Transport != nil {
+		t.Error("Expected the default transport without --debug-http")
+	}
+	log.SetTraceHTTP(true)
+	t.Cleanup(func() { log.SetTraceHTTP(false) })
+	if opts = clientOptions(api.ClientOptions{}); opts.Transport == nil {
+		t.Error("Expected a tracing transport with --debug-http")
+	}
+}
+
+func TestArchiveFileName(t *testing.T) {
+	testCases := []struct {
+		filename string
+		commit   string
+		expected string
+	}{
+		{"owner-repo-v1.0.0.tar.gz", "0123456789abcdef", "owner-repo-v1.0.0-0123456.tar.gz"},
+		{"owner-repo-v1.0.0.zip", "0123456789abcdef", "owner-repo-v1.0.0-0123456.zip"},
+		{"owner-repo-HEAD.zip", "", "owner-repo-HEAD.zip"},
+	}
+
+	for _, tc := range testCases {
+		if got := archiveFileName(tc.filename, tc.commit); got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestArchiveCommit(t *testing.T) {
+	release := &github.Release{TagName: "v1.0.0", CommitSHA: "0123456789abcdef"}
+	if got := archiveCommit(config.Config{Tag: "v1.0.0"}, release); got != release.CommitSHA {
+		t.Errorf("Expected %q, got %q", release.CommitSHA, got)
+	}
+	if got := archiveCommit(config.Config{}, release); got != "" {
+		t.Errorf("Expected no commit for the default branch archive, got %q", got)
+	}
+}
+
+func TestUseFallbackArchive(t *testing.T) {
+	empty := &github.Release{TagName: "v1.0.0"}
+	withAssets := &github.Release{TagName: "v1.0.0", Assets: []github.Asset{{Name: "app.zip"}}}
+
+	testCases := []struct {
+		name     string
+		cfg      config.Config
+		release  *github.Release
+		expected bool
+	}{
+		{"no assets", config.Config{FallbackArchive: "tar.gz"}, empty, true},
+		{"with assets", config.Config{FallbackArchive: "tar.gz"}, withAssets, false},
+		{"without the flag", config.Config{}, empty, false},
+		{"explicit archive", config.Config{FallbackArchive: "tar.gz", Archive: "zip"}, empty, false},
+	}
+
+	for _, tc := range testCases {
+		if got := useFallbackArchive(tc.cfg, tc.release); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestDownloadReleaseAssets_NoAssets(t *testing.T) {
+	_, _, _, err := downloadReleaseAssets(config.Config{Pattern: "*"}, &github.Release{TagName: "v1.0.0"}, nil)
+	if !errors.Is(err, errNoMatchingAssets) {
+		t.Fatalf("Expected errNoMatchingAssets, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "--fallback-archive") {
+		t.Errorf("Expected the error to suggest --fallback-archive, got %v", err)
+	}
+}
+
+func TestDownloadReleaseAssets_StdoutNeedsOneAsset(t *testing.T) {
+	release := &github.Release{TagName: "v1.0.0", Assets: []github.Asset{{ID: 1, Name: "app.tar.gz"}, {ID: 2, Name: "app.zip"}}}
+	_, _, _, err := downloadReleaseAssets(config.Config{Pattern: "app.*", Stdout: true}, release, nil)
+	if err == nil || !strings.Contains(err.Error(), "exactly one matching asset, 2 match") {
+		t.Errorf("Expected an error for two matching assets, got %v", err)
+	}
+	_, _, _, err = downloadReleaseAssets(config.Config{Pattern: "app.*", Output: "app"}, release, nil)
+	if err == nil || !strings.Contains(err.Error(), "--output requires exactly one") {
+		t.Errorf("Expected an error for two matching assets, got %v", err)
+	}
+}
+
+func TestOutputDirectory(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cfg       config.Config
+		directory string
+		output    string
+	}{
+		{"unset", config.Config{Directory: "tools"}, "tools", ""},
+		{"relative", config.Config{Directory: "tools", Output: "bin/app"}, filepath.Join("tools", "bin"), filepath.Join("tools", "bin", "app")},
+		{"absolute", config.Config{Directory: "tools", Output: "/opt/app"}, "/opt", "/opt/app"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := outputDirectory(tc.cfg)
+			if cfg.Directory != tc.directory || cfg.Output != tc.output {
+				t.Errorf("Expected %q in %q, got %q in %q", tc.output, tc.directory, cfg.Output, cfg.Directory)
+			}
+		})
+	}
+}
+
+func TestCopyToStdout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.tar.gz")
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := copyToStdout(&out, []string{path}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if out.String() != "content" {
+		t.Errorf("Expected the file content, got %q", out.String())
+	}
+	if err := copyToStdout(&out, []string{path, path}); err == nil {
+		t.Error("Expected an error for two files, got nil")
+	}
+}
+
+func TestFetchAsset_Resume(t *testing.T) {
+	content := "0123456789"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Range") {
+		case "":
+			_, _ = w.Write([]byte(content))
+		case "bytes=4-":
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte(content[4:]))
+		default:
+			t.Errorf("Unexpected range %q", r.Header.Get("Range"))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	asset := github.Asset{Name: "app.bin", URL: server.URL, Size: len(content)}
+	path := filepath.Join(dir, "app.bin")
+
+	if err := os.WriteFile(path+partSuffix, []byte(content[:4]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.New()
+	size, _, err := fetchAsset(server.Client(), testRoot(t, dir), asset, path, nil, sum)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := hex.EncodeToString(sum.Sum(nil)); got != sha256Hex(content) {
+		t.Errorf("Expected the digest of the whole file after resuming, got %s", got)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content || size != int64(len(content)) {
+		t.Errorf("Expected %q (%d bytes), got %q (%d bytes)", content, len(content), data, size)
+	}
+	if _, err := os.Stat(path + partSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be renamed, got %v", err)
+	}
+
+	if _, _, err := fetchAsset(server.Client(), testRoot(t, dir), asset, path, nil); err != nil {
+		t.Fatalf("Expected no error for a fresh download, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("Expected %q, got %q", content, data)
+	}
+}
+
+func TestFetchAsset_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.bin")
+	_, _, err := fetchAsset(server.Client(), testRoot(t, dir), github.Asset{Name: "app.bin", URL: server.URL, Size: 10}, path, nil)
+	if class, ok := retry.Classify(err); !ok || class != retry.ServerError {
+		t.Errorf("Expected a retryable server error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no file to be written, got %v", err)
+	}
+}
+
+func TestFetchAsset_Cache(t *testing.T) {
+	content := "0123456789"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	objects := cas.Store{Root: t.TempDir()}
+	client := middleware.Chain(server.Client(), middleware.Cache(objects))
+	asset := github.Asset{Name: "app.bin", URL: server.URL, Size: len(content), Digest: "sha256:" + sha256Hex(content)}
+
+	for _, cached := range []bool{false, true} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.bin")
+		size, fromCache, err := fetchAsset(client, testRoot(t, dir), asset, path, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if fromCache != cached || size != int64(len(content)) {
+			t.Errorf("Expected %d bytes with cached %v, got %d bytes with cached %v", len(content), cached, size, fromCache)
+		}
+		if data, _ := os.ReadFile(path); string(data) != content {
+			t.Errorf("Expected %q, got %q", content, data)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected the second download to be served from the cache, got %d requests", requests)
+	}
+}
+
+func TestDownloadArchive(t *testing.T) {
+	content := "archive content"
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/v3/repos/owner/repo/tarball/v1.0.0" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		// The first attempt fails, and is retried by the middlewares
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+	t.Setenv("GH_HOST", strings.TrimPrefix(server.URL, "https://"))
+
+	policy := retry.Policy{Retries: 2, Classes: map[retry.Class]bool{retry.ServerError: true}, Sleep: func(time.Duration) {}}
+	archives := middleware.Chain(server.Client(), middleware.Resume(policy), middleware.Retry(policy), middleware.CheckStatus())
+	dir := t.TempDir()
+
+	path, err := downloadArchive(archives, "owner/repo", "v1.0.0", "", "tar.gz", dir, failExisting)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content || path != filepath.Join(dir, "owner-repo-v1.0.0.tar.gz") {
+		t.Errorf("Expected %q at owner-repo-v1.0.0.tar.gz, got %q at %s", content, data, path)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+
+	if _, err := downloadArchive(archives, "owner/repo", "v1.0.0", "", "tar.gz", dir, failExisting); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an existing archive to fail, got %v", err)
+	}
+	if _, err := downloadArchive(archives, "owner/repo", "v1.0.0", "", "tar.gz", dir, skipExisting); err != nil || requests != 2 {
+		t.Errorf("Expected an existing archive to be skipped without a request, got %d requests and %v", requests, err)
+	}
+}
+
+// testRoot confines the writes of a test to dir
+func testRoot(t *testing.T, dir string) *confine.Root {
+	t.Helper()
+	root, err := confine.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeRoot(root) })
+	return root
+}
+
+func TestFetchAsset_Confined(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pwned"))
+	}))
+	defer server.Close()
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "downloads")
+	outside := filepath.Join(parent, "outside")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	root := testRoot(t, dir)
+	// A partial file planted as a symlink must not redirect the transfer
+	if err := os.Symlink(filepath.Join(outside, "target"), filepath.Join(dir, "app.bin"+partSuffix)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "sub")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(dir, "app.bin"),
+		filepath.Join(dir, "..", "outside", "app.bin"),
+		filepath.Join(dir, "sub", "app.bin"),
+	} {
+		asset := github.Asset{Name: filepath.Base(path), URL: server.URL, Size: 5}
+		if _, _, err := fetchAsset(server.Client(), root, asset, path, nil); !errors.Is(err, confine.ErrOutside) {
+			t.Errorf("Expected ErrOutside for %s, got %v", path, err)
+		}
+	}
+
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing written outside the directory, got %v", entries)
+	}
+}
+
+func TestLogFailures(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.SetOutput(&buf)
+	if err := log.SetFormat(log.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		log.SetOutput(previous)
+		if err := log.SetFormat(log.FormatText); err != nil {
+			t.Error(err)
+		}
+	})
+
+	failing := logFailures(func(asset github.Asset) error {
+		if asset.Name == "app.zip" {
+			return &VerificationError{Err: errors.New("checksum mismatch")}
+		}
+		return errors.New("connection reset")
+	})
+	if err := failing(github.Asset{Name: "app.zip"}); err == nil {
+		t.Error("Expected the error to be returned, got nil")
+	}
+	if err := failing(github.Asset{Name: "app.tar.gz"}); err == nil {
+		t.Error("Expected the error to be returned, got nil")
+	}
+	if err := logFailures(func(github.Asset) error { return nil })(github.Asset{Name: "ok"}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events, got %q", buf.String())
+	}
+	for i, expected := range []string{
+		`"event":"verify_failed","asset":"app.zip","error":"checksum mismatch"}`,
+		`"event":"download_failed","asset":"app.tar.gz","error":"connection reset"}`,
+	} {
+		if !strings.HasSuffix(lines[i], expected) {
+			t.Errorf("Expected event %d to end with %s, got %s", i, expected, lines[i])
+		}
+	}
+}
+
+func TestDownloadAssets_DigestMismatchRunsAgain(t *testing.T) {
+	t.Setenv("GH_TOKEN", "test-token")
+	content := "good content"
+	served := "tampered content"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(served))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	asset := github.Asset{ID: 1, Name: "app.bin", URL: server.URL, Size: len(content), Digest: "sha256:" + sha256Hex(content)}
+	download := func() error {
+		run := newAssetRun(1, false)
+		run.verifyDigest = true
+		var err error
+		captureStdout(t, func() {
+			err = downloadAssets(run, []github.Asset{asset}, dir, false)
+		})
+		return err
+	}
+
+	err := download()
+	if ExitCode(err) != ExitVerificationFailed || !strings.Contains(err.Error(), "the file was deleted") {
+		t.Fatalf("Expected a verification failure deleting the file, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.bin")); !os.IsNotExist(err) {
+		t.Errorf("Expected the mismatching file to be deleted, got %v", err)
+	}
+
+	served = content
+	if err := download(); err != nil {
+		t.Fatalf("Expected the next run to download the asset again, got %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "app.bin")); err != nil || string(data) != content {
+		t.Errorf("Expected %q, got %q (%v)", content, data, err)
+	}
+}
@@ -0,0 +1,117 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/confine"
+)
+
+// downloaderArgs are the argument templates of the downloaders known to
+// --downloader
+var downloaderArgs = map[string]string{
+	"aria2c": "--quiet --allow-overwrite=true --auto-file-renaming=false --split=8 --max-connection-per-server=8 --dir {dir} --out {name} {url}",
+	"curl":   "--fail --location --silent --show-error --output {path} {url}",
+	"wget":   "--quiet --output-document {path} {url}",
+}
+
+// externalDownloader delegates the transfer of assets to another program.
+// The program receives a pre-authorized URL, so it needs no credentials;
+// resolution, naming and the checks after the transfer stay here.
+type externalDownloader struct {
+	program   string
+	args      []string
+	redirects *http.Client
+}
+
+// newExternalDownloader returns a downloader running program with the
+// whitespace-separated argument template, or the built-in template of a known
+// program when it is empty. Templates may use {url}, {path}, {dir} and {name}.
+func newExternalDownloader(program, template string) (*externalDownloader, error) {
+	if template == "" {
+		name := strings.TrimSuffix(filepath.Base(program), ".exe")
+		known, ok := downloaderArgs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown downloader '%s': set its arguments with --downloader-args", program)
+		}
+		template = known
+	}
+	if !strings.Contains(template, "{url}") {
+		return nil, fmt.Errorf("downloader arguments must contain {url}")
+	}
+	if _, err := exec.LookPath(program); err != nil {
+		return nil, fmt.Errorf("downloader not found: %w", err)
+	}
+
+	redirects, err := newRedirectClient()
+	if err != nil {
+		return nil, err
+	}
+	return &externalDownloader{program: program, args: strings.Fields(template), redirects: redirects}, nil
+}
+
+// command returns the command transferring url to path
+func (d *externalDownloader) command(url, path string) *exec.Cmd {
+	replacer := strings.NewReplacer(
+		"{url}", url,
+		"{path}", path,
+		"{dir}", filepath.Dir(path),
+		"{name}", filepath.Base(path),
+	)
+	args := make([]string, len(d.args))
+	for i, arg := range d.args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	cmd := exec.Command(d.program, args...)
+	// Keep stdout for our own output, which may be machine-readable
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// download transfers the asset at the API URL to path below root and
+// returns the number of bytes written. The program cannot be confined, so it
+// writes to a directory of its own created directly in root, and the file
+// is then moved to path through root.
+func (d *externalDownloader) download(root *confine.Root, url, path string) (int64, error) {
+	location, err := redirectLocation(d.redirects, url)
+	if err != nil {
+		return 0, err
+	}
+
+	staging, err := os.MkdirTemp(root.Dir(), ".gh-download-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	staged := filepath.Join(staging, filepath.Base(path))
+	defer func() {
+		if err := root.Remove(staged); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", staged, err)
+		}
+		if err := root.Remove(staging); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", staging, err)
+		}
+	}()
+
+	if err := d.command(location, staged).Run(); err != nil {
+		return 0, fmt.Errorf("%s failed: %w", d.program, err)
+	}
+
+	info, err := root.Lstat(staged)
+	if err != nil {
+		return 0, fmt.Errorf("%s did not write %s: %w", d.program, path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("%s did not write a regular file to %s", d.program, path)
+	}
+	if err := root.Rename(staged, path); err != nil {
+		return 0, fmt.Errorf("failed to move %s into place: %w", path, err)
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,98 @@
+package download
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/confine"
+)
+
+func TestNewExternalDownloader_Errors(t *testing.T) {
+	if _, err := newExternalDownloader("axel", ""); err == nil {
+		t.Error("Expected error for an unknown downloader without arguments, got nil")
+	}
+	if _, err := newExternalDownloader("cp", "{path}"); err == nil {
+		t.Error("Expected error for arguments without {url}, got nil")
+	}
+	if _, err := newExternalDownloader("gh-download-missing-program", "{url}"); err == nil {
+		t.Error("Expected error for a missing program, got nil")
+	}
+}
+
+func TestExternalDownloader_Command(t *testing.T) {
+	d := &externalDownloader{program: "aria2c", args: []string{"--dir", "{dir}", "--out", "{name}", "{url}"}}
+
+	cmd := d.command("https://objects.example.com/signed", filepath.Join("out", "tool v1.zip"))
+	expected := []string{"aria2c", "--dir", "out", "--out", "tool v1.zip", "https://objects.example.com/signed"}
+	if !slices.Equal(cmd.Args, expected) {
+		t.Errorf("Expected %q, got %q", expected, cmd.Args)
+	}
+}
+
+func TestExternalDownloader_Download(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	if err := os.WriteFile(source, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The "pre-authorized URL" is a local path so that cp can transfer it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", source)
+		w.WriteHeader(http.StatusFound)
+	}))
+	defer server.Close()
+
+	redirects := server.Client()
+	redirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	d := &externalDownloader{program: "cp", args: []string{"{url}", "{path}"}, redirects: redirects}
+
+	out := t.TempDir()
+	root := testRoot(t, out)
+	target := filepath.Join(out, "asset.bin")
+	written, err := d.download(root, server.URL+"/asset", target)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if written != int64(len("content")) {
+		t.Errorf("Expected %d bytes, got %d", len("content"), written)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "content" {
+		t.Errorf("Expected the file to be moved into place, got %q, %v", data, err)
+	}
+
+	// The file reaches its path through root only
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(out, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.download(root, server.URL+"/asset", filepath.Join(out, "link", "asset.bin")); !errors.Is(err, confine.ErrOutside) {
+		t.Errorf("Expected ErrOutside for a path through a symlink, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "asset.bin")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside the directory, got %v", err)
+	}
+
+	d.program = "false"
+	if _, err := d.download(root, server.URL+"/asset", target); err == nil {
+		t.Error("Expected error when the downloader fails, got nil")
+	}
+
+	// No staging directory is left behind
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "asset.bin" && entry.Name() != "link" {
+			t.Errorf("Unexpected entry %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// existingPolicy is what a run does with an asset whose file already exists
+type existingPolicy int
+
+const (
+	// overwriteExisting replaces the file, as --clobber, plan --apply and
+	// verify --repair do
+	overwriteExisting existingPolicy = iota
+	// failExisting fails the asset, as gh release download does by default
+	failExisting
+	// skipExisting keeps a file identical to its asset and fails the asset
+	// when the file differs
+	skipExisting
+)
+
+// existingFilePolicy returns the policy of a download for files already at
+// the paths of its assets: --clobber overwrites them, --skip-existing skips
+// those identical to their asset and otherwise they are an error.
+// --idempotent-json is meant to be run again and again, so it updates the
+// files that changed.
+func existingFilePolicy(cfg config.Config) (existingPolicy, error) {
+	switch {
+	case cfg.Clobber && cfg.SkipExisting:
+		return 0, fmt.Errorf("--clobber and --skip-existing cannot be used together")
+	case cfg.SkipExisting:
+		return skipExisting, nil
+	case cfg.Clobber, cfg.IdempotentJSON:
+		return overwriteExisting, nil
+	default:
+		return failExisting, nil
+	}
+}
+
+// checkExisting applies policy to the file of an asset at path and reports
+// whether the asset is to be skipped. Under skipExisting a file is identical
+// when it has the size of the asset and, when GitHub reports one, its
+// digest; the digest is only computed for files of the right size.
+func checkExisting(policy existingPolicy, asset github.Asset, path string) (bool, error) {
+	if policy == overwriteExisting {
+		return false, nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	if policy == failExisting {
+		return false, fmt.Errorf("%s already exists; use --clobber to overwrite it or --skip-existing to keep it", path)
+	}
+
+	identical := info.Mode().IsRegular() && info.Size() == int64(asset.Size)
+	if algorithm, expected, ok := githubDigest(asset); identical && ok {
+		actual, err := checksum.File(path, algorithm)
+		if err != nil {
+			return false, err
+		}
+		identical = actual == expected
+	}
+	if !identical {
+		return false, fmt.Errorf("%s already exists and differs from %s; use --clobber to overwrite it", path, asset.Name)
+	}
+	return true, nil
+}
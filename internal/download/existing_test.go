@@ -0,0 +1,80 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestExistingFilePolicy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      config.Config
+		expected existingPolicy
+	}{
+		{"default", config.Config{}, failExisting},
+		{"clobber", config.Config{Clobber: true}, overwriteExisting},
+		{"skip existing", config.Config{SkipExisting: true}, skipExisting},
+		{"idempotent json", config.Config{IdempotentJSON: true}, overwriteExisting},
+		{"idempotent json skip existing", config.Config{IdempotentJSON: true, SkipExisting: true}, skipExisting},
+	}
+	for _, tc := range testCases {
+		policy, err := existingFilePolicy(tc.cfg)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+		}
+		if policy != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, policy)
+		}
+	}
+
+	if _, err := existingFilePolicy(config.Config{Clobber: true, SkipExisting: true}); err == nil {
+		t.Error("Expected an error for --clobber with --skip-existing")
+	}
+}
+
+func TestCheckExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.tar.gz")
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	identical := github.Asset{Name: "app.tar.gz", Size: 7, Digest: "sha256:" + sha256Hex("content")}
+	undigested := github.Asset{Name: "app.tar.gz", Size: 7}
+	changed := github.Asset{Name: "app.tar.gz", Size: 7, Digest: "sha256:" + sha256Hex("CONTENT")}
+	resized := github.Asset{Name: "app.tar.gz", Size: 8}
+
+	testCases := []struct {
+		name   string
+		policy existingPolicy
+		asset  github.Asset
+		path   string
+		skip   bool
+		err    string
+	}{
+		{"missing", failExisting, identical, filepath.Join(dir, "missing"), false, ""},
+		{"overwrite", overwriteExisting, changed, path, false, ""},
+		{"fail", failExisting, identical, path, false, "already exists; use --clobber"},
+		{"skip identical", skipExisting, identical, path, true, ""},
+		{"skip by size", skipExisting, undigested, path, true, ""},
+		{"skip changed", skipExisting, changed, path, false, "differs from app.tar.gz"},
+		{"skip resized", skipExisting, resized, path, false, "differs from app.tar.gz"},
+		{"skip directory", skipExisting, github.Asset{Name: "dir", Size: 0}, dir, false, "differs from dir"},
+	}
+	for _, tc := range testCases {
+		skip, err := checkExisting(tc.policy, tc.asset, tc.path)
+		if tc.err == "" && err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.name, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.err, err)
+		}
+		if skip != tc.skip {
+			t.Errorf("%s: expected skip %v, got %v", tc.name, tc.skip, skip)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// exportRelease is a release as written by export. Timestamps are RFC 3339
+// in UTC, and omitted when the API has none, e.g. for drafts.
+type exportRelease struct {
+	Repository      string        `json:"repository"`
+	ID              int           `json:"id"`
+	Tag             string        `json:"tag"`
+	Name            string        `json:"name"`
+	Draft           bool          `json:"draft"`
+	Prerelease      bool          `json:"prerelease"`
+	CreatedAt       string        `json:"created_at,omitempty"`
+	PublishedAt     string        `json:"published_at,omitempty"`
+	Author          string        `json:"author,omitempty"`
+	TargetCommitish string        `json:"target_commitish,omitempty"`
+	Reactions       int           `json:"reactions"`
+	Body            string        `json:"body"`
+	Assets          []exportAsset `json:"assets"`
+}
+
+type exportAsset struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Label         string `json:"label,omitempty"`
+	ContentType   string `json:"content_type"`
+	State         string `json:"state,omitempty"`
+	Size          int    `json:"size"`
+	DownloadCount int    `json:"download_count"`
+	Digest        string `json:"digest,omitempty"`
+	CreatedAt     string `json:"created_at,omitempty"`
+	UpdatedAt     string `json:"updated_at,omitempty"`
+	URL           string `json:"url"`
+}
+
+// Export writes the metadata of a release, or with --all of every release,
+// with all asset metadata to --output or stdout, as one JSON array or, with
+// --format ndjson or an .ndjson or .jsonl output file, one release per line.
+func Export(cfg config.Config) (err error) {
+	if cfg.Repository == "" {
+		return fmt.Errorf("repository is required")
+	}
+	ndjson, err := exportNDJSON(cfg)
+	if err != nil {
+		return err
+	}
+	if err := validateSort(cfg.Sort); err != nil {
+		return err
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+
+	var releases []github.Release
+	if cfg.All {
+		if releases, err = github.GetAllReleases(client, cfg.Repository); err != nil {
+			return fmt.Errorf("failed to get releases: %w", err)
+		}
+	} else {
+		release, err := github.GetRelease(client, cfg.Repository, cfg.Tag)
+		if err != nil {
+			return fmt.Errorf("failed to get release: %w", err)
+		}
+		releases = []github.Release{*release}
+	}
+
+	releases = sortReleases(releases, cfg.Sort)
+	exported := make([]exportRelease, len(releases))
+	for i, release := range releases {
+		release.Assets = sortAssets(release.Assets, cfg.Sort)
+		exported[i] = exportedRelease(cfg.Repository, release)
+	}
+
+	if cfg.Output == "" {
+		return writeExport(os.Stdout, exported, ndjson)
+	}
+
+	file, err := os.Create(cfg.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", cfg.Output, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	if err := writeExport(file, exported, ndjson); err != nil {
+		return fmt.Errorf("failed to write %s: %w", cfg.Output, err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d releases of %s to %s\n", len(exported), cfg.Repository, cfg.Output)
+	return nil
+}
+
+// exportNDJSON decides between a JSON array and NDJSON from --format or the
+// extension of --output
+func exportNDJSON(cfg config.Config) (bool, error) {
+	switch cfg.Format {
+	case "json":
+		return false, nil
+	case "ndjson":
+		return true, nil
+	case "":
+		ext := strings.ToLower(filepath.Ext(cfg.Output))
+		return ext == ".ndjson" || ext == ".jsonl", nil
+	default:
+		return false, fmt.Errorf("export format must be 'json' or 'ndjson', got %q", cfg.Format)
+	}
+}
+
+func writeExport(w io.Writer, releases []exportRelease, ndjson bool) error {
+	encoder := json.NewEncoder(w)
+	if ndjson {
+		for _, release := range releases {
+			if err := encoder.Encode(release); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(releases)
+}
+
+func exportedRelease(repository string, release github.Release) exportRelease {
+	exported := exportRelease{
+		Repository:      repository,
+		ID:              release.ID,
+		Tag:             release.TagName,
+		Name:            release.Name,
+		Draft:           release.Draft,
+		Prerelease:      release.Prerelease,
+		CreatedAt:       normalizeTimestamp(release.CreatedAt),
+		PublishedAt:     normalizeTimestamp(release.PublishedAt),
+		Author:          release.Author.Login,
+		TargetCommitish: release.TargetCommitish,
+		Reactions:       release.ReactionCount(),
+		Body:            release.Body,
+		Assets:          make([]exportAsset, len(release.Assets)),
+	}
+	for i, asset := range release.Assets {
+		exported.Assets[i] = exportAsset{
+			ID:            asset.ID,
+			Name:          asset.Name,
+			Label:         asset.Label,
+			ContentType:   asset.ContentType,
+			State:         asset.State,
+			Size:          asset.Size,
+			DownloadCount: asset.DownloadCount,
+			Digest:        asset.Digest,
+			CreatedAt:     normalizeTimestamp(asset.CreatedAt),
+			UpdatedAt:     normalizeTimestamp(asset.UpdatedAt),
+			URL:           asset.BrowserDownloadURL,
+		}
+	}
+	return exported
+}
+
+// normalizeTimestamp returns an API timestamp as RFC 3339 in UTC; values that
+// cannot be parsed are kept as they are
+func normalizeTimestamp(value string) string {
+	if value == "" {
+		return ""
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.UTC().Format(time.RFC3339)
+}
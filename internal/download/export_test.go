@@ -0,0 +1,105 @@
+package download
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestExportedRelease(t *testing.T) {
+	release := github.Release{
+		ID:          1,
+		TagName:     "v1.0.0",
+		Name:        "First",
+		CreatedAt:   "2024-05-01T09:00:00+09:00",
+		PublishedAt: "",
+		Author:      github.User{Login: "octocat"},
+		Reactions:   &github.Reactions{TotalCount: 2, PlusOne: 2},
+		Assets: []github.Asset{{
+			ID:                 10,
+			Name:               "tool.tar.gz",
+			Size:               42,
+			DownloadCount:      7,
+			CreatedAt:          "2024-05-01T00:00:00Z",
+			BrowserDownloadURL: "https://github.com/owner/repo/releases/download/v1.0.0/tool.tar.gz",
+		}},
+	}
+
+	exported := exportedRelease("owner/repo", release)
+	if exported.Repository != "owner/repo" || exported.Tag != "v1.0.0" || exported.Author != "octocat" || exported.Reactions != 2 {
+		t.Errorf("Unexpected release %+v", exported)
+	}
+	if exported.CreatedAt != "2024-05-01T00:00:00Z" || exported.PublishedAt != "" {
+		t.Errorf("Expected timestamps normalized to UTC, got %q and %q", exported.CreatedAt, exported.PublishedAt)
+	}
+	if len(exported.Assets) != 1 || exported.Assets[0].DownloadCount != 7 || exported.Assets[0].URL == "" {
+		t.Errorf("Unexpected assets %+v", exported.Assets)
+	}
+}
+
+func TestNormalizeTimestamp(t *testing.T) {
+	testCases := map[string]string{
+		"":                          "",
+		"2024-05-01T12:00:00Z":      "2024-05-01T12:00:00Z",
+		"2024-05-01T12:00:00-02:00": "2024-05-01T14:00:00Z",
+		"yesterday":                 "yesterday",
+	}
+	for value, expected := range testCases {
+		if got := normalizeTimestamp(value); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, value, got)
+		}
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	testCases := []struct {
+		cfg      config.Config
+		expected bool
+	}{
+		{config.Config{}, false},
+		{config.Config{Output: "releases.json"}, false},
+		{config.Config{Output: "releases.NDJSON"}, true},
+		{config.Config{Output: "releases.jsonl"}, true},
+		{config.Config{Output: "releases.jsonl", Format: "json"}, false},
+		{config.Config{Format: "ndjson"}, true},
+	}
+	for _, tc := range testCases {
+		got, err := exportNDJSON(tc.cfg)
+		if err != nil || got != tc.expected {
+			t.Errorf("Expected %t for %+v, got %t, %v", tc.expected, tc.cfg, got, err)
+		}
+	}
+	if _, err := exportNDJSON(config.Config{Format: "csv"}); err == nil {
+		t.Error("Expected error for an unknown format, got nil")
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	releases := []exportRelease{{Tag: "v2", Assets: []exportAsset{}}, {Tag: "v1", Assets: []exportAsset{}}}
+
+	var buf bytes.Buffer
+	if err := writeExport(&buf, releases, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	var first exportRelease
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.Tag != "v2" {
+		t.Errorf("Unexpected first line %q: %v", lines[0], err)
+	}
+
+	buf.Reset()
+	if err := writeExport(&buf, releases, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var all []exportRelease
+	if err := json.Unmarshal(buf.Bytes(), &all); err != nil || len(all) != 2 {
+		t.Errorf("Expected a JSON array of 2 releases, got %q: %v", buf.String(), err)
+	}
+}
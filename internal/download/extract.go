@@ -0,0 +1,157 @@
+package download
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/extract"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/middleware"
+	"github.com/23prime/gh-download/internal/remote"
+	"github.com/23prime/gh-download/internal/tracing"
+)
+
+func extractOptions(cfg config.Config) extract.Options {
+	return extract.Options{
+		Include:         cfg.Include,
+		StripComponents: cfg.Strip,
+	}
+}
+
+// splitExtractable separates zip assets, which can be extracted remotely,
+// and tar.gz assets, which are extracted while streaming, from the rest.
+func splitExtractable(assets []github.Asset) (zips, tars, others []github.Asset) {
+	for _, asset := range assets {
+		name := strings.ToLower(asset.Name)
+		switch {
+		case strings.HasSuffix(name, ".zip"):
+			zips = append(zips, asset)
+		case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+			tars = append(tars, asset)
+		default:
+			others = append(others, asset)
+		}
+	}
+	return zips, tars, others
+}
+
+// extractZipAssets extracts the selected members from zip assets without
+// downloading the whole archives: the central directory and the compressed
+// bytes of selected members are fetched with range requests.
+func extractZipAssets(run *assetRun, assets []github.Asset, dir string, opts extract.Options) error {
+	if len(assets) == 0 {
+		return nil
+	}
+
+	httpClient, err := newAssetHTTPClient()
+	if err != nil {
+		return fmt.Errorf("failed to create download client: %w", err)
+	}
+
+	return run.each(assets, func(asset github.Asset) (err error) {
+		run.begin("Extracting", asset.Name)
+		span := startAssetSpan("extract", asset)
+		defer func() {
+			span.End(err)
+		}()
+
+		file := remote.Open(httpClient, asset.URL, int64(asset.Size))
+		reader, err := zip.NewReader(file, file.Size())
+		if err != nil {
+			return fmt.Errorf("failed to read zip directory of %s: %w", asset.Name, err)
+		}
+
+		written, err := extract.Zip(reader, dir, opts)
+		run.record(asset.Name, written...)
+		if len(written) > 0 {
+			run.markChanged()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", asset.Name, err)
+		}
+
+		run.done(asset.Name, "done (%d files)", len(written))
+		return nil
+	})
+}
+
+// extractTarGzAssets extracts the selected members of tar.gz assets while
+// streaming them, so unselected members never hit the disk.
+func extractTarGzAssets(run *assetRun, assets []github.Asset, dir string, opts extract.Options) error {
+	if len(assets) == 0 {
+		return nil
+	}
+
+	// A stream cut during extraction continues where it stopped, as members
+	// were already written; when the server cannot resume it, the asset fails
+	downloadClient, err := newAssetDoer(middleware.Resume(run.policy), middleware.Retry(run.policy), middleware.CheckStatus())
+	if err != nil {
+		return fmt.Errorf("failed to create download client: %w", err)
+	}
+
+	return run.each(assets, func(asset github.Asset) (err error) {
+		run.begin("Extracting", asset.Name)
+		span := startAssetSpan("extract", asset)
+		defer func() {
+			span.End(err)
+		}()
+
+		req, err := http.NewRequest("GET", asset.URL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", asset.Name, err)
+		}
+		resp, err := downloadClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", asset.Name, err)
+		}
+
+		written, err := extract.TarGz(resp.Body, dir, opts)
+		run.record(asset.Name, written...)
+		if len(written) > 0 {
+			run.markChanged()
+		}
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", asset.Name, err)
+		}
+
+		run.done(asset.Name, "done (%d files)", len(written))
+		return nil
+	})
+}
+
+// extractArchive streams the source tarball of a tag into dir through
+// archives, see newArchiveDoer, and returns the paths written
+func extractArchive(archives middleware.Doer, repo, tag, archiveFormat, dir string, opts extract.Options) ([]string, error) {
+	if archiveFormat != "tar.gz" {
+		return nil, fmt.Errorf("--extract with --archive requires the 'tar.gz' format")
+	}
+
+	span := tracer.Start("extract archive", tracing.String("repository", repo), tracing.String("tag", tag))
+	resp, err := requestArchive(archives, repo, tag, archiveFormat)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	written, err := extract.TarGz(resp.Body, dir, opts)
+	span.SetAttr(tracing.Int("files", int64(len(written))))
+	span.End(err)
+	if err != nil {
+		return written, fmt.Errorf("failed to extract archive: %w", err)
+	}
+
+	fmt.Printf("Extracted %d files from archive to %s\n", len(written), dir)
+	return written, nil
+}
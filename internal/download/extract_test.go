@@ -0,0 +1,30 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestSplitExtractable(t *testing.T) {
+	assets := []github.Asset{
+		{Name: "app-linux.tar.gz"},
+		{Name: "app-windows.ZIP"},
+		{Name: "app-darwin.tgz"},
+		{Name: "checksums.txt"},
+		{Name: "app.deb"},
+	}
+
+	zips, tars, others := splitExtractable(assets)
+
+	if got := strings.Join(assetNames(zips), ","); got != "app-windows.ZIP" {
+		t.Errorf("Unexpected zip assets %q", got)
+	}
+	if got := strings.Join(assetNames(tars), ","); got != "app-linux.tar.gz,app-darwin.tgz" {
+		t.Errorf("Unexpected tar assets %q", got)
+	}
+	if got := strings.Join(assetNames(others), ","); got != "checksums.txt,app.deb" {
+		t.Errorf("Unexpected other assets %q", got)
+	}
+}
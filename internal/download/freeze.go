@@ -0,0 +1,185 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/manifest"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// Freeze writes a manifest of what the download history says is installed,
+// optionally for one repository: for each repository and directory the
+// files of its latest run, pinned to their tag and digests. Teammates
+// replay it with install to get the same files. With --output the manifest
+// is written to that file instead of stdout.
+func Freeze(cfg config.Config) error {
+	var entries []state.HistoryEntry
+	err := withStore(func(store state.Store) error {
+		var err error
+		entries, err = store.ReadHistory()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	entries = filterHistory(entries, cfg.Repository)
+
+	base, err := filepath.Abs(cfg.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", cfg.Directory, err)
+	}
+	m, err := freezeManifest(entries, base)
+	if err != nil {
+		return err
+	}
+	if len(m.Tools) == 0 {
+		return fmt.Errorf("no downloads recorded to freeze")
+	}
+
+	if cfg.Output == "" {
+		return m.Write(os.Stdout)
+	}
+	if err := writeManifestFile(cfg.Output, m); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Froze %d tools to %s\n", len(m.Tools), cfg.Output)
+	return nil
+}
+
+// Install syncs --dir with the tools of the manifest given to --from-file,
+// such as one written by freeze: plan with --apply
+func Install(cfg config.Config) error {
+	cfg.Apply = true
+	return Plan(cfg)
+}
+
+// freezeManifest makes a tool of the latest entry for each repository and
+// directory. Directories below base are given relative to it, as install
+// takes them below --dir. Recorded files that are gone are left out.
+func freezeManifest(entries []state.HistoryEntry, base string) (*manifest.Manifest, error) {
+	type target struct{ repository, directory string }
+	latest := make(map[target]state.HistoryEntry)
+	var order []target
+	for _, entry := range entries {
+		key := target{strings.ToLower(historyRepository(entry)), entry.Directory}
+		if _, ok := latest[key]; !ok {
+			order = append(order, key)
+		}
+		// The history is oldest first
+		latest[key] = entry
+	}
+
+	m := &manifest.Manifest{Tools: make(map[string]manifest.Tool)}
+	for _, key := range order {
+		entry := latest[key]
+		tool, err := freezeTool(entry, base)
+		if err != nil {
+			return nil, err
+		}
+		if len(tool.Digests) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s %s: none of its release assets are left in %s\n", historyRepository(entry), entry.Tag, entry.Directory)
+			continue
+		}
+		m.Tools[freezeToolName(m, tool)] = tool
+	}
+	return m, nil
+}
+
+// freezeTool pins the files of an entry to their SHA-256 digests, by the
+// name of the asset each was saved from, and names the files saved under
+// another name. Files that are no asset as downloaded, such as extracted
+// files and source archives, are left out with a warning.
+func freezeTool(entry state.HistoryEntry, base string) (manifest.Tool, error) {
+	tool := manifest.Tool{
+		Repo:    entry.Repository,
+		Host:    entry.Host,
+		Tag:     entry.Tag,
+		Pattern: entry.Pattern,
+		Dir:     freezeDirectory(entry.Directory, base),
+		Digests: make(map[string]string),
+	}
+
+	var unpinned []string
+	for _, path := range entry.Paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return manifest.Tool{}, fmt.Errorf("failed to check %s: %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		name, ok := entry.Assets[path]
+		file, err := filepath.Rel(entry.Directory, path)
+		if !ok || err != nil || !filepath.IsLocal(file) {
+			unpinned = append(unpinned, path)
+			continue
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return manifest.Tool{}, err
+		}
+		tool.Digests[name] = "sha256:" + digest
+		if file = filepath.ToSlash(file); file != name {
+			if tool.Files == nil {
+				tool.Files = make(map[string]string)
+			}
+			tool.Files[name] = file
+		}
+	}
+	if len(unpinned) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: leaving %d files of %s %s out: they are not release assets as downloaded, such as extracted files: %s\n", len(unpinned), historyRepository(entry), entry.Tag, strings.Join(unpinned, ", "))
+	}
+	return tool, nil
+}
+
+// freezeDirectory returns dir relative to base when it is below it, in the
+// home directory as ~/..., and otherwise as it is
+func freezeDirectory(dir, base string) string {
+	if rel, err := filepath.Rel(base, dir); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, dir); err == nil && filepath.IsLocal(rel) {
+			return "~/" + filepath.ToSlash(rel)
+		}
+	}
+	return dir
+}
+
+// freezeToolName names a tool after its repository, adding the owner and
+// then a number when the name is taken
+func freezeToolName(m *manifest.Manifest, tool manifest.Tool) string {
+	owner, repo, _ := strings.Cut(tool.Repo, "/")
+	name := repo
+	if _, taken := m.Tools[name]; taken {
+		name = owner + "-" + repo
+	}
+	for i := 2; ; i++ {
+		if _, taken := m.Tools[name]; !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s-%s-%d", owner, repo, i)
+	}
+}
+
+// writeManifestFile writes a manifest to path
+func writeManifestFile(path string, m *manifest.Manifest) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to write %s: %w", path, closeErr)
+		}
+	}()
+	return m.Write(file)
+}
@@ -0,0 +1,109 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/manifest"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+func TestFreezeManifest(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "tools", "gh")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"gh.tar.gz", "gh.tgz", "LICENSE"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("hello\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []state.HistoryEntry{
+		{Time: started, Repository: "cli/cli", Tag: "v2.0.0", Directory: dir, Paths: []string{filepath.Join(dir, "gh.tar.gz")}},
+		{Time: started.Add(time.Hour), Repository: "cli/cli", Tag: "v2.1.0", Pattern: "gh_*", Directory: dir,
+			// gh.tgz was saved with -O and LICENSE extracted from an archive
+			Paths:  []string{filepath.Join(dir, "gh.tar.gz"), filepath.Join(dir, "gh.tgz"), filepath.Join(dir, "LICENSE"), filepath.Join(dir, "gone.txt")},
+			Assets: map[string]string{filepath.Join(dir, "gh.tar.gz"): "gh_linux.tar.gz", filepath.Join(dir, "gh.tgz"): "gh_darwin.tar.gz", filepath.Join(dir, "gone.txt"): "gone.txt"}},
+		{Time: started, Repository: "owner/gone", Tag: "v1.0.0", Directory: dir, Paths: []string{filepath.Join(dir, "removed.zip")}},
+	}
+
+	m, err := freezeManifest(entries, base)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(m.Tools) != 1 {
+		t.Fatalf("Expected only the tool with files left, got %+v", m.Tools)
+	}
+	tool := m.Tools["cli"]
+	if tool.Repo != "cli/cli" || tool.Tag != "v2.1.0" || tool.Pattern != "gh_*" || tool.Dir != "tools/gh" {
+		t.Errorf("Expected cli/cli v2.1.0 matching gh_* in tools/gh, got %+v", tool)
+	}
+	// sha256 of "hello\n"
+	if digest := tool.Digests["gh_linux.tar.gz"]; digest != "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" || len(tool.Digests) != 2 {
+		t.Errorf("Expected the digests of the two assets, got %v", tool.Digests)
+	}
+	if len(tool.Files) != 2 || tool.Files["gh_linux.tar.gz"] != "gh.tar.gz" || tool.Files["gh_darwin.tar.gz"] != "gh.tgz" {
+		t.Errorf("Expected the file names of the assets, got %v", tool.Files)
+	}
+}
+
+func TestFreezeDirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	base := filepath.Join(home, "project")
+
+	testCases := []struct {
+		dir      string
+		expected string
+	}{
+		{filepath.Join(base, "tools", "gh"), "tools/gh"},
+		{base, "."},
+		{filepath.Join(home, "bin"), "~/bin"},
+		{"/opt/tools", "/opt/tools"},
+	}
+	for _, tc := range testCases {
+		if got := freezeDirectory(tc.dir, base); got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.dir, tc.expected, got)
+		}
+	}
+}
+
+func TestFreezeToolName(t *testing.T) {
+	m := &manifest.Manifest{Tools: map[string]manifest.Tool{}}
+	for _, expected := range []string{"app", "owner-app", "owner-app-2"} {
+		name := freezeToolName(m, manifest.Tool{Repo: "owner/app"})
+		if name != expected {
+			t.Errorf("Expected %q, got %q", expected, name)
+		}
+		m.Tools[name] = manifest.Tool{Repo: "owner/app"}
+	}
+}
+
+func TestFreezeManifest_Host(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.tar.gz")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assets := map[string]string{path: "app.tar.gz"}
+	entries := []state.HistoryEntry{
+		{Repository: "owner/app", Tag: "v1.0.0", Directory: dir, Paths: []string{path}, Assets: assets},
+		{Repository: "owner/app", Host: "ghe.example.com", Tag: "v2.0.0", Directory: dir, Paths: []string{path}, Assets: assets},
+	}
+	m, err := freezeManifest(entries, dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(m.Tools) != 2 || m.Tools["app"].Host != "" || m.Tools["owner-app"].Host != "ghe.example.com" || m.Tools["owner-app"].Tag != "v2.0.0" {
+		t.Errorf("Expected a tool for each host, got %+v", m.Tools)
+	}
+	if matched := filterHistory(entries, "GHE.example.com/owner/app"); len(matched) != 1 || matched[0].Tag != "v2.0.0" {
+		t.Errorf("Expected only the entry of ghe.example.com, got %+v", matched)
+	}
+}
@@ -0,0 +1,117 @@
+package download
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// passingConclusions are the check run conclusions that do not block a
+// release
+var passingConclusions = map[string]bool{
+	"success": true,
+	"neutral": true,
+	"skipped": true,
+}
+
+// releaseCommit returns the SHA of the commit the tag of a release points to
+func releaseCommit(client github.HTTPClient, cfg config.Config, release *github.Release) (string, error) {
+	if release.CommitSHA != "" {
+		return release.CommitSHA, nil
+	}
+	commit, err := github.GetCommit(client, cfg.Repository, release.TagName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit of %s: %w", release.TagName, err)
+	}
+	return commit.SHA, nil
+}
+
+// requireChecksPassed refuses releases whose commit has failed or unfinished
+// commit statuses or check runs, so automation does not pull artifacts built
+// from a red commit
+func requireChecksPassed(client github.HTTPClient, cfg config.Config, release *github.Release) error {
+	sha, err := releaseCommit(client, cfg, release)
+	if err != nil {
+		return err
+	}
+
+	status, err := github.GetCombinedStatus(client, cfg.Repository, sha)
+	if err != nil {
+		return fmt.Errorf("failed to get commit status: %w", err)
+	}
+	runs, err := github.GetCheckRuns(client, cfg.Repository, sha)
+	if err != nil {
+		return fmt.Errorf("failed to get check runs: %w", err)
+	}
+
+	blocking := blockingChecks(status.Statuses, runs)
+	if len(blocking) > 0 {
+		return fmt.Errorf("checks on %s (%s) have not passed: %s", release.TagName, shortSHA(sha), strings.Join(blocking, ", "))
+	}
+
+	fmt.Printf("All %d checks on %s passed\n", len(status.Statuses)+len(runs), release.TagName)
+	return nil
+}
+
+// blockingChecks returns the statuses and check runs that did not pass, as
+// "name (state)"
+func blockingChecks(statuses []github.CommitStatus, runs []github.CheckRun) []string {
+	var blocking []string
+	for _, status := range statuses {
+		if status.State != "success" {
+			blocking = append(blocking, fmt.Sprintf("%s (%s)", status.Context, status.State))
+		}
+	}
+	for _, run := range runs {
+		switch {
+		case run.Status != "completed":
+			blocking = append(blocking, fmt.Sprintf("%s (%s)", run.Name, run.Status))
+		case !passingConclusions[run.Conclusion]:
+			blocking = append(blocking, fmt.Sprintf("%s (%s)", run.Name, run.Conclusion))
+		}
+	}
+	return blocking
+}
+
+// requireDeployment refuses releases whose commit was never deployed
+// successfully to the --require-deployment environment, for teams that
+// promote releases by deploying them. A deployment whose latest status is "inactive" was replaced by a
+// later one after succeeding, so it counts as well.
+func requireDeployment(client github.HTTPClient, cfg config.Config, release *github.Release) error {
+	environment := cfg.RequireDeployment
+	sha, err := releaseCommit(client, cfg, release)
+	if err != nil {
+		return err
+	}
+
+	deployments, err := github.ListDeployments(client, cfg.Repository, sha, environment)
+	if err != nil {
+		return fmt.Errorf("failed to get deployments: %w", err)
+	}
+
+	var states []string
+	for _, deployment := range deployments {
+		statuses, err := github.ListDeploymentStatuses(client, cfg.Repository, deployment.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get statuses of deployment %d: %w", deployment.ID, err)
+		}
+		if len(statuses) == 0 {
+			states = append(states, "pending")
+			continue
+		}
+		switch state := statuses[0].State; state {
+		case "success", "inactive":
+			fmt.Printf("%s (%s) is deployed to %s\n", release.TagName, shortSHA(sha), environment)
+			return nil
+		default:
+			states = append(states, state)
+		}
+	}
+
+	if len(states) == 0 {
+		return fmt.Errorf("%s (%s) has no deployment to %s", release.TagName, shortSHA(sha), environment)
+	}
+	return fmt.Errorf("%s (%s) has no successful deployment to %s: %s", release.TagName, shortSHA(sha), environment, strings.Join(states, ", "))
+}
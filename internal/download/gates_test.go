@@ -0,0 +1,208 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// jsonClient answers API requests with canned JSON responses by endpoint
+type jsonClient map[string]string
+
+func (c jsonClient) Get(endpoint string, response interface{}) error {
+	body, ok := c[endpoint]
+	if !ok {
+		return fmt.Errorf("HTTP 404: Not Found (%s)", endpoint)
+	}
+	return json.Unmarshal([]byte(body), response)
+}
+
+func TestBlockingChecks(t *testing.T) {
+	statuses := []github.CommitStatus{
+		{Context: "ci/build", State: "success"},
+		{Context: "ci/deploy", State: "pending"},
+	}
+	runs := []github.CheckRun{
+		{Name: "test", Status: "completed", Conclusion: "success"},
+		{Name: "docs", Status: "completed", Conclusion: "skipped"},
+		{Name: "lint", Status: "completed", Conclusion: "failure"},
+		{Name: "e2e", Status: "in_progress"},
+	}
+
+	blocking := blockingChecks(statuses, runs)
+	expected := []string{"ci/deploy (pending)", "lint (failure)", "e2e (in_progress)"}
+	if strings.Join(blocking, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, blocking)
+	}
+}
+
+func TestRequireChecksPassed(t *testing.T) {
+	cfg := config.Config{Repository: "owner/repo"}
+	release := &github.Release{TagName: "v1.0.0"}
+	client := jsonClient{
+		"repos/owner/repo/commits/v1.0.0":                                   `{"sha":"0123456789abcdef"}`,
+		"repos/owner/repo/commits/0123456789abcdef/status?per_page=100":     `{"state":"success","statuses":[{"context":"ci","state":"success"}]}`,
+		"repos/owner/repo/commits/0123456789abcdef/check-runs?per_page=100": `{"check_runs":[{"name":"test","status":"completed","conclusion":"success"}]}`,
+	}
+	if err := requireChecksPassed(client, cfg, release); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	client["repos/owner/repo/commits/0123456789abcdef/check-runs?per_page=100"] = `{"check_runs":[{"name":"test","status":"completed","conclusion":"failure"}]}`
+	err := requireChecksPassed(client, cfg, release)
+	if err == nil || !strings.Contains(err.Error(), "test (failure)") || !strings.Contains(err.Error(), "0123456") {
+		t.Errorf("Expected failed check error, got %v", err)
+	}
+}
+
+func TestRequireDeployment(t *testing.T) {
+	cfg := config.Config{Repository: "owner/repo", RequireDeployment: "production"}
+	release := &github.Release{TagName: "v1.0.0"}
+	deployments := "repos/owner/repo/deployments?environment=production&per_page=100&sha=0123456789abcdef"
+
+	testCases := []struct {
+		name        string
+		deployments string
+		statuses    string
+		expected    string
+	}{
+		{"deployed", `[{"id":1}]`, `[{"state":"success"},{"state":"in_progress"}]`, ""},
+		{"superseded", `[{"id":1}]`, `[{"state":"inactive"},{"state":"success"}]`, ""},
+		{"failed", `[{"id":1}]`, `[{"state":"failure"},{"state":"in_progress"}]`, "no successful deployment to production: failure"},
+		{"never deployed", `[]`, `[]`, "no deployment to production"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := jsonClient{
+				"repos/owner/repo/commits/v1.0.0": `{"sha":"0123456789abcdef"}`,
+				deployments:                       tc.deployments,
+				"repos/owner/repo/deployments/1/statuses?per_page=100": tc.statuses,
+			}
+			err := requireDeployment(client, cfg, release)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestResolveRelease_MinReactions(t *testing.T) {
+	// A full first page of releases without enough reactions
+	page := make([]string, 0, 100)
+	for i := range 100 {
+		page = append(page, fmt.Sprintf(`{"tag_name":"v3.%d.0","reactions":{"total_count":1}}`, 100-i))
+	}
+	client := jsonClient{
+		"repos/owner/repo/releases?per_page=100&page=1": "[" + strings.Join(page, ",") + "]",
+		"repos/owner/repo/releases?per_page=100&page=2": `[
+			{"tag_name":"v3.0.0","reactions":{"total_count":1}},
+			{"tag_name":"v2.1.0-rc.1","prerelease":true,"reactions":{"total_count":9}},
+			{"tag_name":"v2.0.0","reactions":{"total_count":5}}
+		]`,
+		"repos/owner/repo/releases/tags/v3.0.0": `{"tag_name":"v3.0.0","reactions":{"total_count":1}}`,
+	}
+
+	release, err := resolveRelease(client, config.Config{Repository: "owner/repo", MinReactions: 5})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if release.TagName != "v2.0.0" {
+		t.Errorf("Expected v2.0.0, got %s", release.TagName)
+	}
+
+	if _, err := resolveRelease(client, config.Config{Repository: "owner/repo", MinReactions: 10}); err == nil {
+		t.Error("Expected an error when no release has enough reactions, got nil")
+	}
+
+	if _, err := resolveRelease(client, config.Config{Repository: "owner/repo", Tag: "v3.0.0", MinReactions: 5}); err == nil {
+		t.Error("Expected an error for a tag with too few reactions, got nil")
+	}
+	if _, err := resolveRelease(client, config.Config{Repository: "owner/repo", Tag: "v3.0.0"}); err != nil {
+		t.Errorf("Expected no error without --min-reactions, got %v", err)
+	}
+}
+
+func TestResolveRelease_Author(t *testing.T) {
+	client := jsonClient{
+		"repos/owner/repo/releases?per_page=100&page=1": `[
+			{"tag_name":"v2.0.0","author":{"login":"someone","type":"User"}},
+			{"tag_name":"v1.0.0","author":{"login":"github-actions[bot]","type":"Bot"}}
+		]`,
+		"repos/owner/repo/releases/tags/v2.0.0": `{"tag_name":"v2.0.0","author":{"login":"someone","type":"User"}}`,
+	}
+	cfg := config.Config{Repository: "owner/repo", Author: "GitHub-Actions[bot]"}
+
+	release, err := resolveRelease(client, cfg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if release.TagName != "v1.0.0" {
+		t.Errorf("Expected v1.0.0, got %s", release.TagName)
+	}
+
+	cfg.Tag = "v2.0.0"
+	if _, err := resolveRelease(client, cfg); err == nil || !strings.Contains(err.Error(), "created by someone") {
+		t.Errorf("Expected an error for a release by another account, got %v", err)
+	}
+}
+
+func TestResolveRelease_Strategy(t *testing.T) {
+	client := jsonClient{
+		"repos/owner/repo/releases?per_page=100&page=1": `[
+			{"tag_name":"v1.9.0","reactions":{"total_count":1},"assets":[{"download_count":3}]},
+			{"tag_name":"v1.10.0","reactions":{"total_count":5},"assets":[{"download_count":7}]},
+			{"tag_name":"v1.2.0","reactions":{"total_count":5},"assets":[{"download_count":50}]}
+		]`,
+	}
+	cfg := config.Config{Repository: "owner/repo", Strategy: github.StrategyHighestSemver}
+
+	release, err := resolveRelease(client, cfg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if release.TagName != "v1.10.0" {
+		t.Errorf("Expected v1.10.0, got %s", release.TagName)
+	}
+
+	cfg.Strategy = github.StrategyMostDownloaded
+	cfg.MinReactions = 5
+	if release, err := resolveRelease(client, cfg); err != nil || release.TagName != "v1.2.0" {
+		t.Errorf("Expected v1.2.0, got %v, %v", release, err)
+	}
+
+	cfg.Tag = "v1.9.0"
+	if _, err := resolveRelease(client, cfg); err == nil {
+		t.Error("Expected an error for --strategy with a tag, got nil")
+	}
+}
+
+func TestListReleases_Paginates(t *testing.T) {
+	page := make([]string, 0, 100)
+	for i := range 100 {
+		page = append(page, fmt.Sprintf(`{"tag_name":"v2.%d.0"}`, 100-i))
+	}
+	client := jsonClient{
+		"repos/owner/repo/releases?per_page=100&page=1": "[" + strings.Join(page, ",") + "]",
+		"repos/owner/repo/releases?per_page=100&page=2": `[{"tag_name":"v1.0.0"}]`,
+	}
+
+	output := captureStdout(t, func() {
+		if err := listReleases(client, config.Config{Repository: "owner/repo"}, nil); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "v2.100.0") || !strings.Contains(output, "v1.0.0") {
+		t.Errorf("Expected the releases of both pages, got:\n%s", output)
+	}
+}
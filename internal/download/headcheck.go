@@ -0,0 +1,98 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/log"
+	"github.com/23prime/gh-download/internal/middleware"
+)
+
+// headFindings is what a HEAD request revealed about an asset before its
+// transfer. Supported is unset when the server did not answer the request,
+// as presigned storage URLs that only allow GET do; nothing is known then.
+type headFindings struct {
+	Supported   bool
+	Status      int
+	Size        int64
+	Ranges      bool
+	ContentType string
+}
+
+func (f headFindings) String() string {
+	if !f.Supported {
+		if f.Status != 0 {
+			return fmt.Sprintf("HEAD not supported (HTTP %d)", f.Status)
+		}
+		return "HEAD not supported"
+	}
+	ranges := "no range requests"
+	if f.Ranges {
+		ranges = "resumable"
+	}
+	size := "unknown size"
+	if f.Size >= 0 {
+		size = fmt.Sprintf("%d bytes", f.Size)
+	}
+	return fmt.Sprintf("%s, %s, %s", size, ranges, f.ContentType)
+}
+
+// headCheck asks for the headers of an asset before transferring it, with
+// --head-check. A size other than the one GitHub reports fails the asset
+// early; failures of the request itself only leave the findings empty.
+func headCheck(client middleware.Doer, asset github.Asset) (headFindings, error) {
+	req, err := http.NewRequest("HEAD", asset.URL, nil)
+	if err != nil {
+		return headFindings{}, nil
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return headFindings{}, nil
+	}
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return headFindings{Status: resp.StatusCode}, nil
+	}
+
+	findings := headFindings{
+		Supported:   true,
+		Status:      resp.StatusCode,
+		Size:        resp.ContentLength,
+		Ranges:      strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes"),
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if findings.Size >= 0 && asset.Size > 0 && findings.Size != int64(asset.Size) {
+		return findings, fmt.Errorf("%s is %d bytes on the server, but GitHub reports %d", asset.Name, findings.Size, asset.Size)
+	}
+	return findings, nil
+}
+
+// prepareTransfer runs the --head-check of an asset about to be fetched to
+// path, reporting the findings with --verbose. A partial download is
+// dropped when the server cannot resume it, so the transfer starts over
+// rather than asking for a range. The partial file is removed through root.
+func (r *assetRun) prepareTransfer(client middleware.Doer, root *confine.Root, asset github.Asset, path string) error {
+	if !r.headCheck {
+		return nil
+	}
+	findings, err := headCheck(client, asset)
+	log.Verbosef("%s: %s", asset.Name, findings)
+	if err != nil {
+		return err
+	}
+
+	part := path + partSuffix
+	if findings.Supported && !findings.Ranges && resumeOffset(part, int64(asset.Size)) > 0 {
+		if err := root.Remove(part); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", part, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package download
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/log"
+)
+
+func TestHeadCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("Expected a HEAD request, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/ranges":
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Length", "10")
+		case "/plain":
+			w.Header().Set("Content-Length", "10")
+		case "/denied":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	client := server.Client()
+
+	findings, err := headCheck(client, github.Asset{Name: "a", URL: server.URL + "/ranges", Size: 10})
+	if err != nil || !findings.Supported || !findings.Ranges || findings.Size != 10 {
+		t.Errorf("Unexpected findings %+v (%v)", findings, err)
+	}
+	if got := findings.String(); got != "10 bytes, resumable, application/gzip" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+
+	if _, err := headCheck(client, github.Asset{Name: "a", URL: server.URL + "/ranges", Size: 12}); err == nil {
+		t.Error("Expected an error for a size mismatch")
+	}
+
+	findings, err = headCheck(client, github.Asset{Name: "a", URL: server.URL + "/denied", Size: 10})
+	if err != nil || findings.Supported || !strings.Contains(findings.String(), "403") {
+		t.Errorf("Expected HEAD to be reported unsupported, got %+v (%v)", findings, err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a")
+	if err := os.WriteFile(path+partSuffix, []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := testRoot(t, dir)
+	run := newAssetRun(1, false)
+	run.headCheck = true
+	if err := run.prepareTransfer(client, root, github.Asset{Name: "a", URL: server.URL + "/ranges", Size: 10}, path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(path + partSuffix); err != nil {
+		t.Errorf("Expected a resumable partial download to be kept, got %v", err)
+	}
+	if err := run.prepareTransfer(client, root, github.Asset{Name: "a", URL: server.URL + "/plain", Size: 10}, path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(path + partSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial download to be removed without range support, got %v", err)
+	}
+
+	// A partial download reached through a symlink out of dir is not removed
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "a"+partSuffix), []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	err = run.prepareTransfer(client, root, github.Asset{Name: "a", URL: server.URL + "/plain", Size: 10}, filepath.Join(dir, "link", "a"))
+	if !errors.Is(err, confine.ErrOutside) {
+		t.Errorf("Expected ErrOutside, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "a"+partSuffix)); err != nil {
+		t.Errorf("Expected the file outside the directory to be kept, got %v", err)
+	}
+}
+
+func TestPrepareTransfer_Verbose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Length", "10")
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	previous := log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(previous)
+		log.SetLevel(log.LevelInfo)
+	})
+
+	run := newAssetRun(1, false)
+	run.headCheck = true
+	asset := github.Asset{Name: "a", URL: server.URL, Size: 10}
+	dir := t.TempDir()
+	if err := run.prepareTransfer(server.Client(), testRoot(t, dir), asset, filepath.Join(dir, "a")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no findings without --verbose, got %q", buf.String())
+	}
+
+	log.SetLevel(log.LevelVerbose)
+	if err := run.prepareTransfer(server.Client(), testRoot(t, dir), asset, filepath.Join(dir, "a")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.String() != "a: 10 bytes, resumable, application/gzip\n" {
+		t.Errorf("Expected the findings to be logged, got %q", buf.String())
+	}
+}
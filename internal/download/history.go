@@ -0,0 +1,135 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// recordHistory appends the files a run wrote to the download history. The
+// history is a convenience, so failing to write it only warns.
+func recordHistory(cfg config.Config, result runResult) {
+	// Files written to stdout are not kept
+	if len(result.Paths) == 0 || cfg.Stdout {
+		return
+	}
+
+	if err := appendHistory(cfg, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record download history: %v\n", err)
+	}
+}
+
+// appendHistory adds the files of result to the download history, with
+// absolute paths, and the host, pattern and asset names freeze pins them by.
+// The repository is OWNER/REPO by then, so the host is taken from the run.
+func appendHistory(cfg config.Config, result runResult) error {
+	directory := cfg.Directory
+	if abs, err := filepath.Abs(directory); err == nil {
+		directory = abs
+	}
+	entry := state.HistoryEntry{
+		Time:       time.Now().UTC(),
+		Repository: cfg.Repository,
+		Tag:        result.Tag,
+		Commit:     result.Commit,
+		Directory:  directory,
+		Paths:      absPaths(result.Paths),
+		Host:       apiHost(),
+	}
+	if cfg.Pattern != "*" {
+		entry.Pattern = cfg.Pattern
+	}
+	for i, path := range result.Paths {
+		name, ok := result.Assets[path]
+		if !ok {
+			continue
+		}
+		if entry.Assets == nil {
+			entry.Assets = make(map[string]string, len(result.Assets))
+		}
+		entry.Assets[entry.Paths[i]] = name
+	}
+	return withStore(func(store state.Store) error {
+		return store.AppendHistory(entry)
+	})
+}
+
+// History prints what earlier runs downloaded, oldest first, optionally only
+// for one repository. With --json the entries are printed as a JSON array,
+// only the fields given if any, and with --format in that format.
+func History(cfg config.Config) error {
+	renderer, err := outputRenderer(cfg)
+	if err != nil {
+		return err
+	}
+
+	var entries []state.HistoryEntry
+	err = withStore(func(store state.Store) error {
+		var err error
+		entries, err = store.ReadHistory()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	entries = filterHistory(entries, cfg.Repository)
+
+	// --json without fields prints whole entries
+	if cfg.JSON && len(cfg.JSONFields) == 0 {
+		if cfg.Format == "" && cfg.Template == "" {
+			if entries == nil {
+				entries = []state.HistoryEntry{}
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(entries)
+		}
+		cfg.JSON = false
+	}
+	if renderer != nil {
+		return renderRecords(cfg, renderer, historyTable(entries), state.HistoryEntry{})
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No downloads recorded")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s  %s %s -> %s (%d files)\n", entry.Time.Local().Format("2006-01-02 15:04"), historyRepository(entry), entry.Tag, entry.Directory, len(entry.Paths))
+		for _, path := range entry.Paths {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	return nil
+}
+
+// filterHistory returns the entries of a repository, given as OWNER/REPO or
+// HOST/OWNER/REPO, or all entries when repository is empty
+func filterHistory(entries []state.HistoryEntry, repository string) []state.HistoryEntry {
+	if repository == "" {
+		return entries
+	}
+
+	var matched []state.HistoryEntry
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Repository, repository) || strings.EqualFold(historyRepository(entry), repository) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// historyRepository returns the repository of an entry as HOST/OWNER/REPO
+// for hosts other than github.com, and as OWNER/REPO otherwise
+func historyRepository(entry state.HistoryEntry) string {
+	if entry.Host != "" {
+		return entry.Host + "/" + entry.Repository
+	}
+	return entry.Repository
+}
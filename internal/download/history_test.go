@@ -0,0 +1,100 @@
+package download
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// captureStdout returns what fn prints on stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String()
+}
+
+func TestRecordHistory(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	dir := t.TempDir()
+
+	recordHistory(config.Config{Repository: "owner/repo", Directory: dir}, runResult{Tag: "v1.0.0"})
+	recordHistory(config.Config{Repository: "owner/repo", Directory: dir, Pattern: "app*"}, runResult{
+		Tag: "v1.0.0", Commit: "abc123",
+		Paths:  []string{filepath.Join(dir, "app.tar.gz"), filepath.Join(dir, "LICENSE")},
+		Assets: map[string]string{filepath.Join(dir, "app.tar.gz"): "app_linux.tar.gz"},
+	})
+
+	entries, err := state.ReadHistory(state.HistoryPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected only runs writing files to be recorded, got %d entries", len(entries))
+	}
+	if entries[0].Repository != "owner/repo" || entries[0].Commit != "abc123" || entries[0].Directory != dir || entries[0].Time.IsZero() {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+	if entries[0].Pattern != "app*" || len(entries[0].Assets) != 1 || entries[0].Assets[filepath.Join(dir, "app.tar.gz")] != "app_linux.tar.gz" {
+		t.Errorf("Expected the pattern and the asset of app.tar.gz, got %+v", entries[0])
+	}
+}
+
+func TestHistory_JSON(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	for _, repo := range []string{"owner/repo", "owner/other"} {
+		if err := state.AppendHistory(state.HistoryPath(), state.HistoryEntry{Repository: repo, Tag: "v1.0.0", Paths: []string{"/mirror/app"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := captureStdout(t, func() {
+		if err := History(config.Config{Repository: "OWNER/repo", JSON: true}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	var entries []state.HistoryEntry
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		t.Fatalf("Expected a JSON array, got %v:\n%s", err, output)
+	}
+	if len(entries) != 1 || entries[0].Repository != "owner/repo" {
+		t.Errorf("Expected only owner/repo, got %+v", entries)
+	}
+}
+
+func TestHistory_Empty(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+
+	output := captureStdout(t, func() {
+		if err := History(config.Config{JSON: true}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if strings.TrimSpace(output) != "[]" {
+		t.Errorf("Expected an empty array, got %q", output)
+	}
+
+	if _, err := os.Stat(state.HistoryPath()); err == nil {
+		t.Error("Expected reading the history not to create it")
+	}
+}
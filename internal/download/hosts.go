@@ -0,0 +1,58 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// hostEnv selects the host the API clients of go-gh talk to, and so the
+// credentials of gh they use
+const hostEnv = "GH_HOST"
+
+// useRepositoryHost points the clients of a run at the host of a
+// HOST/OWNER/REPO repository, with the credentials gh keeps for that host,
+// and returns the config with the repository as OWNER/REPO and a function
+// restoring the previous host. Runs over several repositories, such as
+// --stdin or tap tools on GitHub Enterprise Server and github.com, so each
+// talk to their own host.
+func useRepositoryHost(cfg config.Config) (config.Config, func(), error) {
+	if strings.Count(cfg.Repository, "/") != 2 {
+		return cfg, func() {}, nil
+	}
+	parsed, err := repository.Parse(cfg.Repository)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("invalid repository format: %w", err)
+	}
+
+	previous, wasSet := os.LookupEnv(hostEnv)
+	if err := os.Setenv(hostEnv, parsed.Host); err != nil {
+		return cfg, nil, fmt.Errorf("failed to select host %s: %w", parsed.Host, err)
+	}
+	restore := func() {
+		var err error
+		if wasSet {
+			err = os.Setenv(hostEnv, previous)
+		} else {
+			err = os.Unsetenv(hostEnv)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restore %s: %v\n", hostEnv, err)
+		}
+	}
+
+	cfg.Repository = parsed.Owner + "/" + parsed.Name
+	return cfg, restore, nil
+}
+
+// apiHost returns the host selected for the run when it is not github.com,
+// to keep what is cached for different hosts apart
+func apiHost() string {
+	if host := os.Getenv(hostEnv); host != "" && !strings.EqualFold(host, "github.com") {
+		return strings.ToLower(host)
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package download
+
+import (
+	"os"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+func TestUseRepositoryHost(t *testing.T) {
+	t.Setenv(hostEnv, "github.com")
+
+	cfg, restore, err := useRepositoryHost(config.Config{Repository: "ghe.example.com/owner/repo"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Repository != "owner/repo" {
+		t.Errorf("Expected owner/repo, got %s", cfg.Repository)
+	}
+	if host := os.Getenv(hostEnv); host != "ghe.example.com" {
+		t.Errorf("Expected %s to select ghe.example.com, got %q", hostEnv, host)
+	}
+	if apiHost() != "ghe.example.com" {
+		t.Errorf("Expected ghe.example.com, got %q", apiHost())
+	}
+
+	restore()
+	if host := os.Getenv(hostEnv); host != "github.com" {
+		t.Errorf("Expected %s to be restored, got %q", hostEnv, host)
+	}
+	if apiHost() != "" {
+		t.Errorf("Expected no host for github.com, got %q", apiHost())
+	}
+
+	cfg, restore, err = useRepositoryHost(config.Config{Repository: "owner/repo"})
+	if err != nil || cfg.Repository != "owner/repo" {
+		t.Errorf("Expected owner/repo to be left alone, got %+v, %v", cfg, err)
+	}
+	restore()
+	if host := os.Getenv(hostEnv); host != "github.com" {
+		t.Errorf("Expected %s to be left alone, got %q", hostEnv, host)
+	}
+}
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/lfs"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// resolveLFSPointers replaces the Git LFS pointer files among paths, below
+// dir, with the objects they point to, fetched from the batch API of repo.
+func resolveLFSPointers(repo, dir string, paths []string) error {
+	pointers := make(map[string][]string)
+	var order []lfs.Pointer
+	for _, path := range paths {
+		pointer, ok, err := lfs.ReadPointer(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, seen := pointers[pointer.OID]; !seen {
+			order = append(order, pointer)
+		}
+		pointers[pointer.OID] = append(pointers[pointer.OID], path)
+	}
+
+	if len(order) == 0 {
+		return nil
+	}
+
+	parsed, err := repository.Parse(repo)
+	if err != nil {
+		return fmt.Errorf("invalid repository format: %w", err)
+	}
+	token, _ := auth.TokenForHost(parsed.Host)
+	client := lfs.NewClient(newPlainHTTPClient(), lfs.Endpoint(parsed.Host, parsed.Owner+"/"+parsed.Name), token)
+
+	root, err := confine.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer closeRoot(root)
+
+	fmt.Printf("Resolving %d LFS objects... ", len(order))
+	objects, err := client.Batch(order)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		for _, path := range pointers[object.OID] {
+			if err := writeLFSObject(client, root, object, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("done\n")
+	return nil
+}
+
+// writeLFSObject downloads object next to path and renames it over the
+// pointer, keeping the pointer's mode
+func writeLFSObject(client *lfs.Client, root *confine.Root, object lfs.Object, path string) error {
+	info, err := root.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(path), fmt.Sprintf(".lfs-%016x", rand.Uint64()))
+	tmp, err := root.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	err = client.Download(object, tmp)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if closeErr := tmp.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = root.Rename(tmpPath, path)
+	}
+	if err != nil {
+		if removeErr := root.Remove(tmpPath); removeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", tmpPath, removeErr)
+		}
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	return nil
+}
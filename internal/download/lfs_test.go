@@ -0,0 +1,20 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveLFSPointers_NoPointers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "README.md")
+	if err := os.WriteFile(path, []byte("# readme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without pointer files the batch API must not be contacted
+	if err := resolveLFSPointers("owner/repo", dir, []string{path}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/23prime/gh-download/internal/color"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/state"
+	"github.com/23prime/gh-download/internal/tmpl"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// errNoMatches is returned by match-test when the pattern matches no name,
+// so scripts can test patterns by exit code
+var errNoMatches = errors.New("no names match the pattern")
+
+// MatchTest evaluates --pattern, with its templates expanded, against the
+// names given as arguments or, with --repo, the assets of a release, and
+// prints which match. Nothing is downloaded.
+func MatchTest(cfg config.Config) error {
+	if !cfg.IsSet("repo") {
+		names := positionalArgs(cfg)
+		if len(names) == 0 {
+			return fmt.Errorf("match-test requires asset names or --repo")
+		}
+		pattern, err := tmpl.Render(cfg.Pattern, tmpl.Data{})
+		if err != nil {
+			return err
+		}
+		return matchNames(pattern, names)
+	}
+
+	cfg, err := applyTapTool(cfg, state.TapsPath())
+	if err != nil {
+		return err
+	}
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	release, err := resolveRelease(client, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get release: %w", err)
+	}
+	if cfg, err = expandTemplates(cfg, release); err != nil {
+		return err
+	}
+
+	fmt.Printf("Release: %s (%s)\n", release.TagName, cfg.Repository)
+	names := make([]string, len(release.Assets))
+	for i, asset := range release.Assets {
+		names[i] = asset.Name
+	}
+	return matchNames(cfg.Pattern, names)
+}
+
+// matchNames prints whether each name matches pattern, with the same
+// matching the download uses
+func matchNames(pattern string, names []string) error {
+	assets := make([]github.Asset, len(names))
+	for i, name := range names {
+		assets[i] = github.Asset{Name: name}
+	}
+	matched, err := github.FilterAssets(assets, pattern)
+	if err != nil {
+		return err
+	}
+	matching := make(map[string]bool, len(matched))
+	for _, asset := range matched {
+		matching[asset.Name] = true
+	}
+
+	fmt.Printf("Pattern: %s\n", color.Stdout.Cyan(pattern))
+	for _, name := range names {
+		if matching[name] {
+			fmt.Printf("  %s     %s\n", color.Stdout.Green("match"), name)
+		} else {
+			fmt.Printf("  %s  %s\n", color.Stdout.Gray("no match"), name)
+		}
+	}
+	fmt.Printf("%d of %d names match\n", len(matched), len(names))
+
+	if len(matched) == 0 {
+		return errNoMatches
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package download
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+func TestMatchNames(t *testing.T) {
+	names := []string{"app-v1-linux-amd64.tar.gz", "app-v1-darwin-arm64.tar.gz", "checksums.txt"}
+
+	output := captureStdout(t, func() {
+		if err := matchNames("app-*-linux-*", names); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	for _, expected := range []string{
+		"Pattern: app-*-linux-*",
+		"  match     app-v1-linux-amd64.tar.gz",
+		"  no match  app-v1-darwin-arm64.tar.gz",
+		"  no match  checksums.txt",
+		"1 of 3 names match",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	captureStdout(t, func() {
+		if err := matchNames("*.deb", names); !errors.Is(err, errNoMatches) {
+			t.Errorf("Expected errNoMatches, got %v", err)
+		}
+		if err := matchNames("[", names); err == nil {
+			t.Error("Expected error for an invalid pattern, got nil")
+		}
+	})
+}
+
+func TestMatchTest_Names(t *testing.T) {
+	name := "app-v1-" + runtime.GOOS + "-" + runtime.GOARCH + ".tar.gz"
+	cfg := config.Config{Pattern: "app-*-{{os}}-{{arch}}.tar.gz", Repository: name, Tag: "app-v1-plan9-mips.tar.gz"}
+
+	output := captureStdout(t, func() {
+		if err := MatchTest(cfg); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if !strings.Contains(output, "  match     "+name) || !strings.Contains(output, "1 of 2 names match") {
+		t.Errorf("Unexpected output:\n%s", output)
+	}
+
+	if err := MatchTest(config.Config{Pattern: "*"}); err == nil {
+		t.Error("Expected error without names, got nil")
+	}
+}
@@ -0,0 +1,60 @@
+package download
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// maxMetadataSize is the size above which an asset is not taken for
+// metadata by its text content type alone
+const maxMetadataSize = 1 << 20
+
+// metadataSuffixes mark signatures, certificates, attestations and SBOMs,
+// which are metadata whatever their size
+var metadataSuffixes = []string{
+	".asc", ".sig", ".pem", ".crt", ".cert", ".minisig",
+	".intoto.jsonl", ".sigstore", ".sigstore.json", ".bundle",
+	".sbom", ".spdx", ".spdx.json", ".cdx.json",
+}
+
+// metadataExtensions are text formats that are metadata when small
+var metadataExtensions = []string{".txt", ".json", ".yml", ".yaml", ".toml", ".md"}
+
+// isMetadataAsset reports whether an asset describes the others rather than
+// being a deliverable: checksum files, signatures and attestations, and
+// other small text files, for --checksums-only
+func isMetadataAsset(asset github.Asset) bool {
+	if checksum.IsChecksumFile(asset.Name) {
+		return true
+	}
+	name := strings.ToLower(asset.Name)
+	for _, suffix := range metadataSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	if asset.Size > maxMetadataSize {
+		return false
+	}
+	contentType := strings.ToLower(asset.ContentType)
+	return strings.HasPrefix(contentType, "text/") ||
+		contentType == "application/json" ||
+		contentType == "application/pgp-signature" ||
+		slices.Contains(metadataExtensions, path.Ext(name))
+}
+
+// metadataAssets returns the assets isMetadataAsset picks
+func metadataAssets(assets []github.Asset) []github.Asset {
+	var picked []github.Asset
+	for _, asset := range assets {
+		if isMetadataAsset(asset) {
+			picked = append(picked, asset)
+		}
+	}
+	return picked
+}
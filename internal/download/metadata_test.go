@@ -0,0 +1,32 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestIsMetadataAsset(t *testing.T) {
+	tests := []struct {
+		asset github.Asset
+		want  bool
+	}{
+		{github.Asset{Name: "SHA256SUMS", Size: 512}, true},
+		{github.Asset{Name: "tool_checksums.txt", Size: 512}, true},
+		{github.Asset{Name: "tool.tar.gz.asc", Size: 833}, true},
+		{github.Asset{Name: "tool.tar.gz.sig", Size: 96}, true},
+		{github.Asset{Name: "multiple.intoto.jsonl", Size: 5 << 20}, true},
+		{github.Asset{Name: "tool.spdx.json", Size: 4 << 20}, true},
+		{github.Asset{Name: "NOTICE", Size: 2048, ContentType: "text/plain"}, true},
+		{github.Asset{Name: "release.json", Size: 2048, ContentType: "application/octet-stream"}, true},
+		{github.Asset{Name: "huge.txt", Size: 8 << 20, ContentType: "text/plain"}, false},
+		{github.Asset{Name: "tool.tar.gz", Size: 2048, ContentType: "application/gzip"}, false},
+		{github.Asset{Name: "tool.exe", Size: 1024, ContentType: "application/octet-stream"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isMetadataAsset(tt.asset); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.asset.Name, tt.want, got)
+		}
+	}
+}
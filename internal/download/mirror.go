@@ -0,0 +1,154 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// forkWait is how long mirrorToFork waits for a new fork to appear, and
+// forkPoll how often it looks; both are shortened in tests
+var (
+	forkWait = 2 * time.Minute
+	forkPoll = 2 * time.Second
+)
+
+// mirrorRelease runs mirrorToFork for --mirror-to-fork
+func mirrorRelease(client *api.RESTClient, cfg config.Config, release *github.Release, paths []string) error {
+	uploader, err := newHTTPClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create upload client: %w", err)
+	}
+	return mirrorToFork(client, uploader, cfg.Repository, cfg.MirrorToFork, release, paths)
+}
+
+// mirrorToFork republishes a release and the files downloaded from it in the
+// fork of the repository owned by org, forking the repository first when
+// there is none. The release keeps its tag, name, notes and prerelease flag.
+// Assets the release of the fork already has are left alone, so running
+// again completes an interrupted mirror.
+func mirrorToFork(client github.WriteClient, uploader *http.Client, repo, org string, release *github.Release, paths []string) error {
+	fork, err := ensureFork(client, repo, org)
+	if err != nil {
+		return err
+	}
+
+	mirrored, err := github.GetRelease(client, fork, release.TagName)
+	if isNotFound(err) {
+		if mirrored, err = github.CreateRelease(client, fork, release); err != nil {
+			return fmt.Errorf("failed to create release %s in %s: %w", release.TagName, fork, err)
+		}
+		fmt.Printf("Created release %s in %s\n", release.TagName, fork)
+	} else if err != nil {
+		return fmt.Errorf("failed to get release %s of %s: %w", release.TagName, fork, err)
+	}
+
+	existing := make(map[string]bool, len(mirrored.Assets))
+	for _, asset := range mirrored.Assets {
+		existing[asset.Name] = true
+	}
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if existing[name] {
+			fmt.Printf("Already mirrored %s\n", name)
+			continue
+		}
+		if err := uploadAsset(uploader, github.AssetUploadURL(mirrored, name), path); err != nil {
+			return fmt.Errorf("failed to mirror %s to %s: %w", name, fork, err)
+		}
+		fmt.Printf("Mirrored %s to %s %s\n", name, fork, release.TagName)
+	}
+	return nil
+}
+
+// ensureFork returns the fork of repo owned by org, creating it when org has
+// no repository of that name yet. A repository of that name that is not a
+// fork of repo is an error rather than a place to publish to.
+func ensureFork(client github.WriteClient, repo, org string) (string, error) {
+	_, name, _ := strings.Cut(repo, "/")
+	fork := org + "/" + name
+
+	existing, err := github.GetRepository(client, fork)
+	if err == nil {
+		if !existing.IsForkOf(repo) {
+			return "", fmt.Errorf("%s exists but is not a fork of %s", fork, repo)
+		}
+		return fork, nil
+	}
+	if !isNotFound(err) {
+		return "", fmt.Errorf("failed to get %s: %w", fork, err)
+	}
+
+	created, err := github.CreateFork(client, repo, org)
+	if err != nil {
+		return "", fmt.Errorf("failed to fork %s to %s: %w", repo, org, err)
+	}
+	fmt.Printf("Forking %s to %s\n", repo, created.FullName)
+
+	deadline := time.Now().Add(forkWait)
+	for {
+		_, err := github.GetRepository(client, created.FullName)
+		if err == nil {
+			return created.FullName, nil
+		}
+		if !isNotFound(err) {
+			return "", fmt.Errorf("failed to get %s: %w", created.FullName, err)
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("fork %s did not appear within %v", created.FullName, forkWait)
+		}
+		time.Sleep(forkPoll)
+	}
+}
+
+// uploadAsset uploads the file at path to the upload URL of a release
+func uploadAsset(client *http.Client, uploadURL, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", path, closeErr)
+		}
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", path, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, uploadURL, file)
+	if err != nil {
+		return err
+	}
+	// Uploads must state their length
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+	if resp.StatusCode != http.StatusCreated {
+		return api.HandleHTTPError(resp)
+	}
+	return nil
+}
+
+// isNotFound reports whether err is a 404 of the GitHub API
+func isNotFound(err error) bool {
+	var httpErr *api.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
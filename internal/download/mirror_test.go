@@ -0,0 +1,105 @@
+package download
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// fakeForkAPI answers the requests of mirrorToFork from its fields
+type fakeForkAPI struct {
+	repos    map[string]github.Repository
+	releases map[string]github.Release
+	// forked is the fork created, which appears at once
+	forked    string
+	uploadURL string
+}
+
+func (f *fakeForkAPI) Get(endpoint string, response interface{}) error {
+	if repo, ok := f.repos[strings.TrimPrefix(endpoint, "repos/")]; ok {
+		*response.(*github.Repository) = repo
+		return nil
+	}
+	if release, ok := f.releases[endpoint]; ok {
+		*response.(*github.Release) = release
+		return nil
+	}
+	return &api.HTTPError{StatusCode: http.StatusNotFound}
+}
+
+func (f *fakeForkAPI) Post(endpoint string, body io.Reader, response interface{}) error {
+	switch {
+	case strings.HasSuffix(endpoint, "/forks"):
+		f.forked = "org/repo"
+		fork := github.Repository{FullName: f.forked, Fork: true, Parent: &github.Repository{FullName: "owner/repo"}}
+		f.repos[f.forked] = fork
+		*response.(*github.Repository) = fork
+	case strings.HasSuffix(endpoint, "/releases"):
+		*response.(*github.Release) = github.Release{TagName: "v1.0.0", UploadURL: f.uploadURL + "{?name,label}"}
+	}
+	return nil
+}
+
+func TestMirrorToFork(t *testing.T) {
+	var uploaded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(body)) {
+			t.Errorf("Expected a Content-Length of %d, got %d", len(body), r.ContentLength)
+		}
+		uploaded = append(uploaded, r.URL.Query().Get("name")+"="+string(body))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"app.tar.gz", "checksums.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	client := &fakeForkAPI{repos: map[string]github.Repository{}, releases: map[string]github.Release{}, uploadURL: server.URL + "/assets"}
+	release := &github.Release{TagName: "v1.0.0", Name: "One", Body: "Notes"}
+	captureStdout(t, func() {
+		if err := mirrorToFork(client, server.Client(), "owner/repo", "org", release, paths); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if client.forked != "org/repo" {
+		t.Errorf("Expected owner/repo to be forked to org, got %q", client.forked)
+	}
+	if strings.Join(uploaded, ",") != "app.tar.gz=app.tar.gz,checksums.txt=checksums.txt" {
+		t.Errorf("Expected both files to be uploaded, got %v", uploaded)
+	}
+
+	// A second run finds the fork and its release and uploads what is missing
+	uploaded = nil
+	client.forked = ""
+	client.releases["repos/org/repo/releases/tags/v1.0.0"] = github.Release{TagName: "v1.0.0", UploadURL: server.URL + "/assets", Assets: []github.Asset{{Name: "app.tar.gz"}}}
+	captureStdout(t, func() {
+		if err := mirrorToFork(client, server.Client(), "owner/repo", "org", release, paths); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	if client.forked != "" || strings.Join(uploaded, ",") != "checksums.txt=checksums.txt" {
+		t.Errorf("Expected only checksums.txt to be uploaded to the existing fork, got %v (forked %q)", uploaded, client.forked)
+	}
+}
+
+func TestEnsureFork_NotAFork(t *testing.T) {
+	client := &fakeForkAPI{repos: map[string]github.Repository{"org/repo": {FullName: "org/repo"}}}
+	if _, err := ensureFork(client, "owner/repo", "org"); err == nil || !strings.Contains(err.Error(), "is not a fork of owner/repo") {
+		t.Errorf("Expected an error for a repository that is no fork, got %v", err)
+	}
+}
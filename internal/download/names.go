@@ -0,0 +1,73 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/filetype"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// sanitizeAssetName makes an asset name safe to use as a file name on every
+// platform: path separators, characters Windows rejects and control
+// characters become underscores.
+func sanitizeAssetName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	// Windows also drops trailing dots and spaces
+	sanitized = strings.TrimRight(sanitized, ". ")
+	if sanitized == "" {
+		sanitized = "_"
+	}
+	return sanitized
+}
+
+// assetFileNames returns the local file name of each asset by asset ID.
+// Names that collide after sanitization, compared case-insensitively as on
+// macOS and Windows, get the asset ID appended before their extension; the
+// asset with the lowest ID keeps the plain name, so the result does not
+// depend on the download order.
+func assetFileNames(assets []github.Asset) map[int]string {
+	byID := make([]github.Asset, len(assets))
+	copy(byID, assets)
+	sort.SliceStable(byID, func(i, j int) bool {
+		return byID[i].ID < byID[j].ID
+	})
+
+	names := make(map[int]string, len(assets))
+	taken := make(map[string]bool, len(assets))
+	for _, asset := range byID {
+		name := sanitizeAssetName(asset.Name)
+		if taken[strings.ToLower(name)] {
+			for taken[strings.ToLower(name)] {
+				name = suffixFileName(name, fmt.Sprintf("-%d", asset.ID))
+			}
+			fmt.Fprintf(os.Stderr, "Warning: asset %q (id %d) collides with another asset, saving it as %s\n", asset.Name, asset.ID, name)
+		}
+		taken[strings.ToLower(name)] = true
+		names[asset.ID] = name
+	}
+
+	return names
+}
+
+// suffixFileName inserts suffix before the extension of name, keeping
+// multi-part extensions such as ".tar.gz" together
+func suffixFileName(name, suffix string) string {
+	_, ext, ok := filetype.ForName(name)
+	if !ok {
+		ext = filepath.Ext(name)
+	}
+	if ext == name {
+		ext = ""
+	}
+	return name[:len(name)-len(ext)] + suffix + ext
+}
@@ -0,0 +1,48 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestSanitizeAssetName(t *testing.T) {
+	testCases := map[string]string{
+		"app.tar.gz":      "app.tar.gz",
+		"dir/app.zip":     "dir_app.zip",
+		`app:v1?.zip`:     "app_v1_.zip",
+		"app.exe. ":       "app.exe",
+		"..":              "_",
+		"name\twith\ttab": "name_with_tab",
+	}
+
+	for input, expected := range testCases {
+		if got := sanitizeAssetName(input); got != expected {
+			t.Errorf("sanitizeAssetName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestAssetFileNames(t *testing.T) {
+	assets := []github.Asset{
+		{ID: 30, Name: "App.tar.gz"},
+		{ID: 10, Name: "app.tar.gz"},
+		{ID: 20, Name: "tool:linux"},
+		{ID: 40, Name: "tool_linux"},
+		{ID: 50, Name: "README"},
+	}
+
+	names := assetFileNames(assets)
+	expected := map[int]string{
+		10: "app.tar.gz",
+		30: "App-30.tar.gz",
+		20: "tool_linux",
+		40: "tool_linux-40",
+		50: "README",
+	}
+	for id, name := range expected {
+		if names[id] != name {
+			t.Errorf("Expected asset %d to be saved as %q, got %q", id, name, names[id])
+		}
+	}
+}
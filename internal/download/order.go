@@ -0,0 +1,117 @@
+package download
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// Supported values for the --order flag
+const (
+	OrderManifest = "manifest"
+	OrderName     = "name"
+	OrderSizeAsc  = "size-asc"
+	OrderSizeDesc = "size-desc"
+)
+
+// orderAssets returns a copy of assets sorted in the requested download order.
+// The manifest order keeps the order returned by the GitHub API.
+func orderAssets(assets []github.Asset, order string) ([]github.Asset, error) {
+	ordered := make([]github.Asset, len(assets))
+	copy(ordered, assets)
+
+	switch order {
+	case "", OrderManifest:
+		// keep API order
+	case OrderName:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Name < ordered[j].Name
+		})
+	case OrderSizeAsc:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Size < ordered[j].Size
+		})
+	case OrderSizeDesc:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Size > ordered[j].Size
+		})
+	default:
+		return nil, fmt.Errorf("order must be one of '%s', '%s', '%s' or '%s'", OrderSizeAsc, OrderSizeDesc, OrderName, OrderManifest)
+	}
+
+	return ordered, nil
+}
+
+// Supported values for the --sort flag, which orders the assets and releases
+// of listings and exports
+const (
+	// SortDefault sorts assets by name and releases by publication date
+	SortDefault = ""
+	SortName    = "name"
+	SortDate    = "date"
+	// SortAPI keeps the order of the GitHub API, which may change between
+	// runs
+	SortAPI = "api"
+)
+
+func validateSort(by string) error {
+	switch by {
+	case SortDefault, SortName, SortDate, SortAPI:
+		return nil
+	default:
+		return fmt.Errorf("sort must be one of '%s', '%s' or '%s'", SortName, SortDate, SortAPI)
+	}
+}
+
+// sortAssets returns a copy of assets in the order of --sort: by name by
+// default, or newest first by the time they were last updated. Ties are
+// broken by name and ID, so the order does not depend on the API.
+func sortAssets(assets []github.Asset, by string) []github.Asset {
+	sorted := make([]github.Asset, len(assets))
+	copy(sorted, assets)
+	if by == SortAPI {
+		return sorted
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if by == SortDate && a.UpdatedAt != b.UpdatedAt {
+			return a.UpdatedAt > b.UpdatedAt
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return sorted
+}
+
+// sortReleases returns a copy of releases in the order of --sort: newest
+// first by publication date by default, or by tag name. Drafts, which are
+// not published, count from their creation. Ties are broken by tag and ID.
+func sortReleases(releases []github.Release, by string) []github.Release {
+	sorted := make([]github.Release, len(releases))
+	copy(sorted, releases)
+	if by == SortAPI {
+		return sorted
+	}
+
+	date := func(release github.Release) string {
+		if release.PublishedAt != "" {
+			return release.PublishedAt
+		}
+		return release.CreatedAt
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if by != SortName && date(a) != date(b) {
+			return date(a) > date(b)
+		}
+		if a.TagName != b.TagName {
+			return a.TagName < b.TagName
+		}
+		return a.ID < b.ID
+	})
+	return sorted
+}
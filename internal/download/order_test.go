@@ -0,0 +1,138 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func assetNames(assets []github.Asset) []string {
+	names := make([]string, len(assets))
+	for i, asset := range assets {
+		names[i] = asset.Name
+	}
+	return names
+}
+
+func TestOrderAssets(t *testing.T) {
+	assets := []github.Asset{
+		{Name: "b.tar.gz", Size: 300},
+		{Name: "c.zip", Size: 100},
+		{Name: "a.txt", Size: 200},
+	}
+
+	testCases := []struct {
+		order    string
+		expected string
+	}{
+		{"", "b.tar.gz,c.zip,a.txt"},
+		{OrderManifest, "b.tar.gz,c.zip,a.txt"},
+		{OrderName, "a.txt,b.tar.gz,c.zip"},
+		{OrderSizeAsc, "c.zip,a.txt,b.tar.gz"},
+		{OrderSizeDesc, "b.tar.gz,a.txt,c.zip"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.order, func(t *testing.T) {
+			ordered, err := orderAssets(assets, tc.order)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			got := strings.Join(assetNames(ordered), ",")
+			if got != tc.expected {
+				t.Errorf("Expected order %q, got %q", tc.expected, got)
+			}
+		})
+	}
+
+	// The input slice must not be reordered
+	if assets[0].Name != "b.tar.gz" {
+		t.Errorf("Expected input slice to be left untouched, got %q first", assets[0].Name)
+	}
+}
+
+func TestOrderAssets_InvalidOrder(t *testing.T) {
+	_, err := orderAssets([]github.Asset{{Name: "a"}}, "random")
+	if err == nil {
+		t.Fatal("Expected error for invalid order, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "order must be one of") {
+		t.Errorf("Expected error about order, got %q", err.Error())
+	}
+}
+
+func TestSortAssets(t *testing.T) {
+	assets := []github.Asset{
+		{ID: 3, Name: "b.zip", UpdatedAt: "2024-01-02T00:00:00Z"},
+		{ID: 1, Name: "c.zip", UpdatedAt: "2024-01-03T00:00:00Z"},
+		{ID: 2, Name: "a.zip", UpdatedAt: "2024-01-02T00:00:00Z"},
+	}
+
+	testCases := []struct {
+		by       string
+		expected []string
+	}{
+		{SortDefault, []string{"a.zip", "b.zip", "c.zip"}},
+		{SortName, []string{"a.zip", "b.zip", "c.zip"}},
+		{SortDate, []string{"c.zip", "a.zip", "b.zip"}},
+		{SortAPI, []string{"b.zip", "c.zip", "a.zip"}},
+	}
+	for _, tc := range testCases {
+		got := assetNames(sortAssets(assets, tc.by))
+		if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+			t.Errorf("Expected %v for %q, got %v", tc.expected, tc.by, got)
+		}
+	}
+	if assets[0].Name != "b.zip" {
+		t.Error("Expected the input to be left unsorted")
+	}
+}
+
+func TestSortReleases(t *testing.T) {
+	releases := []github.Release{
+		{ID: 1, TagName: "v1.0.0", PublishedAt: "2024-01-01T00:00:00Z"},
+		{ID: 4, TagName: "v2.0.0-draft", CreatedAt: "2024-03-01T00:00:00Z", Draft: true},
+		{ID: 3, TagName: "v1.2.0", PublishedAt: "2024-02-01T00:00:00Z"},
+		{ID: 2, TagName: "v1.1.0", PublishedAt: "2024-02-01T00:00:00Z"},
+	}
+
+	testCases := []struct {
+		by       string
+		expected string
+	}{
+		{SortDefault, "v2.0.0-draft,v1.1.0,v1.2.0,v1.0.0"},
+		{SortDate, "v2.0.0-draft,v1.1.0,v1.2.0,v1.0.0"},
+		{SortName, "v1.0.0,v1.1.0,v1.2.0,v2.0.0-draft"},
+		{SortAPI, "v1.0.0,v2.0.0-draft,v1.2.0,v1.1.0"},
+	}
+	for _, tc := range testCases {
+		var tags []string
+		for _, release := range sortReleases(releases, tc.by) {
+			tags = append(tags, release.TagName)
+		}
+		if strings.Join(tags, ",") != tc.expected {
+			t.Errorf("Expected %s for %q, got %v", tc.expected, tc.by, tags)
+		}
+	}
+}
+
+func TestSortAssets_Deterministic(t *testing.T) {
+	// The same assets in any API order list the same way
+	shuffled := [][]github.Asset{
+		{{ID: 1, Name: "app.zip"}, {ID: 2, Name: "app.tar.gz"}, {ID: 3, Name: "checksums.txt"}},
+		{{ID: 3, Name: "checksums.txt"}, {ID: 1, Name: "app.zip"}, {ID: 2, Name: "app.tar.gz"}},
+		{{ID: 2, Name: "app.tar.gz"}, {ID: 3, Name: "checksums.txt"}, {ID: 1, Name: "app.zip"}},
+	}
+	expected := strings.Join(assetNames(sortAssets(shuffled[0], SortDefault)), ",")
+	for _, assets := range shuffled[1:] {
+		if got := strings.Join(assetNames(sortAssets(assets, SortDefault)), ","); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+	if err := validateSort("size"); err == nil {
+		t.Error("Expected an error for an unknown sort")
+	}
+}
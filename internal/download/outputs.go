@@ -0,0 +1,174 @@
+package download
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/envfile"
+	"github.com/23prime/gh-download/internal/render"
+)
+
+// writeRunOutputs writes the result of a run as key=value pairs for later CI
+// steps: to $GITHUB_OUTPUT with --github-output, and to the --env-file path
+func writeRunOutputs(cfg config.Config, result runResult) error {
+	var targets []string
+	if cfg.GitHubOutput {
+		path := os.Getenv("GITHUB_OUTPUT")
+		if path == "" {
+			return fmt.Errorf("--github-output requires the GITHUB_OUTPUT environment variable")
+		}
+		targets = append(targets, path)
+	}
+	if cfg.EnvFile != "" {
+		targets = append(targets, cfg.EnvFile)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	algorithm, err := checksumAlgorithm(cfg)
+	if err != nil {
+		return err
+	}
+	pairs, err := runOutputs(result, algorithm)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		if err := envfile.Append(target, pairs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runOutputs returns the outputs of a run: the resolved tag, the absolute
+// paths written, their digests in sha256sum format computed with algorithm,
+// and whether anything changed
+func runOutputs(result runResult, algorithm checksum.Algorithm) ([][2]string, error) {
+	paths := absPaths(result.Paths)
+
+	digests := make([]string, 0, len(paths))
+	for _, path := range paths {
+		digest, err := checksum.File(path, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest+"  "+path)
+	}
+
+	return [][2]string{
+		{"tag", result.Tag},
+		{"count", strconv.Itoa(len(paths))},
+		{"paths", strings.Join(paths, "\n")},
+		{"digests", strings.Join(digests, "\n")},
+		{"up-to-date", strconv.FormatBool(result.UpToDate)},
+	}, nil
+}
+
+// existingSHA256 returns the SHA-256 of the file at path, or "" when it does
+// not exist
+func existingSHA256(path string) (string, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return fileSHA256(path)
+}
+
+// resultFile is a file in the --idempotent-json result
+type resultFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// resultJSON is the single object printed by --idempotent-json. Arrays are
+// sorted so that identical runs produce identical output.
+type resultJSON struct {
+	Tag      string       `json:"tag"`
+	Changed  bool         `json:"changed"`
+	UpToDate bool         `json:"up_to_date"`
+	Files    []resultFile `json:"files"`
+	Error    string       `json:"error,omitempty"`
+	Author   string       `json:"author,omitempty"`
+	// Commit and TargetCommitish are omitted when unknown
+	Commit          string `json:"commit,omitempty"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	// Reactions and DiscussionURL are omitted for releases without any
+	Reactions     int    `json:"reactions,omitempty"`
+	DiscussionURL string `json:"discussion_url,omitempty"`
+	// StaleMetadataCachedAt is set when --stale-ok resolved the release from
+	// metadata cached at that time
+	StaleMetadataCachedAt string `json:"stale_metadata_cached_at,omitempty"`
+	// Release tells how the release was chosen, omitted when the run failed
+	// before
+	Release *ResolvedRelease `json:"release,omitempty"`
+}
+
+// writeResultJSON writes the result of a run, or the error that ended it, as
+// one JSON object
+func writeResultJSON(w io.Writer, result runResult, runErr error) error {
+	out, err := newResultJSON(result, runErr)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// writeResultYAML writes the object of writeResultJSON as YAML, with the same
+// field names and types, for --idempotent-json --format yaml
+func writeResultYAML(w io.Writer, result runResult, runErr error) error {
+	out, err := newResultJSON(result, runErr)
+	if err != nil {
+		return err
+	}
+	return render.WriteYAML(w, out)
+}
+
+func newResultJSON(result runResult, runErr error) (resultJSON, error) {
+	out := resultJSON{
+		Tag:             result.Tag,
+		Changed:         result.Changed,
+		UpToDate:        result.UpToDate,
+		Files:           []resultFile{},
+		Author:          result.Author,
+		Commit:          result.Commit,
+		TargetCommitish: result.TargetCommitish,
+		Reactions:       result.Reactions,
+		DiscussionURL:   result.DiscussionURL,
+		Release:         result.Resolved,
+	}
+	if !result.CachedAt.IsZero() {
+		out.StaleMetadataCachedAt = result.CachedAt.UTC().Format(time.RFC3339)
+	}
+
+	if runErr != nil {
+		out.Error = runErr.Error()
+	} else {
+		paths := absPaths(result.Paths)
+		slices.Sort(paths)
+		for _, path := range slices.Compact(paths) {
+			digest, err := fileSHA256(path)
+			if err != nil {
+				return resultJSON{}, err
+			}
+			out.Files = append(out.Files, resultFile{Path: path, SHA256: digest})
+		}
+	}
+	return out, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	return checksum.File(path, checksum.SHA256)
+}
@@ -0,0 +1,198 @@
+package download
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRunOutputs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, err := runOutputs(runResult{Tag: "v1.0.0", Paths: []string{path}}, checksum.SHA256)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	values := make(map[string]string)
+	for _, pair := range pairs {
+		values[pair[0]] = pair[1]
+	}
+
+	expected := map[string]string{
+		"tag":        "v1.0.0",
+		"count":      "1",
+		"paths":      path,
+		"digests":    "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  " + path,
+		"up-to-date": "false",
+	}
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, values[key])
+		}
+	}
+
+	pairs, err = runOutputs(runResult{Paths: []string{path}}, checksum.MD5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if digests := pairs[3][1]; digests != "b1946ac92492d2347c6235b4d2611184  "+path {
+		t.Errorf("Expected md5 digests, got %q", digests)
+	}
+}
+
+func TestWriteRunOutputs(t *testing.T) {
+	dir := t.TempDir()
+	githubOutput := filepath.Join(dir, "github_output")
+	envFile := filepath.Join(dir, "env")
+	t.Setenv("GITHUB_OUTPUT", githubOutput)
+
+	cfg := config.Config{GitHubOutput: true, EnvFile: envFile}
+	if err := writeRunOutputs(cfg, runResult{Tag: "v2.0.0", UpToDate: true}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, path := range []string{githubOutput, envFile} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected %s to be written, got %v", path, err)
+		}
+		if !strings.Contains(string(data), "tag=v2.0.0\n") || !strings.Contains(string(data), "up-to-date=true\n") {
+			t.Errorf("Unexpected outputs in %s:\n%s", path, data)
+		}
+	}
+}
+
+func TestWriteRunOutputs_MissingGitHubOutput(t *testing.T) {
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	err := writeRunOutputs(config.Config{GitHubOutput: true}, runResult{})
+	if err == nil {
+		t.Fatal("Expected error without GITHUB_OUTPUT, got nil")
+	}
+}
+
+func TestWriteResultJSON(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"b.txt", "a.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	var buf strings.Builder
+	if err := writeResultJSON(&buf, runResult{Tag: "v1.0.0", Paths: paths, Changed: true}, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var got resultJSON
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, buf.String())
+	}
+	if got.Tag != "v1.0.0" || !got.Changed || len(got.Files) != 2 {
+		t.Fatalf("Unexpected result %+v", got)
+	}
+	if filepath.Base(got.Files[0].Path) != "a.txt" || filepath.Base(got.Files[1].Path) != "b.txt" {
+		t.Errorf("Expected files sorted by path, got %+v", got.Files)
+	}
+}
+
+func TestWriteResultJSON_Error(t *testing.T) {
+	var buf strings.Builder
+	if err := writeResultJSON(&buf, runResult{}, errors.New("release not found")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var got resultJSON
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if got.Error != "release not found" || got.Files == nil {
+		t.Errorf("Unexpected result %+v", got)
+	}
+}
+
+func TestWriteResultJSON_Reactions(t *testing.T) {
+	var buf strings.Builder
+	if err := writeResultJSON(&buf, runResult{Tag: "v1.0.0"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "reactions") || strings.Contains(buf.String(), "discussion_url") {
+		t.Errorf("Expected no reactions without any, got %s", buf.String())
+	}
+
+	buf.Reset()
+	result := runResult{Tag: "v1.0.0", Reactions: 12, DiscussionURL: "https://github.com/owner/repo/discussions/1"}
+	if err := writeResultJSON(&buf, result, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got resultJSON
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Reactions != 12 || got.DiscussionURL != result.DiscussionURL {
+		t.Errorf("Unexpected result %+v", got)
+	}
+}
+
+func TestWriteResultJSON_Release(t *testing.T) {
+	var buf strings.Builder
+	resolved := newResolvedRelease(&github.Release{TagName: "v2.0.0"}, "", "--author alice")
+	if err := writeResultJSON(&buf, runResult{Tag: "v2.0.0", Resolved: &resolved}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got resultJSON
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Release == nil || got.Release.Method != ResolvedFiltered || got.Release.Filter != "--author alice" {
+		t.Errorf("Unexpected release %+v", got.Release)
+	}
+}
+
+func TestWriteResultYAML(t *testing.T) {
+	result := runResult{Tag: "v2.0.0", Changed: true, Reactions: 12, Author: "alice"}
+
+	var jsonBuf, yamlBuf strings.Builder
+	if err := writeResultJSON(&jsonBuf, result, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeResultYAML(&yamlBuf, result, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var fromYAML, fromJSON any
+	if err := yaml.Unmarshal([]byte(yamlBuf.String()), &fromYAML); err != nil {
+		t.Fatalf("Expected valid YAML, got %v:\n%s", err, yamlBuf.String())
+	}
+	if err := json.Unmarshal([]byte(jsonBuf.String()), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	// The YAML decodes to the same document as the JSON
+	yamlJSON, err := json.Marshal(fromYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonJSON, err := json.Marshal(fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(yamlJSON) != string(jsonJSON) {
+		t.Errorf("Expected the YAML to round-trip to %s, got %s", jsonJSON, yamlJSON)
+	}
+}
@@ -0,0 +1,122 @@
+package download
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/tmpl"
+)
+
+// pathPlaceholder matches the placeholders of --output-template, such as
+// {tag}
+var pathPlaceholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// pathFields are the placeholders of --output-template, sorted
+var pathFields = []string{"arch", "name", "os", "owner", "published", "repo", "tag", "version"}
+
+// unknownPlatform stands in for the os or arch of assets whose names do not
+// tell, such as checksums.txt
+const unknownPlatform = "any"
+
+// osWords and archWords are the words naming operating systems and
+// architectures in asset names, mapped to GOOS and GOARCH. The first word
+// found in a name wins.
+var (
+	osWords = []struct{ word, os string }{
+		{"windows", "windows"}, {"darwin", "darwin"}, {"macos", "darwin"}, {"linux", "linux"},
+		{"freebsd", "freebsd"}, {"openbsd", "openbsd"}, {"netbsd", "netbsd"},
+		{"apple", "darwin"}, {"osx", "darwin"}, {"win", "windows"},
+	}
+	archWords = []struct{ word, arch string }{
+		{"amd64", "amd64"}, {"aarch64", "arm64"}, {"arm64", "arm64"},
+		{"armv7", "arm"}, {"armv6", "arm"}, {"armhf", "arm"}, {"i386", "386"}, {"i686", "386"},
+		{"x64", "amd64"}, {"386", "386"}, {"x86", "386"}, {"arm", "arm"},
+	}
+)
+
+// checkPathTemplate rejects unknown placeholders in an --output-template
+// before anything is downloaded
+func checkPathTemplate(template string) error {
+	for _, match := range pathPlaceholder.FindAllStringSubmatch(template, -1) {
+		i := sort.SearchStrings(pathFields, match[1])
+		if i == len(pathFields) || pathFields[i] != match[1] {
+			return fmt.Errorf("unknown placeholder %s in --output-template; available: {%s}", match[0], strings.Join(pathFields, "}, {"))
+		}
+	}
+	return nil
+}
+
+// templateFileNames expands --output-template for each asset into a path
+// relative to the directory of the run. Placeholders are replaced by the
+// release metadata in data and by the name and platform of the asset, each
+// sanitized like an asset name, so only the slashes of the template make
+// directories. Paths leaving the directory, or given to two assets, fail.
+func templateFileNames(template string, data tmpl.Data, assets []github.Asset, fileNames map[int]string) (map[int]string, error) {
+	if err := checkPathTemplate(template); err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string, len(assets))
+	taken := make(map[string]string, len(assets))
+	for _, asset := range assets {
+		assetOS, assetArch := assetPlatform(asset.Name)
+		values := map[string]string{
+			"owner":   data.Owner,
+			"repo":    data.Name,
+			"tag":     data.Tag,
+			"version": strings.TrimPrefix(strings.TrimPrefix(data.Tag, "v"), "V"),
+			"name":    fileNames[asset.ID],
+			"os":      assetOS,
+			"arch":    assetArch,
+		}
+		if !data.PublishedAt.IsZero() {
+			values["published"] = data.PublishedAt.Format("2006-01-02")
+		}
+
+		expanded := pathPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+			return sanitizeAssetName(values[strings.Trim(placeholder, "{}")])
+		})
+		name := filepath.FromSlash(path.Clean(expanded))
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("--output-template %q puts %s at %s, outside the download directory", template, asset.Name, expanded)
+		}
+		if other, ok := taken[strings.ToLower(name)]; ok {
+			return nil, fmt.Errorf("--output-template %q puts both %s and %s at %s; add {name}", template, other, asset.Name, name)
+		}
+		taken[strings.ToLower(name)] = asset.Name
+		names[asset.ID] = name
+	}
+	return names, nil
+}
+
+// assetPlatform returns the operating system and architecture an asset name
+// is for, such as linux and amd64 for app_Linux_x86_64.tar.gz, or "any" for
+// either when the name does not tell
+func assetPlatform(name string) (string, string) {
+	name = strings.NewReplacer("x86_64", "amd64", "x86-64", "amd64").Replace(strings.ToLower(name))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	assetOS, assetArch := unknownPlatform, unknownPlatform
+	for _, candidate := range osWords {
+		if slices.Contains(words, candidate.word) {
+			assetOS = candidate.os
+			break
+		}
+	}
+	for _, candidate := range archWords {
+		if slices.Contains(words, candidate.word) {
+			assetArch = candidate.arch
+			break
+		}
+	}
+	return assetOS, assetArch
+}
@@ -0,0 +1,89 @@
+package download
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/tmpl"
+)
+
+func TestAssetPlatform(t *testing.T) {
+	testCases := []struct {
+		name string
+		os   string
+		arch string
+	}{
+		{"app_Linux_x86_64.tar.gz", "linux", "amd64"},
+		{"app-v1.0.0-darwin-arm64.zip", "darwin", "arm64"},
+		{"app-windows-amd64.exe", "windows", "amd64"},
+		{"app_1.0.0_linux_armv7.deb", "linux", "arm"},
+		{"app-macos-universal.pkg", "darwin", "any"},
+		{"app-aarch64-unknown-linux-gnu.tar.xz", "linux", "arm64"},
+		{"checksums.txt", "any", "any"},
+		{"darwinian.txt", "any", "any"},
+	}
+	for _, tc := range testCases {
+		os, arch := assetPlatform(tc.name)
+		if os != tc.os || arch != tc.arch {
+			t.Errorf("%s: expected %s/%s, got %s/%s", tc.name, tc.os, tc.arch, os, arch)
+		}
+	}
+}
+
+func TestTemplateFileNames(t *testing.T) {
+	data := tmpl.Data{Repo: "owner/app", Owner: "owner", Name: "app", Tag: "v1.2.0", PublishedAt: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)}
+	assets := []github.Asset{
+		{ID: 1, Name: "app_linux_amd64.tar.gz"},
+		{ID: 2, Name: "app_darwin_arm64.tar.gz"},
+		{ID: 3, Name: "checksums.txt"},
+	}
+
+	names, err := templateFileNames("{tag}/{os}-{arch}/{name}", data, assets, assetFileNames(assets))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := map[int]string{
+		1: filepath.Join("v1.2.0", "linux-amd64", "app_linux_amd64.tar.gz"),
+		2: filepath.Join("v1.2.0", "darwin-arm64", "app_darwin_arm64.tar.gz"),
+		3: filepath.Join("v1.2.0", "any-any", "checksums.txt"),
+	}
+	for id, name := range expected {
+		if names[id] != name {
+			t.Errorf("Asset %d: expected %s, got %s", id, name, names[id])
+		}
+	}
+
+	names, err = templateFileNames("{owner}/{repo}/{version}-{published}/{name}", data, assets[:1], assetFileNames(assets[:1]))
+	if err != nil || names[1] != filepath.Join("owner", "app", "1.2.0-2026-03-04", "app_linux_amd64.tar.gz") {
+		t.Errorf("Expected the release fields, got %v, %v", names, err)
+	}
+}
+
+func TestTemplateFileNames_Invalid(t *testing.T) {
+	data := tmpl.Data{Owner: "owner", Name: "app", Tag: "../.."}
+	assets := []github.Asset{{ID: 1, Name: "app.tar.gz"}, {ID: 2, Name: "app.zip"}}
+
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{"{platform}/{name}", "unknown placeholder {platform}"},
+		{"{tag}", "puts both app.tar.gz and app.zip"},
+		{"../{name}", "outside the download directory"},
+	}
+	for _, tc := range testCases {
+		_, err := templateFileNames(tc.template, data, assets, assetFileNames(assets))
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected error %q, got %v", tc.template, tc.expected, err)
+		}
+	}
+
+	// Placeholders cannot add directories, so a tag of ../.. stays below
+	names, err := templateFileNames("{tag}/{name}", data, assets, assetFileNames(assets))
+	if err != nil || names[1] != filepath.Join(".._", "app.tar.gz") {
+		t.Errorf("Expected the tag to be sanitized, got %v, %v", names, err)
+	}
+}
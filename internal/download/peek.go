@@ -0,0 +1,98 @@
+package download
+
+import (
+	"archive/zip"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/filetype"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/remote"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// detectBytes is the minimum number of bytes fetched to detect a file type
+const detectBytes = 512
+
+// Peek prints the file type and leading bytes of the matching assets using
+// range requests, without downloading them. Zip assets also list their
+// contents, read from the central directory at the end of the archive.
+func Peek(cfg config.Config) error {
+	if cfg.Repository == "" {
+		return fmt.Errorf("repository is required")
+	}
+	if cfg.Bytes <= 0 {
+		return fmt.Errorf("bytes must be a positive number")
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+
+	release, err := github.GetRelease(client, cfg.Repository, cfg.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to get release: %w", err)
+	}
+
+	printReleaseHeader(release, newResolvedRelease(release, cfg.Tag, ""), cfg.Repository)
+
+	matchingAssets, err := github.FilterAssets(release.Assets, cfg.Pattern)
+	if err != nil {
+		return fmt.Errorf("failed to filter assets: %w", err)
+	}
+
+	if len(matchingAssets) == 0 {
+		return fmt.Errorf("no assets found matching pattern '%s'", cfg.Pattern)
+	}
+
+	httpClient, err := newAssetHTTPClient()
+	if err != nil {
+		return fmt.Errorf("failed to create download client: %w", err)
+	}
+
+	for _, asset := range matchingAssets {
+		file := remote.Open(httpClient, asset.URL, int64(asset.Size))
+		if err := peekAsset(file, asset, cfg.Bytes); err != nil {
+			return fmt.Errorf("failed to peek %s: %w", asset.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func peekAsset(file *remote.File, asset github.Asset, n int) error {
+	head, err := file.Head(int64(max(n, detectBytes)))
+	if err != nil {
+		return err
+	}
+	kind := filetype.Detect(head)
+
+	fmt.Printf("\n%s (%d bytes)\n", asset.Name, asset.Size)
+	fmt.Printf("   Type: %s\n", kind.Name)
+	fmt.Printf("   Content-Type: %s\n", asset.ContentType)
+
+	head = head[:min(n, len(head))]
+	fmt.Printf("   First %d bytes:\n", len(head))
+	for _, line := range strings.Split(strings.TrimRight(hex.Dump(head), "\n"), "\n") {
+		fmt.Printf("     %s\n", line)
+	}
+
+	if kind != filetype.Zip {
+		return nil
+	}
+
+	reader, err := zip.NewReader(file, file.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read zip directory: %w", err)
+	}
+
+	fmt.Printf("   Contents (%d entries):\n", len(reader.File))
+	for _, entry := range reader.File {
+		fmt.Printf("     %s (%d bytes)\n", entry.Name, entry.UncompressedSize64)
+	}
+
+	return nil
+}
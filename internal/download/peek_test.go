@@ -0,0 +1,153 @@
+package download
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/remote"
+)
+
+func TestPeekAsset(t *testing.T) {
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for _, name := range []string{"bin/tool", "README.md"} {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte("content of " + name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"/notes.txt":  []byte("hello, world\n"),
+		"/tool.zip":   archive.Bytes(),
+		"/broken.zip": []byte("PK\x03\x04 not really a zip"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		n       int
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{
+			name: "text",
+			path: "/notes.txt",
+			n:    5,
+			want: []string{
+				"notes.txt (13 bytes)",
+				"Type: text",
+				"Content-Type: text/plain",
+				"First 5 bytes:",
+				"68 65 6c 6c 6f",
+			},
+			notWant: []string{"Contents"},
+		},
+		{
+			name: "more bytes than the file",
+			path: "/notes.txt",
+			n:    100,
+			want: []string{"First 13 bytes:"},
+		},
+		{
+			name: "zip",
+			path: "/tool.zip",
+			n:    4,
+			want: []string{
+				"Type: zip archive",
+				"First 4 bytes:",
+				"Contents (2 entries):",
+				"bin/tool (19 bytes)",
+				"README.md (20 bytes)",
+			},
+		},
+		{
+			name:    "broken zip",
+			path:    "/broken.zip",
+			n:       4,
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			path:    "/missing",
+			size:    10,
+			n:       4,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := len(files[tt.path])
+			if tt.size != 0 {
+				size = tt.size
+			}
+			name := strings.TrimPrefix(tt.path, "/")
+			asset := github.Asset{Name: name, URL: server.URL + tt.path, Size: size, ContentType: "text/plain"}
+			file := remote.Open(server.Client(), asset.URL, int64(size))
+
+			var err error
+			output := captureStdout(t, func() {
+				err = peekAsset(file, asset, tt.n)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(output, notWant) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", notWant, output)
+				}
+			}
+		})
+	}
+}
+
+func TestPeekValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{name: "no repository", cfg: config.Config{Bytes: 16}, want: "repository is required"},
+		{name: "zero bytes", cfg: config.Config{Repository: "owner/repo"}, want: "bytes must be a positive number"},
+		{name: "negative bytes", cfg: config.Config{Repository: "owner/repo", Bytes: -1}, want: "bytes must be a positive number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Peek(tt.cfg)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
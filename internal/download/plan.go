@@ -0,0 +1,417 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/manifest"
+	"github.com/23prime/gh-download/internal/progress"
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// planAction is what a plan does with a file
+type planAction string
+
+const (
+	planCreate    planAction = "create"
+	planUpdate    planAction = "update"
+	planDelete    planAction = "delete"
+	planUnchanged planAction = "unchanged"
+)
+
+// planSymbols mark the actions in a printed plan, as terraform does
+var planSymbols = map[planAction]string{
+	planCreate:    "+",
+	planUpdate:    "~",
+	planDelete:    "-",
+	planUnchanged: "=",
+}
+
+// planChange is a file of a tool directory and what the plan does with it.
+// Asset is the asset the file is downloaded from, unless it is deleted.
+type planChange struct {
+	Action planAction
+	Name   string
+	Asset  github.Asset
+}
+
+// toolPlan is the plan for the directory of one tool of a manifest
+type toolPlan struct {
+	Name string
+	// Repository is HOST/OWNER/REPO for tools of another host
+	Repository string
+	Tag        string
+	Dir        string
+	// DependsOn are the tools applied before this one
+	DependsOn []string
+	Changes   []planChange
+	// fileNames are the file names of the matching assets by ID
+	fileNames map[int]string
+	// checksums are the digests the manifest pins, if any
+	checksums *checksumSet
+}
+
+// Plan prints what syncing --dir with the tools of the manifest given to
+// --from-file would download, update and delete, and with --apply makes
+// those changes
+func Plan(cfg config.Config) error {
+	if cfg.FromFile == "" {
+		return fmt.Errorf("--from-file is required")
+	}
+	m, err := manifest.Load(cfg.FromFile)
+	if err != nil {
+		return err
+	}
+
+	var plans []toolPlan
+	for _, name := range m.Order() {
+		tool := m.Tools[name]
+		release, err := toolRelease(tool)
+		if err != nil {
+			return fmt.Errorf("tool %s: failed to get release: %w", name, err)
+		}
+		plan, err := planTool(name, tool, cfg.Directory, release)
+		if err != nil {
+			return fmt.Errorf("tool %s: %w", name, err)
+		}
+		plans = append(plans, plan)
+	}
+
+	pending := printPlan(os.Stdout, plans)
+	if !cfg.Apply {
+		if pending > 0 {
+			fmt.Println("\nRun again with --apply to make these changes.")
+		}
+		return nil
+	}
+	if pending == 0 {
+		return nil
+	}
+
+	unlock, err := lockDirectory(cfg)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return applyPlans(cfg, plans)
+}
+
+// toolRepository returns the repository of a tool as useRepositoryHost
+// takes it
+func toolRepository(tool manifest.Tool) string {
+	if tool.Host != "" {
+		return tool.Host + "/" + tool.Repo
+	}
+	return tool.Repo
+}
+
+// toolRelease returns the release of a tool: its tag, or the latest release
+func toolRelease(tool manifest.Tool) (*github.Release, error) {
+	cfg, restoreHost, err := useRepositoryHost(config.Config{Repository: toolRepository(tool)})
+	if err != nil {
+		return nil, err
+	}
+	defer restoreHost()
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	return github.GetRelease(client, cfg.Repository, tool.Tag)
+}
+
+// toolDirectory returns the directory of a tool: its dir below dir, or
+// named after the tool without one. Absolute dirs and dirs in the home
+// directory are used as they are.
+func toolDirectory(dir, name string, tool manifest.Tool) string {
+	switch {
+	case tool.Dir == "":
+		return filepath.Join(dir, name)
+	case strings.HasPrefix(tool.Dir, "~/"):
+		return expandHome(tool.Dir)
+	case filepath.IsAbs(tool.Dir):
+		return tool.Dir
+	default:
+		return filepath.Join(dir, tool.Dir)
+	}
+}
+
+// planTool compares the directory of a tool with the assets of its release
+// matching its pattern, and pinned by its digests if any, saved under the
+// names its files give if any. Files are unchanged
+// when they have the pinned digest, or else the digest GitHub reports for the
+// asset or, without one, its size. Other files in the directory are deleted,
+// except hidden ones such as the lock.
+func planTool(name string, tool manifest.Tool, dir string, release *github.Release) (toolPlan, error) {
+	pattern := tool.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+	assets, err := github.FilterAssets(release.Assets, pattern)
+	if err != nil {
+		return toolPlan{}, fmt.Errorf("failed to filter assets: %w", err)
+	}
+	assets, checksums, err := pinnedAssets(tool, assets, release.TagName)
+	if err != nil {
+		return toolPlan{}, err
+	}
+
+	plan := toolPlan{
+		Name:       name,
+		Repository: toolRepository(tool),
+		Tag:        release.TagName,
+		Dir:        toolDirectory(dir, name, tool),
+		DependsOn:  tool.DependsOn,
+		fileNames:  assetFileNames(assets),
+		checksums:  checksums,
+	}
+	for _, asset := range assets {
+		if file, ok := tool.Files[asset.Name]; ok {
+			plan.fileNames[asset.ID] = filepath.FromSlash(file)
+		}
+	}
+	expected := make(map[string]bool, len(assets))
+	for _, asset := range assets {
+		fileName := plan.fileNames[asset.ID]
+		expected[fileName] = true
+		action, err := planAsset(asset, filepath.Join(plan.Dir, fileName), checksums.pinned(asset))
+		if err != nil {
+			return toolPlan{}, err
+		}
+		plan.Changes = append(plan.Changes, planChange{Action: action, Name: fileName, Asset: asset})
+	}
+
+	entries, err := os.ReadDir(plan.Dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return toolPlan{}, fmt.Errorf("failed to read %s: %w", plan.Dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || expected[entry.Name()] {
+			continue
+		}
+		plan.Changes = append(plan.Changes, planChange{Action: planDelete, Name: entry.Name()})
+	}
+
+	sort.Slice(plan.Changes, func(i, j int) bool {
+		return plan.Changes[i].Name < plan.Changes[j].Name
+	})
+	return plan, nil
+}
+
+// pinnedAssets keeps the assets a tool pins with its digests and returns the
+// digests to verify them with. Without digests the assets are returned as
+// they are. A pinned asset missing from the release, or reported by GitHub
+// with another digest, fails the plan.
+func pinnedAssets(tool manifest.Tool, assets []github.Asset, tag string) ([]github.Asset, *checksumSet, error) {
+	if len(tool.Digests) == 0 {
+		return assets, nil, nil
+	}
+
+	set := &checksumSet{entries: make(map[string][]checksum.Entry)}
+	var pinned []github.Asset
+	for _, asset := range assets {
+		digest, ok := tool.Digests[asset.Name]
+		if !ok {
+			continue
+		}
+		entry, err := checksum.ParseDigest(digest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("asset %s: %w", asset.Name, err)
+		}
+		entry.Source = "the manifest"
+		if algorithm, reported, ok := githubDigest(asset); ok && algorithm == entry.Algorithm && reported != entry.Digest {
+			return nil, nil, &VerificationError{Err: fmt.Errorf("%w: %s has %s %s on GitHub, the manifest pins %s", retry.ErrChecksum, asset.Name, algorithm, reported, entry.Digest)}
+		}
+		set.entries[asset.Name] = []checksum.Entry{entry}
+		pinned = append(pinned, asset)
+	}
+
+	names := make([]string, 0, len(tool.Digests))
+	for name := range tool.Digests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := set.entries[name]; !ok {
+			return nil, nil, fmt.Errorf("asset %s pinned by the manifest is not in release %s", name, tag)
+		}
+	}
+	return pinned, set, nil
+}
+
+// pinned returns the entry pinning the digest of an asset, or nil
+func (s *checksumSet) pinned(asset github.Asset) *checksum.Entry {
+	if s == nil || len(s.entries[asset.Name]) == 0 {
+		return nil
+	}
+	return &s.entries[asset.Name][0]
+}
+
+// planAsset returns what the plan does with the file of an asset at path,
+// comparing it with the pinned digest when there is one
+func planAsset(asset github.Asset, path string, pinned *checksum.Entry) (planAction, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return planCreate, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check %s: %w", path, err)
+	}
+
+	if pinned != nil {
+		current, err := checksum.File(path, pinned.Algorithm)
+		if err != nil {
+			return "", err
+		}
+		if current == pinned.Digest {
+			return planUnchanged, nil
+		}
+		return planUpdate, nil
+	}
+	if digest := assetSHA256(asset); digest != "" {
+		current, err := fileSHA256(path)
+		if err != nil {
+			return "", err
+		}
+		if current == digest {
+			return planUnchanged, nil
+		}
+		return planUpdate, nil
+	}
+	if info.Size() == int64(asset.Size) {
+		return planUnchanged, nil
+	}
+	return planUpdate, nil
+}
+
+// printPlan writes the plans to w and returns the number of changes
+func printPlan(w io.Writer, plans []toolPlan) int {
+	counts := make(map[planAction]int)
+	for _, plan := range plans {
+		var after string
+		if len(plan.DependsOn) > 0 {
+			after = fmt.Sprintf(" (after %s)", strings.Join(plan.DependsOn, ", "))
+		}
+		fmt.Fprintf(w, "%s: %s %s -> %s%s\n", plan.Name, plan.Repository, plan.Tag, plan.Dir, after)
+		for _, change := range plan.Changes {
+			counts[change.Action]++
+			switch change.Action {
+			case planCreate, planUpdate:
+				fmt.Fprintf(w, "  %s %s (%s)\n", planSymbols[change.Action], change.Name, progress.FormatBytes(int64(change.Asset.Size)))
+			default:
+				fmt.Fprintf(w, "  %s %s\n", planSymbols[change.Action], change.Name)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nPlan: %d to download, %d to update, %d to delete, %d unchanged.\n",
+		counts[planCreate], counts[planUpdate], counts[planDelete], counts[planUnchanged])
+	return counts[planCreate] + counts[planUpdate] + counts[planDelete]
+}
+
+// applyPlans downloads the new and changed assets of each tool and deletes
+// the files no longer wanted
+func applyPlans(cfg config.Config, plans []toolPlan) error {
+	policy, err := retryPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	return applyInOrder(plans, cfg.ContinueOnError, func(plan toolPlan) error {
+		return applyPlan(plan, policy)
+	})
+}
+
+// applyInOrder applies the plans in their dependency order. A tool whose
+// dependency failed or was skipped is skipped in turn, so that nothing is
+// installed unchecked; with continueOnError the other tools are still
+// applied. The failures and skipped tools are reported at the end.
+func applyInOrder(plans []toolPlan, continueOnError bool, apply func(toolPlan) error) error {
+	failed := make(map[string]bool)
+	var report []string
+	for _, plan := range plans {
+		if dependency, ok := failedDependency(plan, failed); ok {
+			failed[plan.Name] = true
+			report = append(report, fmt.Sprintf("%s: skipped, depends on %s, which did not sync", plan.Name, dependency))
+			continue
+		}
+		if err := apply(plan); err != nil {
+			if !continueOnError {
+				return fmt.Errorf("tool %s: %w", plan.Name, err)
+			}
+			fmt.Fprintf(os.Stderr, "Error: tool %s: %v\n", plan.Name, err)
+			failed[plan.Name] = true
+			report = append(report, fmt.Sprintf("%s: failed: %v", plan.Name, err))
+		}
+	}
+	if len(report) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "\n%d of %d tools did not sync:\n", len(report), len(plans))
+	for _, line := range report {
+		fmt.Fprintf(os.Stderr, "  - %s\n", line)
+	}
+	return fmt.Errorf("%d of %d tools did not sync", len(report), len(plans))
+}
+
+// failedDependency returns the first dependency of plan that failed
+func failedDependency(plan toolPlan, failed map[string]bool) (string, bool) {
+	for _, dependency := range plan.DependsOn {
+		if failed[dependency] {
+			return dependency, true
+		}
+	}
+	return "", false
+}
+
+// applyPlan downloads the new and changed assets of a tool and deletes its
+// files no longer wanted
+func applyPlan(plan toolPlan, policy retry.Policy) error {
+	var assets []github.Asset
+	for _, change := range plan.Changes {
+		if change.Action == planCreate || change.Action == planUpdate {
+			assets = append(assets, change.Asset)
+		}
+	}
+	if len(assets) > 0 {
+		if err := applyDownloads(plan, assets, policy); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range plan.Changes {
+		if change.Action != planDelete {
+			continue
+		}
+		path := filepath.Join(plan.Dir, change.Name)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		fmt.Printf("Removed %s\n", path)
+	}
+	return nil
+}
+
+func applyDownloads(plan toolPlan, assets []github.Asset, policy retry.Policy) error {
+	_, restoreHost, err := useRepositoryHost(config.Config{Repository: plan.Repository})
+	if err != nil {
+		return err
+	}
+	defer restoreHost()
+
+	run := newAssetRun(len(assets), false)
+	run.policy = policy
+	run.verifyDigest = true
+	run.fileNames = plan.fileNames
+	run.checksums = plan.checksums
+	return downloadAssets(run, assets, plan.Dir, false)
+}
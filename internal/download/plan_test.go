@@ -0,0 +1,197 @@
+package download
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/manifest"
+)
+
+func TestToolDirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	testCases := []struct {
+		tool     manifest.Tool
+		expected string
+	}{
+		{manifest.Tool{}, filepath.Join("tools", "gh")},
+		{manifest.Tool{Dir: "bin"}, filepath.Join("tools", "bin")},
+		{manifest.Tool{Dir: "~/bin"}, filepath.Join(home, "bin")},
+		{manifest.Tool{Dir: "/opt/gh"}, "/opt/gh"},
+	}
+	for _, tc := range testCases {
+		if got := toolDirectory("tools", "gh", tc.tool); got != tc.expected {
+			t.Errorf("Dir %q: expected %q, got %q", tc.tool.Dir, tc.expected, got)
+		}
+	}
+}
+
+func TestPlanTool(t *testing.T) {
+	dir := t.TempDir()
+	toolDir := filepath.Join(dir, "gh")
+	if err := os.MkdirAll(toolDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"same.tar.gz":    "same",
+		"changed.tar.gz": "old",
+		"sized.tar.gz":   "12345",
+		"old.tar.gz":     "gone",
+		".hidden":        "kept",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(toolDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	release := &github.Release{TagName: "v2.0.0", Assets: []github.Asset{
+		{ID: 1, Name: "same.tar.gz", Size: 4, Digest: "sha256:" + sha256Hex("same")},
+		{ID: 2, Name: "changed.tar.gz", Size: 3, Digest: "sha256:" + sha256Hex("new")},
+		{ID: 3, Name: "sized.tar.gz", Size: 5},
+		{ID: 4, Name: "new.tar.gz", Size: 2048},
+		{ID: 5, Name: "notes.txt", Size: 10},
+	}}
+
+	plan, err := planTool("gh", manifest.Tool{Repo: "cli/cli", Pattern: "*.tar.gz"}, dir, release)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]planAction{
+		"changed.tar.gz": planUpdate,
+		"new.tar.gz":     planCreate,
+		"old.tar.gz":     planDelete,
+		"same.tar.gz":    planUnchanged,
+		"sized.tar.gz":   planUnchanged,
+	}
+	if len(plan.Changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), plan.Changes)
+	}
+	for _, change := range plan.Changes {
+		if expected[change.Name] != change.Action {
+			t.Errorf("%s: expected %s, got %s", change.Name, expected[change.Name], change.Action)
+		}
+	}
+
+	var out bytes.Buffer
+	if pending := printPlan(&out, []toolPlan{plan}); pending != 3 {
+		t.Errorf("Expected 3 pending changes, got %d", pending)
+	}
+	for _, line := range []string{
+		"gh: cli/cli v2.0.0 -> " + toolDir,
+		"  + new.tar.gz (2.0 KiB)",
+		"  ~ changed.tar.gz (3 B)",
+		"  - old.tar.gz",
+		"  = same.tar.gz",
+		"Plan: 1 to download, 1 to update, 1 to delete, 2 unchanged.",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %q in plan:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestPlanTool_Files(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "gh", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gh", "bin", "gh.tgz"), []byte("same"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	release := &github.Release{TagName: "v2.0.0", Assets: []github.Asset{{ID: 1, Name: "gh_linux.tar.gz", Size: 4}}}
+	tool := manifest.Tool{
+		Repo:    "cli/cli",
+		Digests: map[string]string{"gh_linux.tar.gz": "sha256:" + sha256Hex("same")},
+		Files:   map[string]string{"gh_linux.tar.gz": "bin/gh.tgz"},
+	}
+	plan, err := planTool("gh", tool, dir, release)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Name != filepath.Join("bin", "gh.tgz") || plan.Changes[0].Action != planUnchanged {
+		t.Errorf("Expected the asset to be unchanged under its file name, got %+v", plan.Changes)
+	}
+}
+
+func TestApplyInOrder_SkipsDependents(t *testing.T) {
+	plans := []toolPlan{
+		{Name: "key"},
+		{Name: "app", DependsOn: []string{"key"}},
+		{Name: "plugin", DependsOn: []string{"app"}},
+		{Name: "other"},
+	}
+	fail := func(plan toolPlan) error {
+		if plan.Name == "key" {
+			return errors.New("digest mismatch")
+		}
+		return nil
+	}
+
+	var applied []string
+	err := applyInOrder(plans, true, func(plan toolPlan) error {
+		applied = append(applied, plan.Name)
+		return fail(plan)
+	})
+	if err == nil || !strings.Contains(err.Error(), "3 of 4 tools") {
+		t.Errorf("Expected 3 of 4 tools to fail, got %v", err)
+	}
+	if strings.Join(applied, ",") != "key,other" {
+		t.Errorf("Expected only key and other to be applied, got %v", applied)
+	}
+
+	applied = nil
+	if err := applyInOrder(plans, false, func(plan toolPlan) error {
+		applied = append(applied, plan.Name)
+		return fail(plan)
+	}); err == nil || !strings.Contains(err.Error(), "tool key: digest mismatch") {
+		t.Errorf("Expected the failure of key, got %v", err)
+	}
+	if strings.Join(applied, ",") != "key" {
+		t.Errorf("Expected to stop after key, got %v", applied)
+	}
+}
+
+func TestPinnedAssets(t *testing.T) {
+	pinned := "sha256:" + strings.Repeat("a", 64)
+	assets := []github.Asset{
+		{ID: 1, Name: "app.tar.gz", Digest: pinned},
+		{ID: 2, Name: "app.zip"},
+		{ID: 3, Name: "notes.txt"},
+	}
+	tool := manifest.Tool{Repo: "owner/app", Digests: map[string]string{"app.tar.gz": pinned, "app.zip": pinned}}
+
+	kept, checksums, err := pinnedAssets(tool, assets, "v1.0.0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(kept) != 2 || kept[0].Name != "app.tar.gz" || kept[1].Name != "app.zip" {
+		t.Errorf("Expected only the pinned assets, got %+v", kept)
+	}
+	if entry := checksums.pinned(assets[1]); entry == nil || entry.Digest != strings.Repeat("a", 64) {
+		t.Errorf("Expected the pinned digest of app.zip, got %+v", entry)
+	}
+
+	tool.Digests["missing.deb"] = pinned
+	if _, _, err := pinnedAssets(tool, assets, "v1.0.0"); err == nil || !strings.Contains(err.Error(), "missing.deb pinned by the manifest is not in release v1.0.0") {
+		t.Errorf("Expected an error for the missing asset, got %v", err)
+	}
+
+	tool.Digests = map[string]string{"app.tar.gz": "sha256:" + strings.Repeat("b", 64)}
+	var verifyErr *VerificationError
+	if _, _, err := pinnedAssets(tool, assets, "v1.0.0"); !errors.As(err, &verifyErr) {
+		t.Errorf("Expected a verification error for a changed asset, got %v", err)
+	}
+
+	if kept, checksums, err := pinnedAssets(manifest.Tool{}, assets, "v1.0.0"); err != nil || len(kept) != 3 || checksums != nil {
+		t.Errorf("Expected every asset without digests, got %d, %v", len(kept), err)
+	}
+}
@@ -0,0 +1,94 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// repositoryAccess is the outcome of checking whether the token can read a
+// repository
+type repositoryAccess struct {
+	Repository string
+	Private    bool
+	Err        error
+}
+
+// preflight checks that the token can read every repository before a long
+// --stdin run starts, printing a line per repository, and fails listing the
+// ones it cannot read instead of erroring midway through the run
+func preflight(repos []string) error {
+	clients := make(map[string]github.HTTPClient)
+	clientFor := func(host string) (github.HTTPClient, error) {
+		if client, ok := clients[host]; ok {
+			return client, nil
+		}
+		client, err := newRESTClient(api.ClientOptions{Host: host})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitHub client for %s: %w", host, err)
+		}
+		clients[host] = client
+		return client, nil
+	}
+
+	fmt.Printf("Checking access to %d repositories:\n", len(repos))
+	var denied []string
+	for _, check := range checkRepositoryAccess(clientFor, repos) {
+		switch {
+		case check.Err != nil:
+			fmt.Printf("  %-8s %s: %v\n", "denied", check.Repository, check.Err)
+			denied = append(denied, check.Repository)
+		case check.Private:
+			fmt.Printf("  %-8s %s (private)\n", "ok", check.Repository)
+		default:
+			fmt.Printf("  %-8s %s\n", "ok", check.Repository)
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("the token cannot read %d of %d repositories", len(denied), len(repos))
+	}
+	return nil
+}
+
+// checkRepositoryAccess reads each repository with the client of its host.
+// GitHub answers 404 rather than 403 for private repositories the token
+// cannot see, so both are reported as missing access.
+func checkRepositoryAccess(clientFor func(host string) (github.HTTPClient, error), repos []string) []repositoryAccess {
+	checks := make([]repositoryAccess, 0, len(repos))
+	for _, repo := range repos {
+		check := repositoryAccess{Repository: repo}
+		check.Private, check.Err = readRepository(clientFor, repo)
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func readRepository(clientFor func(host string) (github.HTTPClient, error), repo string) (bool, error) {
+	parsed, err := repository.Parse(repo)
+	if err != nil {
+		return false, fmt.Errorf("invalid repository format: %w", err)
+	}
+	client, err := clientFor(parsed.Host)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := github.GetRepository(client, parsed.Owner+"/"+parsed.Name)
+	var httpErr *api.HTTPError
+	switch {
+	case github.SSOAuthorizationURL(err) != "":
+		return false, fmt.Errorf("the token is not authorized for single sign-on; authorize it at %s", github.SSOAuthorizationURL(err))
+	case errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusNotFound || httpErr.StatusCode == http.StatusForbidden):
+		return false, fmt.Errorf("not found or no access with this token (HTTP %d)", httpErr.StatusCode)
+	case err != nil:
+		return false, err
+	case found.Permissions != nil && !found.Permissions.Pull:
+		return found.Private, fmt.Errorf("the token has no read permission")
+	}
+	return found.Private, nil
+}
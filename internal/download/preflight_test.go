@@ -0,0 +1,68 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// accessClient answers repository requests with canned JSON, and with a
+// 404 for repositories it does not know
+type accessClient map[string]string
+
+func (c accessClient) Get(endpoint string, response interface{}) error {
+	if endpoint == "repos/sso/repo" {
+		headers := http.Header{}
+		headers.Set("X-GitHub-SSO", "required; url=https://github.com/orgs/sso/sso?authorization_request=abc")
+		return &api.HTTPError{StatusCode: 403, Headers: headers}
+	}
+	if _, ok := c[endpoint]; !ok {
+		return &api.HTTPError{StatusCode: 404, Message: "Not Found"}
+	}
+	return jsonClient(c).Get(endpoint, response)
+}
+
+func TestCheckRepositoryAccess(t *testing.T) {
+	var hosts []string
+	clientFor := func(host string) (github.HTTPClient, error) {
+		hosts = append(hosts, host)
+		if host == "broken.example.com" {
+			return nil, fmt.Errorf("no token for %s", host)
+		}
+		return accessClient{
+			"repos/owner/public":  `{"full_name":"owner/public","permissions":{"pull":true}}`,
+			"repos/owner/private": `{"full_name":"owner/private","private":true,"permissions":{"pull":true}}`,
+			"repos/owner/blocked": `{"full_name":"owner/blocked","private":true,"permissions":{"pull":false}}`,
+		}, nil
+	}
+
+	checks := checkRepositoryAccess(clientFor, []string{"owner/public", "owner/private", "owner/blocked", "owner/missing", "broken.example.com/owner/repo", "sso/repo"})
+	if len(checks) != 6 {
+		t.Fatalf("Expected 6 checks, got %d", len(checks))
+	}
+	if checks[0].Err != nil || checks[0].Private {
+		t.Errorf("Expected public access, got %+v", checks[0])
+	}
+	if checks[1].Err != nil || !checks[1].Private {
+		t.Errorf("Expected private access, got %+v", checks[1])
+	}
+	if checks[2].Err == nil || !strings.Contains(checks[2].Err.Error(), "no read permission") {
+		t.Errorf("Expected missing permission, got %+v", checks[2])
+	}
+	if checks[3].Err == nil || !strings.Contains(checks[3].Err.Error(), "HTTP 404") {
+		t.Errorf("Expected missing access, got %+v", checks[3])
+	}
+	if checks[4].Err == nil {
+		t.Errorf("Expected the client error, got %+v", checks[4])
+	}
+	if checks[5].Err == nil || !strings.Contains(checks[5].Err.Error(), "https://github.com/orgs/sso/sso?authorization_request=abc") {
+		t.Errorf("Expected the SSO authorization URL, got %+v", checks[5])
+	}
+	if hosts[4] != "broken.example.com" {
+		t.Errorf("Expected the repository host to be used, got %v", hosts)
+	}
+}
@@ -0,0 +1,89 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
+)
+
+// provenanceName is the file --write-provenance drops in the target
+// directory
+const provenanceName = ".gh-download.json"
+
+// provenanceFile is a file listed in the provenance record, by its path
+// relative to the target directory
+type provenanceFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// provenance records how a directory was populated, so anyone finding it
+// later knows how to reproduce it
+type provenance struct {
+	Tool       string           `json:"tool"`
+	Version    string           `json:"version"`
+	Time       string           `json:"time"`
+	Command    []string         `json:"command"`
+	Repository string           `json:"repository"`
+	Release    *ResolvedRelease `json:"release,omitempty"`
+	Commit     string           `json:"commit,omitempty"`
+	Files      []provenanceFile `json:"files"`
+}
+
+// writeProvenance writes the provenance record of a run to the target
+// directory with --write-provenance, replacing the one of an earlier run
+func writeProvenance(cfg config.Config, result runResult, args []string, now time.Time) error {
+	record := provenance{
+		Tool:       "gh-download",
+		Version:    toolVersion(),
+		Time:       now.UTC().Format(time.RFC3339),
+		Command:    append([]string{"gh", "download"}, args...),
+		Repository: cfg.Repository,
+		Release:    result.Resolved,
+		Commit:     result.Commit,
+		Files:      []provenanceFile{},
+	}
+
+	paths := slices.Clone(result.Paths)
+	slices.Sort(paths)
+	for _, path := range slices.Compact(paths) {
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		name := path
+		if rel, err := filepath.Rel(cfg.Directory, path); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+		record.Files = append(record.Files, provenanceFile{Path: name, SHA256: digest})
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	root, err := confine.Open(cfg.Directory)
+	if err != nil {
+		return err
+	}
+	defer closeRoot(root)
+	if err := root.WriteFile(filepath.Join(cfg.Directory, provenanceName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+	return nil
+}
+
+// toolVersion returns the version of the module gh-download was built from,
+// "(devel)" for local builds
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,52 @@
+package download
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestWriteProvenance(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "app.zip")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := newResolvedRelease(&github.Release{TagName: "v1.0.0"}, "", "")
+	cfg := config.Config{Repository: "owner/repo", Directory: dir}
+	result := runResult{Tag: "v1.0.0", Commit: "abc123", Paths: []string{path, path}, Resolved: &resolved}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := writeProvenance(cfg, result, []string{"owner/repo", "--write-provenance"}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, provenanceName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got provenance
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tool != "gh-download" || got.Version == "" || got.Time != "2025-03-01T12:00:00Z" {
+		t.Errorf("Unexpected tool, version or time in %+v", got)
+	}
+	if len(got.Command) != 4 || got.Command[0] != "gh" || got.Command[3] != "--write-provenance" {
+		t.Errorf("Unexpected command %v", got.Command)
+	}
+	if got.Release == nil || got.Release.Method != ResolvedLatest || got.Commit != "abc123" {
+		t.Errorf("Unexpected release %+v and commit %s", got.Release, got.Commit)
+	}
+	if len(got.Files) != 1 || got.Files[0].Path != "sub/app.zip" || got.Files[0].SHA256 != sha256Hex("content") {
+		t.Errorf("Unexpected files %+v", got.Files)
+	}
+}
@@ -0,0 +1,92 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/output"
+	"github.com/23prime/gh-download/internal/render"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// outputRenderer returns the renderer of --format and --template, JSON with
+// --json, or nil when commands print their usual text
+func outputRenderer(cfg config.Config) (*render.Renderer, error) {
+	switch {
+	case cfg.Format == "" && cfg.Template != "":
+		return render.New(render.FormatTemplate, cfg.Template)
+	case cfg.Format == "" && cfg.JSON:
+		return render.New(render.FormatJSON, "")
+	case cfg.Format == "":
+		return nil, nil
+	case cfg.JSON && (cfg.Format == render.FormatTable || cfg.Format == render.FormatCSV || cfg.Format == render.FormatTSV):
+		return nil, fmt.Errorf("--json cannot be used with --format %s", cfg.Format)
+	}
+	return render.New(cfg.Format, cfg.Template)
+}
+
+// renderRecords prints the records of a table with renderer, reduced to the
+// fields of --json when given. record is a zero record, whose fields are
+// listed when --json names none or an unknown one.
+func renderRecords(cfg config.Config, renderer *render.Renderer, t render.Table, record any) error {
+	if cfg.JSON {
+		selector, err := output.NewSelector(record, cfg.JSONFields)
+		if err != nil {
+			return err
+		}
+		if t.Values, err = selector.SelectAll(t.Values); err != nil {
+			return err
+		}
+	}
+	return renderer.Render(os.Stdout, t)
+}
+
+func assetTable(assets []github.Asset) render.Table {
+	t := render.Table{Columns: []string{"name", "size", "content_type", "download_count", "updated_at"}}
+	for _, asset := range assets {
+		t.Add(asset, asset.Name, strconv.Itoa(asset.Size), asset.ContentType, strconv.Itoa(asset.DownloadCount), asset.UpdatedAt)
+	}
+	return t
+}
+
+func releaseTable(releases []github.Release) render.Table {
+	t := render.Table{Columns: []string{"tag_name", "name", "status", "published_at", "author", "assets"}}
+	for _, release := range releases {
+		var status []string
+		if release.Draft {
+			status = append(status, "draft")
+		}
+		if release.Prerelease {
+			status = append(status, "prerelease")
+		}
+		t.Add(release, release.TagName, release.Name, strings.Join(status, ","), release.PublishedAt, release.Author.Login, strconv.Itoa(len(release.Assets)))
+	}
+	return t
+}
+
+func assetChangeTable(changes []github.AssetChange) render.Table {
+	t := render.Table{Columns: []string{"kind", "key", "base", "head", "base_size", "head_size"}}
+	for _, change := range changes {
+		var base, head, baseSize, headSize string
+		if change.Base != nil {
+			base, baseSize = change.Base.Name, strconv.Itoa(change.Base.Size)
+		}
+		if change.Head != nil {
+			head, headSize = change.Head.Name, strconv.Itoa(change.Head.Size)
+		}
+		t.Add(change, change.Kind, change.Key, base, head, baseSize, headSize)
+	}
+	return t
+}
+
+func historyTable(entries []state.HistoryEntry) render.Table {
+	t := render.Table{Columns: []string{"time", "repository", "tag", "commit", "directory", "files"}}
+	for _, entry := range entries {
+		t.Add(entry, entry.Time.UTC().Format("2006-01-02T15:04:05Z"), historyRepository(entry), entry.Tag, entry.Commit, entry.Directory, strconv.Itoa(len(entry.Paths)))
+	}
+	return t
+}
@@ -0,0 +1,91 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+func TestOutputRenderer(t *testing.T) {
+	renderer, err := outputRenderer(config.Config{})
+	if err != nil || renderer != nil {
+		t.Errorf("Expected no renderer without --format, got %v (%v)", renderer, err)
+	}
+	if renderer, err := outputRenderer(config.Config{Template: "{{len .}}"}); err != nil || renderer == nil {
+		t.Errorf("Expected --template to select the template format, got %v", err)
+	}
+	if _, err := outputRenderer(config.Config{Format: "xml"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestAssetChangeTable(t *testing.T) {
+	changes := github.DiffAssets(
+		[]github.Asset{{Name: "app-1.0.0.tar.gz", Size: 10}, {Name: "old.txt", Size: 1}},
+		[]github.Asset{{Name: "app-1.1.0.tar.gz", Size: 12}, {Name: "new.txt", Size: 2}},
+		"v1.0.0", "v1.1.0",
+	)
+	table := assetChangeTable(changes)
+
+	expected := [][]string{
+		{"changed", "app-{version}.tar.gz", "app-1.0.0.tar.gz", "app-1.1.0.tar.gz", "10", "12"},
+		{"added", "new.txt", "", "new.txt", "", "2"},
+		{"removed", "old.txt", "old.txt", "", "1", ""},
+	}
+	if len(table.Rows) != len(expected) || len(table.Values) != len(expected) {
+		t.Fatalf("Expected %d rows, got %v", len(expected), table.Rows)
+	}
+	for i, row := range expected {
+		if strings.Join(table.Rows[i], "|") != strings.Join(row, "|") {
+			t.Errorf("Expected row %v, got %v", row, table.Rows[i])
+		}
+	}
+}
+
+func TestHistory_Format(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	if err := state.AppendHistory(state.HistoryPath(), state.HistoryEntry{Repository: "owner/repo", Tag: "v1.0.0", Directory: "/mirror", Paths: []string{"/mirror/app"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := History(config.Config{Format: "csv"}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	expected := "time,repository,tag,commit,directory,files\n0001-01-01T00:00:00Z,owner/repo,v1.0.0,,/mirror,1\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	if err := History(config.Config{Format: "template"}); err == nil {
+		t.Error("Expected an error for --format template without --template")
+	}
+}
+
+func TestHistory_JSONFields(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	if err := state.AppendHistory(state.HistoryPath(), state.HistoryEntry{Repository: "owner/repo", Tag: "v1.0.0", Paths: []string{"/mirror/app"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := History(config.Config{JSON: true, JSONFields: []string{"tag", "paths"}, Format: "ndjson"}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	expected := `{"paths":["/mirror/app"],"tag":"v1.0.0"}` + "\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	if err := History(config.Config{JSON: true, JSONFields: []string{"tagName"}}); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+	if err := History(config.Config{JSON: true, JSONFields: []string{"tag"}, Format: "csv"}); err == nil {
+		t.Error("Expected an error for --json with --format csv")
+	}
+}
@@ -0,0 +1,84 @@
+package download
+
+import (
+	"fmt"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// How a release was chosen, the Method of a ResolvedRelease
+const (
+	// ResolvedLatest is the release GitHub reports as the latest
+	ResolvedLatest = "latest"
+	// ResolvedTag is the release of the tag given with --tag
+	ResolvedTag = "tag"
+	// ResolvedFiltered is the newest stable release matching --min-reactions
+	// and --author
+	ResolvedFiltered = "filtered"
+	// ResolvedResume is the release of the tag recorded in a --resume token
+	ResolvedResume = "resume"
+	// ResolvedStrategy is the release chosen by --strategy
+	ResolvedStrategy = "strategy"
+)
+
+// ResolvedRelease describes which release a run chose and why, so that users
+// and scripts can tell exactly which tag "latest" resolved to
+type ResolvedRelease struct {
+	Tag    string `json:"tag"`
+	Method string `json:"method"`
+	// Requested is the tag asked for, empty unless Method is ResolvedTag or
+	// ResolvedResume
+	Requested string `json:"requested,omitempty"`
+	// Filter describes the release filters with ResolvedFiltered and
+	// ResolvedStrategy
+	Filter string `json:"filter,omitempty"`
+	// Strategy is the --strategy with ResolvedStrategy
+	Strategy   string `json:"strategy,omitempty"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// newResolvedRelease describes release as resolved from the requested tag,
+// or from filter when no tag was requested and filter is not empty
+func newResolvedRelease(release *github.Release, tag, filter string) ResolvedRelease {
+	resolved := ResolvedRelease{
+		Tag:        release.TagName,
+		Method:     ResolvedLatest,
+		Draft:      release.Draft,
+		Prerelease: release.Prerelease,
+	}
+	switch {
+	case tag != "":
+		resolved.Method = ResolvedTag
+		resolved.Requested = tag
+	case filter != "":
+		resolved.Method = ResolvedFiltered
+		resolved.Filter = filter
+	}
+	return resolved
+}
+
+// String describes the resolution as in "latest: v1.2.3"
+func (r ResolvedRelease) String() string {
+	var s string
+	switch r.Method {
+	case ResolvedFiltered:
+		s = fmt.Sprintf("newest stable release matching %s: %s", r.Filter, r.Tag)
+	case ResolvedStrategy:
+		s = fmt.Sprintf("%s: %s", r.Strategy, r.Tag)
+		if r.Filter != "" {
+			s = fmt.Sprintf("%s matching %s: %s", r.Strategy, r.Filter, r.Tag)
+		}
+	case ResolvedResume:
+		s = fmt.Sprintf("tag: %s, resumed", r.Tag)
+	default:
+		s = fmt.Sprintf("%s: %s", r.Method, r.Tag)
+	}
+	if r.Draft {
+		s += ", draft"
+	}
+	if r.Prerelease {
+		s += ", prerelease"
+	}
+	return s
+}
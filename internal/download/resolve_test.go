@@ -0,0 +1,42 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestNewResolvedRelease(t *testing.T) {
+	release := &github.Release{TagName: "v1.2.3"}
+	prerelease := &github.Release{TagName: "v2.0.0-rc.1", Prerelease: true}
+
+	tests := []struct {
+		name     string
+		resolved ResolvedRelease
+		method   string
+		want     string
+	}{
+		{"latest", newResolvedRelease(release, "", ""), ResolvedLatest, "latest: v1.2.3"},
+		{"tag", newResolvedRelease(release, "v1.2.3", ""), ResolvedTag, "tag: v1.2.3"},
+		{"tag wins over filter", newResolvedRelease(release, "v1.2.3", "--author alice"), ResolvedTag, "tag: v1.2.3"},
+		{"filtered", newResolvedRelease(release, "", "--min-reactions 5"), ResolvedFiltered, "newest stable release matching --min-reactions 5: v1.2.3"},
+		{"prerelease", newResolvedRelease(prerelease, "v2.0.0-rc.1", ""), ResolvedTag, "tag: v2.0.0-rc.1, prerelease"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.resolved.Method != tt.method {
+				t.Errorf("Expected method %s, got %s", tt.method, tt.resolved.Method)
+			}
+			if got := tt.resolved.String(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	resumed := newResolvedRelease(release, "v1.2.3", "")
+	resumed.Method = ResolvedResume
+	if got := resumed.String(); got != "tag: v1.2.3, resumed" {
+		t.Errorf("Expected resumed description, got %q", got)
+	}
+}
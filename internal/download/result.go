@@ -0,0 +1,460 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/23prime/gh-download/internal/cas"
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/progress"
+	"github.com/23prime/gh-download/internal/retry"
+)
+
+// Exit codes. Wrappers may depend on these values.
+const (
+	// ExitOK means every asset succeeded
+	ExitOK = 0
+	// ExitError is any failure outside the contract below, such as an
+	// unknown release or an aborted run, and a --check finding files to
+	// change
+	ExitError = 1
+	// ExitUsage means the command line could not be parsed (see
+	// config.ParseArgs)
+	ExitUsage = 2
+	// ExitPartialFailure means some assets failed with --continue-on-error,
+	// or were abandoned after --asset-timeout
+	ExitPartialFailure = 10
+	// ExitAllFailed means every asset failed with --continue-on-error
+	ExitAllFailed = 11
+	// ExitVerificationFailed means the only failures were verification
+	// failures; every asset was downloaded
+	ExitVerificationFailed = 12
+	// ExitBudgetExhausted means --max-duration ran out before every download
+	// was started
+	ExitBudgetExhausted = 13
+)
+
+// VerificationError marks a failure to verify a downloaded asset, as opposed
+// to a failure to download it
+type VerificationError struct {
+	Err error
+}
+
+func (e *VerificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// errAbandoned marks an asset given up on after --asset-timeout. It fails
+// the asset without stopping the run, even without --continue-on-error, so
+// that one slow asset does not hold up the others.
+var errAbandoned = errors.New("abandoned")
+
+// AssetFailure is an asset that failed during a run
+type AssetFailure struct {
+	Name string
+	Err  error
+}
+
+// DownloadError reports the assets that failed during a run with
+// --continue-on-error
+type DownloadError struct {
+	Total    int
+	Failures []AssetFailure
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("%d of %d assets failed", len(e.Failures), e.Total)
+}
+
+// ExitCode maps the outcome of the run to the exit code contract
+func (e *DownloadError) ExitCode() int {
+	verificationOnly := true
+	for _, failure := range e.Failures {
+		var verr *VerificationError
+		if !errors.As(failure.Err, &verr) {
+			verificationOnly = false
+		}
+	}
+
+	switch {
+	case len(e.Failures) == 0:
+		return ExitOK
+	case verificationOnly:
+		return ExitVerificationFailed
+	case len(e.Failures) == e.Total:
+		return ExitAllFailed
+	default:
+		return ExitPartialFailure
+	}
+}
+
+// ExitCode returns the process exit code for the error returned by a command
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var derr *DownloadError
+	if errors.As(err, &derr) {
+		return derr.ExitCode()
+	}
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		return ExitVerificationFailed
+	}
+	var berr *BudgetError
+	if errors.As(err, &berr) {
+		return ExitBudgetExhausted
+	}
+	return ExitError
+}
+
+// SSOAuthorizationURL returns the URL where the token must be authorized for
+// the single sign-on of an organization when err, or any asset failure of a
+// run, was refused for that reason, or an empty string
+func SSOAuthorizationURL(err error) string {
+	var derr *DownloadError
+	if errors.As(err, &derr) {
+		for _, failure := range derr.Failures {
+			if url := github.SSOAuthorizationURL(failure.Err); url != "" {
+				return url
+			}
+		}
+	}
+	return github.SSOAuthorizationURL(err)
+}
+
+// assetRun tracks the assets of one run. Without continueOnError the first
+// failure aborts the run; otherwise failures are collected and reported
+// together once every asset was attempted. It also records the paths written
+// and whether any content changed. With a concurrency above one, assets are
+// processed by that many workers at once.
+type assetRun struct {
+	// mu guards the results below and keeps output lines whole while
+	// workers run concurrently
+	mu              sync.Mutex
+	concurrency     int
+	continueOnError bool
+	total           int
+	failures        []AssetFailure
+	// order holds the names of the assets handed to each, in input order,
+	// and written the paths written for each of them; results reports the
+	// paths in that order however the workers finish
+	order   []string
+	written map[string][]string
+	// assets are the names of the assets the files of the results were
+	// saved from, by path, for the files that are an asset as downloaded
+	assets  map[string]string
+	changed bool
+	// skipUnchanged skips assets whose file already has the digest GitHub
+	// reports, and tracks changes by comparing digests
+	skipUnchanged bool
+	// existing is what to do with assets whose file already exists
+	existing existingPolicy
+	// downloader transfers assets instead of the built-in client when set
+	downloader *externalDownloader
+	// policy retries failed transfers
+	policy retry.Policy
+	// assetTimeout abandons the transfer of an asset taking longer, with
+	// --asset-timeout
+	assetTimeout time.Duration
+	// deadline stops the run from starting new assets once passed; the
+	// names of the assets not started are collected in undone
+	deadline time.Time
+	undone   []string
+	// checksums verify downloaded assets when set
+	checksums *checksumSet
+	// pinned is the digest the only asset must have, with --checksum
+	pinned *checksum.Entry
+	// sidecar is the algorithm of the checksum files written next to
+	// downloaded assets, or empty to write none
+	sidecar checksum.Algorithm
+	// verifyDigest verifies downloaded assets against the digests GitHub
+	// reports, for the assets it reports one for
+	verifyDigest bool
+	// signatures verifies downloaded assets against their signature assets
+	// with --verify-signature
+	signatures *signatureVerifier
+	// cosign verifies downloaded assets against their sigstore signing
+	// material with --cosign
+	cosign *cosignVerifier
+	// provenance verifies that downloaded assets are subjects of the
+	// provenance of the release with --verify-provenance
+	provenance *provenanceSet
+	// headCheck asks for the headers of each asset before its transfer
+	headCheck bool
+	// fileNames overrides the file names of assets by ID, for runs over a
+	// subset of the assets whose names depend on the whole release
+	fileNames map[int]string
+	// objects serves assets with a known digest from the content-addressable
+	// cache and stores the ones downloaded, with --cache
+	objects *cas.Store
+	// display draws progress bars when stdout is a terminal; begin then
+	// prints nothing and done prints the whole line, as the bars sit below
+	display *progress.Display
+	actions map[string]string
+}
+
+func newAssetRun(total int, continueOnError bool) *assetRun {
+	return &assetRun{continueOnError: continueOnError, total: total}
+}
+
+// each calls fn for every asset, returning an error only when the run is
+// aborted
+func (r *assetRun) each(assets []github.Asset, fn func(github.Asset) error) error {
+	r.mu.Lock()
+	for _, asset := range assets {
+		r.track(asset.Name)
+	}
+	r.mu.Unlock()
+
+	if r.concurrency > 1 {
+		return r.eachConcurrently(assets, fn)
+	}
+
+	for i, asset := range assets {
+		if expired(r.deadline) {
+			r.skip(assets[i:])
+			return nil
+		}
+		if err := fn(asset); err != nil {
+			if !r.continueOnError && !errors.Is(err, errAbandoned) {
+				return err
+			}
+			r.fail(asset.Name, err)
+		}
+	}
+	return nil
+}
+
+// eachConcurrently is each with r.concurrency workers. Assets are started in
+// order; after a failure without continueOnError no more are started, and
+// the first failure is returned once the running ones are done.
+func (r *assetRun) eachConcurrently(assets []github.Asset, fn func(github.Asset) error) error {
+	var abort error
+	aborted := func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return abort != nil
+	}
+
+	jobs := make(chan github.Asset)
+	var wg sync.WaitGroup
+	for range min(r.concurrency, len(assets)) {
+		wg.Go(func() {
+			for asset := range jobs {
+				err := fn(asset)
+				switch {
+				case err == nil:
+				case r.continueOnError, errors.Is(err, errAbandoned):
+					r.fail(asset.Name, err)
+				default:
+					r.mu.Lock()
+					if abort == nil {
+						abort = err
+					}
+					r.mu.Unlock()
+				}
+			}
+		})
+	}
+
+	for i, asset := range assets {
+		if aborted() {
+			break
+		}
+		if expired(r.deadline) {
+			r.skip(assets[i:])
+			break
+		}
+		jobs <- asset
+	}
+	close(jobs)
+	wg.Wait()
+	return abort
+}
+
+// fail records the failure of an asset with continueOnError
+func (r *assetRun) fail(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.display != nil {
+		r.display.Println(os.Stdout, fmt.Sprintf("%s %s... failed", r.actions[name], name))
+		r.display.Println(os.Stderr, fmt.Sprintf("Error: %v", err))
+		r.failures = append(r.failures, AssetFailure{Name: name, Err: err})
+		return
+	}
+	if r.concurrency > 1 {
+		fmt.Printf("%s: failed\n", name)
+	} else {
+		fmt.Printf("failed\n")
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	r.failures = append(r.failures, AssetFailure{Name: name, Err: err})
+}
+
+// skip records assets not started before the deadline
+func (r *assetRun) skip(assets []github.Asset) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, asset := range assets {
+		r.undone = append(r.undone, asset.Name)
+	}
+}
+
+// begin reports that work on an asset starts, as in "Downloading x...";
+// done then completes the line. Concurrent runs print whole lines instead,
+// naming the asset again in done.
+func (r *assetRun) begin(action, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.display != nil {
+		if r.actions == nil {
+			r.actions = make(map[string]string)
+		}
+		r.actions[name] = action
+		return
+	}
+	if r.concurrency > 1 {
+		fmt.Printf("%s %s...\n", action, name)
+	} else {
+		fmt.Printf("%s %s... ", action, name)
+	}
+}
+
+// done reports the outcome of work on an asset announced with begin
+func (r *assetRun) done(name, format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.display != nil {
+		r.display.Println(os.Stdout, fmt.Sprintf("%s %s... %s", r.actions[name], name, fmt.Sprintf(format, args...)))
+		return
+	}
+	if r.concurrency > 1 {
+		fmt.Printf("%s: %s\n", name, fmt.Sprintf(format, args...))
+	} else {
+		fmt.Printf(format+"\n", args...)
+	}
+}
+
+// noteWriter writes the lines of the log package through note while the
+// progress bars are drawn
+type noteWriter struct {
+	run *assetRun
+}
+
+func (w noteWriter) Write(p []byte) (int, error) {
+	w.run.note(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// note prints a whole line of detail to stderr, above the progress bars
+func (r *assetRun) note(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.display != nil {
+		r.display.Println(os.Stderr, line)
+		return
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
+
+// abandonOnTimeout turns the end of the --asset-timeout of an asset, during
+// fn, into errAbandoned. The cause is kept as text only, so that the retry
+// policy does not take it for a network failure and try again.
+func (r *assetRun) abandonOnTimeout(fn func() error) func() error {
+	return func() error {
+		err := fn()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w after --asset-timeout %s (%v)", errAbandoned, r.assetTimeout, err)
+		}
+		return err
+	}
+}
+
+// startBar adds a progress bar for the transfer of an asset, or returns nil
+// without a display
+func (r *assetRun) startBar(asset github.Asset) *progress.Bar {
+	if r.display == nil {
+		return nil
+	}
+	return r.display.Start(asset.Name, int64(asset.Size))
+}
+
+// record adds paths the run wrote for the asset named name
+func (r *assetRun) record(name string, paths ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.add(name, paths...)
+}
+
+// recordAsset records the file an asset was saved to
+func (r *assetRun) recordAsset(path, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.add(name, path)
+	if r.assets == nil {
+		r.assets = make(map[string]string)
+	}
+	r.assets[path] = name
+}
+
+// add records paths for an asset; r.mu must be held
+func (r *assetRun) add(name string, paths ...string) {
+	if r.written == nil {
+		r.written = make(map[string][]string)
+	}
+	r.track(name)
+	r.written[name] = append(r.written[name], paths...)
+}
+
+// track adds an asset to the order of the results unless it is there
+// already; r.mu must be held
+func (r *assetRun) track(name string) {
+	if !slices.Contains(r.order, name) {
+		r.order = append(r.order, name)
+	}
+}
+
+// results returns the paths written by the run, by the order of their
+// assets in the run rather than the order the transfers finished in
+func (r *assetRun) results() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var paths []string
+	for _, name := range r.order {
+		paths = append(paths, r.written[name]...)
+	}
+	return paths
+}
+
+// markChanged records that the run changed content
+func (r *assetRun) markChanged() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changed = true
+}
+
+// err returns the collected failures as a *DownloadError, or nil
+func (r *assetRun) err() error {
+	if len(r.failures) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "\n%d of %d assets failed:\n", len(r.failures), r.total)
+	for _, failure := range r.failures {
+		fmt.Fprintf(os.Stderr, "  - %s: %v\n", failure.Name, failure.Err)
+	}
+	return &DownloadError{Total: r.total, Failures: r.failures}
+}
@@ -0,0 +1,252 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+func TestExitCode(t *testing.T) {
+	downloadErr := errors.New("connection reset")
+	verifyErr := &VerificationError{Err: errors.New("checksum mismatch")}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"success", nil, ExitOK},
+		{"generic", errors.New("release not found"), ExitError},
+		{"some failed", &DownloadError{Total: 3, Failures: []AssetFailure{{Name: "a", Err: downloadErr}}}, ExitPartialFailure},
+		{"all failed", &DownloadError{Total: 2, Failures: []AssetFailure{{Name: "a", Err: downloadErr}, {Name: "b", Err: verifyErr}}}, ExitAllFailed},
+		{"verification only", &DownloadError{Total: 2, Failures: []AssetFailure{{Name: "a", Err: fmt.Errorf("a: %w", verifyErr)}}}, ExitVerificationFailed},
+		{"all verification", &DownloadError{Total: 1, Failures: []AssetFailure{{Name: "a", Err: verifyErr}}}, ExitVerificationFailed},
+		{"verification aborted", verifyErr, ExitVerificationFailed},
+		{"wrapped", fmt.Errorf("run: %w", &DownloadError{Total: 2, Failures: []AssetFailure{{Name: "a", Err: downloadErr}}}), ExitPartialFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExitCode(tc.err); got != tc.expected {
+				t.Errorf("Expected exit code %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSSOAuthorizationURL(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("X-GitHub-SSO", "required; url=https://github.com/orgs/acme/sso?authorization_request=abc")
+	ssoErr := fmt.Errorf("failed to download a: %w", &api.HTTPError{StatusCode: 403, Headers: headers})
+
+	if got := SSOAuthorizationURL(fmt.Errorf("failed to get release: %w", ssoErr)); got != "https://github.com/orgs/acme/sso?authorization_request=abc" {
+		t.Errorf("Expected the authorization URL, got %q", got)
+	}
+	derr := &DownloadError{Total: 2, Failures: []AssetFailure{{Name: "a", Err: errors.New("connection reset")}, {Name: "b", Err: ssoErr}}}
+	if got := SSOAuthorizationURL(derr); got == "" {
+		t.Errorf("Expected the authorization URL of a failed asset, got none")
+	}
+	if got := SSOAuthorizationURL(errors.New("release not found")); got != "" {
+		t.Errorf("Expected no authorization URL, got %q", got)
+	}
+}
+
+func TestAssetRun(t *testing.T) {
+	assets := []github.Asset{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	fail := func(asset github.Asset) error {
+		if asset.Name == "b" {
+			return errors.New("failed")
+		}
+		return nil
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		run := newAssetRun(len(assets), false)
+		var attempted []string
+		err := run.each(assets, func(asset github.Asset) error {
+			attempted = append(attempted, asset.Name)
+			return fail(asset)
+		})
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if len(attempted) != 2 {
+			t.Errorf("Expected the run to stop after the failure, attempted %v", attempted)
+		}
+	})
+
+	t.Run("continue", func(t *testing.T) {
+		run := newAssetRun(len(assets), true)
+		var attempted []string
+		err := run.each(assets, func(asset github.Asset) error {
+			attempted = append(attempted, asset.Name)
+			return fail(asset)
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(attempted) != 3 {
+			t.Errorf("Expected every asset to be attempted, attempted %v", attempted)
+		}
+
+		if got := ExitCode(run.err()); got != ExitPartialFailure {
+			t.Errorf("Expected exit code %d, got %d", ExitPartialFailure, got)
+		}
+	})
+}
+
+func TestAssetRun_AssetTimeout(t *testing.T) {
+	assets := []github.Asset{{Name: "a"}, {Name: "slow"}, {Name: "c"}}
+	run := newAssetRun(len(assets), false)
+	run.assetTimeout = 10 * time.Minute
+	policy := retry.Policy{Retries: 2, Classes: map[retry.Class]bool{retry.Network: true}}
+	policy.Sleep = func(time.Duration) {}
+
+	attempts := 0
+	var attempted []string
+	err := run.each(assets, func(asset github.Asset) error {
+		attempted = append(attempted, asset.Name)
+		return policy.Do(asset.Name, run.abandonOnTimeout(func() error {
+			if asset.Name != "slow" {
+				return nil
+			}
+			attempts++
+			return fmt.Errorf("failed to write slow.part: %w", context.DeadlineExceeded)
+		}))
+	})
+	if err != nil {
+		t.Fatalf("Expected an abandoned asset not to stop the run, got %v", err)
+	}
+	if len(attempted) != 3 {
+		t.Errorf("Expected every asset to be attempted, attempted %v", attempted)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected an abandoned asset not to be retried, got %d attempts", attempts)
+	}
+
+	if len(run.failures) != 1 || !errors.Is(run.failures[0].Err, errAbandoned) {
+		t.Fatalf("Expected the slow asset to be reported as abandoned, got %v", run.failures)
+	}
+	if got := run.failures[0].Err.Error(); got != "abandoned after --asset-timeout 10m0s (failed to write slow.part: context deadline exceeded)" {
+		t.Errorf("Unexpected error %q", got)
+	}
+	if got := ExitCode(run.err()); got != ExitPartialFailure {
+		t.Errorf("Expected exit code %d, got %d", ExitPartialFailure, got)
+	}
+}
+
+func TestAssetRun_Concurrent(t *testing.T) {
+	assets := []github.Asset{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+
+	t.Run("overlaps", func(t *testing.T) {
+		run := newAssetRun(len(assets), false)
+		run.concurrency = 2
+		var mu sync.Mutex
+		var running, peak int
+		release := make(chan struct{})
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		err := run.each(assets, func(asset github.Asset) error {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+			run.record(asset.Name, asset.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if peak != 2 {
+			t.Errorf("Expected 2 assets at once, got %d", peak)
+		}
+		if results := run.results(); len(results) != len(assets) {
+			t.Errorf("Expected every asset to be recorded, got %v", results)
+		}
+	})
+
+	t.Run("results in input order", func(t *testing.T) {
+		run := newAssetRun(len(assets), false)
+		run.concurrency = len(assets)
+		// The assets finish in reverse order: each waits for the one after it
+		finished := make(map[string]chan struct{})
+		for _, asset := range assets {
+			finished[asset.Name] = make(chan struct{})
+		}
+		err := run.each(assets, func(asset github.Asset) error {
+			defer close(finished[asset.Name])
+			for i, next := range assets[:len(assets)-1] {
+				if next.Name == asset.Name {
+					<-finished[assets[i+1].Name]
+				}
+			}
+			if asset.Name == "b" {
+				run.record(asset.Name, "b/1", "b/2")
+			} else {
+				run.recordAsset(asset.Name+".bin", asset.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		expected := []string{"a.bin", "b/1", "b/2", "c.bin", "d.bin"}
+		if results := run.results(); strings.Join(results, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+		if run.assets["d.bin"] != "d" {
+			t.Errorf("Expected d.bin to be recorded as asset d, got %v", run.assets)
+		}
+	})
+
+	t.Run("continue", func(t *testing.T) {
+		run := newAssetRun(len(assets), true)
+		run.concurrency = 3
+		captureStdout(t, func() {
+			if err := run.each(assets, func(asset github.Asset) error {
+				if asset.Name == "b" || asset.Name == "d" {
+					return errors.New("failed")
+				}
+				return nil
+			}); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		if len(run.failures) != 2 {
+			t.Errorf("Expected 2 failures, got %v", run.failures)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		run := newAssetRun(len(assets), false)
+		run.concurrency = 2
+		var attempted atomic.Int32
+		err := run.each(assets, func(asset github.Asset) error {
+			attempted.Add(1)
+			return errors.New("failed")
+		})
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if attempted.Load() > 3 {
+			t.Errorf("Expected no assets to be started after the failure, attempted %d", attempted.Load())
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/log"
+	"github.com/23prime/gh-download/internal/schema"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+// outputSchemas are the JSON Schemas of the machine-readable outputs by the
+// name schema takes
+var outputSchemas = map[string]func() map[string]any{
+	"json": func() map[string]any {
+		return schema.For(exportRelease{}, "gh-download export record",
+			"A release as written by export: an item of the JSON array, or a line of NDJSON")
+	},
+	"report": func() map[string]any {
+		return schema.For(resultJSON{}, "gh-download --idempotent-json result",
+			"The single object printed by --idempotent-json, also as YAML with --format yaml")
+	},
+	"events": func() map[string]any {
+		return schema.Tagged(log.Envelope{}, "event", log.Events, "gh-download --log-format json event",
+			"A line written to stderr by --log-format json")
+	},
+	"history": func() map[string]any {
+		return schema.For(state.HistoryEntry{}, "gh-download history entry",
+			"An entry of the array printed by history --json")
+	},
+}
+
+// Schema prints the JSON Schema of a machine-readable output, generated from
+// the structs that write it
+func Schema(cfg config.Config) error {
+	names := make([]string, 0, len(outputSchemas))
+	for name := range outputSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := positionalArgs(cfg)
+	if len(args) != 1 {
+		return fmt.Errorf("usage: gh download schema %s", strings.Join(names, "|"))
+	}
+	generate, ok := outputSchemas[args[0]]
+	if !ok {
+		return fmt.Errorf("no schema for %q; available: %s", args[0], strings.Join(names, ", "))
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(generate())
+}
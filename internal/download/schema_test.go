@@ -0,0 +1,103 @@
+package download
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/log"
+)
+
+func TestSchema(t *testing.T) {
+	output := captureStdout(t, func() {
+		if err := Schema(config.Config{Repository: "report"}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	var got map[string]any
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("Expected a JSON Schema, got %v:\n%s", err, output)
+	}
+
+	// Every field a report can have is described
+	resolved := newResolvedRelease(&github.Release{TagName: "v1.0.0"}, "", "--author alice")
+	var report strings.Builder
+	if err := writeResultJSON(&report, runResult{Tag: "v1.0.0", Author: "alice", Commit: "abc", TargetCommitish: "main", Reactions: 1, DiscussionURL: "https://example.com", Resolved: &resolved}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(report.String()), &fields); err != nil {
+		t.Fatal(err)
+	}
+	properties := got["properties"].(map[string]any)
+	for field := range fields {
+		if _, ok := properties[field]; !ok {
+			t.Errorf("Expected the schema to describe %q", field)
+		}
+	}
+}
+
+func TestSchema_Events(t *testing.T) {
+	output := captureStdout(t, func() {
+		if err := Schema(config.Config{Repository: "events"}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+	var got struct {
+		OneOf []struct {
+			Properties map[string]map[string]any `json:"properties"`
+		} `json:"oneOf"`
+	}
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("Expected a JSON Schema, got %v:\n%s", err, output)
+	}
+	events := make(map[string]map[string]map[string]any)
+	for _, variant := range got.OneOf {
+		events[variant.Properties["event"]["const"].(string)] = variant.Properties
+	}
+
+	// Every field a logged event has is described by the schema of its name
+	var buf bytes.Buffer
+	previous := log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(previous) })
+	if err := log.SetFormat(log.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := log.SetFormat(log.FormatText); err != nil {
+			t.Error(err)
+		}
+	})
+	log.Event(log.LevelInfo, "download_done", log.DownloadDone{Asset: "app.zip", Bytes: 3}, "")
+	log.Event(log.LevelInfo, "error", log.Failure{Error: "failed", ExitCode: 1, SSOURL: "https://example.com"}, "")
+	log.Infof("a message")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatal(err)
+		}
+		properties, ok := events[fields["event"].(string)]
+		if !ok {
+			t.Errorf("Expected a schema for %q", fields["event"])
+			continue
+		}
+		for field := range fields {
+			if _, ok := properties[field]; !ok {
+				t.Errorf("Expected the schema of %s to describe %q", fields["event"], field)
+			}
+		}
+	}
+}
+
+func TestSchema_Unknown(t *testing.T) {
+	if err := Schema(config.Config{Repository: "metrics"}); err == nil || !strings.Contains(err.Error(), "events, history, json, report") {
+		t.Errorf("Expected an error listing the schemas, got %v", err)
+	}
+	if err := Schema(config.Config{}); err == nil {
+		t.Error("Expected an error without a name")
+	}
+}
@@ -0,0 +1,505 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/23prime/gh-download/internal/access"
+	"github.com/23prime/gh-download/internal/cas"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/state"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// proxyMetadataTTL is how long the proxy reuses the metadata of a release
+// before asking the API again, so that many clients pulling the same release
+// cost one request
+const proxyMetadataTTL = 5 * time.Minute
+
+// errAssetNotFound is returned for requests naming no asset of the release
+var errAssetNotFound = errors.New("asset not found")
+
+// errFetchRunning is returned under overlapSkip for requests missing the
+// cache while another request fetches the same asset
+var errFetchRunning = errors.New("the asset is being fetched for another request")
+
+// fetchRetryAfter is the Retry-After, in seconds, of the requests skipped
+// under overlapSkip
+const fetchRetryAfter = 5
+
+// overlapPolicy is what a request missing the cache does while another
+// request fetches the same asset from GitHub
+type overlapPolicy int
+
+const (
+	// overlapQueue waits for the running fetch and serves its result
+	overlapQueue overlapPolicy = iota
+	// overlapSkip answers at once with 503 and Retry-After, so that requests
+	// do not pile up behind a fetch on a slow link
+	overlapSkip
+	// overlapRestart cancels the running fetch, such as one stalled on a
+	// slow link, and fetches the asset again; the requests waiting for the
+	// cancelled fetch wait for the new one
+	overlapRestart
+)
+
+// parseOverlapPolicy parses --overlap-policy
+func parseOverlapPolicy(s string) (overlapPolicy, error) {
+	switch s {
+	case "", "queue":
+		return overlapQueue, nil
+	case "skip":
+		return overlapSkip, nil
+	case "cancel-and-restart":
+		return overlapRestart, nil
+	default:
+		return 0, fmt.Errorf("invalid overlap policy '%s': must be queue, skip or cancel-and-restart", s)
+	}
+}
+
+// proxy is a read-through cache of release assets. Requests for
+// /<owner>/<repo>/<tag>/<asset> are answered from the content-addressable
+// cache, or fetched from GitHub with the credentials of the proxy and added
+// to it; the tag "latest" stands for the latest release.
+type proxy struct {
+	client  github.HTTPClient
+	assets  *http.Client
+	objects cas.Store
+	// log receives a line per request
+	log io.Writer
+	// policy limits which clients may pull which repositories; nil lets
+	// every client pull every repository
+	policy *access.Policy
+	// audit records every request when set
+	audit state.Store
+
+	// overlap is what a request does while another fetches the same object
+	overlap overlapPolicy
+
+	mu       sync.Mutex
+	releases map[string]cachedRelease
+	// fetching holds the running fetch of each object, so concurrent misses
+	// download it once
+	fetching map[string]*objectFetch
+}
+
+// objectFetch is a running download of an object into the cache
+type objectFetch struct {
+	cancel context.CancelFunc
+	// done is closed once the fetch ended, with err set
+	done chan struct{}
+	err  error
+}
+
+type cachedRelease struct {
+	release *github.Release
+	fetched time.Time
+}
+
+func newProxy(client github.HTTPClient, assets *http.Client, objects cas.Store, log io.Writer) *proxy {
+	return &proxy{
+		client:   client,
+		assets:   assets,
+		objects:  objects,
+		log:      log,
+		releases: make(map[string]cachedRelease),
+		fetching: make(map[string]*objectFetch),
+	}
+}
+
+// Serve runs the read-through cache on --listen until it fails, so a team or
+// CI fleet shares one rate-limit budget and downloads at LAN speed. With
+// --access-file only the clients it lists may pull, each only the
+// repositories allowed to it; every request is added to the audit log.
+// --overlap-policy decides what requests for an asset being fetched do.
+func Serve(cfg config.Config) (err error) {
+	overlap, err := parseOverlapPolicy(cfg.OverlapPolicy)
+	if err != nil {
+		return err
+	}
+
+	var policy *access.Policy
+	if cfg.AccessFile != "" {
+		if policy, err = access.Load(cfg.AccessFile); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: serving without --access-file, every client that can connect may pull anything this machine's credentials can read\n")
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	assets, err := newAssetHTTPClient()
+	if err != nil {
+		return fmt.Errorf("failed to create download client: %w", err)
+	}
+
+	store, err := state.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	objects := cas.Store{Root: cas.Dir()}
+	handler := newProxy(client, assets, objects, os.Stdout)
+	handler.policy = policy
+	handler.audit = store
+	handler.overlap = overlap
+	fmt.Printf("Serving release assets on http://%s/<owner>/<repo>/<tag>/<asset> from %s\n", cfg.Listen, objects.Root)
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	counter := &countingResponseWriter{ResponseWriter: w}
+	w = counter
+	var outcome proxyOutcome
+	var err error
+	repo, tag, name, ok := parseProxyPath(r.URL.Path)
+	if ok {
+		outcome, err = p.authorize(r, repo)
+	} else {
+		outcome, err = proxyOutcome{status: http.StatusNotFound}, fmt.Errorf("expected /<owner>/<repo>/<tag>/<asset>")
+	}
+	if err == nil {
+		outcome.status, outcome.cache, err = p.serveAsset(w, r, repo, tag, name)
+	}
+	if err != nil {
+		if outcome.status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gh-download"`)
+		}
+		http.Error(w, err.Error(), outcome.status)
+	}
+	outcome.bytes = counter.written
+	p.record(r, outcome)
+}
+
+// countingResponseWriter counts the bytes of the response body, for the
+// audit log
+type countingResponseWriter struct {
+	http.ResponseWriter
+	written int64
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// proxyOutcome is how a request was answered: its status, the client of
+// --access-file it came from, for served assets hit, miss, or bypass for
+// assets GitHub reports no digest for, and the bytes of the body sent
+type proxyOutcome struct {
+	status int
+	client string
+	cache  string
+	bytes  int64
+}
+
+// authorize checks the token of a request against --access-file, when given
+func (p *proxy) authorize(r *http.Request, repo string) (proxyOutcome, error) {
+	if p.policy == nil {
+		return proxyOutcome{}, nil
+	}
+	client, err := p.policy.Authorize(requestToken(r), repo)
+	switch {
+	case errors.Is(err, access.ErrUnauthenticated):
+		return proxyOutcome{status: http.StatusUnauthorized}, err
+	case err != nil:
+		return proxyOutcome{status: http.StatusForbidden, client: client}, err
+	}
+	return proxyOutcome{client: client}, nil
+}
+
+// requestToken returns the token of a request, sent as a bearer token or as
+// the password of basic authentication so that curl -u and .netrc work
+func requestToken(r *http.Request) string {
+	if _, password, ok := r.BasicAuth(); ok {
+		return password
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && (strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, "token")) {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// record logs a request and adds it to the audit log
+func (p *proxy) record(r *http.Request, outcome proxyOutcome) {
+	entry := state.AuditEntry{
+		Time:   time.Now().UTC(),
+		Client: outcome.client,
+		Remote: r.RemoteAddr,
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Status: outcome.status,
+		Cache:  outcome.cache,
+		Bytes:  outcome.bytes,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := fmt.Fprintf(p.log, "%s %s %s %s %d %s\n", entry.Time.Format(time.RFC3339), orDash(entry.Client), entry.Method, entry.Path, entry.Status, orDash(entry.Cache)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to log request: %v\n", err)
+	}
+	if p.audit != nil {
+		if err := p.audit.AppendAudit(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record audit entry: %v\n", err)
+		}
+	}
+}
+
+// orDash returns s, or "-" when it is empty, for log fields
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// serveAsset answers a request for an asset and returns its status, where
+// the content came from and the error to report to the client
+func (p *proxy) serveAsset(w http.ResponseWriter, r *http.Request, repo, tag, name string) (int, string, error) {
+	asset, err := p.findAsset(repo, tag, name)
+	if errors.Is(err, errAssetNotFound) {
+		return http.StatusNotFound, "", err
+	}
+	if err != nil {
+		return http.StatusBadGateway, "", err
+	}
+
+	digest := assetSHA256(asset)
+	if digest == "" {
+		return p.passThrough(w, r, asset)
+	}
+
+	source := "hit"
+	if !p.objects.Has(digest) {
+		source = "miss"
+		err := p.fetchObject(asset, digest)
+		if errors.Is(err, errFetchRunning) {
+			w.Header().Set("Retry-After", strconv.Itoa(fetchRetryAfter))
+			return http.StatusServiceUnavailable, source, err
+		}
+		if err != nil {
+			return http.StatusBadGateway, source, err
+		}
+	}
+
+	file, err := os.Open(p.objects.Path(digest))
+	if err != nil {
+		return http.StatusInternalServerError, source, fmt.Errorf("failed to open cached %s", asset.Name)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", file.Name(), closeErr)
+		}
+	}()
+
+	setAssetHeaders(w, asset)
+	w.Header().Set("X-Cache", strings.ToUpper(source))
+	modified, err := time.Parse(time.RFC3339, asset.UpdatedAt)
+	if err != nil {
+		modified = time.Time{}
+	}
+	http.ServeContent(w, r, asset.Name, modified, file)
+	return http.StatusOK, source, nil
+}
+
+// parseProxyPath splits /<owner>/<repo>/<tag>/<asset> into the repository,
+// the tag (empty for "latest") and the asset name
+func parseProxyPath(path string) (repo, tag, name string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) != 4 {
+		return "", "", "", false
+	}
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return "", "", "", false
+		}
+	}
+	tag = parts[2]
+	if tag == "latest" {
+		tag = ""
+	}
+	return parts[0] + "/" + parts[1], tag, parts[3], true
+}
+
+// findAsset returns the asset called name in the release, reusing metadata
+// younger than proxyMetadataTTL
+func (p *proxy) findAsset(repo, tag, name string) (github.Asset, error) {
+	key := repo + "@" + tag
+	p.mu.Lock()
+	cached, ok := p.releases[key]
+	p.mu.Unlock()
+
+	if !ok || time.Since(cached.fetched) > proxyMetadataTTL {
+		release, err := github.GetRelease(p.client, repo, tag)
+		if err != nil {
+			return github.Asset{}, fmt.Errorf("failed to get release: %w", err)
+		}
+		cached = cachedRelease{release: release, fetched: time.Now()}
+		p.mu.Lock()
+		p.releases[key] = cached
+		p.mu.Unlock()
+	}
+
+	for _, asset := range cached.release.Assets {
+		if asset.Name == name {
+			return asset, nil
+		}
+	}
+	return github.Asset{}, fmt.Errorf("%w: %s has no asset %s", errAssetNotFound, cached.release.TagName, name)
+}
+
+// fetchObject downloads an asset into the cache unless a concurrent request
+// already did. While another request fetches it, p.overlap decides whether
+// to wait for that fetch, to give up with errFetchRunning, or to cancel it
+// and fetch the asset again.
+func (p *proxy) fetchObject(asset github.Asset, digest string) error {
+	overlap := p.overlap
+	for {
+		p.mu.Lock()
+		running, ok := p.fetching[digest]
+		if ok && overlap != overlapRestart {
+			p.mu.Unlock()
+			if overlap == overlapSkip {
+				return fmt.Errorf("%w: %s", errFetchRunning, asset.Name)
+			}
+			<-running.done
+			// A fetch cancelled by a restart leaves the asset to the new one
+			if errors.Is(running.err, context.Canceled) {
+				continue
+			}
+			return running.err
+		}
+		if ok {
+			running.cancel()
+		}
+		if p.objects.Has(digest) {
+			p.mu.Unlock()
+			return nil
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		fetch := &objectFetch{cancel: cancel, done: make(chan struct{})}
+		p.fetching[digest] = fetch
+		p.mu.Unlock()
+
+		fetch.err = p.downloadObject(ctx, asset, digest)
+		cancel()
+		p.mu.Lock()
+		if p.fetching[digest] == fetch {
+			delete(p.fetching, digest)
+		}
+		p.mu.Unlock()
+		close(fetch.done)
+
+		if !errors.Is(fetch.err, context.Canceled) {
+			return fetch.err
+		}
+		// Restarted by another request: wait for its fetch instead
+		overlap = overlapQueue
+	}
+}
+
+// downloadObject downloads an asset into the cache
+func (p *proxy) downloadObject(ctx context.Context, asset github.Asset, digest string) error {
+	resp, err := p.openAsset(ctx, asset)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+	if err := p.objects.Add(digest, resp.Body); err != nil {
+		return fmt.Errorf("failed to cache %s: %w", asset.Name, err)
+	}
+	return nil
+}
+
+// passThrough streams an asset GitHub reports no digest for to the client
+// without caching it, as it could not be verified later
+func (p *proxy) passThrough(w http.ResponseWriter, r *http.Request, asset github.Asset) (int, string, error) {
+	resp, err := p.openAsset(r.Context(), asset)
+	if err != nil {
+		return http.StatusBadGateway, "bypass", err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	setAssetHeaders(w, asset)
+	w.Header().Set("X-Cache", "BYPASS")
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(resp.ContentLength))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to send %s: %v\n", asset.Name, err)
+		}
+	}
+	return http.StatusOK, "bypass", nil
+}
+
+// openAsset requests the content of an asset from GitHub
+func (p *proxy) openAsset(ctx context.Context, asset github.Asset) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", asset.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	resp, err := p.assets.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() {
+			if closeErr := resp.Body.Close(); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+			}
+		}()
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, api.HandleHTTPError(resp))
+	}
+	return resp, nil
+}
+
+// setAssetHeaders describes the asset in the response
+func setAssetHeaders(w http.ResponseWriter, asset github.Asset) {
+	if asset.ContentType != "" {
+		w.Header().Set("Content-Type", asset.ContentType)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sanitizeAssetName(asset.Name)))
+	if asset.Digest != "" {
+		w.Header().Set("Digest", asset.Digest)
+	}
+}
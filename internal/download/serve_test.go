@@ -0,0 +1,321 @@
+package download
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/access"
+	"github.com/23prime/gh-download/internal/cas"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+func TestParseProxyPath(t *testing.T) {
+	testCases := []struct {
+		path string
+		repo string
+		tag  string
+		name string
+		ok   bool
+	}{
+		{"/owner/repo/v1.0.0/tool.tar.gz", "owner/repo", "v1.0.0", "tool.tar.gz", true},
+		{"/owner/repo/latest/tool.tar.gz", "owner/repo", "", "tool.tar.gz", true},
+		{"/owner/repo/v1.0.0", "", "", "", false},
+		{"/owner/repo/v1.0.0/dir/tool.tar.gz", "", "", "", false},
+		{"/owner/../v1.0.0/tool.tar.gz", "", "", "", false},
+		{"/owner//v1.0.0/tool.tar.gz", "", "", "", false},
+	}
+
+	for _, tc := range testCases {
+		repo, tag, name, ok := parseProxyPath(tc.path)
+		if ok != tc.ok || repo != tc.repo || tag != tc.tag || name != tc.name {
+			t.Errorf("Expected %q, %q, %q, %t for %s, got %q, %q, %q, %t", tc.repo, tc.tag, tc.name, tc.ok, tc.path, repo, tag, name, ok)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var fetches atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		switch r.URL.Path {
+		case "/assets/1":
+			_, _ = w.Write([]byte("content"))
+		case "/assets/2":
+			_, _ = w.Write([]byte("unverified"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	client := jsonClient{
+		"repos/owner/repo/releases/tags/v1.0.0": `{"tag_name":"v1.0.0","assets":[` +
+			`{"id":1,"name":"tool.tar.gz","url":"` + upstream.URL + `/assets/1","digest":"sha256:` + sha256Hex("content") + `"},` +
+			`{"id":2,"name":"notes.txt","url":"` + upstream.URL + `/assets/2"}]}`,
+	}
+	objects := cas.Store{Root: t.TempDir()}
+	var log bytes.Buffer
+	server := httptest.NewServer(newProxy(client, upstream.Client(), objects, &log))
+
+	get := func(path string) (int, string, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, resp.Header.Get("X-Cache"), string(body)
+	}
+
+	for _, expected := range []string{"MISS", "HIT"} {
+		status, cache, body := get("/owner/repo/v1.0.0/tool.tar.gz")
+		if status != http.StatusOK || cache != expected || body != "content" {
+			t.Errorf("Expected 200 %s content, got %d %s %q", expected, status, cache, body)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected one upstream fetch, got %d", fetches.Load())
+	}
+	if !objects.Has(sha256Hex("content")) {
+		t.Error("Expected the asset to be cached")
+	}
+
+	if status, cache, body := get("/owner/repo/v1.0.0/notes.txt"); status != http.StatusOK || cache != "BYPASS" || body != "unverified" {
+		t.Errorf("Expected 200 BYPASS unverified, got %d %s %q", status, cache, body)
+	}
+	if status, _, _ := get("/owner/repo/v1.0.0/missing.txt"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown asset, got %d", status)
+	}
+	if status, _, _ := get("/owner/repo/v2.0.0/tool.tar.gz"); status != http.StatusBadGateway {
+		t.Errorf("Expected 502 for an unknown release, got %d", status)
+	}
+	if status, _, _ := get("/owner/repo"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an invalid path, got %d", status)
+	}
+
+	// Close waits for the handlers, so every request is logged
+	server.Close()
+	if !strings.Contains(log.String(), "- GET /owner/repo/v1.0.0/tool.tar.gz 200 hit") {
+		t.Errorf("Expected the requests to be logged, got %q", log.String())
+	}
+}
+
+func TestProxy_Access(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer upstream.Close()
+
+	release := `{"tag_name":"v1.0.0","assets":[{"id":1,"name":"tool.tar.gz","url":"` + upstream.URL + `/assets/1","digest":"sha256:` + sha256Hex("content") + `"}]}`
+	client := jsonClient{
+		"repos/owner/repo/releases/tags/v1.0.0": release,
+		"repos/other/repo/releases/tags/v1.0.0": release,
+	}
+	policy, err := access.Parse([]byte("clients:\n  ci:\n    token_sha256: " + sha256Hex("ci-token") + "\n    repos: [owner/*]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := state.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	handler := newProxy(client, upstream.Client(), cas.Store{Root: t.TempDir()}, io.Discard)
+	handler.policy = policy
+	handler.audit = store
+	server := httptest.NewServer(handler)
+
+	testCases := []struct {
+		path     string
+		auth     func(*http.Request)
+		expected int
+	}{
+		{"/owner/repo/v1.0.0/tool.tar.gz", func(*http.Request) {}, http.StatusUnauthorized},
+		{"/owner/repo/v1.0.0/tool.tar.gz", func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized},
+		{"/owner/repo/v1.0.0/tool.tar.gz", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ci-token") }, http.StatusOK},
+		{"/owner/repo/v1.0.0/tool.tar.gz", func(r *http.Request) { r.SetBasicAuth("ci", "ci-token") }, http.StatusOK},
+		{"/other/repo/v1.0.0/tool.tar.gz", func(r *http.Request) { r.Header.Set("Authorization", "token ci-token") }, http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest("GET", server.URL+tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.auth(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.expected {
+			t.Errorf("Expected %d for %s, got %d", tc.expected, tc.path, resp.StatusCode)
+		}
+		if tc.expected == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Error("Expected a WWW-Authenticate header")
+		}
+	}
+	server.Close()
+
+	data, err := os.ReadFile(state.AuditPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(testCases) {
+		t.Fatalf("Expected %d audit entries, got %d", len(testCases), len(lines))
+	}
+	if !strings.Contains(lines[4], `"client":"ci"`) || !strings.Contains(lines[4], `"status":403`) {
+		t.Errorf("Unexpected audit entry %s", lines[4])
+	}
+	if !strings.Contains(lines[2], `"bytes":7`) {
+		t.Errorf("Expected the size of the served asset in %s", lines[2])
+	}
+}
+
+func TestParseOverlapPolicy(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected overlapPolicy
+		err      bool
+	}{
+		{"", overlapQueue, false},
+		{"queue", overlapQueue, false},
+		{"skip", overlapSkip, false},
+		{"cancel-and-restart", overlapRestart, false},
+		{"restart", 0, true},
+	}
+
+	for _, tc := range testCases {
+		policy, err := parseOverlapPolicy(tc.value)
+		if (err != nil) != tc.err || policy != tc.expected {
+			t.Errorf("Expected %v (error %t) for %q, got %v (%v)", tc.expected, tc.err, tc.value, policy, err)
+		}
+	}
+}
+
+func TestProxy_Overlap(t *testing.T) {
+	testCases := []struct {
+		name    string
+		overlap overlapPolicy
+		second  int
+		fetches int32
+	}{
+		{name: "queue", overlap: overlapQueue, second: http.StatusOK, fetches: 1},
+		{name: "skip", overlap: overlapSkip, second: http.StatusServiceUnavailable, fetches: 1},
+		{name: "cancel-and-restart", overlap: overlapRestart, second: http.StatusOK, fetches: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The first fetch stalls until released or cancelled, like a
+			// download on a slow link
+			var fetches atomic.Int32
+			started := make(chan struct{})
+			release := make(chan struct{})
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if fetches.Add(1) == 1 {
+					close(started)
+					select {
+					case <-release:
+					case <-r.Context().Done():
+						return
+					}
+				}
+				_, _ = w.Write([]byte("content"))
+			}))
+			defer upstream.Close()
+			defer func() {
+				select {
+				case <-release:
+				default:
+					close(release)
+				}
+			}()
+
+			client := jsonClient{
+				"repos/owner/repo/releases/tags/v1.0.0": `{"tag_name":"v1.0.0","assets":[` +
+					`{"id":1,"name":"tool.tar.gz","url":"` + upstream.URL + `/assets/1","digest":"sha256:` + sha256Hex("content") + `"}]}`,
+			}
+			handler := newProxy(client, upstream.Client(), cas.Store{Root: t.TempDir()}, io.Discard)
+			handler.overlap = tc.overlap
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			get := func() (*http.Response, string) {
+				resp, err := http.Get(server.URL + "/owner/repo/v1.0.0/tool.tar.gz")
+				if err != nil {
+					t.Error(err)
+					return nil, ""
+				}
+				defer func() {
+					if err := resp.Body.Close(); err != nil {
+						t.Error(err)
+					}
+				}()
+				body, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				return resp, string(body)
+			}
+
+			first := make(chan string, 1)
+			go func() {
+				resp, body := get()
+				if resp != nil && resp.StatusCode != http.StatusOK {
+					body = resp.Status
+				}
+				first <- body
+			}()
+			<-started
+
+			second := make(chan *http.Response, 1)
+			go func() {
+				resp, _ := get()
+				second <- resp
+			}()
+			if tc.overlap == overlapQueue {
+				select {
+				case <-second:
+					t.Fatal("Expected the second request to wait for the running fetch")
+				case <-time.After(50 * time.Millisecond):
+				}
+				close(release)
+			}
+
+			resp := <-second
+			if resp == nil || resp.StatusCode != tc.second {
+				t.Fatalf("Expected %d for the second request, got %v", tc.second, resp)
+			}
+			if tc.second == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") == "" {
+				t.Error("Expected a Retry-After header")
+			}
+			if tc.overlap == overlapSkip {
+				close(release)
+			}
+			if body := <-first; body != "content" {
+				t.Errorf("Expected the first request to get the content, got %q", body)
+			}
+			if fetches.Load() != tc.fetches {
+				t.Errorf("Expected %d upstream fetches, got %d", tc.fetches, fetches.Load())
+			}
+		})
+	}
+}
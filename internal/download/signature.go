@@ -0,0 +1,143 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/middleware"
+)
+
+// signatureSuffixes are appended to the name of an asset to find its
+// detached signature, armored or binary
+var signatureSuffixes = []string{".asc", ".sig"}
+
+// signatureVerifier checks downloaded assets against the detached GPG
+// signatures published next to them, with --verify-signature. The signer
+// keys are imported into a keyring of its own in dir.
+type signatureVerifier struct {
+	dir    string
+	assets []github.Asset
+	client middleware.Doer
+	// mu runs one gpg at a time, as they share the keyring
+	mu sync.Mutex
+}
+
+// newSignatureVerifier imports the keys of --signer-key, or else the GPG
+// keys the author of the release added to GitHub, which client gets;
+// signatures are downloaded with downloads. The caller must close the
+// verifier.
+func newSignatureVerifier(client github.HTTPClient, downloads middleware.Doer, cfg config.Config, release *github.Release) (*signatureVerifier, error) {
+	if cfg.Extract {
+		return nil, fmt.Errorf("--verify-signature cannot be used with --extract")
+	}
+
+	dir, err := os.MkdirTemp("", "gh-download-signature-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	v := &signatureVerifier{dir: dir, assets: release.Assets, client: downloads}
+	if err := v.importKeys(client, cfg, release); err != nil {
+		v.close()
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *signatureVerifier) importKeys(client github.HTTPClient, cfg config.Config, release *github.Release) error {
+	keyPath := cfg.SignerKey
+	if keyPath == "" {
+		login := release.Author.Login
+		if login == "" {
+			return fmt.Errorf("release %s has no author to get GPG keys of; use --signer-key", release.TagName)
+		}
+		keys, err := github.GetUserGPGKeys(client, login)
+		if err != nil {
+			return fmt.Errorf("failed to get GPG keys of %s: %w", login, err)
+		}
+		var armored []string
+		for _, key := range keys {
+			if key.RawKey != "" {
+				armored = append(armored, strings.TrimSpace(key.RawKey))
+			}
+		}
+		if len(armored) == 0 {
+			return fmt.Errorf("%s has no GPG keys on GitHub; use --signer-key", login)
+		}
+		keyPath = filepath.Join(v.dir, "keys.asc")
+		if err := os.WriteFile(keyPath, []byte(strings.Join(armored, "\n")+"\n"), 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Verifying signatures with %d GPG keys of %s\n", len(armored), login)
+	}
+
+	home := filepath.Join(v.dir, "gnupg")
+	if err := os.Mkdir(home, 0700); err != nil {
+		return err
+	}
+	if err := runVerifier(nil, "gpg", "--homedir", home, "--batch", "--quiet", "--import", keyPath); err != nil {
+		return fmt.Errorf("failed to import %s: %w", keyPath, err)
+	}
+	return nil
+}
+
+func (v *signatureVerifier) close() {
+	if v == nil {
+		return
+	}
+	if removeErr := os.RemoveAll(v.dir); removeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", v.dir, removeErr)
+	}
+}
+
+// signatureAsset returns the detached signature of the asset named name
+// among assets. Signatures themselves are not signed.
+func signatureAsset(assets []github.Asset, name string) (github.Asset, bool) {
+	for _, suffix := range signatureSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return github.Asset{}, false
+		}
+	}
+	for _, suffix := range signatureSuffixes {
+		for _, asset := range assets {
+			if asset.Name == name+suffix {
+				return asset, true
+			}
+		}
+	}
+	return github.Asset{}, false
+}
+
+// verify downloads the signature of an asset and checks the file at path
+// against it, reporting whether there was a signature. Assets without one
+// pass.
+func (v *signatureVerifier) verify(asset github.Asset, path string) (bool, error) {
+	if v == nil {
+		return false, nil
+	}
+	signature, ok := signatureAsset(v.assets, asset.Name)
+	if !ok {
+		return false, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	root, err := confine.Open(v.dir)
+	if err != nil {
+		return true, err
+	}
+	defer closeRoot(root)
+	signaturePath := filepath.Join(v.dir, signature.Name)
+	if _, _, err := fetchAsset(v.client, root, signature, signaturePath, nil); err != nil {
+		return true, err
+	}
+	if err := runVerifier(nil, "gpg", "--homedir", filepath.Join(v.dir, "gnupg"), "--batch", "--verify", signaturePath, path); err != nil {
+		return true, &VerificationError{Err: fmt.Errorf("signature %s does not verify %s: %w", signature.Name, asset.Name, err)}
+	}
+	return true, nil
+}
@@ -0,0 +1,122 @@
+package download
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestSignatureAsset(t *testing.T) {
+	assets := []github.Asset{
+		{Name: "app.tar.gz"},
+		{Name: "app.tar.gz.asc"},
+		{Name: "tool.zip"},
+		{Name: "tool.zip.sig"},
+		{Name: "other.zip"},
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "app.tar.gz", want: "app.tar.gz.asc"},
+		{name: "tool.zip", want: "tool.zip.sig"},
+		{name: "other.zip"},
+		{name: "app.tar.gz.asc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, ok := signatureAsset(assets, tt.name)
+			if ok != (tt.want != "") || signature.Name != tt.want {
+				t.Errorf("Expected %q, got %q (%v)", tt.want, signature.Name, ok)
+			}
+		})
+	}
+}
+
+func TestSignatureVerifier_NoKeys(t *testing.T) {
+	client := jsonClient(map[string]string{"users/octocat/gpg_keys": `[]`})
+	release := &github.Release{TagName: "v1.0.0"}
+	release.Author.Login = "octocat"
+
+	if _, err := newSignatureVerifier(client, nil, config.Config{VerifySignature: true}, release); err == nil {
+		t.Error("Expected an error for an author without GPG keys")
+	}
+	if _, err := newSignatureVerifier(client, nil, config.Config{VerifySignature: true, Extract: true}, release); err == nil {
+		t.Error("Expected an error with --extract")
+	}
+}
+
+func TestSignatureVerifier_Verify(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not available")
+	}
+
+	dir := t.TempDir()
+	home := filepath.Join(dir, "gnupg")
+	if err := os.Mkdir(home, 0700); err != nil {
+		t.Fatal(err)
+	}
+	gpg := func(args ...string) {
+		t.Helper()
+		args = append([]string{"--homedir", home, "--batch", "--quiet", "--pinentry-mode", "loopback", "--passphrase", ""}, args...)
+		if output, err := exec.Command("gpg", args...).CombinedOutput(); err != nil {
+			t.Skipf("gpg %v failed: %v: %s", args, err, output)
+		}
+	}
+	gpg("--quick-gen-key", "Maintainer <maintainer@example.com>", "ed25519", "sign", "never")
+	keyPath := filepath.Join(dir, "maintainer.asc")
+	gpg("--armor", "--output", keyPath, "--export", "maintainer@example.com")
+
+	assetPath := filepath.Join(dir, "app.tar.gz")
+	if err := os.WriteFile(assetPath, []byte("release content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gpg("--armor", "--output", assetPath+".asc", "--detach-sign", assetPath)
+	signature, err := os.ReadFile(assetPath + ".asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(signature)
+	}))
+	defer server.Close()
+
+	release := &github.Release{TagName: "v1.0.0", Assets: []github.Asset{
+		{Name: "app.tar.gz"},
+		{Name: "app.tar.gz.asc", URL: server.URL, Size: len(signature)},
+		{Name: "unsigned.zip"},
+	}}
+	v, err := newSignatureVerifier(jsonClient(nil), server.Client(), config.Config{SignerKey: keyPath}, release)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer v.close()
+
+	signed, err := v.verify(github.Asset{Name: "app.tar.gz"}, assetPath)
+	if err != nil || !signed {
+		t.Errorf("Expected a verified signature, got %v (%v)", err, signed)
+	}
+
+	signed, err = v.verify(github.Asset{Name: "unsigned.zip"}, assetPath)
+	if err != nil || signed {
+		t.Errorf("Expected an asset without signature to pass, got %v (%v)", err, signed)
+	}
+
+	if err := os.WriteFile(assetPath, []byte("tampered content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = v.verify(github.Asset{Name: "app.tar.gz"}, assetPath)
+	var verr *VerificationError
+	if !errors.As(err, &verr) {
+		t.Errorf("Expected a VerificationError for a tampered asset, got %v", err)
+	}
+}
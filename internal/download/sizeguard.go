@@ -0,0 +1,97 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/progress"
+)
+
+// Actions of --on-oversize for assets above --max-asset-size
+const (
+	OversizeFail = "fail"
+	OversizeSkip = "skip"
+)
+
+// sizeUnits are the multipliers of the units --max-asset-size takes. Units
+// are binary, so 2G is 2 GiB.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// parseSize parses a size such as "500M", "2G" or "1.5GiB" into bytes
+func parseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	end := len(trimmed)
+	for end > 0 && (trimmed[end-1] < '0' || trimmed[end-1] > '9') && trimmed[end-1] != '.' {
+		end--
+	}
+	number, unit := trimmed[:end], strings.ToUpper(strings.TrimSpace(trimmed[end:]))
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// applySizeLimit handles the assets above --max-asset-size: it fails naming
+// them or, with --on-oversize skip, leaves them out with a warning
+func applySizeLimit(cfg config.Config, assets []github.Asset) ([]github.Asset, error) {
+	if cfg.MaxAssetSize == "" {
+		return assets, nil
+	}
+	limit, err := parseSize(cfg.MaxAssetSize)
+	if err != nil {
+		return nil, fmt.Errorf("--max-asset-size: %w", err)
+	}
+	action := cfg.OnOversize
+	if action == "" {
+		action = OversizeFail
+	}
+	if action != OversizeFail && action != OversizeSkip {
+		return nil, fmt.Errorf("invalid --on-oversize '%s': must be fail or skip", cfg.OnOversize)
+	}
+
+	var kept []github.Asset
+	var oversized []string
+	for _, asset := range assets {
+		if int64(asset.Size) <= limit {
+			kept = append(kept, asset)
+			continue
+		}
+		oversized = append(oversized, fmt.Sprintf("%s (%s)", asset.Name, progress.FormatBytes(int64(asset.Size))))
+	}
+	if len(oversized) == 0 {
+		return assets, nil
+	}
+
+	if action == OversizeFail {
+		return nil, fmt.Errorf("%d assets exceed --max-asset-size %s: %s", len(oversized), cfg.MaxAssetSize, strings.Join(oversized, ", "))
+	}
+	for _, name := range oversized {
+		fmt.Fprintf(os.Stderr, "Warning: skipping %s above --max-asset-size %s\n", name, cfg.MaxAssetSize)
+	}
+	return kept, nil
+}
@@ -0,0 +1,60 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{"1024", 1024},
+		{"512B", 512},
+		{"10k", 10 << 10},
+		{"500M", 500 << 20},
+		{"2G", 2 << 30},
+		{"1.5GiB", 3 << 29},
+		{"1 TB", 1 << 40},
+	}
+	for _, tc := range testCases {
+		got, err := parseSize(tc.input)
+		if err != nil || got != tc.expected {
+			t.Errorf("%q: expected %d, got %d (%v)", tc.input, tc.expected, got, err)
+		}
+	}
+
+	for _, invalid := range []string{"", "G", "2X", "-1M", "1.2.3G"} {
+		if _, err := parseSize(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestApplySizeLimit(t *testing.T) {
+	assets := []github.Asset{
+		{Name: "app.tar.gz", Size: 10 << 20},
+		{Name: "debug-symbols.tar.gz", Size: 3 << 30},
+	}
+
+	kept, err := applySizeLimit(config.Config{}, assets)
+	if err != nil || len(kept) != 2 {
+		t.Errorf("Expected every asset without a limit, got %v (%v)", kept, err)
+	}
+
+	if _, err := applySizeLimit(config.Config{MaxAssetSize: "2G", OnOversize: OversizeFail}, assets); err == nil {
+		t.Error("Expected an error for an oversized asset")
+	}
+
+	kept, err = applySizeLimit(config.Config{MaxAssetSize: "2G", OnOversize: OversizeSkip}, assets)
+	if err != nil || len(kept) != 1 || kept[0].Name != "app.tar.gz" {
+		t.Errorf("Expected the oversized asset to be skipped, got %v (%v)", kept, err)
+	}
+
+	if _, err := applySizeLimit(config.Config{MaxAssetSize: "2G", OnOversize: "ignore"}, assets); err == nil {
+		t.Error("Expected an error for an invalid --on-oversize")
+	}
+}
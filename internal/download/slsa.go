@@ -0,0 +1,268 @@
+package download
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/attest"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/verify/sigstore"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// provenanceSuffix ends the names of the provenance assets of a release, as
+// the SLSA GitHub generator publishes them
+const provenanceSuffix = ".intoto.jsonl"
+
+// maxProvenanceSize limits the provenance files read into memory
+const maxProvenanceSize = 16 << 20
+
+// verifyAttestation checks a blob against an attestation with cosign;
+// replaced in tests
+var verifyAttestation = sigstore.VerifyAttestation
+
+// provenanceSet holds the statements of the provenance of a release, with
+// --verify-provenance, by the SHA-256 digests of their subjects. Their
+// signing material is kept in dir for cosign until the set is closed.
+type provenanceSet struct {
+	files    []string
+	dir      string
+	identity sigstore.Identity
+	digests  map[string]provenanceStatement
+}
+
+// provenanceStatement is a statement of a provenance file: its predicate
+// type and the paths of its signing material in the dir of the set
+type provenanceStatement struct {
+	predicateType string
+	material      sigstore.Material
+}
+
+// parsedStatement is a statement of a provenance file whose signature was
+// checked against the certificate it carries
+type parsedStatement struct {
+	statement attest.Statement
+	// bundle is the line when it is a sigstore bundle
+	bundle   []byte
+	envelope attest.Envelope
+	cert     *x509.Certificate
+}
+
+// provenanceLine is a line of a provenance file: a DSSE envelope whose
+// signatures carry the certificate of the signer, or a sigstore bundle
+// holding such an envelope
+type provenanceLine struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		KeyID string `json:"keyid"`
+		Sig   string `json:"sig"`
+		Cert  string `json:"cert"`
+	} `json:"signatures"`
+	DSSEEnvelope *attest.Envelope `json:"dsseEnvelope"`
+}
+
+// loadProvenance checks the provenance assets of a release and collects the
+// statements by the digests of their subjects. Each statement must be signed
+// by the key of the certificate it carries, issued to --certificate-identity
+// by --certificate-oidc-issuer; verify then has cosign check the certificate
+// chain and the transparency log entry of the statement of each asset.
+func loadProvenance(cfg config.Config, release *github.Release) (_ *provenanceSet, err error) {
+	if cfg.CertificateIdentity == "" || cfg.CertificateOIDCIssuer == "" {
+		return nil, fmt.Errorf("--verify-provenance requires --certificate-identity and --certificate-oidc-issuer")
+	}
+	var assets []github.Asset
+	for _, asset := range release.Assets {
+		if strings.HasSuffix(asset.Name, provenanceSuffix) {
+			assets = append(assets, asset)
+		}
+	}
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("no provenance (*%s) in release %s", provenanceSuffix, release.TagName)
+	}
+	identity := sigstore.Identity{Subject: cfg.CertificateIdentity, Issuer: cfg.CertificateOIDCIssuer}
+
+	client, err := newAssetRESTClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download client: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "gh-download-provenance-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	set := &provenanceSet{dir: dir, identity: identity, digests: make(map[string]provenanceStatement)}
+	defer func() {
+		if err != nil {
+			set.close()
+		}
+	}()
+
+	for _, asset := range assets {
+		data, err := fetchProvenanceFile(client, asset)
+		if err != nil {
+			return nil, err
+		}
+		statements, err := verifyProvenance(data, identity)
+		if err != nil {
+			return nil, &VerificationError{Err: fmt.Errorf("provenance %s: %w", asset.Name, err)}
+		}
+		for i, parsed := range statements {
+			material, err := set.writeMaterial(fmt.Sprintf("%s-%d", asset.Name, i+1), parsed)
+			if err != nil {
+				return nil, err
+			}
+			for _, subject := range parsed.statement.Subject {
+				if digest := subject.Digest["sha256"]; digest != "" {
+					set.digests[strings.ToLower(digest)] = provenanceStatement{predicateType: parsed.statement.PredicateType, material: material}
+				}
+			}
+		}
+		set.files = append(set.files, asset.Name)
+	}
+	fmt.Printf("Checked provenance %s with %d subjects\n", strings.Join(set.files, ", "), len(set.digests))
+	return set, nil
+}
+
+// writeMaterial writes the signing material of a statement into the dir of
+// the set for cosign: the bundle, or the envelope and the certificate
+func (p *provenanceSet) writeMaterial(name string, parsed parsedStatement) (sigstore.Material, error) {
+	if parsed.bundle != nil {
+		path := filepath.Join(p.dir, name+".sigstore.json")
+		return sigstore.Material{Bundle: path}, os.WriteFile(path, parsed.bundle, 0600)
+	}
+
+	envelope, err := json.Marshal(parsed.envelope)
+	if err != nil {
+		return sigstore.Material{}, err
+	}
+	material := sigstore.Material{Signature: filepath.Join(p.dir, name+".intoto.json"), Certificate: filepath.Join(p.dir, name+".pem")}
+	if err := os.WriteFile(material.Signature, envelope, 0600); err != nil {
+		return material, err
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: parsed.cert.Raw})
+	return material, os.WriteFile(material.Certificate, cert, 0600)
+}
+
+func (p *provenanceSet) close() {
+	if p == nil {
+		return
+	}
+	if removeErr := os.RemoveAll(p.dir); removeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", p.dir, removeErr)
+	}
+}
+
+func fetchProvenanceFile(client *api.RESTClient, asset github.Asset) ([]byte, error) {
+	resp, err := client.Request("GET", asset.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProvenanceSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	return data, nil
+}
+
+// verifyProvenance checks the signature of every statement of a provenance
+// file against the certificate it carries, which must be issued to
+// identity, and returns the statements. Whether the certificates are
+// genuine is left to cosign.
+func verifyProvenance(data []byte, identity sigstore.Identity) ([]parsedStatement, error) {
+	var statements []parsedStatement
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxProvenanceSize)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		parsed, err := parseProvenanceLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if err := sigstore.CheckIdentity(parsed.cert, identity); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		statement, err := attest.Verify(parsed.envelope, parsed.cert.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		parsed.statement = statement
+		statements = append(statements, parsed)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no statements")
+	}
+	return statements, nil
+}
+
+// parseProvenanceLine returns the envelope of a line and the certificate of
+// its signer
+func parseProvenanceLine(line []byte) (parsedStatement, error) {
+	var parsed provenanceLine
+	if err := json.Unmarshal(line, &parsed); err != nil {
+		return parsedStatement{}, fmt.Errorf("invalid envelope: %w", err)
+	}
+
+	if parsed.DSSEEnvelope != nil {
+		cert, err := sigstore.BundleCertificate(line)
+		return parsedStatement{bundle: slices.Clone(line), envelope: *parsed.DSSEEnvelope, cert: cert}, err
+	}
+
+	result := parsedStatement{envelope: attest.Envelope{PayloadType: parsed.PayloadType, Payload: parsed.Payload}}
+	for _, signature := range parsed.Signatures {
+		result.envelope.Signatures = append(result.envelope.Signatures, attest.Signature{KeyID: signature.KeyID, Sig: signature.Sig})
+		if result.cert == nil && signature.Cert != "" {
+			cert, err := sigstore.ParseCertificate([]byte(signature.Cert))
+			if err != nil {
+				return result, err
+			}
+			result.cert = cert
+		}
+	}
+	if result.cert == nil {
+		return result, fmt.Errorf("envelope has no signing certificate")
+	}
+	return result, nil
+}
+
+// verify checks that a downloaded asset is a subject of the provenance,
+// and has cosign verify the statement naming it. The provenance files
+// themselves are not.
+func (p *provenanceSet) verify(asset github.Asset, path string) (bool, error) {
+	if p == nil || strings.HasSuffix(asset.Name, provenanceSuffix) {
+		return false, nil
+	}
+	digest, err := fileSHA256(path)
+	if err != nil {
+		return false, err
+	}
+	statement, ok := p.digests[digest]
+	if !ok {
+		return false, &VerificationError{Err: fmt.Errorf("%s (sha256 %s) is not a subject of %s", asset.Name, digest, strings.Join(p.files, ", "))}
+	}
+	if err := verifyAttestation(path, statement.material, p.identity, statement.predicateType); err != nil {
+		return false, &VerificationError{Err: fmt.Errorf("provenance of %s: %w", asset.Name, err)}
+	}
+	return true, nil
+}
@@ -0,0 +1,211 @@
+package download
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/attest"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/verify/sigstore"
+)
+
+const (
+	provenanceSubject = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v2.0.0"
+	provenanceIssuer  = "https://token.actions.githubusercontent.com"
+)
+
+// provenanceLines returns a provenance file with one statement over
+// subjects in the DSSE form of the SLSA generator, and the same statement
+// in a sigstore bundle
+func provenanceLines(t *testing.T, subjects []attest.Subject) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := url.Parse(provenanceSubject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := asn1.Marshal(provenanceIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		NotBefore:       time.Now(),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		URIs:            []*url.URL{subject},
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}, Value: issuer}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := attest.Sign(attest.NewStatement(subjects), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := map[string]any{
+		"payloadType": envelope.PayloadType,
+		"payload":     envelope.Payload,
+		"signatures": []map[string]string{{
+			"sig":  envelope.Signatures[0].Sig,
+			"cert": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		}},
+	}
+	bundle := map[string]any{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"verificationMaterial": map[string]any{
+			"certificate": map[string]string{"rawBytes": base64.StdEncoding.EncodeToString(der)},
+		},
+		"dsseEnvelope": envelope,
+	}
+	dsse, err := json.Marshal(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundled, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(dsse, '\n'), append(bundled, '\n')
+}
+
+func TestVerifyProvenance(t *testing.T) {
+	subjects := []attest.Subject{{Name: "app.tar.gz", Digest: map[string]string{"sha256": sha256Hex("app")}}}
+	dsse, bundle := provenanceLines(t, subjects)
+	identity := sigstore.Identity{Subject: provenanceSubject, Issuer: provenanceIssuer}
+
+	for name, data := range map[string][]byte{"dsse": dsse, "bundle": bundle} {
+		got, err := verifyProvenance(data, identity)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+			continue
+		}
+		if len(got) != 1 || got[0].statement.Subject[0].Digest["sha256"] != sha256Hex("app") || (name == "bundle") != (got[0].bundle != nil) {
+			t.Errorf("%s: unexpected subjects %+v", name, got)
+		}
+	}
+
+	if _, err := verifyProvenance(dsse, sigstore.Identity{Subject: "someone@example.com", Issuer: provenanceIssuer}); err == nil {
+		t.Error("Expected an error for another signer")
+	}
+
+	var tampered map[string]any
+	if err := json.Unmarshal(dsse, &tampered); err != nil {
+		t.Fatal(err)
+	}
+	other, err := json.Marshal(attest.NewStatement([]attest.Subject{{Name: "evil", Digest: map[string]string{"sha256": sha256Hex("evil")}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered["payload"] = base64.StdEncoding.EncodeToString(other)
+	data, err := json.Marshal(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyProvenance(data, identity); err == nil {
+		t.Error("Expected an error for a tampered statement")
+	}
+
+	if _, err := verifyProvenance([]byte("\n"), identity); err == nil {
+		t.Error("Expected an error for a file without statements")
+	}
+}
+
+func TestProvenanceSet_Verify(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.tar.gz")
+	if err := os.WriteFile(path, []byte("app"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	material := sigstore.Material{Bundle: filepath.Join(dir, "multiple.intoto.jsonl-1.sigstore.json")}
+	set := &provenanceSet{
+		files:    []string{"multiple.intoto.jsonl"},
+		identity: sigstore.Identity{Subject: provenanceSubject, Issuer: provenanceIssuer},
+		digests:  map[string]provenanceStatement{sha256Hex("app"): {predicateType: "https://slsa.dev/provenance/v1", material: material}},
+	}
+	var verified []string
+	var cosignErr error
+	previous := verifyAttestation
+	verifyAttestation = func(path string, got sigstore.Material, identity sigstore.Identity, predicateType string) error {
+		if got != material || identity != set.identity || predicateType != "https://slsa.dev/provenance/v1" {
+			t.Errorf("Unexpected attestation check of %s: %+v %+v %s", path, got, identity, predicateType)
+		}
+		verified = append(verified, path)
+		return cosignErr
+	}
+	t.Cleanup(func() { verifyAttestation = previous })
+
+	if ok, err := set.verify(github.Asset{Name: "app.tar.gz"}, path); err != nil || !ok || len(verified) != 1 {
+		t.Errorf("Expected a subject to verify with cosign, got %v (%v, %d checks)", err, ok, len(verified))
+	}
+	if ok, err := set.verify(github.Asset{Name: "multiple.intoto.jsonl"}, path); err != nil || ok {
+		t.Errorf("Expected the provenance itself to be skipped, got %v (%v)", err, ok)
+	}
+
+	// A statement signed with a certificate Fulcio did not issue
+	cosignErr = errors.New("certificate chain invalid")
+	var verr *VerificationError
+	if _, err := set.verify(github.Asset{Name: "app.tar.gz"}, path); !errors.As(err, &verr) {
+		t.Errorf("Expected a VerificationError when cosign rejects the statement, got %v", err)
+	}
+	cosignErr = nil
+
+	if err := os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.verify(github.Asset{Name: "app.tar.gz"}, path); !errors.As(err, &verr) {
+		t.Errorf("Expected a VerificationError for a file outside the subjects, got %v", err)
+	}
+}
+
+func TestProvenanceSet_WriteMaterial(t *testing.T) {
+	dsse, bundle := provenanceLines(t, []attest.Subject{{Name: "app.tar.gz", Digest: map[string]string{"sha256": sha256Hex("app")}}})
+	set := &provenanceSet{dir: t.TempDir()}
+	for name, data := range map[string][]byte{"dsse": dsse, "bundle": bundle} {
+		statements, err := verifyProvenance(data, sigstore.Identity{Subject: provenanceSubject, Issuer: provenanceIssuer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		material, err := set.writeMaterial(name, statements[0])
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		for _, path := range []string{material.Bundle, material.Signature, material.Certificate} {
+			if path == "" {
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("%s: expected %s to be written, got %v", name, path, err)
+			}
+		}
+		if (name == "bundle") != (material.Bundle != "") {
+			t.Errorf("%s: unexpected material %+v", name, material)
+		}
+	}
+}
+
+func TestLoadProvenance_RequiresIdentity(t *testing.T) {
+	release := &github.Release{Assets: []github.Asset{{Name: "multiple.intoto.jsonl"}}}
+	if _, err := loadProvenance(config.Config{VerifyProvenance: true, CertificateIdentity: provenanceSubject}, release); err == nil {
+		t.Error("Expected an error without --certificate-oidc-issuer")
+	}
+}
@@ -0,0 +1,109 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/log"
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/23prime/gh-download/internal/state"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// metadataTimeout bounds each metadata request with --stale-ok, so a slow API
+// falls back to the cache instead of stalling the run
+const metadataTimeout = 30 * time.Second
+
+// metadataClient returns the client resolving releases: with --stale-ok one
+// whose requests time out after metadataTimeout, otherwise client
+func metadataClient(cfg config.Config, client *api.RESTClient) (github.HTTPClient, error) {
+	if cfg.StaleOK <= 0 {
+		return client, nil
+	}
+	timeoutClient, err := newRESTClient(api.ClientOptions{Timeout: metadataTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	return timeoutClient, nil
+}
+
+// releaseCacheKey names the cached metadata of the release a run resolves,
+// keeping releases of other hosts and picked by different filters apart
+func releaseCacheKey(cfg config.Config) string {
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	if cfg.Strategy != "" {
+		tag = cfg.Strategy
+	}
+	if cfg.MinReactions > 0 || cfg.Author != "" {
+		tag += " " + releaseFilterDescription(cfg)
+	}
+	repository := cfg.Repository
+	if host := apiHost(); host != "" {
+		repository = host + "/" + repository
+	}
+	return "releases/" + repository + "/" + url.PathEscape(tag)
+}
+
+// apiUnavailable reports whether err means the API is down or overloaded
+// rather than that the request itself is wrong
+func apiUnavailable(err error) bool {
+	class, ok := retry.Classify(err)
+	return ok && class != retry.Checksum
+}
+
+// resolveReleaseOrCached resolves the release of a run. With --stale-ok the
+// resolved metadata is cached, and while the API is unavailable the release
+// is taken from a cache entry younger than --stale-ok instead; the time it
+// was cached is returned in that case and zero otherwise.
+func resolveReleaseOrCached(client github.HTTPClient, cfg config.Config) (*github.Release, time.Time, error) {
+	release, err := resolveRelease(client, cfg)
+	if err == nil || cfg.StaleOK <= 0 || !apiUnavailable(err) {
+		return release, time.Time{}, err
+	}
+
+	var cached github.Release
+	var fetched time.Time
+	cacheErr := withStore(func(store state.Store) error {
+		var err error
+		fetched, err = store.ReadCache(releaseCacheKey(cfg), &cached)
+		return err
+	})
+	if errors.Is(cacheErr, state.ErrNotCached) {
+		return nil, time.Time{}, fmt.Errorf("%w (no cached metadata to fall back to)", err)
+	}
+	if cacheErr != nil {
+		return nil, time.Time{}, fmt.Errorf("%w (%v)", err, cacheErr)
+	}
+	age := time.Since(fetched)
+	if age > cfg.StaleOK {
+		return nil, time.Time{}, fmt.Errorf("%w (cached metadata is %s old, older than --stale-ok %s)", err, age.Round(time.Second), cfg.StaleOK)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: GitHub API unavailable: %v\n", err)
+	fmt.Printf("Using STALE release metadata cached %s ago (%s)\n", age.Round(time.Second), fetched.Local().Format(time.RFC3339))
+	return &cached, fetched, nil
+}
+
+// cacheRelease stores the metadata of a freshly resolved release for
+// --stale-ok. The cache is a fallback, so failing to write it only warns.
+func cacheRelease(cfg config.Config, release *github.Release) {
+	if cfg.StaleOK <= 0 {
+		return
+	}
+	err := withStore(func(store state.Store) error {
+		return store.WriteCache(releaseCacheKey(cfg), release, time.Now())
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache release metadata: %v\n", err)
+		return
+	}
+	log.Debugf("Cached the metadata of %s for --stale-ok", release.TagName)
+}
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/state"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// downClient fails every request as an API outage would
+type downClient struct {
+	status int
+}
+
+func (c downClient) Get(endpoint string, response interface{}) error {
+	return &api.HTTPError{StatusCode: c.status, Message: "unavailable"}
+}
+
+func TestResolveReleaseOrCached(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	cfg := config.Config{Repository: "owner/repo", StaleOK: 24 * time.Hour}
+	client := jsonClient{
+		"repos/owner/repo/releases/latest": `{"tag_name":"v1.0.0","assets":[{"name":"tool.tar.gz"}]}`,
+	}
+
+	release, cachedAt, err := resolveReleaseOrCached(client, cfg)
+	if err != nil || !cachedAt.IsZero() {
+		t.Fatalf("Expected a fresh release, got %v, %v", cachedAt, err)
+	}
+	cacheRelease(cfg, release)
+
+	var fallback *github.Release
+	output := captureStdout(t, func() {
+		fallback, cachedAt, err = resolveReleaseOrCached(downClient{status: 503}, cfg)
+	})
+	if err != nil {
+		t.Fatalf("Expected the cache to be used, got %v", err)
+	}
+	if cachedAt.IsZero() || fallback.TagName != "v1.0.0" || len(fallback.Assets) != 1 {
+		t.Errorf("Unexpected cached release %+v at %v", fallback, cachedAt)
+	}
+	if !strings.Contains(output, "STALE") {
+		t.Errorf("Expected stale metadata to be marked, got %q", output)
+	}
+
+	if _, _, err := resolveReleaseOrCached(downClient{status: 404}, cfg); err == nil {
+		t.Error("Expected a 404 not to fall back to the cache")
+	}
+
+	cfg.Tag = "v2.0.0"
+	if _, _, err := resolveReleaseOrCached(downClient{status: 502}, cfg); err == nil || !strings.Contains(err.Error(), "no cached metadata") {
+		t.Errorf("Expected a missing cache error, got %v", err)
+	}
+
+	cfg.Tag = ""
+	cfg.StaleOK = 0
+	if _, _, err := resolveReleaseOrCached(downClient{status: 503}, cfg); err == nil {
+		t.Error("Expected no fallback without --stale-ok")
+	}
+}
+
+func TestResolveReleaseOrCached_TooOld(t *testing.T) {
+	t.Setenv(state.DirEnv, t.TempDir())
+	cfg := config.Config{Repository: "owner/repo", StaleOK: time.Hour}
+	release := &github.Release{TagName: "v1.0.0"}
+	if err := state.WriteCache(state.CachePath(releaseCacheKey(cfg)), release, time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := resolveReleaseOrCached(downClient{status: 503}, cfg)
+	if err == nil || !strings.Contains(err.Error(), "older than --stale-ok") {
+		t.Errorf("Expected a stale cache error, got %v", err)
+	}
+}
+
+func TestReleaseCacheKey(t *testing.T) {
+	t.Setenv(hostEnv, "")
+	testCases := []struct {
+		cfg      config.Config
+		expected string
+	}{
+		{config.Config{Repository: "owner/repo"}, "releases/owner/repo/latest"},
+		{config.Config{Repository: "owner/repo", Tag: "release/1.0"}, "releases/owner/repo/release%2F1.0"},
+		{config.Config{Repository: "owner/repo", Author: "bot"}, "releases/owner/repo/latest%20--author%20bot"},
+	}
+	for _, tc := range testCases {
+		if got := releaseCacheKey(tc.cfg); got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, got)
+		}
+	}
+
+	t.Setenv(hostEnv, "GHE.example.com")
+	if got := releaseCacheKey(config.Config{Repository: "owner/repo"}); got != "releases/ghe.example.com/owner/repo/latest" {
+		t.Errorf("Expected the host in the key, got %q", got)
+	}
+}
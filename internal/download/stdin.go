@@ -0,0 +1,133 @@
+package download
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// errNoMatchingAssets reports a release without assets matching the pattern
+var errNoMatchingAssets = errors.New("no assets found")
+
+// readRepositories reads newline-separated repositories, ignoring blank
+// lines and "#" comments
+func readRepositories(r io.Reader) ([]string, error) {
+	var repos []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		repos = append(repos, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repositories from stdin: %w", err)
+	}
+	return repos, nil
+}
+
+// downloadFromRepositories runs the download for every repository read from
+// r, writing the files of each into its own "owner/repo" directory below
+// the target directory. Repositories without a release or without matching
+// assets are skipped; other failures follow --continue-on-error, and once a
+// host failed --max-host-failures times in a row its remaining repositories
+// are skipped and reported as failed. With --max-duration the repositories
+// not finished in time are left for --resume, which replaces the input.
+// --preflight first checks that the token can read every repository.
+func downloadFromRepositories(cfg config.Config, r io.Reader) (runResult, error) {
+	if cfg.Repository != "" {
+		return runResult{}, fmt.Errorf("--stdin cannot be combined with a repository argument")
+	}
+
+	resume, err := decodeResumeToken(cfg.Resume)
+	if err != nil {
+		return runResult{}, err
+	}
+
+	repos := resume.Repositories
+	if repos == nil {
+		if repos, err = readRepositories(r); err != nil {
+			return runResult{}, err
+		}
+	}
+	if len(repos) == 0 {
+		return runResult{}, fmt.Errorf("no repositories given on stdin")
+	}
+	if cfg.Preflight {
+		if err := preflight(repos); err != nil {
+			return runResult{}, err
+		}
+		fmt.Println()
+	}
+
+	result := runResult{UpToDate: true}
+	var failures []AssetFailure
+	breaker := newCircuitBreaker(cfg.MaxHostFailures)
+	for i, repo := range repos {
+		if expired(cfg.Deadline) {
+			return result, newBudgetError(repos[i:], resumeToken{Repositories: repos[i:]})
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+
+		parsed, err := repository.Parse(repo)
+		if err != nil {
+			return result, fmt.Errorf("invalid repository '%s': %w", repo, err)
+		}
+
+		if err := breaker.check(parsed.Host); err != nil {
+			fmt.Printf("Skipping %s: %v\n", repo, err)
+			failures = append(failures, AssetFailure{Name: repo, Err: err})
+			continue
+		}
+
+		repoCfg := cfg
+		repoCfg.Repository = repo
+		repoCfg.Resume = ""
+		repoCfg.Directory = filepath.Join(cfg.Directory, parsed.Owner, parsed.Name)
+
+		repoResult, err := downloadFromRelease(repoCfg)
+		result.Paths = append(result.Paths, repoResult.Paths...)
+		result.Changed = result.Changed || repoResult.Changed
+		result.UpToDate = result.UpToDate && repoResult.UpToDate
+
+		var httpErr *api.HTTPError
+		var budgetErr *BudgetError
+		switch {
+		case errors.As(err, &budgetErr):
+			// The interrupted repository is resumed in full
+			return result, newBudgetError(repos[i:], resumeToken{Repositories: repos[i:]})
+		case err == nil:
+			breaker.record(parsed.Host, false)
+		case errors.Is(err, errNoMatchingAssets),
+			errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound:
+			fmt.Printf("Skipping %s: %v\n", repo, err)
+		case cfg.ContinueOnError:
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", repo, err)
+			failures = append(failures, AssetFailure{Name: repo, Err: err})
+			breaker.record(parsed.Host, true)
+		default:
+			return result, fmt.Errorf("%s: %w", repo, err)
+		}
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d of %d repositories failed:\n", len(failures), len(repos))
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "  - %s: %v\n", failure.Name, failure.Err)
+		}
+		return result, &DownloadError{Total: len(repos), Failures: failures}
+	}
+	return result, nil
+}
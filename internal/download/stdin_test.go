@@ -0,0 +1,47 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+func TestReadRepositories(t *testing.T) {
+	input := "owner/one\n\n# comment\n  owner/two  \nhost.example.com/owner/three\n"
+
+	repos, err := readRepositories(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"owner/one", "owner/two", "host.example.com/owner/three"}
+	if strings.Join(repos, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, repos)
+	}
+}
+
+func TestDownloadFromRepositories_InvalidInput(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      config.Config
+		input    string
+		expected string
+	}{
+		{"repository argument", config.Config{Repository: "owner/repo"}, "owner/other\n", "cannot be combined"},
+		{"empty input", config.Config{}, "\n# nothing\n", "no repositories"},
+		{"invalid repository", config.Config{}, "not a repo\n", "invalid repository"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := downloadFromRepositories(tc.cfg, strings.NewReader(tc.input))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error to contain %q, got %q", tc.expected, err.Error())
+			}
+		})
+	}
+}
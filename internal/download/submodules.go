@@ -0,0 +1,197 @@
+package download
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/extract"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/middleware"
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// maxSubmoduleDepth bounds recursion through nested submodules
+const maxSubmoduleDepth = 5
+
+// submodule is an entry of a .gitmodules file
+type submodule struct {
+	Name string
+	Path string
+	URL  string
+}
+
+// parseGitmodules reads the submodule sections of a .gitmodules file
+func parseGitmodules(content string) []submodule {
+	var modules []submodule
+	var current *submodule
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			current = nil
+			section := strings.Trim(line, "[]")
+			if name, ok := strings.CutPrefix(section, "submodule "); ok {
+				modules = append(modules, submodule{Name: strings.Trim(name, `"`)})
+				current = &modules[len(modules)-1]
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || current == nil {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "path":
+			current.Path = strings.TrimSpace(value)
+		case "url":
+			current.URL = strings.TrimSpace(value)
+		}
+	}
+
+	return modules
+}
+
+// submoduleRepo resolves a submodule URL to an owner/repo on host. Relative
+// URLs are resolved against the parent repository.
+func submoduleRepo(rawURL, parent, host string) (string, error) {
+	if strings.HasPrefix(rawURL, "./") || strings.HasPrefix(rawURL, "../") {
+		resolved := path.Clean(path.Join(parent, rawURL))
+		resolved = strings.TrimSuffix(resolved, ".git")
+		if strings.HasPrefix(resolved, "../") || strings.Count(resolved, "/") != 1 {
+			return "", fmt.Errorf("cannot resolve relative submodule url %q", rawURL)
+		}
+		return resolved, nil
+	}
+
+	repo, err := repository.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse submodule url %q: %w", rawURL, err)
+	}
+	if !strings.EqualFold(repo.Host, host) {
+		return "", fmt.Errorf("submodule %q is not hosted on %s", rawURL, host)
+	}
+	return repo.Owner + "/" + repo.Name, nil
+}
+
+// extractWithSubmodules extracts the source tarball of repo at the release
+// tag into dir, then places the archive of every submodule at its pinned
+// commit into its path, producing a complete snapshot. It returns the paths
+// written.
+func extractWithSubmodules(client *api.RESTClient, archives middleware.Doer, cfg config.Config, release *github.Release) ([]string, error) {
+	if cfg.Archive != "tar.gz" || !cfg.Extract {
+		return nil, fmt.Errorf("--with-submodules requires --archive tar.gz and --extract")
+	}
+
+	ref := cfg.Tag
+	if ref == "" {
+		ref = release.TagName
+	}
+
+	commit, err := github.GetCommit(client, cfg.Repository, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+
+	// Without the "owner-repo-sha" directory submodule paths line up with
+	// the extracted tree
+	opts := extractOptions(cfg)
+	opts.StripComponents = 1
+	opts.Include = ""
+
+	written, err := extractArchive(archives, cfg.Repository, commit.SHA, cfg.Archive, cfg.Directory, opts)
+	if err != nil {
+		return written, err
+	}
+	if err := resolvePointers(cfg, written); err != nil {
+		return written, err
+	}
+
+	parent, err := repository.Parse(cfg.Repository)
+	if err != nil {
+		return written, fmt.Errorf("invalid repository format: %w", err)
+	}
+
+	modules, err := extractSubmodules(client, archives, parent.Host, cfg.Repository, commit.SHA, cfg.Directory, cfg.ResolveLFS, 1)
+	return append(written, modules...), err
+}
+
+// extractSubmodules places the submodules recorded in repo at sha below dir,
+// recursing into nested submodules up to maxSubmoduleDepth. LFS pointers are
+// resolved against each submodule's own repository when resolveLFS is set.
+// It returns the paths written.
+func extractSubmodules(client *api.RESTClient, archives middleware.Doer, host, repo, sha, dir string, resolveLFS bool, depth int) ([]string, error) {
+	entry, err := github.GetContentEntry(client, repo, ".gitmodules", sha)
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitmodules of %s: %w", repo, err)
+	}
+
+	content, err := entry.Decoded()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode .gitmodules of %s: %w", repo, err)
+	}
+
+	var written []string
+	for _, module := range parseGitmodules(string(content)) {
+		if module.Path == "" || module.URL == "" {
+			continue
+		}
+
+		pinned, err := github.GetContentEntry(client, repo, module.Path, sha)
+		if err != nil {
+			return written, fmt.Errorf("failed to resolve submodule %s: %w", module.Path, err)
+		}
+		if pinned.Type != "submodule" {
+			return written, fmt.Errorf("%s is not a submodule at %s", module.Path, shortSHA(sha))
+		}
+
+		moduleRepo, err := submoduleRepo(module.URL, repo, host)
+		if err != nil {
+			fmt.Printf("Skipping submodule %s: %v\n", module.Path, err)
+			continue
+		}
+
+		target, err := extract.SafePath(dir, module.Path)
+		if err != nil {
+			return written, err
+		}
+
+		fmt.Printf("Submodule %s: %s@%s\n", module.Path, moduleRepo, shortSHA(pinned.SHA))
+		opts := extract.Options{StripComponents: 1}
+		files, err := extractArchive(archives, moduleRepo, pinned.SHA, "tar.gz", target, opts)
+		written = append(written, files...)
+		if err != nil {
+			return written, fmt.Errorf("failed to extract submodule %s: %w", module.Path, err)
+		}
+		if resolveLFS {
+			if err := resolveLFSPointers(moduleRepo, target, files); err != nil {
+				return written, err
+			}
+		}
+
+		if depth < maxSubmoduleDepth {
+			nested, err := extractSubmodules(client, archives, host, moduleRepo, pinned.SHA, target, resolveLFS, depth+1)
+			written = append(written, nested...)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
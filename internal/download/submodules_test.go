@@ -0,0 +1,62 @@
+package download
+
+import (
+	"testing"
+)
+
+func TestParseGitmodules(t *testing.T) {
+	content := `# comment
+[submodule "vendor/lib"]
+	path = vendor/lib
+	url = https://github.com/owner/lib.git
+[core]
+	path = ignored
+[submodule "docs"]
+	path = docs
+	url = ../docs.git
+`
+
+	modules := parseGitmodules(content)
+	if len(modules) != 2 {
+		t.Fatalf("Expected 2 submodules, got %d", len(modules))
+	}
+	if modules[0].Name != "vendor/lib" || modules[0].Path != "vendor/lib" || modules[0].URL != "https://github.com/owner/lib.git" {
+		t.Errorf("Unexpected first submodule: %+v", modules[0])
+	}
+	if modules[1].Path != "docs" || modules[1].URL != "../docs.git" {
+		t.Errorf("Unexpected second submodule: %+v", modules[1])
+	}
+}
+
+func TestSubmoduleRepo(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected string
+		wantErr  bool
+	}{
+		{"https://github.com/owner/lib.git", "owner/lib", false},
+		{"git@github.com:owner/lib.git", "owner/lib", false},
+		{"../docs.git", "owner/docs", false},
+		{"../../other/tool", "other/tool", false},
+		{"https://gitlab.com/owner/lib.git", "", true},
+		{"../../../escape", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			got, err := submoduleRepo(tc.url, "owner/repo", "github.com")
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package download
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// Armor headers of the signature formats git uses for tags
+const (
+	pgpSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+	sshSignatureHeader = "-----BEGIN SSH SIGNATURE-----"
+)
+
+// sshSignerPrincipal is the principal the key given to --tag-signing-key is
+// allowed for when checking SSH signatures
+const sshSignerPrincipal = "gh-download"
+
+// verifyTagSignature refuses releases whose tag is not an annotated tag with
+// a signature GitHub verified. With --tag-signing-key the signature is also
+// checked locally, so trust does not rest on GitHub alone.
+func verifyTagSignature(client github.HTTPClient, cfg config.Config, release *github.Release) error {
+	tag, err := github.GetAnnotatedTag(client, cfg.Repository, release.TagName)
+	if err != nil {
+		return fmt.Errorf("failed to get tag %s: %w", release.TagName, err)
+	}
+
+	verification := tag.Verification
+	if verification.Signature == "" {
+		return fmt.Errorf("tag %s is not signed", release.TagName)
+	}
+	if !verification.Verified {
+		return fmt.Errorf("signature of tag %s is not verified by GitHub: %s", release.TagName, verification.Reason)
+	}
+
+	if cfg.TagSigningKey != "" {
+		if err := checkSignature(verification, cfg.TagSigningKey); err != nil {
+			return fmt.Errorf("signature of tag %s does not match %s: %w", release.TagName, cfg.TagSigningKey, err)
+		}
+	}
+
+	fmt.Printf("Tag %s is signed by %s (%s)\n", release.TagName, tag.Tagger.Name, verification.Reason)
+	return nil
+}
+
+// checkSignature checks a signature against the public key in keyPath, with
+// gpg for PGP signatures and ssh-keygen for SSH signatures as git does
+func checkSignature(verification github.Verification, keyPath string) error {
+	dir, err := os.MkdirTemp("", "gh-download-tag-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() {
+		if removeErr := os.RemoveAll(dir); removeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", dir, removeErr)
+		}
+	}()
+
+	payload := filepath.Join(dir, "payload")
+	signature := filepath.Join(dir, "payload.sig")
+	if err := os.WriteFile(payload, []byte(verification.Payload), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(signature, []byte(verification.Signature), 0600); err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(verification.Signature, pgpSignatureHeader):
+		home := filepath.Join(dir, "gnupg")
+		if err := os.Mkdir(home, 0700); err != nil {
+			return err
+		}
+		if err := runVerifier(nil, "gpg", "--homedir", home, "--batch", "--quiet", "--import", keyPath); err != nil {
+			return err
+		}
+		return runVerifier(nil, "gpg", "--homedir", home, "--batch", "--verify", signature, payload)
+	case strings.HasPrefix(verification.Signature, sshSignatureHeader):
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return err
+		}
+		signers := filepath.Join(dir, "allowed_signers")
+		if err := os.WriteFile(signers, []byte(sshSignerPrincipal+" "+strings.TrimSpace(string(key))+"\n"), 0600); err != nil {
+			return err
+		}
+		return runVerifier(strings.NewReader(verification.Payload), "ssh-keygen", "-Y", "verify", "-f", signers, "-I", sshSignerPrincipal, "-n", "git", "-s", signature)
+	default:
+		return fmt.Errorf("unsupported signature format")
+	}
+}
+
+// runVerifier runs a signature verification program, returning its output
+// as the error when it fails
+func runVerifier(stdin *strings.Reader, program string, args ...string) error {
+	if _, err := exec.LookPath(program); err != nil {
+		return fmt.Errorf("%s is required to check signatures locally: %w", program, err)
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(program, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", program, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package download
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+// tagClient serves a tag ref and tag object for signature tests
+type tagClient struct {
+	refType string
+	tag     github.Tag
+}
+
+func (c *tagClient) Get(endpoint string, response interface{}) error {
+	switch response := response.(type) {
+	case *github.Ref:
+		response.Object.Type = c.refType
+		response.Object.SHA = "abc123"
+	case *github.Tag:
+		*response = c.tag
+	}
+	return nil
+}
+
+func TestVerifyTagSignature(t *testing.T) {
+	release := &github.Release{TagName: "v1.0.0"}
+	cfg := config.Config{Repository: "owner/repo", VerifyTagSignature: true}
+	signature := sshSignatureHeader + "\n...\n"
+
+	testCases := []struct {
+		name     string
+		client   *tagClient
+		expected string
+	}{
+		{"verified", &tagClient{refType: "tag", tag: github.Tag{Verification: github.Verification{Verified: true, Reason: "valid", Signature: signature}}}, ""},
+		{"lightweight", &tagClient{refType: "commit"}, "lightweight"},
+		{"unsigned", &tagClient{refType: "tag", tag: github.Tag{Verification: github.Verification{Reason: "unsigned"}}}, "not signed"},
+		{"unverified", &tagClient{refType: "tag", tag: github.Tag{Verification: github.Verification{Reason: "unknown_key", Signature: signature}}}, "unknown_key"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyTagSignature(tc.client, cfg, release)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckSignature_SSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not available")
+	}
+
+	dir := t.TempDir()
+	sshKeygen := func(args ...string) {
+		t.Helper()
+		if output, err := exec.Command("ssh-keygen", args...).CombinedOutput(); err != nil {
+			t.Fatalf("ssh-keygen failed: %v: %s", err, output)
+		}
+	}
+	key := filepath.Join(dir, "key")
+	other := filepath.Join(dir, "other")
+	sshKeygen("-q", "-t", "ed25519", "-N", "", "-f", key)
+	sshKeygen("-q", "-t", "ed25519", "-N", "", "-f", other)
+
+	payload := "object abc123\ntype commit\ntag v1.0.0\n\nRelease v1.0.0\n"
+	payloadPath := filepath.Join(dir, "payload")
+	if err := os.WriteFile(payloadPath, []byte(payload), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sshKeygen("-Y", "sign", "-q", "-f", key, "-n", "git", payloadPath)
+	signature, err := os.ReadFile(payloadPath + ".sig")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification := github.Verification{Verified: true, Payload: payload, Signature: string(signature)}
+	if err := checkSignature(verification, key+".pub"); err != nil {
+		t.Errorf("Expected signature to match, got %v", err)
+	}
+	if err := checkSignature(verification, other+".pub"); err == nil {
+		t.Error("Expected signature not to match another key, got nil")
+	}
+
+	verification.Payload += "tampered"
+	if err := checkSignature(verification, key+".pub"); err == nil {
+		t.Error("Expected tampered payload to fail, got nil")
+	}
+}
+
+func TestCheckSignature_UnsupportedFormat(t *testing.T) {
+	err := checkSignature(github.Verification{Signature: "garbage"}, "key.pub")
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Expected unsupported format error, got %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/manifest"
+	"github.com/23prime/gh-download/internal/state"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// maxTapSize bounds the manifest fetched from a URL tap
+const maxTapSize = 4 << 20
+
+// Tap manages the taps: tool manifests fetched from a repository or URL so a
+// team can curate the releases and patterns its developers install. The
+// subcommand and its arguments are the positional arguments after "tap".
+func Tap(cfg config.Config) error {
+	args := positionalArgs(cfg)
+	if len(args) == 0 {
+		return fmt.Errorf("tap requires a subcommand: add, update, remove or list")
+	}
+
+	dir := state.TapsPath()
+	switch args[0] {
+	case "add":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: gh download tap add <owner/repo[/path] | url> [name]")
+		}
+		var name string
+		if len(args) == 3 {
+			name = args[2]
+		}
+		return addTap(dir, args[1], name)
+	case "update":
+		return updateTaps(dir, args[1:])
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: gh download tap remove <name>")
+		}
+		if err := state.RemoveTap(dir, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed tap %s\n", args[1])
+		return nil
+	case "list":
+		return listTaps(dir)
+	default:
+		return fmt.Errorf("unknown tap subcommand %q: use add, update, remove or list", args[0])
+	}
+}
+
+// positionalArgs returns the positional arguments of commands that do not
+// take a repository, which the parser spreads over the repository, tag and
+// remaining arguments
+func positionalArgs(cfg config.Config) []string {
+	var args []string
+	for _, arg := range append([]string{cfg.Repository, cfg.Tag}, cfg.Args...) {
+		if arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// isURLSource reports whether a tap source is a URL rather than a repository
+func isURLSource(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// checkTapURL rejects tap URLs without TLS, whose manifests, which decide
+// what gets installed, could be swapped in transit
+func checkTapURL(source string) error {
+	if isURLSource(source) && !strings.HasPrefix(source, "https://") {
+		return fmt.Errorf("tap URL must use https, got %q", source)
+	}
+	return nil
+}
+
+// defaultTapName names a tap after its repository; URL taps need a name
+func defaultTapName(source string) (string, error) {
+	if isURLSource(source) {
+		return "", fmt.Errorf("a name is required for a tap fetched from a URL")
+	}
+	parts := strings.SplitN(source, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("tap source must be owner/repo[/path] or a URL, got %q", source)
+	}
+	return parts[0] + "/" + parts[1], nil
+}
+
+func addTap(dir, source, name string) error {
+	if err := checkTapURL(source); err != nil {
+		return err
+	}
+	if name == "" {
+		var err error
+		if name, err = defaultTapName(source); err != nil {
+			return err
+		}
+	}
+	if err := state.ValidateTapName(name); err != nil {
+		return err
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	return refreshTap(client, newPlainHTTPClient(), dir, state.Tap{Name: name, Source: source})
+}
+
+// updateTaps refetches the named taps, or every tap when names is empty
+func updateTaps(dir string, names []string) error {
+	taps, err := state.ReadTaps(dir)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		byName := make(map[string]state.Tap, len(taps))
+		for _, tap := range taps {
+			byName[tap.Name] = tap
+		}
+		taps = taps[:0]
+		for _, name := range names {
+			tap, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("no tap named %s", name)
+			}
+			taps = append(taps, tap)
+		}
+	}
+	if len(taps) == 0 {
+		fmt.Println("No taps to update")
+		return nil
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	for _, tap := range taps {
+		if err := refreshTap(client, newPlainHTTPClient(), dir, tap); err != nil {
+			return fmt.Errorf("failed to update tap %s: %w", tap.Name, err)
+		}
+	}
+	return nil
+}
+
+// refreshTap fetches the manifest of a tap and stores it if it is valid, so
+// a broken upstream change never replaces a working manifest
+func refreshTap(client github.HTTPClient, httpClient *http.Client, dir string, tap state.Tap) error {
+	data, err := fetchTap(client, httpClient, tap.Source)
+	if err != nil {
+		return err
+	}
+	m, err := manifest.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	tap.Updated = time.Now().UTC()
+	if err := state.WriteTap(dir, tap, data); err != nil {
+		return err
+	}
+	fmt.Printf("Tapped %s from %s (%d tools)\n", tap.Name, tap.Source, len(m.Tools))
+	return nil
+}
+
+// fetchTap returns the manifest of a tap source: the file at a URL, or
+// gh-download.yml (or the given path) on the default branch of a repository
+func fetchTap(client github.HTTPClient, httpClient *http.Client, source string) ([]byte, error) {
+	if isURLSource(source) {
+		if err := checkTapURL(source); err != nil {
+			return nil, err
+		}
+		return fetchTapURL(httpClient, source)
+	}
+
+	parts := strings.SplitN(source, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("tap source must be owner/repo[/path] or a URL, got %q", source)
+	}
+	path := manifest.FileName
+	if len(parts) == 3 && parts[2] != "" {
+		path = parts[2]
+	}
+
+	entry, err := github.GetContentEntry(client, parts[0]+"/"+parts[1], path, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from %s/%s: %w", path, parts[0], parts[1], err)
+	}
+	if entry.Type != "file" {
+		return nil, fmt.Errorf("%s in %s/%s is not a file", path, parts[0], parts[1])
+	}
+	return entry.Decoded()
+}
+
+func fetchTapURL(httpClient *http.Client, url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTapSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	if len(data) > maxTapSize {
+		return nil, fmt.Errorf("manifest at %s is larger than %d bytes", url, maxTapSize)
+	}
+	return data, nil
+}
+
+func listTaps(dir string) error {
+	taps, err := state.ReadTaps(dir)
+	if err != nil {
+		return err
+	}
+	if len(taps) == 0 {
+		fmt.Println("No taps added")
+		return nil
+	}
+
+	for _, tap := range taps {
+		fmt.Printf("%s  %s (updated %s)\n", tap.Name, tap.Source, tap.Updated.Local().Format("2006-01-02 15:04"))
+		m, err := manifest.Load(state.TapManifestPath(dir, tap.Name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: tap %s: %v\n", tap.Name, err)
+			continue
+		}
+		for _, name := range m.Names() {
+			tool := m.Tools[name]
+			line := fmt.Sprintf("  %s -> %s", name, tool.Repo)
+			if tool.Tag != "" {
+				line += " " + tool.Tag
+			}
+			if tool.Pattern != "" {
+				line += fmt.Sprintf(" (%s)", tool.Pattern)
+			}
+			if tool.Description != "" {
+				line += "  " + tool.Description
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// findTapTool looks a tool up in the taps in dir and returns it with the name
+// of its tap. A name qualified with its tap, as in "org/tools:gh", picks the
+// tool of that tap; a plain name must be defined by exactly one tap.
+func findTapTool(dir, name string) (manifest.Tool, string, error) {
+	tapName, toolName, qualified := strings.Cut(name, ":")
+	if !qualified {
+		tapName, toolName = "", name
+	}
+
+	taps, err := state.ReadTaps(dir)
+	if err != nil {
+		return manifest.Tool{}, "", err
+	}
+
+	var found []string
+	var tool manifest.Tool
+	for _, tap := range taps {
+		if tapName != "" && tap.Name != tapName {
+			continue
+		}
+		m, err := manifest.Load(state.TapManifestPath(dir, tap.Name))
+		if err != nil {
+			return manifest.Tool{}, "", fmt.Errorf("tap %s: %w", tap.Name, err)
+		}
+		if t, ok := m.Tools[toolName]; ok {
+			tool = t
+			found = append(found, tap.Name)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return manifest.Tool{}, "", fmt.Errorf("%s is neither owner/repo nor a tool of a tap", name)
+	case 1:
+		return tool, found[0], nil
+	default:
+		return manifest.Tool{}, "", fmt.Errorf("tool %s is defined by several taps (%s); qualify it as <tap>:%s", toolName, strings.Join(found, ", "), toolName)
+	}
+}
+
+// applyTapTool resolves a repository argument that names a tool of a tap
+// into the repository, tag, pattern and directory the tap curates. Flags
+// given on the command line take precedence over the tap. A tool on another
+// host becomes a HOST/OWNER/REPO repository.
+func applyTapTool(cfg config.Config, dir string) (config.Config, error) {
+	if slashes := strings.Count(cfg.Repository, "/"); (slashes == 1 || slashes == 2) && !strings.Contains(cfg.Repository, ":") {
+		return cfg, nil
+	}
+
+	tool, tapName, err := findTapTool(dir, cfg.Repository)
+	if err != nil {
+		return cfg, err
+	}
+	repo := tool.Repo
+	if tool.Host != "" {
+		repo = tool.Host + "/" + tool.Repo
+	}
+	fmt.Printf("Using %s from tap %s: %s\n", cfg.Repository, tapName, repo)
+
+	cfg.Repository = repo
+	if cfg.Tag == "" {
+		cfg.Tag = tool.Tag
+	}
+	if tool.Pattern != "" && !cfg.IsSet("pattern") {
+		cfg.Pattern = tool.Pattern
+	}
+	if tool.Dir != "" && !cfg.IsSet("dir") {
+		cfg.Directory = expandHome(tool.Dir)
+	}
+	return cfg, nil
+}
+
+// expandHome replaces a leading "~/" with the home directory, as a manifest
+// shared between users cannot name it
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
@@ -0,0 +1,183 @@
+package download
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/state"
+)
+
+const tapManifest = `tools:
+  gh:
+    repo: cli/cli
+    tag: v2.40.0
+    pattern: "*_linux_amd64.tar.gz"
+    dir: ~/bin
+  jq:
+    repo: jqlang/jq
+  deploy:
+    repo: platform/deploy
+    host: ghe.example.com
+`
+
+func writeTestTap(t *testing.T, dir, name, manifest string) {
+	t.Helper()
+	if err := state.WriteTap(dir, state.Tap{Name: name, Source: name}, []byte(manifest)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchTap_Repository(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString([]byte(tapManifest))
+	client := jsonClient{
+		"repos/org/tools/contents/gh-download.yml?ref=": `{"type":"file","encoding":"base64","content":"` + content + `"}`,
+		"repos/org/tools/contents/teams/ops.yml?ref=":   `{"type":"file","encoding":"base64","content":"` + content + `"}`,
+		"repos/org/tools/contents/teams?ref=":           `{"type":"dir"}`,
+	}
+
+	for _, source := range []string{"org/tools", "org/tools/teams/ops.yml"} {
+		data, err := fetchTap(client, nil, source)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", source, err)
+		}
+		if string(data) != tapManifest {
+			t.Errorf("Unexpected manifest for %s: %q", source, data)
+		}
+	}
+
+	if _, err := fetchTap(client, nil, "org/tools/teams"); err == nil {
+		t.Error("Expected error for a directory, got nil")
+	}
+	if _, err := fetchTap(client, nil, "org"); err == nil {
+		t.Error("Expected error for an invalid source, got nil")
+	}
+}
+
+func TestFetchTap_URL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tools.yml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(tapManifest))
+	}))
+	defer server.Close()
+
+	data, err := fetchTap(nil, server.Client(), server.URL+"/tools.yml")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(data) != tapManifest {
+		t.Errorf("Unexpected manifest %q", data)
+	}
+
+	if _, err := fetchTap(nil, server.Client(), server.URL+"/missing.yml"); err == nil {
+		t.Error("Expected error for a missing manifest, got nil")
+	}
+}
+
+func TestFetchTap_InsecureURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request over plain HTTP, got %s", r.URL)
+	}))
+	defer server.Close()
+
+	_, err := fetchTap(nil, server.Client(), server.URL+"/tools.yml")
+	if err == nil || !strings.Contains(err.Error(), "must use https") {
+		t.Errorf("Expected error for a plain HTTP tap, got %v", err)
+	}
+	if err := addTap(t.TempDir(), "http://example.com/tools.yml", "team"); err == nil || !strings.Contains(err.Error(), "must use https") {
+		t.Errorf("Expected error for a plain HTTP tap, got %v", err)
+	}
+}
+
+func TestRefreshTap_KeepsManifestOnInvalidUpdate(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTap(t, dir, "org/tools", tapManifest)
+
+	content := base64.StdEncoding.EncodeToString([]byte("tools:\n  gh:\n    repo: broken\n"))
+	client := jsonClient{
+		"repos/org/tools/contents/gh-download.yml?ref=": `{"type":"file","encoding":"base64","content":"` + content + `"}`,
+	}
+	if err := refreshTap(client, nil, dir, state.Tap{Name: "org/tools", Source: "org/tools"}); err == nil {
+		t.Fatal("Expected error for an invalid manifest, got nil")
+	}
+
+	data, err := os.ReadFile(state.TapManifestPath(dir, "org/tools"))
+	if err != nil || string(data) != tapManifest {
+		t.Errorf("Expected the previous manifest to be kept, got %q, %v", data, err)
+	}
+}
+
+func TestDefaultTapName(t *testing.T) {
+	if name, err := defaultTapName("org/tools/teams/ops.yml"); err != nil || name != "org/tools" {
+		t.Errorf("Expected org/tools, got %q, %v", name, err)
+	}
+	if _, err := defaultTapName("https://example.com/tools.yml"); err == nil {
+		t.Error("Expected error for a URL without a name, got nil")
+	}
+}
+
+func TestPositionalArgs(t *testing.T) {
+	cfg := config.Config{Repository: "add", Tag: "https://example.com/tools.yml", Args: []string{"team"}}
+	if got := strings.Join(positionalArgs(cfg), " "); got != "add https://example.com/tools.yml team" {
+		t.Errorf("Unexpected arguments %q", got)
+	}
+}
+
+func TestApplyTapTool(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	writeTestTap(t, dir, "org/tools", tapManifest)
+
+	cfg, err := applyTapTool(config.Config{Repository: "gh", Pattern: "*", Directory: "."}, dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Repository != "cli/cli" || cfg.Tag != "v2.40.0" || cfg.Pattern != "*_linux_amd64.tar.gz" || cfg.Directory != filepath.Join(home, "bin") {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	explicit := config.Config{
+		Repository: "org/tools:gh",
+		Tag:        "v2.41.0",
+		Pattern:    "*.deb",
+		Directory:  "out",
+		Flags:      []config.Flag{{Name: "pattern", Value: "*.deb"}, {Name: "dir", Value: "out"}},
+	}
+	cfg, err = applyTapTool(explicit, dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Repository != "cli/cli" || cfg.Tag != "v2.41.0" || cfg.Pattern != "*.deb" || cfg.Directory != "out" {
+		t.Errorf("Expected flags to take precedence, got %+v", cfg)
+	}
+
+	if cfg, err := applyTapTool(config.Config{Repository: "deploy"}, dir); err != nil || cfg.Repository != "ghe.example.com/platform/deploy" {
+		t.Errorf("Expected the tool host in the repository, got %+v, %v", cfg, err)
+	}
+
+	for _, repo := range []string{"owner/repo", "ghe.example.com/owner/repo"} {
+		if cfg, err := applyTapTool(config.Config{Repository: repo}, dir); err != nil || cfg.Repository != repo {
+			t.Errorf("Expected repositories to be left alone, got %+v, %v", cfg, err)
+		}
+	}
+	if _, err := applyTapTool(config.Config{Repository: "unknown"}, dir); err == nil {
+		t.Error("Expected error for an unknown tool, got nil")
+	}
+
+	writeTestTap(t, dir, "team", "tools:\n  gh:\n    repo: cli/cli\n")
+	if _, err := applyTapTool(config.Config{Repository: "gh"}, dir); err == nil || !strings.Contains(err.Error(), "several taps") {
+		t.Errorf("Expected an ambiguity error, got %v", err)
+	}
+	if cfg, err := applyTapTool(config.Config{Repository: "team:gh"}, dir); err != nil || cfg.Repository != "cli/cli" {
+		t.Errorf("Expected the qualified tool to resolve, got %+v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,35 @@
+package download
+
+import (
+	"strings"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/tmpl"
+)
+
+// templateData returns the metadata of a release available in templates
+func templateData(repository string, release *github.Release) tmpl.Data {
+	owner, name, _ := strings.Cut(repository, "/")
+	data := tmpl.Data{Repo: repository, Owner: owner, Name: name, Tag: release.TagName}
+	if published, err := time.Parse(time.RFC3339, release.PublishedAt); err == nil {
+		data.PublishedAt = published
+	}
+	return data
+}
+
+// expandTemplates expands the templates in --dir and --pattern, including
+// those coming from a tap, for the resolved release
+func expandTemplates(cfg config.Config, release *github.Release) (config.Config, error) {
+	data := templateData(cfg.Repository, release)
+
+	var err error
+	if cfg.Directory, err = tmpl.Render(cfg.Directory, data); err != nil {
+		return cfg, err
+	}
+	if cfg.Pattern, err = tmpl.Render(cfg.Pattern, data); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
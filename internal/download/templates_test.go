@@ -0,0 +1,33 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestExpandTemplates(t *testing.T) {
+	release := &github.Release{TagName: "v1.4.0", PublishedAt: "2024-05-01T12:00:00Z"}
+	cfg := config.Config{
+		Repository: "owner/Tool",
+		Directory:  "tools/{{.Name | lower}}/v{{semverMajor .Tag}}/{{date \"2006-01\" .PublishedAt}}",
+		Pattern:    "tool-{{.Tag | trimPrefix \"v\"}}-*",
+	}
+
+	expanded, err := expandTemplates(cfg, release)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expanded.Directory != "tools/tool/v1/2024-05" {
+		t.Errorf("Expected directory tools/tool/v1/2024-05, got %q", expanded.Directory)
+	}
+	if expanded.Pattern != "tool-1.4.0-*" {
+		t.Errorf("Expected pattern tool-1.4.0-*, got %q", expanded.Pattern)
+	}
+
+	cfg.Pattern = "{{.Asset}}"
+	if _, err := expandTemplates(cfg, release); err == nil {
+		t.Error("Expected error for an unknown field, got nil")
+	}
+}
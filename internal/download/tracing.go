@@ -0,0 +1,44 @@
+package download
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/tracing"
+)
+
+// tracer records the spans of the run for --otel-endpoint; nil, and so a
+// no-op, when tracing is off
+var tracer *tracing.Tracer
+
+// startTracing starts tracing the run when --otel-endpoint is set and returns
+// a function that exports the spans once the run ended with the given error
+func startTracing(cfg config.Config) (func(error), error) {
+	if cfg.OTelEndpoint == "" {
+		return func(error) {}, nil
+	}
+
+	t, err := tracing.New(cfg.OTelEndpoint, "gh-download",
+		tracing.String("repository", cfg.Repository),
+		tracing.String("tag", cfg.Tag),
+		tracing.String("pattern", cfg.Pattern))
+	if err != nil {
+		return nil, err
+	}
+	tracer = t
+
+	return func(runErr error) {
+		// A collector being down must not fail the download
+		if err := tracer.Shutdown(runErr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		tracer = nil
+	}, nil
+}
+
+// startAssetSpan starts a span for an operation on asset
+func startAssetSpan(name string, asset github.Asset) *tracing.Span {
+	return tracer.Start(name, tracing.String("asset", asset.Name), tracing.Int("size", int64(asset.Size)))
+}
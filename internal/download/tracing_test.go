@@ -0,0 +1,62 @@
+package download
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+)
+
+func TestStartTracing(t *testing.T) {
+	var spans []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						Name string `json:"name"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatal(err)
+		}
+		for _, span := range request.ResourceSpans[0].ScopeSpans[0].Spans {
+			spans = append(spans, span.Name)
+		}
+	}))
+	defer server.Close()
+
+	end, err := startTracing(config.Config{OTelEndpoint: server.URL, Repository: "owner/repo"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	startAssetSpan("transfer", github.Asset{Name: "tool.zip", Size: 10}).End(nil)
+	end(nil)
+
+	if len(spans) != 2 || spans[0] != "transfer" || spans[1] != "gh-download" {
+		t.Errorf("Expected transfer and root spans, got %v", spans)
+	}
+	if tracer != nil {
+		t.Error("Expected tracing to be off after the run")
+	}
+}
+
+func TestStartTracing_Disabled(t *testing.T) {
+	end, err := startTracing(config.Config{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tracer != nil {
+		t.Error("Expected no tracer without --otel-endpoint")
+	}
+	end(nil)
+
+	if _, err := startTracing(config.Config{OTelEndpoint: "localhost:4318"}); err == nil {
+		t.Error("Expected error for an invalid endpoint, got nil")
+	}
+}
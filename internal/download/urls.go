@@ -0,0 +1,194 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// resolveURLs returns the download URLs of the matching assets, or of the
+// source archive with --archive, instead of downloading them. Browser URLs
+// are returned unless signed is set, in which case the pre-authorized URLs
+// the API redirects to are resolved; those work without credentials for a
+// few minutes. With --api-urls the REST API URLs are returned as they are.
+func resolveURLs(cfg config.Config, release *github.Release) ([]string, error) {
+	var redirects *http.Client
+	if cfg.SignedURLs {
+		client, err := newRedirectClient()
+		if err != nil {
+			return nil, err
+		}
+		redirects = client
+	}
+
+	if cfg.Archive != "" {
+		url, err := archiveURL(redirects, cfg.Repository, cfg.Tag, cfg.Archive, cfg.APIURLs)
+		if err != nil {
+			return nil, err
+		}
+		return []string{url}, nil
+	}
+
+	matchingAssets, err := github.FilterAssets(release.Assets, cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter assets: %w", err)
+	}
+	if len(matchingAssets) == 0 {
+		return nil, fmt.Errorf("%w matching pattern '%s'", errNoMatchingAssets, cfg.Pattern)
+	}
+
+	matchingAssets, err = orderAssets(matchingAssets, cfg.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(matchingAssets))
+	for _, asset := range matchingAssets {
+		if cfg.APIURLs {
+			urls = append(urls, asset.URL)
+			continue
+		}
+		if redirects == nil {
+			urls = append(urls, asset.BrowserDownloadURL)
+			continue
+		}
+		url, err := redirectLocation(redirects, asset.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve URL of %s: %w", asset.Name, err)
+		}
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+// presignURLs resolves the signed URLs of the matching assets, like
+// --urls-only --signed, and reports on stderr when the first of them
+// expires, so they can be handed to a system without GitHub credentials
+func presignURLs(cfg config.Config, release *github.Release) ([]string, error) {
+	cfg.SignedURLs = true
+	urls, err := resolveURLs(cfg, release)
+	if err != nil {
+		return nil, err
+	}
+
+	var earliest time.Time
+	for _, signed := range urls {
+		expiry, ok := signedURLExpiry(signed)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: could not tell when a signed URL expires; GitHub keeps them valid for a few minutes\n")
+			return urls, nil
+		}
+		if earliest.IsZero() || expiry.Before(earliest) {
+			earliest = expiry
+		}
+	}
+	if !earliest.IsZero() {
+		fmt.Fprintf(os.Stderr, "Signed URLs are valid until %s (%s)\n", earliest.Local().Format(time.RFC3339), time.Until(earliest).Round(time.Second))
+	}
+	return urls, nil
+}
+
+// signedURLExpiry returns when a pre-authorized URL expires, read from its
+// S3 (X-Amz-Date and X-Amz-Expires) or Azure (se) signature parameters
+func signedURLExpiry(signed string) (time.Time, bool) {
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		return time.Time{}, false
+	}
+	query := parsed.Query()
+
+	if date, expires := query.Get("X-Amz-Date"), query.Get("X-Amz-Expires"); date != "" && expires != "" {
+		signedAt, err := time.Parse("20060102T150405Z", date)
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds, err := strconv.Atoi(expires)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return signedAt.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if se := query.Get("se"); se != "" {
+		expiry, err := time.Parse(time.RFC3339, se)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return expiry, true
+	}
+	return time.Time{}, false
+}
+
+// archiveURL returns the URL of a source archive: the web URL, the REST API
+// URL with api, or the pre-authorized URL the API redirects to when
+// redirects is set
+func archiveURL(redirects *http.Client, repo, tag, archiveFormat string, api bool) (string, error) {
+	parsed, err := repository.Parse(repo)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository format: %w", err)
+	}
+
+	endpoint, _, err := archiveEndpoint(parsed.Owner+"/"+parsed.Name, tag, archiveFormat)
+	if err != nil {
+		return "", err
+	}
+
+	if api {
+		return apiURL(parsed.Host, endpoint), nil
+	}
+	if redirects != nil {
+		return redirectLocation(redirects, apiURL(parsed.Host, endpoint))
+	}
+
+	ref := tag
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return fmt.Sprintf("https://%s/%s/%s/archive/%s.%s", parsed.Host, parsed.Owner, parsed.Name, ref, archiveFormat), nil
+}
+
+// apiURL returns the REST API URL of an endpoint on host
+func apiURL(host, endpoint string) string {
+	if host == "github.com" {
+		return "https://api.github.com/" + endpoint
+	}
+	return fmt.Sprintf("https://%s/api/v3/%s", host, endpoint)
+}
+
+// newRedirectClient returns an authenticated client for raw asset content
+// that does not follow redirects, to read pre-authorized URLs
+func newRedirectClient() (*http.Client, error) {
+	client, err := newAssetHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download client: %w", err)
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client, nil
+}
+
+// redirectLocation requests url without following redirects and returns
+// the location it redirects to
+func redirectLocation(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+	}
+
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+		return "", fmt.Errorf("expected a redirect, got %s", resp.Status)
+	}
+	return location, nil
+}
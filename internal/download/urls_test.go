@@ -0,0 +1,146 @@
+package download
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/config"
+)
+
+func TestRedirectLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/asset" {
+			http.Redirect(w, r, "https://objects.example.com/signed?token=abc", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	location, err := redirectLocation(client, server.URL+"/asset")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if location != "https://objects.example.com/signed?token=abc" {
+		t.Errorf("Unexpected location %q", location)
+	}
+
+	if _, err := redirectLocation(client, server.URL+"/other"); err == nil {
+		t.Error("Expected error without a redirect, got nil")
+	}
+}
+
+func TestSignedURLExpiry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"s3", "https://objects.githubusercontent.com/a?X-Amz-Date=20250102T030405Z&X-Amz-Expires=300&X-Amz-Signature=x", "2025-01-02T03:09:05Z"},
+		{"azure", "https://release-assets.githubusercontent.com/a?sv=2018-11-09&se=2025-06-10T18%3A25%3A37Z&sig=x", "2025-06-10T18:25:37Z"},
+		{"unsigned", "https://github.com/owner/repo/releases/download/v1/a", ""},
+		{"invalid", "https://objects.githubusercontent.com/a?X-Amz-Date=yesterday&X-Amz-Expires=300", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expiry, ok := signedURLExpiry(tc.url)
+			if tc.expected == "" {
+				if ok {
+					t.Errorf("Expected no expiry, got %v", expiry)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("Expected an expiry")
+			}
+			if got := expiry.UTC().Format(time.RFC3339); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestArchiveURL(t *testing.T) {
+	testCases := []struct {
+		repo     string
+		tag      string
+		format   string
+		api      bool
+		expected string
+	}{
+		{"owner/repo", "v1.0.0", "tar.gz", false, "https://github.com/owner/repo/archive/v1.0.0.tar.gz"},
+		{"owner/repo", "", "zip", false, "https://github.com/owner/repo/archive/HEAD.zip"},
+		{"ghe.example.com/owner/repo", "v2", "zip", false, "https://ghe.example.com/owner/repo/archive/v2.zip"},
+		{"owner/repo", "v1.0.0", "tar.gz", true, "https://api.github.com/repos/owner/repo/tarball/v1.0.0"},
+		{"ghe.example.com/owner/repo", "v2", "zip", true, "https://ghe.example.com/api/v3/repos/owner/repo/zipball/v2"},
+	}
+
+	for _, tc := range testCases {
+		got, err := archiveURL(nil, tc.repo, tc.tag, tc.format, tc.api)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestCheckOutputModes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      config.Config
+		expected string
+	}{
+		{"none", config.Config{}, ""},
+		{"urls only", config.Config{URLsOnly: true, SignedURLs: true}, ""},
+		{"conflict", config.Config{URLsOnly: true, PrintPaths: true}, "--print-paths and --urls-only cannot be used together"},
+		{"signed alone", config.Config{SignedURLs: true}, "--signed requires --urls-only or --emit-commands"},
+		{"presign", config.Config{Presign: true}, ""},
+		{"preflight without stdin", config.Config{Preflight: true}, "--preflight requires --stdin"},
+		{"presign and urls only", config.Config{Presign: true, URLsOnly: true}, "--presign and --urls-only cannot be used together"},
+		{"api urls", config.Config{URLsOnly: true, APIURLs: true}, ""},
+		{"api urls alone", config.Config{APIURLs: true}, "--api-urls requires --urls-only"},
+		{"api and signed urls", config.Config{URLsOnly: true, APIURLs: true, SignedURLs: true}, "--api-urls and --signed cannot be used together"},
+		{"quiet", config.Config{Quiet: true}, ""},
+		{"quiet and json", config.Config{Quiet: true, IdempotentJSON: true}, "--idempotent-json and --quiet cannot be used together"},
+		{"quiet list", config.Config{Quiet: true, List: true}, "--quiet cannot be used with --list or --releases"},
+		{"stdout", config.Config{Stdout: true}, ""},
+		{"stdout and print paths", config.Config{Stdout: true, PrintPaths: true}, "--print-paths and --stdout cannot be used together"},
+		{"stdout extract", config.Config{Stdout: true, Extract: true}, "--stdout writes a single asset"},
+		{"output", config.Config{Output: "app"}, ""},
+		{"output and stdout", config.Config{Output: "app", Stdout: true}, "--output names a single asset"},
+		{"output archive", config.Config{Output: "src.zip", Archive: "zip"}, "--output names a single asset"},
+		{"output template", config.Config{OutputTemplate: "{tag}/{name}"}, ""},
+		{"output template and output", config.Config{OutputTemplate: "{tag}/{name}", Output: "app"}, "--output-template cannot be used with --output"},
+		{"output template placeholder", config.Config{OutputTemplate: "{platform}/{name}"}, "unknown placeholder {platform}"},
+		{"mirror to fork", config.Config{MirrorToFork: "org"}, ""},
+		{"copy url", config.Config{List: true, CopyURL: true}, ""},
+		{"copy url without list", config.Config{CopyURL: true}, "--copy-url requires --list"},
+		{"mirror to fork extract", config.Config{MirrorToFork: "org", Extract: true}, "--mirror-to-fork republishes the assets"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkOutputModes(tc.cfg)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
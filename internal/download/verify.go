@@ -0,0 +1,444 @@
+package download
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// checksumsAuto makes --checksum-file use every checksum asset of the release
+const checksumsAuto = "auto"
+
+// checksumAlgorithm returns the algorithm selected by --checksum-algo
+func checksumAlgorithm(cfg config.Config) (checksum.Algorithm, error) {
+	if cfg.ChecksumAlgo == "" {
+		return checksum.SHA256, nil
+	}
+	return checksum.Parse(cfg.ChecksumAlgo)
+}
+
+// checksumSet holds the entries of the checksum files of a release by the
+// name of the asset they apply to
+type checksumSet struct {
+	entries map[string][]checksum.Entry
+	// files are the names of the checksum assets read
+	files []string
+}
+
+// loadChecksums fetches the checksum assets selected by --checksum-file, a
+// glob pattern or "auto" for every asset named like a checksum file, and
+// merges their entries. --verify is "auto" unless --checksum-file is given.
+// Digests whose algorithm the file does not reveal are read with fallback
+// when their length fits. It returns nil without either flag.
+func loadChecksums(cfg config.Config, release *github.Release, fallback checksum.Algorithm) (*checksumSet, error) {
+	if cfg.Verify && cfg.ChecksumFile == "" {
+		cfg.ChecksumFile = checksumsAuto
+	}
+	if cfg.ChecksumFile == "" {
+		return nil, nil
+	}
+
+	var assets []github.Asset
+	if cfg.ChecksumFile == checksumsAuto {
+		for _, asset := range release.Assets {
+			if checksum.IsChecksumFile(asset.Name) {
+				assets = append(assets, asset)
+			}
+		}
+	} else {
+		matching, err := github.FilterAssets(release.Assets, cfg.ChecksumFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter checksum files: %w", err)
+		}
+		assets = matching
+	}
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("no checksum files matching '%s' in release %s", cfg.ChecksumFile, release.TagName)
+	}
+
+	client, err := newAssetRESTClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download client: %w", err)
+	}
+
+	set := &checksumSet{entries: make(map[string][]checksum.Entry)}
+	for _, asset := range assets {
+		entries, err := fetchChecksumFile(client, asset, fallback)
+		if err != nil {
+			return nil, err
+		}
+		for name, entry := range entries {
+			set.entries[name] = append(set.entries[name], entry)
+		}
+		set.files = append(set.files, asset.Name)
+	}
+	return set, nil
+}
+
+func fetchChecksumFile(client *api.RESTClient, asset github.Asset, fallback checksum.Algorithm) (map[string]checksum.Entry, error) {
+	resp, err := client.Request("GET", asset.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	return checksum.ParseFile(asset.Name, resp.Body, fallback)
+}
+
+// pinnedChecksum parses --checksum, which pins the digest of the only asset
+// of the run, or returns nil without it
+func pinnedChecksum(cfg config.Config, assets []github.Asset) (*checksum.Entry, error) {
+	if cfg.Checksum == "" {
+		return nil, nil
+	}
+	entry, err := checksum.ParseDigest(cfg.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	if len(assets) != 1 {
+		return nil, fmt.Errorf("--checksum requires the pattern to match exactly one asset, got %d", len(assets))
+	}
+	if cfg.Extract {
+		return nil, fmt.Errorf("--checksum cannot be used with --extract")
+	}
+	entry.Source = "--checksum"
+	return &entry, nil
+}
+
+// verifyPinned compares the file of a downloaded asset with the --checksum
+// digest and deletes it on a mismatch, so that nothing unverified is left
+// behind. sum holds the digest computed while streaming the file, or nil to
+// read the file.
+func verifyPinned(pinned *checksum.Entry, asset github.Asset, path string, sum hash.Hash) error {
+	if pinned == nil {
+		return nil
+	}
+
+	var actual string
+	if sum != nil {
+		actual = hex.EncodeToString(sum.Sum(nil))
+	} else {
+		digest, err := checksum.File(path, pinned.Algorithm)
+		if err != nil {
+			return err
+		}
+		actual = digest
+	}
+	if actual == pinned.Digest {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", path, err)
+	}
+	return &VerificationError{Err: fmt.Errorf("%w: %s has %s %s, expected %s from %s; the file was deleted",
+		retry.ErrChecksum, asset.Name, pinned.Algorithm, actual, pinned.Digest, pinned.Source)}
+}
+
+// verify compares the file of a downloaded asset with every entry for it
+// that uses an algorithm trusted to verify. An asset without such an entry
+// fails, since nothing vouches for it; the checksum files themselves are
+// exempt.
+func (s *checksumSet) verify(asset github.Asset, path string) error {
+	if s == nil || slices.Contains(s.files, asset.Name) {
+		return nil
+	}
+
+	var verified bool
+	for _, entry := range s.entries[asset.Name] {
+		if !entry.Algorithm.CanVerify() {
+			continue
+		}
+		actual, err := checksum.File(path, entry.Algorithm)
+		if err != nil {
+			return err
+		}
+		if actual != entry.Digest {
+			return &VerificationError{Err: fmt.Errorf("%w: %s has %s %s, expected %s from %s",
+				retry.ErrChecksum, asset.Name, entry.Algorithm, actual, entry.Digest, entry.Source)}
+		}
+		verified = true
+	}
+
+	if !verified {
+		return &VerificationError{Err: fmt.Errorf("no usable checksum entry for %s in %s", asset.Name, strings.Join(s.files, ", "))}
+	}
+	return nil
+}
+
+// writeSidecar writes the --emit-sidecar-checksums file of a downloaded file
+// through the root of its directory
+func writeSidecar(root *confine.Root, path string, algorithm checksum.Algorithm) error {
+	if algorithm == "" {
+		return nil
+	}
+	sidecar, err := checksum.WriteSidecar(root, path, algorithm)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", filepath.Base(sidecar))
+	return nil
+}
+
+// Outcomes of verifying a local file
+const (
+	verifyPass       = "PASS"
+	verifyFail       = "FAIL"
+	verifyMissing    = "MISSING"
+	verifyUnverified = "UNVERIFIED"
+)
+
+// assetCheck is the outcome of verifying the local file of an asset
+type assetCheck struct {
+	Asset  github.Asset
+	Path   string
+	Status string
+	Err    error
+}
+
+// Verify checks the files previously downloaded into the target directory
+// against the digests GitHub reports for the matching assets and against the
+// --checksum-file entries, without downloading anything, and prints a report.
+// Missing or corrupted files fail the command with a *VerificationError,
+// unless --repair downloads them again and they pass.
+func Verify(cfg config.Config) error {
+	if cfg.Repository == "" {
+		return fmt.Errorf("repository is required")
+	}
+
+	client, err := newRESTClient(api.ClientOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+
+	release, err := github.GetRelease(client, cfg.Repository, cfg.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to get release: %w", err)
+	}
+	if cfg, err = expandTemplates(cfg, release); err != nil {
+		return err
+	}
+
+	printReleaseHeader(release, newResolvedRelease(release, cfg.Tag, ""), cfg.Repository)
+
+	matchingAssets, err := github.FilterAssets(release.Assets, cfg.Pattern)
+	if err != nil {
+		return fmt.Errorf("failed to filter assets: %w", err)
+	}
+	if len(matchingAssets) == 0 {
+		return fmt.Errorf("%w matching pattern '%s'", errNoMatchingAssets, cfg.Pattern)
+	}
+
+	algorithm, err := checksumAlgorithm(cfg)
+	if err != nil {
+		return err
+	}
+	checksums, err := loadChecksums(cfg, release, algorithm)
+	if err != nil {
+		return err
+	}
+
+	checks := checkAssets(matchingAssets, cfg.Directory, checksums)
+	if cfg.Repair {
+		unlock, err := lockDirectory(cfg)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		if checks, err = repairChecks(cfg, checks, checksums); err != nil {
+			return err
+		}
+	}
+	return reportChecks(checks)
+}
+
+// checkAssets verifies the local file of every asset in dir
+func checkAssets(assets []github.Asset, dir string, checksums *checksumSet) []assetCheck {
+	fileNames := assetFileNames(assets)
+	checks := make([]assetCheck, 0, len(assets))
+	for _, asset := range assets {
+		checks = append(checks, checkAsset(asset, filepath.Join(dir, fileNames[asset.ID]), checksums))
+	}
+	return checks
+}
+
+func checkAsset(asset github.Asset, path string, checksums *checksumSet) assetCheck {
+	check := assetCheck{Asset: asset, Path: path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		check.Status, check.Err = verifyMissing, err
+		return check
+	}
+	if asset.Size > 0 && info.Size() != int64(asset.Size) {
+		check.Status = verifyFail
+		check.Err = &VerificationError{Err: fmt.Errorf("%s has %d bytes, GitHub reports %d", asset.Name, info.Size(), asset.Size)}
+		return check
+	}
+
+	checked := checksums != nil
+	if err := checksums.verify(asset, path); err != nil {
+		check.Status, check.Err = verifyFail, err
+		return check
+	}
+
+	verified, err := verifyGitHubDigest(asset, path, nil)
+	if err != nil {
+		check.Status, check.Err = verifyFail, err
+		return check
+	}
+
+	check.Status = verifyPass
+	checked = checked || verified
+	if !checked {
+		check.Status = verifyUnverified
+	}
+	return check
+}
+
+// githubDigest returns the algorithm and hex digest GitHub reports for an
+// asset, or false when there is none trusted to verify
+func githubDigest(asset github.Asset) (checksum.Algorithm, string, bool) {
+	name, expected, ok := strings.Cut(asset.Digest, ":")
+	if !ok {
+		return "", "", false
+	}
+	algorithm, err := checksum.Parse(name)
+	if err != nil || !algorithm.CanVerify() {
+		return "", "", false
+	}
+	return algorithm, expected, true
+}
+
+// githubDigestHash returns a hash to compute the digest GitHub reports for
+// an asset while it downloads, or nil when there is none
+func githubDigestHash(asset github.Asset) (hash.Hash, error) {
+	algorithm, _, ok := githubDigest(asset)
+	if !ok {
+		return nil, nil
+	}
+	return algorithm.New()
+}
+
+// verifyGitHubDigest compares the file of an asset with the digest GitHub
+// reports for it, returning false when there is none to compare with. sum
+// holds the digest computed while streaming the file (see githubDigestHash),
+// or nil to read the file.
+func verifyGitHubDigest(asset github.Asset, path string, sum hash.Hash) (bool, error) {
+	algorithm, expected, ok := githubDigest(asset)
+	if !ok {
+		return false, nil
+	}
+
+	var actual string
+	if sum != nil {
+		actual = hex.EncodeToString(sum.Sum(nil))
+	} else {
+		digest, err := checksum.File(path, algorithm)
+		if err != nil {
+			return false, err
+		}
+		actual = digest
+	}
+	if actual != expected {
+		return false, &VerificationError{Err: fmt.Errorf("%w: %s has %s %s, GitHub reports %s", retry.ErrChecksum, asset.Name, algorithm, actual, expected)}
+	}
+	return true, nil
+}
+
+// discardUnverified deletes a downloaded file that failed verification, so
+// that the next run downloads it again rather than finding it in the way,
+// and notes the deletion in the error
+func discardUnverified(root *confine.Root, path string, err error) error {
+	if removeErr := root.Remove(path); removeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", path, removeErr)
+		return err
+	}
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		verr.Err = fmt.Errorf("%w; the file was deleted", verr.Err)
+	}
+	return err
+}
+
+// repairChecks downloads the assets of failed or missing files again, with
+// the configured retries, and returns the checks with those files verified
+// anew
+func repairChecks(cfg config.Config, checks []assetCheck, checksums *checksumSet) ([]assetCheck, error) {
+	var assets []github.Asset
+	fileNames := make(map[int]string)
+	for _, check := range checks {
+		if check.Status == verifyFail || check.Status == verifyMissing {
+			assets = append(assets, check.Asset)
+			fileNames[check.Asset.ID] = filepath.Base(check.Path)
+		}
+	}
+	if len(assets) == 0 {
+		return checks, nil
+	}
+
+	fmt.Printf("\nRepairing %d files:\n", len(assets))
+	run := newAssetRun(len(assets), true)
+	run.checksums = checksums
+	run.fileNames = fileNames
+	run.verifyDigest = true
+	policy, err := retryPolicy(cfg)
+	if err != nil {
+		return checks, err
+	}
+	run.policy = policy
+	if err := downloadAssets(run, assets, cfg.Directory, false); err != nil {
+		return checks, err
+	}
+	fmt.Println()
+
+	repaired := make([]assetCheck, len(checks))
+	for i, check := range checks {
+		repaired[i] = check
+		if check.Status == verifyFail || check.Status == verifyMissing {
+			repaired[i] = checkAsset(check.Asset, check.Path, checksums)
+		}
+	}
+	return repaired, nil
+}
+
+// reportChecks prints one line per check and a summary, and returns a
+// *VerificationError when any file is missing or failed
+func reportChecks(checks []assetCheck) error {
+	counts := make(map[string]int)
+	for _, check := range checks {
+		counts[check.Status]++
+		switch check.Status {
+		case verifyPass:
+			fmt.Printf("%-10s %s\n", check.Status, check.Path)
+		case verifyUnverified:
+			fmt.Printf("%-10s %s (no digest or checksum entry)\n", check.Status, check.Path)
+		default:
+			fmt.Printf("%-10s %s: %v\n", check.Status, check.Path, check.Err)
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed, %d missing, %d unverified\n",
+		counts[verifyPass], counts[verifyFail], counts[verifyMissing], counts[verifyUnverified])
+
+	if bad := counts[verifyFail] + counts[verifyMissing]; bad > 0 {
+		return &VerificationError{Err: fmt.Errorf("%d of %d files failed verification", bad, len(checks))}
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package download
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"github.com/23prime/gh-download/internal/config"
+	"github.com/23prime/gh-download/internal/confine"
+	"github.com/23prime/gh-download/internal/github"
+	"github.com/23prime/gh-download/internal/retry"
+)
+
+func TestChecksumSet_Verify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sha512Digest := "e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629"
+	set := &checksumSet{
+		entries: map[string][]checksum.Entry{
+			"app.txt": {
+				{Algorithm: checksum.SHA512, Digest: sha512Digest, Source: "SHA512SUMS"},
+				{Algorithm: checksum.MD5, Digest: "00", Source: "MD5SUMS"},
+			},
+			"other.txt":  {{Algorithm: checksum.SHA512, Digest: "00", Source: "SHA512SUMS"}},
+			"legacy.txt": {{Algorithm: checksum.MD5, Digest: "b1946ac92492d2347c6235b4d2611184", Source: "MD5SUMS"}},
+		},
+		files: []string{"SHA512SUMS", "MD5SUMS"},
+	}
+
+	if err := set.verify(github.Asset{Name: "app.txt"}, path); err != nil {
+		t.Errorf("Expected a matching checksum with md5 entries ignored, got %v", err)
+	}
+
+	err := set.verify(github.Asset{Name: "other.txt"}, path)
+	var verr *VerificationError
+	if !errors.As(err, &verr) || !errors.Is(err, retry.ErrChecksum) {
+		t.Errorf("Expected a checksum VerificationError, got %v", err)
+	}
+
+	for _, name := range []string{"unlisted.txt", "legacy.txt"} {
+		err := set.verify(github.Asset{Name: name}, path)
+		if !errors.As(err, &verr) || errors.Is(err, retry.ErrChecksum) || !strings.Contains(err.Error(), "no usable checksum entry") {
+			t.Errorf("%s: expected a missing entry VerificationError, got %v", name, err)
+		}
+	}
+
+	if err := set.verify(github.Asset{Name: "MD5SUMS"}, path); err != nil {
+		t.Errorf("Expected checksum files to be exempt, got %v", err)
+	}
+	var none *checksumSet
+	if err := none.verify(github.Asset{Name: "other.txt"}, path); err != nil {
+		t.Errorf("Expected no verification without checksums, got %v", err)
+	}
+}
+
+func TestLoadChecksums_NoFiles(t *testing.T) {
+	release := &github.Release{TagName: "v1.0.0", Assets: []github.Asset{{Name: "app.zip"}}}
+
+	for _, pattern := range []string{"SHA256SUMS", "*.sha256", checksumsAuto} {
+		_, err := loadChecksums(config.Config{ChecksumFile: pattern}, release, checksum.SHA256)
+		if err == nil || !strings.Contains(err.Error(), "no checksum files") {
+			t.Errorf("%s: expected no checksum files error, got %v", pattern, err)
+		}
+	}
+
+	if set, err := loadChecksums(config.Config{}, release, checksum.SHA256); set != nil || err != nil {
+		t.Errorf("Expected nothing without --checksum-file, got %v %v", set, err)
+	}
+
+	_, err := loadChecksums(config.Config{Verify: true}, release, checksum.SHA256)
+	if err == nil || !strings.Contains(err.Error(), "no checksum files matching 'auto'") {
+		t.Errorf("Expected --verify to look for every checksum file, got %v", err)
+	}
+}
+
+func TestChecksumAlgorithm(t *testing.T) {
+	if a, err := checksumAlgorithm(config.Config{}); err != nil || a != checksum.SHA256 {
+		t.Errorf("Expected sha256 by default, got %q %v", a, err)
+	}
+	if _, err := checksumAlgorithm(config.Config{ChecksumAlgo: "crc32"}); err == nil {
+		t.Error("Expected error for an unknown algorithm, got nil")
+	}
+}
+
+func TestWriteSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := confine.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeRoot(root)
+
+	if err := writeSidecar(root, path, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected no sidecar without an algorithm, got %d files", len(entries))
+	}
+
+	if err := writeSidecar(root, path, checksum.SHA512); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := os.Stat(path + ".sha512"); err != nil {
+		t.Errorf("Expected app.txt.sha512 to be written, got %v", err)
+	}
+}
+
+func TestCheckAsset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest := "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	wrong := &checksumSet{
+		entries: map[string][]checksum.Entry{"app.txt": {{Algorithm: checksum.SHA256, Digest: strings.Repeat("0", 64), Source: "SHA256SUMS"}}},
+		files:   []string{"SHA256SUMS"},
+	}
+
+	testCases := []struct {
+		name      string
+		asset     github.Asset
+		path      string
+		checksums *checksumSet
+		expected  string
+	}{
+		{"github digest", github.Asset{Name: "app.txt", Digest: digest}, path, nil, verifyPass},
+		{"wrong github digest", github.Asset{Name: "app.txt", Digest: "sha256:" + strings.Repeat("0", 64)}, path, nil, verifyFail},
+		{"wrong checksum entry", github.Asset{Name: "app.txt", Digest: digest}, path, wrong, verifyFail},
+		{"no digest", github.Asset{Name: "app.txt"}, path, nil, verifyUnverified},
+		{"wrong size", github.Asset{Name: "app.txt", Digest: digest, Size: 100}, path, nil, verifyFail},
+		{"missing", github.Asset{Name: "gone.txt", Digest: digest}, filepath.Join(dir, "gone.txt"), nil, verifyMissing},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checkAsset(tc.asset, tc.path, tc.checksums)
+			if check.Status != tc.expected {
+				t.Errorf("Expected %s, got %s (%v)", tc.expected, check.Status, check.Err)
+			}
+		})
+	}
+}
+
+func TestReportChecks(t *testing.T) {
+	if err := reportChecks([]assetCheck{{Status: verifyPass}, {Status: verifyUnverified}}); err != nil {
+		t.Errorf("Expected unverified files not to fail, got %v", err)
+	}
+
+	err := reportChecks([]assetCheck{{Status: verifyPass}, {Status: verifyMissing, Err: os.ErrNotExist}})
+	if ExitCode(err) != ExitVerificationFailed {
+		t.Errorf("Expected exit code %d, got %d (%v)", ExitVerificationFailed, ExitCode(err), err)
+	}
+}
+
+func TestVerifyGitHubDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checked, err := verifyGitHubDigest(github.Asset{Name: "app.txt", Digest: "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"}, path, nil)
+	if !checked || err != nil {
+		t.Errorf("Expected digest to match, got checked=%t err=%v", checked, err)
+	}
+
+	_, err = verifyGitHubDigest(github.Asset{Name: "app.txt", Digest: "sha256:" + strings.Repeat("0", 64)}, path, nil)
+	if !errors.Is(err, retry.ErrChecksum) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+
+	for _, digest := range []string{"", "md5:b1946ac92492d2347c6235b4d2611184", "unknown:00"} {
+		if checked, err := verifyGitHubDigest(github.Asset{Name: "app.txt", Digest: digest}, path, nil); checked || err != nil {
+			t.Errorf("Expected %q to be skipped, got checked=%t err=%v", digest, checked, err)
+		}
+	}
+}
+
+func TestVerifyGitHubDigest_Streamed(t *testing.T) {
+	asset := github.Asset{Name: "app.txt", Digest: "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"}
+	sum, err := githubDigestHash(asset)
+	if err != nil || sum == nil {
+		t.Fatalf("Expected a hash for the digest, got %v, %v", sum, err)
+	}
+	sum.Write([]byte("hello\n"))
+
+	// The file is not read when the digest was computed while streaming
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	if checked, err := verifyGitHubDigest(asset, missing, sum); !checked || err != nil {
+		t.Errorf("Expected the streamed digest to match, got checked=%t err=%v", checked, err)
+	}
+
+	if sum, err := githubDigestHash(github.Asset{Name: "old.txt"}); sum != nil || err != nil {
+		t.Errorf("Expected no hash without a digest, got %v, %v", sum, err)
+	}
+}
+
+func TestRepairChecks_NothingToRepair(t *testing.T) {
+	checks := []assetCheck{{Status: verifyPass}, {Status: verifyUnverified}}
+	repaired, err := repairChecks(config.Config{Repair: true}, checks, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(repaired) != len(checks) || repaired[0].Status != verifyPass || repaired[1].Status != verifyUnverified {
+		t.Errorf("Expected checks to be unchanged, got %+v", repaired)
+	}
+}
+
+func TestRetryPolicy_Repair(t *testing.T) {
+	policy, err := retryPolicy(config.Config{Retries: 2, RetryOn: retry.DefaultClasses})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Classes[retry.Checksum] {
+		t.Error("Expected checksum failures not to be retried by default")
+	}
+
+	policy, err = retryPolicy(config.Config{Retries: 2, RetryOn: retry.DefaultClasses, Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.Classes[retry.Checksum] {
+		t.Error("Expected --repair to retry checksum failures")
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	cfg := config.Config{Retries: 2, RetryOn: retry.DefaultClasses, RetryDelay: 5 * time.Second, Flags: []config.Flag{{Name: "retry-delay", Value: "5s"}}}
+	policy, err := retryPolicy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Backoff != 5*time.Second {
+		t.Errorf("Expected a 5s backoff, got %s", policy.Backoff)
+	}
+
+	cfg.RetryDelay = -time.Second
+	if _, err := retryPolicy(cfg); err == nil {
+		t.Error("Expected error for a negative retry delay, got nil")
+	}
+}
+
+func TestPinnedChecksum(t *testing.T) {
+	digest := "sha256:" + sha256Hex("content")
+	one := []github.Asset{{Name: "app.zip"}}
+
+	pinned, err := pinnedChecksum(config.Config{Checksum: digest}, one)
+	if err != nil || pinned == nil || pinned.Digest != sha256Hex("content") {
+		t.Fatalf("Expected the pinned digest, got %+v, %v", pinned, err)
+	}
+	if pinned, err := pinnedChecksum(config.Config{}, one); pinned != nil || err != nil {
+		t.Errorf("Expected nothing without --checksum, got %+v, %v", pinned, err)
+	}
+	if _, err := pinnedChecksum(config.Config{Checksum: digest}, append(one, github.Asset{Name: "app.tar.gz"})); err == nil || !strings.Contains(err.Error(), "exactly one asset") {
+		t.Errorf("Expected error for several assets, got %v", err)
+	}
+	if _, err := pinnedChecksum(config.Config{Checksum: digest, Extract: true}, one); err == nil {
+		t.Error("Expected error with --extract, got nil")
+	}
+	if _, err := pinnedChecksum(config.Config{Checksum: "sha256:abc"}, one); err == nil {
+		t.Error("Expected error for an invalid digest, got nil")
+	}
+}
+
+func TestVerifyPinned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.zip")
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	asset := github.Asset{Name: "app.zip"}
+
+	pinned := &checksum.Entry{Algorithm: checksum.SHA256, Digest: sha256Hex("content"), Source: "--checksum"}
+	if err := verifyPinned(pinned, asset, path, nil); err != nil {
+		t.Errorf("Expected a match, got %v", err)
+	}
+
+	pinned.Digest = sha256Hex("other")
+	err := verifyPinned(pinned, asset, path, nil)
+	var verr *VerificationError
+	if !errors.As(err, &verr) || !errors.Is(err, retry.ErrChecksum) {
+		t.Errorf("Expected a checksum verification error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the mismatching file to be deleted, got %v", err)
+	}
+}
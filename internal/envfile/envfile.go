@@ -0,0 +1,69 @@
+package envfile
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Write writes key=value pairs in the format of GitHub Actions'
+// $GITHUB_OUTPUT and $GITHUB_ENV files. Multi-line values use the
+// "key<<DELIMITER" form with a random delimiter that cannot occur in the
+// value.
+func Write(w io.Writer, pairs [][2]string) error {
+	for _, pair := range pairs {
+		key, value := pair[0], pair[1]
+		if key == "" || strings.ContainsAny(key, "=\r\n") {
+			return fmt.Errorf("invalid output name '%s'", key)
+		}
+
+		var err error
+		if strings.ContainsAny(value, "\r\n") {
+			var delimiter string
+			delimiter, err = newDelimiter(value)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", key, delimiter, value, delimiter)
+		} else {
+			_, err = fmt.Fprintf(w, "%s=%s\n", key, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append appends pairs to the file at path, creating it if needed
+func Append(path string, pairs [][2]string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	err = Write(file, pairs)
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func newDelimiter(value string) (string, error) {
+	for {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		delimiter := "ghadelimiter_" + hex.EncodeToString(buf)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package envfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, [][2]string{
+		{"tag", "v1.0.0"},
+		{"paths", "/tmp/a\n/tmp/b"},
+		{"up-to-date", "false"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	pattern := regexp.MustCompile(`^tag=v1\.0\.0\npaths<<(ghadelimiter_[0-9a-f]+)\n/tmp/a\n/tmp/b\n(ghadelimiter_[0-9a-f]+)\nup-to-date=false\n$`)
+	match := pattern.FindStringSubmatch(buf.String())
+	if match == nil {
+		t.Fatalf("Unexpected output:\n%s", buf.String())
+	}
+	if match[1] != match[2] {
+		t.Errorf("Expected matching delimiters, got %q and %q", match[1], match[2])
+	}
+}
+
+func TestWrite_InvalidKey(t *testing.T) {
+	for _, key := range []string{"", "a=b", "a\nb"} {
+		if err := Write(&bytes.Buffer{}, [][2]string{{key, "value"}}); err == nil {
+			t.Errorf("Expected error for key %q, got nil", key)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(path, []byte("existing=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Append(path, [][2]string{{"tag", "v1"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "existing=1\n") || !strings.HasSuffix(string(data), "tag=v1\n") {
+		t.Errorf("Expected output to be appended, got %q", data)
+	}
+}
@@ -0,0 +1,356 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/confine"
+)
+
+// Options select and place archive members
+type Options struct {
+	// Include is a glob pattern matched against member names after
+	// StripComponents is applied. "**" matches any number of directories.
+	Include string
+	// StripComponents removes this many leading path elements from member
+	// names; members with fewer elements are skipped.
+	StripComponents int
+}
+
+// SafePath joins an archive member name onto dir, refusing names that would
+// escape dir (absolute paths, ".." segments, volume names).
+func SafePath(dir, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("unsafe path in archive: %q", name)
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("unsafe path in archive: %q", name)
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(cleaned)), nil
+}
+
+// Match reports whether an archive member matches pattern. An empty pattern
+// matches everything. Patterns without a slash are matched against the base
+// name so that "tool" finds "tool-1.0/bin/tool"; "**" matches any number of
+// directories, so "subdir/**" selects a whole tree.
+func Match(pattern, name string) (bool, error) {
+	if pattern == "" || pattern == "*" {
+		return true, nil
+	}
+
+	name = strings.TrimSuffix(name, "/")
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+
+	match, err := matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	return match, nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every possible number of directories for "**"
+			for i := 0; i <= len(name); i++ {
+				match, err := matchSegments(pattern[1:], name[i:])
+				if err != nil || match {
+					return match, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		match, err := path.Match(pattern[0], name[0])
+		if err != nil || !match {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// strip removes the leading path elements of name
+func (o Options) strip(name string) (string, bool) {
+	name = strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "./")
+	if o.StripComponents <= 0 {
+		return name, name != ""
+	}
+
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	if len(parts) <= o.StripComponents {
+		return "", false
+	}
+	return strings.Join(parts[o.StripComponents:], "/"), true
+}
+
+// target resolves where a member is written, or reports it is not selected
+func (o Options) target(dir, name string) (string, bool, error) {
+	stripped, ok := o.strip(name)
+	if !ok {
+		return "", false, nil
+	}
+
+	match, err := Match(o.Include, stripped)
+	if err != nil || !match {
+		return "", false, err
+	}
+
+	target, err := SafePath(dir, stripped)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Refuse to write through symlinks created by earlier members
+	if err := checkNoSymlinks(dir, target); err != nil {
+		return "", false, err
+	}
+
+	return target, true, nil
+}
+
+// checkNoSymlinks makes sure no existing parent of target below dir is a
+// symlink, which would redirect writes outside of dir.
+func checkNoSymlinks(dir, target string) error {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+
+	current := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("unsafe path in archive: %q is a symlink", current)
+		}
+	}
+	return nil
+}
+
+// openRoot confines the writes of an extraction to dir, so that whatever
+// the checks on member names miss, nothing lands outside of it
+func openRoot(dir string) (*confine.Root, func(), error) {
+	root, err := confine.Open(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return root, func() {
+		if err := root.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", dir, err)
+		}
+	}, nil
+}
+
+// Write writes src to the relative path name below dir with the same safety
+// checks as extraction, returning the path written.
+func Write(dir, name string, src io.Reader, perm os.FileMode) (string, error) {
+	target, err := SafePath(dir, name)
+	if err != nil {
+		return "", err
+	}
+	if err := checkNoSymlinks(dir, target); err != nil {
+		return "", err
+	}
+	root, closeRoot, err := openRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	defer closeRoot()
+	return target, writeFile(root, target, src, perm)
+}
+
+// Zip extracts the members of r selected by opts into dir and returns the
+// paths written. Only the compressed bytes of selected members are read, so a
+// remote reader transfers just what is needed.
+func Zip(r *zip.Reader, dir string, opts Options) ([]string, error) {
+	root, closeRoot, err := openRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRoot()
+
+	var written []string
+	for _, entry := range r.File {
+		target, ok, err := opts.target(dir, entry.Name)
+		if err != nil {
+			return written, err
+		}
+		if !ok {
+			continue
+		}
+
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			if err := root.MkdirAll(target, 0755); err != nil {
+				return written, fmt.Errorf("failed to create directory: %w", err)
+			}
+			continue
+		case !mode.IsRegular():
+			fmt.Fprintf(os.Stderr, "Warning: skipping non-regular file %s\n", entry.Name)
+			continue
+		}
+
+		if err := extractZipFile(root, entry, target); err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+
+	return written, nil
+}
+
+func extractZipFile(root *confine.Root, entry *zip.File, target string) error {
+	src, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", entry.Name, err)
+	}
+	defer func() {
+		if closeErr := src.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", entry.Name, closeErr)
+		}
+	}()
+
+	return writeFile(root, target, src, entry.Mode().Perm())
+}
+
+// TarGz extracts the members of a gzip-compressed tar stream selected by
+// opts into dir and returns the paths written. Members are processed while
+// streaming; unselected members are skipped without touching the disk.
+func TarGz(r io.Reader, dir string, opts Options) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip stream: %w", err)
+	}
+	defer func() {
+		if closeErr := gz.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close gzip stream: %v\n", closeErr)
+		}
+	}()
+
+	return Tar(gz, dir, opts)
+}
+
+// Tar extracts the members of an uncompressed tar stream selected by opts
+// into dir and returns the paths written.
+func Tar(r io.Reader, dir string, opts Options) ([]string, error) {
+	root, closeRoot, err := openRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRoot()
+
+	var written []string
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to read tar stream: %w", err)
+		}
+
+		target, ok, err := opts.target(dir, header.Name)
+		if err != nil {
+			return written, err
+		}
+		if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(target, 0755); err != nil {
+				return written, fmt.Errorf("failed to create directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := writeFile(root, target, tr, os.FileMode(header.Mode).Perm()); err != nil {
+				return written, err
+			}
+			written = append(written, target)
+		case tar.TypeSymlink:
+			if err := writeSymlink(root, target, header.Linkname); err != nil {
+				return written, err
+			}
+			written = append(written, target)
+		case tar.TypeXGlobalHeader:
+			// pax metadata such as the commit id of GitHub tarballs
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: skipping unsupported tar entry %s\n", header.Name)
+		}
+	}
+}
+
+// writeSymlink creates a relative symlink whose destination stays in dir
+func writeSymlink(root *confine.Root, target, linkname string) error {
+	if path.IsAbs(linkname) || filepath.IsAbs(linkname) {
+		return fmt.Errorf("unsafe symlink in archive: %q -> %q", target, linkname)
+	}
+
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+	if _, err := root.Rel(resolved); err != nil {
+		return fmt.Errorf("unsafe symlink in archive: %q -> %q", target, linkname)
+	}
+
+	if err := root.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	if err := root.Symlink(linkname, target); err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", target, err)
+	}
+	return nil
+}
+
+func writeFile(root *confine.Root, target string, src io.Reader, perm os.FileMode) error {
+	if err := root.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Never follow an existing symlink at the target itself
+	if info, err := root.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := root.Remove(target); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", target, err)
+		}
+	}
+
+	file, err := root.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", target, err)
+	}
+
+	_, err = io.Copy(file, src)
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+
+	return nil
+}
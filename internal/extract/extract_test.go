@@ -0,0 +1,350 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildZip(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(0755)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestSafePath(t *testing.T) {
+	testCases := []struct {
+		name string
+		safe bool
+	}{
+		{"bin/tool", true},
+		{"./bin/tool", true},
+		{"a/../b", true},
+		{"../evil", false},
+		{"a/../../evil", false},
+		{"/etc/passwd", false},
+		{"..\\evil", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, err := SafePath("out", tc.name)
+			if tc.safe && err != nil {
+				t.Errorf("Expected %q to be safe, got %v", tc.name, err)
+			}
+			if !tc.safe && err == nil {
+				t.Errorf("Expected %q to be rejected, got %q", tc.name, target)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"", "anything", true},
+		{"*", "dir/anything", true},
+		{"bin/tool", "bin/tool", true},
+		{"bin/tool", "tool-1.0/bin/tool", false},
+		{"tool", "tool-1.0/bin/tool", true},
+		{"*.md", "docs/README.md", true},
+		{"bin/*", "bin/tool", true},
+		{"bin/*", "lib/tool", false},
+		{"subdir/**", "subdir/a/b/c.txt", true},
+		{"subdir/**", "subdir", true},
+		{"subdir/**", "other/a.txt", false},
+		{"**/*.go", "a/b/main.go", true},
+		{"**/*.go", "main.go", true},
+		{"src/**/test/*.txt", "src/x/y/test/a.txt", true},
+		{"src/**/test/*.txt", "src/x/y/a.txt", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			got, err := Match(tc.pattern, tc.name)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("Expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestZip_SelectiveExtraction(t *testing.T) {
+	zr := buildZip(t, map[string]string{
+		"bin/tool":  "binary",
+		"README.md": "readme",
+		"lib/x.so":  "library",
+	})
+	dir := t.TempDir()
+
+	written, err := Zip(zr, dir, Options{Include: "bin/tool"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(written) != 1 {
+		t.Fatalf("Expected 1 file written, got %d", len(written))
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "bin", "tool"))
+	if err != nil {
+		t.Fatalf("Expected extracted file, got %v", err)
+	}
+	if string(data) != "binary" {
+		t.Errorf("Expected content 'binary', got %q", data)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "bin", "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected executable bit to be preserved, got %v", info.Mode())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); !os.IsNotExist(err) {
+		t.Errorf("Expected README.md not to be extracted")
+	}
+}
+
+func TestZip_RejectsTraversal(t *testing.T) {
+	zr := buildZip(t, map[string]string{"../evil": "pwned"})
+	dir := t.TempDir()
+
+	_, err := Zip(zr, dir, Options{})
+	if err == nil {
+		t.Fatal("Expected error for path traversal, got nil")
+	}
+	if !strings.Contains(err.Error(), "unsafe path") {
+		t.Errorf("Expected unsafe path error, got %q", err.Error())
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "evil")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside the target directory")
+	}
+}
+
+type tarEntry struct {
+	name     string
+	content  string
+	typeflag byte
+	linkname string
+}
+
+func buildTarGz(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		typeflag := entry.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		header := &tar.Header{
+			Name:     entry.name,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+			Typeflag: typeflag,
+			Linkname: entry.linkname,
+		}
+		if typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if typeflag == tar.TypeReg {
+			_, _ = tw.Write([]byte(entry.content))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestTarGz_IncludeAndStrip(t *testing.T) {
+	archive := buildTarGz(t, []tarEntry{
+		{name: "repo-abc123/", typeflag: tar.TypeDir},
+		{name: "repo-abc123/README.md", content: "readme"},
+		{name: "repo-abc123/subdir/a.txt", content: "a"},
+		{name: "repo-abc123/subdir/nested/b.txt", content: "b"},
+		{name: "repo-abc123/other/c.txt", content: "c"},
+	})
+	dir := t.TempDir()
+
+	written, err := TarGz(archive, dir, Options{Include: "subdir/**", StripComponents: 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(written) != 2 {
+		t.Errorf("Expected 2 files written, got %d: %v", len(written), written)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "subdir", "nested", "b.txt"))
+	if err != nil {
+		t.Fatalf("Expected extracted file, got %v", err)
+	}
+	if string(data) != "b" {
+		t.Errorf("Expected content 'b', got %q", data)
+	}
+
+	for _, name := range []string{"README.md", "other", "repo-abc123"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be extracted", name)
+		}
+	}
+}
+
+func TestTarGz_RejectsTraversal(t *testing.T) {
+	archive := buildTarGz(t, []tarEntry{{name: "../evil", content: "pwned"}})
+
+	_, err := TarGz(archive, t.TempDir(), Options{})
+	if err == nil {
+		t.Fatal("Expected error for path traversal, got nil")
+	}
+	if !strings.Contains(err.Error(), "unsafe path") {
+		t.Errorf("Expected unsafe path error, got %q", err.Error())
+	}
+}
+
+func TestTarGz_RejectsEscapingSymlink(t *testing.T) {
+	archive := buildTarGz(t, []tarEntry{
+		{name: "link", typeflag: tar.TypeSymlink, linkname: "../../etc"},
+	})
+
+	_, err := TarGz(archive, t.TempDir(), Options{})
+	if err == nil {
+		t.Fatal("Expected error for escaping symlink, got nil")
+	}
+	if !strings.Contains(err.Error(), "unsafe symlink") {
+		t.Errorf("Expected unsafe symlink error, got %q", err.Error())
+	}
+}
+
+func TestTarGz_RejectsWritesThroughSymlink(t *testing.T) {
+	outside := t.TempDir()
+	dir := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := buildTarGz(t, []tarEntry{{name: "link/file", content: "pwned"}})
+
+	_, err := TarGz(archive, dir, Options{})
+	if err == nil {
+		t.Fatal("Expected error when writing through a symlink, got nil")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "file")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written through the symlink")
+	}
+}
+
+func TestTarGz_InternalSymlink(t *testing.T) {
+	archive := buildTarGz(t, []tarEntry{
+		{name: "bin/tool-1.0", content: "binary"},
+		{name: "bin/tool", typeflag: tar.TypeSymlink, linkname: "tool-1.0"},
+	})
+	dir := t.TempDir()
+
+	if _, err := TarGz(archive, dir, Options{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "bin", "tool"))
+	if err != nil {
+		t.Fatalf("Expected symlink to resolve, got %v", err)
+	}
+	if string(data) != "binary" {
+		t.Errorf("Expected content 'binary', got %q", data)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	target, err := Write(dir, "a/b.txt", strings.NewReader("content"), 0644)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if target != filepath.Join(dir, "a", "b.txt") {
+		t.Errorf("Unexpected target %q", target)
+	}
+
+	if _, err := Write(dir, "../escape.txt", strings.NewReader("x"), 0644); err == nil {
+		t.Error("Expected error for path traversal, got nil")
+	}
+}
+
+// The name checks above run before each write, so a symlink swapped in
+// between the two, or one they miss, must still not lead outside of dir
+func TestWriteFile_ConfinedToRoot(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "dir")
+	outside := filepath.Join(parent, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	root, closeRoot, err := openRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeRoot()
+
+	if err := os.Symlink("../outside", filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("escape", filepath.Join(dir, "chain")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"escape/file", "chain/file", "chain/nested/file"} {
+		if err := writeFile(root, filepath.Join(dir, name), strings.NewReader("pwned"), 0644); err == nil {
+			t.Errorf("Expected an error writing %s, got nil", name)
+		}
+	}
+	if err := writeSymlink(root, filepath.Join(dir, "chain", "link"), "file"); err == nil {
+		t.Error("Expected an error creating a symlink through a symlink, got nil")
+	}
+
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing written outside the directory, got %v", entries)
+	}
+}
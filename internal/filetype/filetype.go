@@ -0,0 +1,217 @@
+package filetype
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Type describes a file format detected from its leading bytes
+type Type struct {
+	Name      string
+	Extension string
+	MIME      string
+}
+
+var (
+	Zip     = Type{Name: "zip archive", Extension: ".zip", MIME: "application/zip"}
+	Gzip    = Type{Name: "gzip compressed data", Extension: ".gz", MIME: "application/gzip"}
+	TarGz   = Type{Name: "gzip compressed tar archive", Extension: ".tar.gz", MIME: "application/gzip"}
+	Bzip2   = Type{Name: "bzip2 compressed data", Extension: ".bz2", MIME: "application/x-bzip2"}
+	Xz      = Type{Name: "xz compressed data", Extension: ".xz", MIME: "application/x-xz"}
+	Zstd    = Type{Name: "zstandard compressed data", Extension: ".zst", MIME: "application/zstd"}
+	SevenZ  = Type{Name: "7-zip archive", Extension: ".7z", MIME: "application/x-7z-compressed"}
+	Rar     = Type{Name: "rar archive", Extension: ".rar", MIME: "application/vnd.rar"}
+	Tar     = Type{Name: "tar archive", Extension: ".tar", MIME: "application/x-tar"}
+	Deb     = Type{Name: "debian package", Extension: ".deb", MIME: "application/vnd.debian.binary-package"}
+	Rpm     = Type{Name: "rpm package", Extension: ".rpm", MIME: "application/x-rpm"}
+	ELF     = Type{Name: "ELF executable", Extension: "", MIME: "application/x-executable"}
+	MachO   = Type{Name: "Mach-O executable", Extension: "", MIME: "application/x-mach-binary"}
+	PE      = Type{Name: "PE executable", Extension: ".exe", MIME: "application/vnd.microsoft.portable-executable"}
+	MSI     = Type{Name: "windows installer", Extension: ".msi", MIME: "application/x-msi"}
+	PDF     = Type{Name: "PDF document", Extension: ".pdf", MIME: "application/pdf"}
+	PNG     = Type{Name: "PNG image", Extension: ".png", MIME: "image/png"}
+	Text    = Type{Name: "text", Extension: "", MIME: "text/plain"}
+	Unknown = Type{Name: "data", Extension: "", MIME: "application/octet-stream"}
+)
+
+type signature struct {
+	offset int
+	magic  []byte
+	kind   Type
+}
+
+var signatures = []signature{
+	{0, []byte("PK\x03\x04"), Zip},
+	{0, []byte("PK\x05\x06"), Zip},
+	{0, []byte{0x1f, 0x8b}, Gzip},
+	{0, []byte("BZh"), Bzip2},
+	{0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, Xz},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, Zstd},
+	{0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, SevenZ},
+	{0, []byte("Rar!\x1a\x07"), Rar},
+	{0, []byte("!<arch>\ndebian"), Deb},
+	{0, []byte{0xed, 0xab, 0xee, 0xdb}, Rpm},
+	{0, []byte("\x7fELF"), ELF},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xce}, MachO},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xcf}, MachO},
+	{0, []byte{0xce, 0xfa, 0xed, 0xfe}, MachO},
+	{0, []byte{0xcf, 0xfa, 0xed, 0xfe}, MachO},
+	{0, []byte{0xca, 0xfe, 0xba, 0xbe}, MachO},
+	{0, []byte("MZ"), PE},
+	{0, []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}, MSI},
+	{0, []byte("%PDF-"), PDF},
+	{0, []byte("\x89PNG\r\n\x1a\n"), PNG},
+	{257, []byte("ustar"), Tar},
+}
+
+// Detect identifies the file type from the leading bytes of a file.
+// A few hundred bytes are enough for every supported signature.
+func Detect(head []byte) Type {
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(head) >= end && bytes.Equal(head[sig.offset:end], sig.magic) {
+			return sig.kind
+		}
+	}
+
+	if len(head) > 0 && isText(head) {
+		return Text
+	}
+
+	return Unknown
+}
+
+func isText(head []byte) bool {
+	// The head may cut a multi-byte rune in half, so ignore a short tail
+	for i := 0; i < utf8.UTFMax-1 && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+
+	for _, b := range head {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// headSize is the number of leading bytes DetectFile reads
+const headSize = 512
+
+// DetectFile identifies the type of the file at path. Unlike Detect it looks
+// inside gzip data, so compressed tarballs are reported as TarGz.
+func DetectFile(path string) (Type, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Unknown, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", path, closeErr)
+		}
+	}()
+
+	head := make([]byte, headSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Unknown, err
+	}
+
+	kind := Detect(head[:n])
+	if kind != Gzip {
+		return kind, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return kind, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return kind, nil
+	}
+	// A truncated or corrupt stream still tells us what it starts with
+	n, err = io.ReadFull(gz, head)
+	if err != nil && n == 0 {
+		return kind, nil
+	}
+	if Detect(head[:n]) == Tar {
+		return TarGz, nil
+	}
+	return kind, nil
+}
+
+// extensions maps file name extensions to the type they promise, longest
+// extensions first
+var extensions = []struct {
+	suffix string
+	kind   Type
+}{
+	{".tar.gz", TarGz},
+	{".tgz", TarGz},
+	{".zip", Zip},
+	{".gz", Gzip},
+	{".bz2", Bzip2},
+	{".xz", Xz},
+	{".zst", Zstd},
+	{".7z", SevenZ},
+	{".rar", Rar},
+	{".tar", Tar},
+	{".deb", Deb},
+	{".rpm", Rpm},
+	{".exe", PE},
+	{".msi", MSI},
+	{".pdf", PDF},
+	{".png", PNG},
+}
+
+// ForName returns the type promised by the extension of a file name and the
+// extension itself
+func ForName(name string) (Type, string, bool) {
+	lower := strings.ToLower(name)
+	for _, ext := range extensions {
+		if strings.HasSuffix(lower, ext.suffix) {
+			return ext.kind, name[len(name)-len(ext.suffix):], true
+		}
+	}
+	return Unknown, "", false
+}
+
+// mimeAliases are content types uploaders commonly use for the same format
+var mimeAliases = map[string]string{
+	"application/x-gzip":           "application/gzip",
+	"application/x-compressed-tar": "application/gzip",
+	"application/x-gtar":           "application/gzip",
+	"application/x-zip-compressed": "application/zip",
+	"application/x-zip":            "application/zip",
+	"application/x-bzip":           "application/x-bzip2",
+	"application/x-rar-compressed": "application/vnd.rar",
+	"application/x-debian-package": "application/vnd.debian.binary-package",
+	"application/x-msdownload":     "application/vnd.microsoft.portable-executable",
+	"application/x-msdos-program":  "application/vnd.microsoft.portable-executable",
+	"application/x-ms-installer":   "application/x-msi",
+}
+
+// NormalizeMIME reduces a content type to the MIME used by Type, dropping
+// parameters and resolving common aliases
+func NormalizeMIME(contentType string) string {
+	mime, _, _ := strings.Cut(contentType, ";")
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	if alias, ok := mimeAliases[mime]; ok {
+		return alias
+	}
+	return mime
+}
+
+// Specific reports whether detection identified a concrete format, as
+// opposed to generic text or unknown data
+func (t Type) Specific() bool {
+	return t != Text && t != Unknown
+}
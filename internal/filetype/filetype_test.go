@@ -0,0 +1,134 @@
+package filetype
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tarHead := make([]byte, 512)
+	copy(tarHead[257:], "ustar")
+
+	testCases := []struct {
+		name     string
+		head     []byte
+		expected Type
+	}{
+		{"zip", []byte("PK\x03\x04\x14\x00"), Zip},
+		{"empty zip", []byte("PK\x05\x06\x00\x00"), Zip},
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00}, Gzip},
+		{"bzip2", []byte("BZh91AY"), Bzip2},
+		{"xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00}, Xz},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, Zstd},
+		{"deb", []byte("!<arch>\ndebian-binary   "), Deb},
+		{"elf", []byte("\x7fELF\x02\x01\x01"), ELF},
+		{"mach-o", []byte{0xcf, 0xfa, 0xed, 0xfe, 0x07}, MachO},
+		{"pe", []byte("MZ\x90\x00"), PE},
+		{"tar", tarHead, Tar},
+		{"text", []byte("sha256  app.tar.gz\n"), Text},
+		{"binary", []byte{0x00, 0x01, 0x02, 0x03}, Unknown},
+		{"empty", []byte{}, Unknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Detect(tc.head)
+			if got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected.Name, got.Name)
+			}
+		})
+	}
+}
+
+func TestDetect_TextWithTruncatedRune(t *testing.T) {
+	// "é" is two bytes; cut it in half at the end of the head
+	head := []byte("caf\xc3")
+	if got := Detect(head); got != Text {
+		t.Errorf("Expected text, got %q", got.Name)
+	}
+}
+
+func writeGzip(t *testing.T, path string, content []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tarHead := make([]byte, 1024)
+	copy(tarHead[257:], "ustar")
+	tarGz := filepath.Join(dir, "app.tar.gz")
+	writeGzip(t, tarGz, tarHead)
+
+	plainGz := filepath.Join(dir, "notes.gz")
+	writeGzip(t, plainGz, []byte("plain text"))
+
+	testCases := []struct {
+		path     string
+		expected Type
+	}{
+		{tarGz, TarGz},
+		{plainGz, Gzip},
+	}
+	for _, tc := range testCases {
+		got, err := DetectFile(tc.path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got != tc.expected {
+			t.Errorf("Expected %q for %s, got %q", tc.expected.Name, filepath.Base(tc.path), got.Name)
+		}
+	}
+}
+
+func TestForName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected Type
+		ext      string
+		ok       bool
+	}{
+		{"app.tar.gz", TarGz, ".tar.gz", true},
+		{"APP.ZIP", Zip, ".ZIP", true},
+		{"app.tgz", TarGz, ".tgz", true},
+		{"app", Unknown, "", false},
+		{"checksums.txt", Unknown, "", false},
+	}
+
+	for _, tc := range testCases {
+		got, ext, ok := ForName(tc.name)
+		if got != tc.expected || ext != tc.ext || ok != tc.ok {
+			t.Errorf("ForName(%q) = %q, %q, %t", tc.name, got.Name, ext, ok)
+		}
+	}
+}
+
+func TestNormalizeMIME(t *testing.T) {
+	testCases := map[string]string{
+		"application/x-gzip":              "application/gzip",
+		"Application/Zip":                 "application/zip",
+		"application/zip; charset=binary": "application/zip",
+		"application/x-zip-compressed":    "application/zip",
+		"application/octet-stream":        "application/octet-stream",
+	}
+
+	for input, expected := range testCases {
+		if got := NormalizeMIME(input); got != expected {
+			t.Errorf("NormalizeMIME(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package github
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// CommitStatus is a status reported for a commit through the statuses API
+type CommitStatus struct {
+	Context     string `json:"context"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+}
+
+// CombinedStatus is the latest status of each context on a commit
+type CombinedStatus struct {
+	State    string         `json:"state"`
+	SHA      string         `json:"sha"`
+	Statuses []CommitStatus `json:"statuses"`
+}
+
+// CheckRun is a check run on a commit. Conclusion is empty until Status is
+// "completed".
+type CheckRun struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+// MaxCheckRuns is the number of check runs fetched for a commit
+const MaxCheckRuns = 100
+
+func GetCombinedStatus(client HTTPClient, repo, ref string) (*CombinedStatus, error) {
+	endpoint := fmt.Sprintf("repos/%s/commits/%s/status?per_page=100", repo, url.PathEscape(ref))
+
+	var status CombinedStatus
+	if err := client.Get(endpoint, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+func GetCheckRuns(client HTTPClient, repo, ref string) ([]CheckRun, error) {
+	endpoint := fmt.Sprintf("repos/%s/commits/%s/check-runs?per_page=%d", repo, url.PathEscape(ref), MaxCheckRuns)
+
+	var response struct {
+		TotalCount int        `json:"total_count"`
+		CheckRuns  []CheckRun `json:"check_runs"`
+	}
+	if err := client.Get(endpoint, &response); err != nil {
+		return nil, err
+	}
+
+	return response.CheckRuns, nil
+}
+
+// Deployment is a deployment of a commit to an environment
+type Deployment struct {
+	ID          int    `json:"id"`
+	SHA         string `json:"sha"`
+	Ref         string `json:"ref"`
+	Environment string `json:"environment"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// DeploymentStatus is a state reported for a deployment, newest first
+type DeploymentStatus struct {
+	State       string `json:"state"`
+	Environment string `json:"environment"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func ListDeployments(client HTTPClient, repo, sha, environment string) ([]Deployment, error) {
+	query := url.Values{"sha": {sha}, "environment": {environment}, "per_page": {"100"}}
+	endpoint := fmt.Sprintf("repos/%s/deployments?%s", repo, query.Encode())
+
+	var deployments []Deployment
+	if err := client.Get(endpoint, &deployments); err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+func ListDeploymentStatuses(client HTTPClient, repo string, id int) ([]DeploymentStatus, error) {
+	endpoint := fmt.Sprintf("repos/%s/deployments/%d/statuses?per_page=100", repo, id)
+
+	var statuses []DeploymentStatus
+	if err := client.Get(endpoint, &statuses); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,89 @@
+package github
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetCombinedStatus(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/commits/abc123/status?per_page=100"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+			status := response.(*CombinedStatus)
+			status.State = "failure"
+			status.Statuses = []CommitStatus{{Context: "ci/build", State: "failure"}}
+			return nil
+		},
+	}
+
+	status, err := GetCombinedStatus(mockClient, "owner/repo", "abc123")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.State != "failure" || len(status.Statuses) != 1 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestGetCheckRuns(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/commits/abc123/check-runs?per_page=100"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+			return json.Unmarshal([]byte(`{"total_count":1,"check_runs":[{"name":"test","status":"completed","conclusion":"success"}]}`), response)
+		},
+	}
+
+	runs, err := GetCheckRuns(mockClient, "owner/repo", "abc123")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(runs) != 1 || runs[0].Conclusion != "success" {
+		t.Errorf("Unexpected check runs %+v", runs)
+	}
+}
+
+func TestListDeployments(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/deployments?environment=production&per_page=100&sha=abc123"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+			return json.Unmarshal([]byte(`[{"id":7,"sha":"abc123","environment":"production"}]`), response)
+		},
+	}
+
+	deployments, err := ListDeployments(mockClient, "owner/repo", "abc123", "production")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(deployments) != 1 || deployments[0].ID != 7 {
+		t.Errorf("Unexpected deployments %+v", deployments)
+	}
+}
+
+func TestListDeploymentStatuses(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/deployments/7/statuses?per_page=100"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+			return json.Unmarshal([]byte(`[{"state":"success"},{"state":"in_progress"}]`), response)
+		},
+	}
+
+	statuses, err := ListDeploymentStatuses(mockClient, "owner/repo", 7)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].State != "success" {
+		t.Errorf("Unexpected statuses %+v", statuses)
+	}
+}
@@ -0,0 +1,190 @@
+package github
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ComparisonFile is a file changed between two refs
+type ComparisonFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename"`
+	Status           string `json:"status"`
+	Additions        int    `json:"additions"`
+	Deletions        int    `json:"deletions"`
+	SHA              string `json:"sha"`
+}
+
+// Comparison is the result of comparing two refs
+type Comparison struct {
+	Status       string           `json:"status"`
+	AheadBy      int              `json:"ahead_by"`
+	BehindBy     int              `json:"behind_by"`
+	TotalCommits int              `json:"total_commits"`
+	Commits      []Commit         `json:"commits"`
+	Files        []ComparisonFile `json:"files"`
+}
+
+// MaxComparisonFiles is the number of files after which the compare
+// endpoint truncates its file list
+const MaxComparisonFiles = 300
+
+func GetComparison(client HTTPClient, repo, base, head string) (*Comparison, error) {
+	endpoint := fmt.Sprintf("repos/%s/compare/%s...%s", repo, url.PathEscape(base), url.PathEscape(head))
+
+	var comparison Comparison
+	if err := client.Get(endpoint, &comparison); err != nil {
+		return nil, err
+	}
+
+	return &comparison, nil
+}
+
+// Kinds of asset changes between two releases
+const (
+	AssetAdded     = "added"
+	AssetRemoved   = "removed"
+	AssetChanged   = "changed"
+	AssetUnchanged = "unchanged"
+)
+
+// AssetChange describes how an asset differs between two releases
+type AssetChange struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+	Base *Asset `json:"base,omitempty"`
+	Head *Asset `json:"head,omitempty"`
+}
+
+// assetKey normalizes an asset name by replacing the release version, so
+// that "app-1.0.0-linux.tar.gz" and "app-1.1.0-linux.tar.gz" are compared
+// with each other.
+func assetKey(name, tag string) string {
+	version := strings.TrimPrefix(tag, "v")
+	if tag != "" {
+		name = strings.ReplaceAll(name, tag, "{version}")
+	}
+	if version != "" {
+		name = strings.ReplaceAll(name, version, "{version}")
+	}
+	return name
+}
+
+// DiffAssets compares the assets of two releases, matching them by name with
+// the release versions normalized away. Changes are sorted by key.
+func DiffAssets(base, head []Asset, baseTag, headTag string) []AssetChange {
+	baseByKey := make(map[string]*Asset, len(base))
+	for i := range base {
+		baseByKey[assetKey(base[i].Name, baseTag)] = &base[i]
+	}
+
+	var changes []AssetChange
+	seen := make(map[string]bool, len(head))
+	for i := range head {
+		key := assetKey(head[i].Name, headTag)
+		seen[key] = true
+
+		baseAsset, ok := baseByKey[key]
+		switch {
+		case !ok:
+			changes = append(changes, AssetChange{Kind: AssetAdded, Key: key, Head: &head[i]})
+		case baseAsset.Size != head[i].Size:
+			changes = append(changes, AssetChange{Kind: AssetChanged, Key: key, Base: baseAsset, Head: &head[i]})
+		default:
+			changes = append(changes, AssetChange{Kind: AssetUnchanged, Key: key, Base: baseAsset, Head: &head[i]})
+		}
+	}
+
+	for key, baseAsset := range baseByKey {
+		if !seen[key] {
+			changes = append(changes, AssetChange{Kind: AssetRemoved, Key: key, Base: baseAsset})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// CompareReleases prints the asset differences between two releases, and
+// optionally the commits and changed files between their tags.
+func CompareReleases(client HTTPClient, repo, base, head string, commits, files bool) error {
+	if base == "" || head == "" {
+		return fmt.Errorf("compare requires a base and a head tag")
+	}
+
+	baseRelease, err := GetRelease(client, repo, base)
+	if err != nil {
+		return fmt.Errorf("failed to get release %s: %w", base, err)
+	}
+	headRelease, err := GetRelease(client, repo, head)
+	if err != nil {
+		return fmt.Errorf("failed to get release %s: %w", head, err)
+	}
+
+	fmt.Printf("Comparing %s...%s in %s\n", base, head, repo)
+
+	fmt.Printf("\nAssets:\n")
+	unchanged := 0
+	for _, change := range DiffAssets(baseRelease.Assets, headRelease.Assets, base, head) {
+		switch change.Kind {
+		case AssetAdded:
+			fmt.Printf("  + %s (%d bytes)\n", change.Head.Name, change.Head.Size)
+		case AssetRemoved:
+			fmt.Printf("  - %s (%d bytes)\n", change.Base.Name, change.Base.Size)
+		case AssetChanged:
+			fmt.Printf("  ~ %s (%d -> %d bytes)\n", change.Head.Name, change.Base.Size, change.Head.Size)
+		default:
+			unchanged++
+		}
+	}
+	fmt.Printf("  %d unchanged\n", unchanged)
+
+	if !commits && !files {
+		return nil
+	}
+
+	comparison, err := GetComparison(client, repo, base, head)
+	if err != nil {
+		return fmt.Errorf("failed to compare %s...%s: %w", base, head, err)
+	}
+
+	if commits {
+		fmt.Printf("\nCommits (%d):\n", comparison.TotalCommits)
+		for _, commit := range comparison.Commits {
+			message, _, _ := strings.Cut(commit.Commit.Message, "\n")
+			sha := commit.SHA
+			if len(sha) > 7 {
+				sha = sha[:7]
+			}
+			fmt.Printf("  %s %s (%s)\n", sha, message, commit.Commit.Author.Name)
+		}
+		if len(comparison.Commits) < comparison.TotalCommits {
+			fmt.Printf("  ... %d more\n", comparison.TotalCommits-len(comparison.Commits))
+		}
+	}
+
+	if files {
+		fmt.Printf("\nFiles (%d):\n", len(comparison.Files))
+		for _, file := range comparison.Files {
+			switch file.Status {
+			case "renamed":
+				fmt.Printf("  R %s -> %s\n", file.PreviousFilename, file.Filename)
+			case "added":
+				fmt.Printf("  A %s (+%d)\n", file.Filename, file.Additions)
+			case "removed":
+				fmt.Printf("  D %s (-%d)\n", file.Filename, file.Deletions)
+			default:
+				fmt.Printf("  M %s (+%d -%d)\n", file.Filename, file.Additions, file.Deletions)
+			}
+		}
+		if len(comparison.Files) >= MaxComparisonFiles {
+			fmt.Printf("  (the file list is truncated at %d files)\n", MaxComparisonFiles)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGetComparison(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/compare/v1.0.0...v1.1.0"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+
+			if comparison, ok := response.(*Comparison); ok {
+				comparison.Status = "ahead"
+				comparison.Files = []ComparisonFile{
+					{Filename: "main.go", Status: "modified"},
+					{Filename: "new.go", PreviousFilename: "old.go", Status: "renamed"},
+				}
+			}
+			return nil
+		},
+	}
+
+	comparison, err := GetComparison(mockClient, "owner/repo", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if comparison.Status != "ahead" {
+		t.Errorf("Expected status 'ahead', got %q", comparison.Status)
+	}
+	if len(comparison.Files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(comparison.Files))
+	}
+	if comparison.Files[1].PreviousFilename != "old.go" {
+		t.Errorf("Expected previous filename 'old.go', got %q", comparison.Files[1].PreviousFilename)
+	}
+}
+
+func TestGetComparison_APIError(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			return fmt.Errorf("API error: 404 Not Found")
+		},
+	}
+
+	comparison, err := GetComparison(mockClient, "owner/repo", "a", "b")
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+	if comparison != nil {
+		t.Errorf("Expected nil comparison on error, got %+v", comparison)
+	}
+}
+
+func TestDiffAssets(t *testing.T) {
+	base := []Asset{
+		{Name: "app-1.0.0-linux.tar.gz", Size: 100},
+		{Name: "app-1.0.0-windows.zip", Size: 200},
+		{Name: "app-1.0.0-386.tar.gz", Size: 50},
+		{Name: "checksums.txt", Size: 10},
+	}
+	head := []Asset{
+		{Name: "app-1.1.0-linux.tar.gz", Size: 120},
+		{Name: "app-1.1.0-windows.zip", Size: 200},
+		{Name: "app-1.1.0-arm64.tar.gz", Size: 90},
+		{Name: "checksums.txt", Size: 10},
+	}
+
+	changes := DiffAssets(base, head, "v1.0.0", "v1.1.0")
+
+	got := make([]string, len(changes))
+	for i, change := range changes {
+		got[i] = change.Kind + ":" + change.Key
+	}
+
+	expected := []string{
+		"removed:app-{version}-386.tar.gz",
+		"added:app-{version}-arm64.tar.gz",
+		"changed:app-{version}-linux.tar.gz",
+		"unchanged:app-{version}-windows.zip",
+		"unchanged:checksums.txt",
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected changes %v, got %v", expected, got)
+	}
+}
+
+func TestCompareReleases(t *testing.T) {
+	releases := map[string]Release{
+		"repos/owner/repo/releases/tags/v1.0.0": {TagName: "v1.0.0", Assets: []Asset{{Name: "app-1.0.0.tar.gz", Size: 1}}},
+		"repos/owner/repo/releases/tags/v1.1.0": {TagName: "v1.1.0", Assets: []Asset{{Name: "app-1.1.0.tar.gz", Size: 2}}},
+	}
+
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			switch r := response.(type) {
+			case *Release:
+				*r = releases[endpoint]
+			case *Comparison:
+				r.TotalCommits = 1
+				r.Commits = []Commit{{SHA: "0123456789"}}
+				r.Commits[0].Commit.Message = "Fix bug\n\nDetails"
+				r.Commits[0].Commit.Author.Name = "octocat"
+				r.Files = []ComparisonFile{{Filename: "main.go", Status: "modified", Additions: 3, Deletions: 1}}
+			}
+			return nil
+		},
+	}
+
+	output := captureOutput(func() {
+		err := CompareReleases(mockClient, "owner/repo", "v1.0.0", "v1.1.0", true, true)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	expectedStrings := []string{
+		"Comparing v1.0.0...v1.1.0 in owner/repo",
+		"~ app-1.1.0.tar.gz (1 -> 2 bytes)",
+		"Commits (1):",
+		"0123456 Fix bug (octocat)",
+		"Files (1):",
+		"M main.go (+3 -1)",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, but it was missing:\n%s", expected, output)
+		}
+	}
+}
+
+func TestCompareReleases_MissingTags(t *testing.T) {
+	err := CompareReleases(&MockHTTPClient{}, "owner/repo", "v1.0.0", "", false, false)
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+	if !strings.Contains(err.Error(), "base and a head tag") {
+		t.Errorf("Unexpected error %q", err.Error())
+	}
+}
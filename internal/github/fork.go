@@ -0,0 +1,77 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// WriteClient is an HTTPClient that can also create resources, such as forks
+// and releases
+type WriteClient interface {
+	HTTPClient
+	Post(endpoint string, body io.Reader, response interface{}) error
+}
+
+// IsForkOf reports whether the repository is a fork of repo, directly or
+// through other forks
+func (r *Repository) IsForkOf(repo string) bool {
+	if !r.Fork {
+		return false
+	}
+	for _, upstream := range []*Repository{r.Parent, r.Source} {
+		if upstream != nil && strings.EqualFold(upstream.FullName, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateFork forks repo into org. GitHub creates the fork asynchronously,
+// so it may take a moment until it can be used.
+func CreateFork(client WriteClient, repo, org string) (*Repository, error) {
+	body, err := json.Marshal(map[string]any{"organization": org})
+	if err != nil {
+		return nil, err
+	}
+	var fork Repository
+	if err := client.Post(fmt.Sprintf("repos/%s/forks", repo), bytes.NewReader(body), &fork); err != nil {
+		return nil, err
+	}
+	return &fork, nil
+}
+
+// CreateRelease publishes a release in repo with the tag, name, notes and
+// prerelease flag of release. The tag is created at the commit of release
+// when the repository does not have it yet.
+func CreateRelease(client WriteClient, repo string, release *Release) (*Release, error) {
+	fields := map[string]any{
+		"tag_name":   release.TagName,
+		"name":       release.Name,
+		"body":       release.Body,
+		"prerelease": release.Prerelease,
+	}
+	if release.CommitSHA != "" {
+		fields["target_commitish"] = release.CommitSHA
+	}
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var created Release
+	if err := client.Post(fmt.Sprintf("repos/%s/releases", repo), bytes.NewReader(body), &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// AssetUploadURL returns the URL to upload an asset named name to, from the
+// URI template of a release such as
+// https://uploads.github.com/repos/o/r/releases/1/assets{?name,label}
+func AssetUploadURL(release *Release, name string) string {
+	base, _, _ := strings.Cut(release.UploadURL, "{")
+	return base + "?name=" + url.QueryEscape(name)
+}
@@ -0,0 +1,74 @@
+package github
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+// mockWriteClient records the bodies posted to it by endpoint
+type mockWriteClient struct {
+	MockHTTPClient
+	posted map[string]map[string]any
+}
+
+func (m *mockWriteClient) Post(endpoint string, body io.Reader, response interface{}) error {
+	var fields map[string]any
+	if err := json.NewDecoder(body).Decode(&fields); err != nil {
+		return err
+	}
+	if m.posted == nil {
+		m.posted = make(map[string]map[string]any)
+	}
+	m.posted[endpoint] = fields
+	return nil
+}
+
+func TestRepository_IsForkOf(t *testing.T) {
+	testCases := []struct {
+		name     string
+		repo     Repository
+		expected bool
+	}{
+		{"parent", Repository{Fork: true, Parent: &Repository{FullName: "Owner/Repo"}}, true},
+		{"source", Repository{Fork: true, Parent: &Repository{FullName: "other/repo"}, Source: &Repository{FullName: "owner/repo"}}, true},
+		{"other fork", Repository{Fork: true, Parent: &Repository{FullName: "other/repo"}}, false},
+		{"not a fork", Repository{}, false},
+	}
+	for _, tc := range testCases {
+		if got := tc.repo.IsForkOf("owner/repo"); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestCreateRelease(t *testing.T) {
+	client := &mockWriteClient{}
+	release := &Release{TagName: "v1.0.0", Name: "One", Body: "Notes", Prerelease: true, CommitSHA: "abc123"}
+	if _, err := CreateRelease(client, "org/repo", release); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	fields := client.posted["repos/org/repo/releases"]
+	if fields["tag_name"] != "v1.0.0" || fields["name"] != "One" || fields["body"] != "Notes" || fields["prerelease"] != true || fields["target_commitish"] != "abc123" {
+		t.Errorf("Unexpected release fields %v", fields)
+	}
+}
+
+func TestCreateFork(t *testing.T) {
+	client := &mockWriteClient{}
+	if _, err := CreateFork(client, "owner/repo", "org"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fields := client.posted["repos/owner/repo/forks"]; fields["organization"] != "org" {
+		t.Errorf("Expected the fork to go to org, got %v", fields)
+	}
+}
+
+func TestAssetUploadURL(t *testing.T) {
+	release := &Release{UploadURL: "https://uploads.github.com/repos/org/repo/releases/1/assets{?name,label}"}
+	expected := "https://uploads.github.com/repos/org/repo/releases/1/assets?name=app+1.0.tar.gz"
+	if got := AssetUploadURL(release, "app 1.0.tar.gz"); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
@@ -1,9 +1,19 @@
 package github
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net/url"
+	"os"
 	"path"
+	"strconv"
 	"strings"
+
+	"github.com/23prime/gh-download/internal/color"
+	"github.com/23prime/gh-download/internal/pager"
+	"github.com/23prime/gh-download/internal/progress"
+	"github.com/cli/go-gh/v2/pkg/tableprinter"
+	"github.com/cli/go-gh/v2/pkg/term"
 )
 
 // HTTPClient interface for abstraction and testing
@@ -21,6 +31,64 @@ type Release struct {
 	CreatedAt   string  `json:"created_at"`
 	PublishedAt string  `json:"published_at"`
 	Assets      []Asset `json:"assets"`
+	// TargetCommitish is the branch or commit the tag was created from
+	TargetCommitish string `json:"target_commitish"`
+	// CommitSHA is the commit the tag points to; the API does not return it,
+	// see ResolveCommitSHA
+	CommitSHA string `json:"commit_sha,omitempty"`
+	// Reactions is nil for releases nobody reacted to
+	Reactions     *Reactions `json:"reactions,omitempty"`
+	DiscussionURL string     `json:"discussion_url,omitempty"`
+	Author        User       `json:"author"`
+	// UploadURL is the URI template assets are uploaded to
+	UploadURL string `json:"upload_url,omitempty"`
+}
+
+// User is the account that created a release. Type is "User" for people and
+// "Bot" for apps such as github-actions[bot].
+type User struct {
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+// Reactions are the reaction counts of a release
+type Reactions struct {
+	TotalCount int `json:"total_count"`
+	PlusOne    int `json:"+1"`
+	MinusOne   int `json:"-1"`
+	Laugh      int `json:"laugh"`
+	Hooray     int `json:"hooray"`
+	Confused   int `json:"confused"`
+	Heart      int `json:"heart"`
+	Rocket     int `json:"rocket"`
+	Eyes       int `json:"eyes"`
+}
+
+// ReactionCount returns the total number of reactions to the release
+func (r *Release) ReactionCount() int {
+	if r.Reactions == nil {
+		return 0
+	}
+	return r.Reactions.TotalCount
+}
+
+// String lists the non-zero reaction counts, e.g. "+1 10, rocket 2"
+func (r Reactions) String() string {
+	counts := []struct {
+		name  string
+		count int
+	}{
+		{"+1", r.PlusOne}, {"-1", r.MinusOne}, {"laugh", r.Laugh}, {"hooray", r.Hooray},
+		{"confused", r.Confused}, {"heart", r.Heart}, {"rocket", r.Rocket}, {"eyes", r.Eyes},
+	}
+
+	var parts []string
+	for _, c := range counts {
+		if c.count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", c.name, c.count))
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
 type Asset struct {
@@ -30,6 +98,13 @@ type Asset struct {
 	Size               int    `json:"size"`
 	BrowserDownloadURL string `json:"browser_download_url"`
 	URL                string `json:"url"`
+	// Digest is the digest GitHub computed for the asset, e.g. "sha256:..."
+	Digest        string `json:"digest"`
+	Label         string `json:"label"`
+	State         string `json:"state"`
+	DownloadCount int    `json:"download_count"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
 }
 
 func GetRelease(client HTTPClient, repo, tag string) (*Release, error) {
@@ -49,6 +124,17 @@ func GetRelease(client HTTPClient, repo, tag string) (*Release, error) {
 	return &release, nil
 }
 
+// ResolveCommitSHA sets the CommitSHA of a release to the commit its tag
+// points to
+func ResolveCommitSHA(client HTTPClient, repo string, release *Release) error {
+	commit, err := GetCommit(client, repo, release.TagName)
+	if err != nil {
+		return err
+	}
+	release.CommitSHA = commit.SHA
+	return nil
+}
+
 func FilterAssets(assets []Asset, pattern string) ([]Asset, error) {
 	if pattern == "*" || pattern == "" {
 		return assets, nil
@@ -68,6 +154,8 @@ func FilterAssets(assets []Asset, pattern string) ([]Asset, error) {
 	return matched, nil
 }
 
+// ListAssets prints the assets matching pattern as a table, see
+// printTable
 func ListAssets(assets []Asset, pattern string) error {
 	matchingAssets, err := FilterAssets(assets, pattern)
 	if err != nil {
@@ -75,71 +163,198 @@ func ListAssets(assets []Asset, pattern string) error {
 	}
 
 	if len(matchingAssets) == 0 {
-		fmt.Printf("No assets found matching pattern '%s'\n", pattern)
+		fmt.Printf("No assets found matching pattern '%s'\n", color.Stdout.Cyan(pattern))
 		return nil
 	}
+	return printTable(func(tp tableprinter.TablePrinter, isTTY bool) {
+		writeAssetTable(tp, isTTY, matchingAssets)
+	})
+}
 
-	fmt.Printf("\nAssets matching pattern '%s':\n", pattern)
-	for i, asset := range matchingAssets {
-		fmt.Printf("%d. %s\n", i+1, asset.Name)
-		fmt.Printf("   Size: %d bytes\n", asset.Size)
-		fmt.Printf("   Content-Type: %s\n", asset.ContentType)
-		if i < len(matchingAssets)-1 {
-			fmt.Println()
+// printTable prints a table on stdout with the go-gh table printer: aligned
+// columns under a header, truncated to the width of the terminal, when
+// stdout is a terminal (or GH_FORCE_TTY is set, as with gh), and
+// tab-separated values without a header when it is piped
+func printTable(write func(tp tableprinter.TablePrinter, isTTY bool)) error {
+	t := term.FromEnv()
+	isTTY := t.IsTerminalOutput()
+	width := 0
+	if isTTY {
+		var err error
+		if width, _, err = t.Size(); err != nil {
+			width = 80
 		}
 	}
+	// A pager shows the table on the terminal stdout was piped away from
+	if paging, pagerWidth := pager.Running(); paging {
+		isTTY, width = true, pagerWidth
+	}
 
-	fmt.Printf("\nTotal: %d assets\n", len(matchingAssets))
-	return nil
+	tp := tableprinter.New(os.Stdout, isTTY, width)
+	write(tp, isTTY)
+	return tp.Render()
 }
 
-func ListReleases(client HTTPClient, repo string) error {
+// writeAssetTable adds a row per asset. Terminals get sizes in binary units
+// and dates; piped output gets byte counts and full timestamps.
+func writeAssetTable(tp tableprinter.TablePrinter, isTTY bool, assets []Asset) {
+	tp.AddHeader([]string{"NAME", "SIZE", "TYPE", "UPDATED"}, tableprinter.WithColor(color.Stdout.Gray))
+	for _, asset := range assets {
+		tp.AddField(asset.Name)
+		if isTTY {
+			tp.AddField(progress.FormatBytes(int64(asset.Size)))
+			tp.AddField(asset.ContentType)
+			tp.AddField(formatDate(asset.UpdatedAt))
+		} else {
+			tp.AddField(strconv.Itoa(asset.Size))
+			tp.AddField(asset.ContentType)
+			tp.AddField(asset.UpdatedAt)
+		}
+		tp.EndRow()
+	}
+}
+
+// releasesPerPage is the largest page size the releases endpoint accepts
+const releasesPerPage = 100
+
+// GetAllReleases returns every release of a repository, newest first,
+// following the pages of the releases endpoint
+func GetAllReleases(client HTTPClient, repo string) ([]Release, error) {
+	var releases []Release
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("repos/%s/releases?per_page=%d&page=%d", repo, releasesPerPage, page)
+
+		var batch []Release
+		if err := client.Get(endpoint, &batch); err != nil {
+			return nil, err
+		}
+		releases = append(releases, batch...)
+		if len(batch) < releasesPerPage {
+			return releases, nil
+		}
+	}
+}
+
+func GetReleases(client HTTPClient, repo string) ([]Release, error) {
 	endpoint := fmt.Sprintf("repos/%s/releases", repo)
 
 	var releases []Release
-	err := client.Get(endpoint, &releases)
+	if err := client.Get(endpoint, &releases); err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// FilterByReactions returns the releases with at least minReactions
+// reactions
+func FilterByReactions(releases []Release, minReactions int) []Release {
+	if minReactions <= 0 {
+		return releases
+	}
+
+	var matched []Release
+	for _, release := range releases {
+		if release.ReactionCount() >= minReactions {
+			matched = append(matched, release)
+		}
+	}
+	return matched
+}
+
+// FilterByAuthor returns the releases created by the account with the given
+// login, compared case-insensitively
+func FilterByAuthor(releases []Release, login string) []Release {
+	if login == "" {
+		return releases
+	}
+
+	var matched []Release
+	for _, release := range releases {
+		if strings.EqualFold(release.Author.Login, login) {
+			matched = append(matched, release)
+		}
+	}
+	return matched
+}
+
+// LatestStable returns the first release that is neither a draft nor a
+// prerelease; releases are listed newest first
+func LatestStable(releases []Release) (*Release, bool) {
+	for _, release := range releases {
+		if !release.Draft && !release.Prerelease {
+			return &release, true
+		}
+	}
+	return nil, false
+}
+
+func ListReleases(client HTTPClient, repo string) error {
+	releases, err := GetReleases(client, repo)
 	if err != nil {
 		return fmt.Errorf("failed to get releases: %w", err)
 	}
 
+	PrintReleases(repo, releases)
+	return nil
+}
+
+// PrintReleases prints the releases as a table, see printTable
+func PrintReleases(repo string, releases []Release) {
 	if len(releases) == 0 {
 		fmt.Printf("No releases found for %s\n", repo)
-		return nil
+		return
 	}
 
-	fmt.Printf("Releases for %s:\n\n", repo)
-
-	for i, release := range releases {
-		fmt.Printf("%d. %s", i+1, release.Name)
-		if release.TagName != "" && release.TagName != release.Name {
-			fmt.Printf(" (%s)", release.TagName)
-		}
+	if err := printTable(func(tp tableprinter.TablePrinter, isTTY bool) {
+		writeReleaseTable(tp, isTTY, releases)
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to print releases: %v\n", err)
+	}
+}
 
-		var status []string
-		if release.Draft {
-			status = append(status, "draft")
-		}
-		if release.Prerelease {
-			status = append(status, "prerelease")
+// writeReleaseTable adds a row per release, titled by its name or else its
+// tag. Terminals get dates and reactions by kind; piped output gets full
+// timestamps and reaction counts.
+func writeReleaseTable(tp tableprinter.TablePrinter, isTTY bool, releases []Release) {
+	tp.AddHeader([]string{"TITLE", "TYPE", "TAG NAME", "TARGET", "PUBLISHED", "AUTHOR", "ASSETS", "REACTIONS", "DISCUSSION"}, tableprinter.WithColor(color.Stdout.Gray))
+	for _, release := range releases {
+		title := release.Name
+		if title == "" {
+			title = release.TagName
 		}
-		if len(status) > 0 {
-			fmt.Printf(" [%s]", strings.Join(status, ", "))
+		var kind string
+		kindColor := color.Stdout.Red
+		switch {
+		case release.Draft:
+			kind = "Draft"
+		case release.Prerelease:
+			kind = "Pre-release"
+			kindColor = color.Stdout.Yellow
 		}
-		fmt.Printf("\n")
 
-		if release.PublishedAt != "" {
-			fmt.Printf("   Published: %s\n", formatDate(release.PublishedAt))
+		tp.AddField(title, tableprinter.WithColor(color.Stdout.Bold))
+		tp.AddField(kind, tableprinter.WithColor(kindColor))
+		tp.AddField(release.TagName)
+		tp.AddField(release.TargetCommitish)
+		if isTTY {
+			tp.AddField(formatDate(release.PublishedAt))
+		} else {
+			tp.AddField(release.PublishedAt)
 		}
-
-		fmt.Printf("   Assets: %d\n", len(release.Assets))
-
-		if i < len(releases)-1 {
-			fmt.Println()
+		tp.AddField(release.Author.Login)
+		tp.AddField(strconv.Itoa(len(release.Assets)))
+		switch count := release.ReactionCount(); {
+		case !isTTY:
+			tp.AddField(strconv.Itoa(count))
+		case count > 0:
+			tp.AddField(fmt.Sprintf("%d (%s)", count, release.Reactions))
+		default:
+			tp.AddField("")
 		}
+		tp.AddField(release.DiscussionURL)
+		tp.EndRow()
 	}
-
-	fmt.Printf("\nTotal: %d releases\n", len(releases))
-	return nil
 }
 
 func formatDate(dateStr string) string {
@@ -154,3 +369,85 @@ func formatDate(dateStr string) string {
 	}
 	return dateStr
 }
+
+// Commit is the subset of a commit returned by the commits endpoint
+type Commit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string `json:"name"`
+			Date string `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+func GetCommit(client HTTPClient, repo, ref string) (*Commit, error) {
+	endpoint := fmt.Sprintf("repos/%s/commits/%s", repo, url.PathEscape(ref))
+
+	var commit Commit
+	if err := client.Get(endpoint, &commit); err != nil {
+		return nil, err
+	}
+
+	return &commit, nil
+}
+
+// ContentEntry is an entry returned by the repository contents endpoint.
+// Submodules have the type "submodule" and carry the pinned commit in SHA.
+type ContentEntry struct {
+	Type            string `json:"type"`
+	Path            string `json:"path"`
+	SHA             string `json:"sha"`
+	Content         string `json:"content"`
+	Encoding        string `json:"encoding"`
+	SubmoduleGitURL string `json:"submodule_git_url"`
+}
+
+// Decoded returns the file content of the entry
+func (e *ContentEntry) Decoded() ([]byte, error) {
+	if e.Encoding != "base64" {
+		return []byte(e.Content), nil
+	}
+	return base64.StdEncoding.DecodeString(strings.ReplaceAll(e.Content, "\n", ""))
+}
+
+func GetContentEntry(client HTTPClient, repo, filePath, ref string) (*ContentEntry, error) {
+	segments := strings.Split(filePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	endpoint := fmt.Sprintf("repos/%s/contents/%s?ref=%s", repo, strings.Join(segments, "/"), url.QueryEscape(ref))
+
+	var entry ContentEntry
+	if err := client.Get(endpoint, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Repository is the subset of a repository returned by the repository
+// endpoint. Permissions are only reported for authenticated requests.
+type Repository struct {
+	FullName    string `json:"full_name"`
+	Private     bool   `json:"private"`
+	Archived    bool   `json:"archived"`
+	Permissions *struct {
+		Pull bool `json:"pull"`
+	} `json:"permissions"`
+	Fork bool `json:"fork"`
+	// Parent is the repository a fork was forked from, and Source the root
+	// of its fork network
+	Parent *Repository `json:"parent,omitempty"`
+	Source *Repository `json:"source,omitempty"`
+}
+
+func GetRepository(client HTTPClient, repo string) (*Repository, error) {
+	var repository Repository
+	if err := client.Get(fmt.Sprintf("repos/%s", repo), &repository); err != nil {
+		return nil, err
+	}
+
+	return &repository, nil
+}
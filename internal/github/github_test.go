@@ -2,11 +2,15 @@ package github
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/23prime/gh-download/internal/color"
+	"github.com/cli/go-gh/v2/pkg/tableprinter"
 )
 
 // captureOutput captures stdout during function execution
@@ -329,8 +333,9 @@ func TestFilterAssets_ComplexPattern(t *testing.T) {
 }
 
 func TestListAssets_WithMatches(t *testing.T) {
+	t.Setenv("GH_FORCE_TTY", "")
 	assets := []Asset{
-		{Name: "app-linux.tar.gz", Size: 1024, ContentType: "application/x-gtar"},
+		{Name: "app-linux.tar.gz", Size: 1024, ContentType: "application/x-gtar", UpdatedAt: "2024-05-01T12:00:00Z"},
 		{Name: "app-windows.zip", Size: 2048, ContentType: "application/zip"},
 		{Name: "checksums.txt", Size: 256, ContentType: "text/plain"},
 	}
@@ -342,20 +347,45 @@ func TestListAssets_WithMatches(t *testing.T) {
 		}
 	})
 
-	// Check output contains expected elements
-	expectedStrings := []string{
-		"Assets matching pattern '*.tar.gz':",
-		"1. app-linux.tar.gz",
-		"Size: 1024 bytes",
-		"Content-Type: application/x-gtar",
-		"Total: 1 assets",
+	// Piped output is tab-separated without a header
+	expected := "app-linux.tar.gz\t1024\tapplication/x-gtar\t2024-05-01T12:00:00Z\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestWriteAssetTable_TTY(t *testing.T) {
+	assets := []Asset{
+		{Name: "app-linux.tar.gz", Size: 1536, ContentType: "application/x-gtar", UpdatedAt: "2024-05-01T12:00:00Z"},
+		{Name: "checksums.txt", Size: 256, ContentType: "text/plain", UpdatedAt: "2024-05-02T12:00:00Z"},
 	}
 
-	for _, expected := range expectedStrings {
-		if !strings.Contains(output, expected) {
-			t.Errorf("Expected output to contain %q, but it was missing", expected)
+	var buf bytes.Buffer
+	tp := tableprinter.New(&buf, true, 120)
+	writeAssetTable(tp, true, assets)
+	if err := tp.Render(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %q", buf.String())
+	}
+	for i, fields := range [][]string{
+		{"NAME", "SIZE", "TYPE", "UPDATED"},
+		{"app-linux.tar.gz", "1.5 KiB", "application/x-gtar", "2024-05-01"},
+		{"checksums.txt", "256 B", "text/plain", "2024-05-02"},
+	} {
+		for _, field := range fields {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("Expected line %d to contain %q, got %q", i, field, lines[i])
+			}
 		}
 	}
+	// Columns are aligned
+	if strings.Index(lines[1], "1.5 KiB") != strings.Index(lines[0], "SIZE") {
+		t.Errorf("Expected aligned columns, got %q", buf.String())
+	}
 }
 
 func TestListAssets_NoMatches(t *testing.T) {
@@ -378,6 +408,7 @@ func TestListAssets_NoMatches(t *testing.T) {
 }
 
 func TestListAssets_AllAssets(t *testing.T) {
+	t.Setenv("GH_FORCE_TTY", "")
 	assets := []Asset{
 		{Name: "app.tar.gz", Size: 1024, ContentType: "application/x-gtar"},
 		{Name: "app.zip", Size: 2048, ContentType: "application/zip"},
@@ -390,17 +421,9 @@ func TestListAssets_AllAssets(t *testing.T) {
 		}
 	})
 
-	expectedStrings := []string{
-		"Assets matching pattern '*':",
-		"1. app.tar.gz",
-		"2. app.zip",
-		"Total: 2 assets",
-	}
-
-	for _, expected := range expectedStrings {
-		if !strings.Contains(output, expected) {
-			t.Errorf("Expected output to contain %q, but it was missing", expected)
-		}
+	expected := "app.tar.gz\t1024\tapplication/x-gtar\t\napp.zip\t2048\tapplication/zip\t\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
 	}
 }
 
@@ -461,22 +484,10 @@ func TestListReleases_WithReleases(t *testing.T) {
 		}
 	})
 
-	// Check output contains expected elements
-	expectedStrings := []string{
-		"Releases for owner/repo:",
-		"1. Release v1.0.0 (v1.0.0)",
-		"Published: 2023-12-01",
-		"Assets: 2",
-		"2. Release v0.9.0 (v0.9.0) [draft, prerelease]",
-		"Published: 2023-11-15",
-		"Assets: 1",
-		"Total: 2 releases",
-	}
-
-	for _, expected := range expectedStrings {
-		if !strings.Contains(output, expected) {
-			t.Errorf("Expected output to contain %q, but it was missing", expected)
-		}
+	expected := "Release v1.0.0\t\tv1.0.0\t\t2023-12-01T10:00:00Z\t\t2\t0\t\n" +
+		"Release v0.9.0\tDraft\tv0.9.0\t\t2023-11-15T15:30:00Z\t\t1\t0\t\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
 	}
 }
 
@@ -549,11 +560,292 @@ func TestListReleases_SameTitleAndTag(t *testing.T) {
 		}
 	})
 
-	// When name and tag are the same, tag should not be shown in parentheses
-	if strings.Contains(output, "v2.0.0 (v2.0.0)") {
-		t.Error("Expected tag not to be shown when it's the same as name")
+	if !strings.HasPrefix(output, "v2.0.0\t\tv2.0.0\t") {
+		t.Errorf("Expected the title and tag columns, got %q", output)
+	}
+}
+
+func TestGetCommit(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/commits/v1.0.0"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+
+			if commit, ok := response.(*Commit); ok {
+				commit.SHA = "abc123"
+			}
+			return nil
+		},
+	}
+
+	commit, err := GetCommit(mockClient, "owner/repo", "v1.0.0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if commit.SHA != "abc123" {
+		t.Errorf("Expected SHA 'abc123', got %q", commit.SHA)
+	}
+}
+
+func TestGetContentEntry(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/contents/third%20party/lib?ref=abc123"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+
+			if entry, ok := response.(*ContentEntry); ok {
+				entry.Type = "submodule"
+				entry.SHA = "def456"
+			}
+			return nil
+		},
+	}
+
+	entry, err := GetContentEntry(mockClient, "owner/repo", "third party/lib", "abc123")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if entry.Type != "submodule" || entry.SHA != "def456" {
+		t.Errorf("Expected submodule at 'def456', got %s at %q", entry.Type, entry.SHA)
+	}
+}
+
+func TestContentEntry_Decoded(t *testing.T) {
+	entry := ContentEntry{Encoding: "base64", Content: "W3N1Ym1v\nZHVsZV0K\n"}
+
+	content, err := entry.Decoded()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(content) != "[submodule]\n" {
+		t.Errorf("Expected decoded content, got %q", content)
+	}
+}
+
+func TestReactions_String(t *testing.T) {
+	reactions := Reactions{TotalCount: 12, PlusOne: 10, Rocket: 2}
+	if got := reactions.String(); got != "+1 10, rocket 2" {
+		t.Errorf("Expected %q, got %q", "+1 10, rocket 2", got)
+	}
+}
+
+func TestFilterByReactions(t *testing.T) {
+	releases := []Release{
+		{TagName: "v1.0.0", Reactions: &Reactions{TotalCount: 3}},
+		{TagName: "v0.9.0"},
+	}
+
+	if got := FilterByReactions(releases, 0); len(got) != 2 {
+		t.Errorf("Expected all releases without a minimum, got %d", len(got))
+	}
+	got := FilterByReactions(releases, 1)
+	if len(got) != 1 || got[0].TagName != "v1.0.0" {
+		t.Errorf("Expected only v1.0.0, got %+v", got)
+	}
+}
+
+func TestPrintReleases_Reactions(t *testing.T) {
+	t.Setenv("GH_FORCE_TTY", "")
+	releases := []Release{{
+		Name:          "v1.0.0",
+		TagName:       "v1.0.0",
+		PublishedAt:   "2024-05-01T12:00:00Z",
+		Reactions:     &Reactions{TotalCount: 3, Heart: 3},
+		DiscussionURL: "https://github.com/owner/repo/discussions/7",
+		Author:        User{Login: "github-actions[bot]", Type: "Bot"},
+	}}
+
+	output := captureOutput(func() {
+		PrintReleases("owner/repo", releases)
+	})
+
+	expected := "v1.0.0\t\tv1.0.0\t\t2024-05-01T12:00:00Z\tgithub-actions[bot]\t0\t3\thttps://github.com/owner/repo/discussions/7\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestWriteReleaseTable_TTY(t *testing.T) {
+	releases := []Release{
+		{Name: "App 2.0", TagName: "v2.0.0", Prerelease: true, PublishedAt: "2024-06-01T12:00:00Z", Reactions: &Reactions{TotalCount: 3, Heart: 3}},
+		{TagName: "v1.0.0", Draft: true, Author: User{Login: "alice"}, Assets: []Asset{{Name: "app.zip"}}},
+	}
+
+	var buf bytes.Buffer
+	tp := tableprinter.New(&buf, true, 200)
+	writeReleaseTable(tp, true, releases)
+	if err := tp.Render(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %q", buf.String())
+	}
+	for i, fields := range [][]string{
+		{"TITLE", "TYPE", "TAG NAME", "PUBLISHED", "AUTHOR", "ASSETS", "REACTIONS"},
+		{"App 2.0", "Pre-release", "v2.0.0", "2024-06-01", "3 (heart 3)"},
+		{"v1.0.0", "Draft", "alice", "1"},
+	} {
+		for _, field := range fields {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("Expected line %d to contain %q, got %q", i, field, lines[i])
+			}
+		}
+	}
+}
+
+func TestWriteReleaseTable_Color(t *testing.T) {
+	if err := color.Setup(color.ModeAlways); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := color.Setup(color.ModeNever); err != nil {
+			t.Error(err)
+		}
+	})
+	releases := []Release{{TagName: "v1.0.0", Draft: true}}
+
+	var buf bytes.Buffer
+	tp := tableprinter.New(&buf, true, 200)
+	writeReleaseTable(tp, true, releases)
+	if err := tp.Render(); err != nil {
+		t.Fatal(err)
+	}
+	for _, colored := range []string{"\x1b[1mv1.0.0\x1b[0m", "\x1b[31mDraft\x1b[0m", "\x1b[90mTITLE"} {
+		if !strings.Contains(buf.String(), colored) {
+			t.Errorf("Expected %q in the table, got %q", colored, buf.String())
+		}
+	}
+
+	// Piped output is never colored
+	buf.Reset()
+	tp = tableprinter.New(&buf, false, 0)
+	writeReleaseTable(tp, false, releases)
+	if err := tp.Render(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("Expected no color when piped, got %q", buf.String())
+	}
+}
+
+func TestResolveCommitSHA(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			expectedEndpoint := "repos/owner/repo/commits/v1.0.0"
+			if endpoint != expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", expectedEndpoint, endpoint)
+			}
+			response.(*Commit).SHA = "0123456789abcdef"
+			return nil
+		},
+	}
+
+	release := &Release{TagName: "v1.0.0", TargetCommitish: "main"}
+	if err := ResolveCommitSHA(mockClient, "owner/repo", release); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if release.CommitSHA != "0123456789abcdef" {
+		t.Errorf("Expected commit SHA to be resolved, got %q", release.CommitSHA)
+	}
+
+	output := captureOutput(func() {
+		PrintReleases("owner/repo", []Release{*release})
+	})
+	if !strings.Contains(output, "\tmain\t") {
+		t.Errorf("Expected output to contain the target, got %q", output)
+	}
+}
+
+func TestFilterByAuthor(t *testing.T) {
+	releases := []Release{
+		{TagName: "v2.0.0", Author: User{Login: "someone", Type: "User"}},
+		{TagName: "v1.0.0", Author: User{Login: "github-actions[bot]", Type: "Bot"}},
+	}
+
+	if got := FilterByAuthor(releases, ""); len(got) != 2 {
+		t.Errorf("Expected all releases without an author, got %d", len(got))
+	}
+	got := FilterByAuthor(releases, "GitHub-Actions[bot]")
+	if len(got) != 1 || got[0].TagName != "v1.0.0" {
+		t.Errorf("Expected only v1.0.0, got %+v", got)
+	}
+}
+
+func TestLatestStable(t *testing.T) {
+	releases := []Release{
+		{TagName: "v3.0.0", Draft: true},
+		{TagName: "v2.1.0-rc.1", Prerelease: true},
+		{TagName: "v2.0.0"},
+	}
+
+	release, ok := LatestStable(releases)
+	if !ok || release.TagName != "v2.0.0" {
+		t.Errorf("Expected v2.0.0, got %+v", release)
+	}
+	if _, ok := LatestStable(releases[:2]); ok {
+		t.Error("Expected no stable release, got one")
+	}
+}
+
+func TestGetAllReleases(t *testing.T) {
+	var endpoints []string
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			endpoints = append(endpoints, endpoint)
+			count := releasesPerPage
+			if strings.HasSuffix(endpoint, "page=2") {
+				count = 3
+			}
+			releases := make([]Release, count)
+			for i := range releases {
+				releases[i].ID = len(endpoints)*1000 + i
+			}
+			*response.(*[]Release) = releases
+			return nil
+		},
+	}
+
+	releases, err := GetAllReleases(mockClient, "owner/repo")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(releases) != releasesPerPage+3 {
+		t.Errorf("Expected %d releases, got %d", releasesPerPage+3, len(releases))
+	}
+	expected := []string{"repos/owner/repo/releases?per_page=100&page=1", "repos/owner/repo/releases?per_page=100&page=2"}
+	if strings.Join(endpoints, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected endpoints %v, got %v", expected, endpoints)
+	}
+
+	mockClient.GetFunc = func(endpoint string, response interface{}) error {
+		return fmt.Errorf("API error")
+	}
+	if _, err := GetAllReleases(mockClient, "owner/repo"); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
+
+func TestGetRepository(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			if endpoint != "repos/owner/repo" {
+				t.Errorf("Expected endpoint %q, got %q", "repos/owner/repo", endpoint)
+			}
+			return json.Unmarshal([]byte(`{"full_name":"owner/repo","private":true,"permissions":{"pull":true}}`), response)
+		},
+	}
+
+	repository, err := GetRepository(mockClient, "owner/repo")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
 	}
-	if !strings.Contains(output, "1. v2.0.0") {
-		t.Error("Expected release name to be shown")
+	if !repository.Private || repository.Permissions == nil || !repository.Permissions.Pull {
+		t.Errorf("Unexpected repository %+v", repository)
 	}
 }
@@ -0,0 +1,23 @@
+package github
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// GPGKey is a public GPG key an account added to GitHub. RawKey is the
+// armored key as uploaded.
+type GPGKey struct {
+	KeyID  string `json:"key_id"`
+	RawKey string `json:"raw_key"`
+}
+
+// GetUserGPGKeys returns the public GPG keys of an account
+func GetUserGPGKeys(client HTTPClient, login string) ([]GPGKey, error) {
+	var keys []GPGKey
+	if err := client.Get(fmt.Sprintf("users/%s/gpg_keys", url.PathEscape(login)), &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,27 @@
+package github
+
+import (
+	"testing"
+)
+
+func TestGetUserGPGKeys(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			if endpoint != "users/octocat/gpg_keys" {
+				t.Errorf("Expected endpoint %q, got %q", "users/octocat/gpg_keys", endpoint)
+			}
+			if keys, ok := response.(*[]GPGKey); ok {
+				*keys = []GPGKey{{KeyID: "3262EFF25BA0D270", RawKey: "-----BEGIN PGP PUBLIC KEY BLOCK-----"}}
+			}
+			return nil
+		},
+	}
+
+	keys, err := GetUserGPGKeys(mockClient, "octocat")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keys) != 1 || keys[0].KeyID != "3262EFF25BA0D270" {
+		t.Errorf("Expected the key 3262EFF25BA0D270, got %+v", keys)
+	}
+}
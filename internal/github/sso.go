@@ -0,0 +1,35 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// ssoHeader is set on responses refused because the token is not authorized
+// for the SAML single sign-on of the organization owning the resource
+const ssoHeader = "X-GitHub-SSO"
+
+// SSOAuthorizationURL returns the URL where the token must be authorized for
+// the single sign-on of an organization, when err is a 403 refused for that
+// reason, or an empty string. The header reads
+// "required; url=https://github.com/orgs/ORG/sso?authorization_request=...".
+func SSOAuthorizationURL(err error) string {
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+		return ""
+	}
+
+	directives := strings.Split(httpErr.Headers.Get(ssoHeader), ";")
+	if strings.TrimSpace(directives[0]) != "required" {
+		return ""
+	}
+	for _, directive := range directives[1:] {
+		if url, ok := strings.CutPrefix(strings.TrimSpace(directive), "url="); ok {
+			return url
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+func TestSSOAuthorizationURL(t *testing.T) {
+	ssoErr := func(status int, header string) error {
+		headers := http.Header{}
+		if header != "" {
+			headers.Set("X-GitHub-SSO", header)
+		}
+		return fmt.Errorf("failed to get release: %w", &api.HTTPError{StatusCode: status, Headers: headers})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "required",
+			err:  ssoErr(403, "required; url=https://github.com/orgs/acme/sso?authorization_request=abc"),
+			want: "https://github.com/orgs/acme/sso?authorization_request=abc",
+		},
+		{
+			name: "partial results",
+			err:  ssoErr(403, "partial-results; organizations=21955855"),
+		},
+		{
+			name: "no header",
+			err:  ssoErr(403, ""),
+		},
+		{
+			name: "not a 403",
+			err:  ssoErr(404, "required; url=https://github.com/orgs/acme/sso"),
+		},
+		{
+			name: "not an HTTP error",
+			err:  fmt.Errorf("connection reset"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SSOAuthorizationURL(tt.err); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Strategies choosing a release among all releases when no tag is given.
+// Drafts and prereleases are never chosen.
+const (
+	// StrategyMostDownloaded chooses the release whose assets were
+	// downloaded most, the newest on a tie
+	StrategyMostDownloaded = "most-downloaded"
+	// StrategyMostRecentStable chooses the newest stable release, which
+	// need not be the one marked as latest
+	StrategyMostRecentStable = "most-recent-stable"
+	// StrategyHighestSemver chooses the release with the highest semantic
+	// version tag, ignoring tags that are not versions
+	StrategyHighestSemver = "highest-semver"
+)
+
+// releaseVersionPattern matches stable version tags such as v1.2.3 or 1.2;
+// tags with a prerelease suffix are not stable versions
+var releaseVersionPattern = regexp.MustCompile(`^[vV]?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:\+.*)?$`)
+
+// SelectRelease returns the release the strategy chooses among releases,
+// which are listed newest first
+func SelectRelease(releases []Release, strategy string) (*Release, error) {
+	var best *Release
+	var bestDownloads int
+	var bestVersion [3]int
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || release.Prerelease {
+			continue
+		}
+
+		switch strategy {
+		case StrategyMostRecentStable:
+			return release, nil
+		case StrategyMostDownloaded:
+			if downloads := release.DownloadCount(); best == nil || downloads > bestDownloads {
+				best, bestDownloads = release, downloads
+			}
+		case StrategyHighestSemver:
+			version, ok := parseVersion(release.TagName)
+			if ok && (best == nil || compareVersions(version, bestVersion) > 0) {
+				best, bestVersion = release, version
+			}
+		default:
+			return nil, fmt.Errorf("invalid strategy '%s': must be most-downloaded, most-recent-stable or highest-semver", strategy)
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no stable release to choose with the %s strategy", strategy)
+	}
+	return best, nil
+}
+
+// DownloadCount returns the number of downloads of all assets of the release
+func (r *Release) DownloadCount() int {
+	var count int
+	for _, asset := range r.Assets {
+		count += asset.DownloadCount
+	}
+	return count
+}
+
+// parseVersion parses a stable version tag into its major, minor and patch
+// numbers
+func parseVersion(tag string) ([3]int, bool) {
+	var version [3]int
+	match := releaseVersionPattern.FindStringSubmatch(tag)
+	if match == nil {
+		return version, false
+	}
+	for i, part := range match[1:4] {
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] > b[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
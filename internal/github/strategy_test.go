@@ -0,0 +1,68 @@
+package github
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectRelease(t *testing.T) {
+	releases := []Release{
+		{TagName: "v3.0.0-rc.1", Prerelease: true, Assets: []Asset{{DownloadCount: 900}}},
+		{TagName: "v2.0.0-draft", Draft: true},
+		{TagName: "v1.10.0", Assets: []Asset{{DownloadCount: 10}, {DownloadCount: 5}}},
+		{TagName: "nightly-2024-01-01", Assets: []Asset{{DownloadCount: 40}}},
+		{TagName: "v1.9.2", Assets: []Asset{{DownloadCount: 40}}},
+		{TagName: "v1.2", Assets: []Asset{{DownloadCount: 3}}},
+	}
+
+	tests := []struct {
+		strategy string
+		want     string
+	}{
+		{StrategyMostRecentStable, "v1.10.0"},
+		{StrategyMostDownloaded, "nightly-2024-01-01"},
+		{StrategyHighestSemver, "v1.10.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			release, err := SelectRelease(releases, tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if release.TagName != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, release.TagName)
+			}
+		})
+	}
+
+	if _, err := SelectRelease(releases, "newest"); err == nil || !strings.Contains(err.Error(), "invalid strategy") {
+		t.Errorf("Expected invalid strategy error, got %v", err)
+	}
+	if _, err := SelectRelease(releases[:2], StrategyMostRecentStable); err == nil {
+		t.Error("Expected error without stable releases, got nil")
+	}
+	if _, err := SelectRelease([]Release{{TagName: "nightly"}}, StrategyHighestSemver); err == nil {
+		t.Error("Expected error without version tags, got nil")
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want [3]int
+		ok   bool
+	}{
+		{"v1.2.3", [3]int{1, 2, 3}, true},
+		{"2.0", [3]int{2, 0, 0}, true},
+		{"V7", [3]int{7, 0, 0}, true},
+		{"v1.2.3+build.5", [3]int{1, 2, 3}, true},
+		{"v1.2.3-rc.1", [3]int{}, false},
+		{"release-1", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.tag)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: expected %v %v, got %v %v", tt.tag, tt.want, tt.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package github
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Ref is a git reference and the object it points to
+type Ref struct {
+	Ref    string `json:"ref"`
+	Object struct {
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"object"`
+}
+
+// Verification is GitHub's verdict on the signature of a git object.
+// Payload is the signed content and Signature the armored signature.
+type Verification struct {
+	Verified  bool   `json:"verified"`
+	Reason    string `json:"reason"`
+	Signature string `json:"signature"`
+	Payload   string `json:"payload"`
+}
+
+// Tag is an annotated tag object
+type Tag struct {
+	Tag     string `json:"tag"`
+	SHA     string `json:"sha"`
+	Message string `json:"message"`
+	Tagger  struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		Date  string `json:"date"`
+	} `json:"tagger"`
+	Object struct {
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"object"`
+	Verification Verification `json:"verification"`
+}
+
+func GetTagRef(client HTTPClient, repo, tag string) (*Ref, error) {
+	endpoint := fmt.Sprintf("repos/%s/git/ref/tags/%s", repo, url.PathEscape(tag))
+
+	var ref Ref
+	if err := client.Get(endpoint, &ref); err != nil {
+		return nil, err
+	}
+
+	return &ref, nil
+}
+
+// GetAnnotatedTag returns the tag object that the tag points to. Lightweight
+// tags point to a commit directly and have no tag object, so they cannot be
+// signed; they return an error.
+func GetAnnotatedTag(client HTTPClient, repo, tag string) (*Tag, error) {
+	ref, err := GetTagRef(client, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Object.Type != "tag" {
+		return nil, fmt.Errorf("tag %s is a lightweight tag pointing to a %s, not an annotated tag", tag, ref.Object.Type)
+	}
+
+	var object Tag
+	if err := client.Get(fmt.Sprintf("repos/%s/git/tags/%s", repo, ref.Object.SHA), &object); err != nil {
+		return nil, err
+	}
+
+	return &object, nil
+}
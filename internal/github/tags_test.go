@@ -0,0 +1,53 @@
+package github
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetAnnotatedTag(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			switch endpoint {
+			case "repos/owner/repo/git/ref/tags/v1.0.0":
+				ref := response.(*Ref)
+				ref.Object.Type = "tag"
+				ref.Object.SHA = "abc123"
+			case "repos/owner/repo/git/tags/abc123":
+				tag := response.(*Tag)
+				tag.Tag = "v1.0.0"
+				tag.Verification = Verification{Verified: true, Reason: "valid"}
+			default:
+				t.Errorf("Unexpected endpoint %q", endpoint)
+			}
+			return nil
+		},
+	}
+
+	tag, err := GetAnnotatedTag(mockClient, "owner/repo", "v1.0.0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tag.Tag != "v1.0.0" || !tag.Verification.Verified {
+		t.Errorf("Unexpected tag %+v", tag)
+	}
+}
+
+func TestGetAnnotatedTag_Lightweight(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			if ref, ok := response.(*Ref); ok {
+				ref.Object.Type = "commit"
+				ref.Object.SHA = "abc123"
+				return nil
+			}
+			t.Errorf("Expected no request for a tag object, got %q", endpoint)
+			return nil
+		},
+	}
+
+	_, err := GetAnnotatedTag(mockClient, "owner/repo", "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "lightweight") {
+		t.Errorf("Expected lightweight tag error, got %v", err)
+	}
+}
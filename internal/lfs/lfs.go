@@ -0,0 +1,229 @@
+package lfs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PointerVersion is the spec line every Git LFS pointer file starts with
+const PointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+// MaxPointerSize is the largest size of a pointer file; anything bigger is
+// real content
+const MaxPointerSize = 1024
+
+// batchSize is the number of objects requested per batch API call
+const batchSize = 100
+
+const mediaType = "application/vnd.git-lfs+json"
+
+// Pointer identifies an LFS object by its SHA-256 and size
+type Pointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// ParsePointer parses the content of a pointer file, reporting false when
+// data is not a pointer
+func ParsePointer(data []byte) (Pointer, bool) {
+	if len(data) > MaxPointerSize || !bytes.HasPrefix(data, []byte(PointerVersion+"\n")) {
+		return Pointer{}, false
+	}
+
+	var pointer Pointer
+	var hasSize bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if !ok || len(oid) != sha256.Size*2 {
+				return Pointer{}, false
+			}
+			if _, err := hex.DecodeString(oid); err != nil {
+				return Pointer{}, false
+			}
+			pointer.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return Pointer{}, false
+			}
+			pointer.Size = size
+			hasSize = true
+		}
+	}
+
+	return pointer, pointer.OID != "" && hasSize
+}
+
+// ReadPointer reads the file at path and parses it as a pointer. Files
+// larger than MaxPointerSize are not read.
+func ReadPointer(path string) (Pointer, bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Pointer{}, false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() > MaxPointerSize {
+		return Pointer{}, false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Pointer{}, false, err
+	}
+
+	pointer, ok := ParsePointer(data)
+	return pointer, ok, nil
+}
+
+// Endpoint returns the batch API endpoint of a repository on host
+func Endpoint(host, repo string) string {
+	return fmt.Sprintf("https://%s/%s.git/info/lfs/objects/batch", host, repo)
+}
+
+// Action is a transfer the batch API asks the client to perform
+type Action struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+// ObjectError is the per-object error reported by the batch API
+type ObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Object is an object in a batch API response
+type Object struct {
+	Pointer
+	Actions struct {
+		Download *Action `json:"download"`
+	} `json:"actions"`
+	Error *ObjectError `json:"error"`
+}
+
+// Client talks to the LFS batch API of one repository
+type Client struct {
+	http     *http.Client
+	endpoint string
+	token    string
+}
+
+// NewClient returns a client for the batch API at endpoint. The token is
+// sent with basic authentication when it is not empty.
+func NewClient(httpClient *http.Client, endpoint, token string) *Client {
+	return &Client{http: httpClient, endpoint: endpoint, token: token}
+}
+
+// Batch asks the batch API where to download pointers from
+func (c *Client) Batch(pointers []Pointer) ([]Object, error) {
+	var objects []Object
+	for start := 0; start < len(pointers); start += batchSize {
+		end := min(start+batchSize, len(pointers))
+		batch, err := c.batch(pointers[start:end])
+		if err != nil {
+			return objects, err
+		}
+		objects = append(objects, batch...)
+	}
+	return objects, nil
+}
+
+func (c *Client) batch(pointers []Pointer) ([]Object, error) {
+	body, err := json.Marshal(map[string]any{
+		"operation": "download",
+		"transfers": []string{"basic"},
+		"objects":   pointers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", mediaType)
+	req.Header.Set("Content-Type", mediaType)
+	if c.token != "" {
+		req.SetBasicAuth("x-access-token", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call LFS batch API: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LFS batch API returned %s", resp.Status)
+	}
+
+	var result struct {
+		Objects []Object `json:"objects"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode LFS batch response: %w", err)
+	}
+	return result.Objects, nil
+}
+
+// Download writes the content of object to w, checking its size and SHA-256
+func (c *Client) Download(object Object, w io.Writer) error {
+	if object.Error != nil {
+		return fmt.Errorf("LFS object %s: %s", object.OID, object.Error.Message)
+	}
+	if object.Actions.Download == nil {
+		return fmt.Errorf("LFS object %s has no download action", object.OID)
+	}
+
+	req, err := http.NewRequest("GET", object.Actions.Download.Href, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range object.Actions.Download.Header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download LFS object %s: %w", object.OID, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download LFS object %s: %s", object.OID, resp.Status)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download LFS object %s: %w", object.OID, err)
+	}
+	if n != object.Size {
+		return fmt.Errorf("LFS object %s has %d bytes, expected %d", object.OID, n, object.Size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != object.OID {
+		return fmt.Errorf("LFS object %s has checksum %s", object.OID, sum)
+	}
+	return nil
+}
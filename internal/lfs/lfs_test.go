@@ -0,0 +1,134 @@
+package lfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func pointerFile(content string) (string, string) {
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+	return oid, fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", PointerVersion, oid, len(content))
+}
+
+func TestParsePointer(t *testing.T) {
+	oid, pointer := pointerFile("large binary")
+
+	parsed, ok := ParsePointer([]byte(pointer))
+	if !ok {
+		t.Fatal("Expected pointer to be parsed")
+	}
+	if parsed.OID != oid || parsed.Size != int64(len("large binary")) {
+		t.Errorf("Unexpected pointer %+v", parsed)
+	}
+
+	testCases := []string{
+		"plain text",
+		PointerVersion + "\nsize 12\n",
+		PointerVersion + "\noid sha256:abc\nsize 12\n",
+		PointerVersion + "\noid sha256:" + oid + "\n",
+		strings.Repeat("x", MaxPointerSize+1),
+	}
+	for _, tc := range testCases {
+		if _, ok := ParsePointer([]byte(tc)); ok {
+			t.Errorf("Expected %q not to be a pointer", tc)
+		}
+	}
+}
+
+func TestReadPointer(t *testing.T) {
+	dir := t.TempDir()
+	_, pointer := pointerFile("content")
+	path := filepath.Join(dir, "asset.bin")
+	if err := os.WriteFile(path, []byte(pointer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := ReadPointer(path); err != nil || !ok {
+		t.Errorf("Expected pointer, got ok=%t err=%v", ok, err)
+	}
+
+	large := filepath.Join(dir, "large.bin")
+	if err := os.WriteFile(large, bytes.Repeat([]byte("x"), MaxPointerSize+1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := ReadPointer(large); err != nil || ok {
+		t.Errorf("Expected no pointer, got ok=%t err=%v", ok, err)
+	}
+}
+
+func TestClient_BatchAndDownload(t *testing.T) {
+	content := "large binary"
+	oid, _ := pointerFile(content)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/owner/repo.git/info/lfs/objects/batch":
+			if r.Header.Get("Accept") != mediaType {
+				t.Errorf("Expected Accept %q, got %q", mediaType, r.Header.Get("Accept"))
+			}
+			if _, password, ok := r.BasicAuth(); !ok || password != "secret" {
+				t.Errorf("Expected basic auth with the token")
+			}
+			var request struct {
+				Operation string    `json:"operation"`
+				Objects   []Pointer `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Fatal(err)
+			}
+			if request.Operation != "download" || len(request.Objects) != 1 {
+				t.Errorf("Unexpected batch request %+v", request)
+			}
+			_, _ = fmt.Fprintf(w, `{"objects":[{"oid":%q,"size":%d,"actions":{"download":{"href":%q,"header":{"X-Test":"1"}}}}]}`,
+				oid, len(content), server.URL+"/objects/"+oid)
+		case "/objects/" + oid:
+			if r.Header.Get("X-Test") != "1" {
+				t.Errorf("Expected action header to be sent")
+			}
+			_, _ = w.Write([]byte(content))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL+"/owner/repo.git/info/lfs/objects/batch", "secret")
+	objects, err := client.Batch([]Pointer{{OID: oid, Size: int64(len(content))}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("Expected 1 object, got %d", len(objects))
+	}
+
+	var buf bytes.Buffer
+	if err := client.Download(objects[0], &buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.String() != content {
+		t.Errorf("Expected %q, got %q", content, buf.String())
+	}
+
+	objects[0].OID = strings.Repeat("0", 64)
+	if err := client.Download(objects[0], &bytes.Buffer{}); err == nil {
+		t.Error("Expected checksum mismatch error, got nil")
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	expected := "https://github.com/owner/repo.git/info/lfs/objects/batch"
+	if got := Endpoint("github.com", "owner/repo"); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
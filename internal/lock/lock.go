@@ -0,0 +1,83 @@
+// Package lock provides advisory file locks that keep concurrent runs out of
+// the same directory. The operating system releases a lock when the process
+// holding it exits, so a crashed run never leaves a directory locked.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned when another process holds the lock
+var ErrLocked = errors.New("locked by another process")
+
+// pollInterval is how often a waiting Acquire retries the lock
+const pollInterval = 100 * time.Millisecond
+
+// Lock is a held advisory lock on a file
+type Lock struct {
+	file *os.File
+}
+
+// Acquire locks the file at path, creating it if needed. When another
+// process holds the lock it waits up to wait for it to be released, and
+// then returns an error wrapping ErrLocked that names the holder.
+func Acquire(path string, wait time.Duration) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err = tryLock(file)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrLocked) || !time.Now().Before(deadline) {
+			holder := Holder(path)
+			if closeErr := file.Close(); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close lock file: %v\n", closeErr)
+			}
+			if errors.Is(err, ErrLocked) && holder != "" {
+				return nil, fmt.Errorf("%w (%s)", err, holder)
+			}
+			return nil, err
+		}
+		time.Sleep(pollInterval)
+	}
+
+	// Record the holder for the error messages of other processes
+	if err := file.Truncate(0); err == nil {
+		if _, err := file.WriteAt([]byte(fmt.Sprintf("pid %d since %s\n", os.Getpid(), time.Now().Format(time.RFC3339))), 0); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write lock file: %v\n", err)
+		}
+	}
+	return &Lock{file: file}, nil
+}
+
+// Holder returns the description the holder of the lock at path recorded,
+// or an empty string when it is unknown
+func Holder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Release unlocks the file. The lock file itself is kept: removing it would
+// let a process waiting on the old file and one creating a new file both
+// think they hold the lock.
+func (l *Lock) Release() error {
+	if err := unlock(l.file); err != nil {
+		if closeErr := l.file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close lock file: %v\n", closeErr)
+		}
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return l.file.Close()
+}
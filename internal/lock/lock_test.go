@@ -0,0 +1,90 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquire_Contention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir.lock")
+
+	held, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = Acquire(path, 0)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked, got %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("Expected the error to name the holder, got %v", err)
+	}
+
+	start := time.Now()
+	if _, err := Acquire(path, 3*pollInterval); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked after waiting, got %v", err)
+	}
+	if waited := time.Since(start); waited < 3*pollInterval {
+		t.Errorf("Expected to wait %v, waited %v", 3*pollInterval, waited)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	again, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatalf("Expected the released lock to be acquired, got %v", err)
+	}
+	if err := again.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquire_WaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir.lock")
+
+	held, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(2 * pollInterval)
+		if err := held.Release(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	waiter, err := Acquire(path, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Expected the lock once released, got %v", err)
+	}
+	if err := waiter.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir.lock")
+	if got := Holder(path); got != "" {
+		t.Errorf("Expected no holder without a lock file, got %q", got)
+	}
+
+	held, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := held.Release(); err != nil {
+			t.Error(err)
+		}
+	}()
+	if got := Holder(path); !strings.HasPrefix(got, fmt.Sprintf("pid %d since ", os.Getpid())) {
+		t.Errorf("Unexpected holder %q", got)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockedOffset is where the locked byte lies, far beyond the content, so the
+// holder description at the start stays readable for other processes
+const lockedOffset = 1 << 30
+
+func tryLock(file *os.File) error {
+	overlapped := windows.Overlapped{Offset: lockedOffset}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrLocked
+	}
+	return err
+}
+
+func unlock(file *os.File) error {
+	overlapped := windows.Overlapped{Offset: lockedOffset}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
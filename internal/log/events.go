@@ -0,0 +1,84 @@
+package log
+
+import "time"
+
+// Envelope holds the properties every line of the json format starts with.
+// The fields of the event follow, as the struct of the event encodes them.
+type Envelope struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+}
+
+// Message is a "log" event: a message of --verbose or --debug
+type Message struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// HTTPTrace is an "http" event: a request traced with --debug-http
+type HTTPTrace struct {
+	Trace string `json:"trace"`
+}
+
+// DownloadStart is a "download_start" event, logged as an asset starts
+// downloading with its expected size
+type DownloadStart struct {
+	Asset string `json:"asset"`
+	Path  string `json:"path"`
+	Size  int    `json:"size"`
+}
+
+// DownloadResume is a "download_resume" event, logged when the transfer of
+// an asset continues a partial file from offset
+type DownloadResume struct {
+	Asset  string `json:"asset"`
+	Offset int64  `json:"offset"`
+}
+
+// DownloadDone is a "download_done" event, logged once an asset is
+// downloaded and verified
+type DownloadDone struct {
+	Asset      string `json:"asset"`
+	Bytes      int64  `json:"bytes"`
+	Cached     bool   `json:"cached"`
+	DurationMS int64  `json:"duration_ms"`
+	Path       string `json:"path"`
+}
+
+// AssetFailed is a "verify_failed" event for an asset that was downloaded
+// but did not verify, and a "download_failed" event for other failures
+type AssetFailed struct {
+	Asset string `json:"asset"`
+	Error string `json:"error"`
+}
+
+// Retry is a "retry" event, logged before a failed operation is retried
+type Retry struct {
+	Attempt int    `json:"attempt"`
+	Class   string `json:"class"`
+	Error   string `json:"error"`
+	Label   string `json:"label"`
+	Retries int    `json:"retries"`
+	WaitMS  int64  `json:"wait_ms"`
+}
+
+// Failure is the "error" event ending a failed run
+type Failure struct {
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+	SSOURL   string `json:"sso_url,omitempty"`
+}
+
+// Events are the events of the json format by name, each with a value of
+// the struct it is written from, for schema events
+var Events = map[string]any{
+	"log":             Message{},
+	"http":            HTTPTrace{},
+	"download_start":  DownloadStart{},
+	"download_resume": DownloadResume{},
+	"download_done":   DownloadDone{},
+	"verify_failed":   AssetFailed{},
+	"download_failed": AssetFailed{},
+	"retry":           Retry{},
+	"error":           Failure{},
+}
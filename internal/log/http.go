@@ -0,0 +1,144 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+var traceHTTP bool
+
+// traceHeaders are the response headers --debug-http shows: the rate limit,
+// the redirect target and the request ID GitHub support asks for
+var traceHeaders = []string{
+	"Location",
+	"X-Ratelimit-Limit",
+	"X-Ratelimit-Remaining",
+	"X-Ratelimit-Used",
+	"X-Ratelimit-Reset",
+	"X-Ratelimit-Resource",
+	"Retry-After",
+	"X-Github-Request-Id",
+}
+
+// SetTraceHTTP turns the HTTP tracing of --debug-http on or off
+func SetTraceHTTP(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	traceHTTP = on
+}
+
+// TracingHTTP reports whether HTTP requests are traced
+func TracingHTTP() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return traceHTTP
+}
+
+// TraceTransport traces the requests made through next with --debug-http:
+// the method, URL and headers of each request, then the status, time taken
+// and rate-limit headers of its response. Each hop of a redirect is a
+// request of its own, so redirect chains show as a series of them, each
+// with its Location. Credentials in headers and in the query of signed URLs
+// are redacted. Traces are written whole, at any level, so concurrent
+// transfers do not interleave.
+func TraceTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !TracingHTTP() {
+			return next.RoundTrip(req)
+		}
+
+		var trace strings.Builder
+		fmt.Fprintf(&trace, "> %s %s\n", req.Method, redactURL(req.URL))
+		for _, name := range slices.Sorted(maps.Keys(req.Header)) {
+			for _, value := range req.Header[name] {
+				fmt.Fprintf(&trace, "> %s: %s\n", name, redactHeader(name, value))
+			}
+		}
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			fmt.Fprintf(&trace, "! failed after %v: %v\n", elapsed, err)
+			write(trace.String())
+			return nil, err
+		}
+		fmt.Fprintf(&trace, "< %s in %v\n", resp.Status, elapsed)
+		for _, name := range traceHeaders {
+			value := resp.Header.Get(name)
+			if value == "" {
+				continue
+			}
+			if name == "Location" {
+				value = redactLocation(req.URL, value)
+			}
+			fmt.Fprintf(&trace, "< %s: %s\n", name, value)
+		}
+		write(trace.String())
+		return resp, nil
+	})
+}
+
+// write writes text as it is, at any level, or as an "http" event in the
+// json format
+func write(text string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if jsonFormat {
+		writeJSON("http", HTTPTrace{Trace: strings.TrimSuffix(text, "\n")})
+		return
+	}
+	if _, err := io.WriteString(out, text); err != nil {
+		return
+	}
+}
+
+// redactHeader hides the value of headers carrying credentials
+func redactHeader(name, value string) string {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization":
+		scheme, _, found := strings.Cut(value, " ")
+		if found {
+			return scheme + " [redacted]"
+		}
+		return "[redacted]"
+	case "Cookie", "Set-Cookie", "X-Amz-Security-Token":
+		return "[redacted]"
+	default:
+		return value
+	}
+}
+
+// redactURL hides the query parameters of signed URLs, such as those of the
+// storage that release assets redirect to, which grant access on their own
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	changed := false
+	for name := range query {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "signature") || strings.Contains(lower, "credential") ||
+			strings.Contains(lower, "token") || lower == "sig" || lower == "jwt" {
+			query.Set(name, "[redacted]")
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.Redacted()
+}
+
+func redactLocation(base *url.URL, location string) string {
+	u, err := base.Parse(location)
+	if err != nil {
+		return "[unparsable location]"
+	}
+	return redactURL(u)
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/asset":
+			http.Redirect(w, r, "/storage?X-Amz-Signature=secret&name=app.zip", http.StatusFound)
+		default:
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: TraceTransport(http.DefaultTransport)}
+
+	buf := capture(t, LevelInfo)
+	t.Cleanup(func() { SetTraceHTTP(false) })
+
+	get := func() {
+		req, err := http.NewRequest("GET", server.URL+"/asset", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "token ghp_secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get()
+	if buf.Len() != 0 {
+		t.Errorf("Expected no trace without --debug-http, got %q", buf.String())
+	}
+
+	SetTraceHTTP(true)
+	get()
+	trace := buf.String()
+	for _, expected := range []string{
+		"> GET " + server.URL + "/asset\n",
+		"> Authorization: token [redacted]\n",
+		"< 302 Found in ",
+		"< Location: " + server.URL + "/storage?X-Amz-Signature=%5Bredacted%5D&name=app.zip\n",
+		"> GET " + server.URL + "/storage?X-Amz-Signature=%5Bredacted%5D&name=app.zip\n",
+		"< 200 OK in ",
+		"< X-Ratelimit-Remaining: 4999\n",
+		"< X-Github-Request-Id: ABCD:1234\n",
+	} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("Expected %q in the trace, got %q", expected, trace)
+		}
+	}
+	if strings.Contains(trace, "secret") {
+		t.Errorf("Expected credentials to be redacted, got %q", trace)
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"authorization", "Bearer abc", "Bearer [redacted]"},
+		{"Authorization", "abc", "[redacted]"},
+		{"Cookie", "session=abc", "[redacted]"},
+		{"Accept", "application/json", "application/json"},
+	}
+	for _, tc := range testCases {
+		if got := redactHeader(tc.name, tc.value); got != tc.expected {
+			t.Errorf("Expected %q for %s, got %q", tc.expected, tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Formats of the diagnostics, chosen with --log-format
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats lists the valid values of --log-format
+var Formats = []string{FormatText, FormatJSON}
+
+var jsonFormat bool
+
+// now is replaced in tests
+var now = time.Now
+
+// SetFormat sets the format of the diagnostics. In the json format each
+// line is a JSON object with its time, the name of the event and its
+// fields, for CI systems to parse.
+func SetFormat(format string) error {
+	switch format {
+	case "", FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("invalid log format '%s': must be one of %s", format, strings.Join(Formats, ", "))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	jsonFormat = format == FormatJSON
+	return nil
+}
+
+// JSON reports whether diagnostics are written as JSON objects
+func JSON() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return jsonFormat
+}
+
+// Event logs an event of the run, such as download_start or retry, with
+// the struct of the event given in Events. In the json format it is written
+// at every level as an object with the fields of event; in the text format
+// the line built from format is logged at level l instead, and nothing when
+// format is empty.
+func Event(l Level, name string, event any, format string, args ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	if jsonFormat {
+		writeJSON(name, event)
+		return
+	}
+	if format == "" || level < l {
+		return
+	}
+	// Diagnostics must not fail the run
+	if _, err := fmt.Fprintf(out, format+"\n", args...); err != nil {
+		return
+	}
+}
+
+// writeJSON writes an event as a JSON object on one line: its Envelope, then
+// the fields of the struct of the event. The caller holds mu.
+func writeJSON(name string, event any) {
+	envelope, err := json.Marshal(Envelope{Time: now().UTC(), Event: name})
+	if err != nil {
+		return
+	}
+	fields, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	var line strings.Builder
+	line.Write(envelope[:len(envelope)-1])
+	if fields = bytes.TrimSuffix(bytes.TrimPrefix(fields, []byte("{")), []byte("}")); len(fields) > 0 {
+		line.WriteString(",")
+		line.Write(fields)
+	}
+	line.WriteString("}\n")
+	if _, err := fmt.Fprint(out, line.String()); err != nil {
+		return
+	}
+}
+
+// levelNames name the levels of messages in the json format
+var levelNames = map[Level]string{
+	LevelInfo:    "info",
+	LevelVerbose: "verbose",
+	LevelDebug:   "debug",
+}
+
+// writeMessage writes a message, which may span lines, as a "log" event.
+// The caller holds mu.
+func writeMessage(l Level, message string) {
+	writeJSON("log", Message{Level: levelNames[l], Message: strings.TrimSuffix(message, "\n")})
+}
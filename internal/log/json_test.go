@@ -0,0 +1,77 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+// jsonFormatFor writes JSON objects at a fixed time for the rest of the test
+func jsonFormatFor(t *testing.T) {
+	t.Helper()
+	if err := SetFormat(FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() {
+		if err := SetFormat(FormatText); err != nil {
+			t.Error(err)
+		}
+		now = time.Now
+	})
+}
+
+func TestEvent_Text(t *testing.T) {
+	buf := capture(t, LevelInfo)
+
+	Event(LevelInfo, "retry", Retry{Attempt: 1}, "Retrying %s", "app.zip")
+	Event(LevelVerbose, "download_done", DownloadDone{Bytes: 10}, "app.zip: %d bytes", 10)
+	Event(LevelInfo, "download_start", DownloadStart{Asset: "app.zip"}, "")
+
+	if got := buf.String(); got != "Retrying app.zip\n" {
+		t.Errorf("Expected only the retry line at the info level, got %q", got)
+	}
+}
+
+func TestEvent_JSON(t *testing.T) {
+	buf := capture(t, LevelInfo)
+	jsonFormatFor(t)
+
+	Event(LevelVerbose, "download_done", DownloadDone{Asset: "app.zip", Bytes: 10, DurationMS: 1500, Path: "app.zip"}, "app.zip: %d bytes", 10)
+	Event(LevelDebug, "verify_failed", AssetFailed{Asset: "app.zip", Error: "digest mismatch"}, "")
+	Verbosef("not logged at the info level")
+	Infof("Retrying %s", "app.zip")
+
+	expected := `{"time":"2026-01-02T03:04:05Z","event":"download_done","asset":"app.zip","bytes":10,"cached":false,"duration_ms":1500,"path":"app.zip"}` + "\n" +
+		`{"time":"2026-01-02T03:04:05Z","event":"verify_failed","asset":"app.zip","error":"digest mismatch"}` + "\n" +
+		`{"time":"2026-01-02T03:04:05Z","event":"log","level":"info","message":"Retrying app.zip"}` + "\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestWriter_JSON(t *testing.T) {
+	buf := capture(t, LevelDebug)
+	jsonFormatFor(t)
+
+	if _, err := Writer().Write([]byte("* Request to https://api.github.com\n")); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"time":"2026-01-02T03:04:05Z","event":"log","level":"debug","message":"* Request to https://api.github.com"}` + "\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	t.Cleanup(func() {
+		if err := SetFormat(FormatText); err != nil {
+			t.Error(err)
+		}
+	})
+	if err := SetFormat("xml"); err == nil {
+		t.Error("Expected an error for an unknown format, got nil")
+	}
+	if err := SetFormat(FormatJSON); err != nil || !JSON() {
+		t.Errorf("Expected the json format, got %v", err)
+	}
+}
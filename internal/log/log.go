@@ -0,0 +1,184 @@
+// Package log writes diagnostics to stderr, apart from the output of
+// commands on stdout, at the level --verbose, --debug and gh's debug
+// environment variables ask for.
+package log
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is how much is logged
+type Level int
+
+const (
+	// LevelInfo logs nothing beyond what commands print themselves
+	LevelInfo Level = iota
+	// LevelVerbose adds what happened along the way: cache hits, what
+	// --head-check found and how long releases and transfers took
+	LevelVerbose
+	// LevelDebug adds the HTTP requests made and what was skipped
+	LevelDebug
+)
+
+var (
+	mu       sync.Mutex
+	out      io.Writer = os.Stderr
+	level              = LevelInfo
+	debugAPI bool
+)
+
+// FromEnv sets the level from the variables gh itself reads: GH_DEBUG (or
+// DEBUG, its older name, when GH_DEBUG is unset) set to anything but "",
+// "0", "false" or "no" enables debug output, and a value containing "api",
+// as in GH_DEBUG=api, also logs the headers and bodies of API requests.
+// GH_VERBOSE enables verbose output the same way.
+func FromEnv() {
+	debug, ok := os.LookupEnv("GH_DEBUG")
+	if !ok {
+		debug = os.Getenv("DEBUG")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	level = LevelInfo
+	debugAPI = false
+	switch {
+	case isSet(debug):
+		level = LevelDebug
+		debugAPI = strings.Contains(debug, "api")
+	case isSet(os.Getenv("GH_VERBOSE")):
+		level = LevelVerbose
+	}
+}
+
+func isSet(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "0", "false", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+// SetLevel sets the level, keeping GH_DEBUG=api
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// Raise raises the level to l, keeping a higher one set from the
+// environment
+func Raise(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = max(level, l)
+}
+
+// SetOutput sets where diagnostics are written, stderr by default, and
+// returns where they were written before
+func SetOutput(w io.Writer) io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+	previous := out
+	out = w
+	return previous
+}
+
+// Enabled reports whether messages of level l are logged
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return level >= l
+}
+
+// DebugAPI reports whether the headers and bodies of API requests are
+// logged, as with GH_DEBUG=api
+func DebugAPI() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return level >= LevelDebug && debugAPI
+}
+
+// Writer returns where diagnostics are written, for loggers of other
+// packages such as the request logging of go-gh clients. In the json format
+// each write becomes a debug message.
+func Writer() io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if jsonFormat {
+			writeMessage(LevelDebug, string(p))
+			return len(p), nil
+		}
+		return out.Write(p)
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// Infof logs a line at every level, for notices such as retries
+func Infof(format string, args ...any) {
+	logf(LevelInfo, format, args...)
+}
+
+// Verbosef logs a line at LevelVerbose
+func Verbosef(format string, args ...any) {
+	logf(LevelVerbose, format, args...)
+}
+
+// Debugf logs a line at LevelDebug
+func Debugf(format string, args ...any) {
+	logf(LevelDebug, "[debug] "+format, args...)
+}
+
+func logf(l Level, format string, args ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	if level < l {
+		return
+	}
+	if jsonFormat {
+		writeMessage(l, fmt.Sprintf(format, args...))
+		return
+	}
+	// Diagnostics must not fail the run
+	if _, err := fmt.Fprintf(out, format+"\n", args...); err != nil {
+		return
+	}
+}
+
+// Transport logs the requests made through next at LevelDebug, for HTTP
+// clients other than go-gh's, which log their own
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !Enabled(LevelDebug) {
+			return next.RoundTrip(req)
+		}
+		Debugf("> %s %s", req.Method, req.URL.Redacted())
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			Debugf("< %s %s failed after %v: %v", req.Method, req.URL.Redacted(), time.Since(start).Round(time.Millisecond), err)
+			return nil, err
+		}
+		Debugf("< %s in %v", resp.Status, time.Since(start).Round(time.Millisecond))
+		return resp, nil
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,131 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// capture logs to a buffer at level l for the rest of the test
+func capture(t *testing.T, l Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetLevel(l)
+	t.Cleanup(func() {
+		SetOutput(os.Stderr)
+		SetLevel(LevelInfo)
+	})
+	return &buf
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Cleanup(func() { SetLevel(LevelInfo) })
+
+	testCases := []struct {
+		debug    string
+		legacy   string
+		verbose  string
+		level    Level
+		debugAPI bool
+	}{
+		{"", "", "", LevelInfo, false},
+		{"1", "", "", LevelDebug, false},
+		{"api", "", "", LevelDebug, true},
+		{"false", "", "1", LevelVerbose, false},
+		{"no", "1", "", LevelInfo, false},
+		{"0", "", "0", LevelInfo, false},
+	}
+	for _, tc := range testCases {
+		t.Setenv("GH_DEBUG", tc.debug)
+		t.Setenv("DEBUG", tc.legacy)
+		t.Setenv("GH_VERBOSE", tc.verbose)
+		FromEnv()
+		if !Enabled(tc.level) || (tc.level < LevelDebug && Enabled(tc.level+1)) {
+			t.Errorf("Expected level %d for GH_DEBUG=%q GH_VERBOSE=%q", tc.level, tc.debug, tc.verbose)
+		}
+		if DebugAPI() != tc.debugAPI {
+			t.Errorf("Expected DebugAPI %v for GH_DEBUG=%q, got %v", tc.debugAPI, tc.debug, DebugAPI())
+		}
+	}
+}
+
+func TestFromEnv_LegacyDebug(t *testing.T) {
+	t.Cleanup(func() { SetLevel(LevelInfo) })
+	t.Setenv("DEBUG", "api")
+	t.Setenv("GH_VERBOSE", "")
+	// DEBUG only counts when GH_DEBUG is unset; Setenv restores it
+	t.Setenv("GH_DEBUG", "")
+	if err := os.Unsetenv("GH_DEBUG"); err != nil {
+		t.Fatal(err)
+	}
+	FromEnv()
+	if !Enabled(LevelDebug) || !DebugAPI() {
+		t.Error("Expected DEBUG=api to enable debug output with API bodies")
+	}
+}
+
+func TestLevels(t *testing.T) {
+	buf := capture(t, LevelVerbose)
+	Verbosef("found %d", 2)
+	Debugf("hidden")
+	if buf.String() != "found 2\n" {
+		t.Errorf("Expected only the verbose line, got %q", buf.String())
+	}
+
+	SetLevel(LevelDebug)
+	Debugf("shown")
+	if !strings.HasSuffix(buf.String(), "[debug] shown\n") {
+		t.Errorf("Expected the debug line, got %q", buf.String())
+	}
+}
+
+func TestRaise(t *testing.T) {
+	capture(t, LevelDebug)
+	Raise(LevelVerbose)
+	if !Enabled(LevelDebug) {
+		t.Error("Expected Raise to keep a higher level")
+	}
+	SetLevel(LevelInfo)
+	Raise(LevelVerbose)
+	if !Enabled(LevelVerbose) || Enabled(LevelDebug) {
+		t.Error("Expected Raise to raise the level to verbose")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+
+	buf := capture(t, LevelInfo)
+	resp, err := client.Get(server.URL + "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no logging below debug, got %q", buf.String())
+	}
+
+	SetLevel(LevelDebug)
+	resp, err = client.Get(server.URL + "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"> GET " + server.URL + "/file", "< 404 Not Found in "} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in the log, got %q", expected, buf.String())
+		}
+	}
+}
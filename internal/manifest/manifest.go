@@ -0,0 +1,169 @@
+// Package manifest reads tool manifests: named, curated release downloads
+// that teams share so everyone installs the same releases and assets.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/checksum"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the manifest a tap repository keeps at its root
+const FileName = "gh-download.yml"
+
+// Tool is a curated download from the releases of a repository
+type Tool struct {
+	Repo        string `yaml:"repo"`
+	Tag         string `yaml:"tag,omitempty"`
+	Pattern     string `yaml:"pattern,omitempty"`
+	Dir         string `yaml:"dir,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// Host is the GitHub host of the repository, such as a GitHub
+	// Enterprise Server; empty for the default host
+	Host string `yaml:"host,omitempty"`
+	// DependsOn names the tools to sync before this one, such as the
+	// signing key its assets are checked with
+	DependsOn []string `yaml:"depends_on,omitempty"`
+	// Digests pin assets by name to their digests as "algorithm:hex", as
+	// freeze writes them. Only these assets are synced, and each must
+	// have its digest.
+	Digests map[string]string `yaml:"digests,omitempty"`
+	// Files name the file an asset is saved as below the tool directory,
+	// by asset name, where that is not the asset name, as freeze writes
+	// them for files renamed with -O, --output-template or --rename-by-type
+	Files map[string]string `yaml:"files,omitempty"`
+}
+
+// Manifest is a set of tools by name
+type Manifest struct {
+	Tools map[string]Tool `yaml:"tools"`
+}
+
+// Parse parses and validates a manifest. Unknown fields are rejected so that
+// typos do not silently install something else.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	for _, name := range m.Names() {
+		if err := validateTool(name, m.Tools[name]); err != nil {
+			return nil, err
+		}
+		for _, dependency := range m.Tools[name].DependsOn {
+			if _, ok := m.Tools[dependency]; !ok {
+				return nil, fmt.Errorf("tool %s: depends on unknown tool %q", name, dependency)
+			}
+		}
+	}
+	if _, err := m.order(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Write writes the manifest as YAML, the tools sorted by name
+func (m *Manifest) Write(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return encoder.Close()
+}
+
+// Load reads and parses the manifest at path
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return Parse(data)
+}
+
+// Names returns the names of the tools in the manifest, sorted
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.Tools))
+	for name := range m.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Order returns the names of the tools with each after the tools it depends
+// on, and otherwise sorted
+func (m *Manifest) Order() []string {
+	// Parse rejects cycles
+	names, err := m.order()
+	if err != nil {
+		return m.Names()
+	}
+	return names
+}
+
+// order sorts the tools topologically, always taking the first tool by name
+// whose dependencies are placed, and fails on a dependency cycle
+func (m *Manifest) order() ([]string, error) {
+	placed := make(map[string]bool, len(m.Tools))
+	remaining := m.Names()
+	names := make([]string, 0, len(remaining))
+	for len(remaining) > 0 {
+		next := -1
+		for i, name := range remaining {
+			ready := true
+			for _, dependency := range m.Tools[name].DependsOn {
+				if !placed[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("dependency cycle between tools %s", strings.Join(remaining, ", "))
+		}
+		placed[remaining[next]] = true
+		names = append(names, remaining[next])
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return names, nil
+}
+
+func validateTool(name string, tool Tool) error {
+	if name == "" || strings.ContainsAny(name, "/ \t") {
+		return fmt.Errorf("invalid tool name %q", name)
+	}
+	owner, repo, ok := strings.Cut(tool.Repo, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return fmt.Errorf("tool %s: repo must be in format owner/repo, got %q", name, tool.Repo)
+	}
+	if strings.ContainsAny(tool.Host, "/: \t") {
+		return fmt.Errorf("tool %s: host must be a host name such as ghe.example.com, got %q", name, tool.Host)
+	}
+	for asset, digest := range tool.Digests {
+		if _, err := checksum.ParseDigest(digest); err != nil {
+			return fmt.Errorf("tool %s: asset %s: %w", name, asset, err)
+		}
+	}
+	for asset, file := range tool.Files {
+		if !filepath.IsLocal(filepath.FromSlash(file)) {
+			return fmt.Errorf("tool %s: asset %s: file must be a relative path below the tool directory, got %q", name, asset, file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := `
+tools:
+  gh:
+    repo: cli/cli
+    pattern: "*_linux_amd64.tar.gz"
+    description: GitHub CLI
+  jq:
+    repo: jqlang/jq
+    tag: jq-1.7.1
+    dir: ~/bin
+`
+	m, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := strings.Join(m.Names(), ","); got != "gh,jq" {
+		t.Errorf("Expected tools gh,jq, got %s", got)
+	}
+	if jq := m.Tools["jq"]; jq.Repo != "jqlang/jq" || jq.Tag != "jq-1.7.1" || jq.Dir != "~/bin" {
+		t.Errorf("Unexpected tool %+v", jq)
+	}
+
+	if m, err := Parse(nil); err != nil || len(m.Tools) != 0 {
+		t.Errorf("Expected an empty manifest, got %+v, %v", m, err)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	testCases := []string{
+		"tools:\n  gh:\n    repo: cli\n",
+		"tools:\n  gh:\n    repo: cli/cli/extra\n",
+		"tools:\n  a/b:\n    repo: cli/cli\n",
+		"tools:\n  gh:\n    repo: cli/cli\n    host: https://ghe.example.com\n",
+		"tools:\n  gh:\n    repo: cli/cli\n    patern: \"*\"\n",
+		"tools: [",
+		"tools:\n  gh:\n    repo: cli/cli\n    digests:\n      gh.tar.gz: md5:d41d8cd98f00b204e9800998ecf8427e\n",
+		"tools:\n  gh:\n    repo: cli/cli\n    digests:\n      gh.tar.gz: sha256:abc\n",
+		"tools:\n  gh:\n    repo: cli/cli\n    files:\n      gh.tar.gz: ../gh.tar.gz\n",
+	}
+	for _, tc := range testCases {
+		if _, err := Parse([]byte(tc)); err == nil {
+			t.Errorf("Expected error for %q, got nil", tc)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	m := &Manifest{Tools: map[string]Tool{
+		"jq": {Repo: "jqlang/jq", Tag: "jq-1.7.1", Digests: map[string]string{"jq-linux-amd64": "sha256:" + strings.Repeat("a", 64)}},
+		"gh": {Repo: "cli/cli", Dir: "bin"},
+	}}
+
+	var out strings.Builder
+	if err := m.Write(&out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "tools:\n  gh:\n    repo: cli/cli\n    dir: bin\n") {
+		t.Errorf("Expected the tools sorted with their set fields, got:\n%s", out.String())
+	}
+
+	parsed, err := Parse([]byte(out.String()))
+	if err != nil {
+		t.Fatalf("Expected the written manifest to parse, got %v", err)
+	}
+	if jq := parsed.Tools["jq"]; jq.Tag != "jq-1.7.1" || jq.Digests["jq-linux-amd64"] != m.Tools["jq"].Digests["jq-linux-amd64"] {
+		t.Errorf("Expected jq to round-trip, got %+v", jq)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	data := `
+tools:
+  app:
+    repo: acme/app
+    depends_on: [checksums, key]
+  checksums:
+    repo: acme/checksums
+    depends_on: [key]
+  key:
+    repo: acme/keys
+  zeta:
+    repo: acme/zeta
+`
+	m, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := strings.Join(m.Order(), ","); got != "key,checksums,app,zeta" {
+		t.Errorf("Expected dependencies first, got %s", got)
+	}
+}
+
+func TestParse_InvalidDependencies(t *testing.T) {
+	testCases := []struct {
+		data     string
+		expected string
+	}{
+		{"tools:\n  a:\n    repo: o/a\n    depends_on: [missing]\n", `tool a: depends on unknown tool "missing"`},
+		{"tools:\n  a:\n    repo: o/a\n    depends_on: [a]\n", "dependency cycle between tools a"},
+		{"tools:\n  a:\n    repo: o/a\n    depends_on: [b]\n  b:\n    repo: o/b\n    depends_on: [a]\n  c:\n    repo: o/c\n", "dependency cycle between tools a, b"},
+	}
+	for _, tc := range testCases {
+		_, err := Parse([]byte(tc.data))
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Expected %q, got %v", tc.expected, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("tools:\n  gh:\n    repo: cli/cli\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if m.Tools["gh"].Repo != "cli/cli" {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("Expected error for a missing manifest, got nil")
+	}
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Objects is a store of content by SHA-256 digest, such as a cas.Store
+type Objects interface {
+	// Has reports whether the content with digest is stored
+	Has(digest string) bool
+	// Open opens the content with digest
+	Open(digest string) (*os.File, error)
+	// Add stores the content read from r under digest, unless it has
+	// another digest
+	Add(digest string, r io.Reader) error
+}
+
+type digestKey struct{}
+
+// WithDigest returns a context for a request that fetches the content with
+// the given SHA-256 digest, for Cache
+func WithDigest(ctx context.Context, digest string) context.Context {
+	return context.WithValue(ctx, digestKey{}, digest)
+}
+
+// cacheHeader marks the responses Cache served from its objects
+const cacheHeader = "X-Gh-Download-Cache"
+
+// FromCache reports whether Cache served resp from its objects
+func FromCache(resp *http.Response) bool {
+	return resp.Header.Get(cacheHeader) == "hit"
+}
+
+// errIncomplete aborts adding a body that was closed before its end
+var errIncomplete = errors.New("response body closed before its end")
+
+// Cache serves GET requests for the whole content with a known digest, see
+// WithDigest, from objects without sending them. Otherwise the complete
+// body of a 200 response to such a request is added to objects as it is
+// read; partial and cut bodies are not, and neither is content with another
+// digest.
+func Cache(objects Objects) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			digest, ok := req.Context().Value(digestKey{}).(string)
+			if !ok || digest == "" || req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+				return next.Do(req)
+			}
+
+			if objects.Has(digest) {
+				file, err := objects.Open(digest)
+				if err == nil {
+					return cachedResponse(req, file)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: failed to read sha256:%s from the cache: %v\n", digest, err)
+			}
+
+			resp, err := next.Do(req)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				return resp, err
+			}
+			resp.Body = newCachingBody(resp.Body, objects, digest)
+			return resp, nil
+		})
+	}
+}
+
+// cachedResponse answers req with the content of file
+func cachedResponse(req *http.Request, file *os.File) (*http.Response, error) {
+	info, err := file.Stat()
+	if err != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", file.Name(), closeErr)
+		}
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set(cacheHeader, "hit")
+	header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          file,
+		ContentLength: info.Size(),
+		Request:       req,
+	}, nil
+}
+
+// cachingBody passes what is read from a body on to objects.Add, which
+// stores it once the body was read to its end
+type cachingBody struct {
+	body   io.ReadCloser
+	pipe   *io.PipeWriter
+	done   chan error
+	digest string
+	ended  bool
+	closed bool
+}
+
+func newCachingBody(body io.ReadCloser, objects Objects, digest string) *cachingBody {
+	reader, writer := io.Pipe()
+	b := &cachingBody{body: body, pipe: writer, done: make(chan error, 1), digest: digest}
+	go func() {
+		err := objects.Add(digest, reader)
+		// Unblock the writer when Add stopped reading early
+		if closeErr := reader.CloseWithError(err); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		b.done <- err
+	}()
+	return b
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 && !b.ended {
+		if _, writeErr := b.pipe.Write(p[:n]); writeErr != nil {
+			// Add gave up; the response is still read to its end
+			b.ended = true
+		}
+	}
+	if err == io.EOF && !b.ended {
+		b.ended = true
+		if closeErr := b.pipe.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to cache sha256:%s: %v\n", b.digest, closeErr)
+		}
+	}
+	return n, err
+}
+
+// Close closes the body, and waits for the content to be stored when the
+// body was read to its end, or discards it otherwise
+func (b *cachingBody) Close() error {
+	err := b.body.Close()
+	if b.closed {
+		return err
+	}
+	b.closed = true
+	if closeErr := b.pipe.CloseWithError(errIncomplete); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to discard sha256:%s: %v\n", b.digest, closeErr)
+	}
+	if addErr := <-b.done; addErr != nil && !errors.Is(addErr, errIncomplete) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache sha256:%s: %v\n", b.digest, addErr)
+	}
+	return err
+}
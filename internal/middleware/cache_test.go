@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/23prime/gh-download/internal/cas"
+)
+
+// serveBody returns a Doer answering every request with 200 and the body
+// body returns, counting the requests
+func serveBody(body func() io.Reader, calls *int) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		*calls++
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(body()), Request: req}, nil
+	})
+}
+
+func digestRequest(t *testing.T, digest string) *http.Request {
+	req := newRequest(t, "GET", nil)
+	return req.WithContext(WithDigest(context.Background(), digest))
+}
+
+func TestCache(t *testing.T) {
+	content := "asset content"
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+
+	testCases := []struct {
+		name   string
+		body   func() io.Reader
+		digest string
+		read   func(io.Reader) error
+		stored bool
+	}{
+		{
+			name:   "complete body",
+			body:   func() io.Reader { return strings.NewReader(content) },
+			digest: digest,
+			read:   func(r io.Reader) error { _, err := io.ReadAll(r); return err },
+			stored: true,
+		},
+		{
+			name:   "cut body",
+			body:   func() io.Reader { return cutReader{strings.NewReader(content[:5])} },
+			digest: digest,
+			read:   func(r io.Reader) error { _, err := io.ReadAll(r); return err },
+		},
+		{
+			name:   "closed before its end",
+			body:   func() io.Reader { return strings.NewReader(content) },
+			digest: digest,
+			read:   func(r io.Reader) error { _, err := r.Read(make([]byte, 5)); return err },
+		},
+		{
+			name:   "other content",
+			body:   func() io.Reader { return strings.NewReader("tampered content") },
+			digest: digest,
+			read:   func(r io.Reader) error { _, err := io.ReadAll(r); return err },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := cas.Store{Root: t.TempDir()}
+			calls := 0
+			resp, err := Chain(serveBody(tc.body, &calls), Cache(objects)).Do(digestRequest(t, tc.digest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if FromCache(resp) {
+				t.Error("Expected a miss not to be marked as served from the cache")
+			}
+			if err := tc.read(resp.Body); err != nil && tc.stored {
+				t.Fatal(err)
+			}
+			if err := resp.Body.Close(); err != nil {
+				t.Error(err)
+			}
+			if objects.Has(tc.digest) != tc.stored {
+				t.Errorf("Expected stored %v, got %v", tc.stored, objects.Has(tc.digest))
+			}
+		})
+	}
+}
+
+func TestCache_Hit(t *testing.T) {
+	content := "asset content"
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+	objects := cas.Store{Root: t.TempDir()}
+	if err := objects.Add(digest, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	doer := Chain(serveBody(func() io.Reader { return strings.NewReader(content) }, &calls), Cache(objects))
+	resp, err := doer.Do(digestRequest(t, digest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(resp.Body)
+	if err != nil || string(got) != content {
+		t.Errorf("Expected %q, got %q and %v", content, got, err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Error(err)
+	}
+	if !FromCache(resp) || resp.ContentLength != int64(len(content)) || calls != 0 {
+		t.Errorf("Expected the content from the cache without a request, got %v, %d bytes and %d requests", FromCache(resp), resp.ContentLength, calls)
+	}
+
+	// Neither a part of the content nor a request without a digest is
+	// served from the cache
+	req := digestRequest(t, digest)
+	req.Header.Set("Range", "bytes=5-")
+	for _, req := range []*http.Request{req, newRequest(t, "GET", nil)} {
+		resp, err := doer.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Error(err)
+		}
+		if FromCache(resp) {
+			t.Errorf("Expected %v to be sent, got a response from the cache", req.Header)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 requests, got %d", calls)
+	}
+}
@@ -0,0 +1,205 @@
+// Package middleware composes the HTTP concerns of transfers, such as
+// headers, status checks, rate limits, retries and tracing, as layers around
+// a minimal Doer, so that each can be tested on its own.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/23prime/gh-download/internal/tracing"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// Doer sends HTTP requests; *http.Client is one
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to a Doer
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer with one concern
+type Middleware func(next Doer) Doer
+
+// Chain wraps doer with middlewares. The first middleware sees the request
+// first and the response last.
+func Chain(doer Doer, middlewares ...Middleware) Doer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		doer = middlewares[i](doer)
+	}
+	return doer
+}
+
+// Headers sets headers on requests that do not set them already
+func Headers(headers map[string]string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for key, value := range headers {
+				if req.Header.Get(key) == "" {
+					req.Header.Set(key, value)
+				}
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// CheckStatus turns responses of 400 and above into *api.HTTPError, closing
+// their body, so that the layers above only see successful responses
+func CheckStatus() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+			if err != nil || resp.StatusCode < http.StatusBadRequest {
+				return resp, err
+			}
+			err = api.HandleHTTPError(resp)
+			if closeErr := resp.Body.Close(); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+			}
+			return nil, err
+		})
+	}
+}
+
+// Retry sends requests again on the failures policy retries. Only requests
+// without a body or with GetBody can be sent again; the others are sent once.
+// A request whose context is done is not sent again, as it would fail the
+// same way. Failed responses must be errors, see CheckStatus.
+func Retry(policy retry.Policy) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return next.Do(req)
+			}
+
+			var resp *http.Response
+			var final error
+			err := policy.Do(req.Method+" "+req.URL.Path, func() error {
+				attempt := req.Clone(req.Context())
+				if req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return err
+					}
+					attempt.Body = body
+				}
+				var err error
+				resp, err = next.Do(attempt)
+				if err != nil && req.Context().Err() != nil {
+					final = err
+					return nil
+				}
+				return err
+			})
+			if final != nil {
+				return nil, final
+			}
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// Deadline ends requests, and the reading of their response bodies, at
+// deadline, for transfers given a limited time. The deadline holds across
+// the requests made, so retries share it.
+func Deadline(deadline time.Time) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, cancel := context.WithDeadline(req.Context(), deadline)
+			resp, err := next.Do(req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+// cancelOnClose releases the context of a response once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// RateLimit holds requests back once a response reports the rate limit as
+// exhausted with X-RateLimit-Remaining, until the X-RateLimit-Reset time, so
+// a run waits instead of failing every following request
+func RateLimit() Middleware {
+	return rateLimit(time.Now, time.Sleep)
+}
+
+func rateLimit(now func() time.Time, sleep func(time.Duration)) Middleware {
+	return func(next Doer) Doer {
+		var mu sync.Mutex
+		var resetAt time.Time
+
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			wait := resetAt.Sub(now())
+			mu.Unlock()
+			if wait > 0 {
+				fmt.Fprintf(os.Stderr, "Rate limit exhausted; waiting %s for it to reset\n", wait.Round(time.Second))
+				sleep(wait)
+			}
+
+			resp, err := next.Do(req)
+			if resp == nil {
+				return resp, err
+			}
+			if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				if reset, parseErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); parseErr == nil {
+					mu.Lock()
+					resetAt = time.Unix(reset, 0)
+					mu.Unlock()
+				}
+			}
+			return resp, err
+		})
+	}
+}
+
+// Trace records a span per request on tracer, which may be nil
+func Trace(tracer *tracing.Tracer) Middleware {
+	return func(next Doer) Doer {
+		if tracer == nil {
+			return next
+		}
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			span := tracer.Start("http "+req.Method,
+				tracing.String("http.method", req.Method),
+				tracing.String("server.address", req.URL.Host),
+				tracing.String("url.path", req.URL.Path))
+			resp, err := next.Do(req)
+			if resp != nil {
+				span.SetAttr(tracing.Int("http.status_code", int64(resp.StatusCode)))
+			}
+			span.End(err)
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/retry"
+	"github.com/23prime/gh-download/internal/tracing"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// respond returns a Doer answering every request with status and headers,
+// counting the requests
+func respond(status int, headers http.Header, calls *int) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		*calls++
+		if headers == nil {
+			headers = http.Header{}
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     headers,
+			Body:       io.NopCloser(strings.NewReader("body")),
+			Request:    req,
+		}, nil
+	})
+}
+
+func newRequest(t *testing.T, method string, body io.Reader) *http.Request {
+	req, err := http.NewRequest(method, "https://example.com/a", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	layer := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.Do(req)
+			})
+		}
+	}
+
+	calls := 0
+	doer := Chain(respond(200, nil, &calls), layer("outer"), layer("inner"))
+	req := newRequest(t, "GET", nil)
+	if _, err := doer.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "outer,inner" || calls != 1 {
+		t.Errorf("Expected outer,inner and 1 call, got %v and %d calls", order, calls)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	var got http.Header
+	doer := Chain(DoerFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	}), Headers(map[string]string{"Accept": "application/octet-stream", "Range": "bytes=0-"}))
+
+	req := newRequest(t, "GET", nil)
+	req.Header.Set("Range", "bytes=10-")
+	if _, err := doer.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("Accept") != "application/octet-stream" {
+		t.Errorf("Expected the Accept header to be set, got %q", got.Get("Accept"))
+	}
+	if got.Get("Range") != "bytes=10-" {
+		t.Errorf("Expected the request's Range header to be kept, got %q", got.Get("Range"))
+	}
+	if req.Header.Get("Accept") != "" {
+		t.Error("Expected the original request to be left unchanged")
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	calls := 0
+	resp, err := Chain(respond(206, nil, &calls), CheckStatus()).Do(newRequest(t, "GET", nil))
+	if err != nil || resp.StatusCode != 206 {
+		t.Errorf("Expected the 206 response, got %v, %v", resp, err)
+	}
+
+	resp, err = Chain(respond(404, nil, &calls), CheckStatus()).Do(newRequest(t, "GET", nil))
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 404 || resp != nil {
+		t.Errorf("Expected an HTTP 404 error, got %v, %v", resp, err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var waits []time.Duration
+	policy := retry.Policy{Retries: 2, Classes: map[retry.Class]bool{retry.ServerError: true}, Backoff: time.Second}
+	policy.Sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	calls := 0
+	_, err := Chain(respond(503, nil, &calls), Retry(policy), CheckStatus()).Do(newRequest(t, "GET", nil))
+	if err == nil || calls != 3 || len(waits) != 2 {
+		t.Errorf("Expected 3 attempts and an error, got %d attempts, %d waits and %v", calls, len(waits), err)
+	}
+
+	calls = 0
+	_, err = Chain(respond(404, nil, &calls), Retry(policy), CheckStatus()).Do(newRequest(t, "GET", nil))
+	if err == nil || calls != 1 {
+		t.Errorf("Expected a 404 to fail at once, got %d attempts and %v", calls, err)
+	}
+
+	calls = 0
+	req := newRequest(t, "POST", io.NopCloser(strings.NewReader("x")))
+	if _, err := Chain(respond(503, nil, &calls), Retry(policy), CheckStatus()).Do(req); err == nil || calls != 1 {
+		t.Errorf("Expected a request whose body cannot be replayed to be sent once, got %d attempts and %v", calls, err)
+	}
+
+	calls = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req = newRequest(t, "GET", nil).WithContext(ctx)
+	if _, err := Chain(respond(503, nil, &calls), Retry(policy), CheckStatus()).Do(req); err == nil || calls != 1 {
+		t.Errorf("Expected a request whose context is done to be sent once, got %d attempts and %v", calls, err)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// A transfer that stalls after its headers
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := Chain(server.Client(), Deadline(time.Now().Add(100*time.Millisecond))).Do(req)
+	if err != nil {
+		t.Fatalf("Expected the response headers before the deadline, got %v", err)
+	}
+	_, err = io.ReadAll(resp.Body)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the body to end at the deadline, got %v", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := Chain(server.Client(), Deadline(time.Now().Add(-time.Second))).Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a request after the deadline to fail, got %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var waits []time.Duration
+	sleep := func(d time.Duration) {
+		waits = append(waits, d)
+		now = now.Add(d)
+	}
+
+	headers := http.Header{}
+	headers.Set("X-RateLimit-Remaining", "0")
+	headers.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(30*time.Second).Unix(), 10))
+	calls := 0
+	doer := Chain(respond(200, headers, &calls), rateLimit(func() time.Time { return now }, sleep))
+
+	for range 2 {
+		if _, err := doer.Do(newRequest(t, "GET", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(waits) != 1 || waits[0] != 30*time.Second {
+		t.Errorf("Expected one wait of 30s before the second request, got %v", waits)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var exported string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		exported = string(body)
+	}))
+	defer collector.Close()
+
+	tracer, err := tracing.New(collector.URL, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	if _, err := Chain(respond(200, nil, &calls), Trace(tracer)).Do(newRequest(t, "GET", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracer.Shutdown(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(exported, "http GET") || !strings.Contains(exported, "http.status_code") {
+		t.Errorf("Expected a span of the request, got %s", exported)
+	}
+
+	if doer := respond(200, nil, &calls); Trace(nil)(doer) == nil {
+		t.Error("Expected Trace(nil) to return the next Doer")
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/23prime/gh-download/internal/retry"
+)
+
+// Resume continues response bodies cut short by a failure policy retries,
+// asking for the rest with a Range header, so that a dropped connection does
+// not fail a transfer or start it over. Bodies of GET requests answered with
+// 200, or with 206 and a Content-Range, are resumed up to policy.Retries
+// times in all, each time only when the server answers with the range asked
+// for; otherwise the read fails with the failure that cut it. Resumed
+// requests go through the layers below, see Retry and CheckStatus.
+func Resume(policy retry.Policy) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+			if err != nil || req.Method != http.MethodGet {
+				return resp, err
+			}
+
+			var offset int64
+			switch resp.StatusCode {
+			case http.StatusOK:
+			case http.StatusPartialContent:
+				start, ok := contentRangeStart(resp)
+				if !ok {
+					return resp, nil
+				}
+				offset = start
+			default:
+				return resp, nil
+			}
+			resp.Body = &resumingBody{req: req, next: next, policy: policy, body: resp.Body, offset: offset}
+			return resp, nil
+		})
+	}
+}
+
+// contentRangeStart returns the offset of the first byte of a 206 response
+// from its Content-Range header, "bytes <start>-<end>/<size>"
+func contentRangeStart(resp *http.Response) (int64, bool) {
+	spec, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	offset, err := strconv.ParseInt(start, 10, 64)
+	return offset, err == nil && offset >= 0
+}
+
+// resumingBody reads a response body, requesting the rest of it from offset
+// when a read fails on a failure its policy retries
+type resumingBody struct {
+	req     *http.Request
+	next    Doer
+	policy  retry.Policy
+	body    io.ReadCloser
+	offset  int64
+	resumed int
+}
+
+func (b *resumingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+	if class, ok := retry.Classify(err); !ok || !b.policy.Classes[class] || b.resumed >= b.policy.Retries || b.req.Context().Err() != nil {
+		return n, err
+	}
+
+	// The failed read is the first attempt; the policy waits and logs before
+	// each request for the rest
+	first := true
+	var body io.ReadCloser
+	resumeErr := b.policy.Do(fmt.Sprintf("%s %s from byte %d", b.req.Method, b.req.URL.Path, b.offset), func() error {
+		if first {
+			first = false
+			return err
+		}
+		var reopenErr error
+		body, reopenErr = b.reopen()
+		return reopenErr
+	})
+	if resumeErr != nil || body == nil {
+		return n, err
+	}
+
+	if closeErr := b.body.Close(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+	}
+	b.body = body
+	b.resumed++
+	return n, nil
+}
+
+// reopen requests the body from offset, accepting only the range asked for
+func (b *resumingBody) reopen() (io.ReadCloser, error) {
+	req := b.req.Clone(b.req.Context())
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+	resp, err := b.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if start, ok := contentRangeStart(resp); resp.StatusCode != http.StatusPartialContent || !ok || start != b.offset {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+		return nil, fmt.Errorf("server did not resume at byte %d (%s)", b.offset, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (b *resumingBody) Close() error {
+	return b.body.Close()
+}
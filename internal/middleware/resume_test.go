@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/23prime/gh-download/internal/retry"
+)
+
+// cutReader fails like a dropped connection where r ends
+type cutReader struct {
+	r io.Reader
+}
+
+func (c cutReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// serveRanges returns a Doer serving content, honoring "bytes=<start>-"
+// ranges, and cutting each response after cut bytes when cut is positive. The
+// Range headers of the requests are recorded.
+func serveRanges(content string, cut int, ranges *[]string) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		*ranges = append(*ranges, req.Header.Get("Range"))
+		status, start := http.StatusOK, 0
+		header := http.Header{}
+		if spec, ok := strings.CutPrefix(req.Header.Get("Range"), "bytes="); ok {
+			offset, err := strconv.Atoi(strings.TrimSuffix(spec, "-"))
+			if err != nil {
+				return nil, err
+			}
+			status, start = http.StatusPartialContent, offset
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+		}
+
+		var body io.Reader = strings.NewReader(content[start:])
+		if cut > 0 && len(content)-start > cut {
+			body = cutReader{io.LimitReader(body, int64(cut))}
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(body), Request: req}, nil
+	})
+}
+
+func TestResume(t *testing.T) {
+	content := "0123456789abcdefghij"
+	policy := retry.Policy{Retries: 5, Classes: map[retry.Class]bool{retry.Network: true}, Sleep: func(time.Duration) {}}
+
+	testCases := []struct {
+		name     string
+		policy   retry.Policy
+		rangeHdr string
+		cut      int
+		expected string
+		ranges   string
+		err      bool
+	}{
+		{
+			name:     "cut body resumed",
+			policy:   policy,
+			cut:      8,
+			expected: content,
+			ranges:   ",bytes=8-,bytes=16-",
+		},
+		{
+			name:     "range request resumed",
+			policy:   policy,
+			rangeHdr: "bytes=4-",
+			cut:      8,
+			expected: content[4:],
+			ranges:   "bytes=4-,bytes=12-",
+		},
+		{
+			name:     "resumes limited to retries",
+			policy:   retry.Policy{Retries: 1, Classes: policy.Classes, Sleep: policy.Sleep},
+			cut:      8,
+			expected: content[:16],
+			ranges:   ",bytes=8-",
+			err:      true,
+		},
+		{
+			name:     "class not retried",
+			policy:   retry.Policy{Retries: 5, Classes: map[retry.Class]bool{retry.ServerError: true}},
+			cut:      8,
+			expected: content[:8],
+			ranges:   "",
+			err:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ranges []string
+			req := newRequest(t, "GET", nil)
+			if tc.rangeHdr != "" {
+				req.Header.Set("Range", tc.rangeHdr)
+			}
+			resp, err := Chain(serveRanges(content, tc.cut, &ranges), Resume(tc.policy)).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(resp.Body)
+			if (err != nil) != tc.err {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+			if strings.Join(ranges, ",") != tc.ranges {
+				t.Errorf("Expected requests with ranges %q, got %q", tc.ranges, strings.Join(ranges, ","))
+			}
+			if err := resp.Body.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestResume_RangeIgnored(t *testing.T) {
+	content := "0123456789abcdefghij"
+	policy := retry.Policy{Retries: 5, Classes: map[retry.Class]bool{retry.Network: true}, Sleep: func(time.Duration) {}}
+
+	// A server that answers every request with the whole content, cut short;
+	// appending it to what was read would corrupt the body
+	calls := 0
+	doer := DoerFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		body := cutReader{io.LimitReader(strings.NewReader(content), 8)}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(body), Request: req}, nil
+	})
+
+	resp, err := Chain(doer, Resume(policy)).Do(newRequest(t, "GET", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(resp.Body)
+	if err == nil || string(got) != content[:8] {
+		t.Errorf("Expected the read to fail after %q, got %q and %v", content[:8], got, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected to give up after the first request for the rest, got %d requests", calls)
+	}
+}
@@ -0,0 +1,126 @@
+// Package output selects the fields of the records printed with --json, as
+// gh does with `--json id,tagName,assets`. Fields are named in camelCase
+// after the JSON keys of the GitHub API, so tag_name is selected as tagName.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FieldName returns the camelCase name of a JSON key, e.g. tagName for
+// tag_name
+func FieldName(key string) string {
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// Fields returns the sorted field names of records like v, a struct or a
+// pointer to one, from the JSON keys of its exported fields
+func Fields(v any) []string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch key {
+		case "-":
+			continue
+		case "":
+			key = field.Name
+		}
+		fields = append(fields, FieldName(key))
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Selector reduces records to a set of fields
+type Selector struct {
+	fields []string
+}
+
+// NewSelector returns a selector of fields for records like v. Unknown
+// fields are an error listing the available ones.
+func NewSelector(v any, fields []string) (*Selector, error) {
+	available := Fields(v)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("specify one or more comma-separated fields for --json:\n  %s", strings.Join(available, "\n  "))
+	}
+	for _, field := range fields {
+		if !slices.Contains(available, field) {
+			return nil, fmt.Errorf("unknown JSON field: %q\nAvailable fields:\n  %s", field, strings.Join(available, "\n  "))
+		}
+	}
+	return &Selector{fields: fields}, nil
+}
+
+// Select returns record reduced to the fields of the selector, with the keys
+// of nested objects in camelCase too. Fields the record omits are null.
+func (s *Selector) Select(record any) (map[string]any, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+	all := camelCase(decoded).(map[string]any)
+
+	selected := make(map[string]any, len(s.fields))
+	for _, field := range s.fields {
+		selected[field] = all[field]
+	}
+	return selected, nil
+}
+
+// SelectAll applies Select to every record
+func (s *Selector) SelectAll(records []any) ([]any, error) {
+	selected := make([]any, 0, len(records))
+	for _, record := range records {
+		value, err := s.Select(record)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, value)
+	}
+	return selected, nil
+}
+
+func camelCase(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			converted[FieldName(key)] = camelCase(item)
+		}
+		return converted
+	case []any:
+		for i, item := range v {
+			v[i] = camelCase(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type asset struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+type release struct {
+	ID        int     `json:"id"`
+	TagName   string  `json:"tag_name"`
+	CommitSHA string  `json:"commit_sha,omitempty"`
+	Assets    []asset `json:"assets"`
+	Internal  string  `json:"-"`
+	Plain     bool
+	hidden    string
+}
+
+func TestFieldName(t *testing.T) {
+	testCases := map[string]string{
+		"id":                   "id",
+		"tag_name":             "tagName",
+		"browser_download_url": "browserDownloadUrl",
+		"Plain":                "Plain",
+	}
+	for key, expected := range testCases {
+		if got := FieldName(key); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, key, got)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	got := strings.Join(Fields(&release{hidden: "x"}), ",")
+	expected := "Plain,assets,commitSha,id,tagName"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if Fields("not a struct") != nil {
+		t.Error("Expected no fields for a string")
+	}
+}
+
+func TestSelector(t *testing.T) {
+	selector, err := NewSelector(release{}, []string{"tagName", "assets", "commitSha"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	selected, err := selector.SelectAll([]any{release{ID: 1, TagName: "v1.0.0", Assets: []asset{{Name: "app.zip", Size: 3}}}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := json.Marshal(selected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"assets":[{"name":"app.zip","size":3}],"commitSha":null,"tagName":"v1.0.0"}]`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestNewSelector_Errors(t *testing.T) {
+	_, err := NewSelector(release{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "tagName") {
+		t.Errorf("Expected an error listing the fields, got %v", err)
+	}
+	_, err = NewSelector(release{}, []string{"tag_name"})
+	if err == nil || !strings.Contains(err.Error(), `unknown JSON field: "tag_name"`) {
+		t.Errorf("Expected an error for an unknown field, got %v", err)
+	}
+}
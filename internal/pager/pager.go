@@ -0,0 +1,141 @@
+// Package pager pipes standard output through the pager gh is configured
+// with, as gh does for long output on terminals.
+package pager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	ghconfig "github.com/cli/go-gh/v2/pkg/config"
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+var (
+	mu      sync.Mutex
+	running bool
+	width   int
+)
+
+// Command returns the pager gh would use: GH_PAGER, even when empty, then
+// the pager setting of the gh config, then PAGER. It returns "" when none is
+// set or the pager is cat, which would page nothing.
+func Command() string {
+	command, ok := os.LookupEnv("GH_PAGER")
+	if !ok {
+		if cfg, err := ghconfig.Read(nil); err == nil {
+			if value, err := cfg.Get([]string{"pager"}); err == nil {
+				command = value
+			}
+		}
+		if command == "" {
+			command = os.Getenv("PAGER")
+		}
+	}
+	if command == "cat" {
+		return ""
+	}
+	return command
+}
+
+// Start starts command with standard output piped into it, when standard
+// output is a terminal, and returns a function that waits for the pager to
+// exit and restores standard output. Like gh, it sets LESS=FRX and LV=-c
+// unless they are set, so that less quits when the output fits on the screen
+// and shows colors.
+func Start(command string) (func() error, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 || !term.IsTerminal(os.Stdout) {
+		return func() error { return nil }, nil
+	}
+
+	terminalWidth, _, err := term.FromEnv().Size()
+	if err != nil {
+		terminalWidth = 80
+	}
+	return start(args, terminalWidth)
+}
+
+func start(args []string, terminalWidth int) (func() error, error) {
+	noop := func() error { return nil }
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		cmd.Env = append(cmd.Env, "LV=-c")
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return noop, fmt.Errorf("failed to start pager %s: %w", args[0], err)
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return noop, fmt.Errorf("failed to start pager %s: %w", args[0], err)
+	}
+	if err := cmd.Start(); err != nil {
+		return noop, closeAll(fmt.Errorf("failed to start pager %s: %w", args[0], err), reader, writer)
+	}
+
+	// Copy through a pipe of our own so that output written after the pager
+	// quits, as with q in less, is discarded instead of failing with EPIPE
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		if _, err := io.Copy(pipe, reader); err != nil {
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				return
+			}
+		}
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	mu.Lock()
+	running, width = true, terminalWidth
+	mu.Unlock()
+
+	return func() error {
+		os.Stdout = stdout
+		mu.Lock()
+		running, width = false, 0
+		mu.Unlock()
+
+		err := writer.Close()
+		<-copied
+		if closeErr := pipe.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if closeErr := reader.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if waitErr := cmd.Wait(); waitErr != nil && err == nil {
+			err = waitErr
+		}
+		return err
+	}, nil
+}
+
+// Running reports whether standard output goes through a pager, and the
+// width of the terminal the pager shows it on, so that output can be laid
+// out for the terminal rather than the pipe
+func Running() (bool, int) {
+	mu.Lock()
+	defer mu.Unlock()
+	return running, width
+}
+
+func closeAll(err error, files ...*os.File) error {
+	for _, f := range files {
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", f.Name(), closeErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package pager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GH_CONFIG_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte("pager: more\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAGER", "less")
+
+	t.Setenv("GH_PAGER", "most")
+	if got := Command(); got != "most" {
+		t.Errorf("Expected GH_PAGER to win, got %q", got)
+	}
+	t.Setenv("GH_PAGER", "")
+	if got := Command(); got != "" {
+		t.Errorf("Expected an empty GH_PAGER to disable paging, got %q", got)
+	}
+	t.Setenv("GH_PAGER", "cat")
+	if got := Command(); got != "" {
+		t.Errorf("Expected no pager for cat, got %q", got)
+	}
+
+	if err := os.Unsetenv("GH_PAGER"); err != nil {
+		t.Fatal(err)
+	}
+	// go-gh reads the gh config once per process, so PAGER cannot be tested
+	// without a configured pager here
+	if got := Command(); got != "more" {
+		t.Errorf("Expected the pager of the gh config before PAGER, got %q", got)
+	}
+}
+
+func TestStart_NotTerminal(t *testing.T) {
+	stdout := os.Stdout
+	stop, err := Start("less")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if os.Stdout != stdout {
+		t.Error("Expected no pager when stdout is not a terminal")
+	}
+	if err := stop(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestStart(t *testing.T) {
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	stop, err := start([]string{"cat"}, 120)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if running, width := Running(); !running || width != 120 {
+		t.Errorf("Expected the pager to run on a 120 column terminal, got %v %d", running, width)
+	}
+	fmt.Println("paged line")
+	if err := stop(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if os.Stdout != out {
+		t.Error("Expected stdout to be restored")
+	}
+	if running, _ := Running(); running {
+		t.Error("Expected the pager to have stopped")
+	}
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "paged line\n" {
+		t.Errorf("Expected the output to go through the pager, got %q", data)
+	}
+}
+
+func TestStart_MissingPager(t *testing.T) {
+	stdout := os.Stdout
+	if _, err := start([]string{"no-such-pager-for-gh-download"}, 80); err == nil {
+		t.Error("Expected an error for a missing pager, got nil")
+	}
+	if os.Stdout != stdout {
+		t.Error("Expected stdout to be left alone")
+	}
+}
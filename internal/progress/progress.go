@@ -0,0 +1,217 @@
+// Package progress draws progress bars for transfers on a terminal: one per
+// running transfer with its speed and remaining time, and a total over all
+// transfers when there are several.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redrawInterval limits how often the bars are redrawn while bytes arrive
+const redrawInterval = 100 * time.Millisecond
+
+// barWidth is the number of cells of a bar
+const barWidth = 24
+
+// Display draws the bars of the running transfers below the lines printed
+// with Println, redrawing them in place with ANSI escape sequences
+type Display struct {
+	out   io.Writer
+	count int
+	now   func() time.Time
+
+	mu       sync.Mutex
+	bars     []*Bar
+	total    *Bar
+	finished int
+	drawn    int
+	lastDraw time.Time
+}
+
+// Bar tracks the bytes of one transfer. A nil *Bar ignores everything, so
+// callers without a display need no checks.
+type Bar struct {
+	display *Display
+	name    string
+	size    int64
+	done    int64
+	started time.Time
+}
+
+// New returns a display for count transfers of size bytes in total, drawing
+// to out. The total is only drawn for more than one transfer.
+func New(out io.Writer, size int64, count int) *Display {
+	d := &Display{out: out, count: count, now: time.Now}
+	d.total = &Bar{display: d, name: "Total", size: size, started: d.now()}
+	return d
+}
+
+// Start adds a bar for a transfer of size bytes
+func (d *Display) Start(name string, size int64) *Bar {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	bar := &Bar{display: d, name: name, size: size, started: d.now()}
+	d.bars = append(d.bars, bar)
+	d.draw(true)
+	return bar
+}
+
+// Println prints a line above the bars, to w such as stderr for errors
+func (d *Display) Println(w io.Writer, line string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+	fmt.Fprintln(w, line)
+	d.draw(true)
+}
+
+// Close removes the bars from the terminal
+func (d *Display) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bars = nil
+	d.clear()
+}
+
+// Write counts p as transferred, so a Bar can be a destination of io.Copy
+// or io.TeeReader
+func (b *Bar) Write(p []byte) (int, error) {
+	b.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// Add counts n bytes as transferred
+func (b *Bar) Add(n int64) {
+	if b == nil {
+		return
+	}
+	d := b.display
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b.done += n
+	d.total.done += n
+	d.draw(false)
+}
+
+// Reset forgets the bytes transferred so far, for a transfer that starts
+// over
+func (b *Bar) Reset() {
+	if b == nil {
+		return
+	}
+	d := b.display
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.total.done -= b.done
+	b.done = 0
+	b.started = d.now()
+}
+
+// Finish removes the bar of a transfer that ended
+func (b *Bar) Finish() {
+	if b == nil {
+		return
+	}
+	d := b.display
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, bar := range d.bars {
+		if bar == b {
+			d.bars = append(d.bars[:i], d.bars[i+1:]...)
+			d.finished++
+			break
+		}
+	}
+	d.draw(true)
+}
+
+// clear erases the lines drawn last, leaving the cursor where they started
+func (d *Display) clear() {
+	if d.drawn > 0 {
+		fmt.Fprintf(d.out, "\x1b[%dA\x1b[J", d.drawn)
+	}
+	d.drawn = 0
+}
+
+// draw redraws the bars, at most every redrawInterval unless forced
+func (d *Display) draw(force bool) {
+	now := d.now()
+	if !force && now.Sub(d.lastDraw) < redrawInterval {
+		return
+	}
+	d.lastDraw = now
+
+	var lines []string
+	for _, bar := range d.bars {
+		lines = append(lines, bar.line(now))
+	}
+	if d.count > 1 && len(d.bars) > 0 {
+		total := *d.total
+		total.name = fmt.Sprintf("Total (%d/%d)", d.finished, d.count)
+		lines = append(lines, total.line(now))
+	}
+
+	d.clear()
+	for _, line := range lines {
+		fmt.Fprintf(d.out, "%s\x1b[K\n", line)
+	}
+	d.drawn = len(lines)
+}
+
+// line renders a bar as "name [=====>    ]  45%  1.2/2.7 MiB  3.2 MiB/s  ETA 5s"
+func (b *Bar) line(now time.Time) string {
+	var fraction float64
+	if b.size > 0 {
+		fraction = min(float64(b.done)/float64(b.size), 1)
+	}
+	filled := int(fraction * barWidth)
+	cells := strings.Repeat("=", filled)
+	if filled < barWidth {
+		cells += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+
+	elapsed := now.Sub(b.started).Seconds()
+	var speed float64
+	if elapsed > 0 {
+		speed = float64(b.done) / elapsed
+	}
+	eta := "--"
+	if speed > 0 && b.size >= b.done {
+		eta = time.Duration(float64(b.size-b.done) / speed * float64(time.Second)).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%-30s [%s] %3.0f%%  %s/%s  %s/s  ETA %s",
+		truncate(b.name, 30), cells, fraction*100, FormatBytes(b.done), FormatBytes(b.size), FormatBytes(int64(speed)), eta)
+}
+
+// truncate shortens s to n runes, marking the cut with "…"
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// FormatBytes formats a byte count with binary units, as in "1.5 MiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, suffix := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		value /= unit
+		if value < unit || suffix == "TiB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
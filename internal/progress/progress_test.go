@@ -0,0 +1,116 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDisplay(size int64, count int) (*Display, *bytes.Buffer, *time.Time) {
+	var out bytes.Buffer
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := New(&out, size, count)
+	d.now = func() time.Time { return now }
+	d.total.started = now
+	return d, &out, &now
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+		{3 << 40, "3.0 TiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d): expected %q, got %q", tt.n, tt.want, got)
+		}
+	}
+}
+
+func TestBar_Line(t *testing.T) {
+	d, _, now := newTestDisplay(2048, 1)
+	bar := d.Start("app.tar.gz", 2048)
+	bar.Add(1024)
+	*now = now.Add(2 * time.Second)
+
+	line := bar.line(*now)
+	for _, want := range []string{"app.tar.gz", " 50%", "1.0 KiB/2.0 KiB", "512 B/s", "ETA 2s", "[============>           ]"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected line to contain %q, got %q", want, line)
+		}
+	}
+}
+
+func TestDisplay_Total(t *testing.T) {
+	d, out, _ := newTestDisplay(300, 2)
+	first := d.Start("a", 100)
+	d.Start("b", 200)
+	first.Add(100)
+	first.Finish()
+
+	if d.total.done != 100 {
+		t.Errorf("Expected 100 bytes in total, got %d", d.total.done)
+	}
+	if !strings.Contains(out.String(), "Total (1/2)") {
+		t.Errorf("Expected total line, got %q", out.String())
+	}
+	if len(d.bars) != 1 {
+		t.Errorf("Expected 1 running bar, got %d", len(d.bars))
+	}
+}
+
+func TestDisplay_SingleTransferHasNoTotal(t *testing.T) {
+	d, out, _ := newTestDisplay(100, 1)
+	d.Start("a", 100)
+
+	if strings.Contains(out.String(), "Total") {
+		t.Errorf("Expected no total line, got %q", out.String())
+	}
+}
+
+func TestBar_Reset(t *testing.T) {
+	d, _, _ := newTestDisplay(100, 1)
+	bar := d.Start("a", 100)
+	bar.Add(60)
+	bar.Reset()
+	bar.Add(10)
+
+	if bar.done != 10 || d.total.done != 10 {
+		t.Errorf("Expected 10 bytes after reset, got %d (total %d)", bar.done, d.total.done)
+	}
+}
+
+func TestDisplay_PrintlnClearsBars(t *testing.T) {
+	d, out, _ := newTestDisplay(100, 1)
+	d.Start("a", 100)
+	out.Reset()
+	d.Println(out, "Downloading a... done")
+
+	if !strings.HasPrefix(out.String(), "\x1b[1A\x1b[J"+"Downloading a... done\n") {
+		t.Errorf("Expected bars cleared before the line, got %q", out.String())
+	}
+
+	d.Close()
+	if d.drawn != 0 {
+		t.Errorf("Expected no lines drawn after Close, got %d", d.drawn)
+	}
+}
+
+func TestNilBar(t *testing.T) {
+	var bar *Bar
+	bar.Reset()
+	bar.Add(10)
+	bar.Finish()
+	if n, err := bar.Write([]byte("abc")); n != 3 || err != nil {
+		t.Errorf("Expected nil bar to accept writes, got %d, %v", n, err)
+	}
+	var d *Display
+	d.Close()
+}
@@ -0,0 +1,136 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// DefaultBlockSize is the amount of data fetched per range request.
+// Reads are rounded up to whole blocks so that the many small reads done by
+// archive/zip do not turn into one request each.
+const DefaultBlockSize = 256 * 1024
+
+const maxCachedBlocks = 32
+
+// File is an io.ReaderAt over a remote file that fetches data with HTTP
+// range requests.
+type File struct {
+	client    *http.Client
+	url       string
+	size      int64
+	blockSize int64
+	blocks    map[int64][]byte
+	order     []int64
+}
+
+// Open returns a File reading url with client. The size must be known in
+// advance (release assets report it in their metadata).
+func Open(client *http.Client, url string, size int64) *File {
+	return &File{
+		client:    client,
+		url:       url,
+		size:      size,
+		blockSize: DefaultBlockSize,
+		blocks:    make(map[int64][]byte),
+	}
+}
+
+// Size returns the size of the remote file
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// ReadAt implements io.ReaderAt
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < f.size {
+		index := off / f.blockSize
+		block, err := f.block(index)
+		if err != nil {
+			return n, err
+		}
+
+		copied := copy(p[n:], block[off-index*f.blockSize:])
+		n += copied
+		off += int64(copied)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *File) block(index int64) ([]byte, error) {
+	if block, ok := f.blocks[index]; ok {
+		return block, nil
+	}
+
+	start := index * f.blockSize
+	end := min(start+f.blockSize, f.size) - 1
+	block, err := f.fetch(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.order) >= maxCachedBlocks {
+		delete(f.blocks, f.order[0])
+		f.order = f.order[1:]
+	}
+	f.blocks[index] = block
+	f.order = append(f.order, index)
+
+	return block, nil
+}
+
+// Head fetches the first n bytes of the remote file with a single request
+func (f *File) Head(n int64) ([]byte, error) {
+	if n > f.size {
+		n = f.size
+	}
+	if n <= 0 {
+		return []byte{}, nil
+	}
+	return f.fetch(0, n-1)
+}
+
+func (f *File) fetch(start, end int64) ([]byte, error) {
+	req, err := http.NewRequest("GET", f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bytes %d-%d: %w", start, end, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("range request not supported: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, end-start+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bytes %d-%d: %w", start, end, err)
+	}
+	if int64(len(data)) != end-start+1 {
+		return nil, fmt.Errorf("short range response: expected %d bytes, got %d", end-start+1, len(data))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,147 @@
+package remote
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRangeServer serves content with range support and counts requests
+func newRangeServer(t *testing.T, content []byte) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.ServeContent(w, r, "asset", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestFile_ReadAt(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	server, _ := newRangeServer(t, content)
+
+	f := Open(server.Client(), server.URL, int64(len(content)))
+	f.blockSize = 64
+
+	buf := make([]byte, 150)
+	n, err := f.ReadAt(buf, 95)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n != 150 {
+		t.Errorf("Expected 150 bytes, got %d", n)
+	}
+	if !bytes.Equal(buf, content[95:245]) {
+		t.Errorf("Unexpected content %q", buf)
+	}
+
+	// Reading past the end returns the remaining bytes and io.EOF
+	n, err = f.ReadAt(buf, int64(len(content)-10))
+	if err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+	if n != 10 {
+		t.Errorf("Expected 10 bytes, got %d", n)
+	}
+}
+
+func TestFile_ReadAt_CachesBlocks(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 1000)
+	server, requests := newRangeServer(t, content)
+
+	f := Open(server.Client(), server.URL, int64(len(content)))
+
+	buf := make([]byte, 10)
+	for i := 0; i < 5; i++ {
+		if _, err := f.ReadAt(buf, int64(i*10)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("Expected 1 request, got %d", got)
+	}
+}
+
+func TestFile_Head(t *testing.T) {
+	content := []byte("PK\x03\x04 rest of the file")
+	server, _ := newRangeServer(t, content)
+
+	f := Open(server.Client(), server.URL, int64(len(content)))
+	head, err := f.Head(4)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(head) != "PK\x03\x04" {
+		t.Errorf("Expected zip magic, got %q", head)
+	}
+
+	head, err = f.Head(1000)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(head) != len(content) {
+		t.Errorf("Expected head to be capped at %d bytes, got %d", len(content), len(head))
+	}
+}
+
+func TestFile_RangeNotSupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("full body"))
+	}))
+	defer server.Close()
+
+	f := Open(server.Client(), server.URL, 9)
+	_, err := f.Head(4)
+	if err == nil {
+		t.Fatal("Expected error when server ignores range, got nil")
+	}
+	if !strings.Contains(err.Error(), "range request not supported") {
+		t.Errorf("Expected range error, got %q", err.Error())
+	}
+}
+
+func TestFile_ZipReader(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"bin/tool", "README.md"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte("content of " + name))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	server, _ := newRangeServer(t, buf.Bytes())
+	f := Open(server.Client(), server.URL, int64(buf.Len()))
+
+	zr, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(zr.File) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(zr.File))
+	}
+
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer rc.Close()
+
+	data, _ := io.ReadAll(rc)
+	if string(data) != "content of bin/tool" {
+		t.Errorf("Unexpected member content %q", data)
+	}
+}
@@ -0,0 +1,202 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/23prime/gh-download/internal/progress"
+	"github.com/23prime/gh-download/internal/tmpl"
+)
+
+// templateFuncs returns the functions of --template: those of the templates
+// in --dir and --pattern, the ones gh offers in its --template, and
+// humanSize. now is the time timeago measures from.
+func templateFuncs(now time.Time) template.FuncMap {
+	gh := template.FuncMap{
+		"timeago": func(input string) (string, error) {
+			t, err := time.Parse(time.RFC3339, input)
+			if err != nil {
+				return "", fmt.Errorf("%q is not an RFC 3339 time", input)
+			}
+			return timeAgo(now.Sub(t)), nil
+		},
+		"timefmt": func(layout, input string) (string, error) {
+			t, err := time.Parse(time.RFC3339, input)
+			if err != nil {
+				return "", fmt.Errorf("%q is not an RFC 3339 time", input)
+			}
+			return t.Format(layout), nil
+		},
+		"truncate":  truncate,
+		"join":      join,
+		"pluck":     pluck,
+		"hyperlink": hyperlink,
+		// The string comes last, as in gh and the functions of tmpl
+		"contains": func(substr, s string) bool {
+			return strings.Contains(s, substr)
+		},
+		"hasPrefix": func(prefix, s string) bool {
+			return strings.HasPrefix(s, prefix)
+		},
+		"hasSuffix": func(suffix, s string) bool {
+			return strings.HasSuffix(s, suffix)
+		},
+		"regexMatch": func(pattern, s string) (bool, error) {
+			return regexp.MatchString(pattern, s)
+		},
+		"humanSize": humanSize,
+		// tablerow and tablerender are bound to the output by tableFuncs
+		"tablerow":    func(...any) string { return "" },
+		"tablerender": func() string { return "" },
+	}
+
+	funcs := tmpl.Funcs()
+	for name, fn := range gh {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// tableFuncs returns tablerow, which adds a row of aligned columns, and
+// tablerender, which writes the rows added so far
+func tableFuncs(tw *tabwriter.Writer) template.FuncMap {
+	return template.FuncMap{
+		"tablerow": func(fields ...any) (string, error) {
+			cells := make([]string, len(fields))
+			for i, field := range fields {
+				cell, err := scalarString(field)
+				if err != nil {
+					return "", err
+				}
+				cells[i] = cell
+			}
+			_, err := fmt.Fprintln(tw, strings.Join(cells, "\t"))
+			return "", err
+		},
+		"tablerender": func() (string, error) {
+			return "", tw.Flush()
+		},
+	}
+}
+
+// newTableWriter returns the writer tablerow aligns columns with
+func newTableWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// timeAgo describes a duration in the past as gh does, e.g. "about 3 days
+// ago"
+func timeAgo(ago time.Duration) string {
+	about := func(amount int, unit string) string {
+		if amount != 1 {
+			unit += "s"
+		}
+		return fmt.Sprintf("about %d %s ago", amount, unit)
+	}
+
+	switch {
+	case ago < time.Minute:
+		return "less than a minute ago"
+	case ago < time.Hour:
+		return about(int(ago.Minutes()), "minute")
+	case ago < 24*time.Hour:
+		return about(int(ago.Hours()), "hour")
+	case ago < 30*24*time.Hour:
+		return about(int(ago.Hours()/24), "day")
+	case ago < 365*24*time.Hour:
+		return about(int(ago.Hours()/24/30), "month")
+	default:
+		return about(int(ago.Hours()/24/365), "year")
+	}
+}
+
+// truncate shortens a value to width characters, ending it with "..." when
+// there is room
+func truncate(width int, value any) (string, error) {
+	s, err := scalarString(value)
+	if err != nil {
+		return "", err
+	}
+	runes := []rune(s)
+	switch {
+	case len(runes) <= width:
+		return s, nil
+	case width <= 3:
+		return string(runes[:max(width, 0)]), nil
+	default:
+		return string(runes[:width-3]) + "...", nil
+	}
+}
+
+func join(sep string, values []any) (string, error) {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		s, err := scalarString(value)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// pluck returns the field of each object in values
+func pluck(field string, values []any) []any {
+	var plucked []any
+	for _, value := range values {
+		if object, ok := value.(map[string]any); ok {
+			plucked = append(plucked, object[field])
+		}
+	}
+	return plucked
+}
+
+// hyperlink makes text a link in terminals that support OSC 8
+func hyperlink(link, text string) string {
+	if text == "" {
+		text = link
+	}
+	return fmt.Sprintf("\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\", link, text)
+}
+
+// humanSize formats a byte count with binary units, as in "1.5 MiB"
+func humanSize(value any) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		return progress.FormatBytes(int64(v)), nil
+	case int:
+		return progress.FormatBytes(int64(v)), nil
+	case int64:
+		return progress.FormatBytes(v), nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a byte count", v)
+		}
+		return progress.FormatBytes(n), nil
+	default:
+		return "", fmt.Errorf("cannot format %T as a size", value)
+	}
+}
+
+// scalarString formats the scalars JSON decodes to
+func scalarString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool, int, int64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("cannot use %T as text", value)
+	}
+}
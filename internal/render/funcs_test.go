@@ -0,0 +1,83 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTimeAgo(t *testing.T) {
+	testCases := []struct {
+		ago      time.Duration
+		expected string
+	}{
+		{30 * time.Second, "less than a minute ago"},
+		{time.Minute, "about 1 minute ago"},
+		{5 * time.Hour, "about 5 hours ago"},
+		{3 * 24 * time.Hour, "about 3 days ago"},
+		{65 * 24 * time.Hour, "about 2 months ago"},
+		{800 * 24 * time.Hour, "about 2 years ago"},
+	}
+	for _, tc := range testCases {
+		if got := timeAgo(tc.ago); got != tc.expected {
+			t.Errorf("Expected %q for %v, got %q", tc.expected, tc.ago, got)
+		}
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	table := Table{}
+	table.Add(map[string]any{"name": "app-linux-amd64.tar.gz", "size": 1536, "updated_at": "2024-05-01T12:00:00Z"})
+	table.Add(map[string]any{"name": "app.zip", "size": 12, "updated_at": "2024-05-03T12:00:00Z"})
+
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{`{{range .}}{{.name}} {{humanSize .size}}{{"\n"}}{{end}}`, "app-linux-amd64.tar.gz 1.5 KiB\napp.zip 12 B\n"},
+		{`{{range .}}{{truncate 10 .name}};{{end}}`, "app-lin...;app.zip;"},
+		{`{{join ", " (pluck "name" .)}}`, "app-linux-amd64.tar.gz, app.zip"},
+		{`{{range .}}{{timefmt "2006-01-02" .updated_at}} {{end}}`, "2024-05-01 2024-05-03 "},
+		{`{{range .}}{{timeago .updated_at}};{{end}}`, "about 1 month ago;about 29 days ago;"},
+		{`{{range .}}{{tablerow .name .size}}{{end}}{{tablerender}}done`, "app-linux-amd64.tar.gz  1536\napp.zip                 12\ndone"},
+		{`{{range .}}{{tablerow .name (.name | upper)}}{{end}}`, "app-linux-amd64.tar.gz  APP-LINUX-AMD64.TAR.GZ\napp.zip                 APP.ZIP\n"},
+		{`{{range .}}{{if hasSuffix ".zip" .name}}{{.name}}{{end}}{{end}}`, "app.zip"},
+		{`{{range .}}{{if contains "linux" .name}}{{.name}}{{end}}{{end}}`, "app-linux-amd64.tar.gz"},
+	}
+	for _, tc := range testCases {
+		r, err := New(FormatTemplate, tc.template)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", tc.template, err)
+		}
+		// timeago measures from the time the template was parsed
+		r.template.Funcs(templateFuncs(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)))
+
+		var buf bytes.Buffer
+		if err := r.Render(&buf, table); err != nil {
+			t.Errorf("Expected no error for %q, got %v", tc.template, err)
+			continue
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("Expected %q for %q, got %q", tc.expected, tc.template, buf.String())
+		}
+	}
+}
+
+func TestTemplateFuncs_Errors(t *testing.T) {
+	table := Table{}
+	table.Add(map[string]any{"name": "app.zip", "size": "big", "assets": []any{}})
+
+	for _, text := range []string{
+		`{{range .}}{{humanSize .size}}{{end}}`,
+		`{{range .}}{{timeago .name}}{{end}}`,
+		`{{range .}}{{tablerow .assets}}{{end}}`,
+	} {
+		r, err := New(FormatTemplate, text)
+		if err != nil {
+			t.Fatalf("Expected no error parsing %q, got %v", text, err)
+		}
+		if err := r.Render(&bytes.Buffer{}, table); err == nil {
+			t.Errorf("Expected an error for %q, got nil", text)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package render writes the records informational commands print, such as
+// the assets of --list and the releases of --releases, in the format chosen
+// with --format: an aligned table, JSON, NDJSON, CSV, TSV, YAML or a Go
+// template.
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatYAML     = "yaml"
+	FormatTemplate = "template"
+)
+
+// Formats are the output formats in the order the usage lists them
+var Formats = []string{FormatTable, FormatJSON, FormatNDJSON, FormatCSV, FormatTSV, FormatYAML, FormatTemplate}
+
+// Table is the records of a command. The table, CSV and TSV formats print
+// Columns and Rows; the others encode Values, the record behind each row.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+	Values  []any
+}
+
+// Add appends a record and its row
+func (t *Table) Add(value any, cells ...string) {
+	t.Values = append(t.Values, value)
+	t.Rows = append(t.Rows, cells)
+}
+
+// Renderer writes tables in one format
+type Renderer struct {
+	format   string
+	template *template.Template
+}
+
+// New returns a renderer for format. The template format requires text, a
+// Go template executed once over the list of records with the functions gh
+// offers in its templates; other formats reject it.
+func New(format, text string) (*Renderer, error) {
+	valid := false
+	for _, f := range Formats {
+		valid = valid || f == format
+	}
+	if !valid {
+		return nil, fmt.Errorf("format must be one of %s, got %q", strings.Join(Formats, ", "), format)
+	}
+
+	r := &Renderer{format: format}
+	switch {
+	case format == FormatTemplate && text == "":
+		return nil, fmt.Errorf("--format template requires --template")
+	case format != FormatTemplate && text != "":
+		return nil, fmt.Errorf("--template requires --format template")
+	case format == FormatTemplate:
+		tmpl, err := template.New("output").Funcs(templateFuncs(time.Now())).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		r.template = tmpl
+	}
+	return r, nil
+}
+
+// Render writes the table to w
+func (r *Renderer) Render(w io.Writer, t Table) error {
+	switch r.format {
+	case FormatTable:
+		return writeTable(w, t)
+	case FormatCSV, FormatTSV:
+		return writeDelimited(w, t, r.format == FormatTSV)
+	}
+
+	values, err := plainValues(t.Values)
+	if err != nil {
+		return err
+	}
+	switch r.format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(values)
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, value := range values {
+			if err := encoder.Encode(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatYAML:
+		return WriteYAML(w, values)
+	default:
+		tw := newTableWriter(w)
+		if err := r.template.Funcs(tableFuncs(tw)).Execute(w, values); err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+		// Rows added with tablerow but not followed by tablerender
+		return tw.Flush()
+	}
+}
+
+// plainValues converts values to the maps, slices and scalars their JSON
+// encoding decodes to, so that YAML and templates see the same field names
+// as JSON
+func plainValues(values []any) ([]any, error) {
+	if values == nil {
+		values = []any{}
+	}
+	plain, err := Plain(values)
+	if err != nil {
+		return nil, err
+	}
+	return plain.([]any), nil
+}
+
+// Plain converts a value to the maps, slices and scalars its JSON encoding
+// decodes to. Integers stay int64 rather than float64, so that large ones
+// such as IDs are not written in exponent notation.
+func Plain(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode records: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var plain any
+	if err := decoder.Decode(&plain); err != nil {
+		return nil, fmt.Errorf("failed to encode records: %w", err)
+	}
+	return plainNumbers(plain), nil
+}
+
+func plainNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = plainNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = plainNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+	return value
+}
+
+// WriteYAML writes value as YAML with the field names and types of its JSON
+// encoding, so that the output round-trips with the JSON of the same value
+func WriteYAML(w io.Writer, value any) error {
+	plain, err := Plain(value)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(plain); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func writeTable(w io.Writer, t Table) error {
+	tw := newTableWriter(w)
+	headers := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		headers[i] = strings.ToUpper(column)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeDelimited(w io.Writer, t Table, tabs bool) error {
+	writer := csv.NewWriter(w)
+	if tabs {
+		writer.Comma = '\t'
+	}
+	if err := writer.Write(t.Columns); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
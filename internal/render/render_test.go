@@ -0,0 +1,92 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+type record struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+func testTable() Table {
+	t := Table{Columns: []string{"name", "size"}}
+	t.Add(record{Name: "app.tar.gz", Size: 1024}, "app.tar.gz", "1024")
+	t.Add(record{Name: "app, notes.txt", Size: 12}, "app, notes.txt", "12")
+	return t
+}
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		format   string
+		template string
+		expected string
+	}{
+		{FormatTable, "", "NAME            SIZE\napp.tar.gz      1024\napp, notes.txt  12\n"},
+		{FormatJSON, "", "[\n  {\n    \"name\": \"app.tar.gz\",\n    \"size\": 1024\n  },\n  {\n    \"name\": \"app, notes.txt\",\n    \"size\": 12\n  }\n]\n"},
+		{FormatNDJSON, "", "{\"name\":\"app.tar.gz\",\"size\":1024}\n{\"name\":\"app, notes.txt\",\"size\":12}\n"},
+		{FormatCSV, "", "name,size\napp.tar.gz,1024\n\"app, notes.txt\",12\n"},
+		{FormatTSV, "", "name\tsize\napp.tar.gz\t1024\napp, notes.txt\t12\n"},
+		{FormatYAML, "", "- name: app.tar.gz\n  size: 1024\n- name: app, notes.txt\n  size: 12\n"},
+		{FormatTemplate, "{{range .}}{{.name}}={{.size}};{{end}}", "app.tar.gz=1024;app, notes.txt=12;"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			r, err := New(tc.format, tc.template)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var buf bytes.Buffer
+			if err := r.Render(&buf, testTable()); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRender_Empty(t *testing.T) {
+	r, err := New(FormatJSON, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := r.Render(&buf, Table{Columns: []string{"name"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", buf.String())
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	testCases := []struct {
+		format   string
+		template string
+	}{
+		{"xml", ""},
+		{"", ""},
+		{FormatTemplate, ""},
+		{FormatJSON, "{{.}}"},
+		{FormatTemplate, "{{range"},
+	}
+	for _, tc := range testCases {
+		if _, err := New(tc.format, tc.template); err == nil {
+			t.Errorf("Expected an error for %q with %q, got nil", tc.format, tc.template)
+		}
+	}
+}
+
+func TestWriteYAML_Numbers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, map[string]any{"id": 123456789012, "ratio": 0.5}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "id: 123456789012\nratio: 0.5\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
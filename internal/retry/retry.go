@@ -0,0 +1,186 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/23prime/gh-download/internal/log"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// Class is a kind of failure that a Policy may retry
+type Class string
+
+const (
+	// ServerError is a 5xx response
+	ServerError Class = "5xx"
+	// RateLimit is a 429 response or a 403 response for an exhausted rate
+	// limit
+	RateLimit Class = "rate-limit"
+	// Network is a connection failure or a transfer cut short
+	Network Class = "network"
+	// Checksum is downloaded content that does not match its digest
+	Checksum Class = "checksum"
+)
+
+// DefaultClasses are retried unless configured otherwise. Checksum failures
+// are not, since a bad upload stays bad.
+const DefaultClasses = "5xx,rate-limit,network"
+
+// ErrChecksum is wrapped by errors reporting content that does not match its
+// digest, so that they classify as Checksum
+var ErrChecksum = errors.New("checksum mismatch")
+
+// DefaultBackoff is the wait before the first retry unless configured
+// otherwise
+const DefaultBackoff = time.Second
+
+// DefaultJitter is the fraction of the backoff added at random to each wait
+const DefaultJitter = 0.5
+
+// maxWait caps the wait before an attempt, including waits requested by the
+// server
+const maxWait = time.Minute
+
+// Policy decides which failures are retried and how often. Failures of other
+// classes, such as 404 or authentication errors, fail immediately.
+type Policy struct {
+	// Retries is the number of attempts after the first
+	Retries int
+	// Classes are the failure classes that are retried
+	Classes map[Class]bool
+	// Backoff is the wait before the first retry, doubling for each
+	// following one
+	Backoff time.Duration
+	// Jitter is the fraction of the backoff added at random to each wait, so
+	// that runs failing together do not retry in lockstep
+	Jitter float64
+	// Sleep waits between attempts; time.Sleep when nil
+	Sleep func(time.Duration)
+}
+
+// NewPolicy returns a policy retrying the comma-separated classes up to
+// retries times
+func NewPolicy(retries int, classes string) (Policy, error) {
+	if retries < 0 {
+		return Policy{}, fmt.Errorf("invalid retries %d: must not be negative", retries)
+	}
+
+	parsed, err := ParseClasses(classes)
+	if err != nil {
+		return Policy{}, err
+	}
+	return Policy{Retries: retries, Classes: parsed, Backoff: DefaultBackoff, Jitter: DefaultJitter}, nil
+}
+
+// ParseClasses parses a comma-separated list of failure classes
+func ParseClasses(s string) (map[Class]bool, error) {
+	classes := make(map[Class]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch class := Class(name); class {
+		case "":
+		case ServerError, RateLimit, Network, Checksum:
+			classes[class] = true
+		default:
+			return nil, fmt.Errorf("invalid retry class '%s': must be 5xx, rate-limit, network or checksum", name)
+		}
+	}
+	return classes, nil
+}
+
+// Only returns a copy of the policy retrying only those of classes it
+// retries, for an operation whose other failures are retried by a layer
+// below
+func (p Policy) Only(classes ...Class) Policy {
+	only := make(map[Class]bool, len(classes))
+	for _, class := range classes {
+		if p.Classes[class] {
+			only[class] = true
+		}
+	}
+	p.Classes = only
+	return p
+}
+
+// Do calls fn until it succeeds, fails with a class the policy does not
+// retry, or runs out of retries. label names the operation in the messages
+// printed before each retry.
+func (p Policy) Do(label string, fn func() error) error {
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	backoff := p.Backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		class, ok := Classify(err)
+		if attempt >= p.Retries || !ok || !p.Classes[class] {
+			return err
+		}
+
+		jitter := time.Duration(rand.Float64() * p.Jitter * float64(backoff))
+		wait := min(max(backoff+jitter, serverWait(err)), maxWait)
+		log.Event(log.LevelInfo, "retry", log.Retry{Label: label, Attempt: attempt + 1, Retries: p.Retries, Class: string(class), WaitMS: wait.Milliseconds(), Error: err.Error()},
+			"Retrying %s in %s after %s failure (%d of %d): %v", label, wait, class, attempt+1, p.Retries, err)
+		sleep(wait)
+		backoff *= 2
+	}
+}
+
+// Classify returns the class of a failure, or false if it has none and must
+// not be retried
+func Classify(err error) (Class, bool) {
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode >= 500:
+			return ServerError, true
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return RateLimit, true
+		case httpErr.StatusCode == http.StatusForbidden && httpErr.Headers.Get("X-RateLimit-Remaining") == "0":
+			return RateLimit, true
+		}
+		return "", false
+	}
+
+	if errors.Is(err, ErrChecksum) {
+		return Checksum, true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return Network, true
+	}
+	return "", false
+}
+
+// serverWait returns how long the server asked to wait before retrying, from
+// Retry-After or the rate limit reset time
+func serverWait(err error) time.Duration {
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(httpErr.Headers.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(httpErr.Headers.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return time.Until(time.Unix(reset, 0))
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+func httpError(status int, headers http.Header) error {
+	if headers == nil {
+		headers = http.Header{}
+	}
+	return fmt.Errorf("failed to download: %w", &api.HTTPError{StatusCode: status, Headers: headers})
+}
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		err   error
+		class Class
+		ok    bool
+	}{
+		{httpError(502, nil), ServerError, true},
+		{httpError(429, nil), RateLimit, true},
+		{httpError(403, http.Header{"X-Ratelimit-Remaining": {"0"}}), RateLimit, true},
+		{httpError(403, nil), "", false},
+		{httpError(404, nil), "", false},
+		{fmt.Errorf("failed to write: %w", io.ErrUnexpectedEOF), Network, true},
+		{fmt.Errorf("asset: %w", ErrChecksum), Checksum, true},
+		{errors.New("permission denied"), "", false},
+	}
+
+	for _, tc := range testCases {
+		class, ok := Classify(tc.err)
+		if class != tc.class || ok != tc.ok {
+			t.Errorf("Classify(%v): expected %q %t, got %q %t", tc.err, tc.class, tc.ok, class, ok)
+		}
+	}
+}
+
+func TestParseClasses(t *testing.T) {
+	classes, err := ParseClasses("5xx, network")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !classes[ServerError] || !classes[Network] || classes[RateLimit] {
+		t.Errorf("Unexpected classes %v", classes)
+	}
+
+	if classes, err := ParseClasses(""); err != nil || len(classes) != 0 {
+		t.Errorf("Expected no classes, got %v %v", classes, err)
+	}
+	if _, err := ParseClasses("5xx,404"); err == nil {
+		t.Error("Expected error for an unknown class, got nil")
+	}
+}
+
+func TestPolicy_Do(t *testing.T) {
+	var waits []time.Duration
+	policy, err := NewPolicy(2, "5xx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Jitter = 0
+	policy.Sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	attempts := 0
+	err = policy.Do("asset", func() error {
+		attempts++
+		if attempts < 3 {
+			return httpError(503, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attempts != 3 || len(waits) != 2 || waits[1] != 2*waits[0] {
+		t.Errorf("Expected 3 attempts with doubling waits, got %d attempts and waits %v", attempts, waits)
+	}
+
+	attempts = 0
+	err = policy.Do("asset", func() error {
+		attempts++
+		return httpError(404, nil)
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected 404 to fail immediately, got %d attempts and %v", attempts, err)
+	}
+
+	attempts = 0
+	err = policy.Do("asset", func() error {
+		attempts++
+		return httpError(500, nil)
+	})
+	if err == nil || attempts != 3 {
+		t.Errorf("Expected to give up after 3 attempts, got %d attempts and %v", attempts, err)
+	}
+}
+
+func TestPolicy_Only(t *testing.T) {
+	policy, err := NewPolicy(2, "5xx,checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	only := policy.Only(Checksum, Network)
+	if len(only.Classes) != 1 || !only.Classes[Checksum] {
+		t.Errorf("Expected only checksum to be retried, got %v", only.Classes)
+	}
+	if only.Retries != 2 || !policy.Classes[ServerError] {
+		t.Errorf("Expected the retries to be kept and the policy unchanged, got %d and %v", only.Retries, policy.Classes)
+	}
+}
+
+func TestPolicy_DoHonorsRetryAfter(t *testing.T) {
+	var waits []time.Duration
+	policy := Policy{Retries: 1, Classes: map[Class]bool{RateLimit: true}, Backoff: time.Second}
+	policy.Sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	attempts := 0
+	err := policy.Do("asset", func() error {
+		attempts++
+		if attempts == 1 {
+			return httpError(429, http.Header{"Retry-After": {"7"}})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(waits) != 1 || waits[0] != 7*time.Second {
+		t.Errorf("Expected to wait 7s, got %v", waits)
+	}
+}
+
+func TestPolicy_DoJitter(t *testing.T) {
+	var waits []time.Duration
+	policy := Policy{Retries: 3, Classes: map[Class]bool{ServerError: true}, Backoff: time.Second, Jitter: 0.5}
+	policy.Sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	err := policy.Do("asset", func() error { return httpError(503, nil) })
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	for i, wait := range waits {
+		base := time.Second << i
+		if wait < base || wait > base+base/2 {
+			t.Errorf("Expected wait %d between %s and %s, got %s", i, base, base+base/2, wait)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package schema generates JSON Schemas of the JSON encoding of Go types from
+// their fields and json tags, so that the schemas of machine-readable outputs
+// cannot drift from the structs that produce them.
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// For returns the JSON Schema of the JSON encoding of values like v. Fields
+// without omitempty are required, and objects allow further properties so
+// that fields added later do not break validation.
+func For(v any, title, description string) map[string]any {
+	s := typeSchema(reflect.TypeOf(v))
+	s["$schema"] = Draft
+	s["title"] = title
+	if description != "" {
+		s["description"] = description
+	}
+	return s
+}
+
+// Tagged returns the JSON Schema of objects that are one of the variants,
+// told apart by the name their key property holds. Each has the fields of
+// common, which holds key, and then those of its variant, as the lines of
+// a log that are an envelope followed by the fields of an event.
+func Tagged(common any, key string, variants map[string]any, title, description string) map[string]any {
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	oneOf := make([]any, 0, len(names))
+	for _, name := range names {
+		properties := map[string]any{}
+		required := []any{}
+		addFields(reflect.TypeOf(common), properties, &required)
+		addFields(reflect.TypeOf(variants[name]), properties, &required)
+		properties[key] = map[string]any{"type": "string", "const": name}
+		oneOf = append(oneOf, map[string]any{"type": "object", "properties": properties, "required": required})
+	}
+
+	s := map[string]any{"$schema": Draft, "title": title, "oneOf": oneOf}
+	if description != "" {
+		s["description"] = description
+	}
+	return s
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := typeSchema(t.Elem())
+		if typ, ok := s["type"].(string); ok {
+			s["type"] = []any{typ, "null"}
+		}
+		return s
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		// nil slices encode as null
+		return map[string]any{"type": []any{"array", "null"}, "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []any{"object", "null"}, "additionalProperties": typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []any{}
+	addFields(t, properties, &required)
+
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// addFields adds the encoded fields of a struct, including those of embedded
+// structs encoding/json inlines
+func addFields(t reflect.Type, properties map[string]any, required *[]any) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = typeSchema(field.Type)
+		if !hasOption(options, "omitempty") && !hasOption(options, "omitzero") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Value float64 `json:"value"`
+}
+
+type Embedded struct {
+	Extra string `json:"extra"`
+}
+
+type record struct {
+	Embedded
+	Name     string            `json:"name"`
+	Count    int               `json:"count,omitempty"`
+	Done     bool              `json:"done"`
+	At       time.Time         `json:"at"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Inner    *inner            `json:"inner,omitempty"`
+	Skipped  string            `json:"-"`
+	Untagged string
+	hidden   string
+}
+
+func TestFor(t *testing.T) {
+	got, err := json.Marshal(For(record{hidden: "x"}, "Record", "A test record"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","description":"A test record",` +
+		`"properties":{"Untagged":{"type":"string"},"at":{"format":"date-time","type":"string"},"count":{"type":"integer"},` +
+		`"done":{"type":"boolean"},"extra":{"type":"string"},` +
+		`"inner":{"properties":{"value":{"type":"number"}},"required":["value"],"type":["object","null"]},` +
+		`"labels":{"additionalProperties":{"type":"string"},"type":["object","null"]},"name":{"type":"string"},` +
+		`"tags":{"items":{"type":"string"},"type":["array","null"]}},` +
+		`"required":["extra","name","done","at","tags","Untagged"],"title":"Record","type":"object"}`
+	if string(got) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestTagged(t *testing.T) {
+	type envelope struct {
+		Kind string `json:"kind"`
+	}
+	type start struct {
+		Size int `json:"size"`
+	}
+	type failed struct {
+		Error string `json:"error,omitempty"`
+	}
+
+	got, err := json.Marshal(Tagged(envelope{}, "kind", map[string]any{"start": start{}, "failed": failed{}}, "Events", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","oneOf":[` +
+		`{"properties":{"error":{"type":"string"},"kind":{"const":"failed","type":"string"}},"required":["kind"],"type":"object"},` +
+		`{"properties":{"kind":{"const":"start","type":"string"},"size":{"type":"integer"}},"required":["kind","size"],"type":"object"}],` +
+		`"title":"Events"}`
+	if string(got) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
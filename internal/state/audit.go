@@ -0,0 +1,90 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AuditFile is the name of the audit log of the serve proxy in the state
+// directory. Like the history it holds one JSON entry per line and is only
+// ever appended to.
+const AuditFile = "audit.jsonl"
+
+// AuditEntry records one request to the serve proxy and how it was answered
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Client string    `json:"client,omitempty"`
+	Remote string    `json:"remote"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+	// Cache is hit, miss or bypass for served assets
+	Cache string `json:"cache,omitempty"`
+	// Bytes is the size of the response body sent
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// AuditPath returns the path of the audit log
+func AuditPath() string {
+	return filepath.Join(Dir(), AuditFile)
+}
+
+// AppendAudit adds an entry to the audit log at path
+func AppendAudit(path string, entry AuditEntry) (err error) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadAudit returns the entries of the audit log at path, oldest first.
+// Lines that are not valid entries are skipped.
+func ReadAudit(path string) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close audit log: %v\n", closeErr)
+		}
+	}()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
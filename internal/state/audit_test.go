@@ -0,0 +1,92 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAudit(t *testing.T) {
+	t.Setenv(DirEnv, t.TempDir())
+	path := AuditPath()
+
+	entry := AuditEntry{Time: time.Now().UTC(), Client: "ci", Remote: "10.0.0.1:1234", Method: "GET", Path: "/owner/repo/v1/tool.tar.gz", Status: 200, Cache: "hit"}
+	for range 2 {
+		if err := AppendAudit(path, entry); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var read AuditEntry
+	if err := json.Unmarshal([]byte(lines[1]), &read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Client != "ci" || read.Status != 200 || read.Cache != "hit" {
+		t.Errorf("Unexpected entry %+v", read)
+	}
+}
+
+func TestStore_AppendAudit(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Setenv(DirEnv, t.TempDir())
+		t.Setenv(BackendEnv, backend)
+
+		store, err := Open()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := store.AppendAudit(AuditEntry{Time: time.Now(), Method: "GET", Path: "/owner/repo/v1/a", Status: 401}); err != nil {
+			t.Errorf("%s: expected no error, got %v", backend, err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, records, err := Query(DatabasePath(), "SELECT status FROM audit")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 1 || records[0][0] != "401" {
+		t.Errorf("Unexpected audit rows %v", records)
+	}
+}
+
+func TestStore_ReadAudit(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Setenv(DirEnv, t.TempDir())
+		t.Setenv(BackendEnv, backend)
+
+		store, err := Open()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		recorded := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		for _, status := range []int{200, 404} {
+			if err := store.AppendAudit(AuditEntry{Time: recorded, Client: "ci", Method: "GET", Path: "/owner/repo/v1/a", Status: status, Bytes: 42}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		entries, err := store.ReadAudit()
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", backend, err)
+		}
+		if len(entries) != 2 || entries[0].Status != 200 || entries[1].Status != 404 || entries[0].Bytes != 42 || !entries[0].Time.Equal(recorded) {
+			t.Errorf("%s: unexpected entries %+v", backend, entries)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheDir is the directory in the state directory holding metadata cached
+// from the API, for use while the API is unavailable
+const CacheDir = "cache"
+
+// ErrNotCached is returned by ReadCache when nothing is cached at a path
+var ErrNotCached = errors.New("not cached")
+
+type cacheEntry struct {
+	Fetched time.Time       `json:"fetched"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// CachePath returns the path caching the value under key, a slash-separated
+// name such as "releases/owner/repo/latest"
+func CachePath(key string) string {
+	return filepath.Join(Dir(), CacheDir, filepath.FromSlash(key)+".json")
+}
+
+// WriteCache stores value at path along with the time it was fetched
+func WriteCache(path string, value any, fetched time.Time) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cacheEntry{Fetched: fetched.UTC(), Value: raw})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
+
+// ReadCache loads the value cached at path into value and returns when it
+// was fetched, or ErrNotCached
+func ReadCache(path string, value any) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, ErrNotCached
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse cache %s: %w", path, err)
+	}
+	if err := json.Unmarshal(entry.Value, value); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse cache %s: %w", path, err)
+	}
+	return entry.Fetched, nil
+}
@@ -0,0 +1,45 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "releases", "owner", "repo", "latest.json")
+	fetched := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var value map[string]string
+	if _, err := ReadCache(path, &value); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("Expected ErrNotCached, got %v", err)
+	}
+
+	if err := WriteCache(path, map[string]string{"tag_name": "v1.0.0"}, fetched); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got, err := ReadCache(path, &value)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !got.Equal(fetched) || value["tag_name"] != "v1.0.0" {
+		t.Errorf("Unexpected cache %v %v", got, value)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCache(path, &value); err == nil || errors.Is(err, ErrNotCached) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	t.Setenv(DirEnv, "/state")
+	expected := filepath.Join("/state", "cache", "releases", "owner", "repo", "latest.json")
+	if got := CachePath("releases/owner/repo/latest"); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
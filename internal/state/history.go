@@ -0,0 +1,96 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryFile is the name of the download history in the state directory.
+// It holds one JSON entry per line and is only ever appended to.
+const HistoryFile = "history.jsonl"
+
+// HistoryEntry records the files one run wrote from a release
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag"`
+	Commit     string    `json:"commit,omitempty"`
+	Directory  string    `json:"directory"`
+	Paths      []string  `json:"paths"`
+	// Host is the GitHub host of the repository, such as a GitHub
+	// Enterprise Server; empty for github.com
+	Host string `json:"host,omitempty"`
+	// Pattern is the --pattern of the run, unless it matched every asset
+	Pattern string `json:"pattern,omitempty"`
+	// Assets are the names of the release assets the files were saved
+	// from, by path. Files that are not a release asset as downloaded, such
+	// as extracted files and source archives, have none.
+	Assets map[string]string `json:"assets,omitempty"`
+}
+
+// HistoryPath returns the path of the download history
+func HistoryPath() string {
+	return filepath.Join(Dir(), HistoryFile)
+}
+
+// AppendHistory adds an entry to the history at path
+func AppendHistory(path string, entry HistoryEntry) (err error) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadHistory returns the entries of the history at path, oldest first. A
+// missing history has no entries; lines that cannot be parsed, such as one
+// cut short by a crash, are skipped.
+func ReadHistory(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close history: %v\n", closeErr)
+		}
+	}()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory_AppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", HistoryFile)
+
+	entries, err := ReadHistory(path)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Expected an empty history, got %v and %v", entries, err)
+	}
+
+	first := HistoryEntry{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Repository: "owner/repo", Tag: "v1.0.0", Directory: "/mirror", Paths: []string{"/mirror/app.tar.gz"}}
+	second := HistoryEntry{Time: first.Time.Add(time.Hour), Repository: "owner/other", Tag: "v2.0.0", Directory: "/mirror", Paths: []string{"/mirror/other.zip"}}
+	for _, entry := range []HistoryEntry{first, second} {
+		if err := AppendHistory(path, entry); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	entries, err = ReadHistory(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Repository != "owner/repo" || !entries[0].Time.Equal(first.Time) || entries[1].Tag != "v2.0.0" {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+}
+
+func TestReadHistory_SkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFile)
+	content := `{"repository":"owner/repo","tag":"v1.0.0"}
+{"repository":"owner/re`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadHistory(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 1 || entries[0].Tag != "v1.0.0" {
+		t.Errorf("Expected only the complete entry, got %+v", entries)
+	}
+}
@@ -0,0 +1,338 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Pure Go driver, as releases are built without cgo
+	_ "modernc.org/sqlite"
+)
+
+// DatabaseFile is the name of the SQLite database in the state directory
+const DatabaseFile = "state.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS history (
+	id         INTEGER PRIMARY KEY,
+	time       TEXT NOT NULL,
+	repository TEXT NOT NULL,
+	tag        TEXT NOT NULL,
+	commit_sha TEXT NOT NULL DEFAULT '',
+	directory  TEXT NOT NULL,
+	paths      TEXT NOT NULL,
+	pattern    TEXT NOT NULL DEFAULT '',
+	assets     TEXT NOT NULL DEFAULT '',
+	host       TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS history_repository ON history (repository);
+CREATE TABLE IF NOT EXISTS cache (
+	key     TEXT PRIMARY KEY,
+	fetched TEXT NOT NULL,
+	value   TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS audit (
+	id     INTEGER PRIMARY KEY,
+	time   TEXT NOT NULL,
+	client TEXT NOT NULL,
+	remote TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path   TEXT NOT NULL,
+	status INTEGER NOT NULL,
+	cache  TEXT NOT NULL,
+	bytes  INTEGER NOT NULL DEFAULT 0
+);
+`
+
+// DatabasePath returns the path of the SQLite database
+func DatabasePath() string {
+	return filepath.Join(Dir(), DatabaseFile)
+}
+
+// sqliteStore keeps the history, the cache and the audit log in tables of
+// one database.
+// Times are stored as RFC 3339 text in UTC, so they sort and compare as
+// strings, the paths of a history entry as a JSON array and its asset names
+// as a JSON object.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database at path, creating it and its tables if
+// needed. Concurrent runs wait for each other's writes instead of failing.
+func OpenSQLite(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if err := initSchema(db); err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close %s: %v\n", path, closeErr)
+		}
+		return nil, fmt.Errorf("failed to initialize %s: %w", path, err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// initSchema creates the tables, and adds the columns that databases
+// created by earlier versions lack
+func initSchema(db *sql.DB) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	columns := []struct{ table, column, definition string }{
+		{"history", "pattern", "TEXT NOT NULL DEFAULT ''"},
+		{"history", "assets", "TEXT NOT NULL DEFAULT ''"},
+		{"history", "host", "TEXT NOT NULL DEFAULT ''"},
+		{"audit", "bytes", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := addColumn(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumn adds a column to a table unless it has one of that name
+func addColumn(db *sql.DB, table, column, definition string) (err error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func (s *sqliteStore) AppendHistory(entry HistoryEntry) error {
+	paths, err := json.Marshal(entry.Paths)
+	if err != nil {
+		return err
+	}
+	var assets []byte
+	if len(entry.Assets) > 0 {
+		if assets, err = json.Marshal(entry.Assets); err != nil {
+			return err
+		}
+	}
+	_, err = s.db.Exec(
+		"INSERT INTO history (time, repository, tag, commit_sha, directory, paths, pattern, assets, host) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		formatTime(entry.Time), entry.Repository, entry.Tag, entry.Commit, entry.Directory, string(paths), entry.Pattern, string(assets), entry.Host,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) ReadHistory() (entries []HistoryEntry, err error) {
+	rows, err := s.db.Query("SELECT time, repository, tag, commit_sha, directory, paths, pattern, assets, host FROM history ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var entry HistoryEntry
+		var recorded, paths, assets string
+		if err := rows.Scan(&recorded, &entry.Repository, &entry.Tag, &entry.Commit, &entry.Directory, &paths, &entry.Pattern, &assets, &entry.Host); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		if entry.Time, err = time.Parse(time.RFC3339Nano, recorded); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		if err := json.Unmarshal([]byte(paths), &entry.Paths); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		if assets != "" {
+			if err := json.Unmarshal([]byte(assets), &entry.Assets); err != nil {
+				return nil, fmt.Errorf("failed to read history: %w", err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *sqliteStore) WriteCache(key string, value any, fetched time.Time) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		"INSERT INTO cache (key, fetched, value) VALUES (?, ?, ?) ON CONFLICT (key) DO UPDATE SET fetched = excluded.fetched, value = excluded.value",
+		key, formatTime(fetched), string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) ReadCache(key string, value any) (time.Time, error) {
+	var fetched, data string
+	err := s.db.QueryRow("SELECT fetched, value FROM cache WHERE key = ?", key).Scan(&fetched, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrNotCached
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, fetched)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse cache %s: %w", key, err)
+	}
+	if err := json.Unmarshal([]byte(data), value); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse cache %s: %w", key, err)
+	}
+	return t, nil
+}
+
+func (s *sqliteStore) CacheKeys() (keys []string, err error) {
+	rows, err := s.db.Query("SELECT key FROM cache ORDER BY key")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to list cache: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *sqliteStore) AppendAudit(entry AuditEntry) error {
+	_, err := s.db.Exec(
+		"INSERT INTO audit (time, client, remote, method, path, status, cache, bytes) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		formatTime(entry.Time), entry.Client, entry.Remote, entry.Method, entry.Path, entry.Status, entry.Cache, entry.Bytes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) ReadAudit() (entries []AuditEntry, err error) {
+	rows, err := s.db.Query("SELECT time, client, remote, method, path, status, cache, bytes FROM audit ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var entry AuditEntry
+		var recorded string
+		if err := rows.Scan(&recorded, &entry.Client, &entry.Remote, &entry.Method, &entry.Path, &entry.Status, &entry.Cache, &entry.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		if entry.Time, err = time.Parse(time.RFC3339Nano, recorded); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// Query runs a read-only SQL query against the database at path and returns
+// the column names and the rows as text, with NULL as an empty string
+func Query(path, query string) (columns []string, records [][]string, err error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(10000)&_pragma=query_only(1)")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	if columns, err = rows.Columns(); err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		targets := make([]any, len(columns))
+		for i := range values {
+			targets[i] = &values[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, nil, err
+		}
+		record := make([]string, len(columns))
+		for i, value := range values {
+			record[i] = value.String
+		}
+		records = append(records, record)
+	}
+	return columns, records, rows.Err()
+}
+
+// formatTime formats t with fixed-width fractional seconds, unlike
+// time.RFC3339Nano, so that stored times sort as strings
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z07:00")
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DatabaseFile)
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	recorded := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	entries := []HistoryEntry{
+		{Time: recorded, Repository: "owner/app", Tag: "v1.0.0", Commit: "abc", Directory: "/mirror", Paths: []string{"/mirror/app"}, Pattern: "app*", Assets: map[string]string{"/mirror/app": "app_linux"}, Host: "ghe.example.com"},
+		{Time: recorded.Add(time.Hour), Repository: "owner/tool", Tag: "v2.0.0", Directory: "/tools", Paths: []string{"/tools/a", "/tools/b"}},
+	}
+	for _, entry := range entries {
+		if err := store.AppendHistory(entry); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	got, err := store.ReadHistory()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(got) != 2 || !got[0].Time.Equal(recorded) || got[0].Commit != "abc" || got[0].Pattern != "app*" || got[0].Assets["/mirror/app"] != "app_linux" || got[0].Host != "ghe.example.com" || len(got[1].Paths) != 2 || got[1].Assets != nil {
+		t.Errorf("Unexpected history %+v", got)
+	}
+
+	var value map[string]string
+	if _, err := store.ReadCache("releases/owner/app/latest", &value); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Expected ErrNotCached, got %v", err)
+	}
+	for _, tag := range []string{"v1.0.0", "v1.1.0"} {
+		if err := store.WriteCache("releases/owner/app/latest", map[string]string{"tag_name": tag}, recorded); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	fetched, err := store.ReadCache("releases/owner/app/latest", &value)
+	if err != nil || !fetched.Equal(recorded) || value["tag_name"] != "v1.1.0" {
+		t.Errorf("Unexpected cache %v %v: %v", fetched, value, err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	columns, rows, err := Query(path, "SELECT repository, count(*) AS runs FROM history GROUP BY repository ORDER BY repository")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(columns, ",") != "repository,runs" || len(rows) != 2 || rows[1][0] != "owner/tool" || rows[1][1] != "1" {
+		t.Errorf("Unexpected query result %v %v", columns, rows)
+	}
+
+	if _, _, err := Query(path, "DELETE FROM history"); err == nil {
+		t.Error("Expected queries to be read-only, got nil")
+	}
+	if _, _, err := Query(filepath.Join(t.TempDir(), DatabaseFile), "SELECT 1"); err == nil {
+		t.Error("Expected error for a missing database, got nil")
+	}
+}
+
+func TestOpenSQLite_AddsColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DatabaseFile)
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The audit table as created before it had a bytes column
+	if _, err := db.Exec("CREATE TABLE audit (id INTEGER PRIMARY KEY, time TEXT NOT NULL, client TEXT NOT NULL, remote TEXT NOT NULL, method TEXT NOT NULL, path TEXT NOT NULL, status INTEGER NOT NULL, cache TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	// The history table as created before it had pattern, assets and host
+	// columns
+	if _, err := db.Exec("CREATE TABLE history (id INTEGER PRIMARY KEY, time TEXT NOT NULL, repository TEXT NOT NULL, tag TEXT NOT NULL, commit_sha TEXT NOT NULL DEFAULT '', directory TEXT NOT NULL, paths TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		store, err := OpenSQLite(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := store.AppendAudit(AuditEntry{Time: time.Now(), Method: "GET", Path: "/a", Status: 200, Bytes: 3}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if err := store.AppendHistory(HistoryEntry{Time: time.Now(), Repository: "owner/app", Paths: []string{"/a"}, Assets: map[string]string{"/a": "a"}}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Package state keeps the local records gh-download maintains across runs,
+// such as the download history, in JSON files or a SQLite database.
+package state
+
+import (
+	"os"
+	"path/filepath"
+
+	ghconfig "github.com/cli/go-gh/v2/pkg/config"
+)
+
+// DirEnv overrides the state directory, e.g. to keep the records of a cron
+// mirror apart from those of interactive use
+const DirEnv = "GH_DOWNLOAD_STATE_DIR"
+
+// Dir returns the directory holding the state files: $GH_DOWNLOAD_STATE_DIR,
+// or gh-download in the state directory of gh
+func Dir() string {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(ghconfig.StateDir(), "gh-download")
+}
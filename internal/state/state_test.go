@@ -0,0 +1,20 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	t.Setenv(DirEnv, "/var/lib/mirror-state")
+	if got := Dir(); got != "/var/lib/mirror-state" {
+		t.Errorf("Expected %q, got %q", "/var/lib/mirror-state", got)
+	}
+
+	t.Setenv(DirEnv, "")
+	t.Setenv("XDG_STATE_HOME", "/home/user/.state")
+	expected := filepath.Join("/home/user/.state", "gh", "gh-download")
+	if got := Dir(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackendEnv selects where the history, the metadata cache and the audit log
+// are kept:
+// "json", the default, keeps them in files in the state directory, and
+// "sqlite" in one database there, for heavy use such as org mirrors
+const BackendEnv = "GH_DOWNLOAD_STATE_BACKEND"
+
+// Backends selectable with $GH_DOWNLOAD_STATE_BACKEND
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+)
+
+// Store keeps the download history, the metadata cache and the audit log of
+// the serve proxy. Cache keys are
+// slash-separated names such as "releases/owner/repo/latest".
+type Store interface {
+	AppendHistory(entry HistoryEntry) error
+	ReadHistory() ([]HistoryEntry, error)
+	WriteCache(key string, value any, fetched time.Time) error
+	// ReadCache returns ErrNotCached when nothing is cached under key
+	ReadCache(key string, value any) (time.Time, error)
+	// CacheKeys returns the keys of every cache entry, sorted
+	CacheKeys() ([]string, error)
+	AppendAudit(entry AuditEntry) error
+	ReadAudit() ([]AuditEntry, error)
+	Close() error
+}
+
+// Backend returns the backend selected with $GH_DOWNLOAD_STATE_BACKEND
+func Backend() (string, error) {
+	switch backend := os.Getenv(BackendEnv); backend {
+	case "", BackendJSON:
+		return BackendJSON, nil
+	case BackendSQLite:
+		return BackendSQLite, nil
+	default:
+		return "", fmt.Errorf("%s must be %q or %q, got %q", BackendEnv, BackendJSON, BackendSQLite, backend)
+	}
+}
+
+// Open opens the store of the selected backend in the state directory
+func Open() (Store, error) {
+	backend, err := Backend()
+	if err != nil {
+		return nil, err
+	}
+	if backend == BackendSQLite {
+		return OpenSQLite(DatabasePath())
+	}
+	return jsonStore{}, nil
+}
+
+// jsonStore keeps the history in history.jsonl, the audit log in
+// audit.jsonl and each cache entry in its own file below the cache directory
+type jsonStore struct{}
+
+func (jsonStore) AppendHistory(entry HistoryEntry) error {
+	return AppendHistory(HistoryPath(), entry)
+}
+
+func (jsonStore) ReadHistory() ([]HistoryEntry, error) {
+	return ReadHistory(HistoryPath())
+}
+
+func (jsonStore) WriteCache(key string, value any, fetched time.Time) error {
+	return WriteCache(CachePath(key), value, fetched)
+}
+
+func (jsonStore) ReadCache(key string, value any) (time.Time, error) {
+	return ReadCache(CachePath(key), value)
+}
+
+func (jsonStore) CacheKeys() ([]string, error) {
+	root := filepath.Join(Dir(), CacheDir)
+	var keys []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		rel, err := filepath.Rel(root, strings.TrimSuffix(path, ".json"))
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (jsonStore) AppendAudit(entry AuditEntry) error {
+	return AppendAudit(AuditPath(), entry)
+}
+
+func (jsonStore) ReadAudit() ([]AuditEntry, error) {
+	return ReadAudit(AuditPath())
+}
+
+func (jsonStore) Close() error {
+	return nil
+}
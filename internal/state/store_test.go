@@ -0,0 +1,66 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(DirEnv, dir)
+
+	t.Setenv(BackendEnv, "")
+	store, err := Open()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := store.(jsonStore); !ok {
+		t.Errorf("Expected the JSON store by default, got %T", store)
+	}
+
+	t.Setenv(BackendEnv, BackendSQLite)
+	store, err = Open()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := store.(*sqliteStore); !ok {
+		t.Errorf("Expected the SQLite store, got %T", store)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(BackendEnv, "postgres")
+	if _, err := Open(); err == nil {
+		t.Error("Expected error for an unknown backend, got nil")
+	}
+}
+
+func TestStore_CacheKeys(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Setenv(DirEnv, t.TempDir())
+		t.Setenv(BackendEnv, backend)
+
+		store, err := Open()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		keys, err := store.CacheKeys()
+		if err != nil || len(keys) != 0 {
+			t.Errorf("%s: expected no keys, got %v, %v", backend, keys, err)
+		}
+		for _, key := range []string{"releases/owner/repo/v1.0.0", "releases/owner/repo/latest"} {
+			if err := store.WriteCache(key, map[string]string{"tag_name": "v1.0.0"}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		keys, err = store.CacheKeys()
+		if err != nil || strings.Join(keys, ",") != "releases/owner/repo/latest,releases/owner/repo/v1.0.0" {
+			t.Errorf("%s: unexpected keys %v, %v", backend, keys, err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TapsDir is the directory in the state directory holding the taps. Each tap
+// keeps its manifest in a directory named after it, and taps.json lists the
+// sources they were fetched from.
+const TapsDir = "taps"
+
+const tapsIndexFile = "taps.json"
+
+// Tap is a tool manifest fetched from a shared source
+type Tap struct {
+	Name    string    `json:"name"`
+	Source  string    `json:"source"`
+	Updated time.Time `json:"updated"`
+}
+
+// TapsPath returns the directory holding the taps
+func TapsPath() string {
+	return filepath.Join(Dir(), TapsDir)
+}
+
+// ValidateTapName rejects tap names that cannot be used as a relative
+// directory, such as ones escaping the taps directory
+func ValidateTapName(name string) error {
+	if name == "" {
+		return fmt.Errorf("tap name is required")
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `\:`) {
+			return fmt.Errorf("invalid tap name %q", name)
+		}
+	}
+	return nil
+}
+
+// TapManifestPath returns the path of the manifest of a tap in dir
+func TapManifestPath(dir, name string) string {
+	return filepath.Join(dir, filepath.FromSlash(name), "manifest.yml")
+}
+
+// ReadTaps returns the taps in dir, sorted by name
+func ReadTaps(dir string) ([]Tap, error) {
+	data, err := os.ReadFile(filepath.Join(dir, tapsIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read taps: %w", err)
+	}
+
+	var taps []Tap
+	if err := json.Unmarshal(data, &taps); err != nil {
+		return nil, fmt.Errorf("failed to parse taps: %w", err)
+	}
+	sort.Slice(taps, func(i, j int) bool {
+		return taps[i].Name < taps[j].Name
+	})
+	return taps, nil
+}
+
+// WriteTap stores the manifest of a tap in dir, adding the tap or replacing
+// an earlier version of it
+func WriteTap(dir string, tap Tap, manifest []byte) error {
+	if err := ValidateTapName(tap.Name); err != nil {
+		return err
+	}
+	taps, err := ReadTaps(dir)
+	if err != nil {
+		return err
+	}
+
+	path := TapManifestPath(dir, tap.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create tap directory: %w", err)
+	}
+	if err := writeFileAtomic(path, manifest); err != nil {
+		return fmt.Errorf("failed to write tap manifest: %w", err)
+	}
+
+	taps = removeTap(taps, tap.Name)
+	return writeTaps(dir, append(taps, tap))
+}
+
+// RemoveTap removes a tap and its manifest from dir
+func RemoveTap(dir, name string) error {
+	taps, err := ReadTaps(dir)
+	if err != nil {
+		return err
+	}
+	remaining := removeTap(taps, name)
+	if len(remaining) == len(taps) {
+		return fmt.Errorf("no tap named %s", name)
+	}
+
+	if err := os.RemoveAll(filepath.Dir(TapManifestPath(dir, name))); err != nil {
+		return fmt.Errorf("failed to remove tap manifest: %w", err)
+	}
+	return writeTaps(dir, remaining)
+}
+
+func removeTap(taps []Tap, name string) []Tap {
+	var kept []Tap
+	for _, tap := range taps {
+		if tap.Name != name {
+			kept = append(kept, tap)
+		}
+	}
+	return kept
+}
+
+func writeTaps(dir string, taps []Tap) error {
+	if taps == nil {
+		taps = []Tap{}
+	}
+	data, err := json.MarshalIndent(taps, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create taps directory: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, tapsIndexFile), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write taps: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data so readers never see a partial
+// file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
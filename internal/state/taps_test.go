@@ -0,0 +1,75 @@
+package state
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWriteTap(t *testing.T) {
+	dir := t.TempDir()
+
+	taps, err := ReadTaps(dir)
+	if err != nil || len(taps) != 0 {
+		t.Fatalf("Expected no taps, got %v, %v", taps, err)
+	}
+
+	updated := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := WriteTap(dir, Tap{Name: "org/tools", Source: "org/tools", Updated: updated}, []byte("v1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := WriteTap(dir, Tap{Name: "extra", Source: "https://example.com/tools.yml", Updated: updated}, []byte("extra")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := WriteTap(dir, Tap{Name: "org/tools", Source: "org/tools", Updated: updated.Add(time.Hour)}, []byte("v2")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	taps, err = ReadTaps(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(taps) != 2 || taps[0].Name != "extra" || taps[1].Name != "org/tools" {
+		t.Fatalf("Unexpected taps %+v", taps)
+	}
+	if !taps[1].Updated.Equal(updated.Add(time.Hour)) {
+		t.Errorf("Expected the tap to be replaced, got %+v", taps[1])
+	}
+	data, err := os.ReadFile(TapManifestPath(dir, "org/tools"))
+	if err != nil || string(data) != "v2" {
+		t.Errorf("Expected manifest v2, got %q, %v", data, err)
+	}
+}
+
+func TestRemoveTap(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteTap(dir, Tap{Name: "org/tools", Source: "org/tools"}, []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveTap(dir, "org/other"); err == nil {
+		t.Error("Expected error for an unknown tap, got nil")
+	}
+	if err := RemoveTap(dir, "org/tools"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if taps, err := ReadTaps(dir); err != nil || len(taps) != 0 {
+		t.Errorf("Expected no taps, got %v, %v", taps, err)
+	}
+	if _, err := os.Stat(TapManifestPath(dir, "org/tools")); !os.IsNotExist(err) {
+		t.Errorf("Expected the manifest to be removed, got %v", err)
+	}
+}
+
+func TestValidateTapName(t *testing.T) {
+	for _, name := range []string{"team", "org/tools"} {
+		if err := ValidateTapName(name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../x", "org//tools", "/abs", `a\b`, "c:x"} {
+		if err := ValidateTapName(name); err == nil {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
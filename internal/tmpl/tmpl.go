@@ -0,0 +1,98 @@
+// Package tmpl expands the Go templates accepted in flags such as --dir and
+// --pattern, with helper functions for common naming schemes.
+package tmpl
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data is the release metadata templates can refer to
+type Data struct {
+	// Repo is the repository in format owner/repo
+	Repo  string
+	Owner string
+	// Name is the repository name without the owner
+	Name        string
+	Tag         string
+	PublishedAt time.Time
+}
+
+var semverPattern = regexp.MustCompile(`^[vV]?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:[-+].*)?$`)
+
+// Funcs returns the helper functions available in templates. Functions
+// taking a string take it last, so they can be used in pipelines such as
+// {{.Tag | trimPrefix "v"}}. os and arch return the platform gh-download runs
+// on, as GOOS and GOARCH name it.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"replace": func(old, replacement, s string) string {
+			return strings.ReplaceAll(s, old, replacement)
+		},
+		"trimPrefix": func(prefix, s string) string {
+			return strings.TrimPrefix(s, prefix)
+		},
+		"trimSuffix": func(suffix, s string) string {
+			return strings.TrimSuffix(s, suffix)
+		},
+		"semverMajor": semverMajor,
+		"date":        date,
+		"os": func() string {
+			return runtime.GOOS
+		},
+		"arch": func() string {
+			return runtime.GOARCH
+		},
+	}
+}
+
+// Render expands text as a template over data. Text without actions is
+// returned as is, and referring to an unknown field is an error.
+func Render(text string, data any) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	t, err := template.New("").Funcs(Funcs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to expand template %q: %w", text, err)
+	}
+	return b.String(), nil
+}
+
+// semverMajor returns the major version of a semantic version such as
+// "v1.2.3"
+func semverMajor(version string) (string, error) {
+	match := semverPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", fmt.Errorf("%q is not a semantic version", version)
+	}
+	return match[1], nil
+}
+
+// date formats a time, or an RFC 3339 timestamp as returned by the API, with
+// a Go layout such as "2006-01-02"
+func date(layout string, value any) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("%q is not an RFC 3339 time", v)
+		}
+		return t.Format(layout), nil
+	default:
+		return "", fmt.Errorf("cannot format %T as a date", value)
+	}
+}
@@ -0,0 +1,76 @@
+package tmpl
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Repo:        "Owner/Tool",
+		Owner:       "Owner",
+		Name:        "Tool",
+		Tag:         "v2.3.1",
+		PublishedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		text     string
+		expected string
+	}{
+		{"plain/dir", "plain/dir"},
+		{"{{semverMajor .Tag}}/{{.Name | lower}}", "2/tool"},
+		{"{{.Repo | upper}}", "OWNER/TOOL"},
+		{"{{.Tag | trimPrefix \"v\"}}", "2.3.1"},
+		{"{{.Tag | replace \".\" \"_\"}}", "v2_3_1"},
+		{"{{\"tool.tar.gz\" | trimSuffix \".gz\"}}", "tool.tar"},
+		{"{{date \"2006-01\" .PublishedAt}}", "2024-05"},
+		{"{{date \"2006\" \"2023-02-03T04:05:06Z\"}}", "2023"},
+		{"app-*-{{os}}-{{arch}}.tar.gz", "app-*-" + runtime.GOOS + "-" + runtime.GOARCH + ".tar.gz"},
+	}
+	for _, tc := range testCases {
+		got, err := Render(tc.text, data)
+		if err != nil {
+			t.Errorf("Expected no error for %q, got %v", tc.text, err)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("Expected %q for %q, got %q", tc.expected, tc.text, got)
+		}
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	testCases := []string{
+		"{{.Unknown}}",
+		"{{.Tag",
+		"{{semverMajor .Name}}",
+		"{{date \"2006\" .Tag}}",
+		"{{nope .Tag}}",
+	}
+	for _, tc := range testCases {
+		if _, err := Render(tc, Data{Name: "tool", Tag: "latest"}); err == nil {
+			t.Errorf("Expected error for %q, got nil", tc)
+		}
+	}
+}
+
+func TestSemverMajor(t *testing.T) {
+	testCases := map[string]string{
+		"v1.2.3":       "1",
+		"10.0":         "10",
+		"V3":           "3",
+		"v2.0.0-rc.1":  "2",
+		"1.2.3+build4": "1",
+	}
+	for version, expected := range testCases {
+		got, err := semverMajor(version)
+		if err != nil || got != expected {
+			t.Errorf("Expected %q for %q, got %q, %v", expected, version, got, err)
+		}
+	}
+	if _, err := semverMajor("jq-1.7.1"); err == nil {
+		t.Error("Expected error for jq-1.7.1, got nil")
+	}
+}
@@ -0,0 +1,255 @@
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status codes of OTLP spans
+const (
+	statusOK    = 1
+	statusError = 2
+)
+
+// spanKindInternal is the OTLP kind of every span recorded here
+const spanKindInternal = 1
+
+// Attr is a span attribute. Values are strings, integers or booleans.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Tracer records the spans of one run below a root span and exports them to
+// an OTLP/HTTP collector. A nil *Tracer records nothing, so callers need no
+// checks when tracing is disabled.
+type Tracer struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	traceID  string
+	root     *Span
+
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// Span is a timed operation. A nil *Span ignores every call.
+type Span struct {
+	tracer   *Tracer
+	id       string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []Attr
+	err      error
+}
+
+// New returns a tracer exporting to the collector at endpoint, and starts
+// its root span named after the service. The endpoint is the base URL of the
+// collector, such as http://localhost:4318; "/v1/traces" is appended unless
+// present.
+func New(endpoint, service string, attrs ...Attr) (*Tracer, error) {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fmt.Errorf("invalid OTLP endpoint '%s': must be an http or https URL", endpoint)
+	}
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+
+	traceID, err := randomID(16)
+	if err != nil {
+		return nil, err
+	}
+	rootID, err := randomID(8)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tracer{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		traceID:  traceID,
+	}
+	t.root = &Span{tracer: t, id: rootID, name: service, start: time.Now(), attrs: attrs}
+	return t, nil
+}
+
+// Start starts a span below the root span
+func (t *Tracer) Start(name string, attrs ...Attr) *Span {
+	if t == nil {
+		return nil
+	}
+	return t.newSpan(name, t.root.id, attrs)
+}
+
+func (t *Tracer) newSpan(name, parentID string, attrs []Attr) *Span {
+	id, err := randomID(8)
+	if err != nil {
+		// Tracing must never break the run; the span is dropped
+		fmt.Fprintf(os.Stderr, "Warning: failed to start span %s: %v\n", name, err)
+		return nil
+	}
+	return &Span{tracer: t, id: id, parentID: parentID, name: name, start: time.Now(), attrs: attrs}
+}
+
+// SetAttr adds attributes to the span
+func (s *Span) SetAttr(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End ends the span, marking it failed when err is not nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// Shutdown ends the root span with err and exports every ended span
+func (t *Tracer) Shutdown(err error) error {
+	if t == nil {
+		return nil
+	}
+	t.root.End(err)
+
+	t.mu.Lock()
+	body, marshalErr := json.Marshal(t.request())
+	t.mu.Unlock()
+	if marshalErr != nil {
+		return fmt.Errorf("failed to encode spans: %w", marshalErr)
+	}
+
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close response body: %v\n", closeErr)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export spans: %s", resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of an export request
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	spanJSON struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Status            status     `json:"status"`
+	}
+	keyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+	status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func (t *Tracer) request() exportRequest {
+	spans := make([]spanJSON, 0, len(t.spans))
+	for _, s := range t.spans {
+		st := status{Code: statusOK}
+		if s.err != nil {
+			st = status{Code: statusError, Message: s.err.Error()}
+		}
+		spans = append(spans, spanJSON{
+			TraceID:           t.traceID,
+			SpanID:            s.id,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        keyValues(s.attrs),
+			Status:            st,
+		})
+	}
+
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: keyValues([]Attr{String("service.name", t.service)})},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: t.service}, Spans: spans}},
+	}}}
+}
+
+func keyValues(attrs []Attr) []keyValue {
+	values := make([]keyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value map[string]any
+		switch v := attr.Value.(type) {
+		case int64:
+			// OTLP JSON encodes 64-bit integers as strings
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		values = append(values, keyValue{Key: attr.Key, Value: value})
+	}
+	return values
+}
+
+func randomID(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
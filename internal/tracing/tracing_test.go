@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracer_Export(t *testing.T) {
+	var received exportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Expected /v1/traces, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON, got %s", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer server.Close()
+
+	tracer, err := New(server.URL, "gh-download", String("repository", "owner/repo"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	span := tracer.Start("transfer", String("asset", "tool.zip"))
+	span.SetAttr(Int("bytes", 42))
+	span.End(errors.New("connection reset"))
+
+	if err := tracer.Shutdown(nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	transfer, root := spans[0], spans[1]
+	if root.Name != "gh-download" || root.ParentSpanID != "" || root.Status.Code != statusOK {
+		t.Errorf("Unexpected root span %+v", root)
+	}
+	if transfer.ParentSpanID != root.SpanID || transfer.TraceID != root.TraceID {
+		t.Errorf("Expected transfer to be a child of the root span, got %+v", transfer)
+	}
+	if transfer.Status.Code != statusError || transfer.Status.Message != "connection reset" {
+		t.Errorf("Expected error status, got %+v", transfer.Status)
+	}
+	if len(transfer.Attributes) != 2 || transfer.Attributes[1].Value["intValue"] != "42" {
+		t.Errorf("Unexpected attributes %+v", transfer.Attributes)
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start("transfer")
+	span.SetAttr(String("asset", "tool.zip"))
+	span.End(nil)
+	if err := tracer.Shutdown(nil); err != nil {
+		t.Errorf("Expected a nil tracer to do nothing, got %v", err)
+	}
+}
+
+func TestNew_InvalidEndpoint(t *testing.T) {
+	if _, err := New("localhost:4318", "gh-download"); err == nil {
+		t.Error("Expected error for an endpoint without scheme, got nil")
+	}
+}
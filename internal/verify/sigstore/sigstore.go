@@ -0,0 +1,250 @@
+// Package sigstore verifies blobs signed with sigstore keyless signing, as
+// cosign sign-blob produces: a signature with the short-lived Fulcio
+// certificate of the signer, or a bundle holding both; and in-toto
+// attestations over blobs signed the same way. The identity in the
+// certificate is checked here; the certificate chain and the transparency
+// log entry are checked by cosign.
+package sigstore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Object identifiers of the OIDC issuer extension of Fulcio certificates:
+// the original one holding the raw issuer, and its DER-encoded successor
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Suffixes appended to the name of a blob to find its signing material
+var (
+	BundleSuffixes    = []string{".sigstore.json", ".sigstore", ".bundle"}
+	SignatureSuffix   = ".sig"
+	CertificateSuffix = ".pem"
+)
+
+// Identity is the signer a certificate must be issued to: the subject, an
+// email address or URI such as the workflow of a GitHub Actions run, and the
+// OIDC issuer that vouched for it
+type Identity struct {
+	Subject string
+	Issuer  string
+}
+
+// Material is the signing material of a blob: a bundle, or a signature and
+// certificate, by the names of the files holding them
+type Material struct {
+	Bundle      string
+	Signature   string
+	Certificate string
+}
+
+// FindMaterial returns the names of the signing material of the blob named
+// name among names, preferring a bundle
+func FindMaterial(names []string, name string) (Material, bool) {
+	for _, suffix := range BundleSuffixes {
+		if slices.Contains(names, name+suffix) {
+			return Material{Bundle: name + suffix}, true
+		}
+	}
+	if slices.Contains(names, name+SignatureSuffix) && slices.Contains(names, name+CertificateSuffix) {
+		return Material{Signature: name + SignatureSuffix, Certificate: name + CertificateSuffix}, true
+	}
+	return Material{}, false
+}
+
+// CertificateIdentity returns the subject and OIDC issuer of a Fulcio
+// certificate
+func CertificateIdentity(cert *x509.Certificate) (Identity, error) {
+	var identity Identity
+	switch {
+	case len(cert.EmailAddresses) > 0:
+		identity.Subject = cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		identity.Subject = cert.URIs[0].String()
+	default:
+		return identity, fmt.Errorf("certificate has no email or URI subject")
+	}
+
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err != nil {
+				return identity, fmt.Errorf("failed to parse OIDC issuer: %w", err)
+			}
+			identity.Issuer = issuer
+		case ext.Id.Equal(oidIssuerV1) && identity.Issuer == "":
+			identity.Issuer = string(ext.Value)
+		}
+	}
+	if identity.Issuer == "" {
+		return identity, fmt.Errorf("certificate has no OIDC issuer")
+	}
+	return identity, nil
+}
+
+// CheckIdentity compares the identity of a certificate with the expected
+// one
+func CheckIdentity(cert *x509.Certificate, want Identity) error {
+	got, err := CertificateIdentity(cert)
+	if err != nil {
+		return err
+	}
+	if got.Subject != want.Subject {
+		return fmt.Errorf("certificate is issued to %s, expected %s", got.Subject, want.Subject)
+	}
+	if got.Issuer != want.Issuer {
+		return fmt.Errorf("certificate is issued by %s, expected %s", got.Issuer, want.Issuer)
+	}
+	return nil
+}
+
+// ParseCertificate parses a certificate file as cosign writes it: PEM, or
+// PEM encoded once more with base64
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("-----BEGIN")) {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("certificate is neither PEM nor base64")
+		}
+		data = decoded
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// BundleCertificate returns the signing certificate of a bundle, in the
+// sigstore bundle format or the earlier one of cosign
+func BundleCertificate(data []byte) (*x509.Certificate, error) {
+	var bundle struct {
+		// sigstore bundle
+		VerificationMaterial struct {
+			Certificate *struct {
+				RawBytes string `json:"rawBytes"`
+			} `json:"certificate"`
+			X509CertificateChain *struct {
+				Certificates []struct {
+					RawBytes string `json:"rawBytes"`
+				} `json:"certificates"`
+			} `json:"x509CertificateChain"`
+		} `json:"verificationMaterial"`
+		// cosign bundle
+		Cert string `json:"cert"`
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	material := bundle.VerificationMaterial
+	var raw string
+	switch {
+	case material.Certificate != nil:
+		raw = material.Certificate.RawBytes
+	case material.X509CertificateChain != nil && len(material.X509CertificateChain.Certificates) > 0:
+		raw = material.X509CertificateChain.Certificates[0].RawBytes
+	case bundle.Cert != "":
+		return ParseCertificate([]byte(bundle.Cert))
+	default:
+		return nil, fmt.Errorf("bundle has no certificate; keyed signatures are not supported")
+	}
+	der, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode bundle certificate: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// Verify checks the blob at path against its signing material, whose paths
+// are given: the identity of the certificate first, then the signature, the
+// certificate chain up to the Fulcio root and the transparency log entry
+// with cosign verify-blob
+func Verify(path string, material Material, identity Identity) error {
+	var cert *x509.Certificate
+	var err error
+	if material.Bundle != "" {
+		data, readErr := os.ReadFile(material.Bundle)
+		if readErr != nil {
+			return readErr
+		}
+		cert, err = BundleCertificate(data)
+	} else {
+		data, readErr := os.ReadFile(material.Certificate)
+		if readErr != nil {
+			return readErr
+		}
+		cert, err = ParseCertificate(data)
+	}
+	if err != nil {
+		return err
+	}
+	if err := CheckIdentity(cert, identity); err != nil {
+		return err
+	}
+
+	return runCosign(cosignArgs(path, material, identity)...)
+}
+
+// VerifyAttestation checks that the blob at path is a subject of the
+// in-toto attestation of predicateType in material, whose paths are given:
+// a bundle, or a DSSE envelope as Signature with its Certificate. cosign
+// verify-blob-attestation checks the identity of the certificate, its chain
+// up to the Fulcio root, the transparency log entry and the signature.
+func VerifyAttestation(path string, material Material, identity Identity, predicateType string) error {
+	return runCosign(attestationArgs(path, material, identity, predicateType)...)
+}
+
+// attestationArgs returns the arguments of cosign verify-blob-attestation
+func attestationArgs(path string, material Material, identity Identity, predicateType string) []string {
+	args := cosignArgs(path, material, identity)
+	args[0] = "verify-blob-attestation"
+	// The blob stays the last argument
+	return append(args[:len(args)-1], "--type", predicateType, path)
+}
+
+// cosignArgs returns the arguments of cosign verify-blob
+func cosignArgs(path string, material Material, identity Identity) []string {
+	args := []string{"verify-blob",
+		"--certificate-identity", identity.Subject,
+		"--certificate-oidc-issuer", identity.Issuer,
+	}
+	switch {
+	case strings.HasSuffix(material.Bundle, ".sigstore.json"):
+		args = append(args, "--bundle", material.Bundle, "--new-bundle-format")
+	case material.Bundle != "":
+		args = append(args, "--bundle", material.Bundle)
+	default:
+		args = append(args, "--signature", material.Signature, "--certificate", material.Certificate)
+	}
+	return append(args, path)
+}
+
+func runCosign(args ...string) error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return fmt.Errorf("cosign is required to verify sigstore signatures: %w", err)
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command("cosign", args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cosign: %w: %s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package sigstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testSubject = "https://github.com/owner/repo/.github/workflows/release.yml@refs/tags/v1.0.0"
+	testIssuer  = "https://token.actions.githubusercontent.com"
+)
+
+// testCertificate returns a self-signed certificate shaped like a Fulcio
+// one, in DER
+func testCertificate(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := url.Parse(testSubject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := asn1.Marshal(testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(10 * time.Minute),
+		URIs:         []*url.URL{subject},
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidIssuerV1, Value: []byte(testIssuer)},
+			{Id: oidIssuerV2, Value: issuer},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestFindMaterial(t *testing.T) {
+	names := []string{"a.tar.gz", "a.tar.gz.sigstore.json", "b.zip", "b.zip.sig", "b.zip.pem", "c.zip", "c.zip.sig"}
+
+	tests := []struct {
+		name string
+		want Material
+		ok   bool
+	}{
+		{"a.tar.gz", Material{Bundle: "a.tar.gz.sigstore.json"}, true},
+		{"b.zip", Material{Signature: "b.zip.sig", Certificate: "b.zip.pem"}, true},
+		{"c.zip", Material{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := FindMaterial(names, tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: expected %+v (%v), got %+v (%v)", tt.name, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestCheckIdentity(t *testing.T) {
+	cert, err := x509.ParseCertificate(testCertificate(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := CertificateIdentity(cert)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if identity.Subject != testSubject || identity.Issuer != testIssuer {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	if err := CheckIdentity(cert, Identity{Subject: testSubject, Issuer: testIssuer}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := CheckIdentity(cert, Identity{Subject: "someone@example.com", Issuer: testIssuer}); err == nil {
+		t.Error("Expected an error for another subject")
+	}
+	if err := CheckIdentity(cert, Identity{Subject: testSubject, Issuer: "https://accounts.google.com"}); err == nil {
+		t.Error("Expected an error for another issuer")
+	}
+}
+
+func TestParseCertificate(t *testing.T) {
+	der := testCertificate(t)
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	for name, data := range map[string][]byte{
+		"pem":    pemData,
+		"base64": []byte(base64.StdEncoding.EncodeToString(pemData)),
+	} {
+		if _, err := ParseCertificate(data); err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+		}
+	}
+	if _, err := ParseCertificate([]byte("not a certificate")); err == nil {
+		t.Error("Expected an error for garbage")
+	}
+}
+
+func TestBundleCertificate(t *testing.T) {
+	der := testCertificate(t)
+	raw := base64.StdEncoding.EncodeToString(der)
+	cosignCert := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	bundles := map[string]string{
+		"certificate": `{"verificationMaterial":{"certificate":{"rawBytes":"` + raw + `"}}}`,
+		"chain":       `{"verificationMaterial":{"x509CertificateChain":{"certificates":[{"rawBytes":"` + raw + `"}]}}}`,
+		"cosign":      `{"base64Signature":"MEUC","cert":"` + cosignCert + `"}`,
+	}
+	for name, bundle := range bundles {
+		cert, err := BundleCertificate([]byte(bundle))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+			continue
+		}
+		if len(cert.URIs) != 1 || cert.URIs[0].String() != testSubject {
+			t.Errorf("%s: unexpected certificate subject %v", name, cert.URIs)
+		}
+	}
+
+	if _, err := BundleCertificate([]byte(`{"verificationMaterial":{"publicKey":{"hint":"abc"}}}`)); err == nil {
+		t.Error("Expected an error for a keyed bundle")
+	}
+}
+
+func TestCosignArgs(t *testing.T) {
+	identity := Identity{Subject: testSubject, Issuer: testIssuer}
+
+	args := cosignArgs("a.tar.gz", Material{Bundle: "a.tar.gz.sigstore.json"}, identity)
+	if !slices.Contains(args, "--new-bundle-format") || args[len(args)-1] != "a.tar.gz" {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+
+	args = cosignArgs("b.zip", Material{Signature: "b.zip.sig", Certificate: "b.zip.pem"}, identity)
+	want := []string{"verify-blob", "--certificate-identity", testSubject, "--certificate-oidc-issuer", testIssuer,
+		"--signature", "b.zip.sig", "--certificate", "b.zip.pem", "b.zip"}
+	if !slices.Equal(args, want) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+}
+
+func TestAttestationArgs(t *testing.T) {
+	identity := Identity{Subject: testSubject, Issuer: testIssuer}
+	args := attestationArgs("a.tar.gz", Material{Signature: "a.intoto.json", Certificate: "a.pem"}, identity, "https://slsa.dev/provenance/v0.2")
+	want := []string{"verify-blob-attestation", "--certificate-identity", testSubject, "--certificate-oidc-issuer", testIssuer,
+		"--signature", "a.intoto.json", "--certificate", "a.pem", "--type", "https://slsa.dev/provenance/v0.2", "a.tar.gz"}
+	if !slices.Equal(args, want) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+}
@@ -4,20 +4,92 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/23prime/gh-download/internal/color"
 	"github.com/23prime/gh-download/internal/config"
 	"github.com/23prime/gh-download/internal/download"
+	"github.com/23prime/gh-download/internal/log"
 )
 
 func main() {
 	cfg := config.ParseArgs()
+	if err := color.Setup(cfg.Color); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	log.FromEnv()
+	switch {
+	case cfg.Debug:
+		log.Raise(log.LevelDebug)
+	case cfg.Verbose:
+		log.Raise(log.LevelVerbose)
+	}
+	if err := log.SetFormat(cfg.LogFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	log.SetTraceHTTP(cfg.DebugHTTP)
 
 	if cfg.Help {
 		config.PrintUsage()
 		return
 	}
 
-	if err := download.DownloadFromRelease(cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	var err error
+	switch cfg.Command {
+	case config.CommandPeek:
+		err = download.Peek(cfg)
+	case config.CommandCompare:
+		err = download.Compare(cfg)
+	case config.CommandActionYAML:
+		err = download.ActionYAML(cfg)
+	case config.CommandAttestMirror:
+		err = download.AttestMirror(cfg)
+	case config.CommandVerify:
+		err = download.Verify(cfg)
+	case config.CommandHistory:
+		err = download.History(cfg)
+	case config.CommandClean:
+		err = download.Clean(cfg)
+	case config.CommandTap:
+		err = download.Tap(cfg)
+	case config.CommandMatchTest:
+		err = download.MatchTest(cfg)
+	case config.CommandExport:
+		err = download.Export(cfg)
+	case config.CommandDB:
+		err = download.DB(cfg)
+	case config.CommandCache:
+		err = download.Cache(cfg)
+	case config.CommandServe:
+		err = download.Serve(cfg)
+	case config.CommandPlan:
+		err = download.Plan(cfg)
+	case config.CommandSchema:
+		err = download.Schema(cfg)
+	case config.CommandAdopt:
+		err = download.Adopt(cfg)
+	case config.CommandFreeze:
+		err = download.Freeze(cfg)
+	case config.CommandInstall:
+		err = download.Install(cfg)
+	case config.CommandAuditReport:
+		err = download.AuditReport(cfg)
+	case config.CommandBrowse:
+		err = download.Browse(cfg)
+	default:
+		err = download.DownloadFromRelease(cfg)
+	}
+
+	if err != nil {
+		url := download.SSOAuthorizationURL(err)
+		if log.JSON() {
+			log.Event(log.LevelInfo, "error", log.Failure{Error: err.Error(), ExitCode: download.ExitCode(err), SSOURL: url}, "")
+			os.Exit(download.ExitCode(err))
+		}
+		fmt.Fprintf(os.Stderr, "%s %v\n", color.Stderr.Red("Error:"), err)
+		if url != "" {
+			fmt.Fprintf(os.Stderr, "The organization enforces SAML single sign-on and the token is not authorized for it.\nAuthorize it at %s and try again.\n", url)
+		}
+		os.Exit(download.ExitCode(err))
 	}
 }
@@ -1,19 +1,43 @@
 package tests
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
+// binaryPath is the gh-download binary built for the tests. Running the
+// program with "go run" would hide its exit code.
+var binaryPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gh-download-bin-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create temp directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	binaryPath = filepath.Join(dir, "gh-download")
+	build := exec.Command("go", "build", "-o", binaryPath, "..")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to build gh-download: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // Helper function to run the main program with arguments
 func runGhDownload(t *testing.T, args ...string) (string, string, int) {
 	t.Helper()
 
-	// Build command to run main.go from the parent directory
-	cmdArgs := append([]string{"run", "../main.go"}, args...)
-	cmd := exec.Command("go", cmdArgs...)
+	cmd := exec.Command(binaryPath, args...)
 
 	// Capture stdout and stderr
 	stdout, err := cmd.Output()
@@ -81,8 +105,9 @@ func TestIntegration_ListAssets(t *testing.T) {
 	expectedStrings := []string{
 		"Release:",
 		"cli/cli",
-		"Assets matching pattern '*':",
-		"Total:",
+		// Piped listings are tab-separated
+		"gh_",
+		"\tapplication/",
 	}
 
 	for _, expected := range expectedStrings {
@@ -104,9 +129,9 @@ func TestIntegration_ListReleases(t *testing.T) {
 	}
 
 	expectedStrings := []string{
-		"Releases for cli/cli:",
-		"Assets:",
-		"Total:",
+		// Piped listings are tab-separated
+		"\tv2.",
+		"\t20",
 	}
 
 	for _, expected := range expectedStrings {
@@ -231,7 +256,123 @@ func TestIntegration_SpecificTag(t *testing.T) {
 		t.Errorf("Expected output to contain tag v2.0.0")
 	}
 
-	if !strings.Contains(stdout, "Assets matching pattern '*':") {
+	if !strings.Contains(stdout, "gh_2.0.0_") {
 		t.Errorf("Expected assets listing")
 	}
 }
+
+// Exit codes of the documented contract
+const (
+	exitPartialFailure     = 10
+	exitAllFailed          = 11
+	exitVerificationFailed = 12
+)
+
+// blockAsset makes downloading an asset fail by putting a directory where
+// the file would be written
+func blockAsset(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+}
+
+func TestIntegration_ExitCode_PartialFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := createTempDir(t)
+	blockAsset(t, tempDir, "gh_2.0.0_windows_386.msi")
+
+	_, stderr, exitCode := runGhDownload(t,
+		"--repo", "cli/cli",
+		"--tag", "v2.0.0",
+		"--pattern", "gh_2.0.0_windows_386.*",
+		"--dir", tempDir,
+		"--continue-on-error")
+
+	if exitCode != exitPartialFailure {
+		t.Errorf("Expected exit code %d, got %d. Stderr: %s", exitPartialFailure, exitCode, stderr)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "gh_2.0.0_windows_386.zip")); err != nil {
+		t.Errorf("Expected the remaining asset to be downloaded: %v", err)
+	}
+	if !strings.Contains(stderr, "1 of 2 assets failed") {
+		t.Errorf("Expected failure summary, got: %s", stderr)
+	}
+}
+
+func TestIntegration_ExitCode_AllFailed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := createTempDir(t)
+	blockAsset(t, tempDir, "gh_2.0.0_checksums.txt")
+
+	_, stderr, exitCode := runGhDownload(t,
+		"--repo", "cli/cli",
+		"--tag", "v2.0.0",
+		"--pattern", "*_checksums.txt",
+		"--dir", tempDir,
+		"--continue-on-error")
+
+	if exitCode != exitAllFailed {
+		t.Errorf("Expected exit code %d, got %d. Stderr: %s", exitAllFailed, exitCode, stderr)
+	}
+}
+
+func TestIntegration_ExitCode_WithoutContinueOnError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := createTempDir(t)
+	blockAsset(t, tempDir, "gh_2.0.0_checksums.txt")
+
+	_, stderr, exitCode := runGhDownload(t,
+		"--repo", "cli/cli",
+		"--tag", "v2.0.0",
+		"--pattern", "*_checksums.txt",
+		"--dir", tempDir)
+
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d. Stderr: %s", exitCode, stderr)
+	}
+}
+
+func TestIntegration_ExitCode_VerificationFailed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tempDir := createTempDir(t)
+
+	_, stderr, exitCode := runGhDownload(t,
+		"--repo", "cli/cli",
+		"--tag", "v2.0.0",
+		"--pattern", "gh_2.0.0_checksums.txt",
+		"--dir", tempDir,
+		"--checksum", "sha256:"+strings.Repeat("0", 64))
+
+	if exitCode != exitVerificationFailed {
+		t.Errorf("Expected exit code %d, got %d. Stderr: %s", exitVerificationFailed, exitCode, stderr)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "gh_2.0.0_checksums.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the mismatching asset to be deleted, got %v", err)
+	}
+	if !strings.Contains(stderr, "the file was deleted") {
+		t.Errorf("Expected checksum mismatch error, got: %s", stderr)
+	}
+}
+
+func TestIntegration_ExitCode_Usage(t *testing.T) {
+	_, stderr, exitCode := runGhDownload(t, "--no-such-flag")
+
+	if exitCode != 2 {
+		t.Errorf("Expected exit code 2, got %d. Stderr: %s", exitCode, stderr)
+	}
+}